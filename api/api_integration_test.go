@@ -57,7 +57,7 @@ func testVaultServerBackends(t testing.TB, backends map[string]logical.Factory)
 	if secret == nil || secret.Data["id"].(string) != rootToken {
 		t.Fatalf("token mismatch: %#v vs %q", secret, rootToken)
 	}
-	return client, func() { defer cluster.CloseListeners() }
+	return client, cluster.Cleanup
 }
 
 // testPostgresDB creates a testing postgres database in a Docker container,
@@ -1,8 +1,10 @@
 package api
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -42,9 +44,10 @@ type WrappingLookupFunc func(operation, path string) string
 // Config is used to configure the creation of the client.
 type Config struct {
 	// Address is the address of the Vault server. This should be a complete
-	// URL such as "http://vault.example.com". If you need a custom SSL
-	// cert or want to enable insecure mode, you need to specify a custom
-	// HttpClient.
+	// URL such as "http://vault.example.com", or the path of the Unix domain
+	// socket of a unix listener such as "unix:///run/vault.sock". If you
+	// need a custom SSL cert or want to enable insecure mode, you need to
+	// specify a custom HttpClient.
 	Address string
 
 	// HttpClient is the HTTP client to use, which will currently always have the
@@ -271,6 +274,23 @@ func NewClient(c *Config) (*Client, error) {
 		return nil, err
 	}
 
+	// Requests to a Unix domain socket are sent over plain HTTP, with every
+	// connection dialed to the socket
+	if u.Scheme == "unix" {
+		socket := u.Host + u.Path
+		if socket == "" {
+			return nil, fmt.Errorf("missing socket path in address %q", c.Address)
+		}
+		tp.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+		u = &url.URL{
+			Scheme: "http",
+			Host:   "localhost",
+		}
+	}
+
 	redirFunc := func() {
 		// Ensure redirects are not automatically followed
 		// Note that this is sane for the API client as it has its own
@@ -100,6 +100,29 @@ func (c *Logical) Write(path string, data map[string]interface{}) (*Secret, erro
 	return nil, nil
 }
 
+// Patch updates the given fields of the data at the path, following JSON
+// merge patch: fields set to nil are removed and maps are merged recursively
+func (c *Logical) Patch(path string, data map[string]interface{}) (*Secret, error) {
+	r := c.c.NewRequest("PATCH", "/v1/"+path)
+	if err := r.SetJSONBody(data); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == 200 {
+		return ParseSecret(resp.Body)
+	}
+
+	return nil, nil
+}
+
 func (c *Logical) Delete(path string) (*Secret, error) {
 	r := c.c.NewRequest("DELETE", "/v1/"+path)
 	resp, err := c.c.RawRequest(r)
@@ -51,6 +51,10 @@ type SSHVerifyResponse struct {
 
 	// Name of the role against which the OTP was issued
 	RoleName string `json:"role_name" structs:"role_name" mapstructure:"role_name"`
+
+	// Port associated with the OTP; zero for OTPs issued before ports were
+	// recorded
+	Port int `json:"port" structs:"port" mapstructure:"port"`
 }
 
 // SSHHelperConfig is a structure which represents the entries from the vault-ssh-helper's configuration file.
@@ -85,7 +85,9 @@ type EnableAuthOptions struct {
 	Type        string `json:"type" structs:"type"`
 	Description string `json:"description" structs:"description"`
 	Local       bool   `json:"local" structs:"local"`
+	SealWrap    bool   `json:"seal_wrap" structs:"seal_wrap"`
 	PluginName  string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+	LazyInit    bool   `json:"lazy_init,omitempty" structs:"lazy_init,omitempty" mapstructure:"lazy_init"`
 }
 
 type AuthMount struct {
@@ -94,10 +96,12 @@ type AuthMount struct {
 	Accessor    string           `json:"accessor" structs:"accessor" mapstructure:"accessor"`
 	Config      AuthConfigOutput `json:"config" structs:"config" mapstructure:"config"`
 	Local       bool             `json:"local" structs:"local" mapstructure:"local"`
+	SealWrap    bool             `json:"seal_wrap" structs:"seal_wrap" mapstructure:"seal_wrap"`
 }
 
 type AuthConfigOutput struct {
 	DefaultLeaseTTL int    `json:"default_lease_ttl" structs:"default_lease_ttl" mapstructure:"default_lease_ttl"`
 	MaxLeaseTTL     int    `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`
 	PluginName      string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+	LazyInit        bool   `json:"lazy_init,omitempty" structs:"lazy_init,omitempty" mapstructure:"lazy_init"`
 }
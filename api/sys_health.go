@@ -17,8 +17,11 @@ type HealthResponse struct {
 	Initialized   bool   `json:"initialized"`
 	Sealed        bool   `json:"sealed"`
 	Standby       bool   `json:"standby"`
+	PerfStandby   bool   `json:"performance_standby,omitempty"`
 	ServerTimeUTC int64  `json:"server_time_utc"`
 	Version       string `json:"version"`
 	ClusterName   string `json:"cluster_name,omitempty"`
 	ClusterID     string `json:"cluster_id,omitempty"`
+
+	QuarantinedPluginMounts []string `json:"quarantined_plugin_mounts,omitempty"`
 }
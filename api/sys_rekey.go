@@ -136,7 +136,7 @@ func (c *Sys) RekeyRetrieveBackup() (*RekeyRetrieveResponse, error) {
 }
 
 func (c *Sys) RekeyRetrieveRecoveryBackup() (*RekeyRetrieveResponse, error) {
-	r := c.c.NewRequest("GET", "/v1/sys/rekey/recovery-backup")
+	r := c.c.NewRequest("GET", "/v1/sys/rekey/recovery-key-backup")
 	resp, err := c.c.RawRequest(r)
 	if err != nil {
 		return nil, err
@@ -159,7 +159,7 @@ func (c *Sys) RekeyDeleteBackup() error {
 }
 
 func (c *Sys) RekeyDeleteRecoveryBackup() error {
-	r := c.c.NewRequest("DELETE", "/v1/sys/rekey/recovery-backup")
+	r := c.c.NewRequest("DELETE", "/v1/sys/rekey/recovery-key-backup")
 	resp, err := c.c.RawRequest(r)
 	if err == nil {
 		defer resp.Body.Close()
@@ -168,31 +168,92 @@ func (c *Sys) RekeyDeleteRecoveryBackup() error {
 	return err
 }
 
+func (c *Sys) RekeyRecoveryKeyVerificationStatus() (*RekeyVerificationStatusResponse, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/rekey-recovery-key/verify")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result RekeyVerificationStatusResponse
+	err = resp.DecodeJSON(&result)
+	return &result, err
+}
+
+func (c *Sys) RekeyRecoveryKeyVerificationUpdate(shard, nonce string) (*RekeyVerificationUpdateResponse, error) {
+	body := map[string]interface{}{
+		"key":   shard,
+		"nonce": nonce,
+	}
+
+	r := c.c.NewRequest("PUT", "/v1/sys/rekey-recovery-key/verify")
+	if err := r.SetJSONBody(body); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result RekeyVerificationUpdateResponse
+	err = resp.DecodeJSON(&result)
+	return &result, err
+}
+
+func (c *Sys) RekeyRecoveryKeyVerificationCancel() error {
+	r := c.c.NewRequest("DELETE", "/v1/sys/rekey-recovery-key/verify")
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
 type RekeyInitRequest struct {
-	SecretShares    int      `json:"secret_shares"`
-	SecretThreshold int      `json:"secret_threshold"`
-	PGPKeys         []string `json:"pgp_keys"`
-	Backup          bool
+	SecretShares        int      `json:"secret_shares"`
+	SecretThreshold     int      `json:"secret_threshold"`
+	PGPKeys             []string `json:"pgp_keys"`
+	Backup              bool
+	RequireVerification bool `json:"require_verification"`
 }
 
 type RekeyStatusResponse struct {
-	Nonce           string
-	Started         bool
-	T               int
-	N               int
-	Progress        int
-	Required        int
-	PGPFingerprints []string `json:"pgp_fingerprints"`
-	Backup          bool
+	Nonce                string
+	Started              bool
+	T                    int
+	N                    int
+	Progress             int
+	Required             int
+	PGPFingerprints      []string `json:"pgp_fingerprints"`
+	Backup               bool
+	VerificationRequired bool `json:"verification_required"`
 }
 
 type RekeyUpdateResponse struct {
-	Nonce           string
-	Complete        bool
-	Keys            []string
-	KeysB64         []string `json:"keys_base64"`
-	PGPFingerprints []string `json:"pgp_fingerprints"`
-	Backup          bool
+	Nonce                string
+	Complete             bool
+	Keys                 []string
+	KeysB64              []string `json:"keys_base64"`
+	PGPFingerprints      []string `json:"pgp_fingerprints"`
+	Backup               bool
+	VerificationRequired bool   `json:"verification_required"`
+	VerificationNonce    string `json:"verification_nonce"`
+}
+
+type RekeyVerificationStatusResponse struct {
+	Nonce    string
+	Started  bool
+	T        int
+	N        int
+	Progress int
+}
+
+type RekeyVerificationUpdateResponse struct {
+	Nonce    string
+	Complete bool
 }
 
 type RekeyRetrieveResponse struct {
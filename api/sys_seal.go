@@ -49,12 +49,29 @@ func sealStatusRequest(c *Sys, r *Request) (*SealStatusResponse, error) {
 }
 
 type SealStatusResponse struct {
-	Sealed      bool   `json:"sealed"`
-	T           int    `json:"t"`
-	N           int    `json:"n"`
-	Progress    int    `json:"progress"`
-	Nonce       string `json:"nonce"`
-	Version     string `json:"version"`
-	ClusterName string `json:"cluster_name,omitempty"`
-	ClusterID   string `json:"cluster_id,omitempty"`
+	Sealed      bool          `json:"sealed"`
+	T           int           `json:"t"`
+	N           int           `json:"n"`
+	Progress    int           `json:"progress"`
+	Nonce       string        `json:"nonce"`
+	Version     string        `json:"version"`
+	ClusterName string        `json:"cluster_name,omitempty"`
+	ClusterID   string        `json:"cluster_id,omitempty"`
+	Experiments []string      `json:"experiments,omitempty"`
+	Unseal      *UnsealStatus `json:"unseal,omitempty"`
+}
+
+type UnsealStatus struct {
+	InProgress bool          `json:"in_progress"`
+	StartTime  string        `json:"start_time"`
+	Error      string        `json:"error,omitempty"`
+	Phases     []UnsealPhase `json:"phases"`
+}
+
+type UnsealPhase struct {
+	Name      string `json:"name"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time,omitempty"`
+	Completed int    `json:"completed,omitempty"`
+	Total     int    `json:"total,omitempty"`
 }
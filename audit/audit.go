@@ -34,6 +34,12 @@ type Backend interface {
 	Invalidate()
 }
 
+// ChainVerifier is implemented by the audit backends that can chain their
+// entries, to verify the entries they wrote
+type ChainVerifier interface {
+	VerifyChain() (*ChainVerification, error)
+}
+
 type BackendConfig struct {
 	// The view to store the salt
 	SaltView logical.Storage
@@ -0,0 +1,239 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/hashicorp/vault/helper/salt"
+)
+
+const (
+	// chainHashPrefix prefixes the hashes of chained entries
+	chainHashPrefix = "sha256:"
+
+	// DefaultChainSignInterval is the default number of chained entries
+	// between two signatures
+	DefaultChainSignInterval = 100
+)
+
+// AuditChain links an audit entry to the previous entry written by the same
+// backend. Every few entries, the link is signed with the HMAC key of the
+// backend, which is protected by the barrier, so that the entries up to it
+// cannot be rewritten without access to Vault.
+type AuditChain struct {
+	Sequence  uint64 `json:"sequence"`
+	PrevHash  string `json:"prev_hash"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// Chain holds the state of the chain of the entries written by an audit
+// backend
+type Chain struct {
+	sync.Mutex
+
+	sequence     uint64
+	prevHash     string
+	signInterval uint64
+}
+
+// NewChain returns a chain signing every signInterval entries, or every
+// DefaultChainSignInterval entries if zero
+func NewChain(signInterval uint64) *Chain {
+	if signInterval == 0 {
+		signInterval = DefaultChainSignInterval
+	}
+	return &Chain{
+		signInterval: signInterval,
+	}
+}
+
+// ParseChain sets up the chaining of the entries of an audit backend from
+// its options. The chain option enables it and chain_sign_interval sets the
+// number of entries between signatures. A nil chain is returned if chaining
+// is disabled.
+func ParseChain(config map[string]string) (*Chain, error) {
+	raw, ok := config["chain"]
+	if !ok {
+		return nil, nil
+	}
+	enabled, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain: %v", err)
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	var signInterval uint64
+	if raw, ok := config["chain_sign_interval"]; ok {
+		signInterval, err = strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chain_sign_interval: %v", err)
+		}
+	}
+	return NewChain(signInterval), nil
+}
+
+// Resume continues the chain after the last entry of the reader, which
+// holds the entries previously written by the backend. Lines that are not
+// chained entries are ignored.
+func (c *Chain) Resume(r io.Reader) error {
+	c.Lock()
+	defer c.Unlock()
+
+	var last []byte
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if link, err := parseChainLink(line); err == nil && link != nil {
+			c.sequence = link.Sequence + 1
+			last = append(last[:0], line...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if last != nil {
+		c.prevHash = chainHash(last)
+	}
+	return nil
+}
+
+// write writes an entry with the next link of the chain. The entry is
+// buffered so that the chain only advances once it has been written
+// entirely.
+func (c *Chain) write(w io.Writer, salt *salt.Salt, writeEntry func(io.Writer, *AuditChain) error) error {
+	c.Lock()
+	defer c.Unlock()
+
+	link := &AuditChain{
+		Sequence: c.sequence,
+		PrevHash: c.prevHash,
+	}
+	if c.sequence%c.signInterval == 0 {
+		link.Signature = chainSignature(salt, link)
+	}
+
+	var buf bytes.Buffer
+	if err := writeEntry(&buf, link); err != nil {
+		return err
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	c.sequence++
+	c.prevHash = chainHash(bytes.TrimRight(buf.Bytes(), "\n"))
+	return nil
+}
+
+// ChainVerification is the result of verifying the chained entries of an
+// audit log
+type ChainVerification struct {
+	// Entries is the number of chained entries verified
+	Entries uint64 `json:"entries"`
+
+	// Signatures is the number of valid signatures, and LastSigned the
+	// sequence number of the last signed entry. Entries after it are
+	// chained but not yet covered by a signature.
+	Signatures uint64 `json:"signatures"`
+	LastSigned uint64 `json:"last_signed"`
+
+	// Valid is false if the chain is broken; Line is then the number of the
+	// first offending line and Error describes the problem
+	Valid bool   `json:"valid"`
+	Line  int    `json:"line,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// VerifyChain verifies the chained entries of the reader: that each entry
+// follows the previous one, and that the signatures are valid for the salt
+// of the backend. The link of the first entry is trusted since the log may
+// have been rotated.
+func VerifyChain(r io.Reader, salt *salt.Salt) (*ChainVerification, error) {
+	result := &ChainVerification{
+		Valid: true,
+	}
+	fail := func(line int, format string, args ...interface{}) (*ChainVerification, error) {
+		result.Valid = false
+		result.Line = line
+		result.Error = fmt.Sprintf(format, args...)
+		return result, nil
+	}
+
+	var prevHash string
+	var prevSequence uint64
+	lineNum := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Bytes()
+		link, err := parseChainLink(line)
+		if err != nil {
+			return fail(lineNum, "unreadable entry: %v", err)
+		}
+		if link == nil {
+			return fail(lineNum, "entry is not chained")
+		}
+
+		if result.Entries > 0 {
+			if link.Sequence != prevSequence+1 {
+				return fail(lineNum, "expected sequence %d, found %d", prevSequence+1, link.Sequence)
+			}
+			if link.PrevHash != prevHash {
+				return fail(lineNum, "previous entry was modified or removed")
+			}
+		}
+		if link.Signature != "" {
+			if link.Signature != chainSignature(salt, link) {
+				return fail(lineNum, "invalid signature")
+			}
+			result.Signatures++
+			result.LastSigned = link.Sequence
+		}
+
+		result.Entries++
+		prevSequence = link.Sequence
+		prevHash = chainHash(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// parseChainLink returns the chain link of an entry, or nil if it has none.
+// Any prefix before the JSON object is ignored.
+func parseChainLink(line []byte) (*AuditChain, error) {
+	start := bytes.IndexByte(line, '{')
+	if start < 0 {
+		return nil, fmt.Errorf("no JSON object found")
+	}
+
+	var entry struct {
+		Chain *AuditChain `json:"chain"`
+	}
+	if err := json.Unmarshal(line[start:], &entry); err != nil {
+		return nil, err
+	}
+	return entry.Chain, nil
+}
+
+func chainHash(line []byte) string {
+	sum := sha256.Sum256(line)
+	return chainHashPrefix + hex.EncodeToString(sum[:])
+}
+
+func chainSignature(salt *salt.Salt, link *AuditChain) string {
+	return salt.GetIdentifiedHMAC(strconv.FormatUint(link.Sequence, 10) + ":" + link.PrevHash)
+}
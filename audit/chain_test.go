@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/salt"
+	"github.com/hashicorp/vault/logical"
+)
+
+func testChainLog(t *testing.T, salter *salt.Salt, chain *Chain, n int) *bytes.Buffer {
+	formatter := AuditFormatter{
+		AuditFormatWriter: &JSONFormatWriter{
+			Prefix: "vault: ",
+			SaltFunc: func() (*salt.Salt, error) {
+				return salter, nil
+			},
+		},
+	}
+	config := FormatterConfig{
+		Chain: chain,
+	}
+
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		req := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "secret/foo",
+		}
+		if err := formatter.FormatRequest(&buf, config, nil, req, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := formatter.FormatResponse(&buf, config, nil, req, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &buf
+}
+
+func TestChain_Verify(t *testing.T) {
+	salter, err := salt.NewSalt(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := testChainLog(t, salter, NewChain(4), 5)
+	result, err := VerifyChain(bytes.NewReader(log.Bytes()), salter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Entries != 10 || result.Signatures != 3 || result.LastSigned != 8 {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// Verifying with another key fails on the first signature
+	other, err := salt.NewSalt(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = VerifyChain(bytes.NewReader(log.Bytes()), other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.Line != 1 {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// Modifying an entry breaks the link of the next one
+	lines := strings.SplitAfter(log.String(), "\n")
+	lines[2] = strings.Replace(lines[2], "secret/foo", "secret/bar", 1)
+	result, err = VerifyChain(strings.NewReader(strings.Join(lines, "")), salter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.Line != 4 {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// So does removing one
+	lines = strings.SplitAfter(log.String(), "\n")
+	lines = append(lines[:5], lines[6:]...)
+	result, err = VerifyChain(strings.NewReader(strings.Join(lines, "")), salter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.Line != 6 {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestChain_Resume(t *testing.T) {
+	salter, err := salt.NewSalt(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := testChainLog(t, salter, NewChain(0), 2)
+
+	chain := NewChain(0)
+	if err := chain.Resume(bytes.NewReader(log.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	log.Write(testChainLog(t, salter, chain, 2).Bytes())
+
+	result, err := VerifyChain(log, salter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Entries != 8 || result.Signatures != 1 {
+		t.Fatalf("bad: %#v", result)
+	}
+}
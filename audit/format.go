@@ -91,17 +91,21 @@ func (f *AuditFormatter) FormatRequest(
 			}
 		}
 
-		// Cache and restore accessor in the request
+		// Cache and restore accessor and non-HMAC data in the request
 		var clientTokenAccessor string
 		if !config.HMACAccessor && req != nil && req.ClientTokenAccessor != "" {
 			clientTokenAccessor = req.ClientTokenAccessor
 		}
+		nonHMACReqData := cacheNonHMACData(req.Data, req.AuditNonHMACReqDataKeys)
 		if err := Hash(salt, req); err != nil {
 			return err
 		}
 		if clientTokenAccessor != "" {
 			req.ClientTokenAccessor = clientTokenAccessor
 		}
+		for k, v := range nonHMACReqData {
+			req.Data[k] = v
+		}
 	}
 
 	// If auth is nil, make an empty one
@@ -136,6 +140,7 @@ func (f *AuditFormatter) FormatRequest(
 			RemoteAddr:          getRemoteAddr(req),
 			ReplicationCluster:  req.ReplicationCluster,
 			Headers:             req.Headers,
+			Canary:              req.Canary,
 		},
 	}
 
@@ -143,10 +148,19 @@ func (f *AuditFormatter) FormatRequest(
 		reqEntry.Request.WrapTTL = int(req.WrapInfo.TTL / time.Second)
 	}
 
+	enrichLoginRequest(config, req, &reqEntry.Request)
+
 	if !config.OmitTime {
 		reqEntry.Time = time.Now().UTC().Format(time.RFC3339)
 	}
 
+	if config.Chain != nil {
+		return config.Chain.write(w, salt, func(w io.Writer, link *AuditChain) error {
+			reqEntry.Chain = link
+			return f.AuditFormatWriter.WriteRequest(w, reqEntry)
+		})
+	}
+
 	return f.AuditFormatWriter.WriteRequest(w, reqEntry)
 }
 
@@ -226,17 +240,21 @@ func (f *AuditFormatter) FormatResponse(
 			}
 		}
 
-		// Cache and restore accessor in the request
+		// Cache and restore accessor and non-HMAC data in the request
 		var clientTokenAccessor string
 		if !config.HMACAccessor && req != nil && req.ClientTokenAccessor != "" {
 			clientTokenAccessor = req.ClientTokenAccessor
 		}
+		nonHMACReqData := cacheNonHMACData(req.Data, req.AuditNonHMACReqDataKeys)
 		if err := Hash(salt, req); err != nil {
 			return err
 		}
 		if clientTokenAccessor != "" {
 			req.ClientTokenAccessor = clientTokenAccessor
 		}
+		for k, v := range nonHMACReqData {
+			req.Data[k] = v
+		}
 
 		// Cache and restore accessor in the response
 		if resp != nil {
@@ -247,9 +265,20 @@ func (f *AuditFormatter) FormatResponse(
 			if !config.HMACAccessor && resp != nil && resp.WrapInfo != nil && resp.WrapInfo.WrappedAccessor != "" {
 				wrappedAccessor = resp.WrapInfo.WrappedAccessor
 			}
+			var listData map[string]interface{}
+			if config.RawListResponses && req != nil && req.Operation == logical.ListOperation {
+				listData = resp.Data
+			}
+			nonHMACRespData := cacheNonHMACData(resp.Data, req.AuditNonHMACRespDataKeys)
 			if err := Hash(salt, resp); err != nil {
 				return err
 			}
+			if listData != nil {
+				resp.Data = listData
+			}
+			for k, v := range nonHMACRespData {
+				resp.Data[k] = v
+			}
 			if accessor != "" {
 				resp.Auth.Accessor = accessor
 			}
@@ -326,6 +355,7 @@ func (f *AuditFormatter) FormatResponse(
 			RemoteAddr:          getRemoteAddr(req),
 			ReplicationCluster:  req.ReplicationCluster,
 			Headers:             req.Headers,
+			Canary:              req.Canary,
 		},
 
 		Response: AuditResponse{
@@ -341,10 +371,19 @@ func (f *AuditFormatter) FormatResponse(
 		respEntry.Request.WrapTTL = int(req.WrapInfo.TTL / time.Second)
 	}
 
+	enrichLoginRequest(config, req, &respEntry.Request)
+
 	if !config.OmitTime {
 		respEntry.Time = time.Now().UTC().Format(time.RFC3339)
 	}
 
+	if config.Chain != nil {
+		return config.Chain.write(w, salt, func(w io.Writer, link *AuditChain) error {
+			respEntry.Chain = link
+			return f.AuditFormatWriter.WriteResponse(w, respEntry)
+		})
+	}
+
 	return f.AuditFormatWriter.WriteResponse(w, respEntry)
 }
 
@@ -355,6 +394,7 @@ type AuditRequestEntry struct {
 	Auth    AuditAuth    `json:"auth"`
 	Request AuditRequest `json:"request"`
 	Error   string       `json:"error"`
+	Chain   *AuditChain  `json:"chain,omitempty"`
 }
 
 // AuditResponseEntry is the structure of a response audit log entry in Audit.
@@ -365,6 +405,7 @@ type AuditResponseEntry struct {
 	Request  AuditRequest  `json:"request"`
 	Response AuditResponse `json:"response"`
 	Error    string        `json:"error"`
+	Chain    *AuditChain   `json:"chain,omitempty"`
 }
 
 type AuditRequest struct {
@@ -378,6 +419,13 @@ type AuditRequest struct {
 	RemoteAddr          string                 `json:"remote_address"`
 	WrapTTL             int                    `json:"wrap_ttl"`
 	Headers             map[string][]string    `json:"headers"`
+
+	// Only set for login requests when enabled on the audit backend
+	UserAgent          string            `json:"user_agent,omitempty"`
+	RemoteAddrMetadata map[string]string `json:"remote_address_metadata,omitempty"`
+
+	// Canary is the name of the canary token tripped by the request
+	Canary string `json:"canary,omitempty"`
 }
 
 type AuditResponse struct {
@@ -410,6 +458,21 @@ type AuditResponseWrapInfo struct {
 }
 
 // getRemoteAddr safely gets the remote address avoiding a nil pointer
+// cacheNonHMACData returns the values of the data under the given keys, which
+// are restored after hashing the data so that they are logged in cleartext
+func cacheNonHMACData(data map[string]interface{}, keys []string) map[string]interface{} {
+	if len(data) == 0 || len(keys) == 0 {
+		return nil
+	}
+	cached := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if v, ok := data[key]; ok {
+			cached[key] = v
+		}
+	}
+	return cached
+}
+
 func getRemoteAddr(req *logical.Request) string {
 	if req != nil && req.Connection != nil {
 		return req.Connection.RemoteAddr
@@ -12,13 +12,17 @@ import (
 type noopFormatWriter struct {
 	salt     *salt.Salt
 	SaltFunc func() (*salt.Salt, error)
+
+	// response is the last response entry written
+	response *AuditResponseEntry
 }
 
 func (n *noopFormatWriter) WriteRequest(_ io.Writer, _ *AuditRequestEntry) error {
 	return nil
 }
 
-func (n *noopFormatWriter) WriteResponse(_ io.Writer, _ *AuditResponseEntry) error {
+func (n *noopFormatWriter) WriteResponse(_ io.Writer, entry *AuditResponseEntry) error {
+	n.response = entry
 	return nil
 }
 
@@ -61,3 +65,83 @@ func TestFormatResponseErrors(t *testing.T) {
 		t.Fatal("expected error due to nil writer")
 	}
 }
+
+func TestFormatResponse_rawListResponses(t *testing.T) {
+	writer := &noopFormatWriter{}
+	formatter := AuditFormatter{
+		AuditFormatWriter: writer,
+	}
+	config := FormatterConfig{
+		RawListResponses: true,
+	}
+
+	cases := []struct {
+		operation logical.Operation
+		raw       bool
+	}{
+		{logical.ListOperation, true},
+		{logical.ReadOperation, false},
+		{logical.UpdateOperation, false},
+	}
+	for _, tc := range cases {
+		req := &logical.Request{
+			Operation: tc.operation,
+			Path:      "secret/",
+		}
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"keys": []string{"foo", "bar"},
+			},
+		}
+		if err := formatter.FormatResponse(ioutil.Discard, config, nil, req, resp, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		keys := writer.response.Response.Data["keys"].([]string)
+		if raw := keys[0] == "foo"; raw != tc.raw {
+			t.Fatalf("%s: expected raw=%t, got keys %v", tc.operation, tc.raw, keys)
+		}
+		if resp.Data["keys"].([]string)[0] != "foo" {
+			t.Fatalf("%s: the response was modified", tc.operation)
+		}
+	}
+}
+
+func TestFormatResponse_nonHMACDataKeys(t *testing.T) {
+	writer := &noopFormatWriter{}
+	formatter := AuditFormatter{
+		AuditFormatWriter: writer,
+	}
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "pki/issue/example",
+		Data: map[string]interface{}{
+			"common_name": "example.com",
+			"ttl":         "1h",
+		},
+		AuditNonHMACReqDataKeys:  []string{"common_name", "missing"},
+		AuditNonHMACRespDataKeys: []string{"serial_number"},
+	}
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"serial_number": "1a:2b",
+			"private_key":   "secret",
+		},
+	}
+	if err := formatter.FormatResponse(ioutil.Discard, FormatterConfig{}, nil, req, resp, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	reqData := writer.response.Request.Data
+	if reqData["common_name"] != "example.com" || reqData["ttl"] == "1h" {
+		t.Fatalf("bad: %#v", reqData)
+	}
+	if _, ok := reqData["missing"]; ok {
+		t.Fatalf("bad: %#v", reqData)
+	}
+	respData := writer.response.Response.Data
+	if respData["serial_number"] != "1a:2b" || respData["private_key"] == "secret" {
+		t.Fatalf("bad: %#v", respData)
+	}
+}
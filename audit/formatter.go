@@ -20,6 +20,20 @@ type FormatterConfig struct {
 	Raw          bool
 	HMACAccessor bool
 
+	// RawListResponses logs the response data of list operations without
+	// hashing it, so that the keys listed can be reconstructed, while the
+	// responses to other operations are still hashed
+	RawListResponses bool
+
+	// LoginUserAgent and LoginIPMetadata enrich the entries of login
+	// requests with the user agent of the client and the metadata of its
+	// IP address
+	LoginUserAgent  bool
+	LoginIPMetadata IPMetadataLookup
+
+	// Chain links each entry to the previous one for tamper evidence
+	Chain *Chain
+
 	// This should only ever be used in a testing context
 	OmitTime bool
 }
@@ -0,0 +1,195 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// IPMetadataLookup returns metadata about an IP address, such as its
+// location from a GeoIP database, to enrich the audit entries of login
+// requests. A nil map is returned if nothing is known about the address.
+type IPMetadataLookup interface {
+	Lookup(net.IP) (map[string]string, error)
+}
+
+// IPMetadataLookupFactory creates an IP metadata lookup from the options of
+// the audit backend using it
+type IPMetadataLookupFactory func(config map[string]string) (IPMetadataLookup, error)
+
+var (
+	ipMetadataLookupsLock sync.RWMutex
+	ipMetadataLookups     = map[string]IPMetadataLookupFactory{
+		"cidr": NewCIDRMetadataLookup,
+	}
+)
+
+// RegisterIPMetadataLookup makes an IP metadata lookup available to audit
+// backends under the given name
+func RegisterIPMetadataLookup(name string, factory IPMetadataLookupFactory) {
+	ipMetadataLookupsLock.Lock()
+	defer ipMetadataLookupsLock.Unlock()
+	ipMetadataLookups[name] = factory
+}
+
+// ParseLoginEnrichment sets up the enrichment of the audit entries of login
+// requests from the options of an audit backend. The login_user_agent option
+// records the user agent of the client, and login_ip_metadata names the IP
+// metadata lookup to use, which takes its own options.
+func ParseLoginEnrichment(config map[string]string, formatConfig *FormatterConfig) error {
+	if raw, ok := config["login_user_agent"]; ok {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid login_user_agent: %v", err)
+		}
+		formatConfig.LoginUserAgent = value
+	}
+
+	if name, ok := config["login_ip_metadata"]; ok && name != "" {
+		ipMetadataLookupsLock.RLock()
+		factory, ok := ipMetadataLookups[name]
+		ipMetadataLookupsLock.RUnlock()
+		if !ok {
+			return fmt.Errorf("unknown IP metadata lookup %q", name)
+		}
+
+		lookup, err := factory(config)
+		if err != nil {
+			return fmt.Errorf("failed to set up IP metadata lookup %q: %v", name, err)
+		}
+		formatConfig.LoginIPMetadata = lookup
+	}
+
+	return nil
+}
+
+// isLoginRequest returns whether the request is a login attempt, which are
+// the requests to credential backends made without a token
+func isLoginRequest(req *logical.Request) bool {
+	return req.ClientToken == "" && strings.HasPrefix(req.Path, "auth/")
+}
+
+// enrichLoginRequest adds the information enabled in the config to the
+// audit entry of a login request
+func enrichLoginRequest(config FormatterConfig, req *logical.Request, entry *AuditRequest) {
+	if !isLoginRequest(req) || req.Connection == nil {
+		return
+	}
+
+	if config.LoginUserAgent {
+		entry.UserAgent = req.Connection.UserAgent
+	}
+
+	if config.LoginIPMetadata != nil {
+		// Failed lookups must not prevent auditing, so they are only left
+		// out of the entry
+		if ip := net.ParseIP(req.Connection.RemoteAddr); ip != nil {
+			metadata, err := config.LoginIPMetadata.Lookup(ip)
+			if err == nil {
+				entry.RemoteAddrMetadata = metadata
+			}
+		}
+	}
+}
+
+// CIDRMetadataLookup looks up the metadata of IP addresses in a JSON file,
+// such as an export of a GeoIP database, that maps CIDR blocks to objects of
+// string metadata. The metadata of the most specific block containing the
+// address is returned. It is created from the login_ip_metadata_path option.
+//
+// The blocks are loaded into one binary trie per address family, so that a
+// lookup walks at most as many nodes as the address has bits, however large
+// the file is.
+type CIDRMetadataLookup struct {
+	ipv4 *cidrTrieNode
+	ipv6 *cidrTrieNode
+}
+
+// cidrTrieNode is the node of a block whose prefix is the path of bits from
+// the root; metadata is nil if no block has that prefix
+type cidrTrieNode struct {
+	children [2]*cidrTrieNode
+	metadata map[string]string
+}
+
+// NewCIDRMetadataLookup is the factory of the "cidr" IP metadata lookup
+func NewCIDRMetadataLookup(config map[string]string) (IPMetadataLookup, error) {
+	path := config["login_ip_metadata_path"]
+	if path == "" {
+		return nil, fmt.Errorf("login_ip_metadata_path is required")
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks map[string]map[string]string
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	l := &CIDRMetadataLookup{
+		ipv4: &cidrTrieNode{},
+		ipv6: &cidrTrieNode{},
+	}
+	for cidr, metadata := range blocks {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR block %q in %s: %v", cidr, path, err)
+		}
+		l.insert(network, metadata)
+	}
+	return l, nil
+}
+
+// insert adds the metadata of a network to the trie of its address family
+func (l *CIDRMetadataLookup) insert(network *net.IPNet, metadata map[string]string) {
+	node, ip := l.ipv6, network.IP.To16()
+	if ip4 := network.IP.To4(); ip4 != nil {
+		node, ip = l.ipv4, ip4
+	}
+
+	ones, _ := network.Mask.Size()
+	for i := 0; i < ones; i++ {
+		bit := ipBit(ip, i)
+		if node.children[bit] == nil {
+			node.children[bit] = &cidrTrieNode{}
+		}
+		node = node.children[bit]
+	}
+	node.metadata = metadata
+}
+
+// Lookup implements IPMetadataLookup
+func (l *CIDRMetadataLookup) Lookup(ip net.IP) (map[string]string, error) {
+	node := l.ipv6
+	if ip4 := ip.To4(); ip4 != nil {
+		node, ip = l.ipv4, ip4
+	} else if ip = ip.To16(); ip == nil {
+		return nil, nil
+	}
+
+	var match map[string]string
+	for i := 0; node != nil; i++ {
+		if node.metadata != nil {
+			match = node.metadata
+		}
+		if i == len(ip)*8 {
+			break
+		}
+		node = node.children[ipBit(ip, i)]
+	}
+	return match, nil
+}
+
+// ipBit returns the i-th most significant bit of the address
+func ipBit(ip net.IP, i int) int {
+	return int(ip[i/8]>>uint(7-i%8)) & 1
+}
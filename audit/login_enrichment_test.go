@@ -0,0 +1,180 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/salt"
+	"github.com/hashicorp/vault/logical"
+)
+
+func testCIDRMetadataFile(t *testing.T) string {
+	f, err := ioutil.TempFile("", "vault-ip-metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(`{
+		"10.0.0.0/8": {"site": "dc1"},
+		"10.1.0.0/16": {"site": "dc1", "rack": "r1"},
+		"10.1.3.5/32": {"site": "dc1", "rack": "r1", "host": "h1"},
+		"2001:db8::/32": {"country": "NZ"}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestCIDRMetadataLookup(t *testing.T) {
+	path := testCIDRMetadataFile(t)
+	defer os.Remove(path)
+
+	if _, err := NewCIDRMetadataLookup(map[string]string{}); err == nil {
+		t.Fatal("expected error without a path")
+	}
+
+	lookup, err := NewCIDRMetadataLookup(map[string]string{"login_ip_metadata_path": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]map[string]string{
+		"10.2.3.4":        {"site": "dc1"},
+		"10.1.3.4":        {"site": "dc1", "rack": "r1"},
+		"10.1.3.5":        {"site": "dc1", "rack": "r1", "host": "h1"},
+		"::ffff:10.1.3.5": {"site": "dc1", "rack": "r1", "host": "h1"},
+		"2001:db8::1":     {"country": "NZ"},
+		"2001:db9::1":     nil,
+		"192.0.2.1":       nil,
+	}
+	for ip, expected := range cases {
+		actual, err := lookup.Lookup(net.ParseIP(ip))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("bad: %s: expected %#v, got %#v", ip, expected, actual)
+		}
+	}
+}
+
+func TestParseLoginEnrichment(t *testing.T) {
+	path := testCIDRMetadataFile(t)
+	defer os.Remove(path)
+
+	var config FormatterConfig
+	if err := ParseLoginEnrichment(map[string]string{}, &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.LoginUserAgent || config.LoginIPMetadata != nil {
+		t.Fatalf("bad: %#v", config)
+	}
+
+	for _, options := range []map[string]string{
+		{"login_user_agent": "maybe"},
+		{"login_ip_metadata": "unknown"},
+		{"login_ip_metadata": "cidr"},
+	} {
+		if err := ParseLoginEnrichment(options, &FormatterConfig{}); err == nil {
+			t.Fatalf("expected error for %v", options)
+		}
+	}
+
+	err := ParseLoginEnrichment(map[string]string{
+		"login_user_agent":       "true",
+		"login_ip_metadata":      "cidr",
+		"login_ip_metadata_path": path,
+	}, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.LoginUserAgent || config.LoginIPMetadata == nil {
+		t.Fatalf("bad: %#v", config)
+	}
+}
+
+func TestFormatJSON_loginEnrichment(t *testing.T) {
+	path := testCIDRMetadataFile(t)
+	defer os.Remove(path)
+
+	salter, err := salt.NewSalt(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatter := AuditFormatter{
+		AuditFormatWriter: &JSONFormatWriter{
+			SaltFunc: func() (*salt.Salt, error) {
+				return salter, nil
+			},
+		},
+	}
+
+	var config FormatterConfig
+	err = ParseLoginEnrichment(map[string]string{
+		"login_user_agent":       "true",
+		"login_ip_metadata":      "cidr",
+		"login_ip_metadata_path": path,
+	}, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newRequest := func(path, token string) *logical.Request {
+		return &logical.Request{
+			Operation:   logical.UpdateOperation,
+			Path:        path,
+			ClientToken: token,
+			Connection: &logical.Connection{
+				RemoteAddr: "10.1.2.3",
+				UserAgent:  "curl/7.54.0",
+			},
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := formatter.FormatRequest(&buf, config, nil, newRequest("auth/userpass/login/bob", ""), nil); err != nil {
+		t.Fatal(err)
+	}
+	var reqEntry AuditRequestEntry
+	if err := json.Unmarshal(buf.Bytes(), &reqEntry); err != nil {
+		t.Fatal(err)
+	}
+	if reqEntry.Request.UserAgent != "curl/7.54.0" {
+		t.Fatalf("bad: %#v", reqEntry.Request)
+	}
+	if !reflect.DeepEqual(reqEntry.Request.RemoteAddrMetadata, map[string]string{"site": "dc1", "rack": "r1"}) {
+		t.Fatalf("bad: %#v", reqEntry.Request)
+	}
+
+	buf.Reset()
+	if err := formatter.FormatResponse(&buf, config, nil, newRequest("auth/userpass/login/bob", ""), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	var respEntry AuditResponseEntry
+	if err := json.Unmarshal(buf.Bytes(), &respEntry); err != nil {
+		t.Fatal(err)
+	}
+	if respEntry.Request.UserAgent != "curl/7.54.0" || respEntry.Request.RemoteAddrMetadata["rack"] != "r1" {
+		t.Fatalf("bad: %#v", respEntry.Request)
+	}
+
+	// Requests made with a token are not login attempts
+	buf.Reset()
+	if err := formatter.FormatRequest(&buf, config, nil, newRequest("auth/userpass/users/bob", "foo"), nil); err != nil {
+		t.Fatal(err)
+	}
+	reqEntry = AuditRequestEntry{}
+	if err := json.Unmarshal(buf.Bytes(), &reqEntry); err != nil {
+		t.Fatal(err)
+	}
+	if reqEntry.Request.UserAgent != "" || reqEntry.Request.RemoteAddrMetadata != nil {
+		t.Fatalf("bad: %#v", reqEntry.Request)
+	}
+}
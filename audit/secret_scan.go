@@ -0,0 +1,169 @@
+package audit
+
+import (
+	"fmt"
+	"net/textproto"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// SecretDetector flags raw secrets in the values of a request, such as a
+// token passed in the request data or in the path, which is not HMACed in
+// the audit log. Detectors are run on the request before it is handed to the
+// audit backends.
+type SecretDetector interface {
+	// Name identifies the detector in the logs and metrics
+	Name() string
+
+	// Detect reports whether the value of the given field of the request is
+	// a raw secret. Fields are "path", "header.<name>", "data_key.<key>" for
+	// the keys of the data, and "data.<key>" for its values, with nested keys
+	// separated by dots.
+	Detect(req *logical.Request, field, value string) bool
+}
+
+// SecretFinding is a raw secret found in a request by a detector
+type SecretFinding struct {
+	Detector string
+	Field    string
+}
+
+func (f *SecretFinding) String() string {
+	return fmt.Sprintf("%s in %s", f.Detector, f.Field)
+}
+
+// SecretScanner runs the registered detectors on requests
+type SecretScanner struct {
+	sync.RWMutex
+
+	// Block is set if requests in which a secret is found must be refused
+	Block bool
+
+	detectors map[string]SecretDetector
+}
+
+// NewSecretScanner returns a scanner with the given detectors registered
+func NewSecretScanner(detectors ...SecretDetector) *SecretScanner {
+	s := &SecretScanner{
+		detectors: make(map[string]SecretDetector),
+	}
+	for _, d := range detectors {
+		s.Register(d)
+	}
+	return s
+}
+
+// Register adds a detector, replacing any detector of the same name
+func (s *SecretScanner) Register(d SecretDetector) {
+	s.Lock()
+	defer s.Unlock()
+	s.detectors[d.Name()] = d
+}
+
+// Deregister removes the detector of the given name
+func (s *SecretScanner) Deregister(name string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.detectors, name)
+}
+
+// Scan runs the detectors on the path, headers and data of the request, and
+// returns the secrets found sorted by detector and field
+func (s *SecretScanner) Scan(req *logical.Request) []*SecretFinding {
+	s.RLock()
+	defer s.RUnlock()
+	if len(s.detectors) == 0 {
+		return nil
+	}
+
+	var findings []*SecretFinding
+	check := func(field, value string) {
+		if value == "" {
+			return
+		}
+		for name, d := range s.detectors {
+			if d.Detect(req, field, value) {
+				findings = append(findings, &SecretFinding{
+					Detector: name,
+					Field:    field,
+				})
+			}
+		}
+	}
+
+	check("path", req.Path)
+	for name, values := range req.Headers {
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		if name == clientTokenHeader {
+			continue
+		}
+		for _, v := range values {
+			check("header."+name, v)
+		}
+	}
+	scanData("", req.Data, check)
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Detector != findings[j].Detector {
+			return findings[i].Detector < findings[j].Detector
+		}
+		return findings[i].Field < findings[j].Field
+	})
+	return findings
+}
+
+// clientTokenHeader is where clients are expected to pass their token
+const clientTokenHeader = "X-Vault-Token"
+
+// scanData calls check on the keys and string values of the data,
+// recursively
+func scanData(prefix string, raw interface{}, check func(field, value string)) {
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			check("data_key."+prefix+key, key)
+			scanData(prefix+key+".", value, check)
+		}
+	case []interface{}:
+		for _, value := range v {
+			scanData(prefix, value, check)
+		}
+	case []string:
+		for _, value := range v {
+			check("data."+strings.TrimSuffix(prefix, "."), value)
+		}
+	case string:
+		check("data."+strings.TrimSuffix(prefix, "."), v)
+	}
+}
+
+// DefaultSecretDetectors returns the detectors registered when none are
+// configured
+func DefaultSecretDetectors() []SecretDetector {
+	return []SecretDetector{
+		ClientTokenDetector{},
+	}
+}
+
+// ClientTokenDetector flags the client token of a request appearing anywhere
+// else than in its X-Vault-Token header, typically because the client passed
+// it as data. It does not flag the token parameter of the token endpoints
+// operating on the token given in the data.
+type ClientTokenDetector struct{}
+
+func (ClientTokenDetector) Name() string {
+	return "client_token"
+}
+
+func (ClientTokenDetector) Detect(req *logical.Request, field, value string) bool {
+	if req.ClientToken == "" {
+		return false
+	}
+	if field == "data.token" && strings.HasPrefix(req.Path, "auth/token/") {
+		return false
+	}
+	return strings.Contains(value, req.ClientToken)
+}
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestSecretScanner_Scan(t *testing.T) {
+	scanner := NewSecretScanner(DefaultSecretDetectors()...)
+
+	req := &logical.Request{
+		Path:        "secret/foo/abcd-1234",
+		ClientToken: "abcd-1234",
+		Headers: map[string][]string{
+			"X-Vault-Token": []string{"abcd-1234"},
+			"x-forwarded":   []string{"token abcd-1234"},
+		},
+		Data: map[string]interface{}{
+			"token": "abcd-1234",
+			"nested": map[string]interface{}{
+				"list": []interface{}{"foo", "abcd-1234"},
+			},
+			"abcd-1234": "bar",
+			"other":     "value",
+		},
+	}
+
+	exp := []*SecretFinding{
+		&SecretFinding{Detector: "client_token", Field: "data.nested.list"},
+		&SecretFinding{Detector: "client_token", Field: "data.token"},
+		&SecretFinding{Detector: "client_token", Field: "data_key.abcd-1234"},
+		&SecretFinding{Detector: "client_token", Field: "header.X-Forwarded"},
+		&SecretFinding{Detector: "client_token", Field: "path"},
+	}
+	if findings := scanner.Scan(req); !reflect.DeepEqual(findings, exp) {
+		t.Fatalf("bad: %v", findings)
+	}
+
+	// The token of the token endpoints is expected in the data
+	req = &logical.Request{
+		Path:        "auth/token/lookup",
+		ClientToken: "abcd-1234",
+		Data: map[string]interface{}{
+			"token": "abcd-1234",
+		},
+	}
+	if findings := scanner.Scan(req); len(findings) != 0 {
+		t.Fatalf("bad: %v", findings)
+	}
+
+	scanner.Deregister("client_token")
+	req.Path = "secret/abcd-1234"
+	if findings := scanner.Scan(req); len(findings) != 0 {
+		t.Fatalf("bad: %v", findings)
+	}
+}
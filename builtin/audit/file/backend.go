@@ -58,6 +58,16 @@ func Factory(conf *audit.BackendConfig) (audit.Backend, error) {
 		logRaw = b
 	}
 
+	// Check if the responses of list operations are logged without hashing
+	logListResponses := false
+	if raw, ok := conf.Config["log_list_responses"]; ok {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		logListResponses = b
+	}
+
 	// Check if mode is provided
 	mode := os.FileMode(0600)
 	if modeRaw, ok := conf.Config["mode"]; ok {
@@ -74,11 +84,30 @@ func Factory(conf *audit.BackendConfig) (audit.Backend, error) {
 		saltConfig: conf.SaltConfig,
 		saltView:   conf.SaltView,
 		formatConfig: audit.FormatterConfig{
-			Raw:          logRaw,
-			HMACAccessor: hmacAccessor,
+			Raw:              logRaw,
+			HMACAccessor:     hmacAccessor,
+			RawListResponses: logListResponses,
 		},
 	}
 
+	if err := audit.ParseLoginEnrichment(conf.Config, &b.formatConfig); err != nil {
+		return nil, err
+	}
+
+	chain, err := audit.ParseChain(conf.Config)
+	if err != nil {
+		return nil, err
+	}
+	if chain != nil {
+		if format != "json" {
+			return nil, fmt.Errorf("chain is only supported with the json format")
+		}
+		if err := b.resumeChain(chain); err != nil {
+			return nil, fmt.Errorf("failed to resume chain from %s: %v", path, err)
+		}
+		b.formatConfig.Chain = chain
+	}
+
 	switch format {
 	case "json":
 		b.formatter.AuditFormatWriter = &audit.JSONFormatWriter{
@@ -231,6 +260,42 @@ func (b *Backend) open() error {
 	return nil
 }
 
+// resumeChain continues the chain after the entries already in the file
+func (b *Backend) resumeChain(chain *audit.Chain) error {
+	f, err := os.Open(b.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return chain.Resume(f)
+}
+
+// VerifyChain verifies the chain of the entries in the file
+func (b *Backend) VerifyChain() (*audit.ChainVerification, error) {
+	if b.formatConfig.Chain == nil {
+		return nil, fmt.Errorf("chain is not enabled on this audit backend")
+	}
+
+	salt, err := b.Salt()
+	if err != nil {
+		return nil, err
+	}
+
+	// Hold off writes so that the last entry is complete
+	b.fileLock.RLock()
+	defer b.fileLock.RUnlock()
+
+	f, err := os.Open(b.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return audit.VerifyChain(f, salt)
+}
+
 func (b *Backend) Reload() error {
 	b.fileLock.Lock()
 	defer b.fileLock.Unlock()
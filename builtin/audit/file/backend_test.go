@@ -89,3 +89,50 @@ func TestAuditFile_fileModeExisting(t *testing.T) {
 		t.Fatalf("File mode does not match.")
 	}
 }
+
+func TestAuditFile_chain(t *testing.T) {
+	path, err := ioutil.TempDir("", "vault-test_audit_file-chain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	conf := &audit.BackendConfig{
+		SaltConfig: &salt.Config{},
+		SaltView:   &logical.InmemStorage{},
+		Config: map[string]string{
+			"path":                filepath.Join(path, "audit.log"),
+			"chain":               "true",
+			"chain_sign_interval": "2",
+		},
+	}
+
+	// The chain continues across backend restarts
+	for i := 0; i < 2; i++ {
+		b, err := Factory(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "secret/foo",
+		}
+		for j := 0; j < 3; j++ {
+			if err := b.LogRequest(nil, req, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	b, err := Factory(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := b.(audit.ChainVerifier).VerifyChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Entries != 6 || result.Signatures != 3 {
+		t.Fatalf("bad: %#v", result)
+	}
+}
@@ -72,12 +72,23 @@ func Factory(conf *audit.BackendConfig) (audit.Backend, error) {
 		logRaw = b
 	}
 
+	// Check if the responses of list operations are logged without hashing
+	logListResponses := false
+	if raw, ok := conf.Config["log_list_responses"]; ok {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		logListResponses = b
+	}
+
 	b := &Backend{
 		saltConfig: conf.SaltConfig,
 		saltView:   conf.SaltView,
 		formatConfig: audit.FormatterConfig{
-			Raw:          logRaw,
-			HMACAccessor: hmacAccessor,
+			Raw:              logRaw,
+			HMACAccessor:     hmacAccessor,
+			RawListResponses: logListResponses,
 		},
 
 		writeDuration: writeDuration,
@@ -85,6 +96,10 @@ func Factory(conf *audit.BackendConfig) (audit.Backend, error) {
 		socketType:    socketType,
 	}
 
+	if err := audit.ParseLoginEnrichment(conf.Config, &b.formatConfig); err != nil {
+		return nil, err
+	}
+
 	switch format {
 	case "json":
 		b.formatter.AuditFormatWriter = &audit.JSONFormatWriter{
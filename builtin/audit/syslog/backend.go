@@ -62,6 +62,16 @@ func Factory(conf *audit.BackendConfig) (audit.Backend, error) {
 		logRaw = b
 	}
 
+	// Check if the responses of list operations are logged without hashing
+	logListResponses := false
+	if raw, ok := conf.Config["log_list_responses"]; ok {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		logListResponses = b
+	}
+
 	// Get the logger
 	logger, err := gsyslog.NewLogger(gsyslog.LOG_INFO, facility, tag)
 	if err != nil {
@@ -73,11 +83,16 @@ func Factory(conf *audit.BackendConfig) (audit.Backend, error) {
 		saltConfig: conf.SaltConfig,
 		saltView:   conf.SaltView,
 		formatConfig: audit.FormatterConfig{
-			Raw:          logRaw,
-			HMACAccessor: hmacAccessor,
+			Raw:              logRaw,
+			HMACAccessor:     hmacAccessor,
+			RawListResponses: logListResponses,
 		},
 	}
 
+	if err := audit.ParseLoginEnrichment(conf.Config, &b.formatConfig); err != nil {
+		return nil, err
+	}
+
 	switch format {
 	case "json":
 		b.formatter.AuditFormatWriter = &audit.JSONFormatWriter{
@@ -47,8 +47,9 @@ func (b *backend) pathLoginUpdate(req *logical.Request, data *framework.FieldDat
 		InternalData: map[string]interface{}{
 			"role_name": roleName,
 		},
-		Metadata: metadata,
-		Policies: role.Policies,
+		Metadata:   metadata,
+		Policies:   role.Policies,
+		BoundCIDRs: role.TokenBoundCIDRs,
 		LeaseOptions: logical.LeaseOptions{
 			Renewable: true,
 		},
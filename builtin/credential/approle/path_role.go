@@ -11,6 +11,7 @@ import (
 	"github.com/hashicorp/vault/helper/locksutil"
 	"github.com/hashicorp/vault/helper/policyutil"
 	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/helper/wrapping"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -36,6 +37,10 @@ type roleStorageEntry struct {
 	// SecretID generated against the role will expire
 	SecretIDTTL time.Duration `json:"secret_id_ttl" structs:"secret_id_ttl" mapstructure:"secret_id_ttl"`
 
+	// Duration, if set, for which the SecretIDs generated against the role
+	// are response-wrapped, so that they are never delivered in plaintext
+	SecretIDWrapTTL time.Duration `json:"secret_id_wrap_ttl" structs:"secret_id_wrap_ttl" mapstructure:"secret_id_wrap_ttl"`
+
 	// TokenNumUses defines the number of allowed uses of the token issued
 	TokenNumUses int `json:"token_num_uses" mapstructure:"token_num_uses" structs:"token_num_uses"`
 
@@ -45,6 +50,10 @@ type roleStorageEntry struct {
 	// Duration after which an issued token should not be allowed to be renewed
 	TokenMaxTTL time.Duration `json:"token_max_ttl" structs:"token_max_ttl" mapstructure:"token_max_ttl"`
 
+	// A constraint, if set, specifies the CIDR blocks from which the issued
+	// tokens can be used
+	TokenBoundCIDRs []string `json:"token_bound_cidrs" structs:"token_bound_cidrs" mapstructure:"token_bound_cidrs"`
+
 	// A constraint, if set, requires 'secret_id' credential to be presented during login
 	BindSecretID bool `json:"bind_secret_id" structs:"bind_secret_id" mapstructure:"bind_secret_id"`
 
@@ -72,9 +81,11 @@ type roleIDStorageEntry struct {
 // role/<role_name>/policies - For updating the param
 // role/<role_name>/secret-id-num-uses - For updating the param
 // role/<role_name>/secret-id-ttl - For updating the param
+// role/<role_name>/secret-id-wrap-ttl - For updating the param
 // role/<role_name>/token-ttl - For updating the param
 // role/<role_name>/token-max-ttl - For updating the param
 // role/<role_name>/token-num-uses - For updating the param
+// role/<role_name>/token-bound-cidrs - For updating the param
 // role/<role_name>/bind-secret-id - For updating the param
 // role/<role_name>/bound-cidr-list - For updating the param
 // role/<role_name>/period - For updating the param
@@ -126,11 +137,22 @@ will expire. Defaults to 0 meaning that the the secret_id is of unlimited use.`,
 					Type: framework.TypeDurationSecond,
 					Description: `Duration in seconds after which the issued SecretID should expire. Defaults
 to 0, meaning no expiration.`,
+				},
+				"secret_id_wrap_ttl": &framework.FieldSchema{
+					Type: framework.TypeDurationSecond,
+					Description: `If set, the SecretIDs issued are always response-wrapped, with a TTL of at
+most this duration in seconds. Defaults to 0, meaning they are only wrapped when
+requested.`,
 				},
 				"token_num_uses": &framework.FieldSchema{
 					Type:        framework.TypeInt,
 					Description: `Number of times issued tokens can be used`,
 				},
+				"token_bound_cidrs": &framework.FieldSchema{
+					Type: framework.TypeCommaStringSlice,
+					Description: `Comma separated string or list of CIDR blocks, if set, specifies blocks of
+IP addresses which can use the issued tokens`,
+				},
 				"token_ttl": &framework.FieldSchema{
 					Type: framework.TypeDurationSecond,
 					Description: `Duration in seconds after which the issued token should expire. Defaults
@@ -268,6 +290,28 @@ to 0, meaning no expiration.`,
 			HelpSynopsis:    strings.TrimSpace(roleHelp["role-secret-id-ttl"][0]),
 			HelpDescription: strings.TrimSpace(roleHelp["role-secret-id-ttl"][1]),
 		},
+		&framework.Path{
+			Pattern: "role/" + framework.GenericNameRegex("role_name") + "/secret-id-wrap-ttl$",
+			Fields: map[string]*framework.FieldSchema{
+				"role_name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the role.",
+				},
+				"secret_id_wrap_ttl": &framework.FieldSchema{
+					Type: framework.TypeDurationSecond,
+					Description: `If set, the SecretIDs issued are always response-wrapped, with a TTL of at
+most this duration in seconds. Defaults to 0, meaning they are only wrapped when
+requested.`,
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.pathRoleSecretIDWrapTTLUpdate,
+				logical.ReadOperation:   b.pathRoleSecretIDWrapTTLRead,
+				logical.DeleteOperation: b.pathRoleSecretIDWrapTTLDelete,
+			},
+			HelpSynopsis:    strings.TrimSpace(roleHelp["role-secret-id-wrap-ttl"][0]),
+			HelpDescription: strings.TrimSpace(roleHelp["role-secret-id-wrap-ttl"][1]),
+		},
 		&framework.Path{
 			Pattern: "role/" + framework.GenericNameRegex("role_name") + "/period$",
 			Fields: map[string]*framework.FieldSchema{
@@ -312,6 +356,27 @@ TTL will be set to the value of this parameter.`,
 			HelpSynopsis:    strings.TrimSpace(roleHelp["role-token-num-uses"][0]),
 			HelpDescription: strings.TrimSpace(roleHelp["role-token-num-uses"][1]),
 		},
+		&framework.Path{
+			Pattern: "role/" + framework.GenericNameRegex("role_name") + "/token-bound-cidrs$",
+			Fields: map[string]*framework.FieldSchema{
+				"role_name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the role.",
+				},
+				"token_bound_cidrs": &framework.FieldSchema{
+					Type: framework.TypeCommaStringSlice,
+					Description: `Comma separated string or list of CIDR blocks, if set, specifies blocks of
+IP addresses which can use the issued tokens`,
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.pathRoleTokenBoundCIDRsUpdate,
+				logical.ReadOperation:   b.pathRoleTokenBoundCIDRsRead,
+				logical.DeleteOperation: b.pathRoleTokenBoundCIDRsDelete,
+			},
+			HelpSynopsis:    strings.TrimSpace(roleHelp["role-token-bound-cidrs"][0]),
+			HelpDescription: strings.TrimSpace(roleHelp["role-token-bound-cidrs"][1]),
+		},
 		&framework.Path{
 			Pattern: "role/" + framework.GenericNameRegex("role_name") + "/token-ttl$",
 			Fields: map[string]*framework.FieldSchema{
@@ -392,6 +457,12 @@ specific set of IP addresses. If 'bound_cidr_list' is set on the role, then the
 list of CIDR blocks listed here should be a subset of the CIDR blocks listed on
 the role.`,
 				},
+				"num_uses": &framework.FieldSchema{
+					Type: framework.TypeInt,
+					Description: `Number of times this SecretID can be used, after which it
+expires. Defaults to the 'secret_id_num_uses' of the role, and cannot exceed it
+if that is set.`,
+				},
 			},
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.UpdateOperation: b.pathRoleSecretIDUpdate,
@@ -497,6 +568,12 @@ specific set of IP addresses. If 'bound_cidr_list' is set on the role, then the
 list of CIDR blocks listed here should be a subset of the CIDR blocks listed on
 the role.`,
 				},
+				"num_uses": &framework.FieldSchema{
+					Type: framework.TypeInt,
+					Description: `Number of times this SecretID can be used, after which it
+expires. Defaults to the 'secret_id_num_uses' of the role, and cannot exceed it
+if that is set.`,
+				},
 			},
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.UpdateOperation: b.pathRoleCustomSecretIDUpdate,
@@ -798,6 +875,15 @@ func (b *backend) pathRoleCreateUpdate(req *logical.Request, data *framework.Fie
 		role.SecretIDTTL = time.Second * time.Duration(data.Get("secret_id_ttl").(int))
 	}
 
+	if secretIDWrapTTLRaw, ok := data.GetOk("secret_id_wrap_ttl"); ok {
+		role.SecretIDWrapTTL = time.Second * time.Duration(secretIDWrapTTLRaw.(int))
+	} else if req.Operation == logical.CreateOperation {
+		role.SecretIDWrapTTL = time.Second * time.Duration(data.Get("secret_id_wrap_ttl").(int))
+	}
+	if role.SecretIDWrapTTL < 0 {
+		return logical.ErrorResponse("secret_id_wrap_ttl cannot be negative"), nil
+	}
+
 	if tokenNumUsesRaw, ok := data.GetOk("token_num_uses"); ok {
 		role.TokenNumUses = tokenNumUsesRaw.(int)
 	} else if req.Operation == logical.CreateOperation {
@@ -807,6 +893,19 @@ func (b *backend) pathRoleCreateUpdate(req *logical.Request, data *framework.Fie
 		return logical.ErrorResponse("token_num_uses cannot be negative"), nil
 	}
 
+	if tokenBoundCIDRsRaw, ok := data.GetOk("token_bound_cidrs"); ok {
+		role.TokenBoundCIDRs = tokenBoundCIDRsRaw.([]string)
+	}
+	if len(role.TokenBoundCIDRs) > 0 {
+		valid, err := cidrutil.ValidateCIDRListSlice(role.TokenBoundCIDRs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to validate CIDR blocks: %v", err)
+		}
+		if !valid {
+			return logical.ErrorResponse("invalid CIDR blocks"), nil
+		}
+	}
+
 	if tokenTTLRaw, ok := data.GetOk("token_ttl"); ok {
 		role.TokenTTL = time.Second * time.Duration(tokenTTLRaw.(int))
 	} else if req.Operation == logical.CreateOperation {
@@ -850,6 +949,7 @@ func (b *backend) pathRoleRead(req *logical.Request, data *framework.FieldData)
 	} else {
 		// Convert the 'time.Duration' values to second.
 		role.SecretIDTTL /= time.Second
+		role.SecretIDWrapTTL /= time.Second
 		role.TokenTTL /= time.Second
 		role.TokenMaxTTL /= time.Second
 		role.Period /= time.Second
@@ -1555,6 +1655,80 @@ func (b *backend) pathRoleSecretIDTTLDelete(req *logical.Request, data *framewor
 	return nil, b.setRoleEntry(req.Storage, roleName, role, "")
 }
 
+func (b *backend) pathRoleSecretIDWrapTTLUpdate(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role_name").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role_name"), nil
+	}
+
+	role, err := b.roleEntry(req.Storage, strings.ToLower(roleName))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	lock := b.roleLock(roleName)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if secretIDWrapTTLRaw, ok := data.GetOk("secret_id_wrap_ttl"); ok {
+		role.SecretIDWrapTTL = time.Second * time.Duration(secretIDWrapTTLRaw.(int))
+		if role.SecretIDWrapTTL < 0 {
+			return logical.ErrorResponse("secret_id_wrap_ttl cannot be negative"), nil
+		}
+		return nil, b.setRoleEntry(req.Storage, roleName, role, "")
+	} else {
+		return logical.ErrorResponse("missing secret_id_wrap_ttl"), nil
+	}
+}
+
+func (b *backend) pathRoleSecretIDWrapTTLRead(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role_name").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role_name"), nil
+	}
+
+	if role, err := b.roleEntry(req.Storage, strings.ToLower(roleName)); err != nil {
+		return nil, err
+	} else if role == nil {
+		return nil, nil
+	} else {
+		role.SecretIDWrapTTL /= time.Second
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"secret_id_wrap_ttl": role.SecretIDWrapTTL,
+			},
+		}, nil
+	}
+}
+
+func (b *backend) pathRoleSecretIDWrapTTLDelete(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role_name").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role_name"), nil
+	}
+
+	role, err := b.roleEntry(req.Storage, strings.ToLower(roleName))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	lock := b.roleLock(roleName)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	role.SecretIDWrapTTL = time.Second * time.Duration(data.GetDefaultOrZero("secret_id_wrap_ttl").(int))
+
+	return nil, b.setRoleEntry(req.Storage, roleName, role, "")
+}
+
 func (b *backend) pathRolePeriodUpdate(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	roleName := data.Get("role_name").(string)
 	if roleName == "" {
@@ -1699,6 +1873,84 @@ func (b *backend) pathRoleTokenNumUsesDelete(req *logical.Request, data *framewo
 	return nil, b.setRoleEntry(req.Storage, roleName, role, "")
 }
 
+func (b *backend) pathRoleTokenBoundCIDRsUpdate(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role_name").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role_name"), nil
+	}
+
+	role, err := b.roleEntry(req.Storage, strings.ToLower(roleName))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	lock := b.roleLock(roleName)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	role.TokenBoundCIDRs = data.Get("token_bound_cidrs").([]string)
+	if len(role.TokenBoundCIDRs) == 0 {
+		return logical.ErrorResponse("missing token_bound_cidrs"), nil
+	}
+
+	valid, err := cidrutil.ValidateCIDRListSlice(role.TokenBoundCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate CIDR blocks: %v", err)
+	}
+	if !valid {
+		return logical.ErrorResponse("failed to validate CIDR blocks"), nil
+	}
+
+	return nil, b.setRoleEntry(req.Storage, roleName, role, "")
+}
+
+func (b *backend) pathRoleTokenBoundCIDRsRead(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role_name").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role_name"), nil
+	}
+
+	if role, err := b.roleEntry(req.Storage, strings.ToLower(roleName)); err != nil {
+		return nil, err
+	} else if role == nil {
+		return nil, nil
+	} else {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"token_bound_cidrs": role.TokenBoundCIDRs,
+			},
+		}, nil
+	}
+}
+
+func (b *backend) pathRoleTokenBoundCIDRsDelete(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role_name").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role_name"), nil
+	}
+
+	role, err := b.roleEntry(req.Storage, strings.ToLower(roleName))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	lock := b.roleLock(roleName)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	role.TokenBoundCIDRs = nil
+
+	return nil, b.setRoleEntry(req.Storage, roleName, role, "")
+}
+
 func (b *backend) pathRoleTokenTTLUpdate(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	roleName := data.Get("role_name").(string)
 	if roleName == "" {
@@ -1909,6 +2161,19 @@ func (b *backend) handleRoleSecretIDCommon(req *logical.Request, data *framework
 		CIDRList:        secretIDCIDRs,
 	}
 
+	// The number of uses of the SecretID can be lowered from the one of the
+	// role, but not raised
+	if numUsesRaw, ok := data.GetOk("num_uses"); ok {
+		numUses := numUsesRaw.(int)
+		switch {
+		case numUses < 0:
+			return logical.ErrorResponse("num_uses cannot be negative"), nil
+		case role.SecretIDNumUses > 0 && (numUses == 0 || numUses > role.SecretIDNumUses):
+			return logical.ErrorResponse(fmt.Sprintf("num_uses cannot exceed the secret_id_num_uses of the role (%d)", role.SecretIDNumUses)), nil
+		}
+		secretIDStorage.SecretIDNumUses = numUses
+	}
+
 	if err = strutil.ParseArbitraryKeyValues(data.Get("metadata").(string), secretIDStorage.Metadata, ","); err != nil {
 		return logical.ErrorResponse(fmt.Sprintf("failed to parse metadata: %v", err)), nil
 	}
@@ -1917,12 +2182,22 @@ func (b *backend) handleRoleSecretIDCommon(req *logical.Request, data *framework
 		return nil, fmt.Errorf("failed to store SecretID: %s", err)
 	}
 
-	return &logical.Response{
+	resp := &logical.Response{
 		Data: map[string]interface{}{
 			"secret_id":          secretID,
 			"secret_id_accessor": secretIDStorage.SecretIDAccessor,
 		},
-	}, nil
+	}
+
+	// Have the SecretID response-wrapped; the shortest of this TTL and the
+	// one requested by the client, if any, is used
+	if role.SecretIDWrapTTL > 0 {
+		resp.WrapInfo = &wrapping.ResponseWrapInfo{
+			TTL: role.SecretIDWrapTTL,
+		}
+	}
+
+	return resp, nil
 }
 
 func (b *backend) roleIDLock(roleID string) *locksutil.LockEntry {
@@ -2052,6 +2327,14 @@ that are generated against the role using 'role/<role_name>/secret-id' or
 'role/<role_name>/custom-secret-id' endpoints.`,
 		``,
 	},
+	"role-secret-id-wrap-ttl": {
+		`Duration in seconds for which the SecretIDs generated against the role are
+response-wrapped.`,
+		`If set, the responses of the 'role/<role_name>/secret-id' and
+'role/<role_name>/custom-secret-id' endpoints are always response-wrapped, so
+that the SecretIDs are only ever delivered through a single-use wrapping token.
+If the client requests a shorter wrapping TTL, it is used instead.`,
+	},
 	"role-secret-id-lookup": {
 		"Read the properties of an issued secret_id",
 		`This endpoint is used to read the properties of a secret_id associated to a
@@ -2080,6 +2363,12 @@ represents a non-expiring 'secret_id'.`,
 		"Number of times issued tokens can be used",
 		`By default, this will be set to zero, indicating that the issued
 tokens can be used any number of times.`,
+	},
+	"role-token-bound-cidrs": {
+		"Comma separated list of CIDR blocks from which the issued tokens can be used",
+		`If set, the tokens issued by logging in with the role can only be used
+from IP addresses within these blocks. Unlike 'bound_cidr_list', which only
+restricts the login, this is checked on every request made with the tokens.`,
 	},
 	"role-token-ttl": {
 		`Duration in seconds, the lifetime of the token issued by using the SecretID that
@@ -574,6 +574,125 @@ func TestAppRole_RoleSecretID(t *testing.T) {
 	}
 }
 
+func TestAppRole_RoleSecretIDWrapTTL(t *testing.T) {
+	var resp *logical.Response
+	var err error
+	b, storage := createBackendWithStorage(t)
+
+	roleReq := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/role1",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"policies":           "p,q,r,s",
+			"secret_id_wrap_ttl": 60,
+		},
+	}
+	resp, err = b.HandleRequest(roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	roleSecretIDReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/role1/secret-id",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(roleSecretIDReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.WrapInfo == nil || resp.WrapInfo.TTL != 60*time.Second {
+		t.Fatalf("secret_id not wrapped: %#v", resp.WrapInfo)
+	}
+
+	roleSecretIDReq.Path = "role/role1/custom-secret-id"
+	roleSecretIDReq.Data = map[string]interface{}{
+		"secret_id": "abcd123",
+	}
+	resp, err = b.HandleRequest(roleSecretIDReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.WrapInfo == nil || resp.WrapInfo.TTL != 60*time.Second {
+		t.Fatalf("secret_id not wrapped: %#v", resp.WrapInfo)
+	}
+
+	roleReq.Path = "role/role1/secret-id-wrap-ttl"
+	roleReq.Operation = logical.DeleteOperation
+	resp, err = b.HandleRequest(roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	roleReq.Operation = logical.ReadOperation
+	resp, err = b.HandleRequest(roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.Data["secret_id_wrap_ttl"].(time.Duration) != 0 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	roleSecretIDReq.Path = "role/role1/secret-id"
+	roleSecretIDReq.Data = nil
+	resp, err = b.HandleRequest(roleSecretIDReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.WrapInfo != nil {
+		t.Fatalf("bad: %#v", resp.WrapInfo)
+	}
+}
+
+func TestAppRole_RoleSecretIDNumUses(t *testing.T) {
+	var resp *logical.Response
+	var err error
+	b, storage := createBackendWithStorage(t)
+
+	createRole(t, b, storage, "role1", "a,b")
+	secretIDCreateReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Storage:   storage,
+		Path:      "role/role1/secret-id",
+		Data: map[string]interface{}{
+			"num_uses": 2,
+		},
+	}
+	resp, err = b.HandleRequest(secretIDCreateReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	secretIDReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Storage:   storage,
+		Path:      "role/role1/secret-id/lookup",
+		Data: map[string]interface{}{
+			"secret_id": resp.Data["secret_id"],
+		},
+	}
+	resp, err = b.HandleRequest(secretIDReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.Data["secret_id_num_uses"] != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The number of uses of the role cannot be exceeded
+	for _, numUses := range []int{-1, 0, 11} {
+		secretIDCreateReq.Data["num_uses"] = numUses
+		resp, err = b.HandleRequest(secretIDCreateReq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("expected error for %d: %#v", numUses, resp)
+		}
+	}
+}
+
 func TestAppRole_RoleCRUD(t *testing.T) {
 	var resp *logical.Response
 	var err error
@@ -587,6 +706,7 @@ func TestAppRole_RoleCRUD(t *testing.T) {
 		"token_max_ttl":      500,
 		"token_num_uses":     600,
 		"bound_cidr_list":    "127.0.0.1/32,127.0.0.1/16",
+		"token_bound_cidrs":  "10.0.0.0/8",
 	}
 	roleReq := &logical.Request{
 		Operation: logical.CreateOperation,
@@ -615,6 +735,7 @@ func TestAppRole_RoleCRUD(t *testing.T) {
 		"token_max_ttl":      500,
 		"token_num_uses":     600,
 		"bound_cidr_list":    "127.0.0.1/32,127.0.0.1/16",
+		"token_bound_cidrs":  []string{"10.0.0.0/8"},
 	}
 	var expectedStruct roleStorageEntry
 	err = mapstructure.Decode(expected, &expectedStruct)
@@ -909,6 +1030,53 @@ func TestAppRole_RoleCRUD(t *testing.T) {
 		t.Fatalf("expected value to be reset")
 	}
 
+	// RUD for 'token-bound-cidrs' field
+	roleReq.Path = "role/role1/token-bound-cidrs"
+	roleReq.Operation = logical.ReadOperation
+	resp, err = b.HandleRequest(roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if !reflect.DeepEqual(resp.Data["token_bound_cidrs"], []string{"10.0.0.0/8"}) {
+		t.Fatalf("bad: token_bound_cidrs: %#v", resp.Data["token_bound_cidrs"])
+	}
+
+	roleReq.Data = map[string]interface{}{"token_bound_cidrs": "invalid"}
+	roleReq.Operation = logical.UpdateOperation
+	if _, err = b.HandleRequest(roleReq); err == nil {
+		t.Fatalf("expected an error for invalid CIDR blocks")
+	}
+
+	roleReq.Data = map[string]interface{}{"token_bound_cidrs": "127.0.0.1/32,192.168.0.0/16"}
+	resp, err = b.HandleRequest(roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	roleReq.Operation = logical.ReadOperation
+	resp, err = b.HandleRequest(roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if !reflect.DeepEqual(resp.Data["token_bound_cidrs"], []string{"127.0.0.1/32", "192.168.0.0/16"}) {
+		t.Fatalf("bad: token_bound_cidrs: %#v", resp.Data["token_bound_cidrs"])
+	}
+
+	roleReq.Operation = logical.DeleteOperation
+	resp, err = b.HandleRequest(roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	roleReq.Operation = logical.ReadOperation
+	resp, err = b.HandleRequest(roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if len(resp.Data["token_bound_cidrs"].([]string)) != 0 {
+		t.Fatalf("expected value to be reset")
+	}
+
 	// RUD for 'period' field
 	roleReq.Path = "role/role1/period"
 	roleReq.Operation = logical.ReadOperation
@@ -943,6 +943,10 @@ func (b *backend) pathLoginRenewIam(
 		}
 	}
 
+	if roleEntry.BoundAccountID != "" && req.Auth.Metadata["account_id"] != roleEntry.BoundAccountID {
+		return nil, fmt.Errorf("role no longer bound to account %q", req.Auth.Metadata["account_id"])
+	}
+
 	return framework.LeaseExtend(roleEntry.TTL, roleEntry.MaxTTL, b.System())(req, data)
 }
 
@@ -1155,6 +1159,9 @@ func (b *backend) pathLoginUpdateIam(
 	} else if roleEntry.BoundIamPrincipalARN != "" && roleEntry.BoundIamPrincipalARN != entity.canonicalArn() {
 		return logical.ErrorResponse(fmt.Sprintf("IAM Principal %q does not belong to the role %q", callerID.Arn, roleName)), nil
 	}
+	if roleEntry.BoundAccountID != "" && callerID.Account != roleEntry.BoundAccountID {
+		return logical.ErrorResponse(fmt.Sprintf("IAM Principal %q does not belong to the account bound to role %q", callerID.Arn, roleName)), nil
+	}
 
 	policies := roleEntry.Policies
 
@@ -33,7 +33,8 @@ using the AMI ID specified by this parameter.`,
 			"bound_account_id": {
 				Type: framework.TypeString,
 				Description: `If set, defines a constraint on the EC2 instances that the account ID
-in its identity document to match the one specified by this parameter.`,
+in its identity document to match the one specified by this parameter. When
+auth_type is iam, the IAM principal must belong to this account.`,
 			},
 			"bound_iam_principal_arn": {
 				Type: framework.TypeString,
@@ -563,8 +564,8 @@ func (b *backend) pathRoleCreateUpdate(
 	numBinds := 0
 
 	if roleEntry.BoundAccountID != "" {
-		if !allowEc2Binds {
-			return logical.ErrorResponse(fmt.Sprintf("specified bound_account_id but not allowing ec2 auth_type or inferring %s", ec2EntityType)), nil
+		if !allowEc2Binds && roleEntry.AuthType != iamAuthType {
+			return logical.ErrorResponse(fmt.Sprintf("specified bound_account_id but not allowing ec2 or iam auth_type or inferring %s", ec2EntityType)), nil
 		}
 		numBinds++
 	}
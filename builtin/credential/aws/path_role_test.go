@@ -374,6 +374,23 @@ func TestBackend_pathIam(t *testing.T) {
 	if resp != nil {
 		t.Fatalf("bad: response: expected: nil actual:%3v\n", resp)
 	}
+
+	// IAM principals can be bound by account alone
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/MyAccountRoleName",
+		Data: map[string]interface{}{
+			"auth_type":        iamAuthType,
+			"bound_account_id": "123456789012",
+		},
+		Storage: storage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != nil && resp.IsError() {
+		t.Fatalf("failed to create role bound to account: %#v", resp)
+	}
 }
 
 func TestBackend_pathRoleMixedTypes(t *testing.T) {
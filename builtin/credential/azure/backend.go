@@ -0,0 +1,73 @@
+package azure
+
+import (
+	"crypto/rsa"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func Factory(conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func Backend() *backend {
+	b := &backend{
+		fetchKeys: fetchSigningKeys,
+	}
+
+	b.Backend = &framework.Backend{
+		Help: backendHelp,
+
+		PathsSpecial: &logical.Paths{
+			Unauthenticated: []string{
+				"login",
+			},
+		},
+
+		Paths: []*framework.Path{
+			pathConfig(b),
+			pathRole(b),
+			pathListRoles(b),
+			pathLogin(b),
+		},
+
+		AuthRenew:   b.pathLoginRenew,
+		BackendType: logical.TypeCredential,
+	}
+
+	return b
+}
+
+type backend struct {
+	*framework.Backend
+
+	// fetchKeys returns the keys Azure AD signs tokens of the tenant with,
+	// by key ID
+	fetchKeys func(tenantID string) (map[string]*rsa.PublicKey, error)
+
+	keysLock      sync.RWMutex
+	keys          map[string]*rsa.PublicKey
+	keysTenantID  string
+	keysFetchedAt time.Time
+}
+
+const backendHelp = `
+The Azure credential provider allows authentication of Azure resources using
+their managed identities.
+
+A virtual machine, or any other resource with a managed identity, obtains a
+token for its identity from the Azure Instance Metadata Service and logs in
+with it. The token is verified against the signing keys of the configured
+Azure AD tenant, and the role used to log in constrains the subscriptions,
+resource groups, virtual machines and service principals that may use it.
+
+After enabling the credential provider, use the "config" route to configure
+it, and the "role" route to create roles.
+`
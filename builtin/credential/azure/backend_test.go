@@ -0,0 +1,216 @@
+package azure
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SermoDigital/jose/crypto"
+	"github.com/SermoDigital/jose/jws"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	testTenantID       = "tenant"
+	testResource       = "https://management.azure.com/"
+	testVMResourceID   = "/subscriptions/SUB-1/resourceGroups/App/providers/Microsoft.Compute/virtualMachines/web-1"
+	testUserIdentityID = "/subscriptions/sub-1/resourceGroups/app/providers/Microsoft.ManagedIdentity/userAssignedIdentities/web"
+)
+
+func testBackend(t *testing.T) (*backend, logical.Storage, *rsa.PrivateKey, *int) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetches := new(int)
+	b.(*backend).fetchKeys = func(tenantID string) (map[string]*rsa.PublicKey, error) {
+		if tenantID != testTenantID {
+			t.Fatalf("bad: tenant: %s", tenantID)
+		}
+		*fetches++
+		return map[string]*rsa.PublicKey{"key-1": &key.PublicKey}, nil
+	}
+	return b.(*backend), config.StorageView, key, fetches
+}
+
+func testToken(t *testing.T, key *rsa.PrivateKey, keyID string, claims jws.Claims) string {
+	token := jws.NewJWT(claims, crypto.SigningMethodRS256)
+	token.(jws.JWS).Protected().Set("kid", keyID)
+	serialized, err := token.Serialize(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(serialized)
+}
+
+func testClaims(resourceID string) jws.Claims {
+	claims := jws.Claims{}
+	claims.SetIssuer("https://sts.windows.net/" + testTenantID + "/")
+	claims.SetAudience(testResource)
+	claims.SetExpiration(time.Now().Add(time.Hour))
+	claims.Set("oid", "SP-1")
+	claims.Set("xms_mirid", resourceID)
+	return claims
+}
+
+func testRequest(t *testing.T, b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   s,
+		Data:      data,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: path: %s, resp: %#v, err: %v", path, resp, err)
+	}
+	return resp
+}
+
+func testLogin(b *backend, s logical.Storage, role, token string) (*logical.Response, error) {
+	return b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "login",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"role": role,
+			"jwt":  token,
+		},
+	})
+}
+
+func testLoginError(t *testing.T, b *backend, s logical.Storage, role, token, reason string) {
+	resp, err := testLogin(b, s, role, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), reason) {
+		t.Fatalf("expected error containing %q: %#v", reason, resp)
+	}
+}
+
+func TestBackend_role(t *testing.T) {
+	b, s, _, _ := testBackend(t)
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/unbound",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"policies": "dev",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error for role without constraints: resp: %#v, err: %v", resp, err)
+	}
+
+	testRequest(t, b, s, logical.CreateOperation, "role/web", map[string]interface{}{
+		"policies":               "dev,prod",
+		"bound_subscription_ids": "SUB-1",
+		"ttl":                    "30m",
+	})
+	resp = testRequest(t, b, s, logical.ReadOperation, "role/web", nil)
+	if resp.Data["ttl"] != int64(1800) || strings.Join(resp.Data["bound_subscription_ids"].([]string), ",") != "sub-1" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if strings.Join(resp.Data["policies"].([]string), ",") != "default,dev,prod" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp = testRequest(t, b, s, logical.ListOperation, "role/", nil)
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 || keys[0] != "web" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestBackend_login(t *testing.T) {
+	b, s, key, fetches := testBackend(t)
+
+	testLoginError(t, b, s, "web", "token", "not configured")
+
+	testRequest(t, b, s, logical.UpdateOperation, "config", map[string]interface{}{
+		"tenant_id": testTenantID,
+		"resource":  testResource,
+	})
+	testRequest(t, b, s, logical.CreateOperation, "role/web", map[string]interface{}{
+		"policies":                    "dev",
+		"bound_service_principal_ids": "sp-1",
+		"bound_subscription_ids":      "sub-1",
+		"bound_resource_groups":       "app",
+		"bound_vm_names":              "web-1",
+		"ttl":                         "30m",
+	})
+
+	resp, err := testLogin(b, s, "web", testToken(t, key, "key-1", testClaims(testVMResourceID)))
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if resp.Auth.TTL != 30*time.Minute || resp.Auth.Metadata["vm_name"] != "web-1" || resp.Auth.Metadata["service_principal_id"] != "sp-1" {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+
+	// Renewal checks the role again
+	renewReq := &logical.Request{
+		Operation: logical.RenewOperation,
+		Path:      "login",
+		Storage:   s,
+		Auth:      resp.Auth,
+	}
+	renewReq.Auth.IssueTime = time.Now()
+	if resp, err := b.HandleRequest(renewReq); err != nil || resp == nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	testRequest(t, b, s, logical.UpdateOperation, "role/web", map[string]interface{}{
+		"bound_vm_names": "web-2",
+	})
+	if _, err := b.HandleRequest(renewReq); err == nil {
+		t.Fatalf("expected renewal to fail")
+	}
+
+	// User-assigned identities are not virtual machines
+	testLoginError(t, b, s, "web", testToken(t, key, "key-1", testClaims(testUserIdentityID)), "system-assigned")
+
+	testRequest(t, b, s, logical.UpdateOperation, "role/web", map[string]interface{}{
+		"bound_vm_names": "",
+	})
+	if resp, err := testLogin(b, s, "web", testToken(t, key, "key-1", testClaims(testUserIdentityID))); err != nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	claims := testClaims(testVMResourceID)
+	claims.SetAudience("https://vault.example.com/")
+	testLoginError(t, b, s, "web", testToken(t, key, "key-1", claims), "invalid token")
+
+	claims = testClaims(testVMResourceID)
+	claims.SetExpiration(time.Now().Add(-time.Hour))
+	testLoginError(t, b, s, "web", testToken(t, key, "key-1", claims), "invalid token")
+
+	claims = testClaims("/subscriptions/sub-2/resourceGroups/app/providers/Microsoft.Compute/virtualMachines/web-1")
+	testLoginError(t, b, s, "web", testToken(t, key, "key-1", claims), "subscription")
+
+	claims = testClaims(testVMResourceID)
+	claims.Del("xms_mirid")
+	testLoginError(t, b, s, "web", testToken(t, key, "key-1", claims), "managed identity")
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testLoginError(t, b, s, "web", testToken(t, otherKey, "key-1", testClaims(testVMResourceID)), "invalid token")
+
+	// Keys are fetched once, and unknown keys do not cause them to be
+	// fetched again right away
+	testLoginError(t, b, s, "web", testToken(t, key, "key-2", testClaims(testVMResourceID)), "unknown signing key")
+	if *fetches != 1 {
+		t.Fatalf("bad: fetched keys %d times", *fetches)
+	}
+}
@@ -0,0 +1,124 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/api"
+)
+
+// metadataTokenURL is the endpoint of the Azure Instance Metadata Service
+// that issues tokens for the managed identities of the resource
+const metadataTokenURL = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+type CLIHandler struct{}
+
+func (h *CLIHandler) Auth(c *api.Client, m map[string]string) (string, error) {
+	mount, ok := m["mount"]
+	if !ok {
+		mount = "azure"
+	}
+
+	role, ok := m["role"]
+	if !ok {
+		return "", fmt.Errorf("'role' must be specified")
+	}
+
+	token, ok := m["jwt"]
+	if !ok {
+		resource, ok := m["resource"]
+		if !ok {
+			return "", fmt.Errorf("either 'jwt' or 'resource' must be specified")
+		}
+
+		var err error
+		if token, err = fetchMetadataToken(resource, m["client_id"]); err != nil {
+			return "", err
+		}
+	}
+
+	path := fmt.Sprintf("auth/%s/login", mount)
+	secret, err := c.Logical().Write(path, map[string]interface{}{
+		"role": role,
+		"jwt":  token,
+	})
+	if err != nil {
+		return "", err
+	}
+	if secret == nil {
+		return "", fmt.Errorf("empty response from credential provider")
+	}
+
+	return secret.Auth.ClientToken, nil
+}
+
+// fetchMetadataToken obtains a token for the managed identity of the resource
+// from the Azure Instance Metadata Service. The client ID selects one of the
+// user-assigned identities; otherwise the system-assigned identity is used.
+func fetchMetadataToken(resource, clientID string) (string, error) {
+	params := url.Values{
+		"api-version": {"2018-02-01"},
+		"resource":    {resource},
+	}
+	if clientID != "" {
+		params.Set("client_id", clientID)
+	}
+
+	req, err := http.NewRequest("GET", metadataTokenURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata", "true")
+
+	resp, err := cleanhttp.DefaultClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach the instance metadata service: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		AccessToken      string `json:"access_token"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("failed to decode the instance metadata service response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to obtain a token from the instance metadata service: %s", out.ErrorDescription)
+	}
+	return out.AccessToken, nil
+}
+
+func (h *CLIHandler) Help() string {
+	help := `
+The Azure credential provider allows Azure resources to authenticate with the
+token of their managed identity. By default the token is obtained from the
+Azure Instance Metadata Service for the given resource, which must be the
+resource configured in the credential provider.
+
+    Example: vault auth -method=azure role=<role> resource=https://management.azure.com/
+
+Key/Value Pairs:
+
+    mount=azure            The mountpoint for the Azure credential provider.
+                           Defaults to "azure"
+
+    role=<string>          The role to log in with.
+
+    resource=<string>      The resource to obtain a token for from the
+                           instance metadata service.
+
+    client_id=<string>     The client ID of the user-assigned identity to
+                           obtain a token for. Defaults to the system-assigned
+                           identity.
+
+    jwt=<string>           A token to log in with instead of obtaining one
+                           from the instance metadata service.
+	`
+
+	return strings.TrimSpace(help)
+}
@@ -0,0 +1,139 @@
+package azure
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+const (
+	// keysURLFormat is the location of the signing keys of a tenant
+	keysURLFormat = "https://login.microsoftonline.com/%s/discovery/keys"
+
+	// keysCacheTTL is how long fetched signing keys are used before they are
+	// fetched again. Keys are refetched sooner when a token references an
+	// unknown key, since Azure AD rotates its keys regularly.
+	keysCacheTTL = 24 * time.Hour
+
+	// keysMinRefreshInterval bounds how often unknown key IDs cause the
+	// keys to be fetched again
+	keysMinRefreshInterval = 5 * time.Minute
+)
+
+// signingKey returns the public key with the given ID that Azure AD signs
+// tokens of the tenant with
+func (b *backend) signingKey(tenantID, keyID string) (*rsa.PublicKey, error) {
+	b.keysLock.RLock()
+	key, ok := b.keys[keyID]
+	valid := b.keysTenantID == tenantID && time.Since(b.keysFetchedAt) < keysCacheTTL
+	b.keysLock.RUnlock()
+	if ok && valid {
+		return key, nil
+	}
+
+	b.keysLock.Lock()
+	defer b.keysLock.Unlock()
+
+	// Another login may have refreshed the keys meanwhile, and keys fetched
+	// moments ago are not fetched again for unknown key IDs
+	if b.keysTenantID == tenantID {
+		age := time.Since(b.keysFetchedAt)
+		if key, ok := b.keys[keyID]; ok && age < keysCacheTTL {
+			return key, nil
+		}
+		if age < keysMinRefreshInterval {
+			return nil, fmt.Errorf("unknown signing key %q", keyID)
+		}
+	}
+
+	keys, err := b.fetchKeys(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %v", err)
+	}
+	b.keys = keys
+	b.keysTenantID = tenantID
+	b.keysFetchedAt = time.Now()
+
+	key, ok = b.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", keyID)
+	}
+	return key, nil
+}
+
+// resetKeys discards the cached signing keys
+func (b *backend) resetKeys() {
+	b.keysLock.Lock()
+	defer b.keysLock.Unlock()
+
+	b.keys = nil
+	b.keysTenantID = ""
+	b.keysFetchedAt = time.Time{}
+}
+
+// jsonWebKey is an RSA key of a JSON web key set
+type jsonWebKey struct {
+	KeyID   string `json:"kid"`
+	KeyType string `json:"kty"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+// fetchSigningKeys fetches the JSON web key set of the tenant from Azure AD
+func fetchSigningKeys(tenantID string) (map[string]*rsa.PublicKey, error) {
+	resp, err := cleanhttp.DefaultClient().Get(fmt.Sprintf(keysURLFormat, tenantID))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var keySet struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&keySet); err != nil {
+		return nil, fmt.Errorf("failed to decode key set: %v", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(keySet.Keys))
+	for _, jwk := range keySet.Keys {
+		if jwk.KeyType != "RSA" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %v", jwk.KeyID, err)
+		}
+		keys[jwk.KeyID] = key
+	}
+	return keys, nil
+}
+
+func (k *jsonWebKey) publicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > int64(^uint32(0)>>1) {
+		return nil, fmt.Errorf("invalid exponent")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
@@ -0,0 +1,113 @@
+package azure
+
+import (
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config",
+		Fields: map[string]*framework.FieldSchema{
+			"tenant_id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "ID of the Azure AD tenant that issues the tokens of the managed identities.",
+			},
+			"resource": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Resource the tokens must be issued for, which is their audience.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+type configEntry struct {
+	TenantID string `json:"tenant_id"`
+	Resource string `json:"resource"`
+}
+
+// Config returns the configuration of the backend, or nil if it has not been
+// written
+func (b *backend) Config(s logical.Storage) (*configEntry, error) {
+	entry, err := s.Get("config")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var config configEntry
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (b *backend) pathConfigRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"tenant_id": config.TenantID,
+			"resource":  config.Resource,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &configEntry{}
+	}
+
+	if v, ok := d.GetOk("tenant_id"); ok {
+		config.TenantID = v.(string)
+	}
+	if v, ok := d.GetOk("resource"); ok {
+		config.Resource = v.(string)
+	}
+	if config.TenantID == "" || config.Resource == "" {
+		return logical.ErrorResponse("tenant_id and resource are required"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("config", config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	b.resetKeys()
+	return nil, nil
+}
+
+const pathConfigHelpSyn = `
+Configure the Azure AD tenant that issues the tokens of managed identities.
+`
+
+const pathConfigHelpDesc = `
+Managed identities log in with tokens issued by the Azure AD tenant of their
+subscription. Tokens are only accepted if they are signed by the tenant and
+issued for the configured resource, such as "https://management.azure.com/".
+`
@@ -0,0 +1,286 @@
+package azure
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SermoDigital/jose/crypto"
+	"github.com/SermoDigital/jose/jws"
+	"github.com/SermoDigital/jose/jwt"
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// clockSkewLeeway is the leeway allowed when checking the expiration and
+// not-before times of tokens
+const clockSkewLeeway = 2 * time.Minute
+
+func pathLogin(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "login$",
+		Fields: map[string]*framework.FieldSchema{
+			"role": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role to log in with.",
+			},
+			"jwt": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Token of the managed identity, as issued by the Azure Instance Metadata Service for the configured resource.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathLogin,
+		},
+
+		HelpSynopsis:    pathLoginHelpSyn,
+		HelpDescription: pathLoginHelpDesc,
+	}
+}
+
+// identity describes the managed identity a token was issued to
+type identity struct {
+	// ServicePrincipalID is the object ID of the identity's service principal
+	ServicePrincipalID string
+
+	// ResourceID is the resource the identity belongs to; it is the
+	// virtual machine itself for system-assigned identities of virtual
+	// machines
+	ResourceID string
+
+	SubscriptionID    string
+	ResourceGroupName string
+
+	// VMName is only set for system-assigned identities of virtual machines
+	VMName string
+}
+
+// parseResourceID fills in the subscription, resource group and virtual
+// machine of the identity from its resource ID, which has the form
+// /subscriptions/<id>/resourceGroups/<name>/providers/<namespace>/<type>/<name>
+func (id *identity) parseResourceID(resourceID string) error {
+	parts := strings.Split(strings.Trim(resourceID, "/"), "/")
+	if len(parts) < 4 || !strings.EqualFold(parts[0], "subscriptions") || !strings.EqualFold(parts[2], "resourceGroups") {
+		return fmt.Errorf("invalid resource ID %q", resourceID)
+	}
+
+	id.ResourceID = resourceID
+	id.SubscriptionID = strings.ToLower(parts[1])
+	id.ResourceGroupName = strings.ToLower(parts[3])
+	if len(parts) == 8 && strings.EqualFold(parts[4], "providers") &&
+		strings.EqualFold(parts[5], "Microsoft.Compute") && strings.EqualFold(parts[6], "virtualMachines") {
+		id.VMName = strings.ToLower(parts[7])
+	}
+	return nil
+}
+
+// metadata returns the token metadata describing the identity
+func (id *identity) metadata() map[string]string {
+	return map[string]string{
+		"service_principal_id": id.ServicePrincipalID,
+		"resource_id":          id.ResourceID,
+		"subscription_id":      id.SubscriptionID,
+		"resource_group_name":  id.ResourceGroupName,
+		"vm_name":              id.VMName,
+	}
+}
+
+// identityFromMetadata returns the identity described by token metadata
+func identityFromMetadata(m map[string]string) *identity {
+	return &identity{
+		ServicePrincipalID: m["service_principal_id"],
+		ResourceID:         m["resource_id"],
+		SubscriptionID:     m["subscription_id"],
+		ResourceGroupName:  m["resource_group_name"],
+		VMName:             m["vm_name"],
+	}
+}
+
+// verifyToken verifies the signature, issuer, audience and validity period
+// of the token and returns the identity it was issued to
+func (b *backend) verifyToken(config *configEntry, token string) (*identity, error) {
+	parsed, err := jws.ParseJWT([]byte(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token: %v", err)
+	}
+
+	keyID, _ := parsed.(jws.JWS).Protected().Get("kid").(string)
+	if keyID == "" {
+		return nil, fmt.Errorf("token does not reference a signing key")
+	}
+	key, err := b.signingKey(config.TenantID, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := jwt.Claims{}
+	expected.SetIssuer(fmt.Sprintf("https://sts.windows.net/%s/", config.TenantID))
+	expected.SetAudience(config.Resource)
+	validator := &jwt.Validator{
+		Expected: expected,
+		EXP:      clockSkewLeeway,
+		NBF:      clockSkewLeeway,
+	}
+	if err := parsed.Validate(key, crypto.SigningMethodRS256, validator); err != nil {
+		return nil, fmt.Errorf("invalid token: %v", err)
+	}
+
+	claims := parsed.Claims()
+	if _, ok := claims.Expiration(); !ok {
+		return nil, fmt.Errorf("token has no expiration time")
+	}
+
+	id := &identity{}
+	id.ServicePrincipalID, _ = claims.Get("oid").(string)
+	id.ServicePrincipalID = strings.ToLower(id.ServicePrincipalID)
+	if id.ServicePrincipalID == "" {
+		return nil, fmt.Errorf("token has no object ID")
+	}
+
+	// Tokens of managed identities reference the resource of the identity
+	resourceID, _ := claims.Get("xms_mirid").(string)
+	if resourceID == "" {
+		return nil, fmt.Errorf("token was not issued to a managed identity")
+	}
+	if err := id.parseResourceID(resourceID); err != nil {
+		return nil, err
+	}
+
+	return id, nil
+}
+
+// checkBindings returns an error if the identity does not meet the bound
+// constraints of the role
+func (r *roleEntry) checkBindings(id *identity) error {
+	if len(r.BoundServicePrincipalIDs) > 0 && !strutil.StrListContains(r.BoundServicePrincipalIDs, id.ServicePrincipalID) {
+		return fmt.Errorf("service principal %q is not allowed by the role", id.ServicePrincipalID)
+	}
+	if len(r.BoundSubscriptionIDs) > 0 && !strutil.StrListContains(r.BoundSubscriptionIDs, id.SubscriptionID) {
+		return fmt.Errorf("subscription %q is not allowed by the role", id.SubscriptionID)
+	}
+	if len(r.BoundResourceGroups) > 0 && !strutil.StrListContains(r.BoundResourceGroups, id.ResourceGroupName) {
+		return fmt.Errorf("resource group %q is not allowed by the role", id.ResourceGroupName)
+	}
+	if len(r.BoundVMNames) > 0 {
+		if id.VMName == "" {
+			return fmt.Errorf("role only allows system-assigned identities of virtual machines")
+		}
+		if !strutil.StrListContains(r.BoundVMNames, id.VMName) {
+			return fmt.Errorf("virtual machine %q is not allowed by the role", id.VMName)
+		}
+	}
+	return nil
+}
+
+func (b *backend) pathLogin(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName := d.Get("role").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role"), nil
+	}
+	token := d.Get("jwt").(string)
+	if token == "" {
+		return logical.ErrorResponse("missing jwt"), nil
+	}
+
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return logical.ErrorResponse("azure credential backend not configured"), nil
+	}
+
+	role, err := b.Role(req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid role %q", roleName)), nil
+	}
+
+	id, err := b.verifyToken(config, token)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if err := role.checkBindings(id); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	metadata := id.metadata()
+	metadata["role"] = roleName
+
+	auth := &logical.Auth{
+		Period: role.Period,
+		InternalData: map[string]interface{}{
+			"role": roleName,
+		},
+		Metadata:    metadata,
+		Policies:    role.Policies,
+		DisplayName: id.ServicePrincipalID,
+		LeaseOptions: logical.LeaseOptions{
+			Renewable: true,
+		},
+	}
+
+	// If 'Period' is set, use the value of 'Period' as the TTL.
+	// Otherwise, set the normal TTL.
+	if role.Period > time.Duration(0) {
+		auth.TTL = role.Period
+	} else {
+		auth.TTL = role.TTL
+	}
+
+	return &logical.Response{
+		Auth: auth,
+	}, nil
+}
+
+func (b *backend) pathLoginRenew(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if req.Auth == nil {
+		return nil, fmt.Errorf("request auth was nil")
+	}
+
+	roleName, _ := req.Auth.InternalData["role"].(string)
+	if roleName == "" {
+		return nil, fmt.Errorf("failed to fetch role during renewal")
+	}
+
+	// Ensure that the role still exists and still allows the identity
+	role, err := b.Role(req.Storage, roleName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate role %q during renewal: %v", roleName, err)
+	}
+	if role == nil {
+		return nil, fmt.Errorf("role %q does not exist during renewal", roleName)
+	}
+	if err := role.checkBindings(identityFromMetadata(req.Auth.Metadata)); err != nil {
+		return nil, err
+	}
+	if !policyutil.EquivalentPolicies(role.Policies, req.Auth.Policies) {
+		return nil, fmt.Errorf("policies have changed, not renewing")
+	}
+
+	// If 'Period' is set on the role, the token should never expire.
+	// Replenish the TTL with 'Period's value.
+	if role.Period > time.Duration(0) {
+		req.Auth.TTL = role.Period
+		return &logical.Response{Auth: req.Auth}, nil
+	}
+	return framework.LeaseExtend(role.TTL, role.MaxTTL, b.System())(req, d)
+}
+
+const pathLoginHelpSyn = `
+Authenticates a managed identity with Vault.
+`
+
+const pathLoginHelpDesc = `
+A managed identity logs in with a token issued to it by the Azure Instance
+Metadata Service for the configured resource. The token must be signed by the
+configured Azure AD tenant, and the identity must meet the constraints of the
+role.
+`
@@ -0,0 +1,224 @@
+package azure
+
+import (
+	"time"
+
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathListRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/?$",
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+func pathRole(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+			"policies": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Comma-separated list of policies of the tokens issued for the role.",
+			},
+			"ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Default TTL of the tokens issued for the role. Defaults to the mount's default TTL.",
+			},
+			"max_ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Maximum TTL of the tokens issued for the role. Defaults to the mount's maximum TTL.",
+			},
+			"period": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "If set, tokens issued for the role are periodic and renewed for this long each time.",
+			},
+			"bound_service_principal_ids": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Comma-separated list of object IDs of the service principals of managed identities allowed to log in.",
+			},
+			"bound_subscription_ids": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Comma-separated list of IDs of the subscriptions whose managed identities are allowed to log in.",
+			},
+			"bound_resource_groups": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Comma-separated list of names of the resource groups whose managed identities are allowed to log in.",
+			},
+			"bound_vm_names": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Comma-separated list of names of the virtual machines whose system-assigned identities are allowed to log in.",
+			},
+		},
+		ExistenceCheck: b.pathRoleExistenceCheck,
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.CreateOperation: b.pathRoleWrite,
+			logical.UpdateOperation: b.pathRoleWrite,
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.DeleteOperation: b.pathRoleDelete,
+		},
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+type roleEntry struct {
+	Policies []string      `json:"policies"`
+	TTL      time.Duration `json:"ttl"`
+	MaxTTL   time.Duration `json:"max_ttl"`
+	Period   time.Duration `json:"period"`
+
+	BoundServicePrincipalIDs []string `json:"bound_service_principal_ids"`
+	BoundSubscriptionIDs     []string `json:"bound_subscription_ids"`
+	BoundResourceGroups      []string `json:"bound_resource_groups"`
+	BoundVMNames             []string `json:"bound_vm_names"`
+}
+
+// Role reads the role from the storage, returning nil if it does not exist
+func (b *backend) Role(s logical.Storage, name string) (*roleEntry, error) {
+	entry, err := s.Get("role/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var role roleEntry
+	if err := entry.DecodeJSON(&role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (b *backend) pathRoleExistenceCheck(req *logical.Request, d *framework.FieldData) (bool, error) {
+	role, err := b.Role(req.Storage, d.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return role != nil, nil
+}
+
+func (b *backend) pathRoleList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roles, err := req.Storage.List("role/")
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(roles), nil
+}
+
+func (b *backend) pathRoleRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, err := b.Role(req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"policies":                    role.Policies,
+			"ttl":                         int64(role.TTL.Seconds()),
+			"max_ttl":                     int64(role.MaxTTL.Seconds()),
+			"period":                      int64(role.Period.Seconds()),
+			"bound_service_principal_ids": role.BoundServicePrincipalIDs,
+			"bound_subscription_ids":      role.BoundSubscriptionIDs,
+			"bound_resource_groups":       role.BoundResourceGroups,
+			"bound_vm_names":              role.BoundVMNames,
+		},
+	}, nil
+}
+
+func (b *backend) pathRoleDelete(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return nil, req.Storage.Delete("role/" + d.Get("name").(string))
+}
+
+func (b *backend) pathRoleWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	role, err := b.Role(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		role = &roleEntry{}
+	}
+
+	if policiesRaw, ok := d.GetOk("policies"); ok {
+		role.Policies = policyutil.SanitizePolicies(policiesRaw.([]string), true)
+	}
+	if ttlRaw, ok := d.GetOk("ttl"); ok {
+		role.TTL = time.Duration(ttlRaw.(int)) * time.Second
+	}
+	if maxTTLRaw, ok := d.GetOk("max_ttl"); ok {
+		role.MaxTTL = time.Duration(maxTTLRaw.(int)) * time.Second
+	}
+	if periodRaw, ok := d.GetOk("period"); ok {
+		role.Period = time.Duration(periodRaw.(int)) * time.Second
+	}
+	if role.TTL < 0 || role.MaxTTL < 0 || role.Period < 0 {
+		return logical.ErrorResponse("ttl, max_ttl and period cannot be negative"), nil
+	}
+	if role.MaxTTL > 0 && role.TTL > role.MaxTTL {
+		return logical.ErrorResponse("ttl cannot be greater than max_ttl"), nil
+	}
+	if role.Period > b.System().MaxLeaseTTL() {
+		return logical.ErrorResponse("period cannot be greater than the mount's maximum TTL"), nil
+	}
+
+	if v, ok := d.GetOk("bound_service_principal_ids"); ok {
+		role.BoundServicePrincipalIDs = strutil.RemoveDuplicates(v.([]string), true)
+	}
+	if v, ok := d.GetOk("bound_subscription_ids"); ok {
+		role.BoundSubscriptionIDs = strutil.RemoveDuplicates(v.([]string), true)
+	}
+	if v, ok := d.GetOk("bound_resource_groups"); ok {
+		role.BoundResourceGroups = strutil.RemoveDuplicates(v.([]string), true)
+	}
+	if v, ok := d.GetOk("bound_vm_names"); ok {
+		role.BoundVMNames = strutil.RemoveDuplicates(v.([]string), true)
+	}
+
+	// A role without any constraint would let every identity of the tenant
+	// log in
+	if len(role.BoundServicePrincipalIDs) == 0 && len(role.BoundSubscriptionIDs) == 0 &&
+		len(role.BoundResourceGroups) == 0 && len(role.BoundVMNames) == 0 {
+		return logical.ErrorResponse("at least one bound constraint must be set on the role"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("role/"+name, role)
+	if err != nil {
+		return nil, err
+	}
+	return nil, req.Storage.Put(entry)
+}
+
+const pathRoleHelpSyn = `
+Manage the roles managed identities log in with.
+`
+
+const pathRoleHelpDesc = `
+A role defines the policies and TTLs of the tokens issued to the managed
+identities logging in with it, and constrains which identities may do so.
+
+Identities are constrained by the object ID of their service principal, and
+by the subscription, resource group and virtual machine they belong to. Every
+constraint that is set must be met. Virtual machine names can only be bound
+for system-assigned identities, whose resource is the virtual machine itself.
+`
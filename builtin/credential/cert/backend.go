@@ -32,9 +32,10 @@ func Backend() *backend {
 			pathCerts(&b),
 			pathCRLs(&b),
 		}),
-		AuthRenew:   b.pathLoginRenew,
-		Invalidate:  b.invalidate,
-		BackendType: logical.TypeCredential,
+		AuthRenew:    b.pathLoginRenew,
+		Invalidate:   b.invalidate,
+		PeriodicFunc: b.periodicFunc,
+		BackendType:  logical.TypeCredential,
 	}
 
 	b.crlUpdateMutex = &sync.RWMutex{}
@@ -59,6 +60,11 @@ func (b *backend) invalidate(key string) {
 	}
 }
 
+func (b *backend) periodicFunc(req *logical.Request) error {
+	// Fetch the CRLs of distribution points that have been updated
+	return b.refreshCRLs(req.Storage)
+}
+
 const backendHelp = `
 The "cert" credential provider allows authentication using
 TLS client certificates. A client connects to Vault and uses
@@ -0,0 +1,310 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+// This file implements the subset of the Online Certificate Status Protocol
+// (RFC 6960) needed to check the status of client certificates: unsigned
+// requests for a single certificate, and basic responses signed by the
+// issuer or by a responder the issuer delegated to.
+
+var (
+	oidSHA1              = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidOCSPBasicResponse = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+
+	// signatureAlgorithms maps the OIDs of the signature algorithms
+	// responders use to their x509 equivalents
+	signatureAlgorithms = map[string]x509.SignatureAlgorithm{
+		"1.2.840.113549.1.1.5":  x509.SHA1WithRSA,
+		"1.2.840.113549.1.1.11": x509.SHA256WithRSA,
+		"1.2.840.113549.1.1.12": x509.SHA384WithRSA,
+		"1.2.840.113549.1.1.13": x509.SHA512WithRSA,
+		"1.2.840.10045.4.1":     x509.ECDSAWithSHA1,
+		"1.2.840.10045.4.3.2":   x509.ECDSAWithSHA256,
+		"1.2.840.10045.4.3.3":   x509.ECDSAWithSHA384,
+		"1.2.840.10045.4.3.4":   x509.ECDSAWithSHA512,
+	}
+)
+
+// ocspStatus is the revocation status of a certificate
+type ocspStatus int
+
+const (
+	ocspGood ocspStatus = iota
+	ocspRevoked
+	ocspUnknown
+)
+
+// ocspResponseSuccessful is the status of responses that carry a basic
+// response; the others denote errors of the responder
+const ocspResponseSuccessful = 0
+
+type ocspCertID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type ocspSingleRequest struct {
+	Cert ocspCertID
+}
+
+type ocspTBSRequest struct {
+	Version     int `asn1:"explicit,tag:0,default:0,optional"`
+	RequestList []ocspSingleRequest
+}
+
+type ocspRequest struct {
+	TBSRequest ocspTBSRequest
+}
+
+type ocspResponse struct {
+	Status   asn1.Enumerated
+	Response ocspResponseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type ocspBasicResponse struct {
+	TBSResponseData    ocspResponseData
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseData struct {
+	Raw                asn1.RawContent
+	Version            int `asn1:"optional,default:0,explicit,tag:0"`
+	RawResponderID     asn1.RawValue
+	ProducedAt         time.Time `asn1:"generalized"`
+	Responses          []ocspSingleResponse
+	ResponseExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type ocspSingleResponse struct {
+	CertID           ocspCertID
+	Good             asn1.Flag        `asn1:"tag:0,optional"`
+	Revoked          ocspRevokedInfo  `asn1:"tag:1,optional"`
+	Unknown          asn1.Flag        `asn1:"tag:2,optional"`
+	ThisUpdate       time.Time        `asn1:"generalized"`
+	NextUpdate       time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	SingleExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type ocspRevokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+// newOCSPCertID identifies the certificate issued by the issuer to
+// responders
+func newOCSPCertID(cert, issuer *x509.Certificate) (ocspCertID, error) {
+	var publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &publicKeyInfo); err != nil {
+		return ocspCertID{}, err
+	}
+
+	nameHash := sha1.Sum(issuer.RawSubject)
+	keyHash := sha1.Sum(publicKeyInfo.PublicKey.RightAlign())
+	return ocspCertID{
+		HashAlgorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidSHA1,
+			Parameters: asn1.RawValue{Tag: asn1.TagNull},
+		},
+		NameHash:      nameHash[:],
+		IssuerKeyHash: keyHash[:],
+		SerialNumber:  cert.SerialNumber,
+	}, nil
+}
+
+func (id ocspCertID) equal(other ocspCertID) bool {
+	return id.HashAlgorithm.Algorithm.Equal(other.HashAlgorithm.Algorithm) &&
+		bytes.Equal(id.NameHash, other.NameHash) &&
+		bytes.Equal(id.IssuerKeyHash, other.IssuerKeyHash) &&
+		id.SerialNumber.Cmp(other.SerialNumber) == 0
+}
+
+// parseOCSPResponse verifies the response of a responder and returns the
+// status of the certificate it describes
+func parseOCSPResponse(raw []byte, id ocspCertID, issuer *x509.Certificate) (ocspStatus, error) {
+	var resp ocspResponse
+	if rest, err := asn1.Unmarshal(raw, &resp); err != nil {
+		return ocspUnknown, err
+	} else if len(rest) > 0 {
+		return ocspUnknown, errors.New("trailing data in OCSP response")
+	}
+	if resp.Status != ocspResponseSuccessful {
+		return ocspUnknown, fmt.Errorf("OCSP responder returned error status %d", resp.Status)
+	}
+	if !resp.Response.ResponseType.Equal(oidOCSPBasicResponse) {
+		return ocspUnknown, errors.New("unsupported OCSP response type")
+	}
+
+	var basic ocspBasicResponse
+	if rest, err := asn1.Unmarshal(resp.Response.Response, &basic); err != nil {
+		return ocspUnknown, err
+	} else if len(rest) > 0 {
+		return ocspUnknown, errors.New("trailing data in OCSP basic response")
+	}
+
+	algorithm, ok := signatureAlgorithms[basic.SignatureAlgorithm.Algorithm.String()]
+	if !ok {
+		return ocspUnknown, fmt.Errorf("unsupported OCSP signature algorithm %s", basic.SignatureAlgorithm.Algorithm)
+	}
+
+	// The response is signed by the issuer itself, or by a certificate the
+	// issuer delegated OCSP signing to
+	signer := issuer
+	if len(basic.Certificates) > 0 {
+		delegate, err := x509.ParseCertificate(basic.Certificates[0].FullBytes)
+		if err != nil {
+			return ocspUnknown, fmt.Errorf("failed to parse OCSP responder certificate: %v", err)
+		}
+		if !delegate.Equal(issuer) {
+			if err := delegate.CheckSignatureFrom(issuer); err != nil {
+				return ocspUnknown, fmt.Errorf("OCSP responder certificate is not signed by the issuer: %v", err)
+			}
+			if !hasExtKeyUsage(delegate, x509.ExtKeyUsageOCSPSigning) {
+				return ocspUnknown, errors.New("OCSP responder certificate is not allowed to sign responses")
+			}
+			signer = delegate
+		}
+	}
+	if err := signer.CheckSignature(algorithm, basic.TBSResponseData.Raw, basic.Signature.RightAlign()); err != nil {
+		return ocspUnknown, fmt.Errorf("invalid OCSP response signature: %v", err)
+	}
+
+	now := time.Now()
+	for _, single := range basic.TBSResponseData.Responses {
+		if !single.CertID.equal(id) {
+			continue
+		}
+		if single.ThisUpdate.After(now.Add(clockSkewLeeway)) {
+			return ocspUnknown, errors.New("OCSP response is not yet valid")
+		}
+		if !single.NextUpdate.IsZero() && single.NextUpdate.Before(now.Add(-clockSkewLeeway)) {
+			return ocspUnknown, errors.New("OCSP response has expired")
+		}
+
+		switch {
+		case bool(single.Good):
+			return ocspGood, nil
+		case !single.Revoked.RevocationTime.IsZero():
+			return ocspRevoked, nil
+		default:
+			return ocspUnknown, nil
+		}
+	}
+	return ocspUnknown, errors.New("OCSP response does not describe the certificate")
+}
+
+func hasExtKeyUsage(cert *x509.Certificate, usage x509.ExtKeyUsage) bool {
+	for _, u := range cert.ExtKeyUsage {
+		if u == usage {
+			return true
+		}
+	}
+	return false
+}
+
+// clockSkewLeeway is the leeway allowed when checking the validity period of
+// OCSP responses
+const clockSkewLeeway = 5 * time.Minute
+
+// queryOCSP asks the responder at the URL for the status of the certificate
+// issued by the issuer
+func queryOCSP(client *http.Client, url string, cert, issuer *x509.Certificate) (ocspStatus, error) {
+	id, err := newOCSPCertID(cert, issuer)
+	if err != nil {
+		return ocspUnknown, err
+	}
+	reqBytes, err := asn1.Marshal(ocspRequest{
+		TBSRequest: ocspTBSRequest{
+			RequestList: []ocspSingleRequest{{Cert: id}},
+		},
+	})
+	if err != nil {
+		return ocspUnknown, err
+	}
+
+	httpResp, err := client.Post(url, "application/ocsp-request", bytes.NewReader(reqBytes))
+	if err != nil {
+		return ocspUnknown, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		return ocspUnknown, fmt.Errorf("OCSP responder returned HTTP status %d", httpResp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return ocspUnknown, err
+	}
+
+	return parseOCSPResponse(body, id, issuer)
+}
+
+// ocspTimeout bounds each query to a responder
+const ocspTimeout = 10 * time.Second
+
+// checkOCSP checks the status of the certificate issued by the issuer with
+// the responders of the entry, or those named in the certificate. It returns
+// an error if the certificate is revoked, or if its status could not be
+// established and the entry does not fail open.
+func (b *backend) checkOCSP(entry *CertEntry, cert, issuer *x509.Certificate) error {
+	servers := entry.OCSPServersOverride
+	if len(servers) == 0 {
+		servers = cert.OCSPServer
+	}
+	if len(servers) == 0 {
+		if entry.OCSPFailOpen {
+			return nil
+		}
+		return errors.New("no OCSP responder is known for the certificate")
+	}
+
+	client := cleanhttp.DefaultClient()
+	client.Timeout = ocspTimeout
+
+	var lastErr error
+	for _, server := range servers {
+		status, err := queryOCSP(client, server, cert, issuer)
+		if err != nil {
+			b.Logger().Warn("cert: OCSP query failed", "server", server, "error", err)
+			lastErr = err
+			continue
+		}
+		switch status {
+		case ocspGood:
+			return nil
+		case ocspRevoked:
+			return errors.New("certificate has been revoked")
+		default:
+			lastErr = fmt.Errorf("OCSP responder %s does not know the certificate", server)
+		}
+	}
+
+	if entry.OCSPFailOpen {
+		return nil
+	}
+	return fmt.Errorf("failed to check the revocation status of the certificate: %v", lastErr)
+}
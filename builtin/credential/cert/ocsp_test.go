@@ -0,0 +1,625 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+type testRevocationCA struct {
+	cert *x509.Certificate
+	key  *rsa.PrivateKey
+	pem  string
+}
+
+func testGenerateRevocationCA(t *testing.T) *testRevocationCA {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Revocation Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testRevocationCA{
+		cert: cert,
+		key:  key,
+		pem:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	}
+}
+
+func (ca *testRevocationCA) issue(t *testing.T, serial int64, ocspServer string) *x509.Certificate {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if ocspServer != "" {
+		template.OCSPServer = []string{ocspServer}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func (ca *testRevocationCA) crl(t *testing.T, nextUpdate time.Time, revoked ...int64) []byte {
+	var revokedCerts []pkix.RevokedCertificate
+	for _, serial := range revoked {
+		revokedCerts = append(revokedCerts, pkix.RevokedCertificate{
+			SerialNumber:   big.NewInt(serial),
+			RevocationTime: time.Now(),
+		})
+	}
+	crl, err := ca.cert.CreateCRL(rand.Reader, ca.key, revokedCerts, time.Now().Add(-time.Hour), nextUpdate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crl
+}
+
+// testOCSPResponder answers OCSP requests for certificates of the CA,
+// reporting the serials in revoked as revoked and the others as good
+type testOCSPResponder struct {
+	*httptest.Server
+
+	sync.Mutex
+	revoked map[int64]bool
+}
+
+func testNewOCSPResponder(t *testing.T, ca *testRevocationCA) *testOCSPResponder {
+	r := &testOCSPResponder{
+		revoked: map[int64]bool{},
+	}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var ocspReq ocspRequest
+		if _, err := asn1.Unmarshal(body, &ocspReq); err != nil || len(ocspReq.TBSRequest.RequestList) != 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		id := ocspReq.TBSRequest.RequestList[0].Cert
+
+		r.Lock()
+		revoked := r.revoked[id.SerialNumber.Int64()]
+		r.Unlock()
+
+		w.Write(testOCSPResponse(t, ca, id, revoked))
+	}))
+	return r
+}
+
+func (r *testOCSPResponder) revoke(serial int64) {
+	r.Lock()
+	defer r.Unlock()
+	r.revoked[serial] = true
+}
+
+func testOCSPResponse(t *testing.T, ca *testRevocationCA, id ocspCertID, revoked bool) []byte {
+	single := ocspSingleResponse{
+		CertID:     id,
+		ThisUpdate: time.Now().Add(-time.Minute).UTC(),
+		NextUpdate: time.Now().Add(time.Hour).UTC(),
+	}
+	if revoked {
+		single.Revoked = ocspRevokedInfo{RevocationTime: time.Now().Add(-time.Minute).UTC()}
+	} else {
+		single.Good = true
+	}
+
+	tbs, err := asn1.Marshal(ocspResponseData{
+		RawResponderID: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true, Bytes: ca.cert.RawSubject},
+		ProducedAt:     time.Now().UTC(),
+		Responses:      []ocspSingleResponse{single},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := sha256.Sum256(tbs)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, ca.key, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	basic, err := asn1.Marshal(struct {
+		TBSResponseData    asn1.RawValue
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		Signature          asn1.BitString
+	}{
+		TBSResponseData:    asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}},
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := asn1.Marshal(ocspResponse{
+		Status: ocspResponseSuccessful,
+		Response: ocspResponseBytes{
+			ResponseType: oidOCSPBasicResponse,
+			Response:     basic,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func testRevocationLogin(b *backend, storage logical.Storage, cert *x509.Certificate) (*logical.Response, error) {
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "login",
+		Storage:   storage,
+		Connection: &logical.Connection{
+			ConnState: &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{cert},
+			},
+		},
+	}
+	return b.HandleRequest(req)
+}
+
+func testRevocationBackend(t *testing.T) (*backend, logical.Storage) {
+	storage := &logical.InmemStorage{}
+	lb, err := Factory(&logical.BackendConfig{
+		System: &logical.StaticSystemView{
+			DefaultLeaseTTLVal: 300 * time.Second,
+			MaxLeaseTTLVal:     1800 * time.Second,
+		},
+		StorageView: storage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lb.(*backend), storage
+}
+
+func testRevocationRenew(b *backend, storage logical.Storage, auth *logical.Auth) (*logical.Response, error) {
+	auth.IssueTime = time.Now()
+	return b.pathLoginRenew(&logical.Request{
+		Storage: storage,
+		Auth:    auth,
+	}, &framework.FieldData{
+		Raw:    map[string]interface{}{},
+		Schema: pathLogin(b).Fields,
+	})
+}
+
+func testDisableRevocationBinding(t *testing.T, b *backend, storage logical.Storage) {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"disable_binding": true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func testWriteRevocationCert(t *testing.T, b *backend, storage logical.Storage, data map[string]interface{}) {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "certs/test",
+		Storage:   storage,
+		Data:      data,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestOCSP_parseResponse(t *testing.T) {
+	ca := testGenerateRevocationCA(t)
+	other := testGenerateRevocationCA(t)
+	cert := ca.issue(t, 10, "")
+
+	id, err := newOCSPCertID(cert, ca.cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := parseOCSPResponse(testOCSPResponse(t, ca, id, false), id, ca.cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != ocspGood {
+		t.Fatalf("bad: %v", status)
+	}
+
+	status, err = parseOCSPResponse(testOCSPResponse(t, ca, id, true), id, ca.cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != ocspRevoked {
+		t.Fatalf("bad: %v", status)
+	}
+
+	// Responses signed by anyone else are rejected
+	if _, err := parseOCSPResponse(testOCSPResponse(t, other, id, false), id, ca.cert); err == nil {
+		t.Fatal("expected error for response with invalid signature")
+	}
+
+	// Responses about other certificates are rejected
+	otherID, err := newOCSPCertID(ca.issue(t, 11, ""), ca.cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseOCSPResponse(testOCSPResponse(t, ca, otherID, false), id, ca.cert); err == nil {
+		t.Fatal("expected error for response about another certificate")
+	}
+}
+
+func TestBackend_OCSP(t *testing.T) {
+	b, storage := testRevocationBackend(t)
+	ca := testGenerateRevocationCA(t)
+	responder := testNewOCSPResponder(t, ca)
+	defer responder.Close()
+
+	testWriteRevocationCert(t, b, storage, map[string]interface{}{
+		"certificate":  ca.pem,
+		"policies":     "foo",
+		"ocsp_enabled": true,
+	})
+
+	// The responder named in the certificate is used
+	good := ca.issue(t, 20, responder.URL)
+	resp, err := testRevocationLogin(b, storage, good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	auth := resp.Auth
+
+	responder.revoke(20)
+	resp, err = testRevocationLogin(b, storage, good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "revoked") {
+		t.Fatalf("expected failure due to revoked certificate, got: %#v", resp)
+	}
+
+	// Renewals without binding check the status of the certificate as well
+	testDisableRevocationBinding(t, b, storage)
+	resp, err = testRevocationRenew(b, storage, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "revoked") {
+		t.Fatalf("expected renewal to fail, got: %#v", resp)
+	}
+
+	// Without a responder the status cannot be established
+	unknown := ca.issue(t, 21, "")
+	resp, err = testRevocationLogin(b, storage, unknown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected failure without OCSP responder, got: %#v", resp)
+	}
+
+	// Unless the responders are overridden
+	testWriteRevocationCert(t, b, storage, map[string]interface{}{
+		"certificate":           ca.pem,
+		"policies":              "foo",
+		"ocsp_enabled":          true,
+		"ocsp_servers_override": responder.URL,
+	})
+	resp, err = testRevocationLogin(b, storage, unknown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Or failing open is allowed, which still rejects revoked certificates
+	responder.Close()
+	testWriteRevocationCert(t, b, storage, map[string]interface{}{
+		"certificate":           ca.pem,
+		"policies":              "foo",
+		"ocsp_enabled":          true,
+		"ocsp_servers_override": responder.URL,
+		"ocsp_fail_open":        true,
+	})
+	resp, err = testRevocationLogin(b, storage, unknown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// OCSP needs the issuer of client certificates
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "certs/leaf",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"certificate": string(pem.EncodeToMemory(&pem.Block{
+				Type:  "CERTIFICATE",
+				Bytes: good.Raw,
+			})),
+			"ocsp_enabled": true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected error for non-CA certificate, got: %#v", resp)
+	}
+}
+
+func TestBackend_CRLDistributionPoint(t *testing.T) {
+	b, storage := testRevocationBackend(t)
+	ca := testGenerateRevocationCA(t)
+
+	var lock sync.Mutex
+	crl := ca.crl(t, time.Now().Add(-time.Minute))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		w.Write(crl)
+	}))
+	defer server.Close()
+
+	testWriteRevocationCert(t, b, storage, map[string]interface{}{
+		"certificate": ca.pem,
+		"policies":    "foo",
+	})
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "crls/remote",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"url": server.URL,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "crls/remote",
+		Storage:   storage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.Data["url"] != server.URL {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	client := ca.issue(t, 30, "")
+	resp, err = testRevocationLogin(b, storage, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	auth := resp.Auth
+
+	// Revoke the certificate at the distribution point; it is picked up by
+	// the periodic refresh since the next update time has passed
+	lock.Lock()
+	crl = ca.crl(t, time.Now().Add(time.Hour), 30)
+	lock.Unlock()
+
+	if err := b.refreshCRLs(storage); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = testRevocationLogin(b, storage, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected failure due to revoked certificate, got: %#v", resp)
+	}
+
+	// Renewal fails as well, even without binding to the certificate
+	testDisableRevocationBinding(t, b, storage)
+	resp, err = testRevocationRenew(b, storage, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected renewal to fail, got: %#v", resp)
+	}
+
+	// Tokens issued before the certificates were kept only have the serial
+	delete(auth.InternalData, "client_certificates")
+	_, err = testRevocationRenew(b, storage, auth)
+	if err == nil || !strings.Contains(err.Error(), "revoked") {
+		t.Fatalf("expected renewal to fail, got: %v", err)
+	}
+}
+
+func TestBackend_CRLDistributionPoint_Untrusted(t *testing.T) {
+	b, storage := testRevocationBackend(t)
+	ca := testGenerateRevocationCA(t)
+	other := testGenerateRevocationCA(t)
+
+	var lock sync.Mutex
+	crl := other.crl(t, time.Now().Add(-time.Minute))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		w.Write(crl)
+	}))
+	defer server.Close()
+
+	testWriteRevocationCert(t, b, storage, map[string]interface{}{
+		"certificate": ca.pem,
+		"policies":    "foo",
+	})
+
+	writeCRL := func() *logical.Response {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "crls/remote",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"url": server.URL,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// CRLs not signed by a trusted CA are rejected
+	if resp := writeCRL(); resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "trusted CA") {
+		t.Fatalf("expected error, got: %#v", resp)
+	}
+
+	// Nor do they replace a CRL when it is refreshed
+	lock.Lock()
+	crl = ca.crl(t, time.Now().Add(-time.Minute), 40)
+	lock.Unlock()
+	if resp := writeCRL(); resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	lock.Lock()
+	crl = other.crl(t, time.Now().Add(time.Hour))
+	lock.Unlock()
+	if err := b.refreshCRLs(storage); err != nil {
+		t.Fatal(err)
+	}
+	if !b.serialInCRLs("40") {
+		t.Fatal("expected the CRL signed by the CA to stay in effect")
+	}
+}
+
+func TestBackend_CRLDistributionPoint_NoNextUpdate(t *testing.T) {
+	b, storage := testRevocationBackend(t)
+	ca := testGenerateRevocationCA(t)
+
+	var lock sync.Mutex
+	crl := ca.crl(t, time.Time{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		w.Write(crl)
+	}))
+	defer server.Close()
+
+	testWriteRevocationCert(t, b, storage, map[string]interface{}{
+		"certificate": ca.pem,
+		"policies":    "foo",
+	})
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "crls/remote",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"url": server.URL,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	lock.Lock()
+	crl = ca.crl(t, time.Time{}, 50)
+	lock.Unlock()
+
+	// The CRL is not fetched again before the default interval
+	if err := b.refreshCRLs(storage); err != nil {
+		t.Fatal(err)
+	}
+	if b.serialInCRLs("50") {
+		t.Fatal("expected the CRL not to be refreshed yet")
+	}
+
+	// But it is once the interval has passed
+	b.crlUpdateMutex.Lock()
+	current := b.crls["remote"]
+	if !current.NextUpdate.IsZero() {
+		b.crlUpdateMutex.Unlock()
+		t.Fatalf("bad: %#v", current)
+	}
+	current.FetchTime = time.Now().Add(-crlDefaultRefreshInterval)
+	b.crls["remote"] = current
+	b.crlUpdateMutex.Unlock()
+
+	if err := b.refreshCRLs(storage); err != nil {
+		t.Fatal(err)
+	}
+	if !b.serialInCRLs("50") {
+		t.Fatal("expected the CRL to be refreshed")
+	}
+}
@@ -67,6 +67,25 @@ seconds. Defaults to system/backend default TTL.`,
 				Description: `TTL for tokens issued by this backend.
 Defaults to system/backend default TTL time.`,
 			},
+
+			"ocsp_enabled": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Whether to check the revocation status of client
+certificates issued by this CA with OCSP at login and renewal.`,
+			},
+
+			"ocsp_servers_override": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `A comma-separated list of OCSP responder URLs to
+query instead of those named in the client certificates.`,
+			},
+
+			"ocsp_fail_open": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, logins are allowed when no OCSP responder
+could establish the status of the client certificate. Revoked
+certificates are always rejected.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -135,6 +154,10 @@ func (b *backend) pathCertRead(
 			"display_name": cert.DisplayName,
 			"policies":     strings.Join(cert.Policies, ","),
 			"ttl":          duration / time.Second,
+
+			"ocsp_enabled":          cert.OCSPEnabled,
+			"ocsp_servers_override": cert.OCSPServersOverride,
+			"ocsp_fail_open":        cert.OCSPFailOpen,
 		},
 	}, nil
 }
@@ -146,6 +169,9 @@ func (b *backend) pathCertWrite(
 	displayName := d.Get("display_name").(string)
 	policies := policyutil.ParsePolicies(d.Get("policies").(string))
 	allowedNames := d.Get("allowed_names").([]string)
+	ocspEnabled := d.Get("ocsp_enabled").(bool)
+	ocspServersOverride := d.Get("ocsp_servers_override").([]string)
+	ocspFailOpen := d.Get("ocsp_fail_open").(bool)
 
 	// Default the display name to the certificate name if not given
 	if displayName == "" {
@@ -171,12 +197,21 @@ func (b *backend) pathCertWrite(
 		}
 	}
 
+	// OCSP requests identify certificates by their issuer, which is only
+	// known for certificates issued by a trusted CA
+	if ocspEnabled && !parsed[0].IsCA {
+		return logical.ErrorResponse("OCSP checking requires a CA certificate"), nil
+	}
+
 	certEntry := &CertEntry{
-		Name:         name,
-		Certificate:  certificate,
-		DisplayName:  displayName,
-		Policies:     policies,
-		AllowedNames: allowedNames,
+		Name:                name,
+		Certificate:         certificate,
+		DisplayName:         displayName,
+		Policies:            policies,
+		AllowedNames:        allowedNames,
+		OCSPEnabled:         ocspEnabled,
+		OCSPServersOverride: ocspServersOverride,
+		OCSPFailOpen:        ocspFailOpen,
 	}
 
 	// Parse the lease duration or default to backend/system default
@@ -210,6 +245,10 @@ type CertEntry struct {
 	Policies     []string
 	TTL          time.Duration
 	AllowedNames []string
+
+	OCSPEnabled         bool
+	OCSPServersOverride []string
+	OCSPFailOpen        bool
 }
 
 const pathCertHelpSyn = `
@@ -2,11 +2,16 @@ package cert
 
 import (
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"fmt"
+	"io/ioutil"
 	"math/big"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/fatih/structs"
+	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/vault/helper/certutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -26,7 +31,15 @@ func pathCRLs(b *backend) *framework.Path {
 				Description: `The public certificate that should be trusted.
 May be DER or PEM encoded. Note: the expiration time
 is ignored; if the CRL is no longer valid, delete it
-using the same name as specified here.`,
+using the same name as specified here. Mutually exclusive
+with "url".`,
+			},
+
+			"url": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The URL of a CRL distribution point to fetch the CRL
+from. The CRL must be signed by a trusted CA, and is
+fetched again once its next update time has passed.`,
 			},
 		},
 
@@ -95,6 +108,18 @@ func (b *backend) findSerialInCRLs(serial *big.Int) map[string]RevokedSerialInfo
 	return ret
 }
 
+// serialInCRLs returns whether the serial, in decimal, is revoked by any CRL
+func (b *backend) serialInCRLs(serial string) bool {
+	b.crlUpdateMutex.RLock()
+	defer b.crlUpdateMutex.RUnlock()
+	for _, crl := range b.crls {
+		if _, ok := crl.Serials[serial]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 func parseSerialString(input string) (*big.Int, error) {
 	ret := &big.Int{}
 
@@ -191,13 +216,26 @@ func (b *backend) pathCRLWrite(
 		return logical.ErrorResponse(`"name" parameter cannot be empty`), nil
 	}
 	crl := d.Get("crl").(string)
+	url := d.Get("url").(string)
 
-	certList, err := x509.ParseCRL([]byte(crl))
-	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf("failed to parse CRL: %v", err)), nil
+	switch {
+	case crl != "" && url != "":
+		return logical.ErrorResponse(`only one of "crl" and "url" can be set`), nil
 	}
-	if certList == nil {
-		return logical.ErrorResponse("parsed CRL is nil"), nil
+
+	var crlInfo *CRLInfo
+	if url != "" {
+		var err error
+		crlInfo, err = b.fetchCRLInfo(req.Storage, url)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	} else {
+		certList, err := parseCRL([]byte(crl))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		crlInfo = newCRLInfo(certList)
 	}
 
 	if err := b.populateCRLs(req.Storage); err != nil {
@@ -207,28 +245,158 @@ func (b *backend) pathCRLWrite(
 	b.crlUpdateMutex.Lock()
 	defer b.crlUpdateMutex.Unlock()
 
-	crlInfo := CRLInfo{
-		Serials: map[string]RevokedSerialInfo{},
+	return nil, b.storeCRL(req.Storage, name, crlInfo)
+}
+
+// storeCRL persists the CRL and makes it effective. The caller must hold
+// crlUpdateMutex for writing.
+func (b *backend) storeCRL(storage logical.Storage, name string, crlInfo *CRLInfo) error {
+	entry, err := logical.StorageEntryJSON("crls/"+name, crlInfo)
+	if err != nil {
+		return err
+	}
+	if err := storage.Put(entry); err != nil {
+		return err
+	}
+
+	b.crls[name] = *crlInfo
+	return nil
+}
+
+// parseCRL parses a DER or PEM encoded CRL
+func parseCRL(raw []byte) (*pkix.CertificateList, error) {
+	certList, err := x509.ParseCRL(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CRL: %v", err)
+	}
+	if certList == nil {
+		return nil, fmt.Errorf("parsed CRL is nil")
+	}
+	return certList, nil
+}
+
+// newCRLInfo returns the revoked serials of the CRL
+func newCRLInfo(certList *pkix.CertificateList) *CRLInfo {
+	crlInfo := &CRLInfo{
+		Serials:    map[string]RevokedSerialInfo{},
+		NextUpdate: certList.TBSCertList.NextUpdate,
 	}
 	for _, revokedCert := range certList.TBSCertList.RevokedCertificates {
 		crlInfo.Serials[revokedCert.SerialNumber.String()] = RevokedSerialInfo{}
 	}
+	return crlInfo
+}
 
-	entry, err := logical.StorageEntryJSON("crls/"+name, crlInfo)
+const (
+	// crlFetchTimeout bounds the download of a CRL from a distribution point
+	crlFetchTimeout = 30 * time.Second
+
+	// crlDefaultRefreshInterval is how often CRLs without a next update time
+	// are fetched again from their distribution point
+	crlDefaultRefreshInterval = time.Hour
+)
+
+// fetchCRL downloads a CRL from a distribution point
+func fetchCRL(url string) ([]byte, error) {
+	client := cleanhttp.DefaultClient()
+	client.Timeout = crlFetchTimeout
+
+	resp, err := client.Get(url)
 	if err != nil {
 		return nil, err
 	}
-	if err = req.Storage.Put(entry); err != nil {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("distribution point returned HTTP status %d", resp.StatusCode)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// fetchCRLInfo downloads a CRL from a distribution point and checks that it
+// is signed by one of the trusted CAs, since anyone able to tamper with the
+// download could otherwise unrevoke certificates
+func (b *backend) fetchCRLInfo(storage logical.Storage, url string) (*CRLInfo, error) {
+	fetched, err := fetchCRL(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch CRL: %v", err)
+	}
+	certList, err := parseCRL(fetched)
+	if err != nil {
 		return nil, err
 	}
 
-	b.crls[name] = crlInfo
+	_, trusted, _ := b.loadTrustedCerts(storage, "")
+	verified := false
+	for _, trust := range trusted {
+		for _, cert := range trust.Certificates {
+			if cert.CheckCRLSignature(certList) == nil {
+				verified = true
+				break
+			}
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("CRL is not signed by a trusted CA")
+	}
 
-	return nil, nil
+	crlInfo := newCRLInfo(certList)
+	crlInfo.URL = url
+	crlInfo.FetchTime = time.Now()
+	return crlInfo, nil
+}
+
+// refreshCRLs fetches the CRLs configured with a distribution point again
+// once their next update time has passed, or periodically if they have none.
+// A CRL that cannot be refreshed stays in effect until the next attempt.
+func (b *backend) refreshCRLs(storage logical.Storage) error {
+	if err := b.populateCRLs(storage); err != nil {
+		return err
+	}
+
+	// Find the stale CRLs first so that logins are not blocked while they
+	// are fetched
+	stale := map[string]string{}
+	now := time.Now()
+	b.crlUpdateMutex.RLock()
+	for name, crl := range b.crls {
+		if crl.URL != "" && !crl.refreshTime().After(now) {
+			stale[name] = crl.URL
+		}
+	}
+	b.crlUpdateMutex.RUnlock()
+
+	for name, url := range stale {
+		crlInfo, err := b.fetchCRLInfo(storage, url)
+		if err == nil {
+			b.crlUpdateMutex.Lock()
+			// Skip CRLs changed or deleted in the meantime
+			if current, ok := b.crls[name]; ok && current.URL == url {
+				err = b.storeCRL(storage, name, crlInfo)
+			}
+			b.crlUpdateMutex.Unlock()
+		}
+		if err != nil {
+			b.Logger().Warn("cert: failed to refresh CRL", "name", name, "url", url, "error", err)
+		}
+	}
+
+	return nil
 }
 
 type CRLInfo struct {
-	Serials map[string]RevokedSerialInfo `json:"serials" structs:"serials" mapstructure:"serials"`
+	Serials    map[string]RevokedSerialInfo `json:"serials" structs:"serials" mapstructure:"serials"`
+	URL        string                       `json:"url" structs:"url" mapstructure:"url"`
+	NextUpdate time.Time                    `json:"next_update" structs:"next_update,omitnested" mapstructure:"next_update"`
+	FetchTime  time.Time                    `json:"fetch_time" structs:"fetch_time,omitnested" mapstructure:"fetch_time"`
+}
+
+// refreshTime returns when a CRL fetched from a distribution point should be
+// fetched again
+func (c *CRLInfo) refreshTime() time.Time {
+	if !c.NextUpdate.IsZero() {
+		return c.NextUpdate
+	}
+	return c.FetchTime.Add(crlDefaultRefreshInterval)
 }
 
 type RevokedSerialInfo struct {
@@ -250,4 +418,9 @@ in which none of the serials are revoked by any CRL -- allows authentication.
 This allows authentication to succeed when interim parts of one chain have been
 revoked; for instance, if a certificate is signed by two intermediate CAs due to
 one of them expiring.
+
+A CRL can be given directly, or as the URL of a CRL distribution point. CRLs
+fetched from a distribution point must be signed by a trusted CA, and are
+fetched again once their next update time has passed, or every hour if they
+have none.
 `
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/helper/certutil"
 	"github.com/hashicorp/vault/helper/policyutil"
@@ -67,12 +68,23 @@ func (b *backend) pathLogin(
 	skid := base64.StdEncoding.EncodeToString(clientCerts[0].SubjectKeyId)
 	akid := base64.StdEncoding.EncodeToString(clientCerts[0].AuthorityKeyId)
 
+	// The certificates are kept so that renewals without binding can check
+	// them again
+	var presented bytes.Buffer
+	for _, cert := range clientCerts {
+		if err := pem.Encode(&presented, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+			return nil, err
+		}
+	}
+
 	// Generate a response
 	resp := &logical.Response{
 		Auth: &logical.Auth{
 			InternalData: map[string]interface{}{
-				"subject_key_id":   skid,
-				"authority_key_id": akid,
+				"subject_key_id":      skid,
+				"authority_key_id":    akid,
+				"serial_number":       clientCerts[0].SerialNumber.String(),
+				"client_certificates": presented.String(),
 			},
 			Policies:    matched.Entry.Policies,
 			DisplayName: matched.Entry.DisplayName,
@@ -125,7 +137,39 @@ func (b *backend) pathLoginRenew(
 			return nil, fmt.Errorf("client identity during renewal not matching client identity used during login")
 		}
 
+	} else if presented, ok := req.Auth.InternalData["client_certificates"].(string); ok {
+		// Without binding the client certificate is not presented again, so
+		// the certificates presented at login go through the checks of a
+		// login again, including CRLs and OCSP
+		renewReq := *req
+		renewReq.Connection = &logical.Connection{
+			ConnState: &tls.ConnectionState{
+				PeerCertificates: parsePEM([]byte(presented)),
+			},
+		}
+		matched, resp, err := b.verifyCredentials(&renewReq, d)
+		if err != nil {
+			return nil, err
+		}
+		if resp != nil {
+			return resp, nil
+		}
+		if matched == nil {
+			return nil, nil
+		}
 	}
+
+	// Tokens issued before the certificates were kept at login only have the
+	// serial of the client certificate, which is checked against the CRLs
+	if serial, ok := req.Auth.InternalData["serial_number"].(string); ok {
+		if err := b.populateCRLs(req.Storage); err != nil {
+			return nil, err
+		}
+		if b.serialInCRLs(serial) {
+			return nil, fmt.Errorf("client certificate has been revoked")
+		}
+	}
+
 	// Get the cert and use its TTL
 	cert, err := b.Cert(req.Storage, req.Auth.Metadata["cert_name"])
 	if err != nil {
@@ -166,8 +210,12 @@ func (b *backend) verifyCredentials(req *logical.Request, d *framework.FieldData
 	// Load the trusted certificates
 	roots, trusted, trustedNonCAs := b.loadTrustedCerts(req.Storage, certName)
 
+	if err := b.populateCRLs(req.Storage); err != nil {
+		return nil, nil, err
+	}
+
 	// Get the list of full chains matching the connection
-	trustedChains, err := validateConnState(roots, connState)
+	trustedChains, err := validateConnState(roots, connState, b.System().ClockSkewTolerance())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -193,12 +241,21 @@ func (b *backend) verifyCredentials(req *logical.Request, d *framework.FieldData
 
 	// Search for a ParsedCert that intersects with the validated chains and any additional constraints
 	matches := make([]*ParsedCert, 0)
+	var revocationErr error
 	for _, trust := range trusted { // For each ParsedCert in the config
 		for _, tCert := range trust.Certificates { // For each certificate in the entry
 			for _, chain := range trustedChains { // For each root chain that we matched
 				for _, cCert := range chain { // For each cert in the matched chain
 					if tCert.Equal(cCert) && // ParsedCert intersects with matched chain
 						b.matchesConstraints(clientCert, chain, trust) { // validate client cert + matched chain against the config
+						// Check the status of the client cert with its issuer,
+						// which is next in the chain
+						if trust.Entry.OCSPEnabled && len(chain) > 1 {
+							if err := b.checkOCSP(trust.Entry, clientCert, chain[1]); err != nil {
+								revocationErr = err
+								continue
+							}
+						}
 						// Add the match to the list
 						matches = append(matches, trust)
 					}
@@ -209,6 +266,9 @@ func (b *backend) verifyCredentials(req *logical.Request, d *framework.FieldData
 
 	// Fail on no matches
 	if len(matches) == 0 {
+		if revocationErr != nil {
+			return nil, logical.ErrorResponse(fmt.Sprintf("revocation check of the login certificate failed: %v", revocationErr)), nil
+		}
 		return nil, logical.ErrorResponse("no chain matching all constraints could be found for this login certificate"), nil
 	}
 
@@ -331,8 +391,9 @@ func parsePEM(raw []byte) (certs []*x509.Certificate) {
 // validateConnState is used to validate that the TLS client is authorized
 // by at trusted certificate. Most of this logic is lifted from the client
 // verification logic here:  http://golang.org/src/crypto/tls/handshake_server.go
-// The trusted chains are returned.
-func validateConnState(roots *x509.CertPool, cs *tls.ConnectionState) ([][]*x509.Certificate, error) {
+// The trusted chains are returned. Certificates whose validity period starts
+// less than the clock skew tolerance in the future are accepted.
+func validateConnState(roots *x509.CertPool, cs *tls.ConnectionState, tolerance time.Duration) ([][]*x509.Certificate, error) {
 	opts := x509.VerifyOptions{
 		Roots:         roots,
 		Intermediates: x509.NewCertPool(),
@@ -344,6 +405,17 @@ func validateConnState(roots *x509.CertPool, cs *tls.ConnectionState) ([][]*x509
 		return nil, nil
 	}
 
+	// The chain is verified at the latest start of the validity periods
+	// within the tolerance, since the verification checks the start and the
+	// end of the periods at the same time
+	now := time.Now()
+	for _, cert := range certs {
+		if cert.NotBefore.After(now) && cert.NotBefore.Sub(now) <= tolerance &&
+			cert.NotBefore.After(opts.CurrentTime) {
+			opts.CurrentTime = cert.NotBefore
+		}
+	}
+
 	if len(certs) > 1 {
 		for _, cert := range certs[1:] {
 			opts.Intermediates.AddCert(cert)
@@ -0,0 +1,131 @@
+package gcp
+
+import (
+	"crypto/rsa"
+	"sync"
+
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func Factory(conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func Backend() *backend {
+	b := &backend{
+		fetchKeys: fetchSigningKeys,
+		newClient: func(credentials string) (computeClient, error) {
+			return newHTTPComputeClient(credentials)
+		},
+		keySets:     make(map[string]*keySet),
+		keySetLocks: locksutil.CreateLocks(),
+	}
+
+	b.Backend = &framework.Backend{
+		Help: backendHelp,
+
+		PathsSpecial: &logical.Paths{
+			Unauthenticated: []string{
+				"login",
+			},
+		},
+
+		Paths: []*framework.Path{
+			pathConfig(b),
+			pathRole(b),
+			pathListRoles(b),
+			pathLogin(b),
+		},
+
+		Invalidate:  b.invalidate,
+		AuthRenew:   b.pathLoginRenew,
+		BackendType: logical.TypeCredential,
+	}
+
+	return b
+}
+
+type backend struct {
+	*framework.Backend
+
+	// fetchKeys returns the keys of the JSON web key set at the URL, by key
+	// ID; it is replaced in tests
+	fetchKeys func(url string) (map[string]*rsa.PublicKey, error)
+
+	keySets     map[string]*keySet
+	keySetsLock sync.Mutex
+
+	// keySetLocks serialize the fetches of each key set
+	keySetLocks []*locksutil.LockEntry
+
+	// newClient creates the client used to look up instances; it is
+	// replaced in tests
+	newClient func(credentials string) (computeClient, error)
+
+	client     computeClient
+	clientLock sync.RWMutex
+}
+
+// Client returns the client used to look up instances, creating it from the
+// configured credentials if needed
+func (b *backend) Client(s logical.Storage) (computeClient, error) {
+	b.clientLock.RLock()
+	if b.client != nil {
+		defer b.clientLock.RUnlock()
+		return b.client, nil
+	}
+	b.clientLock.RUnlock()
+
+	b.clientLock.Lock()
+	defer b.clientLock.Unlock()
+
+	// Check again in case it was created while waiting for the lock
+	if b.client != nil {
+		return b.client, nil
+	}
+
+	config, err := b.Config(s)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := b.newClient(config.Credentials)
+	if err != nil {
+		return nil, err
+	}
+	b.client = client
+	return b.client, nil
+}
+
+// resetClient forces the client to be recreated on next use
+func (b *backend) resetClient() {
+	b.clientLock.Lock()
+	b.client = nil
+	b.clientLock.Unlock()
+}
+
+func (b *backend) invalidate(key string) {
+	switch key {
+	case "config":
+		b.resetClient()
+	}
+}
+
+const backendHelp = `
+The GCP credential provider allows authentication of Google Cloud service
+accounts and Compute Engine instances.
+
+Service accounts log in with a JWT they signed through the IAM signJwt API,
+and instances log in with an identity token from their metadata server. Both
+are verified against the certificates published by Google, and the role used
+to log in constrains the service accounts, projects, zones and instance
+labels that may use it.
+
+After enabling the credential provider, use the "role" route to create roles.
+`
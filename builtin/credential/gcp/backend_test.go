@@ -0,0 +1,365 @@
+package gcp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SermoDigital/jose/crypto"
+	"github.com/SermoDigital/jose/jws"
+	"github.com/hashicorp/vault/logical"
+)
+
+const testServiceAccount = "app@my-project.iam.gserviceaccount.com"
+
+// testComputeClient returns instances from memory
+type testComputeClient struct {
+	instances map[string]*instance
+}
+
+func (c *testComputeClient) Instance(project, zone, name string) (*instance, error) {
+	return c.instances[project+"/"+zone+"/"+name], nil
+}
+
+func testBackend(t *testing.T) (*backend, logical.Storage, *rsa.PrivateKey, *testComputeClient) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Google and the service account sign with the same test key, under
+	// different key IDs
+	b.(*backend).fetchKeys = func(u string) (map[string]*rsa.PublicKey, error) {
+		switch u {
+		case googleCertsURL:
+			return map[string]*rsa.PublicKey{"google": &key.PublicKey}, nil
+		case serviceAccountCertsURL(testServiceAccount):
+			return map[string]*rsa.PublicKey{"sa": &key.PublicKey}, nil
+		}
+		return map[string]*rsa.PublicKey{}, nil
+	}
+	client := &testComputeClient{
+		instances: map[string]*instance{
+			"my-project/us-central1-a/web-1": &instance{
+				Name:   "web-1",
+				Labels: map[string]string{"env": "prod", "team": "web"},
+			},
+		},
+	}
+	b.(*backend).newClient = func(string) (computeClient, error) {
+		return client, nil
+	}
+	return b.(*backend), config.StorageView, key, client
+}
+
+func testToken(t *testing.T, key *rsa.PrivateKey, keyID string, claims jws.Claims) string {
+	token := jws.NewJWT(claims, crypto.SigningMethodRS256)
+	token.(jws.JWS).Protected().Set("kid", keyID)
+	serialized, err := token.Serialize(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(serialized)
+}
+
+func testIAMClaims(role string) jws.Claims {
+	claims := jws.Claims{}
+	claims.SetSubject(testServiceAccount)
+	claims.SetAudience("vault/" + role)
+	claims.SetExpiration(time.Now().Add(10 * time.Minute))
+	return claims
+}
+
+func testGCEClaims(role string) jws.Claims {
+	claims := jws.Claims{}
+	claims.SetIssuer("https://accounts.google.com")
+	claims.SetAudience("vault/" + role)
+	claims.SetExpiration(time.Now().Add(time.Hour))
+	claims.Set("email", testServiceAccount)
+	claims.Set("google", map[string]interface{}{
+		"compute_engine": map[string]interface{}{
+			"project_id":    "my-project",
+			"zone":          "us-central1-a",
+			"instance_name": "web-1",
+			"instance_id":   "1234",
+		},
+	})
+	return claims
+}
+
+func testRequest(t *testing.T, b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   s,
+		Data:      data,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: path: %s, resp: %#v, err: %v", path, resp, err)
+	}
+	return resp
+}
+
+func testLogin(t *testing.T, b *backend, s logical.Storage, role, token string) *logical.Response {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "login",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"role": role,
+			"jwt":  token,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func testLoginError(t *testing.T, b *backend, s logical.Storage, role, token, reason string) {
+	resp := testLogin(t, b, s, role, token)
+	if resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), reason) {
+		t.Fatalf("expected error containing %q: %#v", reason, resp)
+	}
+}
+
+func TestBackend_role(t *testing.T) {
+	b, s, _, _ := testBackend(t)
+
+	for _, data := range []map[string]interface{}{
+		{"type": "other", "bound_projects": "my-project"},
+		{"type": "iam"},
+		{"type": "iam", "bound_projects": "my-project", "bound_zones": "us-central1-a"},
+		{"type": "gce", "bound_labels": "env"},
+	} {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "role/bad",
+			Storage:   s,
+			Data:      data,
+		})
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected error for %v: resp: %#v, err: %v", data, resp, err)
+		}
+	}
+
+	testRequest(t, b, s, logical.CreateOperation, "role/web", map[string]interface{}{
+		"type":         "gce",
+		"policies":     "web",
+		"bound_labels": "team:web,env:prod",
+	})
+	resp := testRequest(t, b, s, logical.ReadOperation, "role/web", nil)
+	if strings.Join(resp.Data["bound_labels"].([]string), ",") != "env:prod,team:web" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if _, ok := resp.Data["max_jwt_exp"]; ok {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/web",
+		Storage:   s,
+		Data:      map[string]interface{}{"type": "iam"},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error changing type: resp: %#v, err: %v", resp, err)
+	}
+
+	testRequest(t, b, s, logical.CreateOperation, "role/app", map[string]interface{}{
+		"type":           "iam",
+		"bound_projects": "my-project",
+	})
+	resp = testRequest(t, b, s, logical.ReadOperation, "role/app", nil)
+	if resp.Data["max_jwt_exp"] != int64(900) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestBackend_loginIAM(t *testing.T) {
+	b, s, key, _ := testBackend(t)
+
+	testRequest(t, b, s, logical.CreateOperation, "role/app", map[string]interface{}{
+		"type":                   "iam",
+		"policies":               "app",
+		"bound_service_accounts": strings.ToUpper(testServiceAccount),
+		"bound_projects":         "my-project",
+		"ttl":                    "30m",
+	})
+
+	resp := testLogin(t, b, s, "app", testToken(t, key, "sa", testIAMClaims("app")))
+	if resp == nil || resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp.Auth.TTL != 30*time.Minute || resp.Auth.Metadata["project_id"] != "my-project" || resp.Auth.DisplayName != testServiceAccount {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+
+	// The audience binds the JWT to the role
+	testLoginError(t, b, s, "app", testToken(t, key, "sa", testIAMClaims("other")), "invalid token")
+
+	claims := testIAMClaims("app")
+	claims.SetExpiration(time.Now().Add(time.Hour))
+	testLoginError(t, b, s, "app", testToken(t, key, "sa", claims), "in the future")
+
+	claims = testIAMClaims("app")
+	claims.RemoveExpiration()
+	testLoginError(t, b, s, "app", testToken(t, key, "sa", claims), "no expiration")
+
+	// Keys of Google do not verify JWTs of service accounts
+	testLoginError(t, b, s, "app", testToken(t, key, "google", testIAMClaims("app")), "unknown signing key")
+
+	// The keys of service accounts that are not allowed by the role are not
+	// fetched
+	fetchKeys := b.fetchKeys
+	var fetched []string
+	b.fetchKeys = func(u string) (map[string]*rsa.PublicKey, error) {
+		fetched = append(fetched, u)
+		return fetchKeys(u)
+	}
+	claims = testIAMClaims("app")
+	claims.SetSubject("other@my-project.iam.gserviceaccount.com")
+	testLoginError(t, b, s, "app", testToken(t, key, "sa", claims), "not allowed")
+	claims.SetSubject("app@other-project.iam.gserviceaccount.com")
+	testLoginError(t, b, s, "app", testToken(t, key, "sa", claims), "not allowed")
+	if len(fetched) != 0 {
+		t.Fatalf("bad: %v", fetched)
+	}
+
+	testRequest(t, b, s, logical.CreateOperation, "role/project", map[string]interface{}{
+		"type":           "iam",
+		"bound_projects": "my-project",
+	})
+	claims = testIAMClaims("project")
+	claims.SetSubject("other@my-project.iam.gserviceaccount.com")
+	testLoginError(t, b, s, "project", testToken(t, key, "sa", claims), "unknown signing key")
+	if len(fetched) != 1 || fetched[0] != serviceAccountCertsURL("other@my-project.iam.gserviceaccount.com") {
+		t.Fatalf("bad: %v", fetched)
+	}
+}
+
+func TestBackend_keySets(t *testing.T) {
+	b, _, key, _ := testBackend(t)
+
+	// Fetching a key set does not hold up the lookup of others
+	fetchKeys := b.fetchKeys
+	started := make(chan struct{})
+	release := make(chan struct{})
+	b.fetchKeys = func(u string) (map[string]*rsa.PublicKey, error) {
+		if u == serviceAccountCertsURL(testServiceAccount) {
+			close(started)
+			<-release
+		}
+		return fetchKeys(u)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := b.signingKey(serviceAccountCertsURL(testServiceAccount), "sa")
+		done <- err
+	}()
+	<-started
+	if k, err := b.signingKey(googleCertsURL, "google"); err != nil || k.N.Cmp(key.N) != 0 {
+		t.Fatalf("bad: key: %v, err: %v", k, err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// Expired key sets are dropped, and the oldest ones once the cache is
+	// full
+	b.keySetsLock.Lock()
+	b.keySets = map[string]*keySet{
+		"expired": &keySet{fetchedAt: time.Now().Add(-keysCacheTTL)},
+		"oldest":  &keySet{fetchedAt: time.Now().Add(-time.Minute)},
+	}
+	for i := len(b.keySets); i < keySetsMaxCount; i++ {
+		b.keySets[fmt.Sprintf("url-%d", i)] = &keySet{fetchedAt: time.Now()}
+	}
+	b.keySetsLock.Unlock()
+
+	b.storeKeySet("new", &keySet{fetchedAt: time.Now()})
+	if _, ok := b.keySets["expired"]; ok || len(b.keySets) != keySetsMaxCount {
+		t.Fatalf("bad: %d", len(b.keySets))
+	}
+	b.storeKeySet("newer", &keySet{fetchedAt: time.Now()})
+	if _, ok := b.keySets["oldest"]; ok || len(b.keySets) != keySetsMaxCount {
+		t.Fatalf("bad: %d", len(b.keySets))
+	}
+	if _, ok := b.keySets["newer"]; !ok {
+		t.Fatal("expected the key set to be cached")
+	}
+}
+
+func TestBackend_loginGCE(t *testing.T) {
+	b, s, key, client := testBackend(t)
+
+	testRequest(t, b, s, logical.CreateOperation, "role/web", map[string]interface{}{
+		"type":           "gce",
+		"policies":       "web",
+		"bound_projects": "my-project",
+		"bound_zones":    "us-central1-a,us-central1-b",
+		"bound_labels":   "env:prod",
+	})
+
+	resp := testLogin(t, b, s, "web", testToken(t, key, "google", testGCEClaims("web")))
+	if resp == nil || resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp.Auth.Metadata["instance_name"] != "web-1" || resp.Auth.Metadata["zone"] != "us-central1-a" || resp.Auth.DisplayName != "web-1" {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+
+	// Renewal checks the labels again
+	renewReq := &logical.Request{
+		Operation: logical.RenewOperation,
+		Path:      "login",
+		Storage:   s,
+		Auth:      resp.Auth,
+	}
+	renewReq.Auth.IssueTime = time.Now()
+	if resp, err := b.HandleRequest(renewReq); err != nil || resp == nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	client.instances["my-project/us-central1-a/web-1"].Labels["env"] = "dev"
+	if _, err := b.HandleRequest(renewReq); err == nil {
+		t.Fatalf("expected renewal to fail")
+	}
+	testLoginError(t, b, s, "web", testToken(t, key, "google", testGCEClaims("web")), "does not have label")
+	client.instances["my-project/us-central1-a/web-1"].Labels["env"] = "prod"
+
+	claims := testGCEClaims("web")
+	claims.SetIssuer("https://example.com")
+	testLoginError(t, b, s, "web", testToken(t, key, "google", claims), "not issued by Google")
+
+	claims = testGCEClaims("web")
+	claims.Del("google")
+	testLoginError(t, b, s, "web", testToken(t, key, "google", claims), "format=full")
+
+	claims = testGCEClaims("web")
+	claims.Set("google", map[string]interface{}{
+		"compute_engine": map[string]interface{}{
+			"project_id":    "my-project",
+			"zone":          "europe-west1-b",
+			"instance_name": "web-1",
+		},
+	})
+	testLoginError(t, b, s, "web", testToken(t, key, "google", claims), "zone")
+
+	// Instance identity tokens do not verify for iam roles
+	testRequest(t, b, s, logical.CreateOperation, "role/app", map[string]interface{}{
+		"type":           "iam",
+		"bound_projects": "my-project",
+	})
+	testLoginError(t, b, s, "app", testToken(t, key, "google", testGCEClaims("app")), "subject")
+}
@@ -0,0 +1,194 @@
+package gcp
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/helper/locksutil"
+)
+
+const (
+	// googleCertsURL publishes the keys Google signs instance identity
+	// tokens with
+	googleCertsURL = "https://www.googleapis.com/oauth2/v3/certs"
+
+	// serviceAccountCertsURLFormat publishes the public keys of the keys of
+	// a service account, which sign the JWTs of the IAM signJwt API
+	serviceAccountCertsURLFormat = "https://www.googleapis.com/service_accounts/v1/jwk/%s"
+
+	// keysCacheTTL is how long fetched keys are used before they are
+	// fetched again. Keys are refetched sooner when a token references an
+	// unknown key, since keys are rotated and service account keys are
+	// created at any time.
+	keysCacheTTL = time.Hour
+
+	// keysMinRefreshInterval bounds how often unknown key IDs cause keys to
+	// be fetched again
+	keysMinRefreshInterval = time.Minute
+
+	// keysFetchTimeout bounds the download of a key set
+	keysFetchTimeout = 30 * time.Second
+
+	// keySetsMaxCount bounds the number of cached key sets, of which there
+	// is one per service account logging in
+	keySetsMaxCount = 1024
+)
+
+// keySet is a cached JSON web key set
+type keySet struct {
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func serviceAccountCertsURL(email string) string {
+	return fmt.Sprintf(serviceAccountCertsURLFormat, url.PathEscape(email))
+}
+
+// cachedSigningKey looks up the key with the given ID in the cached key set
+// of the URL. It returns false if the key set needs to be fetched.
+func (b *backend) cachedSigningKey(keysURL, keyID string) (*rsa.PublicKey, bool, error) {
+	b.keySetsLock.Lock()
+	defer b.keySetsLock.Unlock()
+
+	set, ok := b.keySets[keysURL]
+	if !ok {
+		return nil, false, nil
+	}
+	age := time.Since(set.fetchedAt)
+	if key, ok := set.keys[keyID]; ok && age < keysCacheTTL {
+		return key, true, nil
+	}
+	if age < keysMinRefreshInterval {
+		return nil, true, fmt.Errorf("unknown signing key %q", keyID)
+	}
+	return nil, false, nil
+}
+
+// storeKeySet caches the key set of the URL. Expired key sets are dropped,
+// and the oldest ones once there are too many.
+func (b *backend) storeKeySet(keysURL string, set *keySet) {
+	b.keySetsLock.Lock()
+	defer b.keySetsLock.Unlock()
+
+	var oldestURL string
+	var oldest time.Time
+	for u, cached := range b.keySets {
+		if time.Since(cached.fetchedAt) >= keysCacheTTL {
+			delete(b.keySets, u)
+			continue
+		}
+		if oldestURL == "" || cached.fetchedAt.Before(oldest) {
+			oldestURL, oldest = u, cached.fetchedAt
+		}
+	}
+	if _, ok := b.keySets[keysURL]; !ok && len(b.keySets) >= keySetsMaxCount {
+		delete(b.keySets, oldestURL)
+	}
+
+	b.keySets[keysURL] = set
+}
+
+// signingKey returns the key with the given ID from the key set at the URL
+func (b *backend) signingKey(keysURL, keyID string) (*rsa.PublicKey, error) {
+	if key, ok, err := b.cachedSigningKey(keysURL, keyID); ok {
+		return key, err
+	}
+
+	// Key sets are fetched without holding the cache lock so that a slow
+	// fetch does not hold up logins using other key sets; concurrent
+	// fetches of the same key set are serialized
+	lock := locksutil.LockForKey(b.keySetLocks, keysURL)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// The key set may have been fetched while waiting for the lock
+	if key, ok, err := b.cachedSigningKey(keysURL, keyID); ok {
+		return key, err
+	}
+
+	keys, err := b.fetchKeys(keysURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %v", err)
+	}
+	b.storeKeySet(keysURL, &keySet{
+		keys:      keys,
+		fetchedAt: time.Now(),
+	})
+
+	key, ok := keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", keyID)
+	}
+	return key, nil
+}
+
+// jsonWebKey is an RSA key of a JSON web key set
+type jsonWebKey struct {
+	KeyID   string `json:"kid"`
+	KeyType string `json:"kty"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+// fetchSigningKeys fetches the JSON web key set at the URL
+func fetchSigningKeys(keysURL string) (map[string]*rsa.PublicKey, error) {
+	client := cleanhttp.DefaultClient()
+	client.Timeout = keysFetchTimeout
+
+	resp, err := client.Get(keysURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode key set: %v", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.KeyType != "RSA" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %v", jwk.KeyID, err)
+		}
+		keys[jwk.KeyID] = key
+	}
+	return keys, nil
+}
+
+func (k *jsonWebKey) publicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > int64(^uint32(0)>>1) {
+		return nil, fmt.Errorf("invalid exponent")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
@@ -0,0 +1,105 @@
+package gcp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/api"
+)
+
+// metadataIdentityURL is the endpoint of the metadata server of Compute
+// Engine instances that issues identity tokens
+const metadataIdentityURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/identity"
+
+type CLIHandler struct{}
+
+func (h *CLIHandler) Auth(c *api.Client, m map[string]string) (string, error) {
+	mount, ok := m["mount"]
+	if !ok {
+		mount = "gcp"
+	}
+
+	role, ok := m["role"]
+	if !ok {
+		return "", fmt.Errorf("'role' must be specified")
+	}
+
+	token, ok := m["jwt"]
+	if !ok {
+		var err error
+		if token, err = fetchIdentityToken(audience(role)); err != nil {
+			return "", err
+		}
+	}
+
+	path := fmt.Sprintf("auth/%s/login", mount)
+	secret, err := c.Logical().Write(path, map[string]interface{}{
+		"role": role,
+		"jwt":  token,
+	})
+	if err != nil {
+		return "", err
+	}
+	if secret == nil {
+		return "", fmt.Errorf("empty response from credential provider")
+	}
+
+	return secret.Auth.ClientToken, nil
+}
+
+// fetchIdentityToken obtains an identity token of the instance from its
+// metadata server
+func fetchIdentityToken(aud string) (string, error) {
+	params := url.Values{
+		"audience": {aud},
+		"format":   {"full"},
+	}
+
+	req, err := http.NewRequest("GET", metadataIdentityURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := cleanhttp.DefaultClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach the metadata server: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to obtain an identity token from the metadata server: %s", strings.TrimSpace(string(body)))
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+func (h *CLIHandler) Help() string {
+	help := `
+The GCP credential provider allows Google Cloud service accounts and Compute
+Engine instances to authenticate. On an instance, the identity token of the
+instance is obtained from its metadata server; service accounts must provide
+a JWT signed through the IAM signJwt API with the audience "vault/<role>".
+
+    Example: vault auth -method=gcp role=<role>
+
+Key/Value Pairs:
+
+    mount=gcp          The mountpoint for the GCP credential provider.
+                       Defaults to "gcp"
+
+    role=<string>      The role to log in with.
+
+    jwt=<string>       A signed JWT or identity token to log in with instead
+                       of obtaining one from the metadata server.
+	`
+
+	return strings.TrimSpace(help)
+}
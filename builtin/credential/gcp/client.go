@@ -0,0 +1,88 @@
+package gcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	computeReadOnlyScope = "https://www.googleapis.com/auth/compute.readonly"
+
+	computeEndpoint = "https://www.googleapis.com/compute/v1/"
+)
+
+// instance is the subset of a Compute Engine instance used by the backend
+type instance struct {
+	Name   string            `json:"name"`
+	Status string            `json:"status"`
+	Labels map[string]string `json:"labels"`
+}
+
+// computeClient is the subset of the Compute Engine API used by the backend
+type computeClient interface {
+	// Instance returns the instance, or nil if it does not exist
+	Instance(project, zone, name string) (*instance, error)
+}
+
+// httpComputeClient implements computeClient over the Compute Engine REST API
+type httpComputeClient struct {
+	client *http.Client
+}
+
+// newHTTPComputeClient returns a client using the given service account
+// credentials, or the application default credentials if none are given
+func newHTTPComputeClient(credentials string) (*httpComputeClient, error) {
+	ctx := context.Background()
+	if credentials == "" {
+		client, err := google.DefaultClient(ctx, computeReadOnlyScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load default credentials: %v", err)
+		}
+		return &httpComputeClient{client: client}, nil
+	}
+
+	jwtConfig, err := google.JWTConfigFromJSON([]byte(credentials), computeReadOnlyScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse credentials: %v", err)
+	}
+	return &httpComputeClient{client: jwtConfig.Client(ctx)}, nil
+}
+
+func (c *httpComputeClient) Instance(project, zone, name string) (*instance, error) {
+	u := fmt.Sprintf("%sprojects/%s/zones/%s/instances/%s", computeEndpoint,
+		url.PathEscape(project), url.PathEscape(zone), url.PathEscape(name))
+	resp, err := c.client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(body, &errResp)
+		return nil, fmt.Errorf("googleapi: error %d: %s", resp.StatusCode, errResp.Error.Message)
+	}
+
+	var out instance
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,94 @@
+package gcp
+
+import (
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config",
+		Fields: map[string]*framework.FieldSchema{
+			"credentials": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "JSON credentials file of the service account used to look up the labels of instances. If not set, the application default credentials are used.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+type configEntry struct {
+	Credentials string `json:"credentials"`
+}
+
+// Config returns the configuration of the backend; an empty configuration is
+// returned if none has been written
+func (b *backend) Config(s logical.Storage) (*configEntry, error) {
+	config := &configEntry{}
+	entry, err := s.Get("config")
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		if err := entry.DecodeJSON(config); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func (b *backend) pathConfigRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// The credentials are never returned
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"credentials_set": config.Credentials != "",
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if v, ok := d.GetOk("credentials"); ok {
+		config.Credentials = v.(string)
+	}
+
+	entry, err := logical.StorageEntryJSON("config", config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	b.resetClient()
+	return nil, nil
+}
+
+const pathConfigHelpSyn = `
+Configure the GCP credentials used by the backend.
+`
+
+const pathConfigHelpDesc = `
+The credentials are only needed for roles bound to instance labels, which are
+looked up through the Compute Engine API. The service account needs the
+compute.instances.get permission in the projects of the instances.
+`
@@ -0,0 +1,384 @@
+package gcp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SermoDigital/jose/crypto"
+	"github.com/SermoDigital/jose/jws"
+	"github.com/SermoDigital/jose/jwt"
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	// clockSkewLeeway is the leeway allowed when checking the expiration
+	// and not-before times of tokens
+	clockSkewLeeway = time.Minute
+
+	// serviceAccountDomain is the domain of the emails of service accounts
+	// created in projects, which are of the form <name>@<project>.<domain>
+	serviceAccountDomain = ".iam.gserviceaccount.com"
+)
+
+func pathLogin(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "login$",
+		Fields: map[string]*framework.FieldSchema{
+			"role": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role to log in with.",
+			},
+			"jwt": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `JWT signed by the service account, or identity token of the instance. Its audience must be "vault/<role>".`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathLogin,
+		},
+
+		HelpSynopsis:    pathLoginHelpSyn,
+		HelpDescription: pathLoginHelpDesc,
+	}
+}
+
+// identity describes the service account or instance that logs in
+type identity struct {
+	ServiceAccount string
+	ProjectID      string
+
+	// Zone, InstanceName and InstanceID are only set for instances
+	Zone         string
+	InstanceName string
+	InstanceID   string
+}
+
+// metadata returns the token metadata describing the identity
+func (id *identity) metadata() map[string]string {
+	m := map[string]string{
+		"service_account_email": id.ServiceAccount,
+		"project_id":            id.ProjectID,
+	}
+	if id.InstanceName != "" {
+		m["zone"] = id.Zone
+		m["instance_name"] = id.InstanceName
+		m["instance_id"] = id.InstanceID
+	}
+	return m
+}
+
+// identityFromMetadata returns the identity described by token metadata
+func identityFromMetadata(m map[string]string) *identity {
+	return &identity{
+		ServiceAccount: m["service_account_email"],
+		ProjectID:      m["project_id"],
+		Zone:           m["zone"],
+		InstanceName:   m["instance_name"],
+		InstanceID:     m["instance_id"],
+	}
+}
+
+// projectFromEmail returns the project of a service account created in a
+// project, or an empty string for other service accounts
+func projectFromEmail(email string) string {
+	i := strings.LastIndex(email, "@")
+	if i < 0 || !strings.HasSuffix(email, serviceAccountDomain) {
+		return ""
+	}
+	return strings.TrimSuffix(email[i+1:], serviceAccountDomain)
+}
+
+// audience is the audience of the tokens used to log in with the role
+func audience(roleName string) string {
+	return "vault/" + roleName
+}
+
+// verifyToken verifies the signature and the registered claims of the token
+// against the key set at the URL returned for its claims by keysURL
+func (b *backend) verifyToken(token string, keysURL func(jwt.Claims) (string, error), validator *jwt.Validator) (jwt.Claims, error) {
+	parsed, err := jws.ParseJWT([]byte(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token: %v", err)
+	}
+
+	keyID, _ := parsed.(jws.JWS).Protected().Get("kid").(string)
+	if keyID == "" {
+		return nil, fmt.Errorf("token does not reference a signing key")
+	}
+	u, err := keysURL(parsed.Claims())
+	if err != nil {
+		return nil, err
+	}
+	key, err := b.signingKey(u, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	validator.EXP = clockSkewLeeway
+	validator.NBF = clockSkewLeeway
+	if err := parsed.Validate(key, crypto.SigningMethodRS256, validator); err != nil {
+		return nil, fmt.Errorf("invalid token: %v", err)
+	}
+
+	claims := parsed.Claims()
+	if _, ok := claims.Expiration(); !ok {
+		return nil, fmt.Errorf("token has no expiration time")
+	}
+	return claims, nil
+}
+
+// verifyIAMToken verifies a JWT signed by a service account through the IAM
+// signJwt API and returns the service account
+func (b *backend) verifyIAMToken(roleName string, role *roleEntry, token string) (*identity, error) {
+	expected := jwt.Claims{}
+	expected.SetAudience(audience(roleName))
+
+	// The subject is checked against the role before its keys are fetched,
+	// so that unverified tokens cannot make Vault fetch the keys of any
+	// service account
+	serviceAccountKeys := func(c jwt.Claims) (string, error) {
+		sub, ok := c.Subject()
+		if !ok || sub == "" {
+			return "", fmt.Errorf("token has no subject")
+		}
+		email := strings.ToLower(sub)
+		if err := checkServiceAccountBindings(role, &identity{
+			ServiceAccount: email,
+			ProjectID:      projectFromEmail(email),
+		}); err != nil {
+			return "", err
+		}
+		return serviceAccountCertsURL(sub), nil
+	}
+
+	claims, err := b.verifyToken(token, serviceAccountKeys, &jwt.Validator{Expected: expected})
+	if err != nil {
+		return nil, err
+	}
+
+	// Service accounts choose the expiration of their JWTs, so it is bounded
+	// to limit how long a leaked JWT can be used
+	exp, _ := claims.Expiration()
+	if exp.After(time.Now().Add(role.MaxJWTExp + clockSkewLeeway)) {
+		return nil, fmt.Errorf("token expires more than %s in the future", role.MaxJWTExp)
+	}
+
+	sub, _ := claims.Subject()
+	email := strings.ToLower(sub)
+	return &identity{
+		ServiceAccount: email,
+		ProjectID:      projectFromEmail(email),
+	}, nil
+}
+
+// verifyGCEToken verifies an identity token of a Compute Engine instance and
+// returns the instance
+func (b *backend) verifyGCEToken(roleName string, token string) (*identity, error) {
+	expected := jwt.Claims{}
+	expected.SetAudience(audience(roleName))
+	validator := &jwt.Validator{
+		Expected: expected,
+		Fn: func(c jwt.Claims) error {
+			if iss, _ := c.Issuer(); iss != "https://accounts.google.com" && iss != "accounts.google.com" {
+				return fmt.Errorf("token was not issued by Google")
+			}
+			return nil
+		},
+	}
+
+	googleKeys := func(jwt.Claims) (string, error) {
+		return googleCertsURL, nil
+	}
+
+	claims, err := b.verifyToken(token, googleKeys, validator)
+	if err != nil {
+		return nil, err
+	}
+
+	// Identity tokens requested with the full format describe the instance
+	google, _ := claims.Get("google").(map[string]interface{})
+	computeEngine, _ := google["compute_engine"].(map[string]interface{})
+	if computeEngine == nil {
+		return nil, fmt.Errorf("token does not describe a Compute Engine instance; request it with format=full")
+	}
+
+	id := &identity{}
+	email, _ := claims.Get("email").(string)
+	id.ServiceAccount = strings.ToLower(email)
+	id.ProjectID, _ = computeEngine["project_id"].(string)
+	id.Zone, _ = computeEngine["zone"].(string)
+	id.InstanceName, _ = computeEngine["instance_name"].(string)
+	id.InstanceID, _ = computeEngine["instance_id"].(string)
+	if id.ProjectID == "" || id.Zone == "" || id.InstanceName == "" {
+		return nil, fmt.Errorf("token does not describe a Compute Engine instance")
+	}
+	return id, nil
+}
+
+// checkServiceAccountBindings returns an error if the service account or
+// the project of the identity is not allowed by the role
+func checkServiceAccountBindings(role *roleEntry, id *identity) error {
+	if len(role.BoundServiceAccounts) > 0 && !strutil.StrListContains(role.BoundServiceAccounts, id.ServiceAccount) {
+		return fmt.Errorf("service account %q is not allowed by the role", id.ServiceAccount)
+	}
+	if len(role.BoundProjects) > 0 && !strutil.StrListContains(role.BoundProjects, id.ProjectID) {
+		return fmt.Errorf("project %q is not allowed by the role", id.ProjectID)
+	}
+	return nil
+}
+
+// checkBindings returns an error if the identity does not meet the bound
+// constraints of the role
+func (b *backend) checkBindings(s logical.Storage, role *roleEntry, id *identity) error {
+	if err := checkServiceAccountBindings(role, id); err != nil {
+		return err
+	}
+	if len(role.BoundZones) > 0 && !strutil.StrListContains(role.BoundZones, id.Zone) {
+		return fmt.Errorf("zone %q is not allowed by the role", id.Zone)
+	}
+
+	if len(role.BoundLabels) > 0 {
+		client, err := b.Client(s)
+		if err != nil {
+			return err
+		}
+		inst, err := client.Instance(id.ProjectID, id.Zone, id.InstanceName)
+		if err != nil {
+			return fmt.Errorf("failed to look up instance %q: %v", id.InstanceName, err)
+		}
+		if inst == nil {
+			return fmt.Errorf("instance %q does not exist", id.InstanceName)
+		}
+		for k, v := range role.BoundLabels {
+			if actual, ok := inst.Labels[k]; !ok || actual != v {
+				return fmt.Errorf("instance %q does not have label %s:%s", id.InstanceName, k, v)
+			}
+		}
+	}
+	return nil
+}
+
+func (b *backend) pathLogin(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName := d.Get("role").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role"), nil
+	}
+	token := d.Get("jwt").(string)
+	if token == "" {
+		return logical.ErrorResponse("missing jwt"), nil
+	}
+
+	role, err := b.Role(req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid role %q", roleName)), nil
+	}
+
+	var id *identity
+	switch role.Type {
+	case roleTypeIAM:
+		id, err = b.verifyIAMToken(roleName, role, token)
+	case roleTypeGCE:
+		id, err = b.verifyGCEToken(roleName, token)
+	default:
+		return nil, fmt.Errorf("role %q has invalid type %q", roleName, role.Type)
+	}
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if err := b.checkBindings(req.Storage, role, id); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	metadata := id.metadata()
+	metadata["role"] = roleName
+
+	displayName := id.ServiceAccount
+	if id.InstanceName != "" {
+		displayName = id.InstanceName
+	}
+
+	auth := &logical.Auth{
+		Period: role.Period,
+		InternalData: map[string]interface{}{
+			"role": roleName,
+		},
+		Metadata:    metadata,
+		Policies:    role.Policies,
+		DisplayName: displayName,
+		LeaseOptions: logical.LeaseOptions{
+			Renewable: true,
+		},
+	}
+
+	// If 'Period' is set, use the value of 'Period' as the TTL.
+	// Otherwise, set the normal TTL.
+	if role.Period > time.Duration(0) {
+		auth.TTL = role.Period
+	} else {
+		auth.TTL = role.TTL
+	}
+
+	return &logical.Response{
+		Auth: auth,
+	}, nil
+}
+
+func (b *backend) pathLoginRenew(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if req.Auth == nil {
+		return nil, fmt.Errorf("request auth was nil")
+	}
+
+	roleName, _ := req.Auth.InternalData["role"].(string)
+	if roleName == "" {
+		return nil, fmt.Errorf("failed to fetch role during renewal")
+	}
+
+	// Ensure that the role still exists and still allows the service
+	// account or instance, which must still have the bound labels
+	role, err := b.Role(req.Storage, roleName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate role %q during renewal: %v", roleName, err)
+	}
+	if role == nil {
+		return nil, fmt.Errorf("role %q does not exist during renewal", roleName)
+	}
+	if err := b.checkBindings(req.Storage, role, identityFromMetadata(req.Auth.Metadata)); err != nil {
+		return nil, err
+	}
+	if !policyutil.EquivalentPolicies(role.Policies, req.Auth.Policies) {
+		return nil, fmt.Errorf("policies have changed, not renewing")
+	}
+
+	// If 'Period' is set on the role, the token should never expire.
+	// Replenish the TTL with 'Period's value.
+	if role.Period > time.Duration(0) {
+		req.Auth.TTL = role.Period
+		return &logical.Response{Auth: req.Auth}, nil
+	}
+	return framework.LeaseExtend(role.TTL, role.MaxTTL, b.System())(req, d)
+}
+
+const pathLoginHelpSyn = `
+Authenticates a GCP service account or Compute Engine instance with Vault.
+`
+
+const pathLoginHelpDesc = `
+Service accounts log in to roles of type "iam" with a JWT they signed through
+the IAM signJwt API. Its subject must be the email of the service account,
+its audience "vault/<role>", and it must expire within the role's
+max_jwt_exp.
+
+Compute Engine instances log in to roles of type "gce" with the identity
+token of their metadata server, requested with the audience "vault/<role>"
+and the "full" format so that it describes the instance.
+`
@@ -0,0 +1,316 @@
+package gcp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	roleTypeIAM = "iam"
+	roleTypeGCE = "gce"
+
+	// defaultMaxJWTExp is the default limit on how far in the future the
+	// expiration of the JWTs of iam roles may be
+	defaultMaxJWTExp = 15 * time.Minute
+)
+
+func pathListRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/?$",
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+func pathRole(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+			"type": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Type of the role, either "iam" for service accounts logging in with a signed JWT, or "gce" for Compute Engine instances logging in with an identity token. Cannot be changed.`,
+			},
+			"policies": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Comma-separated list of policies of the tokens issued for the role.",
+			},
+			"ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Default TTL of the tokens issued for the role. Defaults to the mount's default TTL.",
+			},
+			"max_ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Maximum TTL of the tokens issued for the role. Defaults to the mount's maximum TTL.",
+			},
+			"period": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "If set, tokens issued for the role are periodic and renewed for this long each time.",
+			},
+			"bound_service_accounts": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Comma-separated list of emails of the service accounts allowed to log in. For gce roles, this is the service account of the instance.",
+			},
+			"bound_projects": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Comma-separated list of IDs of the projects whose service accounts or instances are allowed to log in.",
+			},
+			"bound_zones": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Comma-separated list of zones of the instances allowed to log in. Only valid for gce roles.",
+			},
+			"bound_labels": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: `Comma-separated list of "key:value" labels that instances must have to log in. Only valid for gce roles.`,
+			},
+			"max_jwt_exp": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "How far in the future the expiration of the JWTs of service accounts may be. Only valid for iam roles. Defaults to 15 minutes.",
+			},
+		},
+		ExistenceCheck: b.pathRoleExistenceCheck,
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.CreateOperation: b.pathRoleWrite,
+			logical.UpdateOperation: b.pathRoleWrite,
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.DeleteOperation: b.pathRoleDelete,
+		},
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+type roleEntry struct {
+	Type     string        `json:"type"`
+	Policies []string      `json:"policies"`
+	TTL      time.Duration `json:"ttl"`
+	MaxTTL   time.Duration `json:"max_ttl"`
+	Period   time.Duration `json:"period"`
+
+	BoundServiceAccounts []string          `json:"bound_service_accounts"`
+	BoundProjects        []string          `json:"bound_projects"`
+	BoundZones           []string          `json:"bound_zones"`
+	BoundLabels          map[string]string `json:"bound_labels"`
+
+	MaxJWTExp time.Duration `json:"max_jwt_exp"`
+}
+
+// Role reads the role from the storage, returning nil if it does not exist
+func (b *backend) Role(s logical.Storage, name string) (*roleEntry, error) {
+	entry, err := s.Get("role/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var role roleEntry
+	if err := entry.DecodeJSON(&role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (b *backend) pathRoleExistenceCheck(req *logical.Request, d *framework.FieldData) (bool, error) {
+	role, err := b.Role(req.Storage, d.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return role != nil, nil
+}
+
+func (b *backend) pathRoleList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roles, err := req.Storage.List("role/")
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(roles), nil
+}
+
+func (b *backend) pathRoleRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, err := b.Role(req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	labels := make([]string, 0, len(role.BoundLabels))
+	for k, v := range role.BoundLabels {
+		labels = append(labels, k+":"+v)
+	}
+
+	data := map[string]interface{}{
+		"type":                   role.Type,
+		"policies":               role.Policies,
+		"ttl":                    int64(role.TTL.Seconds()),
+		"max_ttl":                int64(role.MaxTTL.Seconds()),
+		"period":                 int64(role.Period.Seconds()),
+		"bound_service_accounts": role.BoundServiceAccounts,
+		"bound_projects":         role.BoundProjects,
+	}
+	switch role.Type {
+	case roleTypeIAM:
+		data["max_jwt_exp"] = int64(role.MaxJWTExp.Seconds())
+	case roleTypeGCE:
+		data["bound_zones"] = role.BoundZones
+		sort.Strings(labels)
+		data["bound_labels"] = labels
+	}
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+func (b *backend) pathRoleDelete(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return nil, req.Storage.Delete("role/" + d.Get("name").(string))
+}
+
+func (b *backend) pathRoleWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	role, err := b.Role(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		role = &roleEntry{
+			Type: d.Get("type").(string),
+		}
+		switch role.Type {
+		case roleTypeIAM:
+			role.MaxJWTExp = defaultMaxJWTExp
+		case roleTypeGCE:
+		default:
+			return logical.ErrorResponse(fmt.Sprintf("type must be %q or %q", roleTypeIAM, roleTypeGCE)), nil
+		}
+	} else if t, ok := d.GetOk("type"); ok && t.(string) != role.Type {
+		return logical.ErrorResponse("the type of a role cannot be changed"), nil
+	}
+
+	if policiesRaw, ok := d.GetOk("policies"); ok {
+		role.Policies = policyutil.SanitizePolicies(policiesRaw.([]string), true)
+	}
+	if ttlRaw, ok := d.GetOk("ttl"); ok {
+		role.TTL = time.Duration(ttlRaw.(int)) * time.Second
+	}
+	if maxTTLRaw, ok := d.GetOk("max_ttl"); ok {
+		role.MaxTTL = time.Duration(maxTTLRaw.(int)) * time.Second
+	}
+	if periodRaw, ok := d.GetOk("period"); ok {
+		role.Period = time.Duration(periodRaw.(int)) * time.Second
+	}
+	if role.TTL < 0 || role.MaxTTL < 0 || role.Period < 0 {
+		return logical.ErrorResponse("ttl, max_ttl and period cannot be negative"), nil
+	}
+	if role.MaxTTL > 0 && role.TTL > role.MaxTTL {
+		return logical.ErrorResponse("ttl cannot be greater than max_ttl"), nil
+	}
+	if role.Period > b.System().MaxLeaseTTL() {
+		return logical.ErrorResponse("period cannot be greater than the mount's maximum TTL"), nil
+	}
+
+	if v, ok := d.GetOk("bound_service_accounts"); ok {
+		role.BoundServiceAccounts = strutil.RemoveDuplicates(v.([]string), true)
+	}
+	if v, ok := d.GetOk("bound_projects"); ok {
+		role.BoundProjects = strutil.RemoveDuplicates(v.([]string), false)
+	}
+
+	switch role.Type {
+	case roleTypeIAM:
+		for _, field := range []string{"bound_zones", "bound_labels"} {
+			if _, ok := d.GetOk(field); ok {
+				return logical.ErrorResponse(fmt.Sprintf("%s is only valid for gce roles", field)), nil
+			}
+		}
+		if v, ok := d.GetOk("max_jwt_exp"); ok {
+			role.MaxJWTExp = time.Duration(v.(int)) * time.Second
+		}
+		if role.MaxJWTExp <= 0 {
+			return logical.ErrorResponse("max_jwt_exp must be positive"), nil
+		}
+		if len(role.BoundServiceAccounts) == 0 && len(role.BoundProjects) == 0 {
+			return logical.ErrorResponse("iam roles must set bound_service_accounts or bound_projects"), nil
+		}
+
+	case roleTypeGCE:
+		if _, ok := d.GetOk("max_jwt_exp"); ok {
+			return logical.ErrorResponse("max_jwt_exp is only valid for iam roles"), nil
+		}
+		if v, ok := d.GetOk("bound_zones"); ok {
+			role.BoundZones = strutil.RemoveDuplicates(v.([]string), false)
+		}
+		if v, ok := d.GetOk("bound_labels"); ok {
+			labels, err := parseLabels(v.([]string))
+			if err != nil {
+				return logical.ErrorResponse(err.Error()), nil
+			}
+			role.BoundLabels = labels
+		}
+		if len(role.BoundServiceAccounts) == 0 && len(role.BoundProjects) == 0 &&
+			len(role.BoundZones) == 0 && len(role.BoundLabels) == 0 {
+			return logical.ErrorResponse("at least one bound constraint must be set on the role"), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON("role/"+name, role)
+	if err != nil {
+		return nil, err
+	}
+	return nil, req.Storage.Put(entry)
+}
+
+// parseLabels parses a list of "key:value" labels
+func parseLabels(list []string) (map[string]string, error) {
+	labels := make(map[string]string, len(list))
+	for _, label := range list {
+		parts := strings.SplitN(label, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid label %q, must be of the form key:value", label)
+		}
+		labels[parts[0]] = parts[1]
+	}
+	return labels, nil
+}
+
+const pathRoleHelpSyn = `
+Manage the roles service accounts and instances log in with.
+`
+
+const pathRoleHelpDesc = `
+A role defines the policies and TTLs of the tokens issued to the service
+accounts or instances logging in with it, and constrains which of them may do
+so. Every constraint that is set must be met.
+
+Roles of type "iam" are used by service accounts, which log in with a JWT
+signed through the IAM signJwt API. They are constrained by the email of the
+service account and by its project; the project of a service account is taken
+from its email, so the default service accounts of Compute Engine and App
+Engine cannot be bound by project.
+
+Roles of type "gce" are used by Compute Engine instances, which log in with
+an identity token from their metadata server. They are constrained by the
+service account, project and zone of the instance, and by its labels, which
+are looked up through the Compute Engine API.
+`
@@ -34,7 +34,14 @@ func Backend() *backend {
 		DefaultKey: "default",
 	}
 
+	b.TeamSlugMap = &framework.PolicyMap{
+		PathMap: framework.PathMap{
+			Name: "team-slugs",
+		},
+	}
+
 	allPaths := append(b.TeamMap.Paths(), b.UserMap.Paths()...)
+	allPaths = append(allPaths, pathTeamSlugs(&b)...)
 
 	b.Backend = &framework.Backend{
 		Help: backendHelp,
@@ -62,6 +69,10 @@ type backend struct {
 
 	TeamMap *framework.PolicyMap
 
+	// TeamSlugMap maps the slugs of teams, qualified by their organization
+	// as "<org>/<slug>", to policies
+	TeamSlugMap *framework.PolicyMap
+
 	UserMap *framework.PolicyMap
 }
 
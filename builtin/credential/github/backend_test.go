@@ -2,7 +2,11 @@ package github
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -198,3 +202,109 @@ func testAccLogin(t *testing.T, policies []string) logicaltest.TestStep {
 		Check: logicaltest.TestCheckAuth(policies),
 	}
 }
+
+// testGitHubServer fakes the GitHub API for bob, a member of the hashicorp
+// and other organizations, part of the ops team of each
+func testGitHubServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var resp string
+		switch r.URL.Path {
+		case "/api/v3/user":
+			resp = `{"login": "bob"}`
+		case "/api/v3/user/orgs":
+			resp = `[{"id": 1, "login": "hashicorp"}, {"id": 2, "login": "Other"}, {"id": 3, "login": "third"}]`
+		case "/api/v3/user/teams":
+			resp = `[
+				{"name": "Ops", "slug": "ops", "organization": {"id": 1, "login": "hashicorp"}},
+				{"name": "Ops", "slug": "ops", "organization": {"id": 2, "login": "Other"}},
+				{"name": "Dev", "slug": "dev", "organization": {"id": 3, "login": "third"}}
+			]`
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(resp))
+	}))
+}
+
+func TestBackend_organizationsAndTeamSlugs(t *testing.T) {
+	server := testGitHubServer(t)
+	defer server.Close()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+			Storage:   config.StorageView,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		return resp
+	}
+
+	write("config", map[string]interface{}{
+		"organizations": "hashicorp,other",
+		"base_url":      server.URL + "/api/v3",
+	})
+	write("map/teams/ops", map[string]interface{}{"value": "ops-any-org"})
+	write("map/team-slugs/other/ops", map[string]interface{}{"value": "ops-other"})
+	write("map/team-slugs/third/dev", map[string]interface{}{"value": "dev-third"})
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config",
+		Storage:   config.StorageView,
+	})
+	if err != nil || resp.Data["base_url"] != server.URL+"/api/v3/" {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "map/team-slugs/OTHER",
+		Storage:   config.StorageView,
+	})
+	if err != nil || !reflect.DeepEqual(resp.Data["keys"], []string{"ops"}) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	// The teams of the third organization are ignored since it is not
+	// allowed
+	resp = write("login", map[string]interface{}{"token": "token"})
+	sort.Strings(resp.Auth.Policies)
+	if !reflect.DeepEqual(resp.Auth.Policies, []string{"ops-any-org", "ops-other"}) {
+		t.Fatalf("bad: %#v", resp.Auth.Policies)
+	}
+	if resp.Auth.Metadata["org"] != "hashicorp" || resp.Auth.Metadata["orgs"] != "hashicorp,Other" {
+		t.Fatalf("bad: %#v", resp.Auth.Metadata)
+	}
+
+	// Users must be part of one of the allowed organizations
+	write("config", map[string]interface{}{
+		"organization": "nope",
+		"base_url":     server.URL + "/api/v3/",
+	})
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "login",
+		Data:      map[string]interface{}{"token": "token"},
+		Storage:   config.StorageView,
+	})
+	if err != nil || !resp.IsError() {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+}
@@ -3,9 +3,11 @@ package github
 import (
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/fatih/structs"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -19,11 +21,19 @@ func pathConfig(b *backend) *framework.Path {
 				Description: "The organization users must be part of",
 			},
 
+			"organizations": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `The organizations users may be part of;
+users must be part of at least one of them
+or of "organization".`,
+			},
+
 			"base_url": &framework.FieldSchema{
 				Type: framework.TypeString,
 				Description: `The API endpoint to use. Useful if you
 are running GitHub Enterprise or an
-API-compatible authentication server.`,
+API-compatible authentication server.
+Example: https://github.example.com/api/v3/`,
 			},
 			"ttl": &framework.FieldSchema{
 				Type:        framework.TypeString,
@@ -45,12 +55,23 @@ API-compatible authentication server.`,
 func (b *backend) pathConfigWrite(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	organization := data.Get("organization").(string)
+	organizations := data.Get("organizations").([]string)
+
 	baseURL := data.Get("base_url").(string)
 	if len(baseURL) != 0 {
-		_, err := url.Parse(baseURL)
+		parsedURL, err := url.Parse(baseURL)
 		if err != nil {
 			return logical.ErrorResponse(fmt.Sprintf("Error parsing given base_url: %s", err)), nil
 		}
+		if (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+			return logical.ErrorResponse("base_url must be an absolute http or https URL"), nil
+		}
+
+		// The GitHub client resolves API paths relative to the base URL,
+		// which must then end with a slash
+		if !strings.HasSuffix(baseURL, "/") {
+			baseURL += "/"
+		}
 	}
 
 	var ttl time.Duration
@@ -77,10 +98,11 @@ func (b *backend) pathConfigWrite(
 	}
 
 	entry, err := logical.StorageEntryJSON("config", config{
-		Organization: organization,
-		BaseURL:      baseURL,
-		TTL:          ttl,
-		MaxTTL:       maxTTL,
+		Organization:  organization,
+		Organizations: organizations,
+		BaseURL:       baseURL,
+		TTL:           ttl,
+		MaxTTL:        maxTTL,
 	})
 
 	if err != nil {
@@ -131,8 +153,18 @@ func (b *backend) Config(s logical.Storage) (*config, error) {
 }
 
 type config struct {
-	Organization string        `json:"organization" structs:"organization" mapstructure:"organization"`
-	BaseURL      string        `json:"base_url" structs:"base_url" mapstructure:"base_url"`
-	TTL          time.Duration `json:"ttl" structs:"ttl" mapstructure:"ttl"`
-	MaxTTL       time.Duration `json:"max_ttl" structs:"max_ttl" mapstructure:"max_ttl"`
+	Organization  string        `json:"organization" structs:"organization" mapstructure:"organization"`
+	Organizations []string      `json:"organizations" structs:"organizations" mapstructure:"organizations"`
+	BaseURL       string        `json:"base_url" structs:"base_url" mapstructure:"base_url"`
+	TTL           time.Duration `json:"ttl" structs:"ttl" mapstructure:"ttl"`
+	MaxTTL        time.Duration `json:"max_ttl" structs:"max_ttl" mapstructure:"max_ttl"`
+}
+
+// allowedOrganizations returns the organizations users may be part of
+func (c *config) allowedOrganizations() []string {
+	var orgs []string
+	if c.Organization != "" {
+		orgs = append(orgs, c.Organization)
+	}
+	return strutil.RemoveDuplicates(append(orgs, c.Organizations...), true)
 }
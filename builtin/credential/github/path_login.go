@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/go-github/github"
 	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -52,6 +53,11 @@ func (b *backend) pathLogin(
 		return logical.ErrorResponse(fmt.Sprintf("error sanitizing TTLs: %s", err)), nil
 	}
 
+	orgLogins := make([]string, 0, len(verifyResp.Orgs))
+	for _, org := range verifyResp.Orgs {
+		orgLogins = append(orgLogins, *org.Login)
+	}
+
 	return &logical.Response{
 		Auth: &logical.Auth{
 			InternalData: map[string]interface{}{
@@ -60,7 +66,8 @@ func (b *backend) pathLogin(
 			Policies: verifyResp.Policies,
 			Metadata: map[string]string{
 				"username": *verifyResp.User.Login,
-				"org":      *verifyResp.Org.Login,
+				"org":      *verifyResp.Orgs[0].Login,
+				"orgs":     strings.Join(orgLogins, ","),
 			},
 			DisplayName: *verifyResp.User.Login,
 			LeaseOptions: logical.LeaseOptions{
@@ -108,7 +115,8 @@ func (b *backend) verifyCredentials(req *logical.Request, token string) (*verify
 	if err != nil {
 		return nil, nil, err
 	}
-	if config.Organization == "" {
+	allowedOrgs := config.allowedOrganizations()
+	if len(allowedOrgs) == 0 {
 		return nil, logical.ErrorResponse(
 			"configure the github credential backend first"), nil
 	}
@@ -132,8 +140,8 @@ func (b *backend) verifyCredentials(req *logical.Request, token string) (*verify
 		return nil, nil, err
 	}
 
-	// Verify that the user is part of the organization
-	var org *github.Organization
+	// Verify that the user is part of one of the allowed organizations
+	var orgs []*github.Organization
 
 	orgOpt := &github.ListOptions{
 		PerPage: 100,
@@ -153,12 +161,11 @@ func (b *backend) verifyCredentials(req *logical.Request, token string) (*verify
 	}
 
 	for _, o := range allOrgs {
-		if strings.ToLower(*o.Login) == strings.ToLower(config.Organization) {
-			org = o
-			break
+		if strutil.StrListContains(allowedOrgs, strings.ToLower(*o.Login)) {
+			orgs = append(orgs, o)
 		}
 	}
-	if org == nil {
+	if len(orgs) == 0 {
 		return nil, logical.ErrorResponse("user is not part of required org"), nil
 	}
 
@@ -182,9 +189,19 @@ func (b *backend) verifyCredentials(req *logical.Request, token string) (*verify
 		teamOpt.Page = resp.NextPage
 	}
 
+	// Team slugs qualified by the organization, for the explicit mappings
+	var teamSlugs []string
+
 	for _, t := range allTeams {
-		// We only care about teams that are part of the organization we use
-		if *t.Organization.ID != *org.ID {
+		// We only care about teams that are part of the organizations we use
+		var org *github.Organization
+		for _, o := range orgs {
+			if *t.Organization.ID == *o.ID {
+				org = o
+				break
+			}
+		}
+		if org == nil {
 			continue
 		}
 
@@ -193,6 +210,7 @@ func (b *backend) verifyCredentials(req *logical.Request, token string) (*verify
 		if *t.Name != *t.Slug {
 			teamNames = append(teamNames, *t.Slug)
 		}
+		teamSlugs = append(teamSlugs, teamSlugKey(*org.Login, *t.Slug))
 	}
 
 	groupPoliciesList, err := b.TeamMap.Policies(req.Storage, teamNames...)
@@ -201,21 +219,32 @@ func (b *backend) verifyCredentials(req *logical.Request, token string) (*verify
 		return nil, nil, err
 	}
 
+	teamSlugPoliciesList, err := b.TeamSlugMap.Policies(req.Storage, teamSlugs...)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
 	userPoliciesList, err := b.UserMap.Policies(req.Storage, []string{*user.Login}...)
 
 	if err != nil {
 		return nil, nil, err
 	}
 
+	policies := append(groupPoliciesList, teamSlugPoliciesList...)
+	policies = append(policies, userPoliciesList...)
+
 	return &verifyCredentialsResp{
 		User:     user,
-		Org:      org,
-		Policies: append(groupPoliciesList, userPoliciesList...),
+		Orgs:     orgs,
+		Policies: policies,
 	}, nil, nil
 }
 
 type verifyCredentialsResp struct {
-	User     *github.User
-	Org      *github.Organization
+	User *github.User
+
+	// Orgs are the allowed organizations the user is part of
+	Orgs     []*github.Organization
 	Policies []string
 }
@@ -0,0 +1,145 @@
+package github
+
+import (
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathTeamSlugs(b *backend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "map/team-slugs/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.pathTeamSlugOrgsList,
+				logical.ReadOperation: b.pathTeamSlugOrgsList,
+			},
+
+			HelpSynopsis:    pathTeamSlugsHelpSyn,
+			HelpDescription: pathTeamSlugsHelpDesc,
+		},
+
+		&framework.Path{
+			Pattern: `map/team-slugs/(?P<org>[-\w.]+)/?$`,
+
+			Fields: map[string]*framework.FieldSchema{
+				"org": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the organization of the teams",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.pathTeamSlugsList,
+				logical.ReadOperation: b.pathTeamSlugsList,
+			},
+
+			HelpSynopsis:    pathTeamSlugsHelpSyn,
+			HelpDescription: pathTeamSlugsHelpDesc,
+		},
+
+		&framework.Path{
+			Pattern: `map/team-slugs/(?P<org>[-\w.]+)/(?P<slug>[-\w]+)$`,
+
+			Fields: map[string]*framework.FieldSchema{
+				"org": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the organization of the team",
+				},
+
+				"slug": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Slug of the team",
+				},
+
+				"value": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Comma-separated list of policies for the team",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.pathTeamSlugRead,
+				logical.UpdateOperation: b.pathTeamSlugWrite,
+				logical.DeleteOperation: b.pathTeamSlugDelete,
+			},
+
+			HelpSynopsis:    pathTeamSlugsHelpSyn,
+			HelpDescription: pathTeamSlugsHelpDesc,
+		},
+	}
+}
+
+func (b *backend) pathTeamSlugOrgsList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	orgs, err := b.TeamSlugMap.List(req.Storage, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(orgs), nil
+}
+
+func (b *backend) pathTeamSlugsList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	org := data.Get("org").(string)
+
+	slugs, err := b.TeamSlugMap.List(req.Storage, teamSlugKey(org, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(slugs), nil
+}
+
+func (b *backend) pathTeamSlugRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	v, err := b.TeamSlugMap.Get(req.Storage, teamSlugKey(data.Get("org").(string), data.Get("slug").(string)))
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: v,
+	}, nil
+}
+
+func (b *backend) pathTeamSlugWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	key := teamSlugKey(data.Get("org").(string), data.Get("slug").(string))
+	err := b.TeamSlugMap.Put(req.Storage, key, map[string]interface{}{
+		"value": data.Get("value").(string),
+	})
+	return nil, err
+}
+
+func (b *backend) pathTeamSlugDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	err := b.TeamSlugMap.Delete(req.Storage, teamSlugKey(data.Get("org").(string), data.Get("slug").(string)))
+	return nil, err
+}
+
+// teamSlugKey returns the key of a team slug mapping, in lower case since
+// organization names and team slugs are case insensitive on GitHub
+func teamSlugKey(org, slug string) string {
+	return strings.ToLower(org + "/" + slug)
+}
+
+const pathTeamSlugsHelpSyn = `
+Map the slugs of teams of an organization to policies.
+`
+
+const pathTeamSlugsHelpDesc = `
+This endpoint maps a team, identified by the slug of the team and the name of
+its organization, to a comma-separated list of policies. Unlike the "teams"
+mappings, which apply to teams of the same name or slug in any of the
+configured organizations, these mappings only apply to the team of the given
+organization.
+
+The policies of all the mappings matching the teams of the user are granted.
+`
@@ -3,6 +3,9 @@ package ldap
 import (
 	"bytes"
 	"fmt"
+	"net/url"
+	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/go-ldap/ldap"
@@ -44,14 +47,63 @@ func Backend() *backend {
 		),
 
 		AuthRenew:   b.pathLoginRenew,
+		Invalidate:  b.invalidate,
+		Clean:       b.ResetPool,
 		BackendType: logical.TypeCredential,
 	}
 
+	b.dialReferral = func(cfg *ConfigEntry, u *url.URL) (ldap.Client, error) {
+		conn, err := cfg.dialURL(u)
+		if err != nil {
+			return nil, err
+		}
+		return conn, nil
+	}
+
 	return &b
 }
 
+// maxReferralHops bounds the chain of referrals followed by a search
+const maxReferralHops = 5
+
 type backend struct {
 	*framework.Backend
+
+	pool     *connPool
+	poolLock sync.Mutex
+
+	// dialReferral connects to the server of a referral
+	dialReferral func(*ConfigEntry, *url.URL) (ldap.Client, error)
+}
+
+// connPool returns the pool of connections to the configured server
+func (b *backend) connPool(cfg *ConfigEntry) *connPool {
+	b.poolLock.Lock()
+	defer b.poolLock.Unlock()
+
+	if b.pool == nil {
+		b.pool = newConnPool(cfg)
+	}
+	return b.pool
+}
+
+// ResetPool closes the pooled connections, so that the next login connects
+// with the current configuration
+func (b *backend) ResetPool() {
+	b.poolLock.Lock()
+	defer b.poolLock.Unlock()
+
+	if b.pool != nil {
+		b.pool.close()
+	}
+	b.pool = nil
+}
+
+func (b *backend) invalidate(key string) {
+	switch key {
+	case "config":
+		b.ResetPool()
+	}
 }
 
 func EscapeLDAPValue(input string) string {
@@ -98,16 +150,14 @@ func (b *backend) Login(req *logical.Request, username string, password string)
 		return nil, logical.ErrorResponse("ldap backend not configured"), nil
 	}
 
-	c, err := cfg.DialLDAP()
+	pool := b.connPool(cfg)
+	c, err := pool.get()
 	if err != nil {
 		return nil, logical.ErrorResponse(err.Error()), nil
 	}
-	if c == nil {
-		return nil, logical.ErrorResponse("invalid connection returned from LDAP dial"), nil
-	}
 
-	// Clean connection
-	defer c.Close()
+	// Return the connection to the pool, bound back as the service account
+	defer pool.put(c)
 
 	userBindDN, err := b.getUserBindDN(cfg, c, username)
 	if err != nil {
@@ -130,7 +180,7 @@ func (b *backend) Login(req *logical.Request, username string, password string)
 	// We re-bind to the BindDN if it's defined because we assume
 	// the BindDN should be the one to search, not the user logging in.
 	if cfg.BindDN != "" && cfg.BindPassword != "" {
-		if err := c.Bind(cfg.BindDN, cfg.BindPassword); err != nil {
+		if err := c.bindService(cfg); err != nil {
 			return nil, logical.ErrorResponse(fmt.Sprintf("Encountered an error while attempting to re-bind with the BindDN User: %s", err.Error())), nil
 		}
 		if b.Logger().IsDebug() {
@@ -234,10 +284,10 @@ func (b *backend) getCN(dn string) string {
  * 2. If upndomain is set, the user dn is constructed as 'username@upndomain'. See https://msdn.microsoft.com/en-us/library/cc223499.aspx
  *
  */
-func (b *backend) getUserBindDN(cfg *ConfigEntry, c *ldap.Conn, username string) (string, error) {
+func (b *backend) getUserBindDN(cfg *ConfigEntry, c *pooledConn, username string) (string, error) {
 	bindDN := ""
 	if cfg.DiscoverDN || (cfg.BindDN != "" && cfg.BindPassword != "") {
-		if err := c.Bind(cfg.BindDN, cfg.BindPassword); err != nil {
+		if err := c.bindService(cfg); err != nil {
 			return bindDN, fmt.Errorf("LDAP bind (service) failed: %v", err)
 		}
 
@@ -245,7 +295,7 @@ func (b *backend) getUserBindDN(cfg *ConfigEntry, c *ldap.Conn, username string)
 		if b.Logger().IsDebug() {
 			b.Logger().Debug("auth/ldap: Discovering user", "userdn", cfg.UserDN, "filter", filter)
 		}
-		result, err := c.Search(&ldap.SearchRequest{
+		result, err := b.search(cfg, c, &ldap.SearchRequest{
 			BaseDN: cfg.UserDN,
 			Scope:  2, // subtree
 			Filter: filter,
@@ -271,7 +321,7 @@ func (b *backend) getUserBindDN(cfg *ConfigEntry, c *ldap.Conn, username string)
 /*
  * Returns the DN of the object representing the authenticated user.
  */
-func (b *backend) getUserDN(cfg *ConfigEntry, c *ldap.Conn, bindDN string) (string, error) {
+func (b *backend) getUserDN(cfg *ConfigEntry, c *pooledConn, bindDN string) (string, error) {
 	userDN := ""
 	if cfg.UPNDomain != "" {
 		// Find the distinguished name for the user if userPrincipalName used for login
@@ -279,7 +329,7 @@ func (b *backend) getUserDN(cfg *ConfigEntry, c *ldap.Conn, bindDN string) (stri
 		if b.Logger().IsDebug() {
 			b.Logger().Debug("auth/ldap: Searching UPN", "userdn", cfg.UserDN, "filter", filter)
 		}
-		result, err := c.Search(&ldap.SearchRequest{
+		result, err := b.search(cfg, c, &ldap.SearchRequest{
 			BaseDN: cfg.UserDN,
 			Scope:  2, // subtree
 			Filter: filter,
@@ -312,23 +362,89 @@ func (b *backend) getUserDN(cfg *ConfigEntry, c *ldap.Conn, bindDN string) (stri
  *   cfg.GroupDN     = "OU=Groups,DC=myorg,DC=com"
  *   cfg.GroupAttr   = "cn"
  *
+ * If cfg.GroupNestingDepth is set, the groups of the groups found are resolved in turn, up to that
+ * many levels, by following their cfg.GroupAttr if it is memberOf, or by running the same query with
+ * the DN of the group as UserDN otherwise.
+ *
+ * If cfg.UseTokenGroups is set, the groups are instead read from the tokenGroups attribute of the
+ * user, which Active Directory computes with all the groups of the user, including nested ones.
+ *
  * NOTE - If cfg.GroupFilter is empty, no query is performed and an empty result slice is returned.
  *
  */
-func (b *backend) getLdapGroups(cfg *ConfigEntry, c *ldap.Conn, userDN string, username string) ([]string, error) {
-	// retrieve the groups in a string/bool map as a structure to avoid duplicates inside
-	ldapMap := make(map[string]bool)
+func (b *backend) getLdapGroups(cfg *ConfigEntry, c *pooledConn, userDN string, username string) ([]string, error) {
+	if cfg.GroupDN == "" {
+		b.Logger().Warn("auth/ldap: GroupDN is empty, will not query server")
+		return make([]string, 0), nil
+	}
+
+	if cfg.UseTokenGroups {
+		return b.getTokenGroups(cfg, c, userDN)
+	}
 
 	if cfg.GroupFilter == "" {
 		b.Logger().Warn("auth/ldap: GroupFilter is empty, will not query server")
 		return make([]string, 0), nil
 	}
 
-	if cfg.GroupDN == "" {
-		b.Logger().Warn("auth/ldap: GroupDN is empty, will not query server")
-		return make([]string, 0), nil
+	entries, err := b.searchGroupFilter(cfg, c, userDN, username)
+	if err != nil {
+		return nil, err
+	}
+
+	// retrieve the groups in a string/bool map as a structure to avoid duplicates inside
+	ldapMap := make(map[string]bool)
+	pending := b.groupsOfEntries(cfg, entries, ldapMap)
+
+	// Resolve the nested groups level by level, skipping the groups already
+	// visited in case of cycles
+	visited := make(map[string]bool)
+	for depth := 0; depth < cfg.GroupNestingDepth && len(pending) > 0; depth++ {
+		var next []string
+		for _, groupDN := range pending {
+			if visited[groupDN] {
+				continue
+			}
+			visited[groupDN] = true
+
+			var entries []*ldap.Entry
+			if strings.EqualFold(cfg.GroupAttr, "memberOf") {
+				result, err := b.search(cfg, c, &ldap.SearchRequest{
+					BaseDN:     groupDN,
+					Scope:      ldap.ScopeBaseObject,
+					Filter:     "(objectClass=*)",
+					Attributes: []string{cfg.GroupAttr},
+				})
+				if err != nil {
+					return nil, fmt.Errorf("LDAP search for nested groups failed: %v", err)
+				}
+				entries = result.Entries
+			} else {
+				entries, err = b.searchGroupFilter(cfg, c, groupDN, b.getCN(groupDN))
+				if err != nil {
+					return nil, err
+				}
+			}
+			next = append(next, b.groupsOfEntries(cfg, entries, ldapMap)...)
+		}
+		pending = next
+	}
+	if cfg.GroupNestingDepth > 0 && len(pending) > 0 && b.Logger().IsDebug() {
+		b.Logger().Debug("auth/ldap: Group nesting depth reached", "group_nesting_depth", cfg.GroupNestingDepth)
 	}
 
+	ldapGroups := make([]string, 0, len(ldapMap))
+	for key, _ := range ldapMap {
+		ldapGroups = append(ldapGroups, key)
+	}
+
+	return ldapGroups, nil
+}
+
+/*
+ * searchGroupFilter runs the query of cfg.GroupFilter for the given UserDN and Username.
+ */
+func (b *backend) searchGroupFilter(cfg *ConfigEntry, c *pooledConn, userDN string, username string) ([]*ldap.Entry, error) {
 	// If groupfilter was defined, resolve it as a Go template and use the query for
 	// returning the user's groups
 	if b.Logger().IsDebug() {
@@ -358,7 +474,7 @@ func (b *backend) getLdapGroups(cfg *ConfigEntry, c *ldap.Conn, userDN string, u
 		b.Logger().Debug("auth/ldap: Searching", "groupdn", cfg.GroupDN, "rendered_query", renderedQuery.String())
 	}
 
-	result, err := c.Search(&ldap.SearchRequest{
+	result, err := b.search(cfg, c, &ldap.SearchRequest{
 		BaseDN: cfg.GroupDN,
 		Scope:  2, // subtree
 		Filter: renderedQuery.String(),
@@ -370,7 +486,16 @@ func (b *backend) getLdapGroups(cfg *ConfigEntry, c *ldap.Conn, userDN string, u
 		return nil, fmt.Errorf("LDAP search failed: %v", err)
 	}
 
-	for _, e := range result.Entries {
+	return result.Entries, nil
+}
+
+/*
+ * groupsOfEntries adds the names of the groups found in the entries to ldapMap, and returns
+ * their DNs so that their own groups can be resolved.
+ */
+func (b *backend) groupsOfEntries(cfg *ConfigEntry, entries []*ldap.Entry, ldapMap map[string]bool) []string {
+	var groupDNs []string
+	for _, e := range entries {
 		dn, err := ldap.ParseDN(e.DN)
 		if err != nil || len(dn.RDNs) == 0 {
 			continue
@@ -382,13 +507,75 @@ func (b *backend) getLdapGroups(cfg *ConfigEntry, c *ldap.Conn, userDN string, u
 			for _, val := range values {
 				groupCN := b.getCN(val)
 				ldapMap[groupCN] = true
+
+				// Values which are DNs, such as memberOf ones, are groups;
+				// otherwise the entry itself is
+				if valDN, err := ldap.ParseDN(val); err == nil && len(valDN.RDNs) > 0 {
+					groupDNs = append(groupDNs, val)
+				} else {
+					groupDNs = append(groupDNs, e.DN)
+				}
 			}
 		} else {
 			// If groupattr didn't resolve, use self (enumerating group objects)
 			groupCN := b.getCN(e.DN)
 			ldapMap[groupCN] = true
+			groupDNs = append(groupDNs, e.DN)
+		}
+	}
+	return groupDNs
+}
+
+/*
+ * getTokenGroups returns the groups of the user found from the SIDs of its tokenGroups attribute.
+ */
+func (b *backend) getTokenGroups(cfg *ConfigEntry, c *pooledConn, userDN string) ([]string, error) {
+	result, err := b.search(cfg, c, &ldap.SearchRequest{
+		BaseDN:     userDN,
+		Scope:      ldap.ScopeBaseObject,
+		Filter:     "(objectClass=*)",
+		Attributes: []string{"tokenGroups"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("LDAP search for tokenGroups failed: %v", err)
+	}
+	if len(result.Entries) == 0 {
+		return nil, fmt.Errorf("LDAP search for tokenGroups returned no entry for %q", userDN)
+	}
+
+	sids := result.Entries[0].GetRawAttributeValues("tokenGroups")
+	if b.Logger().IsDebug() {
+		b.Logger().Debug("auth/ldap: Resolving tokenGroups", "num_sids", len(sids))
+	}
+	if len(sids) == 0 {
+		return make([]string, 0), nil
+	}
+
+	var filter bytes.Buffer
+	filter.WriteString("(|")
+	for _, sid := range sids {
+		filter.WriteString("(objectSid=")
+		for _, octet := range sid {
+			fmt.Fprintf(&filter, "\\%02x", octet)
 		}
+		filter.WriteString(")")
 	}
+	filter.WriteString(")")
+
+	result, err = b.search(cfg, c, &ldap.SearchRequest{
+		BaseDN: cfg.GroupDN,
+		Scope:  2, // subtree
+		Filter: filter.String(),
+		Attributes: []string{
+			cfg.GroupAttr,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("LDAP search for tokenGroups failed: %v", err)
+	}
+
+	ldapMap := make(map[string]bool)
+	b.groupsOfEntries(cfg, result.Entries, ldapMap)
 
 	ldapGroups := make([]string, 0, len(ldapMap))
 	for key, _ := range ldapMap {
@@ -398,6 +585,76 @@ func (b *backend) getLdapGroups(cfg *ConfigEntry, c *ldap.Conn, userDN string, u
 	return ldapGroups, nil
 }
 
+/*
+ * search runs a search and, if cfg.FollowReferrals is set, follows the referrals it returns
+ * to other servers, adding the entries they return to the result.
+ */
+func (b *backend) search(cfg *ConfigEntry, c *pooledConn, req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	return b.searchReferrals(cfg, c, req, 0)
+}
+
+func (b *backend) searchReferrals(cfg *ConfigEntry, c *pooledConn, req *ldap.SearchRequest, hops int) (*ldap.SearchResult, error) {
+	result, err := c.Search(req)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.FollowReferrals || len(result.Referrals) == 0 {
+		return result, nil
+	}
+	if hops >= maxReferralHops {
+		b.Logger().Warn("auth/ldap: Not following referrals, too many hops", "referrals", result.Referrals)
+		return result, nil
+	}
+
+	for _, referral := range result.Referrals {
+		entries, err := b.followReferral(cfg, req, referral, hops+1)
+		if err != nil {
+			b.Logger().Warn("auth/ldap: Failed to follow referral", "referral", referral, "error", err)
+			continue
+		}
+		result.Entries = append(result.Entries, entries...)
+	}
+	result.Referrals = nil
+
+	return result, nil
+}
+
+/*
+ * followReferral runs a search on the server of a referral, from the base DN of the referral
+ * if it has one.
+ */
+func (b *backend) followReferral(cfg *ConfigEntry, req *ldap.SearchRequest, referral string, hops int) ([]*ldap.Entry, error) {
+	u, err := url.Parse(referral)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := b.dialReferral(cfg, u)
+	if err != nil {
+		return nil, err
+	}
+	c := &pooledConn{Client: conn}
+	defer c.Close()
+
+	if err := c.bindService(cfg); err != nil {
+		return nil, fmt.Errorf("LDAP bind (service) failed: %v", err)
+	}
+
+	referred := *req
+	if baseDN := strings.TrimPrefix(u.Path, "/"); baseDN != "" {
+		referred.BaseDN = baseDN
+	}
+
+	if b.Logger().IsDebug() {
+		b.Logger().Debug("auth/ldap: Following referral", "referral", referral, "basedn", referred.BaseDN)
+	}
+	result, err := b.searchReferrals(cfg, c, &referred, hops)
+	if err != nil {
+		return nil, err
+	}
+	return result.Entries, nil
+}
+
 const backendHelp = `
 The "ldap" credential provider allows authentication querying
 a LDAP server, checking username and password, and associating groups
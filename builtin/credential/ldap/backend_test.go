@@ -2,11 +2,14 @@ package ldap
 
 import (
 	"fmt"
+	"net/url"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/go-ldap/ldap"
 	"github.com/hashicorp/vault/logical"
 	logicaltest "github.com/hashicorp/vault/logical/testing"
 	"github.com/mitchellh/mapstructure"
@@ -257,6 +260,11 @@ func TestBackend_configDefaultsAfterUpdate(t *testing.T) {
 						t.Errorf("Default mismatch: userattr. Expected: '%s', received :'%s'", defaultUserAttr, cfg["userattr"])
 					}
 
+					defaultMaxIdleConnections := 4
+					if cfg["max_idle_connections"] != defaultMaxIdleConnections {
+						t.Errorf("Default mismatch: max_idle_connections. Expected: '%d', received :'%v'", defaultMaxIdleConnections, cfg["max_idle_connections"])
+					}
+
 					defaultDenyNullBind := true
 					if cfg["deny_null_bind"] != defaultDenyNullBind {
 						t.Errorf("Default mismatch: deny_null_bind. Expected: '%s', received :'%s'", defaultDenyNullBind, cfg["deny_null_bind"])
@@ -538,3 +546,166 @@ func testAccStepUserList(t *testing.T, users []string) logicaltest.TestStep {
 		},
 	}
 }
+
+// testGroupDirectory answers the searches of the group filter
+// "(member=<dn>)" and base searches of memberOf, for bob who is a member of
+// dev, itself a member of eng, itself a member of all, which is a member of
+// dev again
+func testGroupDirectory(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	memberOf := map[string][]string{
+		"uid=bob,ou=people,dc=example,dc=com": []string{"CN=dev,ou=groups,dc=example,dc=com"},
+		"CN=dev,ou=groups,dc=example,dc=com":  []string{"CN=eng,ou=groups,dc=example,dc=com"},
+		"CN=eng,ou=groups,dc=example,dc=com":  []string{"CN=all,ou=groups,dc=example,dc=com"},
+		"CN=all,ou=groups,dc=example,dc=com":  []string{"CN=dev,ou=groups,dc=example,dc=com"},
+	}
+
+	result := &ldap.SearchResult{}
+	switch {
+	case req.Scope == ldap.ScopeBaseObject:
+		result.Entries = append(result.Entries, ldap.NewEntry(req.BaseDN, map[string][]string{
+			"memberOf": memberOf[req.BaseDN],
+		}))
+	case strings.HasPrefix(req.Filter, "(uid="):
+		dn := "uid=bob,ou=people,dc=example,dc=com"
+		result.Entries = append(result.Entries, ldap.NewEntry(dn, map[string][]string{
+			"memberOf": memberOf[dn],
+		}))
+	case strings.HasPrefix(req.Filter, "(member="):
+		member := strings.TrimSuffix(strings.TrimPrefix(req.Filter, "(member="), ")")
+		for _, group := range memberOf[member] {
+			result.Entries = append(result.Entries, ldap.NewEntry(group, nil))
+		}
+	}
+	return result, nil
+}
+
+func TestLdapAuthBackend_nestedGroups(t *testing.T) {
+	b, _ := createBackendWithStorage(t)
+	c := &pooledConn{Client: &fakeLDAPClient{search: testGroupDirectory}}
+
+	cases := []struct {
+		groupFilter string
+		groupAttr   string
+		depth       int
+		expected    []string
+	}{
+		{"(member={{.UserDN}})", "cn", 0, []string{"dev"}},
+		{"(member={{.UserDN}})", "cn", 1, []string{"dev", "eng"}},
+		{"(member={{.UserDN}})", "cn", 10, []string{"all", "dev", "eng"}},
+		{"(uid={{.Username}})", "memberOf", 0, []string{"dev"}},
+		{"(uid={{.Username}})", "memberOf", 2, []string{"all", "dev", "eng"}},
+	}
+	for _, tc := range cases {
+		cfg := &ConfigEntry{
+			GroupDN:           "ou=groups,dc=example,dc=com",
+			GroupFilter:       tc.groupFilter,
+			GroupAttr:         tc.groupAttr,
+			GroupNestingDepth: tc.depth,
+		}
+		groups, err := b.getLdapGroups(cfg, c, "uid=bob,ou=people,dc=example,dc=com", "bob")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(groups)
+		if !reflect.DeepEqual(groups, tc.expected) {
+			t.Fatalf("bad groups for %#v: %#v", tc, groups)
+		}
+	}
+}
+
+func TestLdapAuthBackend_tokenGroups(t *testing.T) {
+	b, _ := createBackendWithStorage(t)
+	c := &pooledConn{Client: &fakeLDAPClient{
+		search: func(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+			if req.Scope == ldap.ScopeBaseObject {
+				entry := ldap.NewEntry(req.BaseDN, nil)
+				entry.Attributes = append(entry.Attributes, &ldap.EntryAttribute{
+					Name:       "tokenGroups",
+					ByteValues: [][]byte{[]byte{0x01, 0x05}, []byte{0xab}},
+				})
+				return &ldap.SearchResult{Entries: []*ldap.Entry{entry}}, nil
+			}
+			if req.Filter != `(|(objectSid=\01\05)(objectSid=\ab))` {
+				return nil, fmt.Errorf("bad filter %q", req.Filter)
+			}
+			return &ldap.SearchResult{Entries: []*ldap.Entry{
+				ldap.NewEntry("CN=dev,ou=groups,dc=example,dc=com", map[string][]string{"cn": []string{"dev"}}),
+				ldap.NewEntry("CN=eng,ou=groups,dc=example,dc=com", map[string][]string{"cn": []string{"eng"}}),
+			}}, nil
+		},
+	}}
+
+	cfg := &ConfigEntry{
+		GroupDN:        "ou=groups,dc=example,dc=com",
+		GroupAttr:      "cn",
+		UseTokenGroups: true,
+	}
+	groups, err := b.getLdapGroups(cfg, c, "cn=bob,ou=people,dc=example,dc=com", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(groups)
+	if !reflect.DeepEqual(groups, []string{"dev", "eng"}) {
+		t.Fatalf("bad: %#v", groups)
+	}
+}
+
+func TestLdapAuthBackend_referrals(t *testing.T) {
+	b, _ := createBackendWithStorage(t)
+	c := &pooledConn{Client: &fakeLDAPClient{
+		search: func(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+			return &ldap.SearchResult{
+				Entries:   []*ldap.Entry{ldap.NewEntry("CN=dev,ou=groups,dc=example,dc=com", nil)},
+				Referrals: []string{"ldap://other.example.com/ou=groups,dc=other,dc=com??sub"},
+			}, nil
+		},
+	}}
+
+	var referred *fakeLDAPClient
+	b.dialReferral = func(cfg *ConfigEntry, u *url.URL) (ldap.Client, error) {
+		if u.Host != "other.example.com" {
+			return nil, fmt.Errorf("bad host %q", u.Host)
+		}
+		referred = &fakeLDAPClient{
+			search: func(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+				if req.BaseDN != "ou=groups,dc=other,dc=com" {
+					return nil, fmt.Errorf("bad base DN %q", req.BaseDN)
+				}
+				return &ldap.SearchResult{
+					Entries: []*ldap.Entry{ldap.NewEntry("CN=ops,ou=groups,dc=other,dc=com", nil)},
+				}, nil
+			},
+		}
+		return referred, nil
+	}
+
+	cfg := &ConfigEntry{
+		GroupDN:      "ou=groups,dc=example,dc=com",
+		GroupFilter:  "(member={{.UserDN}})",
+		GroupAttr:    "cn",
+		BindDN:       "cn=admin,dc=example,dc=com",
+		BindPassword: "password",
+	}
+
+	// Referrals are ignored unless followed
+	groups, err := b.getLdapGroups(cfg, c, "uid=bob,ou=people,dc=example,dc=com", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(groups, []string{"dev"}) || referred != nil {
+		t.Fatalf("bad: %#v", groups)
+	}
+
+	cfg.FollowReferrals = true
+	groups, err = b.getLdapGroups(cfg, c, "uid=bob,ou=people,dc=example,dc=com", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(groups)
+	if !reflect.DeepEqual(groups, []string{"dev", "ops"}) {
+		t.Fatalf("bad: %#v", groups)
+	}
+	if !reflect.DeepEqual(referred.binds, []string{cfg.BindDN}) || !referred.closed {
+		t.Fatalf("bad referral connection: %#v", referred)
+	}
+}
@@ -114,6 +114,35 @@ Default: cn`,
 				Default:     true,
 				Description: "Denies an unauthenticated LDAP bind request if the user's password is empty; defaults to true",
 			},
+
+			"max_idle_connections": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     4,
+				Description: "Maximum number of idle connections to the LDAP server kept open between logins; 0 disables pooling. Defaults to 4",
+			},
+
+			"idle_connection_timeout": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Default:     300,
+				Description: "Duration after which an idle connection to the LDAP server is closed. Defaults to 5 minutes",
+			},
+
+			"follow_referrals": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: "Follow the referrals returned by user and group searches to other LDAP servers (optional)",
+			},
+
+			"group_nesting_depth": &framework.FieldSchema{
+				Type: framework.TypeInt,
+				Description: `Number of levels of nested groups to resolve: the groups of the user's
+groups are found with <groupfilter> given the group DN as UserDN, or by following
+their <groupattr> if it is "memberOf". Defaults to 0, which does not resolve nested groups`,
+			},
+
+			"use_token_groups": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: "Use the Active Directory tokenGroups constructed attribute of the user to find all the groups it is a member of, including nested groups (optional)",
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -285,6 +314,23 @@ func (b *backend) newConfigEntry(d *framework.FieldData) (*ConfigEntry, error) {
 	if discoverDN {
 		cfg.DiscoverDN = discoverDN
 	}
+	cfg.MaxIdleConnections = d.Get("max_idle_connections").(int)
+	if cfg.MaxIdleConnections < 0 {
+		return nil, fmt.Errorf("'max_idle_connections' cannot be negative")
+	}
+	cfg.IdleConnectionTimeout = d.Get("idle_connection_timeout").(int)
+	followReferrals := d.Get("follow_referrals").(bool)
+	if followReferrals {
+		cfg.FollowReferrals = followReferrals
+	}
+	cfg.GroupNestingDepth = d.Get("group_nesting_depth").(int)
+	if cfg.GroupNestingDepth < 0 {
+		return nil, fmt.Errorf("'group_nesting_depth' cannot be negative")
+	}
+	useTokenGroups := d.Get("use_token_groups").(bool)
+	if useTokenGroups {
+		cfg.UseTokenGroups = useTokenGroups
+	}
 
 	return cfg, nil
 }
@@ -306,6 +352,9 @@ func (b *backend) pathConfigWrite(
 		return nil, err
 	}
 
+	// Connections to the previous server must not be reused
+	b.ResetPool()
+
 	return nil, nil
 }
 
@@ -327,6 +376,12 @@ type ConfigEntry struct {
 	DiscoverDN    bool   `json:"discoverdn" structs:"discoverdn" mapstructure:"discoverdn"`
 	TLSMinVersion string `json:"tls_min_version" structs:"tls_min_version" mapstructure:"tls_min_version"`
 	TLSMaxVersion string `json:"tls_max_version" structs:"tls_max_version" mapstructure:"tls_max_version"`
+
+	MaxIdleConnections    int  `json:"max_idle_connections" structs:"max_idle_connections" mapstructure:"max_idle_connections"`
+	IdleConnectionTimeout int  `json:"idle_connection_timeout" structs:"idle_connection_timeout" mapstructure:"idle_connection_timeout"`
+	FollowReferrals       bool `json:"follow_referrals" structs:"follow_referrals" mapstructure:"follow_referrals"`
+	GroupNestingDepth     int  `json:"group_nesting_depth" structs:"group_nesting_depth" mapstructure:"group_nesting_depth"`
+	UseTokenGroups        bool `json:"use_token_groups" structs:"use_token_groups" mapstructure:"use_token_groups"`
 }
 
 func (c *ConfigEntry) GetTLSConfig(host string) (*tls.Config, error) {
@@ -374,45 +429,7 @@ func (c *ConfigEntry) DialLDAP() (*ldap.Conn, error) {
 			retErr = multierror.Append(retErr, fmt.Errorf("error parsing url %q: %s", uut, err.Error()))
 			continue
 		}
-		host, port, err := net.SplitHostPort(u.Host)
-		if err != nil {
-			host = u.Host
-		}
-
-		var tlsConfig *tls.Config
-		switch u.Scheme {
-		case "ldap":
-			if port == "" {
-				port = "389"
-			}
-			conn, err = ldap.Dial("tcp", net.JoinHostPort(host, port))
-			if err != nil {
-				break
-			}
-			if conn == nil {
-				err = fmt.Errorf("empty connection after dialing")
-				break
-			}
-			if c.StartTLS {
-				tlsConfig, err = c.GetTLSConfig(host)
-				if err != nil {
-					break
-				}
-				err = conn.StartTLS(tlsConfig)
-			}
-		case "ldaps":
-			if port == "" {
-				port = "636"
-			}
-			tlsConfig, err = c.GetTLSConfig(host)
-			if err != nil {
-				break
-			}
-			conn, err = ldap.DialTLS("tcp", net.JoinHostPort(host, port), tlsConfig)
-		default:
-			retErr = multierror.Append(retErr, fmt.Errorf("invalid LDAP scheme in url %q", net.JoinHostPort(host, port)))
-			continue
-		}
+		conn, err = c.dialURL(u)
 		if err == nil {
 			if retErr != nil {
 				if c.logger.IsDebug() {
@@ -428,6 +445,54 @@ func (c *ConfigEntry) DialLDAP() (*ldap.Conn, error) {
 	return conn, retErr.ErrorOrNil()
 }
 
+// dialURL connects to the LDAP server of the URL
+func (c *ConfigEntry) dialURL(u *url.URL) (*ldap.Conn, error) {
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		host = u.Host
+	}
+
+	var conn *ldap.Conn
+	var tlsConfig *tls.Config
+	switch u.Scheme {
+	case "ldap":
+		if port == "" {
+			port = "389"
+		}
+		conn, err = ldap.Dial("tcp", net.JoinHostPort(host, port))
+		if err != nil {
+			break
+		}
+		if conn == nil {
+			err = fmt.Errorf("empty connection after dialing")
+			break
+		}
+		if c.StartTLS {
+			tlsConfig, err = c.GetTLSConfig(host)
+			if err != nil {
+				break
+			}
+			err = conn.StartTLS(tlsConfig)
+		}
+	case "ldaps":
+		if port == "" {
+			port = "636"
+		}
+		tlsConfig, err = c.GetTLSConfig(host)
+		if err != nil {
+			break
+		}
+		conn, err = ldap.DialTLS("tcp", net.JoinHostPort(host, port), tlsConfig)
+	default:
+		return nil, fmt.Errorf("invalid LDAP scheme in url %q", net.JoinHostPort(host, port))
+	}
+	if err != nil && conn != nil {
+		conn.Close()
+		conn = nil
+	}
+	return conn, err
+}
+
 /*
  * Returns FieldData describing our ConfigEntry struct schema
  */
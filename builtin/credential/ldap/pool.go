@@ -0,0 +1,158 @@
+package ldap
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap"
+)
+
+const (
+	// poolHealthCheckInterval is how long a connection can stay idle before
+	// it is checked again when taken from the pool
+	poolHealthCheckInterval = 30 * time.Second
+)
+
+// connPool keeps idle connections to the LDAP server bound as the service
+// account, so that logins do not have to dial, negotiate TLS and bind every
+// time
+type connPool struct {
+	sync.Mutex
+
+	cfg    *ConfigEntry
+	dial   func() (ldap.Client, error)
+	idle   []*pooledConn
+	closed bool
+}
+
+func newConnPool(cfg *ConfigEntry) *connPool {
+	return &connPool{
+		cfg: cfg,
+		dial: func() (ldap.Client, error) {
+			return cfg.DialLDAP()
+		},
+	}
+}
+
+// get returns a healthy idle connection, or a new connection if there is
+// none
+func (p *connPool) get() (*pooledConn, error) {
+	for {
+		p.Lock()
+		if len(p.idle) == 0 {
+			p.Unlock()
+			break
+		}
+		conn := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.Unlock()
+
+		idle := time.Since(conn.idleSince)
+		if idle < time.Duration(p.cfg.IdleConnectionTimeout)*time.Second && (idle < poolHealthCheckInterval || conn.healthy()) {
+			return conn, nil
+		}
+		conn.Close()
+	}
+
+	conn, err := p.dial()
+	if err != nil {
+		return nil, err
+	}
+	return &pooledConn{Client: conn}, nil
+}
+
+// put returns a connection to the pool once bound back as the service
+// account. Connections that failed, cannot be bound back or do not fit in
+// the pool are closed.
+func (p *connPool) put(conn *pooledConn) {
+	if p.cfg.MaxIdleConnections == 0 || conn.broken || conn.bindService(p.cfg) != nil {
+		conn.Close()
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	if p.closed || len(p.idle) >= p.cfg.MaxIdleConnections {
+		conn.Close()
+		return
+	}
+	conn.idleSince = time.Now()
+	p.idle = append(p.idle, conn)
+}
+
+// close closes the idle connections of the pool. Connections in use are
+// closed when they are put back.
+func (p *connPool) close() {
+	p.Lock()
+	defer p.Unlock()
+	for _, conn := range p.idle {
+		conn.Close()
+	}
+	p.idle = nil
+	p.closed = true
+}
+
+// pooledConn is a connection of the pool, which tracks the account it is
+// bound as and whether it failed
+type pooledConn struct {
+	ldap.Client
+
+	// service is set while the connection is bound as the service account
+	service bool
+
+	// broken is set once an operation failed with a network error
+	broken bool
+
+	idleSince time.Time
+}
+
+func (c *pooledConn) Bind(username, password string) error {
+	c.service = false
+	err := c.Client.Bind(username, password)
+	c.checkErr(err)
+	return err
+}
+
+func (c *pooledConn) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	result, err := c.Client.Search(req)
+	c.checkErr(err)
+	return result, err
+}
+
+// bindService binds the connection as the service account, or anonymously
+// if there is none, unless it already is
+func (c *pooledConn) bindService(cfg *ConfigEntry) error {
+	if c.service {
+		return nil
+	}
+
+	var err error
+	if cfg.BindDN != "" && cfg.BindPassword != "" {
+		err = c.Bind(cfg.BindDN, cfg.BindPassword)
+	} else {
+		err = c.Bind("", "")
+	}
+	if err != nil {
+		return err
+	}
+	c.service = true
+	return nil
+}
+
+// healthy checks that the server still answers on the connection by
+// reading its root DSE
+func (c *pooledConn) healthy() bool {
+	c.Search(&ldap.SearchRequest{
+		BaseDN:     "",
+		Scope:      ldap.ScopeBaseObject,
+		Filter:     "(objectClass=*)",
+		Attributes: []string{"1.1"},
+	})
+	return !c.broken
+}
+
+func (c *pooledConn) checkErr(err error) {
+	if ldap.IsErrorWithCode(err, ldap.ErrorNetwork) {
+		c.broken = true
+	}
+}
@@ -0,0 +1,118 @@
+package ldap
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap"
+)
+
+// fakeLDAPClient answers searches with a function and records binds
+type fakeLDAPClient struct {
+	ldap.Client
+
+	search func(*ldap.SearchRequest) (*ldap.SearchResult, error)
+	binds  []string
+	closed bool
+}
+
+func (c *fakeLDAPClient) Bind(username, password string) error {
+	c.binds = append(c.binds, username)
+	return nil
+}
+
+func (c *fakeLDAPClient) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	if c.search == nil {
+		return &ldap.SearchResult{}, nil
+	}
+	return c.search(req)
+}
+
+func (c *fakeLDAPClient) Close() {
+	c.closed = true
+}
+
+func TestConnPool(t *testing.T) {
+	cfg := &ConfigEntry{
+		BindDN:                "cn=admin,dc=example,dc=com",
+		BindPassword:          "password",
+		MaxIdleConnections:    1,
+		IdleConnectionTimeout: 300,
+	}
+
+	var dialed []*fakeLDAPClient
+	pool := newConnPool(cfg)
+	pool.dial = func() (ldap.Client, error) {
+		conn := &fakeLDAPClient{}
+		dialed = append(dialed, conn)
+		return conn, nil
+	}
+
+	// A connection bound as a user is bound back as the service account
+	// and reused
+	c, err := pool.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Bind("uid=bob,dc=example,dc=com", "secret")
+	pool.put(c)
+	if c2, _ := pool.get(); c2 != c || len(dialed) != 1 {
+		t.Fatalf("connection not reused")
+	}
+	if exp := []string{"uid=bob,dc=example,dc=com", cfg.BindDN}; !reflect.DeepEqual(dialed[0].binds, exp) {
+		t.Fatalf("bad: %#v", dialed[0].binds)
+	}
+
+	// Already bound as the service account, it is not bound again
+	c.bindService(cfg)
+	pool.put(c)
+	if len(dialed[0].binds) != 2 {
+		t.Fatalf("bad: %#v", dialed[0].binds)
+	}
+
+	// Connections beyond the pool size are closed
+	c, _ = pool.get()
+	c2, _ := pool.get()
+	pool.put(c)
+	pool.put(c2)
+	if len(dialed) != 2 || !dialed[1].closed || dialed[0].closed {
+		t.Fatalf("bad: %d dialed", len(dialed))
+	}
+
+	// Connections which failed are closed
+	c, _ = pool.get()
+	dialed[0].search = func(*ldap.SearchRequest) (*ldap.SearchResult, error) {
+		return nil, ldap.NewError(ldap.ErrorNetwork, errors.New("connection closed"))
+	}
+	c.Search(&ldap.SearchRequest{})
+	pool.put(c)
+	if !dialed[0].closed {
+		t.Fatal("broken connection not closed")
+	}
+
+	// Connections idle for too long are checked, and closed if broken
+	c, _ = pool.get()
+	if len(dialed) != 3 {
+		t.Fatalf("bad: %d dialed", len(dialed))
+	}
+	pool.put(c)
+	c.idleSince = time.Now().Add(-time.Minute)
+	dialed[2].search = dialed[0].search
+	if c, _ = pool.get(); c.Client != dialed[3] || !dialed[2].closed {
+		t.Fatal("unhealthy connection reused")
+	}
+	pool.put(c)
+
+	// Connections are not reused once the pool is closed
+	pool.close()
+	if !dialed[3].closed {
+		t.Fatal("idle connection not closed")
+	}
+	c, _ = pool.get()
+	pool.put(c)
+	if !dialed[4].closed {
+		t.Fatal("connection put after close not closed")
+	}
+}
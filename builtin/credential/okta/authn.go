@@ -0,0 +1,183 @@
+package okta
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+const (
+	// pushPollInterval is how often the result of an Okta Verify push is
+	// polled
+	pushPollInterval = 2 * time.Second
+
+	// pushTimeout bounds the wait for an Okta Verify push to be answered,
+	// in case Okta does not time it out itself
+	pushTimeout = 5 * time.Minute
+)
+
+// authnResponse is the part of the Okta authentication API response used to
+// verify factors, which the Okta client does not expose
+type authnResponse struct {
+	Status       string `json:"status"`
+	StateToken   string `json:"stateToken"`
+	FactorResult string `json:"factorResult"`
+	Embedded     struct {
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+		Factors []authnFactor `json:"factors"`
+	} `json:"_embedded"`
+	Links struct {
+		Next struct {
+			Href string `json:"href"`
+		} `json:"next"`
+	} `json:"_links"`
+}
+
+type authnFactor struct {
+	ID         string `json:"id"`
+	FactorType string `json:"factorType"`
+	Provider   string `json:"provider"`
+	Links      struct {
+		Verify struct {
+			Href string `json:"href"`
+		} `json:"verify"`
+	} `json:"_links"`
+}
+
+// authnClient calls the Okta authentication API
+type authnClient struct {
+	client  *http.Client
+	baseURL string
+}
+
+func (c *ConfigEntry) authnClient() *authnClient {
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = "okta.com"
+	}
+	return &authnClient{
+		client:  cleanhttp.DefaultClient(),
+		baseURL: fmt.Sprintf("https://%s.%s/api/v1/", c.Org, baseURL),
+	}
+}
+
+// authenticate verifies the password of the user and, unless skipped,
+// its Okta Verify factor: the TOTP passcode if given, or a push otherwise.
+// It returns the Okta ID of the user.
+func (c *authnClient) authenticate(username, password, totp string, verifyFactor bool) (string, error) {
+	var result authnResponse
+	err := c.post(c.baseURL+"authn", map[string]string{
+		"username": username,
+		"password": password,
+	}, &result)
+	if err != nil {
+		return "", err
+	}
+
+	switch result.Status {
+	case "SUCCESS":
+	case "MFA_REQUIRED":
+		if verifyFactor {
+			if err := c.verifyFactor(&result, totp); err != nil {
+				return "", err
+			}
+		}
+	case "MFA_ENROLL":
+		return "", fmt.Errorf("user must enroll in Okta Verify")
+	default:
+		return "", fmt.Errorf("unexpected authentication status %q", result.Status)
+	}
+
+	return result.Embedded.User.ID, nil
+}
+
+// verifyFactor verifies the Okta Verify factor of an authentication
+// transaction requiring MFA
+func (c *authnClient) verifyFactor(txn *authnResponse, totp string) error {
+	factorType := "push"
+	body := map[string]string{
+		"stateToken": txn.StateToken,
+	}
+	if totp != "" {
+		factorType = "token:software:totp"
+		body["passCode"] = totp
+	}
+
+	var factor *authnFactor
+	for i, f := range txn.Embedded.Factors {
+		if f.Provider == "OKTA" && f.FactorType == factorType {
+			factor = &txn.Embedded.Factors[i]
+			break
+		}
+	}
+	if factor == nil {
+		return fmt.Errorf("user has no Okta Verify %s factor", factorType)
+	}
+
+	var result authnResponse
+	if err := c.post(factor.Links.Verify.Href, body, &result); err != nil {
+		return err
+	}
+
+	// Pushes are answered on the device of the user; poll until then
+	deadline := time.Now().Add(pushTimeout)
+	for result.Status == "MFA_CHALLENGE" && result.FactorResult == "WAITING" {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for Okta Verify push")
+		}
+		time.Sleep(pushPollInterval)
+
+		next := result.Links.Next.Href
+		result = authnResponse{}
+		if err := c.post(next, map[string]string{"stateToken": txn.StateToken}, &result); err != nil {
+			return err
+		}
+	}
+
+	if result.Status != "SUCCESS" {
+		switch result.FactorResult {
+		case "REJECTED":
+			return fmt.Errorf("Okta Verify push rejected")
+		case "TIMEOUT":
+			return fmt.Errorf("Okta Verify push timed out")
+		default:
+			return fmt.Errorf("Okta Verify failed with status %q", result.Status)
+		}
+	}
+	return nil
+}
+
+func (c *authnClient) post(url string, body, result interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var oktaErr struct {
+			ErrorSummary string `json:"errorSummary"`
+		}
+		json.NewDecoder(resp.Body).Decode(&oktaErr)
+		return fmt.Errorf("%s (status %d)", oktaErr.ErrorSummary, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,89 @@
+package okta
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testAuthnServer fakes the Okta authentication API for a user enrolled in
+// Okta Verify, accepting the TOTP passcode 123456 and rejecting pushes
+func testAuthnServer(t *testing.T) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		var resp string
+		switch r.URL.Path {
+		case "/api/v1/authn":
+			if body["password"] != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				resp = `{"errorSummary": "Authentication failed"}`
+				break
+			}
+			resp = fmt.Sprintf(`{
+				"status": "MFA_REQUIRED",
+				"stateToken": "state",
+				"_embedded": {
+					"user": {"id": "user-id"},
+					"factors": [
+						{"factorType": "push", "provider": "OKTA", "_links": {"verify": {"href": "%[1]s/verify/push"}}},
+						{"factorType": "token:software:totp", "provider": "OKTA", "_links": {"verify": {"href": "%[1]s/verify/totp"}}}
+					]
+				}
+			}`, server.URL)
+		case "/verify/totp":
+			if body["stateToken"] != "state" || body["passCode"] != "123456" {
+				w.WriteHeader(http.StatusForbidden)
+				resp = `{"errorSummary": "Invalid Passcode/Answer"}`
+				break
+			}
+			resp = `{"status": "SUCCESS"}`
+		case "/verify/push":
+			resp = `{"status": "MFA_CHALLENGE", "factorResult": "REJECTED"}`
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte(resp))
+	}))
+	return server
+}
+
+func TestAuthn_OktaVerify(t *testing.T) {
+	server := testAuthnServer(t)
+	defer server.Close()
+
+	client := &authnClient{
+		client:  server.Client(),
+		baseURL: server.URL + "/api/v1/",
+	}
+
+	if _, err := client.authenticate("bob", "wrong", "123456", true); err == nil {
+		t.Fatal("expected error for wrong password")
+	}
+
+	userID, err := client.authenticate("bob", "secret", "123456", true)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if userID != "user-id" {
+		t.Fatalf("bad: %s", userID)
+	}
+
+	if _, err := client.authenticate("bob", "secret", "000000", true); err == nil {
+		t.Fatal("expected error for wrong passcode")
+	}
+	if _, err := client.authenticate("bob", "secret", "", true); err == nil {
+		t.Fatal("expected error for rejected push")
+	}
+
+	// Skipping the factor only checks the password
+	if _, err := client.authenticate("bob", "secret", "", false); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
@@ -46,7 +46,10 @@ type backend struct {
 	*framework.Backend
 }
 
-func (b *backend) Login(req *logical.Request, username string, password string) ([]string, *logical.Response, error) {
+// Login verifies the credentials of the user with Okta and returns its
+// policies. The Okta Verify factor of the user is verified unless
+// verifyFactor is false, as when renewing, or MFA is bypassed.
+func (b *backend) Login(req *logical.Request, username, password, totp string, verifyFactor bool) ([]string, *logical.Response, error) {
 	cfg, err := b.Config(req.Storage)
 	if err != nil {
 		return nil, nil, err
@@ -55,16 +58,15 @@ func (b *backend) Login(req *logical.Request, username string, password string)
 		return nil, logical.ErrorResponse("Okta backend not configured"), nil
 	}
 
-	client := cfg.OktaClient()
-	auth, err := client.Authenticate(username, password)
+	userID, err := cfg.authnClient().authenticate(username, password, totp, verifyFactor && !cfg.bypassOktaMFA())
 	if err != nil {
 		return nil, logical.ErrorResponse(fmt.Sprintf("Okta auth failed: %v", err)), nil
 	}
-	if auth == nil {
+	if userID == "" {
 		return nil, logical.ErrorResponse("okta auth backend unexpected failure"), nil
 	}
 
-	oktaGroups, err := b.getOktaGroups(cfg, auth.Embedded.User.ID)
+	oktaGroups, err := b.getOktaGroups(cfg, userID)
 	if err != nil {
 		return nil, logical.ErrorResponse(err.Error()), nil
 	}
@@ -140,7 +142,8 @@ func (b *backend) getOktaGroups(cfg *ConfigEntry, userID string) ([]string, erro
 const backendHelp = `
 The Okta credential provider allows authentication querying,
 checking username and password, and associating policies.  If an api token is configure
-groups are pulled down from Okta.
+groups are pulled down from Okta. Users enrolled in Okta Verify must confirm
+logins with a push or a TOTP passcode.
 
 Configuration of the connection is done through the "config" and "policies"
 endpoints by a user with root access. Authentication is then done
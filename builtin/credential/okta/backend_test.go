@@ -66,6 +66,58 @@ func TestBackend_Config(t *testing.T) {
 	})
 }
 
+func TestBackend_ConfigBypassOktaMFA(t *testing.T) {
+	b, err := Factory(&logical.BackendConfig{
+		Logger: logformat.NewVaultLogger(log.LevelTrace),
+		System: &logical.StaticSystemView{},
+	})
+	if err != nil {
+		t.Fatalf("Unable to create backend: %s", err)
+	}
+	storage := &logical.InmemStorage{}
+
+	readBypass := func() interface{} {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "config",
+			Storage:   storage,
+		})
+		if err != nil || resp.IsError() {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+		return resp.Data["bypass_okta_mfa"]
+	}
+
+	// Configurations written before factors were verified keep skipping them
+	entry, err := logical.StorageEntryJSON("config", map[string]interface{}{
+		"organization": "example",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(entry); err != nil {
+		t.Fatal(err)
+	}
+	if bypass := readBypass(); bypass != true {
+		t.Fatalf("bad: %#v", bypass)
+	}
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"bypass_okta_mfa": false,
+		},
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if bypass := readBypass(); bypass != false {
+		t.Fatalf("bad: %#v", bypass)
+	}
+}
+
 func testLoginWrite(t *testing.T, username, password, reason string, expectedTTL time.Duration, policies []string) logicaltest.TestStep {
 	return logicaltest.TestStep{
 		Operation: logical.UpdateOperation,
@@ -37,6 +37,9 @@ func (h *CLIHandler) Auth(c *api.Client, m map[string]string) (string, error) {
 	data := map[string]interface{}{
 		"password": password,
 	}
+	if totp, ok := m["totp"]; ok {
+		data["totp"] = totp
+	}
 
 	path := fmt.Sprintf("auth/%s/login/%s", mount, username)
 	secret, err := c.Logical().Write(path, data)
@@ -56,7 +59,9 @@ func (h *CLIHandler) Help() string {
 The Okta credential provider allows you to authenticate with Okta.
 To use it, first configure it through the "config" endpoint, and then
 login by specifying username and password. If password is not provided
-on the command line, it will be read from stdin.
+on the command line, it will be read from stdin. Users enrolled in Okta
+Verify can give a TOTP passcode with "totp"; otherwise a push is sent to
+their device.
 
     Example: vault auth -method=okta username=john
 
@@ -27,6 +27,11 @@ func pathConfig(b *backend) *framework.Path {
 				Description: `The API endpoint to use. Useful if you
 are using Okta development accounts.`,
 			},
+			"bypass_okta_mfa": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Default:     true,
+				Description: `Skip the Okta Verify factor of users enrolled in it. Useful when using Vault's own MFA instead. Defaults to true.`,
+			},
 			"ttl": &framework.FieldSchema{
 				Type:        framework.TypeDurationSecond,
 				Description: `Duration after which authentication will be expired`,
@@ -82,10 +87,11 @@ func (b *backend) pathConfigRead(
 
 	resp := &logical.Response{
 		Data: map[string]interface{}{
-			"organization": cfg.Org,
-			"base_url":     cfg.BaseURL,
-			"ttl":          cfg.TTL,
-			"max_ttl":      cfg.MaxTTL,
+			"organization":    cfg.Org,
+			"base_url":        cfg.BaseURL,
+			"bypass_okta_mfa": cfg.bypassOktaMFA(),
+			"ttl":             cfg.TTL,
+			"max_ttl":         cfg.MaxTTL,
 		},
 	}
 
@@ -129,6 +135,12 @@ func (b *backend) pathConfigWrite(
 		cfg.BaseURL = d.Get("base_url").(string)
 	}
 
+	bypass, ok := d.GetOk("bypass_okta_mfa")
+	if ok {
+		bypassOktaMFA := bypass.(bool)
+		cfg.BypassOktaMFA = &bypassOktaMFA
+	}
+
 	ttl := d.Get("ttl").(int)
 	cfg.TTL = time.Duration(ttl) * time.Second
 
@@ -170,13 +182,21 @@ func (c *ConfigEntry) OktaClient() *okta.Client {
 	return client
 }
 
+// bypassOktaMFA returns whether the Okta Verify factor is skipped on login.
+// It is skipped unless configured otherwise, as it was before factors were
+// verified, so that upgrading does not send push challenges to users.
+func (c *ConfigEntry) bypassOktaMFA() bool {
+	return c.BypassOktaMFA == nil || *c.BypassOktaMFA
+}
+
 // ConfigEntry for Okta
 type ConfigEntry struct {
-	Org     string        `json:"organization"`
-	Token   string        `json:"token"`
-	BaseURL string        `json:"base_url"`
-	TTL     time.Duration `json:"ttl"`
-	MaxTTL  time.Duration `json:"max_ttl"`
+	Org           string        `json:"organization"`
+	Token         string        `json:"token"`
+	BaseURL       string        `json:"base_url"`
+	BypassOktaMFA *bool         `json:"bypass_okta_mfa,omitempty"`
+	TTL           time.Duration `json:"ttl"`
+	MaxTTL        time.Duration `json:"max_ttl"`
 }
 
 const pathConfigHelp = `
@@ -24,6 +24,11 @@ func pathLogin(b *backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "Password for this user.",
 			},
+
+			"totp": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Okta Verify TOTP passcode. If not given, an Okta Verify push is sent to users enrolled in it.",
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -39,8 +44,9 @@ func (b *backend) pathLogin(
 	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	username := d.Get("username").(string)
 	password := d.Get("password").(string)
+	totp := d.Get("totp").(string)
 
-	policies, resp, err := b.Login(req, username, password)
+	policies, resp, err := b.Login(req, username, password, totp, true)
 	// Handle an internal error
 	if err != nil {
 		return nil, err
@@ -85,7 +91,9 @@ func (b *backend) pathLoginRenew(
 	username := req.Auth.Metadata["username"]
 	password := req.Auth.InternalData["password"].(string)
 
-	loginPolicies, resp, err := b.Login(req, username, password)
+	// The factor was verified at login; renewals only check that the
+	// password and the group memberships are unchanged
+	loginPolicies, resp, err := b.Login(req, username, password, "", false)
 	if len(loginPolicies) == 0 {
 		return resp, err
 	}
@@ -120,5 +128,7 @@ Log in with a username and password.
 `
 
 const pathLoginDesc = `
-This endpoint authenticates using a username and password.
+This endpoint authenticates using a username and password. Users enrolled in
+Okta Verify must also give a TOTP passcode or accept the push sent to their
+device.
 `
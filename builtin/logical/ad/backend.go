@@ -0,0 +1,76 @@
+package ad
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// Factory creates and configures the backend
+func Factory(conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Backend creates a new backend with all the paths belonging to it
+func Backend() *backend {
+	var b backend
+	b.passwordSetter = &ldapPasswordSetter{}
+	b.roleLocks = locksutil.CreateLocks()
+	b.Backend = &framework.Backend{
+		Help: strings.TrimSpace(backendHelp),
+
+		Paths: []*framework.Path{
+			pathConfig(&b),
+			pathListRoles(&b),
+			pathRoles(&b),
+			pathCreds(&b),
+			pathRotateRole(&b),
+		},
+
+		PeriodicFunc: b.rotateExpiredPasswords,
+		Invalidate:   b.invalidate,
+		BackendType:  logical.TypeLogical,
+	}
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+
+	// passwordSetter changes passwords in the directory; it is replaced in
+	// tests
+	passwordSetter passwordSetter
+
+	// roleLocks serialize rotations of the password of a given role
+	roleLocks []*locksutil.LockEntry
+
+	config     *configEntry
+	configLock sync.RWMutex
+}
+
+func (b *backend) invalidate(key string) {
+	switch key {
+	case "config":
+		b.configLock.Lock()
+		b.config = nil
+		b.configLock.Unlock()
+	}
+}
+
+const backendHelp = `
+The Active Directory backend rotates the passwords of existing AD service
+accounts.
+
+After configuring the connection to the domain with "config", each role
+manages the password of one service account. The current password is served
+by reading "creds/<role>"; passwords are rotated automatically once they are
+older than the role's TTL, or on demand by writing to "rotate-role/<role>".
+`
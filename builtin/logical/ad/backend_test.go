@@ -0,0 +1,242 @@
+package ad
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// testPasswordSetter records the passwords set on each DN
+type testPasswordSetter struct {
+	sync.Mutex
+	passwords map[string]string
+	err       error
+}
+
+func (s *testPasswordSetter) SetPassword(config *configEntry, dn, password string) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.passwords[dn] = password
+	return nil
+}
+
+func (s *testPasswordSetter) password(dn string) string {
+	s.Lock()
+	defer s.Unlock()
+	return s.passwords[dn]
+}
+
+func testBackend(t *testing.T) (*backend, logical.Storage, *testPasswordSetter) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setter := &testPasswordSetter{passwords: make(map[string]string)}
+	b.(*backend).passwordSetter = setter
+	return b.(*backend), config.StorageView, setter
+}
+
+func testRequest(t *testing.T, b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   s,
+		Data:      data,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: path: %s, resp: %#v, err: %v", path, resp, err)
+	}
+	return resp
+}
+
+func TestBackend_rotation(t *testing.T) {
+	b, s, setter := testBackend(t)
+	dn := "CN=svc-app,OU=Service Accounts,DC=example,DC=com"
+
+	testRequest(t, b, s, logical.UpdateOperation, "config", map[string]interface{}{
+		"url":             "ldaps://dc.example.com",
+		"binddn":          "CN=vault,DC=example,DC=com",
+		"bindpass":        "secret",
+		"password_length": 20,
+	})
+	testRequest(t, b, s, logical.UpdateOperation, "roles/app", map[string]interface{}{
+		"service_account_dn": dn,
+		"ttl":                "1h",
+	})
+
+	resp := testRequest(t, b, s, logical.ReadOperation, "config", nil)
+	if _, ok := resp.Data["bindpass"]; ok {
+		t.Fatalf("bind password returned: %#v", resp.Data)
+	}
+
+	// The first read sets the password
+	resp = testRequest(t, b, s, logical.ReadOperation, "creds/app", nil)
+	first := resp.Data["current_password"].(string)
+	if len(first) != 20 || setter.password(dn) != first {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp.Data["username"] != dn || resp.Data["last_password"] != "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if ttl := resp.Data["ttl"].(int64); ttl <= 0 || ttl > 3600 {
+		t.Fatalf("bad ttl: %d", ttl)
+	}
+
+	// Later reads return the same password
+	resp = testRequest(t, b, s, logical.ReadOperation, "creds/app", nil)
+	if resp.Data["current_password"] != first {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Rotating on demand keeps the previous password
+	testRequest(t, b, s, logical.UpdateOperation, "rotate-role/app", nil)
+	resp = testRequest(t, b, s, logical.ReadOperation, "creds/app", nil)
+	second := resp.Data["current_password"].(string)
+	if second == first || resp.Data["last_password"] != first || setter.password(dn) != second {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The periodic function only rotates expired passwords
+	if err := b.rotateExpiredPasswords(&logical.Request{Storage: s}); err != nil {
+		t.Fatal(err)
+	}
+	if setter.password(dn) != second {
+		t.Fatal("password rotated before its ttl")
+	}
+
+	creds, err := b.passwords(s, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds.LastRotated = creds.LastRotated.Add(-2 * time.Hour)
+	if err := putPasswords(s, "app", creds); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.rotateExpiredPasswords(&logical.Request{Storage: s}); err != nil {
+		t.Fatal(err)
+	}
+	resp = testRequest(t, b, s, logical.ReadOperation, "creds/app", nil)
+	if resp.Data["current_password"] == second || resp.Data["last_password"] != second {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Deleting the role removes its passwords
+	testRequest(t, b, s, logical.DeleteOperation, "roles/app", nil)
+	if creds, err := b.passwords(s, "app"); err != nil || creds != nil {
+		t.Fatalf("bad: %#v, %v", creds, err)
+	}
+}
+
+func TestBackend_rotationFailure(t *testing.T) {
+	b, s, setter := testBackend(t)
+	dn := "CN=svc-app,DC=example,DC=com"
+
+	testRequest(t, b, s, logical.UpdateOperation, "config", map[string]interface{}{
+		"binddn":   "CN=vault,DC=example,DC=com",
+		"bindpass": "secret",
+	})
+	testRequest(t, b, s, logical.UpdateOperation, "roles/app", map[string]interface{}{
+		"service_account_dn": dn,
+	})
+	resp := testRequest(t, b, s, logical.ReadOperation, "creds/app", nil)
+	current := resp.Data["current_password"].(string)
+
+	setter.err = fmt.Errorf("constraint violation")
+	_, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "rotate-role/app",
+		Storage:   s,
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	// A failed rotation leaves the stored password unchanged
+	resp = testRequest(t, b, s, logical.ReadOperation, "creds/app", nil)
+	if resp.Data["current_password"] != current {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if _, ok := resp.Data["pending_password"]; ok {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestBackend_configValidation(t *testing.T) {
+	b, s, _ := testBackend(t)
+
+	for _, data := range []map[string]interface{}{
+		{"binddn": "CN=vault"},
+		{"binddn": "CN=vault", "bindpass": "secret", "password_length": 8},
+		{"binddn": "CN=vault", "bindpass": "secret", "tls_min_version": "ssl3"},
+	} {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Storage:   s,
+			Data:      data,
+		})
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected error for %#v, got resp: %#v, err: %v", data, resp, err)
+		}
+	}
+
+	// Reading creds requires a config
+	testRequest(t, b, s, logical.UpdateOperation, "roles/app", map[string]interface{}{
+		"service_account_dn": "CN=svc-app",
+	})
+	if _, err := b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/app",
+		Storage:   s,
+	}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestGeneratePassword(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		password, err := generatePassword(minPasswordLength)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(password) != minPasswordLength {
+			t.Fatalf("bad length: %q", password)
+		}
+
+		var lower, upper, digit, symbol bool
+		for _, r := range password {
+			switch {
+			case unicode.IsLower(r):
+				lower = true
+			case unicode.IsUpper(r):
+				upper = true
+			case unicode.IsDigit(r):
+				digit = true
+			case strings.ContainsRune(passwordSymbols, r):
+				symbol = true
+			default:
+				t.Fatalf("unexpected character in %q", password)
+			}
+		}
+		if !lower || !upper || !digit || !symbol {
+			t.Fatalf("missing character class in %q", password)
+		}
+	}
+}
+
+func TestEncodePassword(t *testing.T) {
+	expected := "\x22\x00a\x00\xe9\x00\x22\x00"
+	if encoded := encodePassword("aé"); encoded != expected {
+		t.Fatalf("bad: %q", encoded)
+	}
+}
@@ -0,0 +1,166 @@
+package ad
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/go-ldap/ldap"
+	"github.com/hashicorp/go-multierror"
+)
+
+// passwordSetter changes the password of an account in the directory
+type passwordSetter interface {
+	SetPassword(config *configEntry, dn, password string) error
+}
+
+// ldapPasswordSetter changes passwords by replacing the unicodePwd
+// attribute of the account over LDAP
+type ldapPasswordSetter struct{}
+
+func (s *ldapPasswordSetter) SetPassword(config *configEntry, dn, password string) error {
+	conn, err := dial(config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.Bind(config.BindDN, config.BindPassword); err != nil {
+		return fmt.Errorf("failed to bind as %q: %v", config.BindDN, err)
+	}
+
+	req := ldap.NewModifyRequest(dn)
+	req.Replace("unicodePwd", []string{encodePassword(password)})
+	if err := conn.Modify(req); err != nil {
+		return fmt.Errorf("failed to set the password of %q: %v", dn, err)
+	}
+	return nil
+}
+
+// dial connects to the first reachable URL of the config. Plain ldap://
+// connections are only accepted with StartTLS since AD refuses password
+// changes over unencrypted connections.
+func dial(config *configEntry) (*ldap.Conn, error) {
+	var retErr *multierror.Error
+	for _, uut := range strings.Split(config.Url, ",") {
+		u, err := url.Parse(uut)
+		if err != nil {
+			retErr = multierror.Append(retErr, fmt.Errorf("error parsing url %q: %v", uut, err))
+			continue
+		}
+		host, port, err := net.SplitHostPort(u.Host)
+		if err != nil {
+			host = u.Host
+		}
+
+		var conn *ldap.Conn
+		switch u.Scheme {
+		case "ldap":
+			if !config.StartTLS {
+				retErr = multierror.Append(retErr, fmt.Errorf("url %q requires starttls to be enabled", uut))
+				continue
+			}
+			if port == "" {
+				port = "389"
+			}
+			conn, err = ldap.Dial("tcp", net.JoinHostPort(host, port))
+			if err != nil {
+				break
+			}
+			tlsConfig, tlsErr := config.tlsConfig(host)
+			if tlsErr != nil {
+				conn.Close()
+				err = tlsErr
+				break
+			}
+			if err = conn.StartTLS(tlsConfig); err != nil {
+				conn.Close()
+			}
+		case "ldaps":
+			if port == "" {
+				port = "636"
+			}
+			tlsConfig, tlsErr := config.tlsConfig(host)
+			if tlsErr != nil {
+				err = tlsErr
+				break
+			}
+			conn, err = ldap.DialTLS("tcp", net.JoinHostPort(host, port), tlsConfig)
+		default:
+			retErr = multierror.Append(retErr, fmt.Errorf("invalid LDAP scheme in url %q", uut))
+			continue
+		}
+		if err == nil {
+			return conn, nil
+		}
+		retErr = multierror.Append(retErr, fmt.Errorf("error connecting to host %q: %v", uut, err))
+	}
+
+	return nil, retErr.ErrorOrNil()
+}
+
+// encodePassword returns the value of the unicodePwd attribute for the
+// given password: the quoted password, encoded as UTF-16LE
+func encodePassword(password string) string {
+	encoded := utf16.Encode([]rune("\"" + password + "\""))
+	buf := make([]byte, 2*len(encoded))
+	for i, r := range encoded {
+		binary.LittleEndian.PutUint16(buf[2*i:], r)
+	}
+	return string(buf)
+}
+
+const (
+	passwordLower   = "abcdefghijklmnopqrstuvwxyz"
+	passwordUpper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	passwordDigits  = "0123456789"
+	passwordSymbols = "-_.!#%+="
+)
+
+// generatePassword returns a random password of the given length that
+// contains characters from each of the classes required by the default AD
+// complexity rules
+func generatePassword(length int) (string, error) {
+	classes := []string{passwordLower, passwordUpper, passwordDigits, passwordSymbols}
+	if length < len(classes) {
+		return "", fmt.Errorf("password length must be at least %d", len(classes))
+	}
+	all := strings.Join(classes, "")
+
+	password := make([]byte, length)
+	for i := range password {
+		charset := all
+		if i < len(classes) {
+			charset = classes[i]
+		}
+		c, err := randomChar(charset)
+		if err != nil {
+			return "", err
+		}
+		password[i] = c
+	}
+
+	// Shuffle so that the class of the leading characters is not fixed
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j.Int64()] = password[j.Int64()], password[i]
+	}
+
+	return string(password), nil
+}
+
+func randomChar(charset string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+	if err != nil {
+		return 0, err
+	}
+	return charset[n.Int64()], nil
+}
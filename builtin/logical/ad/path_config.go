@@ -0,0 +1,247 @@
+package ad
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/tlsutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	// defaultRotationTTL is the age after which passwords are rotated when
+	// neither the role nor the config set a TTL
+	defaultRotationTTL = 24 * time.Hour
+
+	defaultPasswordLength = 64
+
+	// minPasswordLength is the shortest password length that leaves room
+	// for every character class required by the default AD complexity rules
+	minPasswordLength = 14
+)
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config",
+		Fields: map[string]*framework.FieldSchema{
+			"url": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "LDAP URL of the domain controller (default: ldaps://127.0.0.1). Multiple URLs can be specified by concatenating them with commas; they will be tried in-order.",
+			},
+			"binddn": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "DN of the account used to change passwords. It must be allowed to reset the passwords of the managed service accounts.",
+			},
+			"bindpass": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Password of the binddn account",
+			},
+			"certificate": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "CA certificate to use when verifying the domain controller's certificate, must be x509 PEM encoded",
+			},
+			"insecure_tls": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: "Skip the domain controller's certificate validation - insecure!",
+			},
+			"starttls": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: "Issue a StartTLS command after establishing an unencrypted connection to an ldap:// URL",
+			},
+			"tls_min_version": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Default:     "tls12",
+				Description: "Minimum TLS version to use. Accepted values are 'tls10', 'tls11' or 'tls12'. Defaults to 'tls12'",
+			},
+			"ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Default age after which passwords are rotated, for roles that do not set their own. Defaults to 24 hours.",
+			},
+			"password_length": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     defaultPasswordLength,
+				Description: fmt.Sprintf("Length of generated passwords. Must be at least %d.", minPasswordLength),
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+type configEntry struct {
+	Url            string        `json:"url" structs:"url" mapstructure:"url"`
+	BindDN         string        `json:"binddn" structs:"binddn" mapstructure:"binddn"`
+	BindPassword   string        `json:"bindpass" structs:"bindpass" mapstructure:"bindpass"`
+	Certificate    string        `json:"certificate" structs:"certificate" mapstructure:"certificate"`
+	InsecureTLS    bool          `json:"insecure_tls" structs:"insecure_tls" mapstructure:"insecure_tls"`
+	StartTLS       bool          `json:"starttls" structs:"starttls" mapstructure:"starttls"`
+	TLSMinVersion  string        `json:"tls_min_version" structs:"tls_min_version" mapstructure:"tls_min_version"`
+	TTL            time.Duration `json:"ttl" structs:"ttl" mapstructure:"ttl"`
+	PasswordLength int           `json:"password_length" structs:"password_length" mapstructure:"password_length"`
+}
+
+func (c *configEntry) tlsConfig(host string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: host,
+	}
+
+	if c.TLSMinVersion != "" {
+		tlsMinVersion, ok := tlsutil.TLSLookup[c.TLSMinVersion]
+		if !ok {
+			return nil, fmt.Errorf("invalid 'tls_min_version' in config")
+		}
+		tlsConfig.MinVersion = tlsMinVersion
+	}
+
+	if c.InsecureTLS {
+		tlsConfig.InsecureSkipVerify = true
+	}
+	if c.Certificate != "" {
+		caPool := x509.NewCertPool()
+		ok := caPool.AppendCertsFromPEM([]byte(c.Certificate))
+		if !ok {
+			return nil, fmt.Errorf("could not append CA certificate")
+		}
+		tlsConfig.RootCAs = caPool
+	}
+	return tlsConfig, nil
+}
+
+// Config returns the configuration of the backend, or nil if it has not
+// been configured yet
+func (b *backend) Config(s logical.Storage) (*configEntry, error) {
+	b.configLock.RLock()
+	if b.config != nil {
+		defer b.configLock.RUnlock()
+		return b.config, nil
+	}
+	b.configLock.RUnlock()
+
+	b.configLock.Lock()
+	defer b.configLock.Unlock()
+
+	// Check again in case it was loaded while waiting for the lock
+	if b.config != nil {
+		return b.config, nil
+	}
+
+	entry, err := s.Get("config")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var config configEntry
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+
+	b.config = &config
+	return b.config, nil
+}
+
+func (b *backend) pathConfigRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	// The bind password is never returned
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"url":             config.Url,
+			"binddn":          config.BindDN,
+			"certificate":     config.Certificate,
+			"insecure_tls":    config.InsecureTLS,
+			"starttls":        config.StartTLS,
+			"tls_min_version": config.TLSMinVersion,
+			"ttl":             int64(config.TTL.Seconds()),
+			"password_length": config.PasswordLength,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config := &configEntry{
+		Url:            strings.ToLower(d.Get("url").(string)),
+		BindDN:         d.Get("binddn").(string),
+		BindPassword:   d.Get("bindpass").(string),
+		InsecureTLS:    d.Get("insecure_tls").(bool),
+		StartTLS:       d.Get("starttls").(bool),
+		TLSMinVersion:  d.Get("tls_min_version").(string),
+		TTL:            time.Duration(d.Get("ttl").(int)) * time.Second,
+		PasswordLength: d.Get("password_length").(int),
+	}
+	if config.Url == "" {
+		config.Url = "ldaps://127.0.0.1"
+	}
+	if config.BindDN == "" || config.BindPassword == "" {
+		return logical.ErrorResponse("binddn and bindpass are required"), nil
+	}
+	if _, ok := tlsutil.TLSLookup[config.TLSMinVersion]; !ok {
+		return logical.ErrorResponse("invalid 'tls_min_version'"), nil
+	}
+	if config.TTL < 0 {
+		return logical.ErrorResponse("ttl cannot be negative"), nil
+	}
+	if config.PasswordLength < minPasswordLength {
+		return logical.ErrorResponse(fmt.Sprintf("password_length must be at least %d", minPasswordLength)), nil
+	}
+
+	if certificate := d.Get("certificate").(string); certificate != "" {
+		block, _ := pem.Decode([]byte(certificate))
+		if block == nil || block.Type != "CERTIFICATE" {
+			return logical.ErrorResponse("failed to decode PEM block in the certificate"), nil
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("failed to parse certificate: %v", err)), nil
+		}
+		config.Certificate = certificate
+	}
+
+	entry, err := logical.StorageEntryJSON("config", config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	b.configLock.Lock()
+	b.config = config
+	b.configLock.Unlock()
+
+	return nil, nil
+}
+
+const pathConfigHelpSyn = `
+Configure the connection to the Active Directory domain.
+`
+
+const pathConfigHelpDesc = `
+This path configures the domain controller used to change passwords and the
+account used to bind to it. Active Directory only accepts password changes
+over an encrypted connection, so either an ldaps:// URL or StartTLS must be
+used.
+
+The "ttl" and "password_length" values apply to every role that does not
+override them.
+`
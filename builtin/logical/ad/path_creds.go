@@ -0,0 +1,285 @@
+package ad
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathCreds(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "creds/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathCredsRead,
+		},
+
+		HelpSynopsis:    pathCredsHelpSyn,
+		HelpDescription: pathCredsHelpDesc,
+	}
+}
+
+func pathRotateRole(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "rotate-role/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRotateRoleUpdate,
+		},
+
+		HelpSynopsis:    pathRotateRoleHelpSyn,
+		HelpDescription: pathRotateRoleHelpDesc,
+	}
+}
+
+// passwordEntry holds the passwords of a role's service account
+type passwordEntry struct {
+	CurrentPassword string    `json:"current_password"`
+	LastPassword    string    `json:"last_password"`
+	LastRotated     time.Time `json:"last_rotated"`
+
+	// PendingPassword is set while a new password is being sent to the
+	// directory. If it is still set afterwards the change may or may not
+	// have been applied, so it is reported alongside the current password.
+	PendingPassword string `json:"pending_password,omitempty"`
+}
+
+func (b *backend) roleLock(name string) *locksutil.LockEntry {
+	return locksutil.LockForKey(b.roleLocks, name)
+}
+
+func (b *backend) passwords(s logical.Storage, name string) (*passwordEntry, error) {
+	entry, err := s.Get("creds/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result passwordEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func putPasswords(s logical.Storage, name string, creds *passwordEntry) error {
+	entry, err := logical.StorageEntryJSON("creds/"+name, creds)
+	if err != nil {
+		return err
+	}
+	return s.Put(entry)
+}
+
+func (b *backend) pathCredsRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	lock := b.roleLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	role, err := b.Role(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown role: %s", name)), nil
+	}
+
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := b.passwords(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The password is set the first time it is requested, since it is not
+	// known until then
+	if creds == nil || creds.CurrentPassword == "" {
+		creds, err = b.rotatePassword(req.Storage, name, role)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ttl := creds.LastRotated.Add(role.rotationTTL(config)).Sub(time.Now())
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"username":         role.ServiceAccountDN,
+			"current_password": creds.CurrentPassword,
+			"last_password":    creds.LastPassword,
+			"last_rotated":     creds.LastRotated.Format(time.RFC3339),
+			"ttl":              int64(ttl.Seconds()),
+		},
+	}
+	if creds.PendingPassword != "" {
+		resp.Data["pending_password"] = creds.PendingPassword
+		resp.AddWarning("A previous rotation did not complete; the account's password may be pending_password")
+	}
+	return resp, nil
+}
+
+func (b *backend) pathRotateRoleUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	lock := b.roleLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	role, err := b.Role(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown role: %s", name)), nil
+	}
+
+	if _, err := b.rotatePassword(req.Storage, name, role); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// rotatePassword sets a new password on the role's service account and
+// stores it. It must be called with the role's lock held.
+func (b *backend) rotatePassword(s logical.Storage, name string, role *roleEntry) (*passwordEntry, error) {
+	config, err := b.Config(s)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, fmt.Errorf("the backend must be configured with config first")
+	}
+
+	creds, err := b.passwords(s, name)
+	if err != nil {
+		return nil, err
+	}
+	if creds == nil {
+		creds = &passwordEntry{}
+	}
+
+	password, err := generatePassword(config.PasswordLength)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the new password before changing it in the directory so that
+	// it is not lost if storing it afterwards fails
+	creds.PendingPassword = password
+	if err := putPasswords(s, name, creds); err != nil {
+		return nil, err
+	}
+
+	if err := b.passwordSetter.SetPassword(config, role.ServiceAccountDN, password); err != nil {
+		creds.PendingPassword = ""
+		if putErr := putPasswords(s, name, creds); putErr != nil {
+			b.Logger().Error("ad: failed to clear pending password", "role", name, "error", putErr)
+		}
+		return nil, err
+	}
+
+	creds.LastPassword = creds.CurrentPassword
+	creds.CurrentPassword = password
+	creds.PendingPassword = ""
+	creds.LastRotated = time.Now().UTC()
+	if err := putPasswords(s, name, creds); err != nil {
+		return nil, err
+	}
+
+	if b.Logger().IsDebug() {
+		b.Logger().Debug("ad: rotated password", "role", name)
+	}
+	return creds, nil
+}
+
+// rotateExpiredPasswords is the periodic function of the backend; it rotates
+// the passwords that are older than their role's TTL
+func (b *backend) rotateExpiredPasswords(req *logical.Request) error {
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		return nil
+	}
+
+	names, err := req.Storage.List("role/")
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err := b.rotateIfExpired(req.Storage, name, config); err != nil {
+			b.Logger().Error("ad: failed to rotate password", "role", name, "error", err)
+		}
+	}
+	return nil
+}
+
+func (b *backend) rotateIfExpired(s logical.Storage, name string, config *configEntry) error {
+	lock := b.roleLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	role, err := b.Role(s, name)
+	if err != nil || role == nil {
+		return err
+	}
+
+	// Passwords that have not been set yet are left alone until they are
+	// first requested
+	creds, err := b.passwords(s, name)
+	if err != nil || creds == nil || creds.CurrentPassword == "" {
+		return err
+	}
+
+	if time.Now().Before(creds.LastRotated.Add(role.rotationTTL(config))) {
+		return nil
+	}
+
+	_, err = b.rotatePassword(s, name, role)
+	return err
+}
+
+const pathCredsHelpSyn = `
+Read the current password of a role's service account.
+`
+
+const pathCredsHelpDesc = `
+This path returns the current and previous passwords of the service account
+managed by the role, along with the number of seconds until the password is
+next rotated. The password is set the first time this path is read.
+`
+
+const pathRotateRoleHelpSyn = `
+Rotate the password of a role's service account now.
+`
+
+const pathRotateRoleHelpDesc = `
+Writing to this path immediately sets a new password on the service account
+managed by the role, regardless of its ttl.
+`
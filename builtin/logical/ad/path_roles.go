@@ -0,0 +1,186 @@
+package ad
+
+import (
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathListRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/?$",
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+func pathRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+			"service_account_dn": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "DN of the existing service account whose password is managed by the role.",
+			},
+			"ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Age after which the password is rotated. Defaults to the ttl set in config.",
+			},
+		},
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.UpdateOperation: b.pathRoleUpdate,
+			logical.DeleteOperation: b.pathRoleDelete,
+		},
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+type roleEntry struct {
+	ServiceAccountDN string        `json:"service_account_dn" structs:"service_account_dn" mapstructure:"service_account_dn"`
+	TTL              time.Duration `json:"ttl" structs:"ttl" mapstructure:"ttl"`
+}
+
+// rotationTTL returns the age after which the role's password is rotated
+func (r *roleEntry) rotationTTL(config *configEntry) time.Duration {
+	switch {
+	case r.TTL > 0:
+		return r.TTL
+	case config != nil && config.TTL > 0:
+		return config.TTL
+	default:
+		return defaultRotationTTL
+	}
+}
+
+// Role reads the role configuration from the storage
+func (b *backend) Role(s logical.Storage, n string) (*roleEntry, error) {
+	entry, err := s.Get("role/" + n)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result roleEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Deletes an existing role, along with the passwords stored for it. The
+// password of the service account itself is left unchanged.
+func (b *backend) pathRoleDelete(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	lock := b.roleLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := req.Storage.Delete("creds/" + name); err != nil {
+		return nil, err
+	}
+	return nil, req.Storage.Delete("role/" + name)
+}
+
+// Reads an existing role
+func (b *backend) pathRoleRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	role, err := b.Role(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	creds, err := b.passwords(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"service_account_dn": role.ServiceAccountDN,
+			"ttl":                int64(role.TTL.Seconds()),
+		},
+	}
+	if creds != nil {
+		resp.Data["last_rotated"] = creds.LastRotated.Format(time.RFC3339)
+	}
+	return resp, nil
+}
+
+// Lists all the roles registered with the backend
+func (b *backend) pathRoleList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roles, err := req.Storage.List("role/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(roles), nil
+}
+
+// Registers a new role with the backend, or updates an existing one
+func (b *backend) pathRoleUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	role, err := b.Role(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		role = &roleEntry{}
+	}
+
+	if dnRaw, ok := d.GetOk("service_account_dn"); ok {
+		role.ServiceAccountDN = dnRaw.(string)
+	}
+	if role.ServiceAccountDN == "" {
+		return logical.ErrorResponse("missing service_account_dn"), nil
+	}
+
+	if ttlRaw, ok := d.GetOk("ttl"); ok {
+		role.TTL = time.Duration(ttlRaw.(int)) * time.Second
+	}
+	if role.TTL < 0 {
+		return logical.ErrorResponse("ttl cannot be negative"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("role/"+name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathRoleHelpSyn = `
+Manage the roles that map to existing service accounts.
+`
+
+const pathRoleHelpDesc = `
+Each role manages the password of one existing service account, identified
+by its DN. The password is first set when the credentials of the role are
+read, and is rotated once it is older than the role's ttl.
+
+Deleting a role removes the stored passwords but does not change the password
+of the service account.
+`
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
 
 	"errors"
 	"strings"
 
+	"github.com/hashicorp/vault/helper/usernametemplate"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -34,15 +36,34 @@ func pathRoles() *framework.Path {
 				Description: "Name of the policy",
 			},
 
+			"credential_type": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Type of credentials issued for the role: "iam_user",
+"assumed_role" or "federation_token". Defaults to "assumed_role" when arn
+references an IAM role and to "iam_user" otherwise.`,
+			},
+
 			"arn": &framework.FieldSchema{
 				Type:        framework.TypeString,
-				Description: "ARN Reference to a managed policy",
+				Description: "ARN Reference to a managed policy, or of the IAM role to assume",
 			},
 
 			"policy": &framework.FieldSchema{
 				Type:        framework.TypeString,
 				Description: "IAM policy document",
 			},
+
+			"default_sts_ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Default lifetime of STS credentials issued for the role when none is requested. Defaults to 1 hour.",
+			},
+
+			"username_template": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Go template generating the names of the IAM users,
+federated users or role sessions created for the role, given the role name as
+.RoleName and the display name of the token as .DisplayName.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -56,19 +77,161 @@ func pathRoles() *framework.Path {
 	}
 }
 
+const (
+	credentialTypeIAMUser         = "iam_user"
+	credentialTypeAssumedRole     = "assumed_role"
+	credentialTypeFederationToken = "federation_token"
+
+	// defaultSTSTTL is the lifetime of STS credentials when neither the
+	// request nor the role sets one
+	defaultSTSTTL = 3600
+)
+
+// awsRoleEntry is a role as stored under "role/". Roles written before
+// credential types existed are stored as the raw policy document or ARN
+// under "policy/" and are read through legacyRoleEntry.
+type awsRoleEntry struct {
+	CredentialType string `json:"credential_type"`
+	PolicyDocument string `json:"policy_document"`
+	Arn            string `json:"arn"`
+	DefaultSTSTTL  int64  `json:"default_sts_ttl"`
+
+	UsernameTemplate string `json:"username_template"`
+}
+
+// isRoleArn returns whether the ARN references an IAM role rather than a
+// managed policy
+func isRoleArn(arn string) bool {
+	return strings.HasPrefix(arn, "arn:") && strings.Contains(arn, ":role/")
+}
+
+func legacyRoleEntry(val string) *awsRoleEntry {
+	if !strings.HasPrefix(val, "arn:") {
+		return &awsRoleEntry{
+			CredentialType: credentialTypeIAMUser,
+			PolicyDocument: val,
+		}
+	}
+	if isRoleArn(val) {
+		return &awsRoleEntry{
+			CredentialType: credentialTypeAssumedRole,
+			Arn:            val,
+		}
+	}
+	return &awsRoleEntry{
+		CredentialType: credentialTypeIAMUser,
+		Arn:            val,
+	}
+}
+
+func (r *awsRoleEntry) validate() error {
+	switch r.CredentialType {
+	case credentialTypeIAMUser:
+		if r.PolicyDocument == "" && r.Arn == "" {
+			return errors.New("Either policy or arn must be provided")
+		}
+		if r.PolicyDocument != "" && r.Arn != "" {
+			return errors.New("Only one of policy or arn should be provided")
+		}
+	case credentialTypeAssumedRole:
+		// The policy, if any, further restricts the assumed role's session
+		if !isRoleArn(r.Arn) {
+			return errors.New("arn must reference an IAM role for the assumed_role credential type")
+		}
+	case credentialTypeFederationToken:
+		if r.PolicyDocument == "" {
+			return errors.New("policy must be provided for the federation_token credential type")
+		}
+		if r.Arn != "" {
+			return errors.New("arn cannot be used with the federation_token credential type")
+		}
+	default:
+		return fmt.Errorf("unknown credential_type %q", r.CredentialType)
+	}
+
+	if r.DefaultSTSTTL < 0 {
+		return errors.New("default_sts_ttl cannot be negative")
+	}
+	if r.DefaultSTSTTL != 0 && r.CredentialType == credentialTypeIAMUser {
+		return errors.New("default_sts_ttl is only valid for the assumed_role and federation_token credential types")
+	}
+
+	if r.UsernameTemplate != "" {
+		if _, err := usernametemplate.Parse(r.UsernameTemplate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stsTTL returns the lifetime of STS credentials issued for the role, given
+// the requested one if any
+func (r *awsRoleEntry) stsTTL(d *framework.FieldData) int64 {
+	if ttlRaw, ok := d.GetOk("ttl"); ok {
+		return int64(ttlRaw.(int))
+	}
+	if r.DefaultSTSTTL > 0 {
+		return r.DefaultSTSTTL
+	}
+	return defaultSTSTTL
+}
+
+// getRole reads the role from storage, falling back to the legacy format
+func getRole(s logical.Storage, name string) (*awsRoleEntry, error) {
+	entry, err := s.Get("role/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		var role awsRoleEntry
+		if err := entry.DecodeJSON(&role); err != nil {
+			return nil, err
+		}
+		return &role, nil
+	}
+
+	entry, err = s.Get("policy/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	return legacyRoleEntry(string(entry.Value)), nil
+}
+
 func (b *backend) pathRoleList(
 	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	entries, err := req.Storage.List("policy/")
+	legacy, err := req.Storage.List("policy/")
 	if err != nil {
 		return nil, err
 	}
+	entries, err := req.Storage.List("role/")
+	if err != nil {
+		return nil, err
+	}
+
+	// A role is only stored in both places while it is being rewritten
+	seen := make(map[string]bool, len(entries))
+	for _, name := range entries {
+		seen[name] = true
+	}
+	for _, name := range legacy {
+		if !seen[name] {
+			entries = append(entries, name)
+		}
+	}
+	sort.Strings(entries)
 	return logical.ListResponse(entries), nil
 }
 
 func pathRolesDelete(
 	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	err := req.Storage.Delete("policy/" + d.Get("name").(string))
-	if err != nil {
+	name := d.Get("name").(string)
+	if err := req.Storage.Delete("role/" + name); err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Delete("policy/" + name); err != nil {
 		return nil, err
 	}
 
@@ -77,73 +240,74 @@ func pathRolesDelete(
 
 func pathRolesRead(
 	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	entry, err := req.Storage.Get("policy/" + d.Get("name").(string))
+	role, err := getRole(req.Storage, d.Get("name").(string))
 	if err != nil {
 		return nil, err
 	}
-	if entry == nil {
+	if role == nil {
 		return nil, nil
 	}
 
-	val := string(entry.Value)
-	if strings.HasPrefix(val, "arn:") {
-		return &logical.Response{
-			Data: map[string]interface{}{
-				"arn": val,
-			},
-		}, nil
+	data := map[string]interface{}{
+		"credential_type": role.CredentialType,
+		"default_sts_ttl": role.DefaultSTSTTL,
+	}
+	if role.PolicyDocument != "" {
+		data["policy"] = role.PolicyDocument
+	}
+	if role.Arn != "" {
+		data["arn"] = role.Arn
+	}
+	if role.UsernameTemplate != "" {
+		data["username_template"] = role.UsernameTemplate
 	}
 	return &logical.Response{
-		Data: map[string]interface{}{
-			"policy": val,
-		},
+		Data: data,
 	}, nil
 }
 
-func useInlinePolicy(d *framework.FieldData) (bool, error) {
-	bp := d.Get("policy").(string) != ""
-	ba := d.Get("arn").(string) != ""
+func pathRolesWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
 
-	if !bp && !ba {
-		return false, errors.New("Either policy or arn must be provided")
+	role := &awsRoleEntry{
+		CredentialType: d.Get("credential_type").(string),
+		Arn:            d.Get("arn").(string),
+		DefaultSTSTTL:  int64(d.Get("default_sts_ttl").(int)),
+
+		UsernameTemplate: d.Get("username_template").(string),
 	}
-	if bp && ba {
-		return false, errors.New("Only one of policy or arn should be provided")
+	if role.CredentialType == "" {
+		role.CredentialType = credentialTypeIAMUser
+		if isRoleArn(role.Arn) {
+			role.CredentialType = credentialTypeAssumedRole
+		}
 	}
-	return bp, nil
-}
 
-func pathRolesWrite(
-	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	var buf bytes.Buffer
+	if policy := d.Get("policy").(string); policy != "" {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, []byte(policy)); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"Error compacting policy: %s", err)), nil
+		}
+		role.PolicyDocument = buf.String()
+	}
 
-	uip, err := useInlinePolicy(d)
+	if err := role.validate(); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("role/"+name, role)
 	if err != nil {
 		return nil, err
 	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
 
-	if uip {
-		if err := json.Compact(&buf, []byte(d.Get("policy").(string))); err != nil {
-			return logical.ErrorResponse(fmt.Sprintf(
-				"Error compacting policy: %s", err)), nil
-		}
-		// Write the policy into storage
-		err := req.Storage.Put(&logical.StorageEntry{
-			Key:   "policy/" + d.Get("name").(string),
-			Value: buf.Bytes(),
-		})
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		// Write the arn ref into storage
-		err := req.Storage.Put(&logical.StorageEntry{
-			Key:   "policy/" + d.Get("name").(string),
-			Value: []byte(d.Get("arn").(string)),
-		})
-		if err != nil {
-			return nil, err
-		}
+	// Drop the legacy entry now that the role has been rewritten
+	if err := req.Storage.Delete("policy/" + name); err != nil {
+		return nil, err
 	}
 
 	return nil, nil
@@ -154,7 +318,7 @@ const pathListRolesHelpSyn = `List the existing roles in this backend`
 const pathListRolesHelpDesc = `Roles will be listed by the role name.`
 
 const pathRolesHelpSyn = `
-Read, write and reference IAM policies that access keys can be made for.
+Read, write and reference IAM policies and roles that credentials can be made for.
 `
 
 const pathRolesHelpDesc = `
@@ -170,5 +334,19 @@ reference (via the arn argument). Inline user policies written are normal
 IAM policies. Vault will not attempt to parse these except to validate
 that they're basic JSON. No validation is performed on arn references.
 
+Each role has a credential type. The "iam_user" type creates an IAM user
+with the policy attached for each set of credentials and deletes it when the
+lease is revoked. The "assumed_role" type assumes the IAM role referenced by
+arn, optionally restricted by an inline policy, and the "federation_token"
+type gets a federation token restricted to the inline policy. Both return
+temporary STS credentials that expire on their own and never need to be
+revoked; their lifetime defaults to default_sts_ttl.
+
+The "username_template" parameter is a Go template generating the names of
+the IAM users, federated users or role sessions, given the role name as
+.RoleName and the display name of the token as .DisplayName. The generated
+names must satisfy the AWS limits: 64 characters for IAM users and role
+sessions, 32 for federated users.
+
 To validate the keys, attempt to read an access key after writing the policy.
 `
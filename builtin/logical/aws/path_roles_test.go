@@ -1,6 +1,7 @@
 package aws
 
 import (
+	"reflect"
 	"strconv"
 	"testing"
 
@@ -62,3 +63,152 @@ func TestBackend_PathListRoles(t *testing.T) {
 		t.Fatalf("failed to list all 10 roles")
 	}
 }
+
+func TestBackend_roleCredentialTypes(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b := Backend()
+	if err := b.Setup(config); err != nil {
+		t.Fatal(err)
+	}
+
+	roleReq := func(name string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "roles/" + name,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+	}
+
+	cases := map[string]struct {
+		data     map[string]interface{}
+		credType string
+		err      bool
+	}{
+		"inline":      {map[string]interface{}{"policy": `{"Version": "2012-10-17"}`}, credentialTypeIAMUser, false},
+		"managed":     {map[string]interface{}{"arn": "arn:aws:iam::aws:policy/ReadOnly"}, credentialTypeIAMUser, false},
+		"inferred":    {map[string]interface{}{"arn": "arn:aws:iam::123456789012:role/deploy"}, credentialTypeAssumedRole, false},
+		"federation":  {map[string]interface{}{"credential_type": "federation_token", "policy": "{}", "default_sts_ttl": "2h"}, credentialTypeFederationToken, false},
+		"session":     {map[string]interface{}{"credential_type": "assumed_role", "arn": "arn:aws:iam::123456789012:role/deploy", "policy": "{}"}, credentialTypeAssumedRole, false},
+		"both":        {map[string]interface{}{"policy": "{}", "arn": "arn:aws:iam::aws:policy/ReadOnly"}, "", true},
+		"no_role_arn": {map[string]interface{}{"credential_type": "assumed_role", "arn": "arn:aws:iam::aws:policy/ReadOnly"}, "", true},
+		"fed_arn":     {map[string]interface{}{"credential_type": "federation_token", "arn": "arn:aws:iam::123456789012:role/deploy"}, "", true},
+		"user_ttl":    {map[string]interface{}{"policy": "{}", "default_sts_ttl": "1h"}, "", true},
+		"unknown":     {map[string]interface{}{"credential_type": "bogus", "policy": "{}"}, "", true},
+		"template":    {map[string]interface{}{"policy": "{}", "username_template": "app-{{.RoleName}}-{{random 8}}"}, credentialTypeIAMUser, false},
+		"bad_tmpl":    {map[string]interface{}{"policy": "{}", "username_template": "{{.Bogus}}"}, "", true},
+	}
+	for name, tc := range cases {
+		resp, err := roleReq(name, tc.data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if tc.err {
+			if resp == nil || !resp.IsError() {
+				t.Fatalf("%s: expected error", name)
+			}
+			continue
+		}
+		if resp != nil && resp.IsError() {
+			t.Fatalf("%s: bad: %#v", name, resp)
+		}
+
+		resp, err = b.HandleRequest(&logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "roles/" + name,
+			Storage:   config.StorageView,
+		})
+		if err != nil || resp == nil {
+			t.Fatalf("%s: bad: resp: %#v, err: %v", name, resp, err)
+		}
+		if resp.Data["credential_type"] != tc.credType {
+			t.Fatalf("%s: bad: %#v", name, resp.Data)
+		}
+	}
+
+	role, err := getRole(config.StorageView, "federation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role.DefaultSTSTTL != 7200 {
+		t.Fatalf("bad: %#v", role)
+	}
+}
+
+func TestBackend_legacyRoles(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b := Backend()
+	if err := b.Setup(config); err != nil {
+		t.Fatal(err)
+	}
+
+	legacy := map[string]string{
+		"inline":  `{"Version":"2012-10-17"}`,
+		"managed": "arn:aws:iam::aws:policy/ReadOnly",
+		"role":    "arn:aws:iam::123456789012:role/deploy",
+	}
+	for name, val := range legacy {
+		err := config.StorageView.Put(&logical.StorageEntry{
+			Key:   "policy/" + name,
+			Value: []byte(val),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string]*awsRoleEntry{
+		"inline":  {CredentialType: credentialTypeIAMUser, PolicyDocument: legacy["inline"]},
+		"managed": {CredentialType: credentialTypeIAMUser, Arn: legacy["managed"]},
+		"role":    {CredentialType: credentialTypeAssumedRole, Arn: legacy["role"]},
+	}
+	for name, exp := range expected {
+		role, err := getRole(config.StorageView, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(role, exp) {
+			t.Fatalf("%s: expected %#v, got %#v", name, exp, role)
+		}
+	}
+
+	// Rewriting a role moves it out of the legacy location
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/inline",
+		Storage:   config.StorageView,
+		Data:      map[string]interface{}{"credential_type": "federation_token", "policy": "{}"},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if entry, err := config.StorageView.Get("policy/inline"); err != nil || entry != nil {
+		t.Fatalf("bad: entry: %#v, err: %v", entry, err)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "roles/",
+		Storage:   config.StorageView,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if keys := resp.Data["keys"].([]string); !reflect.DeepEqual(keys, []string{"inline", "managed", "role"}) {
+		t.Fatalf("bad: %v", keys)
+	}
+
+	// The managed policy can't be used for STS credentials
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "sts/managed",
+		Storage:   config.StorageView,
+	})
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+}
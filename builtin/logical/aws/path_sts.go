@@ -2,7 +2,6 @@ package aws
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -42,35 +41,44 @@ the session for AWS account owners defaults to one hour.`,
 func (b *backend) pathSTSRead(
 	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	policyName := d.Get("name").(string)
-	ttl := int64(d.Get("ttl").(int))
 
-	// Read the policy
-	policy, err := req.Storage.Get("policy/" + policyName)
+	// Read the role
+	role, err := getRole(req.Storage, policyName)
 	if err != nil {
 		return nil, fmt.Errorf("error retrieving role: %s", err)
 	}
-	if policy == nil {
+	if role == nil {
 		return logical.ErrorResponse(fmt.Sprintf(
 			"Role '%s' not found", policyName)), nil
 	}
-	policyValue := string(policy.Value)
-	if strings.HasPrefix(policyValue, "arn:") {
-		if strings.Contains(policyValue, ":role/") {
-			return b.assumeRole(
-				req.Storage,
-				req.DisplayName, policyName, policyValue,
-				ttl,
-			)
-		} else {
-			return logical.ErrorResponse(
-					"Can't generate STS credentials for a managed policy; use a role to assume or an inline policy instead"),
-				logical.ErrInvalidRequest
-		}
+
+	// Inline policies of iam_user roles have always been usable for
+	// federation tokens, but managed policies cannot be
+	if role.CredentialType == credentialTypeIAMUser && role.Arn != "" {
+		return logical.ErrorResponse(
+				"Can't generate STS credentials for a managed policy; use a role to assume or an inline policy instead"),
+			logical.ErrInvalidRequest
+	}
+
+	return b.stsCredsCreate(req, policyName, role, role.stsTTL(d))
+}
+
+// stsCredsCreate returns STS credentials for the role, assuming the role's
+// IAM role if it has one and getting a federation token otherwise
+func (b *backend) stsCredsCreate(req *logical.Request, policyName string,
+	role *awsRoleEntry, ttl int64) (*logical.Response, error) {
+	if role.CredentialType == credentialTypeAssumedRole {
+		return b.assumeRole(
+			req.Storage,
+			req.DisplayName, policyName, role.Arn, role.PolicyDocument, role.UsernameTemplate,
+			ttl,
+		)
 	}
+
 	// Use the helper to create the secret
 	return b.secretTokenCreate(
 		req.Storage,
-		req.DisplayName, policyName, policyValue,
+		req.DisplayName, policyName, role.PolicyDocument, role.UsernameTemplate,
 		ttl,
 	)
 }
@@ -85,8 +93,10 @@ accessing AWS. The IAM policy used to back this key pair will be
 the "name" parameter. For example, if this backend is mounted at "aws",
 then "aws/sts/deploy" would generate access keys for the "deploy" role.
 
-Note, these credentials are instantiated using the AWS STS backend.
+Note, these credentials are instantiated using the AWS STS backend. Roles
+with the "assumed_role" or "federation_token" credential type also return
+STS credentials from "aws/creds/<name>".
 
-The access keys will have a lease associated with them. The access keys
-can be revoked by using the lease ID.
+The access keys will have a lease associated with them, but they are not
+renewable and expire on their own; revoking the lease has no effect on them.
 `
@@ -18,10 +18,17 @@ func pathUser(b *backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "Name of the role",
 			},
+			"ttl": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `Lifetime of STS credentials in seconds, for roles with the
+assumed_role or federation_token credential type. Defaults to the role's
+default_sts_ttl.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
-			logical.ReadOperation: b.pathUserRead,
+			logical.ReadOperation:   b.pathUserRead,
+			logical.UpdateOperation: b.pathUserRead,
 		},
 
 		HelpSynopsis:    pathUserHelpSyn,
@@ -33,19 +40,32 @@ func (b *backend) pathUserRead(
 	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	policyName := d.Get("name").(string)
 
-	// Read the policy
-	policy, err := req.Storage.Get("policy/" + policyName)
+	// Read the role
+	role, err := getRole(req.Storage, policyName)
 	if err != nil {
 		return nil, fmt.Errorf("error retrieving role: %s", err)
 	}
-	if policy == nil {
+	if role == nil {
 		return logical.ErrorResponse(fmt.Sprintf(
 			"Role '%s' not found", policyName)), nil
 	}
 
+	if role.CredentialType != credentialTypeIAMUser {
+		return b.stsCredsCreate(req, policyName, role, role.stsTTL(d))
+	}
+	if _, ok := d.GetOk("ttl"); ok {
+		return logical.ErrorResponse(
+			"ttl is only valid for the assumed_role and federation_token credential types"), nil
+	}
+
+	policy := role.PolicyDocument
+	if role.Arn != "" {
+		policy = role.Arn
+	}
+
 	// Use the helper to create the secret
 	return b.secretAccessKeysCreate(
-		req.Storage, req.DisplayName, policyName, string(policy.Value))
+		req.Storage, req.DisplayName, policyName, policy, role.UsernameTemplate)
 }
 
 func pathUserRollback(req *logical.Request, _kind string, data interface{}) error {
@@ -169,6 +189,9 @@ accessing AWS. The IAM policy used to back this key pair will be
 the "name" parameter. For example, if this backend is mounted at "aws",
 then "aws/creds/deploy" would generate access keys for the "deploy" role.
 
+Roles with the "assumed_role" or "federation_token" credential type return
+temporary STS credentials instead, as "aws/sts/<name>" does.
+
 The access keys will have a lease associated with them. The access keys
 can be revoked by using the lease ID.
 `
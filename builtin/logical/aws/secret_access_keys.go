@@ -11,6 +11,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/hashicorp/vault/helper/usernametemplate"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -64,15 +65,48 @@ func genUsername(displayName, policyName, userType string) (ret string, warning
 	return
 }
 
+// generateUsername returns the name of the IAM user, federated user or role
+// session to create, generated from the username template of the role if it
+// has one. Templated names are not truncated; those AWS would reject are.
+func generateUsername(usernameTemplate, displayName, policyName, userType string) (string, string, error) {
+	if usernameTemplate == "" {
+		username, warning := genUsername(displayName, policyName, userType)
+		return username, warning, nil
+	}
+
+	username, err := usernametemplate.Generate(usernameTemplate, usernametemplate.Data{
+		RoleName:    policyName,
+		DisplayName: normalizeDisplayName(displayName),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	maxLen := 64
+	if userType == "sts" {
+		maxLen = 32
+	}
+	if len(username) < 2 || len(username) > maxLen {
+		return "", "", fmt.Errorf("username %q generated from the template must be between 2 and %d characters long", username, maxLen)
+	}
+	if normalizeDisplayName(username) != username {
+		return "", "", fmt.Errorf("username %q generated from the template contains characters not allowed by AWS", username)
+	}
+	return username, "", nil
+}
+
 func (b *backend) secretTokenCreate(s logical.Storage,
-	displayName, policyName, policy string,
+	displayName, policyName, policy, usernameTemplate string,
 	lifeTimeInSeconds int64) (*logical.Response, error) {
 	STSClient, err := clientSTS(s)
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), nil
 	}
 
-	username, usernameWarning := genUsername(displayName, policyName, "sts")
+	username, usernameWarning, err := generateUsername(usernameTemplate, displayName, policyName, "sts")
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
 
 	tokenResp, err := STSClient.GetFederationToken(
 		&sts.GetFederationTokenInput{
@@ -110,21 +144,27 @@ func (b *backend) secretTokenCreate(s logical.Storage,
 }
 
 func (b *backend) assumeRole(s logical.Storage,
-	displayName, policyName, policy string,
+	displayName, policyName, policy, sessionPolicy, usernameTemplate string,
 	lifeTimeInSeconds int64) (*logical.Response, error) {
 	STSClient, err := clientSTS(s)
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), nil
 	}
 
-	username, usernameWarning := genUsername(displayName, policyName, "iam_user")
+	username, usernameWarning, err := generateUsername(usernameTemplate, displayName, policyName, "iam_user")
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
 
-	tokenResp, err := STSClient.AssumeRole(
-		&sts.AssumeRoleInput{
-			RoleSessionName: aws.String(username),
-			RoleArn:         aws.String(policy),
-			DurationSeconds: &lifeTimeInSeconds,
-		})
+	input := &sts.AssumeRoleInput{
+		RoleSessionName: aws.String(username),
+		RoleArn:         aws.String(policy),
+		DurationSeconds: &lifeTimeInSeconds,
+	}
+	if sessionPolicy != "" {
+		input.Policy = aws.String(sessionPolicy)
+	}
+	tokenResp, err := STSClient.AssumeRole(input)
 
 	if err != nil {
 		return logical.ErrorResponse(fmt.Sprintf(
@@ -156,13 +196,16 @@ func (b *backend) assumeRole(s logical.Storage,
 
 func (b *backend) secretAccessKeysCreate(
 	s logical.Storage,
-	displayName, policyName string, policy string) (*logical.Response, error) {
+	displayName, policyName string, policy, usernameTemplate string) (*logical.Response, error) {
 	client, err := clientIAM(s)
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), nil
 	}
 
-	username, usernameWarning := genUsername(displayName, policyName, "iam_user")
+	username, usernameWarning, err := generateUsername(usernameTemplate, displayName, policyName, "iam_user")
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
 
 	// Write to the WAL that this user will be created. We do this before
 	// the user is created because if switch the order then the WAL put
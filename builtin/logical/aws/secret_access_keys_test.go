@@ -1,6 +1,7 @@
 package aws
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -42,3 +43,23 @@ func TestNormalizeDisplayName_NormNotRequired(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateUsername_template(t *testing.T) {
+	username, warning, err := generateUsername(`app-{{.RoleName}}-{{.DisplayName}}-{{random 8}}`, "ldap alice", "deploy", "iam_user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(username, "app-deploy-ldap_alice-") || len(username) != 30 || warning != "" {
+		t.Fatalf("bad: %q %q", username, warning)
+	}
+
+	// Federated user names are limited to 32 characters and templated names
+	// are not truncated
+	if _, _, err := generateUsername(`{{.RoleName}}-{{random 30}}`, "", "deploy", "sts"); err == nil {
+		t.Fatal("expected an error")
+	}
+	// Characters not allowed by AWS are rejected
+	if _, _, err := generateUsername(`app/{{.RoleName}}`, "", "deploy", "iam_user"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,111 @@
+package azure
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// Factory creates and configures the backend
+func Factory(conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Backend creates a new backend with all the paths and secrets belonging to it
+func Backend() *backend {
+	var b backend
+	b.newClient = func(config *configEntry) azureClient {
+		return newHTTPAzureClient(config)
+	}
+	b.Backend = &framework.Backend{
+		Help: strings.TrimSpace(backendHelp),
+
+		Paths: []*framework.Path{
+			pathConfig(&b),
+			pathListRoles(&b),
+			pathRoles(&b),
+			pathCreds(&b),
+		},
+
+		Secrets: []*framework.Secret{
+			secretServicePrincipal(&b),
+		},
+
+		Invalidate:  b.invalidate,
+		BackendType: logical.TypeLogical,
+	}
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+
+	// newClient creates the client used to call the Azure APIs; it is
+	// replaced in tests
+	newClient func(config *configEntry) azureClient
+
+	client     azureClient
+	clientLock sync.RWMutex
+}
+
+// Client returns the client used to call the Azure APIs, creating it from
+// the configured credentials if needed. A nil client is returned if the
+// backend is not configured.
+func (b *backend) Client(s logical.Storage) (azureClient, error) {
+	b.clientLock.RLock()
+	if b.client != nil {
+		defer b.clientLock.RUnlock()
+		return b.client, nil
+	}
+	b.clientLock.RUnlock()
+
+	b.clientLock.Lock()
+	defer b.clientLock.Unlock()
+
+	// Check again in case it was created while waiting for the lock
+	if b.client != nil {
+		return b.client, nil
+	}
+
+	config, err := b.Config(s)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	b.client = b.newClient(config)
+	return b.client, nil
+}
+
+// resetClient forces the client to be recreated on next use
+func (b *backend) resetClient() {
+	b.clientLock.Lock()
+	b.client = nil
+	b.clientLock.Unlock()
+}
+
+func (b *backend) invalidate(key string) {
+	switch key {
+	case "config":
+		b.resetClient()
+	}
+}
+
+const backendHelp = `
+The Azure backend issues dynamic Azure service principals.
+
+After configuring the credentials of the backend with "config", each role
+lists the Azure roles to assign and their scopes. Reading "creds/<role>"
+creates a new service principal with those role assignments and returns its
+client ID and secret; the service principal is deleted when the lease
+expires or is revoked.
+`
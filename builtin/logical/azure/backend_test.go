@@ -0,0 +1,221 @@
+package azure
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// testAzureClient keeps applications and role assignments in memory
+type testAzureClient struct {
+	sync.Mutex
+	apps        map[string]time.Time
+	assignments map[string][]string
+	next        int
+	assignErr   error
+}
+
+func newTestAzureClient() *testAzureClient {
+	return &testAzureClient{
+		apps:        make(map[string]time.Time),
+		assignments: make(map[string][]string),
+	}
+}
+
+func (c *testAzureClient) CreateServicePrincipal(displayName string, expiresAt time.Time) (*servicePrincipal, error) {
+	c.Lock()
+	defer c.Unlock()
+	c.next++
+	appObjectID := fmt.Sprintf("app-%d", c.next)
+	c.apps[appObjectID] = expiresAt
+	return &servicePrincipal{
+		AppID:       fmt.Sprintf("client-%d", c.next),
+		AppObjectID: appObjectID,
+		ObjectID:    fmt.Sprintf("sp-%d", c.next),
+		Password:    "secret",
+	}, nil
+}
+
+func (c *testAzureClient) DeleteApplication(appObjectID string) error {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.apps[appObjectID]; !ok {
+		return &apiError{StatusCode: 404}
+	}
+	delete(c.apps, appObjectID)
+	return nil
+}
+
+func (c *testAzureClient) AssignRole(principalID, roleDefinitionID, scope string) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.assignErr != nil {
+		return "", c.assignErr
+	}
+	c.next++
+	id := fmt.Sprintf("%s/providers/Microsoft.Authorization/roleAssignments/%d", scope, c.next)
+	c.assignments[id] = []string{principalID, roleDefinitionID}
+	return id, nil
+}
+
+func (c *testAzureClient) DeleteRoleAssignment(assignmentID string) error {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.assignments[assignmentID]; !ok {
+		return &apiError{StatusCode: 404}
+	}
+	delete(c.assignments, assignmentID)
+	return nil
+}
+
+func testBackend(t *testing.T) (*backend, logical.Storage, *testAzureClient) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newTestAzureClient()
+	b.(*backend).newClient = func(*configEntry) azureClient {
+		return client
+	}
+	return b.(*backend), config.StorageView, client
+}
+
+func testRequest(t *testing.T, b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   s,
+		Data:      data,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: path: %s, resp: %#v, err: %v", path, resp, err)
+	}
+	return resp
+}
+
+func testConfigure(t *testing.T, b *backend, s logical.Storage) {
+	testRequest(t, b, s, logical.UpdateOperation, "config", map[string]interface{}{
+		"subscription_id": "sub",
+		"tenant_id":       "tenant",
+		"client_id":       "vault",
+		"client_secret":   "vault-secret",
+		"ttl":             "1h",
+		"max_ttl":         "2h",
+	})
+	testRequest(t, b, s, logical.UpdateOperation, "roles/app", map[string]interface{}{
+		"azure_roles": `[{"role_id": "acdd72a7-3385-48ef-bd42-f606fba81ae7", "scope": "/subscriptions/sub/resourceGroups/app"}, {"role_id": "/subscriptions/sub/providers/Microsoft.Authorization/roleDefinitions/other", "scope": "/subscriptions/sub"}]`,
+		"ttl":         "30m",
+	})
+}
+
+func TestBackend_config(t *testing.T) {
+	b, s, _ := testBackend(t)
+	testConfigure(t, b, s)
+
+	resp := testRequest(t, b, s, logical.ReadOperation, "config", nil)
+	if _, ok := resp.Data["client_secret"]; ok {
+		t.Fatalf("client secret returned: %#v", resp.Data)
+	}
+	if resp.Data["tenant_id"] != "tenant" || resp.Data["max_ttl"] != int64(7200) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/bad",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"azure_roles": `[{"role_id": "reader", "scope": "subscriptions/sub"}]`,
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error for relative scope: resp: %#v, err: %v", resp, err)
+	}
+}
+
+func TestBackend_creds(t *testing.T) {
+	b, s, client := testBackend(t)
+	testConfigure(t, b, s)
+
+	resp := testRequest(t, b, s, logical.ReadOperation, "creds/app", nil)
+	if resp.Secret == nil || resp.Secret.TTL != 30*time.Minute {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp.Data["client_id"] != "client-1" || resp.Data["client_secret"] != "secret" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	if len(client.apps) != 1 {
+		t.Fatalf("bad: apps: %v", client.apps)
+	}
+	if expiry := client.apps["app-1"]; expiry.Sub(time.Now()) > 2*time.Hour || expiry.Sub(time.Now()) < time.Hour {
+		t.Fatalf("bad: password expiry: %s", expiry)
+	}
+
+	var roleDefinitions []string
+	for _, assignment := range client.assignments {
+		if assignment[0] != "sp-1" {
+			t.Fatalf("bad: assignment: %v", assignment)
+		}
+		roleDefinitions = append(roleDefinitions, assignment[1])
+	}
+	if len(roleDefinitions) != 2 {
+		t.Fatalf("bad: assignments: %v", client.assignments)
+	}
+	expected := "/subscriptions/sub/providers/Microsoft.Authorization/roleDefinitions/acdd72a7-3385-48ef-bd42-f606fba81ae7"
+	if roleDefinitions[0] != expected && roleDefinitions[1] != expected {
+		t.Fatalf("role definition ID not expanded: %v", roleDefinitions)
+	}
+
+	// Revocation deletes the role assignments and the application, and
+	// succeeds if they are already gone
+	req := logical.RevokeRequest("", resp.Secret, nil)
+	req.Storage = s
+	for i := 0; i < 2; i++ {
+		if _, err := b.HandleRequest(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(client.apps) != 0 || len(client.assignments) != 0 {
+		t.Fatalf("bad: apps: %v, assignments: %v", client.apps, client.assignments)
+	}
+}
+
+func TestBackend_credsAssignmentFailure(t *testing.T) {
+	b, s, client := testBackend(t)
+	testConfigure(t, b, s)
+	client.assignErr = fmt.Errorf("forbidden")
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/app",
+		Storage:   s,
+	})
+	if err == nil {
+		t.Fatalf("expected error: resp: %#v", resp)
+	}
+
+	// The service principal is not left behind
+	if len(client.apps) != 0 || len(client.assignments) != 0 {
+		t.Fatalf("bad: apps: %v, assignments: %v", client.apps, client.assignments)
+	}
+}
+
+func TestAzureRole_roleDefinitionID(t *testing.T) {
+	cases := map[string]string{
+		"guid": "/subscriptions/sub/providers/Microsoft.Authorization/roleDefinitions/guid",
+		"/subscriptions/other/providers/Microsoft.Authorization/roleDefinitions/guid": "/subscriptions/other/providers/Microsoft.Authorization/roleDefinitions/guid",
+	}
+	for roleID, expected := range cases {
+		r := &azureRole{RoleID: roleID}
+		if actual := r.roleDefinitionID("sub"); !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("bad: %s: %s", roleID, actual)
+		}
+	}
+}
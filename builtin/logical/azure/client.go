@@ -0,0 +1,295 @@
+package azure
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
+)
+
+const (
+	loginEndpoint           = "https://login.microsoftonline.com/"
+	graphEndpoint           = "https://graph.microsoft.com/v1.0/"
+	resourceManagerEndpoint = "https://management.azure.com"
+
+	graphScope           = "https://graph.microsoft.com/.default"
+	resourceManagerScope = "https://management.azure.com/.default"
+
+	roleAssignmentAPIVersion = "2015-07-01"
+
+	// roleAssignmentAttempts bounds the retries of a role assignment made
+	// before the new service principal is visible to the resource manager
+	roleAssignmentAttempts = 10
+)
+
+// roleAssignmentRetryInterval is the delay between role assignment attempts
+var roleAssignmentRetryInterval = 3 * time.Second
+
+// servicePrincipal is a service principal created along with its application
+type servicePrincipal struct {
+	// AppID is the client ID used to log in as the service principal
+	AppID string
+
+	// AppObjectID is the object ID of the application; deleting the
+	// application deletes the service principal
+	AppObjectID string
+
+	// ObjectID is the object ID of the service principal, to which roles
+	// are assigned
+	ObjectID string
+
+	// Password is the client secret of the service principal
+	Password string
+}
+
+// azureClient is the subset of the Azure AD and resource manager APIs used
+// by the backend
+type azureClient interface {
+	// CreateServicePrincipal creates an application, its service principal
+	// and a password that expires at the given time
+	CreateServicePrincipal(displayName string, expiresAt time.Time) (*servicePrincipal, error)
+	DeleteApplication(appObjectID string) error
+
+	// AssignRole assigns the role definition to the principal at the scope
+	// and returns the ID of the role assignment
+	AssignRole(principalID, roleDefinitionID, scope string) (string, error)
+	DeleteRoleAssignment(assignmentID string) error
+}
+
+// apiError is returned for non-successful responses of the Azure APIs
+type apiError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("azure: error %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*apiError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// clientCredentialsSource fetches tokens with the OAuth2 client credentials
+// grant of Azure AD
+type clientCredentialsSource struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scope        string
+}
+
+func (s *clientCredentialsSource) Token() (*oauth2.Token, error) {
+	resp, err := http.PostForm(s.tokenURL, url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {s.clientID},
+		"client_secret": {s.clientSecret},
+		"scope":         {s.scope},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		AccessToken      string      `json:"access_token"`
+		TokenType        string      `json:"token_type"`
+		ExpiresIn        json.Number `json:"expires_in"`
+		ErrorDescription string      `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to obtain token: %s", out.ErrorDescription)
+	}
+
+	expiresIn, err := out.ExpiresIn.Int64()
+	if err != nil {
+		return nil, fmt.Errorf("invalid token expiry: %v", err)
+	}
+	return &oauth2.Token{
+		AccessToken: out.AccessToken,
+		TokenType:   out.TokenType,
+		Expiry:      time.Now().Add(time.Duration(expiresIn) * time.Second),
+	}, nil
+}
+
+// httpAzureClient implements azureClient over the Microsoft Graph and Azure
+// resource manager REST APIs
+type httpAzureClient struct {
+	graph           *http.Client
+	resourceManager *http.Client
+}
+
+func newHTTPAzureClient(config *configEntry) *httpAzureClient {
+	ctx := context.Background()
+	newClient := func(scope string) *http.Client {
+		return oauth2.NewClient(ctx, oauth2.ReuseTokenSource(nil, &clientCredentialsSource{
+			tokenURL:     loginEndpoint + url.PathEscape(config.TenantID) + "/oauth2/v2.0/token",
+			clientID:     config.ClientID,
+			clientSecret: config.ClientSecret,
+			scope:        scope,
+		}))
+	}
+	return &httpAzureClient{
+		graph:           newClient(graphScope),
+		resourceManager: newClient(resourceManagerScope),
+	}
+}
+
+func do(client *http.Client, method, u string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		buf, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var errResp struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(respBody, &errResp)
+		return &apiError{
+			StatusCode: resp.StatusCode,
+			Code:       errResp.Error.Code,
+			Message:    errResp.Error.Message,
+		}
+	}
+
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+func (c *httpAzureClient) CreateServicePrincipal(displayName string, expiresAt time.Time) (*servicePrincipal, error) {
+	var app struct {
+		ID    string `json:"id"`
+		AppID string `json:"appId"`
+	}
+	if err := do(c.graph, "POST", graphEndpoint+"applications", map[string]interface{}{
+		"displayName": displayName,
+	}, &app); err != nil {
+		return nil, err
+	}
+
+	sp, err := c.createServicePrincipal(app.ID, app.AppID, expiresAt)
+	if err != nil {
+		// Do not leave an unused application behind
+		if delErr := c.DeleteApplication(app.ID); delErr != nil {
+			return nil, fmt.Errorf("%v; additionally failed to delete application %s: %v", err, app.ID, delErr)
+		}
+		return nil, err
+	}
+	return sp, nil
+}
+
+func (c *httpAzureClient) createServicePrincipal(appObjectID, appID string, expiresAt time.Time) (*servicePrincipal, error) {
+	var sp struct {
+		ID string `json:"id"`
+	}
+	if err := do(c.graph, "POST", graphEndpoint+"servicePrincipals", map[string]interface{}{
+		"appId": appID,
+	}, &sp); err != nil {
+		return nil, err
+	}
+
+	var password struct {
+		SecretText string `json:"secretText"`
+	}
+	if err := do(c.graph, "POST", graphEndpoint+"applications/"+url.PathEscape(appObjectID)+"/addPassword", map[string]interface{}{
+		"passwordCredential": map[string]interface{}{
+			"displayName": "vault",
+			"endDateTime": expiresAt.UTC().Format(time.RFC3339),
+		},
+	}, &password); err != nil {
+		return nil, err
+	}
+
+	return &servicePrincipal{
+		AppID:       appID,
+		AppObjectID: appObjectID,
+		ObjectID:    sp.ID,
+		Password:    password.SecretText,
+	}, nil
+}
+
+func (c *httpAzureClient) DeleteApplication(appObjectID string) error {
+	return do(c.graph, "DELETE", graphEndpoint+"applications/"+url.PathEscape(appObjectID), nil, nil)
+}
+
+func (c *httpAzureClient) AssignRole(principalID, roleDefinitionID, scope string) (string, error) {
+	name, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+	u := fmt.Sprintf("%s/%s/providers/Microsoft.Authorization/roleAssignments/%s?api-version=%s",
+		resourceManagerEndpoint, strings.Trim(scope, "/"), name, roleAssignmentAPIVersion)
+	in := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"roleDefinitionId": roleDefinitionID,
+			"principalId":      principalID,
+		},
+	}
+
+	var out struct {
+		ID string `json:"id"`
+	}
+	for i := 0; ; i++ {
+		err = do(c.resourceManager, "PUT", u, in, &out)
+
+		// New service principals take a while to replicate to the resource
+		// manager, which rejects assignments to them until then
+		apiErr, ok := err.(*apiError)
+		if !ok || apiErr.Code != "PrincipalNotFound" || i+1 >= roleAssignmentAttempts {
+			break
+		}
+		time.Sleep(roleAssignmentRetryInterval)
+	}
+	if err != nil {
+		return "", err
+	}
+	return out.ID, nil
+}
+
+func (c *httpAzureClient) DeleteRoleAssignment(assignmentID string) error {
+	u := fmt.Sprintf("%s/%s?api-version=%s", resourceManagerEndpoint, strings.Trim(assignmentID, "/"), roleAssignmentAPIVersion)
+	return do(c.resourceManager, "DELETE", u, nil, nil)
+}
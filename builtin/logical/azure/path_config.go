@@ -0,0 +1,160 @@
+package azure
+
+import (
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config",
+		Fields: map[string]*framework.FieldSchema{
+			"subscription_id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "ID of the subscription in which roles are assigned. Used to expand role definition IDs given as GUIDs.",
+			},
+			"tenant_id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "ID of the Azure AD tenant in which service principals are created.",
+			},
+			"client_id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Client ID of the application used by the backend. It must be allowed to manage applications and role assignments.",
+			},
+			"client_secret": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Client secret of the application used by the backend.",
+			},
+			"ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Default lease TTL of service principals. Defaults to the mount's default TTL.",
+			},
+			"max_ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Maximum lease TTL of service principals. Defaults to the mount's maximum TTL.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+type configEntry struct {
+	SubscriptionID string        `json:"subscription_id"`
+	TenantID       string        `json:"tenant_id"`
+	ClientID       string        `json:"client_id"`
+	ClientSecret   string        `json:"client_secret"`
+	TTL            time.Duration `json:"ttl"`
+	MaxTTL         time.Duration `json:"max_ttl"`
+}
+
+// Config returns the configuration of the backend, or nil if it has not been
+// written
+func (b *backend) Config(s logical.Storage) (*configEntry, error) {
+	entry, err := s.Get("config")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var config configEntry
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (b *backend) pathConfigRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	// The client secret is never returned
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"subscription_id": config.SubscriptionID,
+			"tenant_id":       config.TenantID,
+			"client_id":       config.ClientID,
+			"ttl":             int64(config.TTL.Seconds()),
+			"max_ttl":         int64(config.MaxTTL.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &configEntry{}
+	}
+
+	if v, ok := d.GetOk("subscription_id"); ok {
+		config.SubscriptionID = v.(string)
+	}
+	if v, ok := d.GetOk("tenant_id"); ok {
+		config.TenantID = v.(string)
+	}
+	if v, ok := d.GetOk("client_id"); ok {
+		config.ClientID = v.(string)
+	}
+	if v, ok := d.GetOk("client_secret"); ok {
+		config.ClientSecret = v.(string)
+	}
+	if config.TenantID == "" || config.ClientID == "" || config.ClientSecret == "" {
+		return logical.ErrorResponse("tenant_id, client_id and client_secret are required"), nil
+	}
+
+	if ttlRaw, ok := d.GetOk("ttl"); ok {
+		config.TTL = time.Duration(ttlRaw.(int)) * time.Second
+	}
+	if maxTTLRaw, ok := d.GetOk("max_ttl"); ok {
+		config.MaxTTL = time.Duration(maxTTLRaw.(int)) * time.Second
+	}
+	if config.TTL < 0 || config.MaxTTL < 0 {
+		return logical.ErrorResponse("ttl and max_ttl cannot be negative"), nil
+	}
+	if config.MaxTTL > 0 && config.TTL > config.MaxTTL {
+		return logical.ErrorResponse("ttl cannot be greater than max_ttl"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("config", config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	b.resetClient()
+	return nil, nil
+}
+
+const pathConfigHelpSyn = `
+Configure the Azure credentials used by the backend.
+`
+
+const pathConfigHelpDesc = `
+The backend logs in to Azure AD as the configured application, which must be
+allowed to create and delete applications through Microsoft Graph and to
+create and delete role assignments in the scopes used by roles.
+
+The ttl and max_ttl values are the defaults for roles that do not set them.
+`
@@ -0,0 +1,188 @@
+package azure
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"github.com/mitchellh/mapstructure"
+)
+
+const secretServicePrincipalType = "service_principal"
+
+func pathCreds(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "creds/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathCredsRead,
+		},
+
+		HelpSynopsis:    pathCredsHelpSyn,
+		HelpDescription: pathCredsHelpDesc,
+	}
+}
+
+func secretServicePrincipal(b *backend) *framework.Secret {
+	return &framework.Secret{
+		Type: secretServicePrincipalType,
+		Fields: map[string]*framework.FieldSchema{
+			"client_id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Client ID of the service principal",
+			},
+			"client_secret": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Client secret of the service principal",
+			},
+		},
+
+		Renew:  b.secretServicePrincipalRenew,
+		Revoke: b.secretServicePrincipalRevoke,
+	}
+}
+
+func (b *backend) pathCredsRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	role, err := b.Role(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown role: %s", name)), nil
+	}
+
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	client, err := b.Client(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return logical.ErrorResponse("backend is not configured"), nil
+	}
+
+	// The password expires at the end of the maximum lease so that the
+	// service principal is unusable even if its revocation fails
+	ttl, maxTTL := role.ttls(config)
+	sysMaxTTL := b.System().MaxLeaseTTL()
+	if maxTTL == 0 || maxTTL > sysMaxTTL {
+		maxTTL = sysMaxTTL
+	}
+	now := time.Now()
+
+	sp, err := client.CreateServicePrincipal(fmt.Sprintf("vault-%s-%d", name, now.Unix()), now.Add(maxTTL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service principal: %v", err)
+	}
+
+	var assignmentIDs []string
+	for _, r := range role.AzureRoles {
+		id, err := client.AssignRole(sp.ObjectID, r.roleDefinitionID(config.SubscriptionID), r.Scope)
+		if err != nil {
+			if cleanupErr := deleteServicePrincipal(client, sp.AppObjectID, assignmentIDs); cleanupErr != nil {
+				b.Logger().Error("azure: failed to clean up service principal", "app_object_id", sp.AppObjectID, "error", cleanupErr)
+			}
+			return nil, fmt.Errorf("failed to assign role %q at scope %q: %v", r.RoleID, r.Scope, err)
+		}
+		assignmentIDs = append(assignmentIDs, id)
+	}
+
+	resp := b.Secret(secretServicePrincipalType).Response(map[string]interface{}{
+		"client_id":     sp.AppID,
+		"client_secret": sp.Password,
+	}, map[string]interface{}{
+		"app_object_id":       sp.AppObjectID,
+		"role_assignment_ids": assignmentIDs,
+		"role":                name,
+	})
+	if ttl > 0 {
+		resp.Secret.TTL = ttl
+	}
+	return resp, nil
+}
+
+func (b *backend) secretServicePrincipalRenew(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// The role may have been deleted, in which case the config values apply
+	role := &roleEntry{}
+	if roleName, ok := req.Secret.InternalData["role"].(string); ok {
+		stored, err := b.Role(req.Storage, roleName)
+		if err != nil {
+			return nil, err
+		}
+		if stored != nil {
+			role = stored
+		}
+	}
+
+	ttl, maxTTL := role.ttls(config)
+	f := framework.LeaseExtend(ttl, maxTTL, b.System())
+	return f(req, d)
+}
+
+func (b *backend) secretServicePrincipalRevoke(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	var internal struct {
+		AppObjectID       string   `mapstructure:"app_object_id"`
+		RoleAssignmentIDs []string `mapstructure:"role_assignment_ids"`
+	}
+	if err := mapstructure.Decode(req.Secret.InternalData, &internal); err != nil {
+		return nil, err
+	}
+	if internal.AppObjectID == "" {
+		return nil, fmt.Errorf("secret is missing app_object_id internal data")
+	}
+
+	client, err := b.Client(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, fmt.Errorf("backend is not configured")
+	}
+
+	return nil, deleteServicePrincipal(client, internal.AppObjectID, internal.RoleAssignmentIDs)
+}
+
+// deleteServicePrincipal deletes the role assignments and then the
+// application of a service principal, ignoring those already deleted
+func deleteServicePrincipal(client azureClient, appObjectID string, assignmentIDs []string) error {
+	for _, id := range assignmentIDs {
+		if err := client.DeleteRoleAssignment(id); err != nil && !isNotFound(err) {
+			return fmt.Errorf("failed to delete role assignment %s: %v", id, err)
+		}
+	}
+	if err := client.DeleteApplication(appObjectID); err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete application %s: %v", appObjectID, err)
+	}
+	return nil
+}
+
+const pathCredsHelpSyn = `
+Create a service principal for a role.
+`
+
+const pathCredsHelpDesc = `
+This path creates a new Azure AD application and service principal, assigns
+it the Azure roles of the role, and returns its client ID and secret. The
+service principal and its role assignments are deleted when the lease
+expires or is revoked. Its secret also expires at the end of the maximum
+lease TTL.
+`
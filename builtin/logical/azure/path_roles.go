@@ -0,0 +1,209 @@
+package azure
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathListRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/?$",
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+func pathRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+			"azure_roles": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `JSON list of the Azure roles assigned to the service principals, such as [{"role_id": "...", "scope": "/subscriptions/..."}].`,
+			},
+			"ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Default lease TTL of the service principals. Defaults to the ttl set in config.",
+			},
+			"max_ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Maximum lease TTL of the service principals. Defaults to the max_ttl set in config.",
+			},
+		},
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.UpdateOperation: b.pathRoleUpdate,
+			logical.DeleteOperation: b.pathRoleDelete,
+		},
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+// azureRole is an Azure role assigned to the service principals of a role
+type azureRole struct {
+	// RoleID is the ID of the role definition, either fully qualified or as
+	// a GUID of the configured subscription
+	RoleID string `json:"role_id" structs:"role_id" mapstructure:"role_id"`
+	Scope  string `json:"scope" structs:"scope" mapstructure:"scope"`
+}
+
+type roleEntry struct {
+	AzureRoles []*azureRole  `json:"azure_roles" structs:"azure_roles" mapstructure:"azure_roles"`
+	TTL        time.Duration `json:"ttl" structs:"ttl" mapstructure:"ttl"`
+	MaxTTL     time.Duration `json:"max_ttl" structs:"max_ttl" mapstructure:"max_ttl"`
+}
+
+// ttls returns the default and maximum lease TTLs of the role's service
+// principals, falling back to the config values
+func (r *roleEntry) ttls(config *configEntry) (time.Duration, time.Duration) {
+	ttl, maxTTL := r.TTL, r.MaxTTL
+	if ttl == 0 && config != nil {
+		ttl = config.TTL
+	}
+	if maxTTL == 0 && config != nil {
+		maxTTL = config.MaxTTL
+	}
+	return ttl, maxTTL
+}
+
+// roleDefinitionID returns the fully qualified ID of the role definition
+func (r *azureRole) roleDefinitionID(subscriptionID string) string {
+	if strings.HasPrefix(r.RoleID, "/") {
+		return r.RoleID
+	}
+	return fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Authorization/roleDefinitions/%s", subscriptionID, r.RoleID)
+}
+
+// Role reads the role configuration from the storage
+func (b *backend) Role(s logical.Storage, n string) (*roleEntry, error) {
+	entry, err := s.Get("role/" + n)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result roleEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Deletes an existing role. Service principals created for it are still
+// deleted when their leases expire.
+func (b *backend) pathRoleDelete(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return nil, req.Storage.Delete("role/" + d.Get("name").(string))
+}
+
+// Reads an existing role
+func (b *backend) pathRoleRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, err := b.Role(req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"azure_roles": role.AzureRoles,
+			"ttl":         int64(role.TTL.Seconds()),
+			"max_ttl":     int64(role.MaxTTL.Seconds()),
+		},
+	}, nil
+}
+
+// Lists all the roles registered with the backend
+func (b *backend) pathRoleList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roles, err := req.Storage.List("role/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(roles), nil
+}
+
+// Registers a new role with the backend, or updates an existing one
+func (b *backend) pathRoleUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	role, err := b.Role(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		role = &roleEntry{}
+	}
+
+	if azureRolesRaw, ok := d.GetOk("azure_roles"); ok {
+		var azureRoles []*azureRole
+		if err := jsonutil.DecodeJSON([]byte(azureRolesRaw.(string)), &azureRoles); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid azure_roles: %v", err)), nil
+		}
+		role.AzureRoles = azureRoles
+	}
+	if len(role.AzureRoles) == 0 {
+		return logical.ErrorResponse("missing azure_roles"), nil
+	}
+	for _, r := range role.AzureRoles {
+		if r == nil || r.RoleID == "" || r.Scope == "" {
+			return logical.ErrorResponse("each of azure_roles must have a role_id and a scope"), nil
+		}
+		if !strings.HasPrefix(r.Scope, "/") {
+			return logical.ErrorResponse(fmt.Sprintf("scope %q must be a resource ID starting with '/'", r.Scope)), nil
+		}
+	}
+
+	if ttlRaw, ok := d.GetOk("ttl"); ok {
+		role.TTL = time.Duration(ttlRaw.(int)) * time.Second
+	}
+	if maxTTLRaw, ok := d.GetOk("max_ttl"); ok {
+		role.MaxTTL = time.Duration(maxTTLRaw.(int)) * time.Second
+	}
+	if role.TTL < 0 || role.MaxTTL < 0 {
+		return logical.ErrorResponse("ttl and max_ttl cannot be negative"), nil
+	}
+	if role.MaxTTL > 0 && role.TTL > role.MaxTTL {
+		return logical.ErrorResponse("ttl cannot be greater than max_ttl"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("role/"+name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathRoleHelpSyn = `
+Manage the roles that define the role assignments of service principals.
+`
+
+const pathRoleHelpDesc = `
+Each role lists the Azure roles assigned to the service principals created
+for it, as a JSON list of objects with a "role_id" and a "scope". The role_id
+is either the full resource ID of a role definition or the GUID of a role
+definition of the configured subscription; the scope is the resource ID of a
+subscription, resource group or resource.
+`
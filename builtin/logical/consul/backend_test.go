@@ -302,6 +302,48 @@ func TestBackend_crud(t *testing.T) {
 	})
 }
 
+func TestBackend_role_usernameTemplate(t *testing.T) {
+	b, _ := Factory(logical.TestBackendConfig())
+	logicaltest.Test(t, logicaltest.TestCase{
+		Backend: b,
+		Steps: []logicaltest.TestStep{
+			logicaltest.TestStep{
+				Operation: logical.UpdateOperation,
+				Path:      "roles/test",
+				Data: map[string]interface{}{
+					"policy":            base64.StdEncoding.EncodeToString([]byte(testPolicy)),
+					"username_template": "{{.Bogus}}",
+				},
+				ErrorOk: true,
+				Check: func(resp *logical.Response) error {
+					if resp == nil || !resp.IsError() {
+						return fmt.Errorf("expected an error, got %#v", resp)
+					}
+					return nil
+				},
+			},
+			logicaltest.TestStep{
+				Operation: logical.UpdateOperation,
+				Path:      "roles/test",
+				Data: map[string]interface{}{
+					"policy":            base64.StdEncoding.EncodeToString([]byte(testPolicy)),
+					"username_template": "app-{{.RoleName}}-{{random 8}}",
+				},
+			},
+			logicaltest.TestStep{
+				Operation: logical.ReadOperation,
+				Path:      "roles/test",
+				Check: func(resp *logical.Response) error {
+					if resp.Data["username_template"] != "app-{{.RoleName}}-{{random 8}}" {
+						return fmt.Errorf("bad: %#v", resp.Data)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
 func TestBackend_role_lease(t *testing.T) {
 	b, _ := Factory(logical.TestBackendConfig())
 	logicaltest.Test(t, logicaltest.TestCase{
@@ -314,6 +356,86 @@ func TestBackend_role_lease(t *testing.T) {
 	})
 }
 
+func TestBackend_renew_roleTTL(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &logical.Request{
+		Storage:   config.StorageView,
+		Operation: logical.UpdateOperation,
+		Path:      "roles/test",
+		Data: map[string]interface{}{
+			"policy":  base64.StdEncoding.EncodeToString([]byte(testPolicy)),
+			"lease":   "2h",
+			"max_ttl": "1h",
+		},
+	}
+	resp, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected error when lease exceeds max_ttl, got %#v", resp)
+	}
+
+	req.Data["lease"] = "1h"
+	req.Data["max_ttl"] = "90m"
+	resp, err = b.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	renew := func(issued time.Duration) time.Duration {
+		req := &logical.Request{
+			Storage:   config.StorageView,
+			Operation: logical.RenewOperation,
+			Secret: &logical.Secret{
+				LeaseOptions: logical.LeaseOptions{
+					IssueTime: time.Now().Add(-issued),
+				},
+				InternalData: map[string]interface{}{
+					"secret_type": SecretTokenType,
+					"token":       "foo",
+					"role":        "test",
+				},
+			},
+		}
+		resp, err := b.HandleRequest(req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+		return resp.Secret.TTL
+	}
+
+	// The role's lease is used as the increment
+	if ttl := renew(0); ttl != time.Hour {
+		t.Fatalf("bad ttl: %v", ttl)
+	}
+
+	// and the role's max_ttl caps it
+	if ttl := renew(time.Hour); ttl > 30*time.Minute || ttl < 29*time.Minute {
+		t.Fatalf("bad ttl: %v", ttl)
+	}
+
+	// Deleting the role falls back to the mount's values
+	req = &logical.Request{
+		Storage:   config.StorageView,
+		Operation: logical.DeleteOperation,
+		Path:      "roles/test",
+	}
+	resp, err = b.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if ttl := renew(time.Hour); ttl != config.System.DefaultLeaseTTL() {
+		t.Fatalf("bad ttl: %v", ttl)
+	}
+}
+
 func testAccStepConfig(
 	t *testing.T, config map[string]interface{}) logicaltest.TestStep {
 	return logicaltest.TestStep{
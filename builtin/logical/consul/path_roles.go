@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/hashicorp/vault/helper/usernametemplate"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -47,6 +48,21 @@ Defaults to 'client'.`,
 				Type:        framework.TypeString,
 				Description: "Lease time of the role.",
 			},
+
+			"max_ttl": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Maximum lifetime of tokens created from the
+role, including renewals. Defaults to the
+mount's maximum TTL.`,
+			},
+
+			"username_template": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Go template generating the names of the
+tokens created from the role, given the role
+name as .RoleName and the display name of the
+token as .DisplayName.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -71,30 +87,25 @@ func pathRolesRead(
 	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	name := d.Get("name").(string)
 
-	entry, err := req.Storage.Get("policy/" + name)
+	result, err := role(req.Storage, name)
 	if err != nil {
 		return nil, err
 	}
-	if entry == nil {
+	if result == nil {
 		return nil, nil
 	}
 
-	var result roleConfig
-	if err := entry.DecodeJSON(&result); err != nil {
-		return nil, err
-	}
-
-	if result.TokenType == "" {
-		result.TokenType = "client"
-	}
-
 	// Generate the response
 	resp := &logical.Response{
 		Data: map[string]interface{}{
 			"lease":      result.Lease.String(),
+			"max_ttl":    result.MaxTTL.String(),
 			"token_type": result.TokenType,
 		},
 	}
+	if result.UsernameTemplate != "" {
+		resp.Data["username_template"] = result.UsernameTemplate
+	}
 	if result.Policy != "" {
 		resp.Data["policy"] = base64.StdEncoding.EncodeToString([]byte(result.Policy))
 	}
@@ -139,10 +150,33 @@ func pathRolesWrite(
 		}
 	}
 
+	var maxTTL time.Duration
+	maxTTLParam := d.Get("max_ttl").(string)
+	if maxTTLParam != "" {
+		maxTTL, err = time.ParseDuration(maxTTLParam)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"error parsing given max_ttl of %s: %s", maxTTLParam, err)), nil
+		}
+	}
+	if maxTTL > 0 && lease > maxTTL {
+		return logical.ErrorResponse("lease cannot be greater than max_ttl"), nil
+	}
+
+	usernameTemplate := d.Get("username_template").(string)
+	if usernameTemplate != "" {
+		if _, err := usernametemplate.Parse(usernameTemplate); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
 	entry, err := logical.StorageEntryJSON("policy/"+name, roleConfig{
 		Policy:    string(policyRaw),
 		Lease:     lease,
+		MaxTTL:    maxTTL,
 		TokenType: tokenType,
+
+		UsernameTemplate: usernameTemplate,
 	})
 	if err != nil {
 		return nil, err
@@ -167,5 +201,30 @@ func pathRolesDelete(
 type roleConfig struct {
 	Policy    string        `json:"policy"`
 	Lease     time.Duration `json:"lease"`
+	MaxTTL    time.Duration `json:"max_ttl"`
 	TokenType string        `json:"token_type"`
+
+	UsernameTemplate string `json:"username_template"`
+}
+
+// role returns the named role, or nil if it does not exist
+func role(s logical.Storage, name string) (*roleConfig, error) {
+	entry, err := s.Get("policy/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving role: %s", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result roleConfig
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	if result.TokenType == "" {
+		result.TokenType = "client"
+	}
+
+	return &result, nil
 }
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/vault/helper/usernametemplate"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -29,21 +30,12 @@ func (b *backend) pathTokenRead(
 	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	name := d.Get("name").(string)
 
-	entry, err := req.Storage.Get("policy/" + name)
+	result, err := role(req.Storage, name)
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving role: %s", err)
-	}
-	if entry == nil {
-		return logical.ErrorResponse(fmt.Sprintf("Role '%s' not found", name)), nil
-	}
-
-	var result roleConfig
-	if err := entry.DecodeJSON(&result); err != nil {
 		return nil, err
 	}
-
-	if result.TokenType == "" {
-		result.TokenType = "client"
+	if result == nil {
+		return logical.ErrorResponse(fmt.Sprintf("Role '%s' not found", name)), nil
 	}
 
 	// Get the consul client
@@ -57,6 +49,15 @@ func (b *backend) pathTokenRead(
 
 	// Generate a name for the token
 	tokenName := fmt.Sprintf("Vault %s %s %d", name, req.DisplayName, time.Now().UnixNano())
+	if result.UsernameTemplate != "" {
+		tokenName, err = usernametemplate.Generate(result.UsernameTemplate, usernametemplate.Data{
+			RoleName:    name,
+			DisplayName: req.DisplayName,
+		})
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
 
 	// Create it
 	token, _, err := c.ACL().Create(&api.ACLEntry{
@@ -73,6 +74,7 @@ func (b *backend) pathTokenRead(
 		"token": token,
 	}, map[string]interface{}{
 		"token": token,
+		"role":  name,
 	})
 	s.Secret.TTL = result.Lease
 
@@ -27,7 +27,22 @@ func secretToken(b *backend) *framework.Secret {
 func (b *backend) secretTokenRenew(
 	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 
-	return framework.LeaseExtend(0, 0, b.System())(req, d)
+	// Tokens created before the role was recorded, and tokens whose role
+	// has since been deleted, fall back to the mount's values
+	roleRaw, ok := req.Secret.InternalData["role"]
+	if !ok {
+		return framework.LeaseExtend(0, 0, b.System())(req, d)
+	}
+
+	result, err := role(req.Storage, roleRaw.(string))
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return framework.LeaseExtend(0, 0, b.System())(req, d)
+	}
+
+	return framework.LeaseExtend(result.Lease, result.MaxTTL, b.System())(req, d)
 }
 
 func secretTokenRevoke(
@@ -131,7 +131,7 @@ func TestBackend_config_connection(t *testing.T) {
 	var err error
 
 	cluster, sys := getCluster(t)
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 
 	config := logical.TestBackendConfig()
 	config.StorageView = &logical.InmemStorage{}
@@ -194,7 +194,7 @@ func TestBackend_config_connection(t *testing.T) {
 
 func TestBackend_basic(t *testing.T) {
 	cluster, sys := getCluster(t)
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 
 	config := logical.TestBackendConfig()
 	config.StorageView = &logical.InmemStorage{}
@@ -285,7 +285,7 @@ func TestBackend_basic(t *testing.T) {
 
 func TestBackend_connectionCrud(t *testing.T) {
 	cluster, sys := getCluster(t)
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 
 	config := logical.TestBackendConfig()
 	config.StorageView = &logical.InmemStorage{}
@@ -430,7 +430,7 @@ func TestBackend_connectionCrud(t *testing.T) {
 
 func TestBackend_roleCrud(t *testing.T) {
 	cluster, sys := getCluster(t)
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 
 	config := logical.TestBackendConfig()
 	config.StorageView = &logical.InmemStorage{}
@@ -540,7 +540,7 @@ func TestBackend_roleCrud(t *testing.T) {
 }
 func TestBackend_allowedRoles(t *testing.T) {
 	cluster, sys := getCluster(t)
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 
 	config := logical.TestBackendConfig()
 	config.StorageView = &logical.InmemStorage{}
@@ -30,10 +30,12 @@ type Statements struct {
 }
 
 // UsernameConfig is used to configure prefixes for the username to be
-// generated.
+// generated. If Template is set, the username is generated from it instead;
+// see the usernametemplate package.
 type UsernameConfig struct {
 	DisplayName string
 	RoleName    string
+	Template    string
 }
 
 // PluginFactory is used to build plugin database types. It wraps the database
@@ -110,7 +110,7 @@ func TestPlugin_Main(t *testing.T) {
 
 func TestPlugin_Initialize(t *testing.T) {
 	cluster, sys := getCluster(t)
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 
 	dbRaw, err := dbplugin.PluginFactory("test-plugin", sys, &log.NullLogger{})
 	if err != nil {
@@ -134,7 +134,7 @@ func TestPlugin_Initialize(t *testing.T) {
 
 func TestPlugin_CreateUser(t *testing.T) {
 	cluster, sys := getCluster(t)
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 
 	db, err := dbplugin.PluginFactory("test-plugin", sys, &log.NullLogger{})
 	if err != nil {
@@ -174,7 +174,7 @@ func TestPlugin_CreateUser(t *testing.T) {
 
 func TestPlugin_RenewUser(t *testing.T) {
 	cluster, sys := getCluster(t)
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 
 	db, err := dbplugin.PluginFactory("test-plugin", sys, &log.NullLogger{})
 	if err != nil {
@@ -208,7 +208,7 @@ func TestPlugin_RenewUser(t *testing.T) {
 
 func TestPlugin_RevokeUser(t *testing.T) {
 	cluster, sys := getCluster(t)
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 
 	db, err := dbplugin.PluginFactory("test-plugin", sys, &log.NullLogger{})
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/helper/usernametemplate"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -62,6 +63,14 @@ func pathRoles(b *databaseBackend) *framework.Path {
 				parameter.`,
 			},
 
+			"username_template": {
+				Type: framework.TypeString,
+				Description: `Go template generating the usernames of the
+				credentials, given the role name as .RoleName and the display
+				name of the token as .DisplayName. Defaults to the format of the
+				plugin.`,
+			},
+
 			"default_ttl": {
 				Type:        framework.TypeDurationSecond,
 				Description: "Default ttl for role.",
@@ -112,6 +121,7 @@ func (b *databaseBackend) pathRoleRead() framework.OperationFunc {
 				"revocation_statements": role.Statements.RevocationStatements,
 				"rollback_statements":   role.Statements.RollbackStatements,
 				"renew_statements":      role.Statements.RenewStatements,
+				"username_template":     role.UsernameTemplate,
 				"default_ttl":           role.DefaultTTL.Seconds(),
 				"max_ttl":               role.MaxTTL.Seconds(),
 			},
@@ -148,6 +158,13 @@ func (b *databaseBackend) pathRoleCreate() framework.OperationFunc {
 		rollbackStmts := data.Get("rollback_statements").(string)
 		renewStmts := data.Get("renew_statements").(string)
 
+		usernameTemplate := data.Get("username_template").(string)
+		if usernameTemplate != "" {
+			if _, err := usernametemplate.Parse(usernameTemplate); err != nil {
+				return logical.ErrorResponse(err.Error()), nil
+			}
+		}
+
 		// Get TTLs
 		defaultTTLRaw := data.Get("default_ttl").(int)
 		maxTTLRaw := data.Get("max_ttl").(int)
@@ -163,10 +180,11 @@ func (b *databaseBackend) pathRoleCreate() framework.OperationFunc {
 
 		// Store it
 		entry, err := logical.StorageEntryJSON("role/"+name, &roleEntry{
-			DBName:     dbName,
-			Statements: statements,
-			DefaultTTL: defaultTTL,
-			MaxTTL:     maxTTL,
+			DBName:           dbName,
+			Statements:       statements,
+			UsernameTemplate: usernameTemplate,
+			DefaultTTL:       defaultTTL,
+			MaxTTL:           maxTTL,
 		})
 		if err != nil {
 			return nil, err
@@ -180,10 +198,11 @@ func (b *databaseBackend) pathRoleCreate() framework.OperationFunc {
 }
 
 type roleEntry struct {
-	DBName     string              `json:"db_name" mapstructure:"db_name" structs:"db_name"`
-	Statements dbplugin.Statements `json:"statments" mapstructure:"statements" structs:"statments"`
-	DefaultTTL time.Duration       `json:"default_ttl" mapstructure:"default_ttl" structs:"default_ttl"`
-	MaxTTL     time.Duration       `json:"max_ttl" mapstructure:"max_ttl" structs:"max_ttl"`
+	DBName           string              `json:"db_name" mapstructure:"db_name" structs:"db_name"`
+	Statements       dbplugin.Statements `json:"statments" mapstructure:"statements" structs:"statments"`
+	UsernameTemplate string              `json:"username_template" mapstructure:"username_template" structs:"username_template"`
+	DefaultTTL       time.Duration       `json:"default_ttl" mapstructure:"default_ttl" structs:"default_ttl"`
+	MaxTTL           time.Duration       `json:"max_ttl" mapstructure:"max_ttl" structs:"max_ttl"`
 }
 
 const pathRoleHelpSyn = `
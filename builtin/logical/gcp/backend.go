@@ -0,0 +1,121 @@
+package gcp
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// Factory creates and configures the backend
+func Factory(conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Backend creates a new backend with all the paths and secrets belonging to it
+func Backend() *backend {
+	var b backend
+	b.newClient = func(credentials string) (iamClient, error) {
+		return newHTTPIAMClient(credentials)
+	}
+	b.rolesetLocks = locksutil.CreateLocks()
+	b.Backend = &framework.Backend{
+		Help: strings.TrimSpace(backendHelp),
+
+		Paths: []*framework.Path{
+			pathConfig(&b),
+			pathListRolesets(&b),
+			pathRoleset(&b),
+			pathKey(&b),
+			pathToken(&b),
+		},
+
+		Secrets: []*framework.Secret{
+			secretServiceAccountKey(&b),
+		},
+
+		Invalidate:  b.invalidate,
+		BackendType: logical.TypeLogical,
+	}
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+
+	// newClient creates the client used to call the GCP APIs; it is
+	// replaced in tests
+	newClient func(credentials string) (iamClient, error)
+
+	client     iamClient
+	clientLock sync.RWMutex
+
+	// rolesetLocks serialize changes to a given roleset
+	rolesetLocks []*locksutil.LockEntry
+}
+
+// Client returns the client used to call the GCP APIs, creating it from the
+// configured credentials if needed
+func (b *backend) Client(s logical.Storage) (iamClient, error) {
+	b.clientLock.RLock()
+	if b.client != nil {
+		defer b.clientLock.RUnlock()
+		return b.client, nil
+	}
+	b.clientLock.RUnlock()
+
+	b.clientLock.Lock()
+	defer b.clientLock.Unlock()
+
+	// Check again in case it was created while waiting for the lock
+	if b.client != nil {
+		return b.client, nil
+	}
+
+	config, err := b.Config(s)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := b.newClient(config.Credentials)
+	if err != nil {
+		return nil, err
+	}
+	b.client = client
+	return b.client, nil
+}
+
+// resetClient forces the client to be recreated on next use
+func (b *backend) resetClient() {
+	b.clientLock.Lock()
+	b.client = nil
+	b.clientLock.Unlock()
+}
+
+func (b *backend) invalidate(key string) {
+	switch key {
+	case "config":
+		b.resetClient()
+	}
+}
+
+func (b *backend) rolesetLock(name string) *locksutil.LockEntry {
+	return locksutil.LockForKey(b.rolesetLocks, name)
+}
+
+const backendHelp = `
+The GCP backend issues short-lived Google Cloud credentials.
+
+Each roleset owns a dedicated service account that is granted the roleset's
+IAM roles on a project. Depending on the roleset, reading "key/<roleset>"
+creates a service account key that is deleted when its lease expires, and
+reading "token/<roleset>" returns an OAuth2 access token for the service
+account.
+`
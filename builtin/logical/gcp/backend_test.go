@@ -0,0 +1,315 @@
+package gcp
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// testIAMClient keeps service accounts, keys and project bindings in memory
+type testIAMClient struct {
+	sync.Mutex
+	accounts map[string]bool
+	keys     map[string]string
+	bindings map[string][]string
+	nextKey  int
+}
+
+func newTestIAMClient() *testIAMClient {
+	return &testIAMClient{
+		accounts: make(map[string]bool),
+		keys:     make(map[string]string),
+		bindings: make(map[string][]string),
+	}
+}
+
+func (c *testIAMClient) CreateServiceAccount(project, accountID, displayName string) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+	email := fmt.Sprintf("%s@%s.iam.gserviceaccount.com", accountID, project)
+	c.accounts[email] = true
+	return email, nil
+}
+
+func (c *testIAMClient) DeleteServiceAccount(project, email string) error {
+	c.Lock()
+	defer c.Unlock()
+	if !c.accounts[email] {
+		return &apiError{Code: 404, Message: "not found"}
+	}
+	delete(c.accounts, email)
+	for name, owner := range c.keys {
+		if owner == email {
+			delete(c.keys, name)
+		}
+	}
+	return nil
+}
+
+func (c *testIAMClient) UpdateProjectBindings(project, member string, add, remove []string) error {
+	c.Lock()
+	defer c.Unlock()
+	policy := &iamPolicy{}
+	for role, members := range c.bindings {
+		policy.Bindings = append(policy.Bindings, &iamBinding{Role: role, Members: members})
+	}
+	policy.update(member, add, remove)
+	c.bindings = make(map[string][]string)
+	for _, binding := range policy.Bindings {
+		c.bindings[binding.Role] = binding.Members
+	}
+	return nil
+}
+
+func (c *testIAMClient) CreateKey(project, email string) (*serviceAccountKey, error) {
+	c.Lock()
+	defer c.Unlock()
+	if !c.accounts[email] {
+		return nil, &apiError{Code: 404, Message: "not found"}
+	}
+	c.nextKey++
+	name := fmt.Sprintf("projects/%s/serviceAccounts/%s/keys/%d", project, email, c.nextKey)
+	c.keys[name] = email
+	return &serviceAccountKey{
+		Name:           name,
+		KeyAlgorithm:   "KEY_ALG_RSA_2048",
+		PrivateKeyType: "TYPE_GOOGLE_CREDENTIALS_FILE",
+		PrivateKeyData: "eyJ0eXBlIjoic2VydmljZV9hY2NvdW50In0=",
+	}, nil
+}
+
+func (c *testIAMClient) DeleteKey(name string) error {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.keys[name]; !ok {
+		return &apiError{Code: 404, Message: "not found"}
+	}
+	delete(c.keys, name)
+	return nil
+}
+
+func (c *testIAMClient) GenerateAccessToken(email string, scopes []string, lifetime time.Duration) (string, time.Time, error) {
+	c.Lock()
+	defer c.Unlock()
+	if !c.accounts[email] {
+		return "", time.Time{}, &apiError{Code: 404, Message: "not found"}
+	}
+	return "ya29." + strings.Join(scopes, ","), time.Now().Add(lifetime), nil
+}
+
+func (c *testIAMClient) roles(member string) []string {
+	c.Lock()
+	defer c.Unlock()
+	var roles []string
+	for role, members := range c.bindings {
+		for _, m := range members {
+			if m == member {
+				roles = append(roles, role)
+			}
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+func testBackend(t *testing.T) (*backend, logical.Storage, *testIAMClient) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newTestIAMClient()
+	b.(*backend).newClient = func(string) (iamClient, error) {
+		return client, nil
+	}
+	return b.(*backend), config.StorageView, client
+}
+
+func testRequest(t *testing.T, b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   s,
+		Data:      data,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: path: %s, resp: %#v, err: %v", path, resp, err)
+	}
+	return resp
+}
+
+func TestBackend_rolesetBindings(t *testing.T) {
+	b, s, client := testBackend(t)
+
+	testRequest(t, b, s, logical.UpdateOperation, "rolesets/app", map[string]interface{}{
+		"project":  "my-project",
+		"bindings": "roles/viewer,roles/storage.objectViewer,roles/viewer",
+	})
+
+	resp := testRequest(t, b, s, logical.ReadOperation, "rolesets/app", nil)
+	email := resp.Data["service_account_email"].(string)
+	if email == "" || resp.Data["secret_type"] != secretTypeKey {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	member := serviceAccountMember(email)
+	if roles := client.roles(member); !reflect.DeepEqual(roles, []string{"roles/storage.objectViewer", "roles/viewer"}) {
+		t.Fatalf("bad: roles: %v", roles)
+	}
+
+	testRequest(t, b, s, logical.UpdateOperation, "rolesets/app", map[string]interface{}{
+		"bindings": "roles/viewer,roles/pubsub.subscriber",
+	})
+	if roles := client.roles(member); !reflect.DeepEqual(roles, []string{"roles/pubsub.subscriber", "roles/viewer"}) {
+		t.Fatalf("bad: roles: %v", roles)
+	}
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "rolesets/app",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"project": "other-project",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error changing project: resp: %#v, err: %v", resp, err)
+	}
+
+	resp = testRequest(t, b, s, logical.ListOperation, "rolesets/", nil)
+	if keys := resp.Data["keys"].([]string); !reflect.DeepEqual(keys, []string{"app"}) {
+		t.Fatalf("bad: %v", keys)
+	}
+
+	testRequest(t, b, s, logical.DeleteOperation, "rolesets/app", nil)
+	if roles := client.roles(member); len(roles) != 0 {
+		t.Fatalf("bad: roles: %v", roles)
+	}
+	if client.accounts[email] {
+		t.Fatalf("service account was not deleted")
+	}
+}
+
+func TestBackend_key(t *testing.T) {
+	b, s, client := testBackend(t)
+
+	testRequest(t, b, s, logical.UpdateOperation, "config", map[string]interface{}{
+		"ttl":     "1h",
+		"max_ttl": "2h",
+	})
+	testRequest(t, b, s, logical.UpdateOperation, "rolesets/app", map[string]interface{}{
+		"project":  "my-project",
+		"bindings": "roles/viewer",
+	})
+
+	resp := testRequest(t, b, s, logical.ReadOperation, "key/app", nil)
+	if resp.Secret == nil || resp.Secret.TTL != time.Hour {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp.Data["private_key_data"] == "" || resp.Data["key_algorithm"] != "KEY_ALG_RSA_2048" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if len(client.keys) != 1 {
+		t.Fatalf("bad: keys: %v", client.keys)
+	}
+	secret := resp.Secret
+
+	// Access tokens cannot be issued for a service account key roleset
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "token/app",
+		Storage:   s,
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: resp: %#v, err: %v", resp, err)
+	}
+
+	req := logical.RevokeRequest("", secret, nil)
+	req.Storage = s
+	if _, err := b.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.keys) != 0 {
+		t.Fatalf("key was not deleted: %v", client.keys)
+	}
+
+	// Revoking a key that is already gone succeeds
+	if _, err := b.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBackend_token(t *testing.T) {
+	b, s, _ := testBackend(t)
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "rolesets/app",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"project":     "my-project",
+			"secret_type": secretTypeAccessToken,
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error without token_scopes: resp: %#v, err: %v", resp, err)
+	}
+
+	testRequest(t, b, s, logical.UpdateOperation, "rolesets/app", map[string]interface{}{
+		"project":      "my-project",
+		"bindings":     "roles/viewer",
+		"secret_type":  secretTypeAccessToken,
+		"token_scopes": cloudPlatformScope,
+	})
+
+	resp = testRequest(t, b, s, logical.ReadOperation, "token/app", nil)
+	if resp.Secret != nil {
+		t.Fatalf("access token was leased: %#v", resp.Secret)
+	}
+	if resp.Data["token"] != "ya29."+cloudPlatformScope {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if ttl := resp.Data["token_ttl"].(int64); ttl <= 0 || ttl > int64(maxAccessTokenLifetime.Seconds()) {
+		t.Fatalf("bad: token_ttl: %d", ttl)
+	}
+}
+
+func TestIAMPolicy_update(t *testing.T) {
+	policy := &iamPolicy{
+		Bindings: []*iamBinding{
+			{Role: "roles/viewer", Members: []string{"user:a@example.com", "serviceAccount:sa"}},
+			{Role: "roles/editor", Members: []string{"serviceAccount:sa"}},
+		},
+	}
+
+	if !policy.update("serviceAccount:sa", []string{"roles/owner"}, []string{"roles/viewer", "roles/editor"}) {
+		t.Fatal("expected policy to change")
+	}
+	expected := []*iamBinding{
+		{Role: "roles/viewer", Members: []string{"user:a@example.com"}},
+		{Role: "roles/owner", Members: []string{"serviceAccount:sa"}},
+	}
+	if !reflect.DeepEqual(policy.Bindings, expected) {
+		t.Fatalf("bad: %#v", policy.Bindings)
+	}
+
+	if policy.update("serviceAccount:sa", []string{"roles/owner"}, []string{"roles/editor"}) {
+		t.Fatal("expected policy to be unchanged")
+	}
+}
+
+func TestServiceAccountID(t *testing.T) {
+	id := serviceAccountID("My_Very.Long-Roleset-Name", time.Unix(1500000000, 0))
+	if id != "vault-my-very-long--1500000000" {
+		t.Fatalf("bad: %s", id)
+	}
+	if len(id) > 30 {
+		t.Fatalf("id too long: %d", len(id))
+	}
+}
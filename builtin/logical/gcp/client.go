@@ -0,0 +1,296 @@
+package gcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+	iamEndpoint             = "https://iam.googleapis.com/v1/"
+	iamCredentialsEndpoint  = "https://iamcredentials.googleapis.com/v1/"
+	resourceManagerEndpoint = "https://cloudresourcemanager.googleapis.com/v1/"
+
+	// maxPolicyUpdateAttempts bounds the retries of a project IAM policy
+	// update that conflicts with a concurrent one
+	maxPolicyUpdateAttempts = 5
+)
+
+// serviceAccountKey is a key created for a service account
+type serviceAccountKey struct {
+	Name            string `json:"name"`
+	KeyAlgorithm    string `json:"keyAlgorithm"`
+	PrivateKeyType  string `json:"privateKeyType"`
+	PrivateKeyData  string `json:"privateKeyData"`
+	ValidBeforeTime string `json:"validBeforeTime"`
+}
+
+// iamClient is the subset of the GCP IAM APIs used by the backend
+type iamClient interface {
+	CreateServiceAccount(project, accountID, displayName string) (string, error)
+	DeleteServiceAccount(project, email string) error
+
+	// UpdateProjectBindings grants the member the add roles on the project
+	// and revokes the remove roles
+	UpdateProjectBindings(project, member string, add, remove []string) error
+
+	CreateKey(project, email string) (*serviceAccountKey, error)
+	DeleteKey(name string) error
+
+	GenerateAccessToken(email string, scopes []string, lifetime time.Duration) (string, time.Time, error)
+}
+
+// apiError is returned for non-successful responses of the GCP APIs
+type apiError struct {
+	Code    int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("googleapi: error %d: %s", e.Code, e.Message)
+}
+
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*apiError)
+	return ok && apiErr.Code == http.StatusNotFound
+}
+
+func isConflict(err error) bool {
+	apiErr, ok := err.(*apiError)
+	return ok && apiErr.Code == http.StatusConflict
+}
+
+// httpIAMClient implements iamClient over the GCP REST APIs
+type httpIAMClient struct {
+	client *http.Client
+}
+
+// newHTTPIAMClient returns a client using the given service account
+// credentials, or the application default credentials if none are given
+func newHTTPIAMClient(credentials string) (*httpIAMClient, error) {
+	ctx := context.Background()
+	if credentials == "" {
+		client, err := google.DefaultClient(ctx, cloudPlatformScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load default credentials: %v", err)
+		}
+		return &httpIAMClient{client: client}, nil
+	}
+
+	jwtConfig, err := google.JWTConfigFromJSON([]byte(credentials), cloudPlatformScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse credentials: %v", err)
+	}
+	return &httpIAMClient{client: jwtConfig.Client(ctx)}, nil
+}
+
+func (c *httpIAMClient) do(method, u string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		buf, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var errResp struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(respBody, &errResp)
+		return &apiError{
+			Code:    resp.StatusCode,
+			Message: errResp.Error.Message,
+		}
+	}
+
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+func serviceAccountURL(project, email string) string {
+	return fmt.Sprintf("%sprojects/%s/serviceAccounts/%s", iamEndpoint, url.PathEscape(project), url.PathEscape(email))
+}
+
+func (c *httpIAMClient) CreateServiceAccount(project, accountID, displayName string) (string, error) {
+	in := map[string]interface{}{
+		"accountId": accountID,
+		"serviceAccount": map[string]interface{}{
+			"displayName": displayName,
+		},
+	}
+	var out struct {
+		Email string `json:"email"`
+	}
+	u := fmt.Sprintf("%sprojects/%s/serviceAccounts", iamEndpoint, url.PathEscape(project))
+	if err := c.do("POST", u, in, &out); err != nil {
+		return "", err
+	}
+	return out.Email, nil
+}
+
+func (c *httpIAMClient) DeleteServiceAccount(project, email string) error {
+	return c.do("DELETE", serviceAccountURL(project, email), nil, nil)
+}
+
+func (c *httpIAMClient) UpdateProjectBindings(project, member string, add, remove []string) error {
+	u := fmt.Sprintf("%sprojects/%s", resourceManagerEndpoint, url.PathEscape(project))
+
+	var err error
+	for i := 0; i < maxPolicyUpdateAttempts; i++ {
+		var policy iamPolicy
+		if err = c.do("POST", u+":getIamPolicy", map[string]interface{}{}, &policy); err != nil {
+			return err
+		}
+
+		if !policy.update(member, add, remove) {
+			return nil
+		}
+
+		// The policy's etag makes the update fail with a conflict if the
+		// policy was changed since it was read, in which case it is retried
+		err = c.do("POST", u+":setIamPolicy", map[string]interface{}{"policy": policy}, nil)
+		if err == nil || !isConflict(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (c *httpIAMClient) CreateKey(project, email string) (*serviceAccountKey, error) {
+	in := map[string]interface{}{
+		"keyAlgorithm":   "KEY_ALG_RSA_2048",
+		"privateKeyType": "TYPE_GOOGLE_CREDENTIALS_FILE",
+	}
+	var key serviceAccountKey
+	if err := c.do("POST", serviceAccountURL(project, email)+"/keys", in, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (c *httpIAMClient) DeleteKey(name string) error {
+	return c.do("DELETE", iamEndpoint+name, nil, nil)
+}
+
+func (c *httpIAMClient) GenerateAccessToken(email string, scopes []string, lifetime time.Duration) (string, time.Time, error) {
+	in := map[string]interface{}{
+		"scope":    scopes,
+		"lifetime": fmt.Sprintf("%ds", int64(lifetime.Seconds())),
+	}
+	var out struct {
+		AccessToken string    `json:"accessToken"`
+		ExpireTime  time.Time `json:"expireTime"`
+	}
+	u := fmt.Sprintf("%sprojects/-/serviceAccounts/%s:generateAccessToken", iamCredentialsEndpoint, url.PathEscape(email))
+	if err := c.do("POST", u, in, &out); err != nil {
+		return "", time.Time{}, err
+	}
+	return out.AccessToken, out.ExpireTime, nil
+}
+
+// iamPolicy is a project IAM policy. Fields that the backend does not
+// modify are kept as they are read so that they are preserved on update.
+type iamPolicy struct {
+	Version      int             `json:"version,omitempty"`
+	Etag         string          `json:"etag,omitempty"`
+	Bindings     []*iamBinding   `json:"bindings,omitempty"`
+	AuditConfigs json.RawMessage `json:"auditConfigs,omitempty"`
+}
+
+type iamBinding struct {
+	Role    string   `json:"role"`
+	Members []string `json:"members"`
+}
+
+// update grants the member the add roles and revokes the remove roles,
+// returning whether the policy was changed
+func (p *iamPolicy) update(member string, add, remove []string) bool {
+	changed := false
+
+	for _, role := range remove {
+		for _, binding := range p.Bindings {
+			if binding.Role != role {
+				continue
+			}
+			for i, m := range binding.Members {
+				if m == member {
+					binding.Members = append(binding.Members[:i], binding.Members[i+1:]...)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	for _, role := range add {
+		var found *iamBinding
+		for _, binding := range p.Bindings {
+			if binding.Role == role {
+				found = binding
+				break
+			}
+		}
+		if found == nil {
+			found = &iamBinding{Role: role}
+			p.Bindings = append(p.Bindings, found)
+		}
+
+		hasMember := false
+		for _, m := range found.Members {
+			if m == member {
+				hasMember = true
+				break
+			}
+		}
+		if !hasMember {
+			found.Members = append(found.Members, member)
+			changed = true
+		}
+	}
+
+	// Bindings without members are rejected by the API
+	bindings := p.Bindings[:0]
+	for _, binding := range p.Bindings {
+		if len(binding.Members) > 0 {
+			bindings = append(bindings, binding)
+		}
+	}
+	p.Bindings = bindings
+
+	return changed
+}
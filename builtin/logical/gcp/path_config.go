@@ -0,0 +1,131 @@
+package gcp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config",
+		Fields: map[string]*framework.FieldSchema{
+			"credentials": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "JSON credentials file of the service account used to manage service accounts, keys and IAM policies. If not set, the application default credentials are used.",
+			},
+			"ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Default lease TTL of service account keys. Defaults to the mount's default TTL.",
+			},
+			"max_ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Maximum lease TTL of service account keys. Defaults to the mount's maximum TTL.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+type configEntry struct {
+	Credentials string        `json:"credentials"`
+	TTL         time.Duration `json:"ttl"`
+	MaxTTL      time.Duration `json:"max_ttl"`
+}
+
+// Config returns the configuration of the backend; an empty configuration is
+// returned if none has been written
+func (b *backend) Config(s logical.Storage) (*configEntry, error) {
+	config := &configEntry{}
+	entry, err := s.Get("config")
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		if err := entry.DecodeJSON(config); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func (b *backend) pathConfigRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// The credentials are never returned
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"ttl":     int64(config.TTL.Seconds()),
+			"max_ttl": int64(config.MaxTTL.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if credentialsRaw, ok := d.GetOk("credentials"); ok {
+		credentials := credentialsRaw.(string)
+		if credentials != "" {
+			var parsed map[string]interface{}
+			if err := jsonutil.DecodeJSON([]byte(credentials), &parsed); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("credentials are not valid JSON: %v", err)), nil
+			}
+		}
+		config.Credentials = credentials
+	}
+	if ttlRaw, ok := d.GetOk("ttl"); ok {
+		config.TTL = time.Duration(ttlRaw.(int)) * time.Second
+	}
+	if maxTTLRaw, ok := d.GetOk("max_ttl"); ok {
+		config.MaxTTL = time.Duration(maxTTLRaw.(int)) * time.Second
+	}
+	if config.TTL < 0 || config.MaxTTL < 0 {
+		return logical.ErrorResponse("ttl and max_ttl cannot be negative"), nil
+	}
+	if config.MaxTTL > 0 && config.TTL > config.MaxTTL {
+		return logical.ErrorResponse("ttl cannot be greater than max_ttl"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("config", config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	b.resetClient()
+	return nil, nil
+}
+
+const pathConfigHelpSyn = `
+Configure the GCP credentials used by the backend.
+`
+
+const pathConfigHelpDesc = `
+The credentials must allow creating and deleting service accounts and their
+keys, generating access tokens for them, and updating the IAM policy of the
+projects used by rolesets. If no credentials are given, the application
+default credentials of the Vault server are used.
+
+The ttl and max_ttl values bound the leases of service account keys.
+`
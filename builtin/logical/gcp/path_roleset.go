@@ -0,0 +1,291 @@
+package gcp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	secretTypeKey         = "service_account_key"
+	secretTypeAccessToken = "access_token"
+)
+
+func pathListRolesets(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "rolesets/?$",
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRolesetList,
+		},
+		HelpSynopsis:    pathRolesetHelpSyn,
+		HelpDescription: pathRolesetHelpDesc,
+	}
+}
+
+func pathRoleset(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "rolesets/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the roleset.",
+			},
+			"project": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "ID of the project in which the roleset's service account is created and its roles granted. Cannot be changed once set.",
+			},
+			"bindings": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "IAM roles granted to the roleset's service account on the project, such as roles/storage.objectViewer.",
+			},
+			"secret_type": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Default:     secretTypeKey,
+				Description: fmt.Sprintf("Type of secret issued for the roleset, either %q or %q.", secretTypeKey, secretTypeAccessToken),
+			},
+			"token_scopes": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "OAuth2 scopes of the access tokens issued for the roleset. Required for the access_token secret type.",
+			},
+		},
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathRolesetRead,
+			logical.UpdateOperation: b.pathRolesetWrite,
+			logical.DeleteOperation: b.pathRolesetDelete,
+		},
+		HelpSynopsis:    pathRolesetHelpSyn,
+		HelpDescription: pathRolesetHelpDesc,
+	}
+}
+
+type rolesetEntry struct {
+	Project             string   `json:"project"`
+	Bindings            []string `json:"bindings"`
+	SecretType          string   `json:"secret_type"`
+	TokenScopes         []string `json:"token_scopes"`
+	ServiceAccountEmail string   `json:"service_account_email"`
+}
+
+// Roleset reads the roleset from the storage
+func (b *backend) Roleset(s logical.Storage, name string) (*rolesetEntry, error) {
+	entry, err := s.Get("roleset/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result rolesetEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (b *backend) pathRolesetList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	rolesets, err := req.Storage.List("roleset/")
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(rolesets), nil
+}
+
+func (b *backend) pathRolesetRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleset, err := b.Roleset(req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if roleset == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"project":               roleset.Project,
+			"bindings":              roleset.Bindings,
+			"secret_type":           roleset.SecretType,
+			"token_scopes":          roleset.TokenScopes,
+			"service_account_email": roleset.ServiceAccountEmail,
+		},
+	}, nil
+}
+
+func (b *backend) pathRolesetWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	lock := b.rolesetLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	roleset, err := b.Roleset(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	isCreate := roleset == nil
+	if isCreate {
+		roleset = &rolesetEntry{}
+	}
+
+	if project := d.Get("project").(string); project != "" {
+		if !isCreate && project != roleset.Project {
+			return logical.ErrorResponse("the project of a roleset cannot be changed"), nil
+		}
+		roleset.Project = project
+	}
+	if roleset.Project == "" {
+		return logical.ErrorResponse("missing project"), nil
+	}
+
+	oldBindings := roleset.Bindings
+	if bindingsRaw, ok := d.GetOk("bindings"); ok {
+		roleset.Bindings = strutil.RemoveDuplicates(bindingsRaw.([]string), false)
+	}
+
+	if secretTypeRaw, ok := d.GetOk("secret_type"); ok {
+		roleset.SecretType = secretTypeRaw.(string)
+	} else if isCreate {
+		roleset.SecretType = d.Get("secret_type").(string)
+	}
+	switch roleset.SecretType {
+	case secretTypeKey, secretTypeAccessToken:
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("invalid secret_type %q", roleset.SecretType)), nil
+	}
+
+	if scopesRaw, ok := d.GetOk("token_scopes"); ok {
+		roleset.TokenScopes = scopesRaw.([]string)
+	}
+	if roleset.SecretType == secretTypeAccessToken && len(roleset.TokenScopes) == 0 {
+		return logical.ErrorResponse("token_scopes are required for the access_token secret type"), nil
+	}
+
+	client, err := b.Client(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if isCreate {
+		email, err := client.CreateServiceAccount(roleset.Project, serviceAccountID(name, time.Now()), "Vault roleset "+name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create service account: %v", err)
+		}
+		roleset.ServiceAccountEmail = email
+	}
+
+	add, remove := diffBindings(oldBindings, roleset.Bindings)
+	if err := client.UpdateProjectBindings(roleset.Project, serviceAccountMember(roleset.ServiceAccountEmail), add, remove); err != nil {
+		if isCreate {
+			if delErr := client.DeleteServiceAccount(roleset.Project, roleset.ServiceAccountEmail); delErr != nil {
+				b.Logger().Error("gcp: failed to delete service account after failing to grant its roles", "service_account", roleset.ServiceAccountEmail, "error", delErr)
+			}
+		}
+		return nil, fmt.Errorf("failed to update project IAM policy: %v", err)
+	}
+
+	entry, err := logical.StorageEntryJSON("roleset/"+name, roleset)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathRolesetDelete(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	lock := b.rolesetLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	roleset, err := b.Roleset(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if roleset == nil {
+		return nil, nil
+	}
+
+	client, err := b.Client(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Deleting the service account also deletes all of its keys
+	member := serviceAccountMember(roleset.ServiceAccountEmail)
+	if err := client.UpdateProjectBindings(roleset.Project, member, nil, roleset.Bindings); err != nil {
+		return nil, fmt.Errorf("failed to update project IAM policy: %v", err)
+	}
+	if err := client.DeleteServiceAccount(roleset.Project, roleset.ServiceAccountEmail); err != nil && !isNotFound(err) {
+		return nil, fmt.Errorf("failed to delete service account: %v", err)
+	}
+
+	return nil, req.Storage.Delete("roleset/" + name)
+}
+
+// serviceAccountID returns the ID of the service account created for the
+// named roleset. IDs must be 6 to 30 characters of lowercase letters, digits
+// and dashes, starting with a letter and not ending with a dash.
+func serviceAccountID(name string, now time.Time) string {
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '-'
+		}
+	}, name)
+	if len(sanitized) > 13 {
+		sanitized = sanitized[:13]
+	}
+	return fmt.Sprintf("vault-%s-%s", sanitized, strconv.FormatInt(now.Unix(), 10))
+}
+
+func serviceAccountMember(email string) string {
+	return "serviceAccount:" + email
+}
+
+// diffBindings returns the roles to add and remove to go from old to new
+func diffBindings(old, new []string) ([]string, []string) {
+	var add, remove []string
+	for _, role := range new {
+		if !strutil.StrListContains(old, role) {
+			add = append(add, role)
+		}
+	}
+	for _, role := range old {
+		if !strutil.StrListContains(new, role) {
+			remove = append(remove, role)
+		}
+	}
+	return add, remove
+}
+
+const pathRolesetHelpSyn = `
+Manage the rolesets that define the permissions of issued credentials.
+`
+
+const pathRolesetHelpDesc = `
+Creating a roleset creates a dedicated service account in the roleset's
+project and grants it the roles listed in "bindings" on the project. Updating
+the bindings updates the project's IAM policy accordingly, and deleting the
+roleset revokes the roles and deletes the service account along with all of
+its keys.
+
+The secret type of the roleset determines whether service account keys are
+issued from "key/<roleset>" or access tokens from "token/<roleset>".
+`
@@ -0,0 +1,209 @@
+package gcp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const secretServiceAccountKeyType = "service_account_key"
+
+// maxAccessTokenLifetime is the longest lifetime GCP allows for access
+// tokens generated for a service account
+const maxAccessTokenLifetime = time.Hour
+
+func pathKey(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "key/" + framework.GenericNameRegex("roleset"),
+		Fields: map[string]*framework.FieldSchema{
+			"roleset": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the roleset.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathKeyRead,
+		},
+
+		HelpSynopsis:    pathKeyHelpSyn,
+		HelpDescription: pathKeyHelpDesc,
+	}
+}
+
+func pathToken(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "token/" + framework.GenericNameRegex("roleset"),
+		Fields: map[string]*framework.FieldSchema{
+			"roleset": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the roleset.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathTokenRead,
+		},
+
+		HelpSynopsis:    pathTokenHelpSyn,
+		HelpDescription: pathTokenHelpDesc,
+	}
+}
+
+func secretServiceAccountKey(b *backend) *framework.Secret {
+	return &framework.Secret{
+		Type: secretServiceAccountKeyType,
+		Fields: map[string]*framework.FieldSchema{
+			"private_key_data": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Base64-encoded JSON credentials file of the key",
+			},
+		},
+
+		Renew:  b.secretKeyRenew,
+		Revoke: b.secretKeyRevoke,
+	}
+}
+
+// rolesetOfType returns the named roleset, or an error response if it does
+// not exist or does not issue the given secret type
+func (b *backend) rolesetOfType(s logical.Storage, name, secretType string) (*rolesetEntry, *logical.Response, error) {
+	roleset, err := b.Roleset(s, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if roleset == nil {
+		return nil, logical.ErrorResponse(fmt.Sprintf("unknown roleset: %s", name)), nil
+	}
+	if roleset.SecretType != secretType {
+		return nil, logical.ErrorResponse(fmt.Sprintf("roleset %q issues secrets of type %q", name, roleset.SecretType)), nil
+	}
+	return roleset, nil, nil
+}
+
+func (b *backend) pathKeyRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("roleset").(string)
+
+	roleset, errResp, err := b.rolesetOfType(req.Storage, name, secretTypeKey)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	client, err := b.Client(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := client.CreateKey(roleset.Project, roleset.ServiceAccountEmail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service account key: %v", err)
+	}
+
+	resp := b.Secret(secretServiceAccountKeyType).Response(map[string]interface{}{
+		"private_key_data": key.PrivateKeyData,
+		"key_algorithm":    key.KeyAlgorithm,
+		"key_type":         key.PrivateKeyType,
+	}, map[string]interface{}{
+		"key_name": key.Name,
+		"roleset":  name,
+	})
+	if config.TTL > 0 {
+		resp.Secret.TTL = config.TTL
+	}
+	return resp, nil
+}
+
+func (b *backend) pathTokenRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("roleset").(string)
+
+	roleset, errResp, err := b.rolesetOfType(req.Storage, name, secretTypeAccessToken)
+	if errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	client, err := b.Client(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Access tokens cannot be revoked, so they are issued without a lease
+	// and with the shortest of the mount's default TTL and the GCP limit
+	lifetime := b.System().DefaultLeaseTTL()
+	if lifetime <= 0 || lifetime > maxAccessTokenLifetime {
+		lifetime = maxAccessTokenLifetime
+	}
+
+	token, expires, err := client.GenerateAccessToken(roleset.ServiceAccountEmail, roleset.TokenScopes, lifetime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %v", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"token":              token,
+			"expires_at_seconds": expires.Unix(),
+			"token_ttl":          int64(expires.Sub(time.Now()).Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) secretKeyRenew(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	f := framework.LeaseExtend(config.TTL, config.MaxTTL, b.System())
+	return f(req, d)
+}
+
+func (b *backend) secretKeyRevoke(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	keyNameRaw, ok := req.Secret.InternalData["key_name"]
+	if !ok {
+		return nil, fmt.Errorf("secret is missing key_name internal data")
+	}
+
+	client, err := b.Client(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// The key is already gone if its roleset was deleted
+	if err := client.DeleteKey(keyNameRaw.(string)); err != nil && !isNotFound(err) {
+		return nil, fmt.Errorf("failed to delete service account key: %v", err)
+	}
+	return nil, nil
+}
+
+const pathKeyHelpSyn = `
+Create a service account key for a roleset.
+`
+
+const pathKeyHelpDesc = `
+This path creates a new key for the service account of the roleset, which
+must have the service_account_key secret type. The key is returned as a
+base64-encoded JSON credentials file and is deleted when its lease expires
+or is revoked.
+`
+
+const pathTokenHelpSyn = `
+Generate an OAuth2 access token for a roleset.
+`
+
+const pathTokenHelpDesc = `
+This path generates an access token for the service account of the roleset,
+which must have the access_token secret type, with the roleset's scopes.
+Access tokens cannot be revoked, so they are returned without a lease; they
+expire after the mount's default TTL, or after one hour, whichever is
+shorter.
+`
@@ -50,6 +50,7 @@ func Backend() *backend {
 			pathSignIntermediate(&b),
 			pathConfigCA(&b),
 			pathConfigCRL(&b),
+			pathConfigAutoTidy(&b),
 			pathConfigURLs(&b),
 			pathSignVerbatim(&b),
 			pathSign(&b),
@@ -69,7 +70,8 @@ func Backend() *backend {
 			secretCerts(&b),
 		},
 
-		BackendType: logical.TypeLogical,
+		PeriodicFunc: b.periodicFunc,
+		BackendType:  logical.TypeLogical,
 	}
 
 	b.crlLifetime = time.Hour * 72
@@ -82,6 +84,12 @@ type backend struct {
 
 	crlLifetime       time.Duration
 	revokeStorageLock sync.RWMutex
+
+	// tidyLock serializes tidy operations
+	tidyLock sync.Mutex
+
+	lastAutoTidy     time.Time
+	lastAutoTidyLock sync.Mutex
 }
 
 const backendHelp = `
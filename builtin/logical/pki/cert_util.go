@@ -12,7 +12,9 @@ import (
 	"encoding/pem"
 	"fmt"
 	"net"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -41,6 +43,8 @@ type creationBundle struct {
 	DNSNames       []string
 	EmailAddresses []string
 	IPAddresses    []net.IP
+	URIs           []*url.URL
+	OtherSANs      []otherSAN
 	IsCA           bool
 	KeyType        string
 	KeyBits        int
@@ -57,6 +61,15 @@ type creationBundle struct {
 
 	// The maximum path length to encode
 	MaxPathLength int
+
+	// The policy OIDs to encode
+	PolicyIdentifiers []asn1.ObjectIdentifier
+}
+
+// otherSAN is an otherName Subject Alternative Name holding a UTF-8 string
+type otherSAN struct {
+	OID   asn1.ObjectIdentifier
+	Value string
 }
 
 type caInfoBundle struct {
@@ -88,6 +101,7 @@ func (b *caInfoBundle) GetCAChain() []*certutil.CertBlock {
 var (
 	hostnameRegex                = regexp.MustCompile(`^(([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9\-]*[a-zA-Z0-9])\.)*([A-Za-z0-9]|[A-Za-z0-9][A-Za-z0-9\-]*[A-Za-z0-9])$`)
 	oidExtensionBasicConstraints = []int{2, 5, 29, 19}
+	oidExtensionSubjectAltName   = []int{2, 5, 29, 17}
 )
 
 func oidInExtensions(oid asn1.ObjectIdentifier, extensions []pkix.Extension) bool {
@@ -415,6 +429,175 @@ func validateNames(req *logical.Request, names []string, role *roleEntry) string
 	return ""
 }
 
+// validateURISANs returns the first URI not matching any of the URI SANs
+// allowed by the role, or an empty string if all are allowed
+func validateURISANs(uris []*url.URL, role *roleEntry) string {
+	allowed := strutil.ParseStringSlice(role.AllowedURISANs, ",")
+	for _, uri := range uris {
+		valid := false
+		for _, pattern := range allowed {
+			if glob.Glob(pattern, uri.String()) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return uri.String()
+		}
+	}
+
+	return ""
+}
+
+// validateOtherSANs returns the first other SAN not matching any of the other
+// SANs allowed by the role, or an empty string if all are allowed
+func validateOtherSANs(others []otherSAN, role *roleEntry) string {
+	if role.AllowedOtherSANs == "*" {
+		return ""
+	}
+
+	allowed := strutil.ParseStringSlice(role.AllowedOtherSANs, ",")
+	for _, other := range others {
+		valid := false
+		for _, pattern := range allowed {
+			oid, value, err := parseOtherSAN(pattern)
+			if err != nil {
+				continue
+			}
+			if oid.Equal(other.OID) && glob.Glob(value, other.Value) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Sprintf("%s;UTF8:%s", other.OID, other.Value)
+		}
+	}
+
+	return ""
+}
+
+// parseOID parses an OID in dotted notation, such as 1.3.6.1.4.1.311.20.2.3
+func parseOID(input string) (asn1.ObjectIdentifier, error) {
+	parts := strings.Split(input, ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("%q is not a valid OID", input)
+	}
+	oid := make(asn1.ObjectIdentifier, 0, len(parts))
+	for _, part := range parts {
+		arc, err := strconv.Atoi(part)
+		if err != nil || arc < 0 {
+			return nil, fmt.Errorf("%q is not a valid OID", input)
+		}
+		oid = append(oid, arc)
+	}
+
+	return oid, nil
+}
+
+// parseOIDs parses a comma-separated list of OIDs in dotted notation
+func parseOIDs(input string) ([]asn1.ObjectIdentifier, error) {
+	var oids []asn1.ObjectIdentifier
+	for _, v := range strutil.ParseStringSlice(input, ",") {
+		oid, err := parseOID(v)
+		if err != nil {
+			return nil, err
+		}
+		oids = append(oids, oid)
+	}
+
+	return oids, nil
+}
+
+// parseOtherSAN parses an other SAN in the format <oid>;UTF8:<value>
+func parseOtherSAN(input string) (asn1.ObjectIdentifier, string, error) {
+	splitOther := strings.SplitN(input, ";", 2)
+	if len(splitOther) != 2 {
+		return nil, "", fmt.Errorf("expected a semicolon in other SAN %q", input)
+	}
+	oid, err := parseOID(strings.TrimSpace(splitOther[0]))
+	if err != nil {
+		return nil, "", err
+	}
+	splitType := strings.SplitN(splitOther[1], ":", 2)
+	if len(splitType) != 2 {
+		return nil, "", fmt.Errorf("expected a colon in other SAN %q", input)
+	}
+	switch strings.ToLower(splitType[0]) {
+	case "utf8", "utf-8":
+	default:
+		return nil, "", fmt.Errorf("only UTF8 other SANs are supported, got %q", splitType[0])
+	}
+
+	return oid, splitType[1], nil
+}
+
+// marshalSANs builds the Subject Alternative Name extension holding all the
+// given names. The standard library cannot encode other names, so the
+// extension is built here whenever there are some.
+func marshalSANs(dnsNames, emailAddresses []string, ipAddresses []net.IP, uris []*url.URL, others []otherSAN) (pkix.Extension, error) {
+	var rawValues []asn1.RawValue
+	for _, other := range others {
+		typeID, err := asn1.Marshal(other.OID)
+		if err != nil {
+			return pkix.Extension{}, err
+		}
+		value, err := asn1.MarshalWithParams(other.Value, "utf8")
+		if err != nil {
+			return pkix.Extension{}, err
+		}
+		explicitValue, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: value})
+		if err != nil {
+			return pkix.Extension{}, err
+		}
+		rawValues = append(rawValues, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: append(typeID, explicitValue...)})
+	}
+	for _, email := range emailAddresses {
+		rawValues = append(rawValues, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, Bytes: []byte(email)})
+	}
+	for _, name := range dnsNames {
+		rawValues = append(rawValues, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, Bytes: []byte(name)})
+	}
+	for _, uri := range uris {
+		rawValues = append(rawValues, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 6, Bytes: []byte(uri.String())})
+	}
+	for _, rawIP := range ipAddresses {
+		ip := rawIP.To4()
+		if ip == nil {
+			ip = rawIP
+		}
+		rawValues = append(rawValues, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 7, Bytes: ip})
+	}
+
+	value, err := asn1.Marshal(rawValues)
+	if err != nil {
+		return pkix.Extension{}, err
+	}
+
+	return pkix.Extension{Id: oidExtensionSubjectAltName, Value: value}, nil
+}
+
+// addSANs sets the Subject Alternative Names of the template, adding the
+// extension directly when there are other names to encode
+func addSANs(certTemplate *x509.Certificate, dnsNames, emailAddresses []string, ipAddresses []net.IP, uris []*url.URL, others []otherSAN) error {
+	certTemplate.DNSNames = dnsNames
+	certTemplate.EmailAddresses = emailAddresses
+	certTemplate.IPAddresses = ipAddresses
+	certTemplate.URIs = uris
+
+	if len(others) == 0 {
+		return nil
+	}
+
+	sanExtension, err := marshalSANs(dnsNames, emailAddresses, ipAddresses, uris, others)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error marshalling subject alternative names: %s", err)}
+	}
+	certTemplate.ExtraExtensions = append(certTemplate.ExtraExtensions, sanExtension)
+
+	return nil
+}
+
 func generateCert(b *backend,
 	role *roleEntry,
 	signingBundle *caInfoBundle,
@@ -704,6 +887,63 @@ func generateCreationBundle(b *backend,
 		}
 	}
 
+	// Get and verify any URI SANs
+	uris := []*url.URL{}
+	{
+		if csr != nil && role.UseCSRSANs {
+			uris = csr.URIs
+		} else {
+			uriAltInt, ok := data.GetOk("uri_sans")
+			if ok {
+				for _, v := range strutil.ParseDedupAndSortStrings(uriAltInt.(string), ",") {
+					parsedURI, err := url.Parse(v)
+					if err != nil || parsedURI.Scheme == "" {
+						return nil, errutil.UserError{Err: fmt.Sprintf(
+							"the value '%s' is not a valid URI", v)}
+					}
+					uris = append(uris, parsedURI)
+				}
+			}
+		}
+
+		badURI := validateURISANs(uris, role)
+		if len(badURI) != 0 {
+			return nil, errutil.UserError{Err: fmt.Sprintf(
+				"URI Subject Alternative Name %s not allowed by this role", badURI)}
+		}
+	}
+
+	// Get and verify any other SANs
+	otherSANs := []otherSAN{}
+	{
+		otherAltInt, ok := data.GetOk("other_sans")
+		if ok {
+			for _, v := range strutil.ParseDedupAndSortStrings(otherAltInt.(string), ",") {
+				oid, value, err := parseOtherSAN(v)
+				if err != nil {
+					return nil, errutil.UserError{Err: err.Error()}
+				}
+				otherSANs = append(otherSANs, otherSAN{
+					OID:   oid,
+					Value: value,
+				})
+			}
+		}
+
+		badOther := validateOtherSANs(otherSANs, role)
+		if len(badOther) != 0 {
+			return nil, errutil.UserError{Err: fmt.Sprintf(
+				"other Subject Alternative Name %s not allowed by this role", badOther)}
+		}
+	}
+
+	// Set the policy identifiers specified in the role
+	policyIdentifiers, err := parseOIDs(role.PolicyIdentifiers)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf(
+			"invalid policy identifiers in role: %s", err)}
+	}
+
 	// Set OU (organizationalUnit) values if specified in the role
 	ou := []string{}
 	{
@@ -791,18 +1031,21 @@ func generateCreationBundle(b *backend,
 	}
 
 	creationBundle := &creationBundle{
-		CommonName:     cn,
-		OU:             ou,
-		Organization:   organization,
-		DNSNames:       dnsNames,
-		EmailAddresses: emailAddresses,
-		IPAddresses:    ipAddresses,
-		KeyType:        role.KeyType,
-		KeyBits:        role.KeyBits,
-		SigningBundle:  signingBundle,
-		TTL:            ttl,
-		KeyUsage:       x509.KeyUsage(parseKeyUsages(role.KeyUsage)),
-		ExtKeyUsage:    extUsage,
+		CommonName:        cn,
+		OU:                ou,
+		Organization:      organization,
+		DNSNames:          dnsNames,
+		EmailAddresses:    emailAddresses,
+		IPAddresses:       ipAddresses,
+		URIs:              uris,
+		OtherSANs:         otherSANs,
+		KeyType:           role.KeyType,
+		KeyBits:           role.KeyBits,
+		SigningBundle:     signingBundle,
+		TTL:               ttl,
+		KeyUsage:          x509.KeyUsage(parseKeyUsages(role.KeyUsage)),
+		ExtKeyUsage:       extUsage,
+		PolicyIdentifiers: policyIdentifiers,
 	}
 
 	// Don't deal with URLs or max path length if it's self-signed, as these
@@ -890,15 +1133,18 @@ func createCertificate(creationInfo *creationBundle) (*certutil.ParsedCertBundle
 	}
 
 	certTemplate := &x509.Certificate{
-		SerialNumber:   serialNumber,
-		Subject:        subject,
-		NotBefore:      time.Now().Add(-30 * time.Second),
-		NotAfter:       time.Now().Add(creationInfo.TTL),
-		IsCA:           false,
-		SubjectKeyId:   subjKeyID,
-		DNSNames:       creationInfo.DNSNames,
-		EmailAddresses: creationInfo.EmailAddresses,
-		IPAddresses:    creationInfo.IPAddresses,
+		SerialNumber:      serialNumber,
+		Subject:           subject,
+		NotBefore:         time.Now().Add(-30 * time.Second),
+		NotAfter:          time.Now().Add(creationInfo.TTL),
+		IsCA:              false,
+		SubjectKeyId:      subjKeyID,
+		PolicyIdentifiers: creationInfo.PolicyIdentifiers,
+	}
+
+	if err := addSANs(certTemplate, creationInfo.DNSNames, creationInfo.EmailAddresses,
+		creationInfo.IPAddresses, creationInfo.URIs, creationInfo.OtherSANs); err != nil {
+		return nil, err
 	}
 
 	// Add this before calling addKeyUsages
@@ -1054,11 +1300,12 @@ func signCertificate(creationInfo *creationBundle,
 	}
 
 	certTemplate := &x509.Certificate{
-		SerialNumber: serialNumber,
-		Subject:      subject,
-		NotBefore:    time.Now().Add(-30 * time.Second),
-		NotAfter:     time.Now().Add(creationInfo.TTL),
-		SubjectKeyId: subjKeyID[:],
+		SerialNumber:      serialNumber,
+		Subject:           subject,
+		NotBefore:         time.Now().Add(-30 * time.Second),
+		NotAfter:          time.Now().Add(creationInfo.TTL),
+		SubjectKeyId:      subjKeyID[:],
+		PolicyIdentifiers: creationInfo.PolicyIdentifiers,
 	}
 
 	switch creationInfo.SigningBundle.PrivateKeyType {
@@ -1074,12 +1321,14 @@ func signCertificate(creationInfo *creationBundle,
 		certTemplate.DNSNames = csr.DNSNames
 		certTemplate.EmailAddresses = csr.EmailAddresses
 		certTemplate.IPAddresses = csr.IPAddresses
+		certTemplate.URIs = csr.URIs
 
 		certTemplate.ExtraExtensions = csr.Extensions
 	} else {
-		certTemplate.DNSNames = creationInfo.DNSNames
-		certTemplate.EmailAddresses = creationInfo.EmailAddresses
-		certTemplate.IPAddresses = creationInfo.IPAddresses
+		if err := addSANs(certTemplate, creationInfo.DNSNames, creationInfo.EmailAddresses,
+			creationInfo.IPAddresses, creationInfo.URIs, creationInfo.OtherSANs); err != nil {
+			return nil, err
+		}
 	}
 
 	addKeyUsages(creationInfo, certTemplate)
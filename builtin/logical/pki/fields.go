@@ -58,6 +58,19 @@ is enabled for the role, this may contain
 email addresses.`,
 	}
 
+	fields["uri_sans"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `The requested URI SANs, if any, in a
+comma-delimited list`,
+	}
+
+	fields["other_sans"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `Requested other SANs, in a comma-delimited list
+with the format <oid>;UTF8:<utf8 string value> for
+each entry`,
+	}
+
 	fields["ttl"] = &framework.FieldSchema{
 		Type: framework.TypeString,
 		Description: `The requested Time To Live for the certificate;
@@ -0,0 +1,177 @@
+package pki
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// autoTidyConfig holds the configuration of the tidy operation run
+// periodically by the backend
+type autoTidyConfig struct {
+	Enabled            bool          `json:"enabled" mapstructure:"enabled" structs:"enabled"`
+	Interval           time.Duration `json:"interval" mapstructure:"interval" structs:"interval"`
+	TidyCertStore      bool          `json:"tidy_cert_store" mapstructure:"tidy_cert_store" structs:"tidy_cert_store"`
+	TidyRevocationList bool          `json:"tidy_revocation_list" mapstructure:"tidy_revocation_list" structs:"tidy_revocation_list"`
+	SafetyBuffer       time.Duration `json:"safety_buffer" mapstructure:"safety_buffer" structs:"safety_buffer"`
+}
+
+func pathConfigAutoTidy(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/auto-tidy",
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `Set to true to enable tidying up periodically`,
+			},
+
+			"interval": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `The minimum amount of time between two runs of
+the periodic tidy operation. Defaults to 12 hours.`,
+				Default: 43200, // 12h
+			},
+
+			"tidy_cert_store": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Set to true to enable tidying up
+the certificate store`,
+			},
+
+			"tidy_revocation_list": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Set to true to enable tidying up
+the revocation list`,
+			},
+
+			"safety_buffer": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `The amount of extra time that must have passed
+beyond certificate expiration before it is removed
+from the backend storage and/or revocation list.
+Defaults to 72 hours.`,
+				Default: 259200, // 72h
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathAutoTidyRead,
+			logical.UpdateOperation: b.pathAutoTidyWrite,
+		},
+
+		HelpSynopsis:    pathConfigAutoTidyHelpSyn,
+		HelpDescription: pathConfigAutoTidyHelpDesc,
+	}
+}
+
+func (b *backend) autoTidyConfig(s logical.Storage) (*autoTidyConfig, error) {
+	entry, err := s.Get("config/auto-tidy")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result autoTidyConfig
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathAutoTidyRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.autoTidyConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":              config.Enabled,
+			"interval":             int64(config.Interval.Seconds()),
+			"tidy_cert_store":      config.TidyCertStore,
+			"tidy_revocation_list": config.TidyRevocationList,
+			"safety_buffer":        int64(config.SafetyBuffer.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathAutoTidyWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config := &autoTidyConfig{
+		Enabled:            d.Get("enabled").(bool),
+		Interval:           time.Duration(d.Get("interval").(int)) * time.Second,
+		TidyCertStore:      d.Get("tidy_cert_store").(bool),
+		TidyRevocationList: d.Get("tidy_revocation_list").(bool),
+		SafetyBuffer:       time.Duration(d.Get("safety_buffer").(int)) * time.Second,
+	}
+
+	if config.Interval < minAutoTidyInterval {
+		return logical.ErrorResponse(fmt.Sprintf("interval must be at least %s", minAutoTidyInterval)), nil
+	}
+	if config.SafetyBuffer < minAutoTidySafetyBuffer {
+		return logical.ErrorResponse(fmt.Sprintf("safety_buffer must be at least %s", minAutoTidySafetyBuffer)), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("config/auto-tidy", config)
+	if err != nil {
+		return nil, err
+	}
+	err = req.Storage.Put(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// periodicFunc runs the configured tidy operation once its interval has
+// elapsed since the last run on this node
+func (b *backend) periodicFunc(req *logical.Request) error {
+	config, err := b.autoTidyConfig(req.Storage)
+	if err != nil {
+		return err
+	}
+	if config == nil || !config.Enabled {
+		return nil
+	}
+
+	b.lastAutoTidyLock.Lock()
+	if time.Since(b.lastAutoTidy) < config.Interval {
+		b.lastAutoTidyLock.Unlock()
+		return nil
+	}
+	b.lastAutoTidy = time.Now()
+	b.lastAutoTidyLock.Unlock()
+
+	b.Logger().Info("pki: running automatic tidy operation")
+	if err := b.tidy(req, config.TidyCertStore, config.TidyRevocationList, config.SafetyBuffer); err != nil {
+		b.Logger().Error("pki: automatic tidy operation failed", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+const pathConfigAutoTidyHelpSyn = `
+Configure the periodic tidy operation.
+`
+
+const pathConfigAutoTidyHelpDesc = `
+This endpoint allows the tidy operation to be run periodically by the backend,
+removing expired certificates and/or revocation information without having to
+call the tidy endpoint. The parameters have the same meaning as those of the
+tidy endpoint; the operation runs at most once per 'interval'.
+
+Automatic runs require a 'safety_buffer' of at least one hour so that clock
+skew cannot lead to a certificate being removed from the CRL while it is still
+considered valid.
+`
@@ -1,6 +1,7 @@
 package pki
 
 import (
+	"crypto/x509"
 	"fmt"
 
 	"github.com/hashicorp/vault/helper/certutil"
@@ -58,6 +59,19 @@ func (b *backend) pathCAWrite(
 		return logical.ErrorResponse("the given certificate is not marked for CA use and cannot be used with this backend"), nil
 	}
 
+	// An imported CA may restrict its key usages; it must be able to sign the
+	// certificates it issues and should be able to sign the CRL published by
+	// this backend
+	resp := &logical.Response{}
+	if keyUsage := parsedBundle.Certificate.KeyUsage; keyUsage != 0 {
+		if keyUsage&x509.KeyUsageCertSign == 0 {
+			return logical.ErrorResponse("the given certificate does not allow the cert sign key usage and cannot be used with this backend"), nil
+		}
+		if keyUsage&x509.KeyUsageCRLSign == 0 {
+			resp.AddWarning("the given certificate does not allow the CRL sign key usage; clients enforcing key usages will reject the CRL published by this backend")
+		}
+	}
+
 	cb, err := parsedBundle.ToCertBundle()
 	if err != nil {
 		return nil, fmt.Errorf("error converting raw values into cert bundle: %s", err)
@@ -82,8 +96,14 @@ func (b *backend) pathCAWrite(
 	}
 
 	err = buildCRL(b, req)
+	if err != nil {
+		return nil, err
+	}
 
-	return nil, err
+	if len(resp.Warnings) == 0 {
+		return nil, nil
+	}
+	return resp, nil
 }
 
 const pathConfigCAHelpSyn = `
@@ -93,7 +113,10 @@ Set the CA certificate and private key used for generated credentials.
 const pathConfigCAHelpDesc = `
 This sets the CA information used for credentials generated by this
 by this mount. This must be a PEM-format, concatenated unencrypted
-secret key and certificate.
+secret key and certificate, optionally followed by the chain of the
+certificate, which is then returned along with issued certificates.
+If the certificate restricts its key usages, it must allow signing
+certificates, and should allow signing CRLs.
 
 For security reasons, the secret key cannot be retrieved later.
 `
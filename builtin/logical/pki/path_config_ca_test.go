@@ -0,0 +1,126 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// testCA generates a CA certificate with the given key usages, signed by the
+// parent if given, and returns it PEM-encoded along with its key
+func testCA(t *testing.T, cn string, keyUsage x509.KeyUsage, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              keyUsage,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, key,
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}))
+}
+
+func TestPki_ConfigCA(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	configCA := func(pemBundle string) (*logical.Response, error) {
+		return b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config/ca",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"pem_bundle": pemBundle,
+			},
+		})
+	}
+
+	// A CA that cannot sign certificates is rejected
+	_, _, certPEM, keyPEM := testCA(t, "no-cert-sign", x509.KeyUsageDigitalSignature, nil, nil)
+	resp, err := configCA(keyPEM + certPEM)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err: %v resp: %#v", err, resp)
+	}
+
+	// A CA that cannot sign CRLs is accepted with a warning
+	_, _, certPEM, keyPEM = testCA(t, "no-crl-sign", x509.KeyUsageCertSign, nil, nil)
+	resp, err = configCA(keyPEM + certPEM)
+	if err != nil || resp == nil || resp.IsError() || len(resp.Warnings) != 1 {
+		t.Fatalf("expected warning: err: %v resp: %#v", err, resp)
+	}
+
+	// An intermediate imported along with its chain is served with issued
+	// certificates
+	root, rootKey, rootPEM, _ := testCA(t, "root", x509.KeyUsageCertSign|x509.KeyUsageCRLSign, nil, nil)
+	_, _, intPEM, intKeyPEM := testCA(t, "intermediate", x509.KeyUsageCertSign|x509.KeyUsageCRLSign, root, rootKey)
+	resp, err = configCA(intKeyPEM + intPEM + rootPEM)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"allow_any_name": true,
+			"ttl":            "1h",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "issue/test",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"common_name": "example.com",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["issuing_ca"] != strings.TrimSpace(intPEM) {
+		t.Fatalf("bad: issuing_ca: %#v", resp.Data["issuing_ca"])
+	}
+	caChain, ok := resp.Data["ca_chain"].([]string)
+	if !ok || len(caChain) != 2 || caChain[0] != strings.TrimSpace(intPEM) || caChain[1] != strings.TrimSpace(rootPEM) {
+		t.Fatalf("bad: ca_chain: %#v", resp.Data["ca_chain"])
+	}
+}
@@ -133,6 +133,7 @@ func (b *backend) pathSignVerbatim(
 		AllowLocalhost:   true,
 		AllowAnyName:     true,
 		AllowIPSANs:      true,
+		AllowedURISANs:   "*",
 		EnforceHostnames: false,
 		KeyType:          "any",
 		UseCSRCommonName: true,
@@ -8,6 +8,7 @@ import (
 
 	"github.com/fatih/structs"
 	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -114,6 +115,33 @@ CN and SANs. Defaults to true.`,
 Any valid IP is accepted.`,
 			},
 
+			"allowed_uri_sans": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `If set, an array of allowed URIs to put in the URI
+Subject Alternative Names. Any valid URI is accepted,
+these values support globbing. If empty, URI SANs
+are not allowed.`,
+			},
+
+			"allowed_other_sans": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `If set, an array of allowed other names to put in
+SANs. These values support globbing and must be in
+the format <oid>;UTF8:<value>. Currently only
+"UTF8" is a valid type. All values, including
+globbing values, must use this syntax, with the
+exception being a single "*" which allows any OID
+and any value (but inputs must still adhere to
+this syntax).`,
+			},
+
+			"policy_identifiers": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `A comma-separated string or list of policy OIDs
+to set in the certificate policies extension of the
+certificates issued by this role.`,
+			},
+
 			"server_flag": &framework.FieldSchema{
 				Type:    framework.TypeBool,
 				Default: true,
@@ -392,6 +420,9 @@ func (b *backend) pathRoleCreate(
 		AllowAnyName:        data.Get("allow_any_name").(bool),
 		EnforceHostnames:    data.Get("enforce_hostnames").(bool),
 		AllowIPSANs:         data.Get("allow_ip_sans").(bool),
+		AllowedURISANs:      data.Get("allowed_uri_sans").(string),
+		AllowedOtherSANs:    data.Get("allowed_other_sans").(string),
+		PolicyIdentifiers:   data.Get("policy_identifiers").(string),
 		ServerFlag:          data.Get("server_flag").(bool),
 		ClientFlag:          data.Get("client_flag").(bool),
 		CodeSigningFlag:     data.Get("code_signing_flag").(bool),
@@ -418,6 +449,20 @@ func (b *backend) pathRoleCreate(
 		return logical.ErrorResponse("RSA keys < 2048 bits are unsafe and not supported"), nil
 	}
 
+	if entry.AllowedOtherSANs != "*" {
+		for _, other := range strutil.ParseStringSlice(entry.AllowedOtherSANs, ",") {
+			if _, _, err := parseOtherSAN(other); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf(
+					"Invalid allowed other SAN %q: %s", other, err)), nil
+			}
+		}
+	}
+
+	if _, err := parseOIDs(entry.PolicyIdentifiers); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"Invalid policy identifiers: %s", err)), nil
+	}
+
 	var maxTTL time.Duration
 	maxSystemTTL := b.System().MaxLeaseTTL()
 	if len(entry.MaxTTL) == 0 {
@@ -518,6 +563,9 @@ type roleEntry struct {
 	AllowAnyName          bool   `json:"allow_any_name" structs:"allow_any_name" mapstructure:"allow_any_name"`
 	EnforceHostnames      bool   `json:"enforce_hostnames" structs:"enforce_hostnames" mapstructure:"enforce_hostnames"`
 	AllowIPSANs           bool   `json:"allow_ip_sans" structs:"allow_ip_sans" mapstructure:"allow_ip_sans"`
+	AllowedURISANs        string `json:"allowed_uri_sans" structs:"allowed_uri_sans" mapstructure:"allowed_uri_sans"`
+	AllowedOtherSANs      string `json:"allowed_other_sans" structs:"allowed_other_sans" mapstructure:"allowed_other_sans"`
+	PolicyIdentifiers     string `json:"policy_identifiers" structs:"policy_identifiers" mapstructure:"policy_identifiers"`
 	ServerFlag            bool   `json:"server_flag" structs:"server_flag" mapstructure:"server_flag"`
 	ClientFlag            bool   `json:"client_flag" structs:"client_flag" mapstructure:"client_flag"`
 	CodeSigningFlag       bool   `json:"code_signing_flag" structs:"code_signing_flag" mapstructure:"code_signing_flag"`
@@ -1,6 +1,10 @@
 package pki
 
 import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/vault/logical"
@@ -308,3 +312,143 @@ func TestPki_CertsLease(t *testing.T) {
 		t.Fatalf("expected a response that contains a secret")
 	}
 }
+
+func TestPki_RoleURIAndOtherSANsAndPolicyIdentifiers(t *testing.T) {
+	var resp *logical.Response
+	var err error
+	b, storage := createBackendWithStorage(t)
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "root/generate/internal",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"common_name": "myvault.com",
+			"ttl":         "5h",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+
+	roleReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/testrole",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"allowed_domains":    "myvault.com",
+			"allow_subdomains":   true,
+			"allowed_uri_sans":   "spiffe://myvault.com/*",
+			"allowed_other_sans": "1.3.6.1.4.1.311.20.2.3;UTF8:*@myvault.com",
+			"policy_identifiers": "1.3.6.1.4.1.44947.1.1.1",
+			"ttl":                "1h",
+		},
+	}
+
+	// Invalid other SANs and OIDs are rejected
+	roleReq.Data["policy_identifiers"] = "1.3.foo"
+	resp, err = b.HandleRequest(roleReq)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err: %v resp: %#v", err, resp)
+	}
+	roleReq.Data["policy_identifiers"] = "1.3.6.1.4.1.44947.1.1.1"
+	roleReq.Data["allowed_other_sans"] = "1.3.6.1.4.1.311.20.2.3;IA5:*"
+	resp, err = b.HandleRequest(roleReq)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err: %v resp: %#v", err, resp)
+	}
+	roleReq.Data["allowed_other_sans"] = "1.3.6.1.4.1.311.20.2.3;UTF8:*@myvault.com"
+	resp, err = b.HandleRequest(roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+
+	issue := func(data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "issue/testrole",
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+
+	// Names not allowed by the role are rejected
+	resp, err = issue(map[string]interface{}{
+		"common_name": "cert.myvault.com",
+		"uri_sans":    "spiffe://example.com/service",
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err: %v resp: %#v", err, resp)
+	}
+	resp, err = issue(map[string]interface{}{
+		"common_name": "cert.myvault.com",
+		"other_sans":  "1.3.6.1.4.1.311.20.2.3;UTF8:user@example.com",
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err: %v resp: %#v", err, resp)
+	}
+
+	resp, err = issue(map[string]interface{}{
+		"common_name": "cert.myvault.com",
+		"uri_sans":    "spiffe://myvault.com/service",
+		"other_sans":  "1.3.6.1.4.1.311.20.2.3;UTF8:user@myvault.com",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+
+	block, _ := pem.Decode([]byte(resp.Data["certificate"].(string)))
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(cert.DNSNames, []string{"cert.myvault.com"}) {
+		t.Fatalf("bad: DNS names: %#v", cert.DNSNames)
+	}
+	if len(cert.URIs) != 1 || cert.URIs[0].String() != "spiffe://myvault.com/service" {
+		t.Fatalf("bad: URIs: %#v", cert.URIs)
+	}
+	if len(cert.PolicyIdentifiers) != 1 || cert.PolicyIdentifiers[0].String() != "1.3.6.1.4.1.44947.1.1.1" {
+		t.Fatalf("bad: policy identifiers: %#v", cert.PolicyIdentifiers)
+	}
+
+	// Find the other name in the SAN extension
+	var otherName string
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidExtensionSubjectAltName) {
+			continue
+		}
+		var seq asn1.RawValue
+		if _, err := asn1.Unmarshal(ext.Value, &seq); err != nil {
+			t.Fatal(err)
+		}
+		rest := seq.Bytes
+		for len(rest) > 0 {
+			var name asn1.RawValue
+			if rest, err = asn1.Unmarshal(rest, &name); err != nil {
+				t.Fatal(err)
+			}
+			if name.Tag != 0 {
+				continue
+			}
+			var oid asn1.ObjectIdentifier
+			valueBytes, err := asn1.Unmarshal(name.Bytes, &oid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var explicitValue asn1.RawValue
+			if _, err := asn1.Unmarshal(valueBytes, &explicitValue); err != nil {
+				t.Fatal(err)
+			}
+			var value string
+			if _, err := asn1.UnmarshalWithParams(explicitValue.Bytes, &value, "utf8"); err != nil {
+				t.Fatal(err)
+			}
+			otherName = oid.String() + ";" + value
+		}
+	}
+	if otherName != "1.3.6.1.4.1.311.20.2.3;user@myvault.com" {
+		t.Fatalf("bad: other name: %q", otherName)
+	}
+}
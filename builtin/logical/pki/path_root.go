@@ -190,6 +190,7 @@ func (b *backend) pathCASignIntermediate(
 		AllowLocalhost:   true,
 		AllowAnyName:     true,
 		AllowIPSANs:      true,
+		AllowedURISANs:   "*",
 		EnforceHostnames: false,
 		KeyType:          "any",
 	}
@@ -0,0 +1,124 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestPki_AutoTidy(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	// putCert stores a self-signed certificate expiring at notAfter
+	putCert := func(serial int64, notAfter time.Time) string {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "tidy.example.com"},
+			NotBefore:    notAfter.Add(-24 * time.Hour),
+			NotAfter:     notAfter,
+		}
+		certBytes, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := big.NewInt(serial).String()
+		if err := storage.Put(&logical.StorageEntry{
+			Key:   "certs/" + name,
+			Value: certBytes,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+
+	certExists := func(name string) bool {
+		entry, err := storage.Get("certs/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entry != nil
+	}
+
+	expired := putCert(1, time.Now().Add(-4*time.Hour))
+	withinBuffer := putCert(2, time.Now().Add(-30*time.Minute))
+
+	// Nothing is tidied until enabled
+	if err := b.periodicFunc(&logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	if !certExists(expired) {
+		t.Fatal("certificate tidied while auto-tidy is disabled")
+	}
+
+	// Safety buffers shorter than the minimum are rejected
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/auto-tidy",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"enabled":         true,
+			"tidy_cert_store": true,
+			"safety_buffer":   "1s",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err: %v resp: %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/auto-tidy",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"enabled":         true,
+			"tidy_cert_store": true,
+			"safety_buffer":   "1h",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config/auto-tidy",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["interval"] != int64(43200) || resp.Data["safety_buffer"] != int64(3600) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Only certificates expired for longer than the safety buffer are removed
+	if err := b.periodicFunc(&logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	if certExists(expired) {
+		t.Fatal("expired certificate not tidied")
+	}
+	if !certExists(withinBuffer) {
+		t.Fatal("certificate within the safety buffer tidied")
+	}
+
+	// The operation does not run again before the interval elapses
+	expired = putCert(3, time.Now().Add(-4*time.Hour))
+	if err := b.periodicFunc(&logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	if !certExists(expired) {
+		t.Fatal("certificate tidied before the interval elapsed")
+	}
+}
@@ -216,6 +216,98 @@ func TestBackend_allowed_users(t *testing.T) {
 	}
 }
 
+func TestBackend_allowed_ports(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Backend(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.Setup(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.Initialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roleData := map[string]interface{}{
+		"key_type":      "otp",
+		"default_user":  "ubuntu",
+		"cidr_list":     "52.207.235.245/16",
+		"port":          22,
+		"allowed_ports": "2222,abc",
+	}
+	roleReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/role1",
+		Storage:   config.StorageView,
+		Data:      roleData,
+	}
+
+	resp, err := b.HandleRequest(roleReq)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected failure: resp:%#v err:%s", resp, err)
+	}
+
+	roleData["allowed_ports"] = "2222, 8022"
+	resp, err = b.HandleRequest(roleReq)
+	if err != nil || resp != nil {
+		t.Fatalf("failed to create role: resp:%#v err:%s", resp, err)
+	}
+
+	credsData := map[string]interface{}{
+		"ip":       "52.207.235.245",
+		"username": "ubuntu",
+	}
+	credsReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Storage:   config.StorageView,
+		Path:      "creds/role1",
+		Data:      credsData,
+	}
+
+	resp, err = b.HandleRequest(credsReq)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("failed to create credential: resp:%#v err:%s", resp, err)
+	}
+	if resp.Data["port"] != 22 {
+		t.Fatalf("bad: port: %#v", resp.Data["port"])
+	}
+
+	credsData["port"] = 8022
+	resp, err = b.HandleRequest(credsReq)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("failed to create credential: resp:%#v err:%s", resp, err)
+	}
+	if resp.Data["port"] != 8022 {
+		t.Fatalf("bad: port: %#v", resp.Data["port"])
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Storage:   config.StorageView,
+		Path:      "verify",
+		Data: map[string]interface{}{
+			"otp": resp.Data["key"],
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("failed to verify OTP: resp:%#v err:%s", resp, err)
+	}
+	if resp.Data["port"] != 8022 {
+		t.Fatalf("bad: port: %#v", resp.Data["port"])
+	}
+
+	credsData["port"] = 2200
+	resp, err = b.HandleRequest(credsReq)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected failure: resp:%#v err:%s", resp, err)
+	}
+}
+
 func testingFactory(conf *logical.BackendConfig) (logical.Backend, error) {
 	_, err := vault.StartSSHHostTestServer()
 	if err != nil {
@@ -14,6 +14,7 @@ type sshOTP struct {
 	Username string `json:"username" structs:"username" mapstructure:"username"`
 	IP       string `json:"ip" structs:"ip" mapstructure:"ip"`
 	RoleName string `json:"role_name" structs:"role_name" mapstructure:"role_name"`
+	Port     int    `json:"port" structs:"port" mapstructure:"port"`
 }
 
 func pathCredsCreate(b *backend) *framework.Path {
@@ -32,6 +33,10 @@ func pathCredsCreate(b *backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "[Required] IP of the remote host",
 			},
+			"port": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Description: "[Optional for OTP type] Port of the remote host, if allowed by the role",
+			},
 		},
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.UpdateOperation: b.pathCredsCreateWrite,
@@ -109,6 +114,18 @@ func (b *backend) pathCredsCreateWrite(
 		return logical.ErrorResponse(fmt.Sprintf("Error validating IP: %v", err)), nil
 	}
 
+	// Validate the requested port, if any
+	port := role.Port
+	if requestedPort := d.Get("port").(int); requestedPort != 0 && requestedPort != role.Port {
+		if role.KeyType != KeyTypeOTP {
+			return logical.ErrorResponse("Requesting a port is only supported for OTP type"), nil
+		}
+		if err := validatePort(requestedPort, role.AllowedPorts); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("Error validating port: %v", err)), nil
+		}
+		port = requestedPort
+	}
+
 	var result *logical.Response
 	if role.KeyType == KeyTypeOTP {
 		// Generate an OTP
@@ -116,6 +133,7 @@ func (b *backend) pathCredsCreateWrite(
 			Username: username,
 			IP:       ip,
 			RoleName: roleName,
+			Port:     port,
 		})
 		if err != nil {
 			return nil, err
@@ -130,7 +148,7 @@ func (b *backend) pathCredsCreateWrite(
 			"key":      otp,
 			"username": username,
 			"ip":       ip,
-			"port":     role.Port,
+			"port":     port,
 		}, map[string]interface{}{
 			"otp": otp,
 		})
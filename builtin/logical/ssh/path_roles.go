@@ -30,6 +30,7 @@ type sshRole struct {
 	CIDRList               string            `mapstructure:"cidr_list" json:"cidr_list"`
 	ExcludeCIDRList        string            `mapstructure:"exclude_cidr_list" json:"exclude_cidr_list"`
 	Port                   int               `mapstructure:"port" json:"port"`
+	AllowedPorts           string            `mapstructure:"allowed_ports" json:"allowed_ports"`
 	InstallScript          string            `mapstructure:"install_script" json:"install_script"`
 	AllowedUsers           string            `mapstructure:"allowed_users" json:"allowed_users"`
 	AllowedDomains         string            `mapstructure:"allowed_domains" json:"allowed_domains"`
@@ -120,6 +121,14 @@ func pathRoles(b *backend) *framework.Path {
 				to inform client about the port number to use. Port number will be
 				returned to client by Vault server along with OTP.`,
 			},
+			"allowed_ports": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `
+				[Optional for OTP type] [Not applicable for Dynamic type] [Not applicable for CA type]
+				Comma separated list of port numbers, besides 'port', which clients can
+				request when creating an OTP. The requested port is returned along with
+				the OTP and by the verify endpoint, so that the helper can check it.`,
+			},
 			"key_type": &framework.FieldSchema{
 				Type: framework.TypeString,
 				Description: `
@@ -334,6 +343,16 @@ func (b *backend) pathRoleWrite(req *logical.Request, d *framework.FieldData) (*
 	}
 	keyType = strings.ToLower(keyType)
 
+	allowedPorts := d.Get("allowed_ports").(string)
+	if allowedPorts != "" {
+		if keyType != KeyTypeOTP {
+			return logical.ErrorResponse("allowed ports are only applicable for OTP type"), nil
+		}
+		if _, err := parsePorts(allowedPorts); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("failed to validate allowed_ports: %v", err)), nil
+		}
+	}
+
 	var roleEntry sshRole
 	if keyType == KeyTypeOTP {
 		defaultUser := d.Get("default_user").(string)
@@ -355,6 +374,7 @@ func (b *backend) pathRoleWrite(req *logical.Request, d *framework.FieldData) (*
 			ExcludeCIDRList: excludeCidrList,
 			KeyType:         KeyTypeOTP,
 			Port:            port,
+			AllowedPorts:    allowedPorts,
 			AllowedUsers:    allowedUsers,
 		}
 	} else if keyType == KeyTypeDynamic {
@@ -547,6 +567,7 @@ func (b *backend) pathRoleRead(req *logical.Request, d *framework.FieldData) (*l
 				"exclude_cidr_list": role.ExcludeCIDRList,
 				"key_type":          role.KeyType,
 				"port":              role.Port,
+				"allowed_ports":     role.AllowedPorts,
 				"allowed_users":     role.AllowedUsers,
 			},
 		}, nil
@@ -84,6 +84,7 @@ func (b *backend) pathVerifyWrite(req *logical.Request, d *framework.FieldData)
 			"username":  otpEntry.Username,
 			"ip":        otpEntry.IP,
 			"role_name": otpEntry.RoleName,
+			"port":      otpEntry.Port,
 		},
 	}, nil
 }
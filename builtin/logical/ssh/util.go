@@ -9,6 +9,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 
@@ -152,6 +153,38 @@ func cidrListContainsIP(ip, cidrList string) (bool, error) {
 	return false, nil
 }
 
+// Parses a comma separated list of port numbers
+func parsePorts(portList string) ([]int, error) {
+	var ports []int
+	for _, item := range strings.Split(portList, ",") {
+		item = strings.TrimSpace(item)
+		port, err := strconv.Atoi(item)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q", item)
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// Returns an error if the port requested by the user is not part of the
+// comma separated list of allowed ports
+func validatePort(port int, allowedPorts string) error {
+	if len(allowedPorts) == 0 {
+		return fmt.Errorf("port %d is not allowed by role", port)
+	}
+	ports, err := parsePorts(allowedPorts)
+	if err != nil {
+		return err
+	}
+	for _, allowed := range ports {
+		if allowed == port {
+			return nil
+		}
+	}
+	return fmt.Errorf("port %d is not allowed by role", port)
+}
+
 func createSSHComm(logger log.Logger, username, ip string, port int, hostkey string) (*comm, error) {
 	signer, err := ssh.ParsePrivateKey([]byte(hostkey))
 	if err != nil {
@@ -2,8 +2,11 @@ package transit
 
 import (
 	"strings"
+	"sync"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/helper/keysutil"
+	"github.com/hashicorp/vault/helper/locksutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -28,6 +31,8 @@ func Backend(conf *logical.BackendConfig) *backend {
 			b.pathKeys(),
 			b.pathListKeys(),
 			b.pathExportKeys(),
+			b.pathBackup(),
+			b.pathRestore(),
 			b.pathEncrypt(),
 			b.pathDecrypt(),
 			b.pathDatakey(),
@@ -36,14 +41,18 @@ func Backend(conf *logical.BackendConfig) *backend {
 			b.pathHMAC(),
 			b.pathSign(),
 			b.pathVerify(),
+			b.pathUsage(),
 		},
 
-		Secrets:     []*framework.Secret{},
-		Invalidate:  b.invalidate,
-		BackendType: logical.TypeLogical,
+		Secrets:      []*framework.Secret{},
+		PeriodicFunc: b.periodicFunc,
+		Invalidate:   b.invalidate,
+		BackendType:  logical.TypeLogical,
 	}
 
 	b.lm = keysutil.NewLockManager(conf.System.CachingDisabled())
+	b.usageLocks = locksutil.CreateLocks()
+	b.usageConfigs = make(map[string]*usageConfig)
 
 	return &b
 }
@@ -51,6 +60,23 @@ func Backend(conf *logical.BackendConfig) *backend {
 type backend struct {
 	*framework.Backend
 	lm *keysutil.LockManager
+
+	usageLocks       []*locksutil.LockEntry
+	usageConfigsLock sync.RWMutex
+	usageConfigs     map[string]*usageConfig
+}
+
+// periodicFunc rotates the keys due for automatic rotation and prunes the
+// usage ledgers
+func (b *backend) periodicFunc(req *logical.Request) error {
+	var result error
+	if err := b.autoRotateKeys(req); err != nil {
+		result = multierror.Append(result, err)
+	}
+	if err := b.pruneUsageLogs(req); err != nil {
+		result = multierror.Append(result, err)
+	}
+	return result
 }
 
 func (b *backend) invalidate(key string) {
@@ -61,5 +87,8 @@ func (b *backend) invalidate(key string) {
 	case strings.HasPrefix(key, "policy/"):
 		name := strings.TrimPrefix(key, "policy/")
 		b.lm.InvalidatePolicy(name)
+		b.evictUsageConfig(name)
+	case strings.HasPrefix(key, usageConfigPrefix):
+		b.evictUsageConfig(strings.TrimPrefix(key, usageConfigPrefix))
 	}
 }
@@ -0,0 +1,118 @@
+package transit
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestTransit_BackupRestore(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	doReq := func(b *backend, storage logical.Storage, op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(&logical.Request{
+			Storage:   storage,
+			Operation: op,
+			Path:      path,
+			Data:      data,
+		})
+	}
+
+	if _, err := doReq(b, storage, logical.UpdateOperation, "keys/foo", nil); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := doReq(b, storage, logical.UpdateOperation, "encrypt/foo", map[string]interface{}{
+		"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	ciphertext := resp.Data["ciphertext"].(string)
+
+	// Rotate and drop the first version from the policy so that it only lives
+	// in the archive
+	if _, err := doReq(b, storage, logical.UpdateOperation, "keys/foo/rotate", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doReq(b, storage, logical.UpdateOperation, "keys/foo/config", map[string]interface{}{
+		"min_decryption_version": 2,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Backups require both flags
+	resp, err = doReq(b, storage, logical.ReadOperation, "backup/foo", nil)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: resp: %#v", resp)
+	}
+	if _, err := doReq(b, storage, logical.UpdateOperation, "keys/foo/config", map[string]interface{}{
+		"allow_plaintext_backup": true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = doReq(b, storage, logical.ReadOperation, "backup/foo", nil)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: resp: %#v", resp)
+	}
+	if _, err := doReq(b, storage, logical.UpdateOperation, "keys/foo/config", map[string]interface{}{
+		"exportable":             true,
+		"min_decryption_version": 1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The flags cannot be disabled
+	resp, err = doReq(b, storage, logical.UpdateOperation, "keys/foo/config", map[string]interface{}{
+		"allow_plaintext_backup": false,
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err: %v resp: %#v", err, resp)
+	}
+
+	resp, err = doReq(b, storage, logical.ReadOperation, "backup/foo", nil)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	backup := resp.Data["backup"].(string)
+
+	// Restore into another backend, under the original name and a new one
+	b2, storage2 := createBackendWithStorage(t)
+	if _, err := doReq(b2, storage2, logical.UpdateOperation, "restore", map[string]interface{}{
+		"backup": backup,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doReq(b2, storage2, logical.UpdateOperation, "restore/bar", map[string]interface{}{
+		"backup": backup,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"foo", "bar"} {
+		resp, err = doReq(b2, storage2, logical.UpdateOperation, "decrypt/"+name, map[string]interface{}{
+			"ciphertext": ciphertext,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("bad: err: %v resp: %#v", err, resp)
+		}
+		if resp.Data["plaintext"] != "dGhlIHF1aWNrIGJyb3duIGZveA==" {
+			t.Fatalf("bad: %#v", resp.Data)
+		}
+
+		resp, err = doReq(b2, storage2, logical.ReadOperation, "keys/"+name, nil)
+		if err != nil || resp == nil {
+			t.Fatalf("bad: err: %v resp: %#v", err, resp)
+		}
+		if resp.Data["latest_version"] != 2 || resp.Data["name"] != name {
+			t.Fatalf("bad: %#v", resp.Data)
+		}
+	}
+
+	// Existing keys are never overwritten
+	resp, err = doReq(b2, storage2, logical.UpdateOperation, "restore", map[string]interface{}{
+		"backup": backup,
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: resp: %#v", resp)
+	}
+}
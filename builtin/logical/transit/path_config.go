@@ -2,6 +2,7 @@ package transit
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -35,6 +36,46 @@ the latest version of the key is allowed.`,
 				Type:        framework.TypeBool,
 				Description: "Whether to allow deletion of the key",
 			},
+
+			"exportable": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables export of the key. Once set, this cannot
+be disabled.`,
+			},
+
+			"allow_plaintext_backup": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables taking a backup of the key in plaintext,
+which also requires the key to be exportable.
+Once set, this cannot be disabled.`,
+			},
+
+			"auto_rotate_period": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `The period after which the key is rotated
+automatically. Must be at least one hour; 0 disables
+automatic rotation.`,
+			},
+
+			"usage_log": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Whether to record each use of the key in its
+usage ledger, readable at keys/<name>/usage.`,
+			},
+
+			"usage_log_max_entries": &framework.FieldSchema{
+				Type: framework.TypeInt,
+				Description: `The maximum number of entries retained in the
+usage ledger; older entries are discarded first.
+Defaults to 1000.`,
+			},
+
+			"usage_log_max_age": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `The maximum age of entries retained in the usage
+ledger. If set to zero, entries are only bounded
+by usage_log_max_entries.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -126,6 +167,50 @@ func (b *backend) pathConfigWrite(
 		}
 	}
 
+	// Keys that were exported or backed up may exist elsewhere, so these can
+	// never be disabled again
+	exportableRaw, ok := d.GetOk("exportable")
+	if ok {
+		exportable := exportableRaw.(bool)
+		if !exportable && p.Exportable {
+			return logical.ErrorResponse("exportable can only be enabled, not disabled"), nil
+		}
+		if exportable && !p.Exportable {
+			p.Exportable = true
+			persistNeeded = true
+		}
+	}
+
+	allowPlaintextBackupRaw, ok := d.GetOk("allow_plaintext_backup")
+	if ok {
+		allowPlaintextBackup := allowPlaintextBackupRaw.(bool)
+		if !allowPlaintextBackup && p.AllowPlaintextBackup {
+			return logical.ErrorResponse("allow_plaintext_backup can only be enabled, not disabled"), nil
+		}
+		if allowPlaintextBackup && !p.AllowPlaintextBackup {
+			p.AllowPlaintextBackup = true
+			persistNeeded = true
+		}
+	}
+
+	autoRotatePeriodRaw, ok := d.GetOk("auto_rotate_period")
+	if ok {
+		autoRotatePeriod := time.Duration(autoRotatePeriodRaw.(int)) * time.Second
+		if autoRotatePeriod != 0 && autoRotatePeriod < minAutoRotatePeriod {
+			return logical.ErrorResponse(
+				fmt.Sprintf("auto rotate period must be 0 or at least %s", minAutoRotatePeriod)), nil
+		}
+		if autoRotatePeriod != p.AutoRotatePeriod {
+			p.AutoRotatePeriod = autoRotatePeriod
+			persistNeeded = true
+		}
+	}
+
+	usageResp, err := b.updateUsageConfig(req.Storage, name, d)
+	if err != nil || usageResp != nil {
+		return usageResp, err
+	}
+
 	// Add this as a guard here before persisting since we now require the min
 	// decryption version to start at 1; even if it's not explicitly set here,
 	// force the upgrade
@@ -145,10 +230,60 @@ func (b *backend) pathConfigWrite(
 	return resp, p.Persist(req.Storage)
 }
 
+// updateUsageConfig applies any usage ledger settings present in the request.
+// A non-nil response indicates the settings were invalid.
+func (b *backend) updateUsageConfig(s logical.Storage, name string, d *framework.FieldData) (*logical.Response, error) {
+	lock := b.usageLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	current, err := b.getUsageConfig(s, name)
+	if err != nil {
+		return nil, err
+	}
+	config := *current
+
+	if enabledRaw, ok := d.GetOk("usage_log"); ok {
+		config.Enabled = enabledRaw.(bool)
+	}
+
+	if maxEntriesRaw, ok := d.GetOk("usage_log_max_entries"); ok {
+		maxEntries := maxEntriesRaw.(int)
+		switch {
+		case maxEntries < 0:
+			return logical.ErrorResponse("usage log max entries cannot be negative"), nil
+		case maxEntries > maxUsageLogMaxEntries:
+			return logical.ErrorResponse(
+				fmt.Sprintf("usage log max entries cannot be greater than %d", maxUsageLogMaxEntries)), nil
+		case maxEntries == 0:
+			maxEntries = defaultUsageLogMaxEntries
+		}
+		config.MaxEntries = maxEntries
+	}
+
+	if maxAgeRaw, ok := d.GetOk("usage_log_max_age"); ok {
+		maxAge := maxAgeRaw.(int)
+		if maxAge < 0 {
+			return logical.ErrorResponse("usage log max age cannot be negative"), nil
+		}
+		config.MaxAge = time.Duration(maxAge) * time.Second
+	}
+
+	if config == *current {
+		return nil, nil
+	}
+
+	return nil, b.setUsageConfig(s, name, &config)
+}
+
 const pathConfigHelpSyn = `Configure a named encryption key`
 
 const pathConfigHelpDesc = `
 This path is used to configure the named key. Currently, this
 supports adjusting the minimum version of the key allowed to
-be used for decryption via the min_decryption_version paramter.
+be used for decryption via the min_decryption_version paramter,
+enabling export and plaintext backups of the key via the
+exportable and allow_plaintext_backup parameters, rotating the
+key automatically via the auto_rotate_period parameter, and enabling
+the per-key usage ledger via the usage_log parameter.
 `
@@ -141,6 +141,10 @@ func (b *backend) pathDatakeyWrite(
 		return nil, fmt.Errorf("empty ciphertext returned")
 	}
 
+	if err := b.recordUsage(req, name, "datakey", 1); err != nil {
+		return nil, err
+	}
+
 	// Generate the response
 	resp := &logical.Response{
 		Data: map[string]interface{}{
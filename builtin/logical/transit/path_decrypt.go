@@ -140,6 +140,10 @@ func (b *backend) pathDecryptWrite(
 		batchResponseItems[i].Plaintext = plaintext
 	}
 
+	if err := b.recordUsage(req, d.Get("name").(string), "decrypt", len(batchInputItems)); err != nil {
+		return nil, err
+	}
+
 	resp := &logical.Response{}
 	if batchInputRaw != nil {
 		resp.Data = map[string]interface{}{
@@ -273,6 +273,10 @@ func (b *backend) pathEncryptWrite(
 		batchResponseItems[i].Ciphertext = ciphertext
 	}
 
+	if err := b.recordUsage(req, name, "encrypt", len(batchInputItems)); err != nil {
+		return nil, err
+	}
+
 	resp := &logical.Response{}
 	if batchInputRaw != nil {
 		resp.Data = map[string]interface{}{
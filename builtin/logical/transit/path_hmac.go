@@ -128,6 +128,10 @@ func (b *backend) pathHMACWrite(
 	retStr := base64.StdEncoding.EncodeToString(retBytes)
 	retStr = fmt.Sprintf("vault:v%s:%s", strconv.Itoa(ver), retStr)
 
+	if err := b.recordUsage(req, name, "hmac", 1); err != nil {
+		return nil, err
+	}
+
 	// Generate the response
 	resp := &logical.Response{
 		Data: map[string]interface{}{
@@ -216,6 +220,10 @@ func (b *backend) pathHMACVerify(
 	hf.Write(input)
 	retBytes := hf.Sum(nil)
 
+	if err := b.recordUsage(req, name, "verify", 1); err != nil {
+		return nil, err
+	}
+
 	return &logical.Response{
 		Data: map[string]interface{}{
 			"valid": hmac.Equal(retBytes, verBytes),
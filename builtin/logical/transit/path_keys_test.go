@@ -24,7 +24,7 @@ func TestTransit_Issue_2958(t *testing.T) {
 
 	cluster := vault.NewTestCluster(t, coreConfig, true)
 	cluster.StartListeners()
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 
 	cores := cluster.Cores
 
@@ -77,3 +77,56 @@ func TestTransit_Issue_2958(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestTransit_MountBackend_Forwarded(t *testing.T) {
+	coreConfig := &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"transit": transit.Factory,
+		},
+	}
+
+	cluster := vault.NewTestCluster(t, coreConfig, true)
+	cluster.StartListeners()
+	defer cluster.Cleanup()
+
+	cores := cluster.Cores
+
+	cores[0].Handler.Handle("/", vaulthttp.Handler(cores[0].Core))
+	cores[1].Handler.Handle("/", vaulthttp.Handler(cores[1].Core))
+	cores[2].Handler.Handle("/", vaulthttp.Handler(cores[2].Core))
+
+	vault.TestWaitActive(t, cores[0].Core)
+
+	cores[0].MountBackend(t, "transit", "transit", map[string]interface{}{
+		"default_lease_ttl": "1h",
+	})
+
+	// Keys written through a standby are forwarded to the active node
+	client := cores[1].Client
+	_, err := client.Logical().Write("transit/keys/foo", map[string]interface{}{
+		"type": "ecdsa-p256",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := cores[0].Client.Logical().Read("transit/keys/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["type"] != "ecdsa-p256" {
+		t.Fatalf("bad: %#v", secret)
+	}
+
+	mounts, err := client.Sys().ListMounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mount, ok := mounts["transit/"]
+	if !ok {
+		t.Fatalf("transit not mounted: %#v", mounts)
+	}
+	if mount.Type != "transit" || mount.Config.DefaultLeaseTTL != 3600 {
+		t.Fatalf("bad: %#v", mount)
+	}
+}
@@ -0,0 +1,53 @@
+package transit
+
+import (
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func (b *backend) pathRestore() *framework.Path {
+	return &framework.Path{
+		Pattern: "restore" + framework.OptionalParamRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"backup": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Backup of the key, as returned by the backup endpoint",
+			},
+
+			"name": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `If set, the name to restore the key under instead
+of the name it was backed up with`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRestoreUpdate,
+		},
+
+		HelpSynopsis:    pathRestoreHelpSyn,
+		HelpDescription: pathRestoreHelpDesc,
+	}
+}
+
+func (b *backend) pathRestoreUpdate(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	backup := d.Get("backup").(string)
+	if backup == "" {
+		return logical.ErrorResponse("missing backup"), logical.ErrInvalidRequest
+	}
+
+	if err := b.lm.RestorePolicy(req.Storage, d.Get("name").(string), backup); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	return nil, nil
+}
+
+const pathRestoreHelpSyn = `Restore a backed up key`
+
+const pathRestoreHelpDesc = `
+This path is used to restore a key returned by the backup endpoint, along with
+all its versions, under the name it was backed up with or under the name given
+in the path. An existing key is never overwritten.
+`
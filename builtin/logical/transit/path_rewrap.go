@@ -160,6 +160,10 @@ func (b *backend) pathRewrapWrite(
 		batchResponseItems[i].Ciphertext = ciphertext
 	}
 
+	if err := b.recordUsage(req, d.Get("name").(string), "rewrap", len(batchInputItems)); err != nil {
+		return nil, err
+	}
+
 	resp := &logical.Response{}
 	if batchInputRaw != nil {
 		resp.Data = map[string]interface{}{
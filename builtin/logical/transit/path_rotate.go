@@ -1,10 +1,19 @@
 package transit
 
 import (
+	"fmt"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
 
+// minAutoRotatePeriod is the shortest auto_rotate_period that can be set on a
+// key, so that automatic rotation cannot grow a key's versions without bound
+const minAutoRotatePeriod = time.Hour
+
 func (b *backend) pathRotate() *framework.Path {
 	return &framework.Path{
 		Pattern: "keys/" + framework.GenericNameRegex("name") + "/rotate",
@@ -46,6 +55,46 @@ func (b *backend) pathRotateWrite(
 	return nil, err
 }
 
+// autoRotateKeys is the periodic function of the backend; it rotates the
+// keys whose latest version is older than their auto_rotate_period. A key
+// failing to rotate does not prevent the others from being rotated.
+func (b *backend) autoRotateKeys(req *logical.Request) error {
+	names, err := req.Storage.List("policy/")
+	if err != nil {
+		return err
+	}
+
+	var result error
+	for _, name := range names {
+		if err := b.autoRotateKey(req, name); err != nil {
+			result = multierror.Append(result, fmt.Errorf("error rotating key %q: %v", name, err))
+		}
+	}
+	return result
+}
+
+func (b *backend) autoRotateKey(req *logical.Request, name string) error {
+	p, lock, err := b.lm.GetPolicyExclusive(req.Storage, name)
+	if lock != nil {
+		defer lock.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+	if p == nil || !p.NeedsAutoRotation(time.Now()) {
+		return nil
+	}
+
+	if err := p.Rotate(req.Storage); err != nil {
+		metrics.IncrCounter([]string{"transit", "auto_rotate", "error"}, 1)
+		return err
+	}
+	metrics.IncrCounter([]string{"transit", "auto_rotate"}, 1)
+	b.Logger().Info("transit: rotated key", "key", name, "version", p.LatestVersion)
+
+	return b.recordUsage(req, name, "auto-rotate", 1)
+}
+
 const pathRotateHelpSyn = `Rotate named encryption key`
 
 const pathRotateHelpDesc = `
@@ -0,0 +1,101 @@
+package transit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestTransit_AutoRotate(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(&logical.Request{
+			Storage:   storage,
+			Operation: op,
+			Path:      path,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: err: %v resp: %#v", err, resp)
+		}
+		return resp
+	}
+
+	// ageLatestVersion makes the latest version of the key look older than
+	// the rotation period
+	ageLatestVersion := func() {
+		p, lock, err := b.lm.GetPolicyExclusive(storage, "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer lock.Unlock()
+		entry := p.Keys[p.LatestVersion]
+		entry.CreationTime = entry.CreationTime.Add(-2 * time.Hour)
+		p.Keys[p.LatestVersion] = entry
+		if err := p.Persist(storage); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	latestVersion := func() int {
+		return doReq(logical.ReadOperation, "keys/foo", nil).Data["latest_version"].(int)
+	}
+
+	doReq(logical.UpdateOperation, "keys/foo", nil)
+
+	// Periods shorter than the minimum are rejected
+	resp, err := b.HandleRequest(&logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo/config",
+		Data: map[string]interface{}{
+			"auto_rotate_period": "30m",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: err: %v resp: %#v", err, resp)
+	}
+
+	doReq(logical.UpdateOperation, "keys/foo/config", map[string]interface{}{
+		"auto_rotate_period": "1h",
+		"usage_log":          true,
+	})
+	if period := doReq(logical.ReadOperation, "keys/foo", nil).Data["auto_rotate_period"]; period != int64(3600) {
+		t.Fatalf("bad: auto_rotate_period: %#v", period)
+	}
+
+	// Keys are only rotated once their latest version is old enough
+	if err := b.autoRotateKeys(&logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	if v := latestVersion(); v != 1 {
+		t.Fatalf("bad: latest_version: %d", v)
+	}
+	ageLatestVersion()
+	if err := b.autoRotateKeys(&logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	if v := latestVersion(); v != 2 {
+		t.Fatalf("bad: latest_version: %d", v)
+	}
+
+	// The rotation is recorded in the usage ledger
+	entries := doReq(logical.ReadOperation, "keys/foo/usage", nil).Data["entries"].([]map[string]interface{})
+	if len(entries) != 1 || entries[0]["operation"] != "auto-rotate" {
+		t.Fatalf("bad: %#v", entries)
+	}
+
+	// Disabling automatic rotation stops it
+	doReq(logical.UpdateOperation, "keys/foo/config", map[string]interface{}{
+		"auto_rotate_period": 0,
+	})
+	ageLatestVersion()
+	if err := b.autoRotateKeys(&logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	if v := latestVersion(); v != 2 {
+		t.Fatalf("bad: latest_version: %d", v)
+	}
+}
@@ -192,6 +192,10 @@ func (b *backend) pathSignWrite(
 		return nil, fmt.Errorf("signature could not be computed")
 	}
 
+	if err := b.recordUsage(req, name, "sign", 1); err != nil {
+		return nil, err
+	}
+
 	// Generate the response
 	resp := &logical.Response{
 		Data: map[string]interface{}{
@@ -289,6 +293,10 @@ func (b *backend) pathVerifyWrite(
 		}
 	}
 
+	if err := b.recordUsage(req, name, "verify", 1); err != nil {
+		return nil, err
+	}
+
 	// Generate the response
 	resp := &logical.Response{
 		Data: map[string]interface{}{
@@ -0,0 +1,351 @@
+package transit
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	usageConfigPrefix = "usage/config/"
+	usageLogPrefix    = "usage/log/"
+
+	// defaultUsageLogMaxEntries is the number of ledger entries retained for
+	// a key when no explicit limit has been configured
+	defaultUsageLogMaxEntries = 1000
+
+	// maxUsageLogMaxEntries bounds the number of entries read when a key's
+	// ledger is exported
+	maxUsageLogMaxEntries = 10000
+)
+
+// usageConfig holds the usage ledger settings for a single named key
+type usageConfig struct {
+	Enabled    bool          `json:"enabled" structs:"enabled" mapstructure:"enabled"`
+	MaxEntries int           `json:"max_entries" structs:"max_entries" mapstructure:"max_entries"`
+	MaxAge     time.Duration `json:"max_age" structs:"max_age" mapstructure:"max_age"`
+}
+
+// usageEntry records a single use of a named key
+type usageEntry struct {
+	Time                time.Time `json:"time" structs:"time" mapstructure:"time"`
+	Operation           string    `json:"operation" structs:"operation" mapstructure:"operation"`
+	RequestID           string    `json:"request_id" structs:"request_id" mapstructure:"request_id"`
+	DisplayName         string    `json:"display_name" structs:"display_name" mapstructure:"display_name"`
+	ClientTokenAccessor string    `json:"client_token_accessor" structs:"client_token_accessor" mapstructure:"client_token_accessor"`
+	Items               int       `json:"items" structs:"items" mapstructure:"items"`
+}
+
+func (b *backend) pathUsage() *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/usage",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathUsageRead,
+			logical.DeleteOperation: b.pathUsageDelete,
+		},
+
+		HelpSynopsis:    pathUsageHelpSyn,
+		HelpDescription: pathUsageHelpDesc,
+	}
+}
+
+func (b *backend) pathUsageRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	lock := b.usageLock(name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	config, err := b.getUsageConfig(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := b.listUsageLog(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries beyond the retention limits may not have been removed by the
+	// periodic function yet
+	keys = keys[len(expiredUsageLogKeys(keys, config, time.Now().UTC())):]
+
+	ret := make([]map[string]interface{}, 0, len(keys))
+	for _, key := range keys {
+		entry, err := req.Storage.Get(usageLogPrefix + name + "/" + key)
+		if err != nil {
+			return nil, fmt.Errorf("error reading usage log: %v", err)
+		}
+		if entry == nil {
+			continue
+		}
+
+		var usage usageEntry
+		if err := entry.DecodeJSON(&usage); err != nil {
+			return nil, fmt.Errorf("error decoding usage log: %v", err)
+		}
+		ret = append(ret, map[string]interface{}{
+			"time":                  usage.Time.Format(time.RFC3339Nano),
+			"operation":             usage.Operation,
+			"request_id":            usage.RequestID,
+			"display_name":          usage.DisplayName,
+			"client_token_accessor": usage.ClientTokenAccessor,
+			"items":                 usage.Items,
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":     config.Enabled,
+			"max_entries": config.MaxEntries,
+			"max_age":     int64(config.MaxAge.Seconds()),
+			"entries":     ret,
+		},
+	}, nil
+}
+
+func (b *backend) pathUsageDelete(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	lock := b.usageLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return nil, b.clearUsageLog(req.Storage, name)
+}
+
+// usageLock returns the lock guarding the usage ledger and configuration of
+// the named key
+func (b *backend) usageLock(name string) *locksutil.LockEntry {
+	return locksutil.LockForKey(b.usageLocks, name)
+}
+
+// getUsageConfig returns the usage ledger configuration for the named key.
+// Stored configurations are cached since they are consulted on every use of
+// a key. It must be called with the key's usage lock held.
+func (b *backend) getUsageConfig(s logical.Storage, name string) (*usageConfig, error) {
+	b.usageConfigsLock.RLock()
+	config, ok := b.usageConfigs[name]
+	b.usageConfigsLock.RUnlock()
+	if ok {
+		return config, nil
+	}
+
+	entry, err := s.Get(usageConfigPrefix + name)
+	if err != nil {
+		return nil, fmt.Errorf("error reading usage configuration: %v", err)
+	}
+	if entry == nil {
+		// Misses are not cached, so that requests for arbitrary names
+		// cannot grow the cache
+		return &usageConfig{
+			MaxEntries: defaultUsageLogMaxEntries,
+		}, nil
+	}
+
+	config = &usageConfig{}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, fmt.Errorf("error decoding usage configuration: %v", err)
+	}
+
+	b.usageConfigsLock.Lock()
+	b.usageConfigs[name] = config
+	b.usageConfigsLock.Unlock()
+	return config, nil
+}
+
+// setUsageConfig must be called with the key's usage lock held
+func (b *backend) setUsageConfig(s logical.Storage, name string, config *usageConfig) error {
+	entry, err := logical.StorageEntryJSON(usageConfigPrefix+name, config)
+	if err != nil {
+		return err
+	}
+	if err := s.Put(entry); err != nil {
+		return err
+	}
+
+	b.usageConfigsLock.Lock()
+	b.usageConfigs[name] = config
+	b.usageConfigsLock.Unlock()
+	return nil
+}
+
+// evictUsageConfig drops the cached usage configuration of the named key
+func (b *backend) evictUsageConfig(name string) {
+	b.usageConfigsLock.Lock()
+	delete(b.usageConfigs, name)
+	b.usageConfigsLock.Unlock()
+}
+
+// deleteUsage removes both the ledger and its configuration for the named
+// key; it is called when the key itself is deleted.
+func (b *backend) deleteUsage(s logical.Storage, name string) error {
+	lock := b.usageLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	b.evictUsageConfig(name)
+	if err := s.Delete(usageConfigPrefix + name); err != nil {
+		return err
+	}
+	return b.clearUsageLog(s, name)
+}
+
+// recordUsage appends an entry to the named key's ledger if the ledger is
+// enabled. Failing to record is treated as a failure of the request so that
+// no use of a key goes unrecorded.
+//
+// Each use is written as its own storage entry, so recording never reads
+// the ledger and concurrent uses of a key do not wait on each other.
+func (b *backend) recordUsage(req *logical.Request, name, operation string, items int) error {
+	lock := b.usageLock(name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	config, err := b.getUsageConfig(req.Storage, name)
+	if err != nil {
+		return err
+	}
+	if !config.Enabled {
+		return nil
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	entry, err := logical.StorageEntryJSON(usageLogPrefix+name+"/"+usageLogKey(now, id), &usageEntry{
+		Time:                now,
+		Operation:           operation,
+		RequestID:           req.ID,
+		DisplayName:         req.DisplayName,
+		ClientTokenAccessor: req.ClientTokenAccessor,
+		Items:               items,
+	})
+	if err != nil {
+		return err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return fmt.Errorf("error recording key usage: %v", err)
+	}
+
+	return nil
+}
+
+// pruneUsageLogs removes the ledger entries that fall outside of the
+// retention limits of their key. It is run by the periodic function of the
+// backend.
+func (b *backend) pruneUsageLogs(req *logical.Request) error {
+	names, err := req.Storage.List(usageConfigPrefix)
+	if err != nil {
+		return err
+	}
+
+	var result error
+	for _, name := range names {
+		if err := b.pruneUsageLog(req.Storage, name); err != nil {
+			result = multierror.Append(result, fmt.Errorf("error pruning usage log of key %q: %v", name, err))
+		}
+	}
+	return result
+}
+
+func (b *backend) pruneUsageLog(s logical.Storage, name string) error {
+	lock := b.usageLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	config, err := b.getUsageConfig(s, name)
+	if err != nil {
+		return err
+	}
+	keys, err := b.listUsageLog(s, name)
+	if err != nil {
+		return err
+	}
+	for _, key := range expiredUsageLogKeys(keys, config, time.Now().UTC()) {
+		if err := s.Delete(usageLogPrefix + name + "/" + key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clearUsageLog must be called with the key's usage lock held
+func (b *backend) clearUsageLog(s logical.Storage, name string) error {
+	keys, err := b.listUsageLog(s, name)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.Delete(usageLogPrefix + name + "/" + key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listUsageLog returns the storage keys of the named key's ledger entries,
+// oldest first
+func (b *backend) listUsageLog(s logical.Storage, name string) ([]string, error) {
+	keys, err := s.List(usageLogPrefix + name + "/")
+	if err != nil {
+		return nil, fmt.Errorf("error listing usage log: %v", err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// usageLogKey returns the storage key of a ledger entry. Keys start with the
+// zero-padded time of the entry so that they sort in the order the entries
+// were recorded.
+func usageLogKey(t time.Time, id string) string {
+	return fmt.Sprintf("%020d-%s", t.UnixNano(), id)
+}
+
+// expiredUsageLogKeys returns the oldest of the sorted ledger keys that fall
+// outside of the configured retention limits.
+func expiredUsageLogKeys(keys []string, config *usageConfig, now time.Time) []string {
+	i := 0
+	if config.MaxAge > 0 {
+		cutoff := fmt.Sprintf("%020d", now.Add(-config.MaxAge).UnixNano())
+		for i < len(keys) && keys[i] < cutoff {
+			i++
+		}
+	}
+
+	if config.MaxEntries > 0 && len(keys)-i > config.MaxEntries {
+		i = len(keys) - config.MaxEntries
+	}
+
+	return keys[:i]
+}
+
+const pathUsageHelpSyn = `Export or clear the usage ledger of a named key`
+
+const pathUsageHelpDesc = `
+When the usage ledger is enabled for a key via its config endpoint, every
+use of the key (encrypt, decrypt, rewrap, datakey, sign, verify and hmac)
+is recorded along with the time, the request ID and the identity of the
+caller. Reading this path exports the retained entries, oldest first;
+deleting it clears them.
+
+Retention is bounded by the usage_log_max_entries and usage_log_max_age
+values set on the key's config endpoint.
+`
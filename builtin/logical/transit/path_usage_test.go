@@ -0,0 +1,207 @@
+package transit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestTransit_UsageLog(t *testing.T) {
+	var b *backend
+	sysView := logical.TestSystemView()
+	storage := &logical.InmemStorage{}
+
+	b = Backend(&logical.BackendConfig{
+		StorageView: storage,
+		System:      sysView,
+	})
+
+	doReq := func(req *logical.Request) *logical.Response {
+		resp, err := b.HandleRequest(req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: err: %v\nresp: %#v\nreq: %#v", err, resp, *req)
+		}
+		return resp
+	}
+
+	readEntries := func() []map[string]interface{} {
+		resp := doReq(&logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "keys/foo/usage",
+		})
+		return resp.Data["entries"].([]map[string]interface{})
+	}
+
+	encryptReq := &logical.Request{
+		ID:                  "req-1",
+		Storage:             storage,
+		Operation:           logical.UpdateOperation,
+		Path:                "encrypt/foo",
+		DisplayName:         "token-app",
+		ClientTokenAccessor: "accessor-1",
+		Data: map[string]interface{}{
+			"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+		},
+	}
+
+	doReq(&logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo",
+	})
+
+	// Nothing should be recorded until the ledger is enabled
+	doReq(encryptReq)
+	if entries := readEntries(); len(entries) != 0 {
+		t.Fatalf("expected no entries, got %#v", entries)
+	}
+
+	doReq(&logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo/config",
+		Data: map[string]interface{}{
+			"usage_log":             true,
+			"usage_log_max_entries": 2,
+		},
+	})
+
+	resp := doReq(encryptReq)
+	ciphertext := resp.Data["ciphertext"].(string)
+
+	entries := readEntries()
+	if len(entries) != 1 {
+		t.Fatalf("expected one entry, got %#v", entries)
+	}
+	if entries[0]["operation"] != "encrypt" ||
+		entries[0]["request_id"] != "req-1" ||
+		entries[0]["display_name"] != "token-app" ||
+		entries[0]["client_token_accessor"] != "accessor-1" ||
+		entries[0]["items"] != 1 {
+		t.Fatalf("bad entry: %#v", entries[0])
+	}
+
+	// Retention should drop the oldest entries
+	doReq(&logical.Request{
+		ID:        "req-2",
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "decrypt/foo",
+		Data: map[string]interface{}{
+			"ciphertext": ciphertext,
+		},
+	})
+	doReq(&logical.Request{
+		ID:        "req-3",
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "hmac/foo",
+		Data: map[string]interface{}{
+			"input": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+		},
+	})
+
+	entries = readEntries()
+	if len(entries) != 2 {
+		t.Fatalf("expected two entries, got %#v", entries)
+	}
+	if entries[0]["operation"] != "decrypt" || entries[1]["operation"] != "hmac" {
+		t.Fatalf("bad entries: %#v", entries)
+	}
+
+	// The periodic function removes the entries beyond the retention limits
+	if keys, err := storage.List(usageLogPrefix + "foo/"); err != nil || len(keys) != 3 {
+		t.Fatalf("expected three stored entries, got %v, err: %v", keys, err)
+	}
+	if err := b.periodicFunc(&logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	if keys, err := storage.List(usageLogPrefix + "foo/"); err != nil || len(keys) != 2 {
+		t.Fatalf("expected two stored entries, got %v, err: %v", keys, err)
+	}
+	if entries := readEntries(); len(entries) != 2 || entries[0]["operation"] != "decrypt" {
+		t.Fatalf("bad entries: %#v", entries)
+	}
+
+	// Clearing the ledger keeps it enabled
+	doReq(&logical.Request{
+		Storage:   storage,
+		Operation: logical.DeleteOperation,
+		Path:      "keys/foo/usage",
+	})
+	if entries := readEntries(); len(entries) != 0 {
+		t.Fatalf("expected no entries, got %#v", entries)
+	}
+	doReq(encryptReq)
+	if entries := readEntries(); len(entries) != 1 {
+		t.Fatalf("expected one entry, got %#v", entries)
+	}
+
+	// Invalid settings should be rejected
+	resp, err := b.HandleRequest(&logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo/config",
+		Data: map[string]interface{}{
+			"usage_log_max_entries": maxUsageLogMaxEntries + 1,
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error response, got resp: %#v, err: %v", resp, err)
+	}
+
+	// Only stored configurations are cached, and they are evicted along
+	// with their key
+	doReq(&logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/bar/usage",
+	})
+	if _, ok := b.usageConfigs["bar"]; ok {
+		t.Fatalf("expected the configuration of a missing key not to be cached")
+	}
+	if _, ok := b.usageConfigs["foo"]; !ok {
+		t.Fatalf("expected the configuration of foo to be cached")
+	}
+	doReq(&logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo/config",
+		Data: map[string]interface{}{
+			"deletion_allowed": true,
+		},
+	})
+	doReq(&logical.Request{
+		Storage:   storage,
+		Operation: logical.DeleteOperation,
+		Path:      "keys/foo",
+	})
+	if _, ok := b.usageConfigs["foo"]; ok {
+		t.Fatalf("expected the configuration of foo to be evicted")
+	}
+	if keys, err := storage.List(usageLogPrefix + "foo/"); err != nil || len(keys) != 0 {
+		t.Fatalf("expected no stored entries, got %v, err: %v", keys, err)
+	}
+}
+
+func TestTransit_UsageLogPrune(t *testing.T) {
+	now := time.Now().UTC()
+	keys := []string{
+		usageLogKey(now.Add(-3*time.Hour), "a"),
+		usageLogKey(now.Add(-2*time.Hour), "b"),
+		usageLogKey(now.Add(-1*time.Hour), "c"),
+		usageLogKey(now, "d"),
+	}
+
+	expired := expiredUsageLogKeys(keys, &usageConfig{MaxAge: 90 * time.Minute}, now)
+	if len(expired) != 2 || expired[1] != keys[1] {
+		t.Fatalf("bad: %#v", expired)
+	}
+
+	expired = expiredUsageLogKeys(keys, &usageConfig{MaxEntries: 3}, now)
+	if len(expired) != 1 || expired[0] != keys[0] {
+		t.Fatalf("bad: %#v", expired)
+	}
+}
@@ -96,6 +96,6 @@ func testConfig(t *testing.T) (*logical.BackendConfig, func()) {
 	vault.TestAddTestPlugin(t, core.Core, "mock-plugin", "TestBackend_PluginMain")
 
 	return config, func() {
-		cluster.CloseListeners()
+		cluster.Cleanup()
 	}
 }
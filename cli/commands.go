@@ -11,17 +11,22 @@ import (
 	credAppId "github.com/hashicorp/vault/builtin/credential/app-id"
 	credAppRole "github.com/hashicorp/vault/builtin/credential/approle"
 	credAws "github.com/hashicorp/vault/builtin/credential/aws"
+	credAzure "github.com/hashicorp/vault/builtin/credential/azure"
 	credCert "github.com/hashicorp/vault/builtin/credential/cert"
+	credGcp "github.com/hashicorp/vault/builtin/credential/gcp"
 	credGitHub "github.com/hashicorp/vault/builtin/credential/github"
 	credLdap "github.com/hashicorp/vault/builtin/credential/ldap"
 	credOkta "github.com/hashicorp/vault/builtin/credential/okta"
 	credRadius "github.com/hashicorp/vault/builtin/credential/radius"
 	credUserpass "github.com/hashicorp/vault/builtin/credential/userpass"
 
+	"github.com/hashicorp/vault/builtin/logical/ad"
 	"github.com/hashicorp/vault/builtin/logical/aws"
+	"github.com/hashicorp/vault/builtin/logical/azure"
 	"github.com/hashicorp/vault/builtin/logical/cassandra"
 	"github.com/hashicorp/vault/builtin/logical/consul"
 	"github.com/hashicorp/vault/builtin/logical/database"
+	"github.com/hashicorp/vault/builtin/logical/gcp"
 	"github.com/hashicorp/vault/builtin/logical/mongodb"
 	"github.com/hashicorp/vault/builtin/logical/mssql"
 	"github.com/hashicorp/vault/builtin/logical/mysql"
@@ -74,6 +79,8 @@ func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 					"approle":  credAppRole.Factory,
 					"cert":     credCert.Factory,
 					"aws":      credAws.Factory,
+					"azure":    credAzure.Factory,
+					"gcp":      credGcp.Factory,
 					"app-id":   credAppId.Factory,
 					"github":   credGitHub.Factory,
 					"userpass": credUserpass.Factory,
@@ -83,6 +90,7 @@ func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 					"plugin":   plugin.Factory,
 				},
 				LogicalBackends: map[string]logical.Factory{
+					"ad":         ad.Factory,
 					"aws":        aws.Factory,
 					"consul":     consul.Factory,
 					"postgresql": postgresql.Factory,
@@ -96,6 +104,8 @@ func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 					"rabbitmq":   rabbitmq.Factory,
 					"database":   database.Factory,
 					"totp":       totp.Factory,
+					"gcp":        gcp.Factory,
+					"azure":      azure.Factory,
 					"plugin":     plugin.Factory,
 				},
 				ShutdownCh: command.MakeShutdownCh(),
@@ -125,6 +135,8 @@ func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 					"okta":     &credOkta.CLIHandler{},
 					"cert":     &credCert.CLIHandler{},
 					"aws":      &credAws.CLIHandler{},
+					"azure":    &credAzure.CLIHandler{},
+					"gcp":      &credGcp.CLIHandler{},
 					"radius":   &credUserpass.CLIHandler{DefaultMount: "radius"},
 				},
 			}, nil
@@ -29,6 +29,7 @@ type RekeyCommand struct {
 
 func (c *RekeyCommand) Run(args []string) int {
 	var init, cancel, status, delete, retrieve, backup, recoveryKey bool
+	var requireVerification, verify bool
 	var shares, threshold int
 	var nonce string
 	var pgpKeys pgpkeys.PubKeyFilesFlag
@@ -40,6 +41,8 @@ func (c *RekeyCommand) Run(args []string) int {
 	flags.BoolVar(&retrieve, "retrieve", false, "")
 	flags.BoolVar(&backup, "backup", false, "")
 	flags.BoolVar(&recoveryKey, "recovery-key", c.RecoveryKey, "")
+	flags.BoolVar(&requireVerification, "require-verification", false, "")
+	flags.BoolVar(&verify, "verify", false, "")
 	flags.IntVar(&shares, "key-shares", 5, "")
 	flags.IntVar(&threshold, "key-threshold", 3, "")
 	flags.StringVar(&nonce, "nonce", "", "")
@@ -63,7 +66,7 @@ func (c *RekeyCommand) Run(args []string) int {
 	// Check if we are running doing any restricted variants
 	switch {
 	case init:
-		return c.initRekey(client, shares, threshold, pgpKeys, backup, recoveryKey)
+		return c.initRekey(client, shares, threshold, pgpKeys, backup, requireVerification, recoveryKey)
 	case cancel:
 		return c.cancelRekey(client, recoveryKey)
 	case status:
@@ -72,6 +75,12 @@ func (c *RekeyCommand) Run(args []string) int {
 		return c.rekeyRetrieveStored(client, recoveryKey)
 	case delete:
 		return c.rekeyDeleteStored(client, recoveryKey)
+	case verify:
+		if !recoveryKey {
+			c.Ui.Error("Verification is only supported when rekeying the recovery key")
+			return 1
+		}
+		return c.verifyRecoveryKey(client, flags.Args())
 	}
 
 	// Check if the rekey is started
@@ -90,17 +99,19 @@ func (c *RekeyCommand) Run(args []string) int {
 	if !rekeyStatus.Started {
 		if recoveryKey {
 			rekeyStatus, err = client.Sys().RekeyRecoveryKeyInit(&api.RekeyInitRequest{
-				SecretShares:    shares,
-				SecretThreshold: threshold,
-				PGPKeys:         pgpKeys,
-				Backup:          backup,
+				SecretShares:        shares,
+				SecretThreshold:     threshold,
+				PGPKeys:             pgpKeys,
+				Backup:              backup,
+				RequireVerification: requireVerification,
 			})
 		} else {
 			rekeyStatus, err = client.Sys().RekeyInit(&api.RekeyInitRequest{
-				SecretShares:    shares,
-				SecretThreshold: threshold,
-				PGPKeys:         pgpKeys,
-				Backup:          backup,
+				SecretShares:        shares,
+				SecretThreshold:     threshold,
+				PGPKeys:             pgpKeys,
+				Backup:              backup,
+				RequireVerification: requireVerification,
 			})
 		}
 		if err != nil {
@@ -191,6 +202,19 @@ func (c *RekeyCommand) Run(args []string) int {
 		))
 	}
 
+	if result.VerificationRequired {
+		c.Ui.Output(fmt.Sprintf(
+			"\n"+
+				"Verification nonce: %s\n\n"+
+				"The new recovery key does not take effect until %d of the above keys\n"+
+				"are provided with 'vault rekey -recovery-key -verify'. Until then the\n"+
+				"previous recovery keys remain valid.",
+			result.VerificationNonce,
+			threshold,
+		))
+		return 0
+	}
+
 	c.Ui.Output(fmt.Sprintf(
 		"\n"+
 			"Vault rekeyed with %d keys and a key threshold of %d. Please\n"+
@@ -212,13 +236,14 @@ func (c *RekeyCommand) Run(args []string) int {
 func (c *RekeyCommand) initRekey(client *api.Client,
 	shares, threshold int,
 	pgpKeys pgpkeys.PubKeyFilesFlag,
-	backup, recoveryKey bool) int {
+	backup, requireVerification, recoveryKey bool) int {
 	// Start the rekey
 	request := &api.RekeyInitRequest{
-		SecretShares:    shares,
-		SecretThreshold: threshold,
-		PGPKeys:         pgpKeys,
-		Backup:          backup,
+		SecretShares:        shares,
+		SecretThreshold:     threshold,
+		PGPKeys:             pgpKeys,
+		Backup:              backup,
+		RequireVerification: requireVerification,
 	}
 	var status *api.RekeyStatusResponse
 	var err error
@@ -256,6 +281,64 @@ be deleted at a later time with 'vault rekey -delete'.
 	return c.dumpRekeyStatus(status)
 }
 
+// verifyRecoveryKey is used to provide a key of the new recovery key to
+// the verification of a recovery key rekey
+func (c *RekeyCommand) verifyRecoveryKey(client *api.Client, args []string) int {
+	status, err := client.Sys().RekeyRecoveryKeyVerificationStatus()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading rekey verification status: %s", err))
+		return 1
+	}
+	if !status.Started {
+		c.Ui.Error("No rekey verification in progress")
+		return 1
+	}
+
+	nonce := c.Nonce
+	key := c.Key
+	if len(args) > 0 {
+		key = args[0]
+	}
+	if key == "" {
+		nonce = status.Nonce
+		fmt.Printf("Verification nonce: %s\n", status.Nonce)
+		fmt.Printf("Key (will be hidden): ")
+		key, err = password.Read(os.Stdin)
+		fmt.Printf("\n")
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error attempting to ask for password: %s", err))
+			return 1
+		}
+	}
+
+	result, err := client.Sys().RekeyRecoveryKeyVerificationUpdate(strings.TrimSpace(key), nonce)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error attempting rekey verification: %s", err))
+		return 1
+	}
+	if !result.Complete {
+		status, err = client.Sys().RekeyRecoveryKeyVerificationStatus()
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error reading rekey verification status: %s", err))
+			return 1
+		}
+		c.Ui.Output(fmt.Sprintf(
+			"Nonce: %s\n"+
+				"Key Shares: %d\n"+
+				"Key Threshold: %d\n"+
+				"Verification Progress: %d",
+			status.Nonce,
+			status.N,
+			status.T,
+			status.Progress,
+		))
+		return 0
+	}
+
+	c.Ui.Output("Rekey verified. The new recovery key is now in effect.")
+	return 0
+}
+
 // cancelRekey is used to abort the rekey process
 func (c *RekeyCommand) cancelRekey(client *api.Client, recovery bool) int {
 	var err error
@@ -415,6 +498,15 @@ Rekey Options:
 
   -recovery-key=false     Whether to rekey the recovery key instead of the
                           barrier key. Only used with Vault HSM.
+
+  -require-verification   Require a threshold of the new recovery keys to be
+                          provided back with '-verify' before the new recovery
+                          key takes effect. Only used with '-recovery-key'.
+
+  -verify                 Provide a key of the new recovery key to the
+                          verification of the rekey, with the verification
+                          nonce given as '-nonce' if the key is passed in on
+                          the command line.
 `
 	return strings.TrimSpace(helpText)
 }
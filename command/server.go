@@ -40,6 +40,9 @@ import (
 	"github.com/hashicorp/vault/meta"
 	"github.com/hashicorp/vault/physical"
 	"github.com/hashicorp/vault/vault"
+	"github.com/hashicorp/vault/vault/seal/azurekeyvault"
+	"github.com/hashicorp/vault/vault/seal/gcpckms"
+	"github.com/hashicorp/vault/vault/seal/transit"
 	"github.com/hashicorp/vault/version"
 )
 
@@ -65,7 +68,7 @@ type ServerCommand struct {
 }
 
 func (c *ServerCommand) Run(args []string) int {
-	var dev, verifyOnly, devHA, devTransactional, devLeasedGeneric bool
+	var dev, verifyOnly, devHA, devTransactional, devLeasedGeneric, devTLS bool
 	var configPath []string
 	var logLevel, devRootTokenID, devListenAddress string
 	flags := c.Meta.FlagSet("server", meta.FlagSetDefault)
@@ -77,6 +80,7 @@ func (c *ServerCommand) Run(args []string) int {
 	flags.BoolVar(&devHA, "dev-ha", false, "")
 	flags.BoolVar(&devTransactional, "dev-transactional", false, "")
 	flags.BoolVar(&devLeasedGeneric, "dev-leased-generic", false, "")
+	flags.BoolVar(&devTLS, "dev-tls", false, "")
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
 	flags.Var((*sliceflag.StringFlag)(&configPath), "config", "config")
 	if err := flags.Parse(args); err != nil {
@@ -86,22 +90,8 @@ func (c *ServerCommand) Run(args []string) int {
 	// Create a logger. We wrap it in a gated writer so that it doesn't
 	// start logging too early.
 	logGate := &gatedwriter.Writer{Writer: colorable.NewColorable(os.Stderr)}
-	var level int
-	logLevel = strings.ToLower(strings.TrimSpace(logLevel))
-	switch logLevel {
-	case "trace":
-		level = log.LevelTrace
-	case "debug":
-		level = log.LevelDebug
-	case "info":
-		level = log.LevelInfo
-	case "notice":
-		level = log.LevelNotice
-	case "warn":
-		level = log.LevelWarn
-	case "err":
-		level = log.LevelError
-	default:
+	level, err := logformat.ParseLevel(logLevel)
+	if err != nil {
 		c.Ui.Output(fmt.Sprintf("Unknown log level %s", logLevel))
 		return 1
 	}
@@ -110,9 +100,11 @@ func (c *ServerCommand) Run(args []string) int {
 	if logFormat == "" {
 		logFormat = os.Getenv("LOGXI_FORMAT")
 	}
+	// The log can only be streamed by sys/monitor in the Vault formats
+	var logMonitors *logformat.LogMonitors
 	switch strings.ToLower(logFormat) {
 	case "vault", "vault_json", "vault-json", "vaultjson", "json", "":
-		c.logger = logformat.NewVaultLoggerWithWriter(logGate, level)
+		c.logger, logMonitors = logformat.NewMonitoredVaultLogger(logGate, level)
 	default:
 		c.logger = log.NewLogger(logGate, "vault")
 		c.logger.SetLevel(level)
@@ -129,7 +121,7 @@ func (c *ServerCommand) Run(args []string) int {
 		devListenAddress = os.Getenv("VAULT_DEV_LISTEN_ADDRESS")
 	}
 
-	if devHA || devTransactional || devLeasedGeneric {
+	if devHA || devTransactional || devLeasedGeneric || devTLS {
 		dev = true
 	}
 
@@ -149,11 +141,33 @@ func (c *ServerCommand) Run(args []string) int {
 
 	// Load the configuration
 	var config *server.Config
+	var devTLSCACert string
+	devScheme := "http"
 	if dev {
 		config = server.DevConfig(devHA, devTransactional)
 		if devListenAddress != "" {
 			config.Listeners[0].Config["address"] = devListenAddress
 		}
+
+		// The CA and the certificate of the listener only live in memory;
+		// clients fetch the CA from sys/dev/ca
+		if devTLS {
+			host, _, err := net.SplitHostPort(config.Listeners[0].Config["address"].(string))
+			if err != nil {
+				c.Ui.Output(fmt.Sprintf("Error parsing dev listen address: %s", err))
+				return 1
+			}
+			devCerts, err := server.GenerateDevTLS([]string{host})
+			if err != nil {
+				c.Ui.Output(fmt.Sprintf("Error generating dev TLS certificates: %s", err))
+				return 1
+			}
+			delete(config.Listeners[0].Config, "tls_disable")
+			config.Listeners[0].Config["tls_cert_pem"] = devCerts.CertPEM
+			config.Listeners[0].Config["tls_key_pem"] = devCerts.KeyPEM
+			devTLSCACert = devCerts.CACertPEM
+			devScheme = "https"
+		}
 	}
 	for _, path := range configPath {
 		current, err := server.LoadConfig(path, c.logger)
@@ -210,6 +224,18 @@ func (c *ServerCommand) Run(args []string) int {
 	info := make(map[string]string)
 
 	var seal vault.Seal = &vault.DefaultSeal{}
+	if config.Seal != nil {
+		kms, err := newSealKMS(config.Seal, c.logger)
+		if err != nil {
+			c.Ui.Output(fmt.Sprintf(
+				"Error initializing seal of type %s: %s",
+				config.Seal.Type, err))
+			return 1
+		}
+		seal = vault.NewAutoSeal(kms)
+		info["seal"] = config.Seal.Type
+		infoKeys = append(infoKeys, "seal")
+	}
 
 	// Ensure that the seal finalizer is called, even if using verify-only
 	defer func() {
@@ -227,24 +253,39 @@ func (c *ServerCommand) Run(args []string) int {
 	}
 
 	coreConfig := &vault.CoreConfig{
-		Physical:           backend,
-		RedirectAddr:       config.Storage.RedirectAddr,
-		HAPhysical:         nil,
-		Seal:               seal,
-		AuditBackends:      c.AuditBackends,
-		CredentialBackends: c.CredentialBackends,
-		LogicalBackends:    c.LogicalBackends,
-		Logger:             c.logger,
-		DisableCache:       config.DisableCache,
-		DisableMlock:       config.DisableMlock,
-		MaxLeaseTTL:        config.MaxLeaseTTL,
-		DefaultLeaseTTL:    config.DefaultLeaseTTL,
-		ClusterName:        config.ClusterName,
-		CacheSize:          config.CacheSize,
-		PluginDirectory:    config.PluginDirectory,
+		Physical:                      backend,
+		RedirectAddr:                  config.Storage.RedirectAddr,
+		HAPhysical:                    nil,
+		Seal:                          seal,
+		AuditBackends:                 c.AuditBackends,
+		CredentialBackends:            c.CredentialBackends,
+		LogicalBackends:               c.LogicalBackends,
+		Logger:                        c.logger,
+		LogMonitors:                   logMonitors,
+		DisableCache:                  config.DisableCache,
+		DisableMlock:                  config.DisableMlock,
+		MaxLeaseTTL:                   config.MaxLeaseTTL,
+		DefaultLeaseTTL:               config.DefaultLeaseTTL,
+		ClusterName:                   config.ClusterName,
+		HALockGracePeriod:             config.HALockGracePeriod,
+		ClockSkewTolerance:            config.ClockSkewTolerance,
+		NTPServer:                     config.NTPServer,
+		CacheSize:                     config.CacheSize,
+		MemorySoftLimit:               uint64(config.MemorySoftLimit),
+		MemoryHardLimit:               uint64(config.MemoryHardLimit),
+		MaxConcurrentRequestsPerToken: config.MaxConcurrentRequestsPerToken,
+		MaxConcurrentRequestsPerIP:    config.MaxConcurrentRequestsPerIP,
+		RequestJournalPath:            config.RequestJournalPath,
+		RequestJournalEntries:         config.RequestJournalEntries,
+		MountApprovals:                config.MountApprovals,
+		MountApprovers:                config.MountApprovers,
+		TokenFormat:                   config.TokenFormat,
+		AuditBlockSecrets:             config.AuditBlockSecrets,
+		PluginDirectory:               config.PluginDirectory,
 	}
 	if dev {
 		coreConfig.DevToken = devRootTokenID
+		coreConfig.DevTLSCACert = devTLSCACert
 		if devLeasedGeneric {
 			coreConfig.LogicalBackends["generic"] = vault.LeasedPassthroughBackendFactory
 		}
@@ -313,7 +354,7 @@ func (c *ServerCommand) Run(args []string) int {
 		}
 	}
 	if coreConfig.RedirectAddr == "" && dev {
-		coreConfig.RedirectAddr = fmt.Sprintf("http://%s", config.Listeners[0].Config["address"])
+		coreConfig.RedirectAddr = fmt.Sprintf("%s://%s", devScheme, config.Listeners[0].Config["address"])
 	}
 
 	// After the redirect bits are sorted out, if no cluster address was
@@ -328,7 +369,7 @@ func (c *ServerCommand) Run(args []string) int {
 		case coreConfig.ClusterAddr == "" && coreConfig.RedirectAddr != "":
 			addrToUse = coreConfig.RedirectAddr
 		case dev:
-			addrToUse = fmt.Sprintf("http://%s", config.Listeners[0].Config["address"])
+			addrToUse = fmt.Sprintf("%s://%s", devScheme, config.Listeners[0].Config["address"])
 		default:
 			goto CLUSTER_SYNTHESIS_COMPLETE
 		}
@@ -421,7 +462,26 @@ CLUSTER_SYNTHESIS_COMPLETE:
 	// Initialize the listeners
 	c.reloadFuncsLock.Lock()
 	lns := make([]net.Listener, 0, len(config.Listeners))
+	lnForwardedFor := make([][]*net.IPNet, 0, len(config.Listeners))
+	lnMaxRequestSize := make([]int64, 0, len(config.Listeners))
+	lnMaxRequestDuration := make([]time.Duration, 0, len(config.Listeners))
 	for i, lnConfig := range config.Listeners {
+		forwardedFor, err := server.ForwardedForAuthorizedAddrs(lnConfig.Config)
+		if err != nil {
+			c.Ui.Output(fmt.Sprintf(
+				"Error initializing listener of type %s: %s",
+				lnConfig.Type, err))
+			return 1
+		}
+
+		maxRequestSize, maxRequestDuration, err := server.RequestLimits(lnConfig.Config)
+		if err != nil {
+			c.Ui.Output(fmt.Sprintf(
+				"Error initializing listener of type %s: %s",
+				lnConfig.Type, err))
+			return 1
+		}
+
 		ln, props, reloadFunc, err := server.NewListener(lnConfig.Type, lnConfig.Config, logGate)
 		if err != nil {
 			c.Ui.Output(fmt.Sprintf(
@@ -431,6 +491,22 @@ CLUSTER_SYNTHESIS_COMPLETE:
 		}
 
 		lns = append(lns, ln)
+		lnForwardedFor = append(lnForwardedFor, forwardedFor)
+		lnMaxRequestSize = append(lnMaxRequestSize, maxRequestSize)
+		lnMaxRequestDuration = append(lnMaxRequestDuration, maxRequestDuration)
+		if maxRequestSize != 0 {
+			props["max_request_size"] = strconv.FormatInt(maxRequestSize, 10)
+		}
+		if maxRequestDuration != 0 {
+			props["max_request_duration"] = maxRequestDuration.String()
+		}
+		if len(forwardedFor) > 0 {
+			blocks := make([]string, 0, len(forwardedFor))
+			for _, block := range forwardedFor {
+				blocks = append(blocks, block.String())
+			}
+			props["x_forwarded_for_authorized_addrs"] = strings.Join(blocks, ",")
+		}
 
 		if reloadFunc != nil {
 			relSlice := (*c.reloadFuncs)["listener|"+lnConfig.Type]
@@ -581,6 +657,13 @@ CLUSTER_SYNTHESIS_COMPLETE:
 			quote = ""
 		}
 
+		var devTLSHelp string
+		if devTLS {
+			devTLSHelp = "The listener uses TLS with a CA generated in memory. Clients can\n" +
+				"fetch the PEM-encoded CA certificate, without a token, from:\n\n" +
+				"    " + devScheme + "://" + config.Listeners[0].Config["address"].(string) + "/v1/sys/dev/ca\n\n"
+		}
+
 		c.Ui.Output(fmt.Sprintf(
 			"==> WARNING: Dev mode is enabled!\n\n"+
 				"In this mode, Vault is completely in-memory and unsealed.\n"+
@@ -589,7 +672,8 @@ CLUSTER_SYNTHESIS_COMPLETE:
 				"immediately begin using the Vault CLI.\n\n"+
 				"The only step you need to take is to set the following\n"+
 				"environment variables:\n\n"+
-				"    "+export+" VAULT_ADDR="+quote+"http://"+config.Listeners[0].Config["address"].(string)+quote+"\n\n"+
+				"    "+export+" VAULT_ADDR="+quote+devScheme+"://"+config.Listeners[0].Config["address"].(string)+quote+"\n\n"+
+				devTLSHelp+
 				"The unseal key and root token are reproduced below in case you\n"+
 				"want to seal/unseal the Vault or play with authentication.\n\n"+
 				"Unseal Key: %s\nRoot Token: %s\n",
@@ -598,14 +682,19 @@ CLUSTER_SYNTHESIS_COMPLETE:
 		))
 	}
 
-	// Initialize the HTTP server
-	server := &http.Server{}
-	if err := http2.ConfigureServer(server, nil); err != nil {
-		c.Ui.Output(fmt.Sprintf("Error configuring server for HTTP/2: %s", err))
-		return 1
-	}
-	server.Handler = handler
-	for _, ln := range lns {
+	// Initialize the HTTP servers; listeners behind trusted proxies take the
+	// client address from the X-Forwarded-For header, and each listener
+	// enforces its own request limits
+	for i, ln := range lns {
+		server := &http.Server{}
+		if err := http2.ConfigureServer(server, nil); err != nil {
+			c.Ui.Output(fmt.Sprintf("Error configuring server for HTTP/2: %s", err))
+			return 1
+		}
+		server.Handler = vaulthttp.WrapRequestLimitsHandler(handler, core, lnMaxRequestSize[i], lnMaxRequestDuration[i])
+		if len(lnForwardedFor[i]) > 0 {
+			server.Handler = vaulthttp.WrapForwardedForHandler(server.Handler, lnForwardedFor[i])
+		}
 		go server.Serve(ln)
 	}
 
@@ -820,6 +909,19 @@ func (c *ServerCommand) detectRedirect(detect physical.RedirectDetect,
 	return url.String(), nil
 }
 
+// newSealKMS returns the KMS of the auto-unseal seal of the configuration
+func newSealKMS(config *server.Seal, logger log.Logger) (vault.KMS, error) {
+	switch config.Type {
+	case gcpckms.SealType:
+		return gcpckms.New(config.Config, logger)
+	case azurekeyvault.SealType:
+		return azurekeyvault.New(config.Config, logger)
+	case transit.SealType:
+		return transit.New(config.Config, logger)
+	}
+	return nil, fmt.Errorf("unknown seal type %q", config.Type)
+}
+
 // setupTelemetry is used to setup the telemetry sub-systems
 func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 	/* Setup telemetry
@@ -836,7 +938,11 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 		telConfig = config.Telemetry
 	}
 
-	metricsConf := metrics.DefaultConfig("vault")
+	prefix := telConfig.MetricsPrefix
+	if prefix == "" {
+		prefix = "vault"
+	}
+	metricsConf := metrics.DefaultConfig(prefix)
 	metricsConf.EnableHostname = !telConfig.DisableHostname
 
 	// Configure the statsite sink
@@ -1019,6 +1125,10 @@ General Options:
                           with the VAULT_DEV_LISTEN_ADDRESS environment
                           variable.
 
+  -dev-tls                Enables Dev mode with TLS on the listener. The CA of
+                          the listener certificate is generated in memory and
+                          served unauthenticated at sys/dev/ca.
+
   -log-level=info         Log verbosity. Defaults to "info", will be output to
                           stderr. Supported values: "trace", "debug", "info",
                           "warn", "err"
@@ -16,6 +16,8 @@ import (
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
 	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/helper/tokenformat"
 )
 
 // Config is the configuration for the vault server.
@@ -24,14 +26,33 @@ type Config struct {
 	Storage   *Storage    `hcl:"-"`
 	HAStorage *Storage    `hcl:"-"`
 
-	HSM *HSM `hcl:"-"`
+	HSM  *HSM  `hcl:"-"`
+	Seal *Seal `hcl:"-"`
 
 	CacheSize       int         `hcl:"cache_size"`
+	MemorySoftLimit int64       `hcl:"memory_soft_limit"`
+	MemoryHardLimit int64       `hcl:"memory_hard_limit"`
 	DisableCache    bool        `hcl:"-"`
 	DisableCacheRaw interface{} `hcl:"disable_cache"`
 	DisableMlock    bool        `hcl:"-"`
 	DisableMlockRaw interface{} `hcl:"disable_mlock"`
 
+	MaxConcurrentRequestsPerToken int `hcl:"max_concurrent_requests_per_token"`
+	MaxConcurrentRequestsPerIP    int `hcl:"max_concurrent_requests_per_ip"`
+
+	RequestJournalPath    string `hcl:"request_journal_path"`
+	RequestJournalEntries int    `hcl:"request_journal_entries"`
+
+	MountApprovals int      `hcl:"mount_approvals"`
+	MountApprovers []string `hcl:"mount_approvers"`
+
+	TokenFormat string `hcl:"token_format"`
+
+	NTPServer string `hcl:"ntp_server"`
+
+	AuditBlockSecrets    bool        `hcl:"-"`
+	AuditBlockSecretsRaw interface{} `hcl:"audit_block_secrets"`
+
 	EnableUI    bool        `hcl:"-"`
 	EnableUIRaw interface{} `hcl:"ui"`
 
@@ -42,6 +63,12 @@ type Config struct {
 	DefaultLeaseTTL    time.Duration `hcl:"-"`
 	DefaultLeaseTTLRaw interface{}   `hcl:"default_lease_ttl"`
 
+	HALockGracePeriod    time.Duration `hcl:"-"`
+	HALockGracePeriodRaw interface{}   `hcl:"ha_lock_grace_period"`
+
+	ClockSkewTolerance    time.Duration `hcl:"-"`
+	ClockSkewToleranceRaw interface{}   `hcl:"clock_skew_tolerance"`
+
 	ClusterName     string `hcl:"cluster_name"`
 	PluginDirectory string `hcl:"plugin_directory"`
 }
@@ -119,6 +146,16 @@ func (h *HSM) GoString() string {
 	return fmt.Sprintf("*%#v", *h)
 }
 
+// Seal is the configuration of the KMS the server auto-unseals with
+type Seal struct {
+	Type   string
+	Config map[string]string
+}
+
+func (s *Seal) GoString() string {
+	return fmt.Sprintf("*%#v", *s)
+}
+
 // Telemetry is the telemetry configuration for the server
 type Telemetry struct {
 	StatsiteAddr string `hcl:"statsite_address"`
@@ -126,6 +163,11 @@ type Telemetry struct {
 
 	DisableHostname bool `hcl:"disable_hostname"`
 
+	// MetricsPrefix is the prefix of the names of the metrics sent to the
+	// sinks.
+	// Default: "vault"
+	MetricsPrefix string `hcl:"metrics_prefix"`
+
 	// Circonus: see https://github.com/circonus-labs/circonus-gometrics
 	// for more details on the various configuration options.
 	// Valid configuration combinations:
@@ -240,6 +282,11 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.HSM = c2.HSM
 	}
 
+	result.Seal = c.Seal
+	if c2.Seal != nil {
+		result.Seal = c2.Seal
+	}
+
 	result.Telemetry = c.Telemetry
 	if c2.Telemetry != nil {
 		result.Telemetry = c2.Telemetry
@@ -250,6 +297,56 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.CacheSize = c2.CacheSize
 	}
 
+	result.MemorySoftLimit = c.MemorySoftLimit
+	if c2.MemorySoftLimit != 0 {
+		result.MemorySoftLimit = c2.MemorySoftLimit
+	}
+
+	result.MemoryHardLimit = c.MemoryHardLimit
+	if c2.MemoryHardLimit != 0 {
+		result.MemoryHardLimit = c2.MemoryHardLimit
+	}
+
+	result.MaxConcurrentRequestsPerToken = c.MaxConcurrentRequestsPerToken
+	if c2.MaxConcurrentRequestsPerToken != 0 {
+		result.MaxConcurrentRequestsPerToken = c2.MaxConcurrentRequestsPerToken
+	}
+
+	result.MaxConcurrentRequestsPerIP = c.MaxConcurrentRequestsPerIP
+	if c2.MaxConcurrentRequestsPerIP != 0 {
+		result.MaxConcurrentRequestsPerIP = c2.MaxConcurrentRequestsPerIP
+	}
+
+	result.RequestJournalPath = c.RequestJournalPath
+	if c2.RequestJournalPath != "" {
+		result.RequestJournalPath = c2.RequestJournalPath
+	}
+
+	result.RequestJournalEntries = c.RequestJournalEntries
+	if c2.RequestJournalEntries != 0 {
+		result.RequestJournalEntries = c2.RequestJournalEntries
+	}
+
+	result.MountApprovals = c.MountApprovals
+	if c2.MountApprovals != 0 {
+		result.MountApprovals = c2.MountApprovals
+	}
+
+	result.MountApprovers = c.MountApprovers
+	if len(c2.MountApprovers) != 0 {
+		result.MountApprovers = c2.MountApprovers
+	}
+
+	result.TokenFormat = c.TokenFormat
+	if c2.TokenFormat != "" {
+		result.TokenFormat = c2.TokenFormat
+	}
+
+	result.NTPServer = c.NTPServer
+	if c2.NTPServer != "" {
+		result.NTPServer = c2.NTPServer
+	}
+
 	// merging these booleans via an OR operation
 	result.DisableCache = c.DisableCache
 	if c2.DisableCache {
@@ -261,6 +358,11 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.DisableMlock = c2.DisableMlock
 	}
 
+	result.AuditBlockSecrets = c.AuditBlockSecrets
+	if c2.AuditBlockSecrets {
+		result.AuditBlockSecrets = c2.AuditBlockSecrets
+	}
+
 	// merge these integers via a MAX operation
 	result.MaxLeaseTTL = c.MaxLeaseTTL
 	if c2.MaxLeaseTTL > result.MaxLeaseTTL {
@@ -272,6 +374,16 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.DefaultLeaseTTL = c2.DefaultLeaseTTL
 	}
 
+	result.HALockGracePeriod = c.HALockGracePeriod
+	if c2.HALockGracePeriod > result.HALockGracePeriod {
+		result.HALockGracePeriod = c2.HALockGracePeriod
+	}
+
+	result.ClockSkewTolerance = c.ClockSkewTolerance
+	if c2.ClockSkewTolerance > result.ClockSkewTolerance {
+		result.ClockSkewTolerance = c2.ClockSkewTolerance
+	}
+
 	result.ClusterName = c.ClusterName
 	if c2.ClusterName != "" {
 		result.ClusterName = c2.ClusterName
@@ -338,6 +450,42 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 			return nil, err
 		}
 	}
+	if result.HALockGracePeriodRaw != nil {
+		if result.HALockGracePeriod, err = parseutil.ParseDurationSecond(result.HALockGracePeriodRaw); err != nil {
+			return nil, err
+		}
+		if result.HALockGracePeriod < 0 {
+			return nil, fmt.Errorf("ha_lock_grace_period cannot be negative")
+		}
+	}
+	if result.ClockSkewToleranceRaw != nil {
+		if result.ClockSkewTolerance, err = parseutil.ParseDurationSecond(result.ClockSkewToleranceRaw); err != nil {
+			return nil, err
+		}
+		if result.ClockSkewTolerance < 0 {
+			return nil, fmt.Errorf("clock_skew_tolerance cannot be negative")
+		}
+	}
+
+	if result.MemorySoftLimit < 0 || result.MemoryHardLimit < 0 {
+		return nil, fmt.Errorf("memory_soft_limit and memory_hard_limit cannot be negative")
+	}
+	if result.MaxConcurrentRequestsPerToken < 0 || result.MaxConcurrentRequestsPerIP < 0 {
+		return nil, fmt.Errorf("max_concurrent_requests_per_token and max_concurrent_requests_per_ip cannot be negative")
+	}
+	if result.RequestJournalEntries < 0 {
+		return nil, fmt.Errorf("request_journal_entries cannot be negative")
+	}
+	if result.MountApprovals < 0 {
+		return nil, fmt.Errorf("mount_approvals cannot be negative")
+	}
+	if len(strutil.RemoveDuplicates(result.MountApprovers, false)) < result.MountApprovals {
+		return nil, fmt.Errorf("mount_approvers must list at least mount_approvals identities")
+	}
+	if !tokenformat.ValidFormat(result.TokenFormat) {
+		return nil, fmt.Errorf("invalid token_format %q; valid formats are: %s",
+			result.TokenFormat, strings.Join(tokenformat.Formats(), ", "))
+	}
 
 	if result.EnableUIRaw != nil {
 		if result.EnableUI, err = parseutil.ParseBool(result.EnableUIRaw); err != nil {
@@ -357,6 +505,12 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		}
 	}
 
+	if result.AuditBlockSecretsRaw != nil {
+		if result.AuditBlockSecrets, err = parseutil.ParseBool(result.AuditBlockSecretsRaw); err != nil {
+			return nil, err
+		}
+	}
+
 	list, ok := obj.Node.(*ast.ObjectList)
 	if !ok {
 		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
@@ -368,14 +522,28 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		"backend",
 		"ha_backend",
 		"hsm",
+		"seal",
 		"listener",
 		"cache_size",
+		"memory_soft_limit",
+		"memory_hard_limit",
+		"max_concurrent_requests_per_token",
+		"max_concurrent_requests_per_ip",
+		"request_journal_path",
+		"request_journal_entries",
+		"mount_approvals",
+		"mount_approvers",
+		"token_format",
+		"audit_block_secrets",
 		"disable_cache",
 		"disable_mlock",
 		"ui",
 		"telemetry",
 		"default_lease_ttl",
 		"max_lease_ttl",
+		"ha_lock_grace_period",
+		"clock_skew_tolerance",
+		"ntp_server",
 		"cluster_name",
 		"plugin_directory",
 	}
@@ -414,6 +582,12 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		}
 	}
 
+	if o := list.Filter("seal"); len(o.Items) > 0 {
+		if err := parseSeal(&result, o); err != nil {
+			return nil, fmt.Errorf("error parsing 'seal': %s", err)
+		}
+	}
+
 	if o := list.Filter("listener"); len(o.Items) > 0 {
 		if err := parseListeners(&result, o); err != nil {
 			return nil, fmt.Errorf("error parsing 'listener': %s", err)
@@ -655,6 +829,32 @@ func parseHSMs(result *Config, list *ast.ObjectList) error {
 	return nil
 }
 
+func parseSeal(result *Config, list *ast.ObjectList) error {
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'seal' block is permitted")
+	}
+
+	// Get our item
+	item := list.Items[0]
+
+	if len(item.Keys) == 0 {
+		return fmt.Errorf("seal type must be specified")
+	}
+	key := item.Keys[0].Token.Value().(string)
+
+	var m map[string]string
+	if err := hcl.DecodeObject(&m, item.Val); err != nil {
+		return multierror.Prefix(err, fmt.Sprintf("seal.%s:", key))
+	}
+
+	result.Seal = &Seal{
+		Type:   strings.ToLower(key),
+		Config: m,
+	}
+
+	return nil
+}
+
 func parseListeners(result *Config, list *ast.ObjectList) error {
 	listeners := make([]*Listener, 0, len(list.Items))
 	for _, item := range list.Items {
@@ -668,7 +868,12 @@ func parseListeners(result *Config, list *ast.ObjectList) error {
 			"cluster_address",
 			"endpoint",
 			"infrastructure",
+			"max_request_duration",
+			"max_request_size",
 			"node_id",
+			"socket_mode",
+			"socket_user",
+			"socket_group",
 			"tls_disable",
 			"tls_cert_file",
 			"tls_key_file",
@@ -677,6 +882,7 @@ func parseListeners(result *Config, list *ast.ObjectList) error {
 			"tls_prefer_server_cipher_suites",
 			"tls_require_and_verify_client_cert",
 			"token",
+			"x_forwarded_for_authorized_addrs",
 		}
 		if err := checkHCLKeys(item.Val, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("listeners.%s:", key))
@@ -725,6 +931,7 @@ func parseTelemetry(result *Config, list *ast.ObjectList) error {
 		"disable_hostname",
 		"dogstatsd_addr",
 		"dogstatsd_tags",
+		"metrics_prefix",
 		"statsd_address",
 		"statsite_address",
 	}
@@ -45,12 +45,22 @@ func TestLoadConfigFile(t *testing.T) {
 			DisableClustering: true,
 		},
 
+		Seal: &Seal{
+			Type: "gcpckms",
+			Config: map[string]string{
+				"project":    "vault-project",
+				"key_ring":   "vault-ring",
+				"crypto_key": "vault-key",
+			},
+		},
+
 		Telemetry: &Telemetry{
 			StatsdAddr:      "bar",
 			StatsiteAddr:    "foo",
 			DisableHostname: false,
 			DogStatsDAddr:   "127.0.0.1:7254",
 			DogStatsDTags:   []string{"tag_1:val_1", "tag_2:val_2"},
+			MetricsPrefix:   "myprefix",
 		},
 
 		DisableCache:    true,
@@ -65,6 +75,9 @@ func TestLoadConfigFile(t *testing.T) {
 		DefaultLeaseTTL:    10 * time.Hour,
 		DefaultLeaseTTLRaw: "10h",
 		ClusterName:        "testcluster",
+
+		HALockGracePeriod:    30 * time.Second,
+		HALockGracePeriodRaw: "30s",
 	}
 	if !reflect.DeepEqual(config, expected) {
 		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config, expected)
@@ -172,6 +185,18 @@ func TestLoadConfigFile_json2(t *testing.T) {
 
 		CacheSize: 45678,
 
+		MemorySoftLimit: 1073741824,
+		MemoryHardLimit: 2147483648,
+
+		MaxConcurrentRequestsPerToken: 16,
+		MaxConcurrentRequestsPerIP:    64,
+
+		TokenFormat: "uuid",
+
+		ClockSkewTolerance:    30 * time.Second,
+		ClockSkewToleranceRaw: "30s",
+		NTPServer:             "pool.ntp.org",
+
 		EnableUI: true,
 
 		Telemetry: &Telemetry{
@@ -312,3 +337,26 @@ telemetry {
 		t.Errorf("bad error: %q", err)
 	}
 }
+
+func TestParseConfig_mountApprovers(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	_, err := ParseConfig(strings.TrimSpace(`
+mount_approvals = 2
+mount_approvers = ["userpass/alice", "userpass/alice"]
+`), logger)
+	if err == nil || !strings.Contains(err.Error(), "mount_approvers") {
+		t.Fatalf("expected error, got: %v", err)
+	}
+
+	config, err := ParseConfig(strings.TrimSpace(`
+mount_approvals = 2
+mount_approvers = ["userpass/alice", "ldap/bob"]
+`), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MountApprovals != 2 || len(config.MountApprovers) != 2 || config.MountApprovers[1] != "ldap/bob" {
+		t.Fatalf("bad: %#v", config)
+	}
+}
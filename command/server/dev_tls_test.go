@@ -0,0 +1,36 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+)
+
+func TestGenerateDevTLS(t *testing.T) {
+	devTLS, err := GenerateDevTLS([]string{"vault.dev", "10.0.0.1", "0.0.0.0"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	cert, err := tls.X509KeyPair([]byte(devTLS.CertPEM), []byte(devTLS.KeyPEM))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(devTLS.CACertPEM)) {
+		t.Fatalf("failed to parse the CA certificate")
+	}
+	for _, host := range []string{"localhost", "127.0.0.1", "::1", "vault.dev", "10.0.0.1"} {
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots}); err != nil {
+			t.Fatalf("host %s: %v", host, err)
+		}
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: "0.0.0.0", Roots: roots}); err == nil {
+		t.Fatalf("expected the unspecified address not to be in the certificate")
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/hashicorp/vault/helper/tlsutil"
@@ -20,7 +23,8 @@ type ListenerFactory func(map[string]interface{}, io.Writer) (net.Listener, map[
 
 // BuiltinListeners is the list of built-in listener types.
 var BuiltinListeners = map[string]ListenerFactory{
-	"tcp": tcpListenerFactory,
+	"tcp":  tcpListenerFactory,
+	"unix": unixListenerFactory,
 }
 
 // NewListener creates a new listener of the given type with the given
@@ -34,6 +38,81 @@ func NewListener(t string, config map[string]interface{}, logger io.Writer) (net
 	return f(config, logger)
 }
 
+// ForwardedForAuthorizedAddrs returns the CIDR blocks of the proxies trusted
+// to set the client address of the requests to a listener in the
+// X-Forwarded-For header, configured as x_forwarded_for_authorized_addrs
+func ForwardedForAuthorizedAddrs(config map[string]interface{}) ([]*net.IPNet, error) {
+	raw, ok := config["x_forwarded_for_authorized_addrs"]
+	if !ok {
+		return nil, nil
+	}
+
+	var blocks []string
+	switch v := raw.(type) {
+	case string:
+		blocks = strings.Split(v, ",")
+	case []interface{}:
+		for _, block := range v {
+			s, ok := block.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid value for 'x_forwarded_for_authorized_addrs': %v", block)
+			}
+			blocks = append(blocks, s)
+		}
+	default:
+		return nil, fmt.Errorf("invalid value for 'x_forwarded_for_authorized_addrs': %v", raw)
+	}
+
+	var ret []*net.IPNet
+	for _, block := range blocks {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for 'x_forwarded_for_authorized_addrs': %v", err)
+		}
+		ret = append(ret, ipNet)
+	}
+	return ret, nil
+}
+
+// RequestLimits returns the maximum size in bytes and duration of the
+// requests to a listener, configured as max_request_size and
+// max_request_duration, or zero for the defaults
+func RequestLimits(config map[string]interface{}) (int64, time.Duration, error) {
+	var maxSize int64
+	if raw, ok := config["max_request_size"]; ok {
+		var err error
+		switch v := raw.(type) {
+		case int:
+			maxSize = int64(v)
+		case string:
+			maxSize, err = strconv.ParseInt(v, 10, 64)
+		default:
+			err = fmt.Errorf("unsupported type %T", raw)
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid value for 'max_request_size': %v", err)
+		}
+	}
+
+	var maxDuration time.Duration
+	if raw, ok := config["max_request_duration"]; ok {
+		var err error
+		maxDuration, err = parseutil.ParseDurationSecond(raw)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid value for 'max_request_duration': %v", err)
+		}
+		if maxDuration < 0 {
+			return 0, 0, fmt.Errorf("invalid value for 'max_request_duration': cannot be negative")
+		}
+	}
+
+	return maxSize, maxDuration, nil
+}
+
 func listenerWrapTLS(
 	ln net.Listener,
 	props map[string]string,
@@ -50,14 +129,19 @@ func listenerWrapTLS(
 		}
 	}
 
-	_, ok := config["tls_cert_file"]
+	// The in-memory certificate and key are only set by dev mode, they
+	// cannot be given in configuration files
+	_, ok := config["tls_cert_pem"]
 	if !ok {
-		return nil, nil, nil, fmt.Errorf("'tls_cert_file' must be set")
-	}
+		_, ok = config["tls_cert_file"]
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("'tls_cert_file' must be set")
+		}
 
-	_, ok = config["tls_key_file"]
-	if !ok {
-		return nil, nil, nil, fmt.Errorf("'tls_key_file' must be set")
+		_, ok = config["tls_key_file"]
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("'tls_key_file' must be set")
+		}
 	}
 
 	addrRaw, ok := config["address"]
@@ -132,7 +216,14 @@ func (cg *certificateGetter) reload(config map[string]interface{}) error {
 		return nil
 	}
 
-	cert, err := tls.LoadX509KeyPair(config["tls_cert_file"].(string), config["tls_key_file"].(string))
+	var cert tls.Certificate
+	var err error
+	if certPEM, ok := config["tls_cert_pem"].(string); ok {
+		keyPEM, _ := config["tls_key_pem"].(string)
+		cert, err = tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	} else {
+		cert, err = tls.LoadX509KeyPair(config["tls_cert_file"].(string), config["tls_key_file"].(string))
+	}
 	if err != nil {
 		return err
 	}
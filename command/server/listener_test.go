@@ -6,6 +6,7 @@ import (
 	"io"
 	"net"
 	"testing"
+	"time"
 )
 
 type testListenerConnFn func(net.Listener) (net.Conn, error)
@@ -62,3 +63,30 @@ func testListenerImpl(t *testing.T, ln net.Listener, connFn testListenerConnFn,
 		t.Fatalf("bad: %v", buf.String())
 	}
 }
+
+func TestRequestLimits(t *testing.T) {
+	maxSize, maxDuration, err := RequestLimits(map[string]interface{}{
+		"max_request_size":     1048576,
+		"max_request_duration": "90s",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if maxSize != 1048576 || maxDuration != 90*time.Second {
+		t.Fatalf("bad: %d %s", maxSize, maxDuration)
+	}
+
+	maxSize, maxDuration, err = RequestLimits(map[string]interface{}{})
+	if err != nil || maxSize != 0 || maxDuration != 0 {
+		t.Fatalf("bad: %d %s %v", maxSize, maxDuration, err)
+	}
+
+	for _, config := range []map[string]interface{}{
+		{"max_request_size": "big"},
+		{"max_request_duration": "-1s"},
+	} {
+		if _, _, err := RequestLimits(config); err == nil {
+			t.Fatalf("expected an error for %#v", config)
+		}
+	}
+}
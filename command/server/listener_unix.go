@@ -0,0 +1,114 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+// unixListenerFactory listens on a Unix domain socket. The API is served
+// over plain HTTP on the socket, access to which is controlled by its file
+// mode and owner rather than TLS.
+func unixListenerFactory(config map[string]interface{}, _ io.Writer) (net.Listener, map[string]string, vault.ReloadFunc, error) {
+	addrRaw, ok := config["address"]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("'address' must be set")
+	}
+	addr := strings.TrimPrefix(addrRaw.(string), "unix://")
+	if addr == "" {
+		return nil, nil, nil, fmt.Errorf("'address' must be set")
+	}
+
+	// Remove the socket left behind by a previous run, which would make
+	// the listen fail, but never any other file
+	if fi, err := os.Lstat(addr); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, nil, nil, fmt.Errorf("%s exists and is not a socket", addr)
+		}
+		if err := os.Remove(addr); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to remove stale socket: %v", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", addr)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := setSocketPermissions(addr, config); err != nil {
+		ln.Close()
+		return nil, nil, nil, err
+	}
+
+	props := map[string]string{
+		"addr": addr,
+		"tls":  "disabled",
+	}
+	return ln, props, nil, nil
+}
+
+// setSocketPermissions applies the socket_mode, socket_user and socket_group
+// of the listener configuration to the socket
+func setSocketPermissions(path string, config map[string]interface{}) error {
+	if raw, ok := config["socket_mode"]; ok {
+		// The mode is given as an octal string, or as a number HCL already
+		// parsed from an unquoted octal literal
+		var mode uint64
+		var err error
+		switch v := raw.(type) {
+		case string:
+			mode, err = strconv.ParseUint(v, 8, 32)
+		case int:
+			mode = uint64(v)
+		default:
+			err = fmt.Errorf("unsupported type %T", raw)
+		}
+		if err != nil || mode > 0777 {
+			return fmt.Errorf("invalid value for 'socket_mode': %v", raw)
+		}
+		if err := os.Chmod(path, os.FileMode(mode)); err != nil {
+			return fmt.Errorf("failed to set the mode of the socket: %v", err)
+		}
+	}
+
+	uid, gid := -1, -1
+	if raw, ok := config["socket_user"]; ok {
+		name := fmt.Sprint(raw)
+		u, err := user.Lookup(name)
+		if err != nil {
+			u, err = user.LookupId(name)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for 'socket_user': %v", err)
+		}
+		if uid, err = strconv.Atoi(u.Uid); err != nil {
+			return fmt.Errorf("invalid value for 'socket_user': %v", err)
+		}
+	}
+	if raw, ok := config["socket_group"]; ok {
+		name := fmt.Sprint(raw)
+		g, err := user.LookupGroup(name)
+		if err != nil {
+			g, err = user.LookupGroupId(name)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for 'socket_group': %v", err)
+		}
+		if gid, err = strconv.Atoi(g.Gid); err != nil {
+			return fmt.Errorf("invalid value for 'socket_group': %v", err)
+		}
+	}
+	if uid != -1 || gid != -1 {
+		if err := os.Chown(path, uid, gid); err != nil {
+			return fmt.Errorf("failed to set the owner of the socket: %v", err)
+		}
+	}
+
+	return nil
+}
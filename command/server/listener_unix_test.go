@@ -0,0 +1,80 @@
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnixListener(t *testing.T) {
+	td, err := ioutil.TempDir("", "vault-test-unix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+	path := filepath.Join(td, "vault.sock")
+
+	// A socket left behind by a previous run is replaced
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, props, _, err := unixListenerFactory(map[string]interface{}{
+		"address":     "unix://" + path,
+		"socket_mode": "0600",
+	}, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if props["addr"] != path || props["tls"] != "disabled" {
+		t.Fatalf("bad: %#v", props)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := fi.Mode().Perm(); mode != 0600 {
+		t.Fatalf("bad: mode %o", mode)
+	}
+
+	connFn := func(lnReal net.Listener) (net.Conn, error) {
+		return net.Dial("unix", path)
+	}
+
+	testListenerImpl(t, ln, connFn, "")
+}
+
+func TestUnixListener_invalid(t *testing.T) {
+	td, err := ioutil.TempDir("", "vault-test-unix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	// Files other than sockets are never removed
+	file := filepath.Join(td, "file")
+	if err := ioutil.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, config := range []map[string]interface{}{
+		{},
+		{"address": file},
+		{"address": filepath.Join(td, "mode.sock"), "socket_mode": "0999"},
+		{"address": filepath.Join(td, "user.sock"), "socket_user": "no-such-user-vault-test"},
+	} {
+		if ln, _, _, err := unixListenerFactory(config, nil); err == nil {
+			ln.Close()
+			t.Fatalf("expected an error for %#v", config)
+		}
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Fatal(err)
+	}
+}
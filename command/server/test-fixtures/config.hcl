@@ -18,13 +18,21 @@ ha_backend "consul" {
     disable_clustering = "true"
 }
 
+seal "gcpckms" {
+    project = "vault-project"
+    key_ring = "vault-ring"
+    crypto_key = "vault-key"
+}
+
 telemetry {
     statsd_address = "bar"
     statsite_address = "foo"
     dogstatsd_addr = "127.0.0.1:7254"
     dogstatsd_tags = ["tag_1:val_1", "tag_2:val_2"]
+    metrics_prefix = "myprefix"
 }
 
 max_lease_ttl = "10h"
 default_lease_ttl = "10h"
 cluster_name = "testcluster"
+ha_lock_grace_period = "30s"
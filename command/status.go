@@ -53,6 +53,10 @@ func (c *StatusCommand) Run(args []string) int {
 		outStr = fmt.Sprintf("%s\nCluster Name: %s\nCluster ID: %s", outStr, sealStatus.ClusterName, sealStatus.ClusterID)
 	}
 
+	if len(sealStatus.Experiments) > 0 {
+		outStr = fmt.Sprintf("%s\nExperiments: %s", outStr, strings.Join(sealStatus.Experiments, ", "))
+	}
+
 	c.Ui.Output(outStr)
 
 	// Mask the 'Vault is sealed' error, since this means HA is enabled,
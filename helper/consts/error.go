@@ -11,6 +11,16 @@ var (
 	// No operation is expected to succeed until active.
 	ErrStandby = errors.New("Vault is in standby mode")
 
+	// ErrLeadershipSuspended is returned if an operation is performed on an
+	// active Vault while it re-acquires its lost HA lock. Operations are
+	// expected to succeed again once the lock is re-acquired or another node
+	// becomes active.
+	ErrLeadershipSuspended = errors.New("Vault is re-acquiring its HA lock")
+
+	// ErrPluginUnavailable is returned if a request is routed to a plugin
+	// backend whose process crashed and is restarting or is quarantined
+	ErrPluginUnavailable = errors.New("plugin is unavailable")
+
 	// Used when .. is used in a path
 	ErrPathContainsParentReferences = errors.New("path cannot contain parent references")
 )
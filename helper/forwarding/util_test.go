@@ -3,16 +3,87 @@ package forwarding
 import (
 	"bufio"
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
 	"net/http"
 	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func Test_ForwardedRequest_GenerateParse(t *testing.T) {
 	testForwardedRequestGenerateParse(t)
 }
 
+// The client certificate chain must survive forwarding so that the cert auth
+// backend can authenticate logins received by standbys
+func Test_ForwardedRequest_PeerCertificates(t *testing.T) {
+	defer os.Unsetenv("VAULT_MESSAGE_TYPE")
+
+	chain := []*x509.Certificate{
+		testCertificate(t, "client"),
+		testCertificate(t, "intermediate"),
+	}
+
+	for _, messageType := range []string{"proto3", "json", "json_compress"} {
+		os.Setenv("VAULT_MESSAGE_TYPE", messageType)
+
+		req, err := http.NewRequest("PUT", "https://127.0.0.1:8200/v1/auth/cert/login", bytes.NewReader(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.TLS = &tls.ConnectionState{
+			PeerCertificates: chain,
+		}
+
+		fwReq, err := GenerateForwardedHTTPRequest(req, "https://127.0.0.1:8201")
+		if err != nil {
+			t.Fatal(err)
+		}
+		finalReq, err := ParseForwardedHTTPRequest(fwReq)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if finalReq.TLS == nil || len(finalReq.TLS.PeerCertificates) != len(chain) {
+			t.Fatalf("%s: bad: %#v", messageType, finalReq.TLS)
+		}
+		for i, cert := range chain {
+			if !cert.Equal(finalReq.TLS.PeerCertificates[i]) {
+				t.Fatalf("%s: certificate %d differs", messageType, i)
+			}
+		}
+	}
+}
+
+func testCertificate(t testing.TB, cn string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
 func Benchmark_ForwardedRequest_GenerateParse_JSON(b *testing.B) {
 	os.Setenv("VAULT_MESSAGE_TYPE", "json")
 	var totalSize int64
@@ -1,6 +1,7 @@
 package keysutil
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"sync"
@@ -377,6 +378,76 @@ func (lm *LockManager) DeletePolicy(storage logical.Storage, name string) error
 	return nil
 }
 
+// RestorePolicy restores a policy along with its archived keys from a backup
+// returned by Policy.Backup, under the given name or, if empty, under the
+// name it was backed up with. An existing policy is never overwritten.
+func (lm *LockManager) RestorePolicy(storage logical.Storage, name, backup string) error {
+	backupBytes, err := base64.StdEncoding.DecodeString(backup)
+	if err != nil {
+		return fmt.Errorf("failed to base64-decode the backup: %v", err)
+	}
+
+	keyData := &KeyData{
+		Policy: &Policy{
+			Keys: keyEntryMap{},
+		},
+	}
+	if err := jsonutil.DecodeJSON(backupBytes, keyData); err != nil {
+		return fmt.Errorf("failed to decode the backup: %v", err)
+	}
+	p := keyData.Policy
+	if p == nil || len(p.Keys) == 0 || p.LatestVersion < 1 {
+		return fmt.Errorf("the backup does not contain a valid key")
+	}
+	if name != "" {
+		p.Name = name
+	}
+	if p.Name == "" {
+		return fmt.Errorf("a name is required to restore the key")
+	}
+	archive := keyData.ArchivedKeys
+	if archive == nil {
+		archive = &archivedKeys{
+			Keys: make([]KeyEntry, 0),
+		}
+	}
+
+	lm.cacheMutex.Lock()
+	lock := lm.policyLock(p.Name, exclusive)
+	defer lock.Unlock()
+	defer lm.cacheMutex.Unlock()
+
+	existing, err := lm.getStoredPolicy(storage, p.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil || (lm.CacheActive() && lm.cache[p.Name] != nil) {
+		return fmt.Errorf("key %q already exists", p.Name)
+	}
+
+	// Write the archive first so that the policy never references archived
+	// keys that are missing
+	if err := p.storeArchive(archive, storage); err != nil {
+		return err
+	}
+	buf, err := p.Serialize()
+	if err != nil {
+		return err
+	}
+	if err := storage.Put(&logical.StorageEntry{
+		Key:   "policy/" + p.Name,
+		Value: buf,
+	}); err != nil {
+		return err
+	}
+
+	if lm.CacheActive() {
+		lm.cache[p.Name] = p
+	}
+
+	return nil
+}
+
 func (lm *LockManager) getStoredPolicy(storage logical.Storage, name string) (*Policy, error) {
 	// Check if the policy already exists
 	raw, err := storage.Get("policy/" + name)
@@ -201,6 +201,21 @@ type Policy struct {
 
 	// The type of key
 	Type KeyType `json:"type"`
+
+	// Whether the key, along with its archive, can be backed up in plaintext
+	// so that it can be restored elsewhere. Once set, it cannot be unset.
+	AllowPlaintextBackup bool `json:"allow_plaintext_backup"`
+
+	// The period after which the latest version of the key is rotated
+	// automatically; zero disables automatic rotation
+	AutoRotatePeriod time.Duration `json:"auto_rotate_period"`
+}
+
+// KeyData is what a backup of a policy holds: the policy along with its
+// archived keys
+type KeyData struct {
+	Policy       *Policy       `json:"policy"`
+	ArchivedKeys *archivedKeys `json:"archived_keys"`
 }
 
 // ArchivedKeys stores old keys. This is used to keep the key loading time sane
@@ -351,6 +366,32 @@ func (p *Policy) Persist(storage logical.Storage) error {
 	return nil
 }
 
+// Backup returns the policy and its archived keys, JSON-encoded and then
+// base64-encoded. Only exportable policies allowing plaintext backups can be
+// backed up.
+func (p *Policy) Backup(storage logical.Storage) (string, error) {
+	if !p.Exportable {
+		return "", fmt.Errorf("exporting is disallowed on the policy")
+	}
+	if !p.AllowPlaintextBackup {
+		return "", fmt.Errorf("plaintext backup is disallowed on the policy")
+	}
+
+	archive, err := p.LoadArchive(storage)
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := json.Marshal(&KeyData{
+		Policy:       p,
+		ArchivedKeys: archive,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(encoded), nil
+}
+
 func (p *Policy) Serialize() ([]byte, error) {
 	return json.Marshal(p)
 }
@@ -864,6 +905,23 @@ func (p *Policy) VerifySignature(context, input []byte, sig string) (bool, error
 	return false, errutil.InternalError{Err: "no valid key type found"}
 }
 
+// NeedsAutoRotation returns whether automatic rotation is enabled on the
+// policy and its latest version is older than the rotation period
+func (p *Policy) NeedsAutoRotation(now time.Time) bool {
+	if p.AutoRotatePeriod <= 0 {
+		return false
+	}
+	latest, ok := p.Keys[p.LatestVersion]
+	if !ok {
+		return false
+	}
+	created := latest.CreationTime
+	if created.IsZero() {
+		created = time.Unix(latest.DeprecatedCreationTime, 0)
+	}
+	return !now.Before(created.Add(p.AutoRotatePeriod))
+}
+
 func (p *Policy) Rotate(storage logical.Storage) error {
 	if p.Keys == nil {
 		// This is an initial key rotation when generating a new policy. We
@@ -0,0 +1,136 @@
+package logformat
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	log "github.com/mgutz/logxi/v1"
+)
+
+// logMonitorBuffer is the number of entries buffered for a monitor; entries
+// logged while the buffer of a monitor is full are dropped rather than
+// slowing down the logger
+const logMonitorBuffer = 512
+
+// LogMonitors streams the entries of a logger to monitors
+type LogMonitors struct {
+	l        sync.RWMutex
+	level    int
+	monitors map[*LogMonitor]struct{}
+}
+
+// LogMonitor receives the entries of a logger at or above its level
+type LogMonitor struct {
+	monitors *LogMonitors
+	level    int
+	entries  chan []byte
+	dropped  uint64
+}
+
+// NewMonitoredVaultLogger creates a new logger like NewVaultLoggerWithWriter,
+// whose entries can also be streamed to monitors
+func NewMonitoredVaultLogger(w io.Writer, level int) (log.Logger, *LogMonitors) {
+	monitors := &LogMonitors{
+		level:    level,
+		monitors: make(map[*LogMonitor]struct{}),
+	}
+	formatter := createVaultFormatter().(*vaultFormatter)
+	formatter.monitors = monitors
+
+	logger := log.NewLogger(w, "vault")
+	return setLevelFormatter(logger, level, formatter), monitors
+}
+
+// Monitor starts streaming the entries logged at or above the level to a
+// new monitor. The level cannot be more verbose than that of the logger, as
+// the logger discards those entries. The monitor must be stopped when done.
+func (m *LogMonitors) Monitor(level int) (*LogMonitor, error) {
+	if level > m.level {
+		return nil, fmt.Errorf("cannot monitor the log at level %s, which is more verbose than the %s level of the server", levelName(level), levelName(m.level))
+	}
+
+	monitor := &LogMonitor{
+		monitors: m,
+		level:    level,
+		entries:  make(chan []byte, logMonitorBuffer),
+	}
+	m.l.Lock()
+	m.monitors[monitor] = struct{}{}
+	m.l.Unlock()
+	return monitor, nil
+}
+
+// broadcast sends the formatted entry logged at the level to the monitors
+func (m *LogMonitors) broadcast(level int, entry []byte) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+	for monitor := range m.monitors {
+		if level > monitor.level {
+			continue
+		}
+		select {
+		case monitor.entries <- append([]byte(nil), entry...):
+		default:
+			atomic.AddUint64(&monitor.dropped, 1)
+		}
+	}
+}
+
+// Entries returns the channel the entries are sent to, one formatted entry
+// per value. It is closed once the monitor is stopped.
+func (m *LogMonitor) Entries() <-chan []byte {
+	return m.entries
+}
+
+// Dropped returns the number of entries dropped because the monitor did not
+// keep up with the logger
+func (m *LogMonitor) Dropped() uint64 {
+	return atomic.LoadUint64(&m.dropped)
+}
+
+// Stop stops streaming entries to the monitor
+func (m *LogMonitor) Stop() {
+	m.monitors.l.Lock()
+	defer m.monitors.l.Unlock()
+	if _, ok := m.monitors.monitors[m]; !ok {
+		return
+	}
+	delete(m.monitors.monitors, m)
+	close(m.entries)
+}
+
+// levelNames are the names of the log levels of the server
+var levelNames = map[int]string{
+	log.LevelTrace:  "trace",
+	log.LevelDebug:  "debug",
+	log.LevelInfo:   "info",
+	log.LevelNotice: "notice",
+	log.LevelWarn:   "warn",
+	log.LevelError:  "err",
+}
+
+// ParseLevel returns the log level of the given name: trace, debug, info,
+// notice, warn or err
+func ParseLevel(name string) (int, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "error" {
+		name = "err"
+	}
+	for level, levelName := range levelNames {
+		if levelName == name {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %s", name)
+}
+
+// levelName returns the name of the log level
+func levelName(level int) string {
+	if name, ok := levelNames[level]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", level)
+}
@@ -0,0 +1,58 @@
+package logformat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestLogMonitors(t *testing.T) {
+	var buf bytes.Buffer
+	logger, monitors := NewMonitoredVaultLogger(&buf, log.LevelDebug)
+
+	if _, err := monitors.Monitor(log.LevelTrace); err == nil {
+		t.Fatal("expected an error monitoring a more verbose level")
+	}
+	monitor, err := monitors.Monitor(log.LevelInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Debug("debug entry")
+	logger.Info("info entry", "key", "value")
+
+	// The writer gets every entry, the monitor those at or above its level
+	if !strings.Contains(buf.String(), "debug entry") || !strings.Contains(buf.String(), "info entry: key=value") {
+		t.Fatalf("bad: %q", buf.String())
+	}
+	entry := string(<-monitor.Entries())
+	if !strings.Contains(entry, "[INFO ] info entry: key=value") {
+		t.Fatalf("bad: %q", entry)
+	}
+
+	monitor.Stop()
+	if _, ok := <-monitor.Entries(); ok {
+		t.Fatal("expected the entries to be closed")
+	}
+	logger.Info("after stop")
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, expected := range map[string]int{
+		"trace":  log.LevelTrace,
+		"DEBUG":  log.LevelDebug,
+		"notice": log.LevelNotice,
+		"err":    log.LevelError,
+		"error":  log.LevelError,
+	} {
+		level, err := ParseLevel(name)
+		if err != nil || level != expected {
+			t.Fatalf("%s: bad: %d %v", name, level, err)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -1,6 +1,7 @@
 package logformat
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -61,12 +62,28 @@ type vaultFormatter struct {
 	*sync.Mutex
 	style  int
 	module string
+
+	// monitors receive the formatted entries too, if set
+	monitors *LogMonitors
 }
 
 func (v *vaultFormatter) Format(writer io.Writer, level int, msg string, args []interface{}) {
 	currTime := time.Now()
 	v.Lock()
 	defer v.Unlock()
+
+	// Format the entry once for the writer and the monitors
+	if v.monitors != nil {
+		var buf bytes.Buffer
+		v.format(&buf, currTime, level, msg, args)
+		writer.Write(buf.Bytes())
+		v.monitors.broadcast(level, buf.Bytes())
+		return
+	}
+	v.format(writer, currTime, level, msg, args)
+}
+
+func (v *vaultFormatter) format(writer io.Writer, currTime time.Time, level int, msg string, args []interface{}) {
 	switch v.style {
 	case stylejson:
 		v.formatJSON(writer, currTime, level, msg, args)
@@ -0,0 +1,32 @@
+package pluginutil
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+var (
+	// PluginMaxMemoryEnv is the ENV name used to pass the maximum memory, in
+	// bytes, the plugin process can use
+	PluginMaxMemoryEnv = "VAULT_PLUGIN_MAX_MEMORY"
+)
+
+// OptionallyLimitMemory determines if the memory of the plugin process should
+// be limited, and if so limits it where the platform supports it.
+func OptionallyLimitMemory() error {
+	raw := os.Getenv(PluginMaxMemoryEnv)
+	if raw == "" {
+		return nil
+	}
+
+	max, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid maximum memory %q: %v", raw, err)
+	}
+	if max == 0 {
+		return nil
+	}
+
+	return limitMemory(max)
+}
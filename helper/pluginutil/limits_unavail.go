@@ -0,0 +1,9 @@
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package pluginutil
+
+func limitMemory(max uint64) error {
+	// There is no portable way to limit the memory of the process here, so
+	// the limit is not enforced
+	return nil
+}
@@ -0,0 +1,14 @@
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package pluginutil
+
+import "syscall"
+
+func limitMemory(max uint64) error {
+	// Limit the address space of the process, which the allocations of the
+	// Go runtime fail beyond
+	return syscall.Setrlimit(syscall.RLIMIT_AS, &syscall.Rlimit{
+		Cur: max,
+		Max: max,
+	})
+}
@@ -41,6 +41,16 @@ type PluginRunner struct {
 	Sha256         []byte                      `json:"sha256" structs:"sha256"`
 	Builtin        bool                        `json:"builtin" structs:"builtin"`
 	BuiltinFactory func() (interface{}, error) `json:"-" structs:"-"`
+
+	// MaxMemory is the maximum memory, in bytes, the plugin process can use,
+	// or 0 for no limit
+	MaxMemory uint64 `json:"max_memory,omitempty" structs:"max_memory"`
+
+	// MaxRestarts is the number of times the plugin can crash within the
+	// RestartWindow and be restarted before its mounts are quarantined, or 0
+	// for the default
+	MaxRestarts   int           `json:"max_restarts,omitempty" structs:"max_restarts"`
+	RestartWindow time.Duration `json:"restart_window,omitempty" structs:"restart_window"`
 }
 
 // Run takes a wrapper instance, and the go-plugin paramaters and executes a
@@ -73,6 +83,10 @@ func (r *PluginRunner) Run(wrapper RunnerUtil, pluginMap map[string]plugin.Plugi
 	if wrapper.MlockEnabled() {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", PluginMlockEnabled, "true"))
 	}
+	// Add the memory limit to the ENV of the plugin
+	if r.MaxMemory > 0 {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", PluginMaxMemoryEnv, r.MaxMemory))
+	}
 
 	secureConfig := &plugin.SecureConfig{
 		Checksum: r.Sha256,
@@ -0,0 +1,208 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Window is a recurring window of time in which something is allowed,
+// written like a crontab schedule: the fields are the minute, hour, day of
+// month, month and day of week (0 or 7 being Sunday), and the window contains
+// every minute the schedule matches. For instance "* 9-17 * * 1-5" allows
+// 09:00 to 17:59 on weekdays. Fields may be "*", a number, a range, a list of
+// those separated by commas, and may have a step such as "*/15".
+//
+// As in cron, when both the day of month and the day of week are restricted,
+// a day matching either of them is in the window. The schedule is evaluated
+// in UTC, unless prefixed with a time zone such as "TZ=Europe/Paris".
+type Window struct {
+	raw      string
+	location *time.Location
+
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+
+	// Whether the day fields are "*", for the cron semantics of days
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// Parse parses the specification of a window
+func Parse(spec string) (*Window, error) {
+	w := &Window{
+		raw:      strings.TrimSpace(spec),
+		location: time.UTC,
+	}
+
+	parts := strings.Fields(w.raw)
+	if len(parts) > 0 && strings.HasPrefix(parts[0], "TZ=") {
+		location, err := time.LoadLocation(strings.TrimPrefix(parts[0], "TZ="))
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone in window %q: %v", spec, err)
+		}
+		w.location = location
+		parts = parts[1:]
+	}
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("window %q must have %d fields", spec, len(fields))
+	}
+
+	bits := []*uint64{&w.minutes, &w.hours, &w.daysOfMonth, &w.months, &w.daysOfWeek}
+	for i, f := range fields {
+		value, err := parseField(parts[i], f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in window %q: %v", f.name, spec, err)
+		}
+		*bits[i] = value
+	}
+
+	// Sunday can be written as 7
+	if w.daysOfWeek&(1<<7) != 0 {
+		w.daysOfWeek |= 1
+	}
+	w.anyDayOfMonth = parts[2] == "*"
+	w.anyDayOfWeek = parts[4] == "*"
+
+	return w, nil
+}
+
+func parseField(raw string, f field) (uint64, error) {
+	var result uint64
+	for _, item := range strings.Split(raw, ",") {
+		step := 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(item[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", item[i+1:])
+			}
+			item = item[:i]
+		}
+
+		low, high := f.min, f.max
+		switch {
+		case item == "*":
+		case strings.Contains(item, "-"):
+			bounds := strings.SplitN(item, "-", 2)
+			var err error
+			if low, err = parseValue(bounds[0], f); err != nil {
+				return 0, err
+			}
+			if high, err = parseValue(bounds[1], f); err != nil {
+				return 0, err
+			}
+			if high < low {
+				return 0, fmt.Errorf("invalid range %q", item)
+			}
+		default:
+			value, err := parseValue(item, f)
+			if err != nil {
+				return 0, err
+			}
+			low = value
+			if step == 1 {
+				high = value
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			result |= 1 << uint(v)
+		}
+	}
+	return result, nil
+}
+
+func parseValue(raw string, f field) (int, error) {
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", raw)
+	}
+	if value < f.min || value > f.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", value, f.min, f.max)
+	}
+	return value, nil
+}
+
+// Contains returns whether the time is in the window
+func (w *Window) Contains(t time.Time) bool {
+	t = t.In(w.location)
+
+	if w.minutes&(1<<uint(t.Minute())) == 0 ||
+		w.hours&(1<<uint(t.Hour())) == 0 ||
+		w.months&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	dayOfMonth := w.daysOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := w.daysOfWeek&(1<<uint(t.Weekday())) != 0
+	if !w.anyDayOfMonth && !w.anyDayOfWeek {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
+
+// String returns the specification of the window
+func (w *Window) String() string {
+	return w.raw
+}
+
+// Windows is a set of windows. The empty set places no restriction, so that
+// everything is allowed at all times.
+type Windows []*Window
+
+// ParseWindows parses the specifications of a set of windows
+func ParseWindows(specs []string) (Windows, error) {
+	var windows Windows
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		w, err := Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// Allows returns whether the time is in any of the windows, or the set is
+// empty
+func (ws Windows) Allows(t time.Time) bool {
+	if len(ws) == 0 {
+		return true
+	}
+	for _, w := range ws {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Strings returns the specifications of the windows
+func (ws Windows) Strings() []string {
+	ret := make([]string, 0, len(ws))
+	for _, w := range ws {
+		ret = append(ret, w.String())
+	}
+	return ret
+}
@@ -0,0 +1,106 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse_invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"* 17-9 * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"TZ=Nowhere/Special * * * * *",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestWindow_Contains(t *testing.T) {
+	cases := []struct {
+		spec     string
+		time     string
+		expected bool
+	}{
+		// Weekday office hours; 2017-08-07 is a Monday
+		{"* 9-17 * * 1-5", "2017-08-07T09:00:00Z", true},
+		{"* 9-17 * * 1-5", "2017-08-07T17:59:00Z", true},
+		{"* 9-17 * * 1-5", "2017-08-07T18:00:00Z", false},
+		{"* 9-17 * * 1-5", "2017-08-06T12:00:00Z", false},
+
+		// Sunday as 7
+		{"* * * * 7", "2017-08-06T12:00:00Z", true},
+		{"* * * * 7", "2017-08-07T12:00:00Z", false},
+
+		// Lists and steps
+		{"0,30 * * * *", "2017-08-07T12:30:00Z", true},
+		{"0,30 * * * *", "2017-08-07T12:31:00Z", false},
+		{"*/15 * * * *", "2017-08-07T12:45:00Z", true},
+		{"*/15 * * * *", "2017-08-07T12:46:00Z", false},
+		{"0-10/5 * * * *", "2017-08-07T12:05:00Z", true},
+		{"0-10/5 * * * *", "2017-08-07T12:15:00Z", false},
+
+		// Restricted day of month and day of week match either
+		{"* * 1 * 1", "2017-08-07T12:00:00Z", true},
+		{"* * 1 * 1", "2017-08-01T12:00:00Z", true},
+		{"* * 1 * 1", "2017-08-02T12:00:00Z", false},
+
+		// Months
+		{"* * * 8 *", "2017-08-07T12:00:00Z", true},
+		{"* * * 9 *", "2017-08-07T12:00:00Z", false},
+
+		// Time zones
+		{"TZ=Asia/Tokyo * 9 * * *", "2017-08-07T00:30:00Z", true},
+		{"TZ=Asia/Tokyo * 9 * * *", "2017-08-07T09:30:00Z", false},
+	}
+
+	for _, c := range cases {
+		w, err := Parse(c.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", c.spec, err)
+		}
+		at, err := time.Parse(time.RFC3339, c.time)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := w.Contains(at); actual != c.expected {
+			t.Fatalf("%s at %s: expected %v, got %v", c.spec, c.time, c.expected, actual)
+		}
+	}
+}
+
+func TestWindows_Allows(t *testing.T) {
+	at, err := time.Parse(time.RFC3339, "2017-08-07T12:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var empty Windows
+	if !empty.Allows(at) {
+		t.Fatal("empty windows should allow all times")
+	}
+
+	windows, err := ParseWindows([]string{"* 0-6 * * *", "* 12 * * *", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 2 {
+		t.Fatalf("bad: %v", windows.Strings())
+	}
+	if !windows.Allows(at) {
+		t.Fatal("expected time to be allowed")
+	}
+	if windows.Allows(at.Add(2 * time.Hour)) {
+		t.Fatal("expected time not to be allowed")
+	}
+}
@@ -0,0 +1,143 @@
+// Package secretcheck provides validators that reject values matching the
+// formats of well-known credentials, so that backends can refuse to store
+// secrets that should never have been handed to them (for instance root
+// cloud provider keys).
+package secretcheck
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/tokenformat"
+)
+
+// Validator checks a single string value
+type Validator interface {
+	// Name returns the name the validator is configured with
+	Name() string
+
+	// Matches returns true if the value should be rejected
+	Matches(value string) bool
+}
+
+// regexpValidator rejects values matching a regular expression
+type regexpValidator struct {
+	name string
+	re   *regexp.Regexp
+}
+
+func (v *regexpValidator) Name() string {
+	return v.name
+}
+
+func (v *regexpValidator) Matches(value string) bool {
+	return v.re.MatchString(value)
+}
+
+// builtinValidators are the validators that can be enabled by name
+var builtinValidators = map[string]Validator{
+	"aws-access-keys": &regexpValidator{
+		name: "aws-access-keys",
+		re:   regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`),
+	},
+	"private-keys": &regexpValidator{
+		name: "private-keys",
+		re:   regexp.MustCompile(`-----BEGIN ([A-Z0-9]+ )*PRIVATE KEY-----`),
+	},
+	"vault-tokens": &regexpValidator{
+		name: "vault-tokens",
+		re:   tokenformat.Pattern,
+	},
+}
+
+// BuiltinNames returns the sorted names of the built-in validators
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtinValidators))
+	for name := range builtinValidators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Checker applies a set of validators to request data
+type Checker struct {
+	validators []Validator
+}
+
+// NewChecker returns a Checker using the named built-in validators and, if
+// denyPattern is not empty, a validator rejecting values that match it.
+func NewChecker(names []string, denyPattern string) (*Checker, error) {
+	c := &Checker{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		v, ok := builtinValidators[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown validator %q; valid validators are: %s",
+				name, strings.Join(BuiltinNames(), ", "))
+		}
+		c.validators = append(c.validators, v)
+	}
+
+	if denyPattern != "" {
+		re, err := regexp.Compile(denyPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deny pattern: %v", err)
+		}
+		c.validators = append(c.validators, &regexpValidator{
+			name: "deny-pattern",
+			re:   re,
+		})
+	}
+
+	return c, nil
+}
+
+// Empty returns true if the checker has no validators
+func (c *Checker) Empty() bool {
+	return c == nil || len(c.validators) == 0
+}
+
+// Check walks the given data, including nested maps and lists, and returns
+// an error naming the offending key and validator for the first string
+// value that is rejected. The value itself is never included in the error.
+func (c *Checker) Check(data map[string]interface{}) error {
+	if c.Empty() {
+		return nil
+	}
+	for k, v := range data {
+		if err := c.check(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Checker) check(key string, value interface{}) error {
+	switch value := value.(type) {
+	case string:
+		for _, v := range c.validators {
+			if v.Matches(value) {
+				return fmt.Errorf("value of %q was rejected by the %q validator", key, v.Name())
+			}
+		}
+	case map[string]interface{}:
+		for k, v := range value {
+			if err := c.check(key+"."+k, v); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, v := range value {
+			if err := c.check(fmt.Sprintf("%s[%d]", key, i), v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
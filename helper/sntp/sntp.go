@@ -0,0 +1,101 @@
+// Package sntp implements a minimal SNTP (RFC 4330) client, used to measure
+// the offset of the local clock from an NTP server.
+package sntp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// DefaultPort is the port NTP servers listen on
+	DefaultPort = "123"
+
+	packetSize = 48
+
+	// modeClient and modeServer are the association modes of requests and
+	// responses
+	modeClient = 3
+	modeServer = 4
+
+	version = 4
+)
+
+// ntpEpochOffset is the number of seconds between the NTP epoch, 1900, and
+// the Unix epoch
+const ntpEpochOffset = 2208988800
+
+// Offset queries the NTP server at the given address, with the default port
+// if none is given, and returns the offset of the local clock from the
+// server's: a positive offset means the local clock is behind.
+func Offset(addr string, timeout time.Duration) (time.Duration, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, DefaultPort)
+	}
+
+	conn, err := net.DialTimeout("udp", addr, timeout)
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to NTP server: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, err
+	}
+
+	req := make([]byte, packetSize)
+	req[0] = version<<3 | modeClient
+	sent := time.Now()
+	putTimestamp(req[40:], sent)
+	if _, err := conn.Write(req); err != nil {
+		return 0, fmt.Errorf("failed to send NTP request: %v", err)
+	}
+
+	resp := make([]byte, packetSize)
+	n, err := conn.Read(resp)
+	received := time.Now()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read NTP response: %v", err)
+	}
+	if n < packetSize {
+		return 0, fmt.Errorf("short NTP response of %d bytes", n)
+	}
+
+	return offset(req, resp, sent, received)
+}
+
+// offset validates the response to the request and computes the clock
+// offset from the four timestamps of the exchange
+func offset(req, resp []byte, sent, received time.Time) (time.Duration, error) {
+	if mode := resp[0] & 0x7; mode != modeServer {
+		return 0, fmt.Errorf("unexpected NTP response mode %d", mode)
+	}
+	if stratum := resp[1]; stratum == 0 {
+		return 0, fmt.Errorf("NTP server refused the request")
+	}
+	// The server echoes the transmit timestamp of the request as the
+	// originate timestamp of the response
+	if binary.BigEndian.Uint64(resp[24:32]) != binary.BigEndian.Uint64(req[40:48]) {
+		return 0, fmt.Errorf("NTP response does not match the request")
+	}
+
+	serverReceived := getTimestamp(resp[32:40])
+	serverSent := getTimestamp(resp[40:48])
+	return (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2, nil
+}
+
+// putTimestamp encodes t as a 64 bit NTP timestamp
+func putTimestamp(b []byte, t time.Time) {
+	seconds := uint64(t.Unix() + ntpEpochOffset)
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	binary.BigEndian.PutUint64(b, seconds<<32|fraction)
+}
+
+// getTimestamp decodes a 64 bit NTP timestamp
+func getTimestamp(b []byte) time.Time {
+	ts := binary.BigEndian.Uint64(b)
+	seconds := int64(ts>>32) - ntpEpochOffset
+	nanos := int64((ts & 0xffffffff) * uint64(time.Second) >> 32)
+	return time.Unix(seconds, nanos)
+}
@@ -0,0 +1,58 @@
+package sntp
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// testServer answers SNTP requests with a clock ahead of the local one by
+// the given skew
+func testServer(t *testing.T, skew time.Duration, stratum byte) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		defer conn.Close()
+		buf := make([]byte, packetSize)
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil || n != packetSize {
+			return
+		}
+		resp := make([]byte, packetSize)
+		resp[0] = version<<3 | modeServer
+		resp[1] = stratum
+		copy(resp[24:32], buf[40:48])
+		putTimestamp(resp[32:40], time.Now().Add(skew))
+		putTimestamp(resp[40:48], time.Now().Add(skew))
+		conn.WriteTo(resp, addr)
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestOffset(t *testing.T) {
+	for _, skew := range []time.Duration{0, time.Minute, -90 * time.Second} {
+		offset, err := Offset(testServer(t, skew, 2), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := offset - skew; diff > 100*time.Millisecond || diff < -100*time.Millisecond {
+			t.Fatalf("expected an offset of %s, got %s", skew, offset)
+		}
+	}
+
+	// Kiss-of-death responses are rejected
+	if _, err := Offset(testServer(t, 0, 0), time.Second); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	now := time.Unix(1500000000, 123456789)
+	b := make([]byte, 8)
+	putTimestamp(b, now)
+	if got := getTimestamp(b); got.Sub(now) > time.Microsecond || now.Sub(got) > time.Microsecond {
+		t.Fatalf("expected %s, got %s", now, got)
+	}
+}
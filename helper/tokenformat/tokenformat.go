@@ -0,0 +1,123 @@
+// Package tokenformat defines the formats of the token IDs Vault issues.
+//
+// Tokens in the versioned format carry a recognizable prefix and the version
+// of the format, followed by a random body, e.g. "hvs.1.<body>", so that
+// secret scanners can find leaked tokens and so that later formats can be
+// told apart from earlier ones. Legacy tokens are UUIDs; they, and tokens
+// with custom IDs, keep being accepted since tokens are looked up by ID
+// whatever their format.
+package tokenformat
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-uuid"
+)
+
+const (
+	// Prefix starts the IDs of tokens in the versioned format
+	Prefix = "hvs."
+
+	// CurrentVersion is the version of the format tokens are issued in
+	CurrentVersion = 1
+
+	// FormatUUID issues legacy UUID tokens
+	FormatUUID = "uuid"
+
+	// FormatV1 issues tokens in version 1 of the versioned format, with a
+	// body of 32 base62 characters
+	FormatV1 = "v1"
+
+	// v1BodyLength is the length of the body of version 1 tokens
+	v1BodyLength = 32
+
+	alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// Pattern matches tokens in the versioned format, whatever their version,
+// for use by secret scanners
+var Pattern = regexp.MustCompile(`\bhvs\.[1-9][0-9]*\.[0-9A-Za-z]{24,}\b`)
+
+// Formats returns the formats tokens can be issued in
+func Formats() []string {
+	return []string{FormatV1, FormatUUID}
+}
+
+// ValidFormat returns true if tokens can be issued in the given format. The
+// empty format selects the default, FormatV1.
+func ValidFormat(format string) bool {
+	switch format {
+	case "", FormatV1, FormatUUID:
+		return true
+	}
+	return false
+}
+
+// Generate returns a new token ID in the given format, reading randomness
+// from entropy
+func Generate(format string, entropy io.Reader) (string, error) {
+	switch format {
+	case FormatUUID:
+		buf := make([]byte, 16)
+		if _, err := io.ReadFull(entropy, buf); err != nil {
+			return "", fmt.Errorf("failed to read random bytes: %v", err)
+		}
+		return uuid.FormatUUID(buf)
+	case "", FormatV1:
+		body, err := randomBase62(entropy, v1BodyLength)
+		if err != nil {
+			return "", err
+		}
+		return Prefix + strconv.Itoa(CurrentVersion) + "." + body, nil
+	default:
+		return "", fmt.Errorf("unknown token format %q", format)
+	}
+}
+
+// Version returns the version of the format of the token, or 0 if the token
+// is not in the versioned format, e.g. a legacy UUID token
+func Version(token string) int {
+	if !strings.HasPrefix(token, Prefix) {
+		return 0
+	}
+	rest := token[len(Prefix):]
+	i := strings.IndexByte(rest, '.')
+	if i <= 0 || i == len(rest)-1 {
+		return 0
+	}
+	version, err := strconv.Atoi(rest[:i])
+	if err != nil || version <= 0 || rest[0] == '0' {
+		return 0
+	}
+	return version
+}
+
+// randomBase62 returns n characters of the base62 alphabet read uniformly
+// from entropy
+func randomBase62(entropy io.Reader, n int) (string, error) {
+	// Bytes above the largest multiple of the alphabet size are discarded so
+	// that every character is equally likely
+	const max = 255 - 256%len(alphabet)
+
+	out := make([]byte, 0, n)
+	buf := make([]byte, n)
+	for len(out) < n {
+		if _, err := io.ReadFull(entropy, buf); err != nil {
+			return "", fmt.Errorf("failed to read random bytes: %v", err)
+		}
+		for _, b := range buf {
+			if int(b) > max {
+				continue
+			}
+			out = append(out, alphabet[int(b)%len(alphabet)])
+			if len(out) == n {
+				break
+			}
+		}
+	}
+	return string(out), nil
+}
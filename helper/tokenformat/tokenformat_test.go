@@ -0,0 +1,75 @@
+package tokenformat
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	for _, format := range []string{"", FormatV1} {
+		token, err := Generate(format, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(token, "hvs.1.") || len(token) != len("hvs.1.")+v1BodyLength {
+			t.Fatalf("bad token: %q", token)
+		}
+		if Version(token) != CurrentVersion {
+			t.Fatalf("bad version: %d", Version(token))
+		}
+		if !Pattern.MatchString("token=" + token + ";") {
+			t.Fatalf("pattern does not match %q", token)
+		}
+	}
+
+	token, err := Generate(FormatUUID, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) != 36 || Version(token) != 0 || Pattern.MatchString(token) {
+		t.Fatalf("bad legacy token: %q", token)
+	}
+
+	if _, err := Generate("v0", rand.Reader); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestGenerate_discardsBiasedBytes(t *testing.T) {
+	// Bytes above 247 are skipped, so the body is taken from the bytes after
+	// the first 32
+	entropy := append(bytes.Repeat([]byte{255}, v1BodyLength), bytes.Repeat([]byte{63}, v1BodyLength)...)
+	token, err := Generate(FormatV1, bytes.NewReader(entropy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "hvs.1."+strings.Repeat("1", v1BodyLength) {
+		t.Fatalf("bad token: %q", token)
+	}
+
+	if _, err := Generate(FormatV1, bytes.NewReader(nil)); err == nil {
+		t.Fatal("expected an error without entropy")
+	}
+}
+
+func TestVersion(t *testing.T) {
+	cases := map[string]int{
+		"hvs.1.abcdefghijklmnopqrstuvwxyz012345": 1,
+		"hvs.12.abc":                             12,
+		"hvs.0.abc":                              0,
+		"hvs.01.abc":                             0,
+		"hvs.1.":                                 0,
+		"hvs..abc":                               0,
+		"hvs.x.abc":                              0,
+		"hvs.1":                                  0,
+		"6a2bde8f-7c7a-3b54-1cf1-0ab1f0b4b1b7":   0,
+		"custom-root":                            0,
+	}
+	for token, expected := range cases {
+		if version := Version(token); version != expected {
+			t.Fatalf("%q: expected version %d, got %d", token, expected, version)
+		}
+	}
+}
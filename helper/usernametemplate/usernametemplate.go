@@ -0,0 +1,141 @@
+// Package usernametemplate generates the usernames of dynamic credentials
+// from Go templates, so that they can satisfy the length, prefix and
+// character set requirements of the systems the credentials are created in.
+//
+// Templates are given the name of the role as .RoleName and the display
+// name of the requesting token as .DisplayName, and can use the following
+// functions:
+//
+//	random N          N random alphanumeric characters
+//	unix_time         the current Unix time in seconds
+//	truncate N S      the first N characters of S
+//	lowercase S       S in lower case
+//	uppercase S       S in upper case
+//	replace OLD NEW S S with every OLD replaced by NEW
+//
+// For example: {{ printf "v-%s-%s" (.RoleName | truncate 8) (random 12) }}
+package usernametemplate
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// maxRandomLength bounds the length of the random strings templates can ask
+// for
+const maxRandomLength = 256
+
+// Data is the data usernames are generated from
+type Data struct {
+	RoleName    string
+	DisplayName string
+}
+
+// Template generates usernames
+type Template struct {
+	tmpl *template.Template
+}
+
+// Parse parses the template and checks that it generates a non-empty
+// username from sample data
+func Parse(text string) (*Template, error) {
+	tmpl, err := template.New("username_template").
+		Option("missingkey=error").
+		Funcs(funcs).
+		Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid username template: %v", err)
+	}
+
+	t := &Template{tmpl: tmpl}
+	if _, err := t.Generate(Data{RoleName: "role", DisplayName: "token"}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Generate returns the username generated from the data
+func (t *Template) Generate(data Data) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to generate username: %v", err)
+	}
+	username := strings.TrimSpace(buf.String())
+	if username == "" {
+		return "", errors.New("username template generated an empty username")
+	}
+	return username, nil
+}
+
+// Generate parses the template and returns the username it generates from
+// the data
+func Generate(text string, data Data) (string, error) {
+	t, err := Parse(text)
+	if err != nil {
+		return "", err
+	}
+	return t.Generate(data)
+}
+
+var funcs = template.FuncMap{
+	"random":    random,
+	"unix_time": unixTime,
+	"truncate":  truncate,
+	"lowercase": strings.ToLower,
+	"uppercase": strings.ToUpper,
+	"replace":   replace,
+}
+
+func random(n int) (string, error) {
+	if n <= 0 || n > maxRandomLength {
+		return "", fmt.Errorf("random length must be between 1 and %d", maxRandomLength)
+	}
+
+	// Bytes above the largest multiple of the alphabet size are discarded so
+	// that every character is equally likely
+	const max = 255 - 256%len(alphabet)
+
+	out := make([]byte, 0, n)
+	buf := make([]byte, n)
+	for len(out) < n {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) > max {
+				continue
+			}
+			out = append(out, alphabet[int(b)%len(alphabet)])
+			if len(out) == n {
+				break
+			}
+		}
+	}
+	return string(out), nil
+}
+
+func unixTime() string {
+	return strconv.FormatInt(time.Now().Unix(), 10)
+}
+
+func truncate(n int, s string) (string, error) {
+	if n < 0 {
+		return "", errors.New("truncate length cannot be negative")
+	}
+	if len(s) > n {
+		return s[:n], nil
+	}
+	return s, nil
+}
+
+func replace(old, new, s string) string {
+	return strings.Replace(s, old, new, -1)
+}
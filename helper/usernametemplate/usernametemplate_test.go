@@ -0,0 +1,47 @@
+package usernametemplate
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	data := Data{
+		RoleName:    "readonly-role-with-a-long-name",
+		DisplayName: "LDAP-Alice",
+	}
+
+	cases := []struct {
+		tmpl    string
+		pattern string
+	}{
+		{`{{.RoleName}}`, `^readonly-role-with-a-long-name$`},
+		{`v_{{.RoleName | truncate 8}}_{{.DisplayName | lowercase | replace "-" "_"}}`, `^v_readonly_ldap_alice$`},
+		{`{{printf "app-%s-%s" (random 12) unix_time}}`, `^app-[0-9A-Za-z]{12}-[0-9]+$`},
+		{`{{uppercase .RoleName | truncate 4}}{{random 4}}`, `^READ[0-9A-Za-z]{4}$`},
+	}
+	for _, tc := range cases {
+		username, err := Generate(tc.tmpl, data)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.tmpl, err)
+		}
+		if !regexp.MustCompile(tc.pattern).MatchString(username) {
+			t.Fatalf("%s: bad: %q", tc.tmpl, username)
+		}
+	}
+}
+
+func TestParse_invalid(t *testing.T) {
+	for _, tmpl := range []string{
+		`{{.RoleName`,
+		`{{.Unknown}}`,
+		`{{random 0}}`,
+		`{{random 1000}}`,
+		`{{"" | truncate 4}}`,
+		`   `,
+	} {
+		if _, err := Parse(tmpl); err == nil {
+			t.Fatalf("%q: expected an error", tmpl)
+		}
+	}
+}
@@ -10,14 +10,14 @@ import (
 )
 
 var preflightHeaders = map[string]string{
-	"Access-Control-Allow-Headers": "*",
-	"Access-Control-Max-Age":       "300",
+	"Access-Control-Max-Age": "300",
 }
 
 var allowedMethods = []string{
 	http.MethodDelete,
 	http.MethodGet,
 	http.MethodOptions,
+	http.MethodPatch,
 	http.MethodPost,
 	http.MethodPut,
 	"LIST", // LIST is not an official HTTP method, but Vault supports it.
@@ -50,12 +50,24 @@ func wrapCORSHandler(h http.Handler, core *vault.Core) http.Handler {
 			return
 		}
 
+		// Return a 403 if the preflight request asks for headers that are
+		// not allowed
+		if req.Method == http.MethodOptions {
+			for _, header := range strutil.ParseDedupAndSortStrings(req.Header.Get("Access-Control-Request-Headers"), ",") {
+				if !corsConf.IsValidHeader(header) {
+					respondError(w, http.StatusForbidden, fmt.Errorf("header %q not allowed", header))
+					return
+				}
+			}
+		}
+
 		w.Header().Set("Access-Control-Allow-Origin", origin)
 		w.Header().Set("Vary", "Origin")
 
 		// apply headers for preflight requests
 		if req.Method == http.MethodOptions {
 			w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowedMethods, ","))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(corsConf.AllowedHeadersList(), ","))
 
 			for k, v := range preflightHeaders {
 				w.Header().Set(k, v)
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ForwardedForHeaderName is the name of the header in which proxies record
+// the addresses of the clients they proxy requests for
+const ForwardedForHeaderName = "X-Forwarded-For"
+
+// WrapForwardedForHandler wraps the handler so that the client address of
+// requests proxied by the given trusted addresses is taken from the
+// X-Forwarded-For header. The last address of the header is used, as it is
+// the one the trusted proxy appended; the previous ones may have been set
+// by the client. The header of requests from other addresses is ignored.
+func WrapForwardedForHandler(h http.Handler, authorizedAddrs []*net.IPNet) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers := r.Header[ForwardedForHeaderName]
+		if len(headers) == 0 {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		host, port, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil || !forwardedForAuthorized(net.ParseIP(host), authorizedAddrs) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		// The header may be repeated, or hold a comma separated list
+		addrs := strings.Split(headers[len(headers)-1], ",")
+		client := net.ParseIP(strings.TrimSpace(addrs[len(addrs)-1]))
+		if client == nil {
+			respondError(w, http.StatusBadRequest, fmt.Errorf("invalid %s header", ForwardedForHeaderName))
+			return
+		}
+
+		r.RemoteAddr = net.JoinHostPort(client.String(), port)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// forwardedForAuthorized returns whether the address is one of the proxies
+// trusted to set the X-Forwarded-For header
+func forwardedForAuthorized(ip net.IP, authorizedAddrs []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, authorized := range authorizedAddrs {
+		if authorized.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestHandler_ForwardedFor(t *testing.T) {
+	core, _, root := vault.TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+	req.Data["token_bound_cidrs"] = "10.1.0.0/16"
+	req.ClientToken = root
+	resp, err := core.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	token := resp.Auth.ClientToken
+
+	_, proxies, err := net.ParseCIDR("127.0.0.0/8")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	handler := WrapForwardedForHandler(Handler(core), []*net.IPNet{proxies})
+
+	lookupSelf := func(remoteAddr, forwardedFor string) int {
+		r := httptest.NewRequest("GET", "/v1/auth/token/lookup-self", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set(AuthHeaderName, token)
+		if forwardedFor != "" {
+			r.Header.Set(ForwardedForHeaderName, forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	cases := []struct {
+		remoteAddr   string
+		forwardedFor string
+		expected     int
+	}{
+		// Proxied by a trusted address, for a client in the bound blocks
+		{"127.0.0.1:4242", "10.1.2.3", http.StatusOK},
+		// Only the address appended by the proxy is used
+		{"127.0.0.1:4242", "10.1.2.3, 192.168.0.1", http.StatusForbidden},
+		{"127.0.0.1:4242", "192.168.0.1, 10.1.2.3", http.StatusOK},
+		// The header of untrusted addresses is ignored
+		{"192.168.0.1:4242", "10.1.2.3", http.StatusForbidden},
+		{"10.1.2.3:4242", "", http.StatusOK},
+		{"127.0.0.1:4242", "", http.StatusForbidden},
+		{"127.0.0.1:4242", "nope", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		if code := lookupSelf(tc.remoteAddr, tc.forwardedFor); code != tc.expected {
+			t.Fatalf("remote address %q, forwarded for %q: expected %d, got %d", tc.remoteAddr, tc.forwardedFor, tc.expected, code)
+		}
+	}
+}
@@ -35,7 +35,7 @@ func TestHTTP_Fallback_Bad_Address(t *testing.T) {
 
 	cluster := vault.NewTestCluster(t, coreConfig, true)
 	cluster.StartListeners()
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 	cores := cluster.Cores
 
 	cores[0].Handler.Handle("/", Handler(cores[0].Core))
@@ -87,7 +87,7 @@ func TestHTTP_Fallback_Disabled(t *testing.T) {
 
 	cluster := vault.NewTestCluster(t, coreConfig, true)
 	cluster.StartListeners()
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 	cores := cluster.Cores
 
 	cores[0].Handler.Handle("/", Handler(cores[0].Core))
@@ -148,7 +148,7 @@ func testHTTP_Forwarding_Stress_Common(t *testing.T, parallel bool, num uint64)
 
 	cluster := vault.NewTestCluster(t, coreConfig, true)
 	cluster.StartListeners()
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 	cores := cluster.Cores
 
 	cores[0].Handler.Handle("/", Handler(cores[0].Core))
@@ -456,7 +456,7 @@ func TestHTTP_Forwarding_ClientTLS(t *testing.T) {
 
 	cluster := vault.NewTestCluster(t, coreConfig, true)
 	cluster.StartListeners()
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 	cores := cluster.Cores
 
 	cores[0].Handler.Handle("/", Handler(cores[0].Core))
@@ -566,9 +566,57 @@ func TestHTTP_Forwarding_ClientTLS(t *testing.T) {
 	}
 }
 
+// This tests that an auth backend enabled on the active node is visible and
+// usable through the standbys
+func TestHTTP_Forwarding_MountAuthBackend(t *testing.T) {
+	coreConfig := &vault.CoreConfig{
+		CredentialBackends: map[string]logical.Factory{
+			"cert": credCert.Factory,
+		},
+	}
+
+	cluster := vault.NewTestCluster(t, coreConfig, true)
+	cluster.StartListeners()
+	defer cluster.Cleanup()
+	cores := cluster.Cores
+
+	cores[0].Handler.Handle("/", Handler(cores[0].Core))
+	cores[1].Handler.Handle("/", Handler(cores[1].Core))
+	cores[2].Handler.Handle("/", Handler(cores[2].Core))
+
+	vault.TestWaitActive(t, cores[0].Core)
+
+	cores[0].MountAuthBackend(t, "cert", "cert", map[string]interface{}{
+		"default_lease_ttl": "1h",
+	})
+
+	for _, core := range cores[1:] {
+		client := core.Client
+		auths, err := client.Sys().ListAuth()
+		if err != nil {
+			t.Fatal(err)
+		}
+		auth, ok := auths["cert/"]
+		if !ok {
+			t.Fatalf("cert not enabled: %#v", auths)
+		}
+		if auth.Type != "cert" || auth.Config.DefaultLeaseTTL != 3600 {
+			t.Fatalf("bad: %#v", auth)
+		}
+
+		_, err = client.Logical().Write("auth/cert/certs/test", map[string]interface{}{
+			"certificate": vault.TestClusterCACert,
+			"policies":    "default",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
 func TestHTTP_Forwarding_HelpOperation(t *testing.T) {
 	cluster := vault.NewTestCluster(t, &vault.CoreConfig{}, true)
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 	cluster.StartListeners()
 	cores := cluster.Cores
 
@@ -591,3 +639,41 @@ func TestHTTP_Forwarding_HelpOperation(t *testing.T) {
 	testHelp(cores[0].Client)
 	testHelp(cores[1].Client)
 }
+
+func TestHTTP_Forwarding_RequestID(t *testing.T) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{}, true)
+	defer cluster.Cleanup()
+	cluster.StartListeners()
+	cores := cluster.Cores
+
+	cores[0].Handler.Handle("/", Handler(cores[0].Core))
+	cores[1].Handler.Handle("/", Handler(cores[1].Core))
+	cores[2].Handler.Handle("/", Handler(cores[2].Core))
+
+	vault.TestWaitActive(t, cores[0].Core)
+	cluster.WaitForStandby(t, cores[1])
+
+	client := cores[1].Client
+	if _, err := client.Logical().Write("secret/foo", map[string]interface{}{"data": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The active node handles the request forwarded by the standby with the
+	// ID the standby gave it
+	resp, err := client.RawRequest(client.NewRequest("GET", "/v1/secret/foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	ids := resp.Header[RequestIDHeaderName]
+	if len(ids) != 1 {
+		t.Fatalf("bad: %#v", resp.Header)
+	}
+	var secret api.Secret
+	if err := resp.DecodeJSON(&secret); err != nil {
+		t.Fatal(err)
+	}
+	if secret.RequestID != ids[0] {
+		t.Fatalf("bad: %q %q", secret.RequestID, ids[0])
+	}
+}
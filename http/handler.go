@@ -6,7 +6,9 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/helper/consts"
@@ -32,9 +34,18 @@ const (
 	// not to use request forwarding
 	NoRequestForwardingHeaderName = "X-Vault-No-Request-Forwarding"
 
-	// MaxRequestSize is the maximum accepted request size. This is to prevent
-	// a denial of service attack where no Content-Length is provided and the server
-	// is fed ever more data until it exhausts memory.
+	// RequestIDHeaderName is the name of the response header containing the
+	// ID of the request, which is also that of its audit entries
+	RequestIDHeaderName = "X-Vault-Request-Id"
+
+	// MFAHeaderName is the name of the header containing the credentials
+	// for MFA methods, in the form "method[:passcode]"; it can be repeated
+	MFAHeaderName = "X-Vault-MFA"
+
+	// MaxRequestSize is the maximum accepted request size, unless the
+	// listener sets its own. This is to prevent a denial of service attack
+	// where no Content-Length is provided and the server is fed ever more
+	// data until it exhausts memory.
 	MaxRequestSize = 32 * 1024 * 1024
 )
 
@@ -53,12 +64,15 @@ func Handler(core *vault.Core) http.Handler {
 	mux.Handle("/v1/sys/leases/", handleRequestForwarding(core, handleLogical(core, false, nil)))
 	mux.Handle("/v1/sys/leader", handleSysLeader(core))
 	mux.Handle("/v1/sys/health", handleSysHealth(core))
+	mux.Handle("/v1/sys/monitor", handleSysMonitor(core))
+	mux.Handle("/v1/sys/pprof/", handleSysPprof(core))
 	mux.Handle("/v1/sys/generate-root/attempt", handleRequestForwarding(core, handleSysGenerateRootAttempt(core)))
 	mux.Handle("/v1/sys/generate-root/update", handleRequestForwarding(core, handleSysGenerateRootUpdate(core)))
 	mux.Handle("/v1/sys/rekey/init", handleRequestForwarding(core, handleSysRekeyInit(core, false)))
 	mux.Handle("/v1/sys/rekey/update", handleRequestForwarding(core, handleSysRekeyUpdate(core, false)))
 	mux.Handle("/v1/sys/rekey-recovery-key/init", handleRequestForwarding(core, handleSysRekeyInit(core, true)))
 	mux.Handle("/v1/sys/rekey-recovery-key/update", handleRequestForwarding(core, handleSysRekeyUpdate(core, true)))
+	mux.Handle("/v1/sys/rekey-recovery-key/verify", handleRequestForwarding(core, handleSysRekeyRecoveryKeyVerify(core)))
 	mux.Handle("/v1/sys/wrapping/lookup", handleRequestForwarding(core, handleLogical(core, false, wrappingVerificationFunc)))
 	mux.Handle("/v1/sys/wrapping/rewrap", handleRequestForwarding(core, handleLogical(core, false, wrappingVerificationFunc)))
 	mux.Handle("/v1/sys/wrapping/unwrap", handleRequestForwarding(core, handleLogical(core, false, wrappingVerificationFunc)))
@@ -72,7 +86,7 @@ func Handler(core *vault.Core) http.Handler {
 
 	// Wrap the help wrapped handler with another layer with a generic
 	// handler
-	genericWrappedHandler := wrapGenericHandler(corsWrappedHandler)
+	genericWrappedHandler := wrapGenericHandler(corsWrappedHandler, core)
 
 	return genericWrappedHandler
 }
@@ -80,11 +94,25 @@ func Handler(core *vault.Core) http.Handler {
 // wrapGenericHandler wraps the handler with an extra layer of handler where
 // tasks that should be commonly handled for all the requests and/or responses
 // are performed.
-func wrapGenericHandler(h http.Handler) http.Handler {
+func wrapGenericHandler(h http.Handler, core *vault.Core) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Set the Cache-Control header for all the responses returned
 		// by Vault
 		w.Header().Set("Cache-Control", "no-store")
+
+		r, err := withRequestID(w, r)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, errwrap.Wrapf("failed to generate identifier for the request: {{err}}", err))
+			return
+		}
+
+		// Reject large requests before reading them if memory is running
+		// low
+		if err := core.CheckRequestSize(r.ContentLength); err != nil {
+			respondError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+
 		h.ServeHTTP(w, r)
 		return
 	})
@@ -125,10 +153,13 @@ func stripPrefix(prefix, path string) (string, bool) {
 }
 
 func parseRequest(r *http.Request, w http.ResponseWriter, out interface{}) error {
-	// Limit the maximum number of bytes to MaxRequestSize to protect
-	// against an indefinite amount of data being read.
-	limit := http.MaxBytesReader(w, r.Body, MaxRequestSize)
-	err := jsonutil.DecodeJSONFromReader(limit, out)
+	// Limit the maximum number of bytes to the maximum request size of the
+	// listener to protect against an indefinite amount of data being read.
+	body := r.Body
+	if limit := maxRequestSize(r); limit > 0 {
+		body = http.MaxBytesReader(w, r.Body, limit)
+	}
+	err := jsonutil.DecodeJSONFromReader(body, out)
 	if err != nil && err != io.EOF {
 		return errwrap.Wrapf("failed to parse JSON input: {{err}}", err)
 	}
@@ -193,6 +224,11 @@ func handleRequestForwarding(core *vault.Core, handler http.Handler) http.Handle
 
 		if header != nil {
 			for k, v := range header {
+				// The active node responds with the ID this node gave
+				// the request, which is already set
+				if k == RequestIDHeaderName {
+					continue
+				}
 				for _, j := range v {
 					w.Header().Add(k, j)
 				}
@@ -313,13 +349,42 @@ func requestWrapInfo(r *http.Request, req *logical.Request) (*logical.Request, e
 	return req, nil
 }
 
+// requestMFACreds adds the MFA credentials of the request headers to the
+// logical request
+func requestMFACreds(r *http.Request, req *logical.Request) *logical.Request {
+	values := r.Header[http.CanonicalHeaderKey(MFAHeaderName)]
+	if len(values) == 0 {
+		return req
+	}
+
+	req.MFACreds = make(map[string]string, len(values))
+	for _, v := range values {
+		parts := strings.SplitN(v, ":", 2)
+		passcode := ""
+		if len(parts) == 2 {
+			passcode = parts[1]
+		}
+		req.MFACreds[strings.TrimSpace(parts[0])] = strings.TrimSpace(passcode)
+	}
+	return req
+}
+
 func respondError(w http.ResponseWriter, status int, err error) {
 	logical.AdjustErrorStatusCode(&status, err)
 
+	// Tell clients rejected for having too many requests in flight when to
+	// retry
+	if status == http.StatusTooManyRequests {
+		w.Header().Set("Retry-After", strconv.Itoa(int(vault.ClientLimitRetryAfter/time.Second)))
+	}
+
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(status)
 
-	resp := &ErrorResponse{Errors: make([]string, 0, 1)}
+	resp := &ErrorResponse{
+		Errors:    make([]string, 0, 1),
+		RequestID: w.Header().Get(RequestIDHeaderName),
+	}
 	if err != nil {
 		resp.Errors = append(resp.Errors, err.Error())
 	}
@@ -352,4 +417,8 @@ func respondOk(w http.ResponseWriter, body interface{}) {
 
 type ErrorResponse struct {
 	Errors []string `json:"errors"`
+
+	// RequestID is the ID of the request, to correlate the error with its
+	// audit entries and logs
+	RequestID string `json:"request_id,omitempty"`
 }
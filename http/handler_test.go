@@ -6,9 +6,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/vault"
@@ -21,7 +23,7 @@ func TestHandler_cors(t *testing.T) {
 
 	// Enable CORS and allow from any origin for testing.
 	corsConfig := core.CORSConfig()
-	err := corsConfig.Enable([]string{addr})
+	err := corsConfig.Enable([]string{addr}, []string{"X-Custom-Header"})
 	if err != nil {
 		t.Fatalf("Error enabling CORS: %s", err)
 	}
@@ -78,7 +80,7 @@ func TestHandler_cors(t *testing.T) {
 	//
 	expHeaders := map[string]string{
 		"Access-Control-Allow-Origin":  addr,
-		"Access-Control-Allow-Headers": "*",
+		"Access-Control-Allow-Headers": strings.Join(vault.StdAllowedHeaders, ",") + ",X-Custom-Header",
 		"Access-Control-Max-Age":       "300",
 		"Vary": "Origin",
 	}
@@ -93,6 +95,32 @@ func TestHandler_cors(t *testing.T) {
 			t.Fatalf("bad:\nExpected: %#v\nActual: %#v\n", expected, actual)
 		}
 	}
+
+	// Server SHOULD accept the standard and configured headers.
+	req.Header.Set("Access-Control-Request-Headers", "x-vault-token, X-Custom-Header")
+
+	client = cleanhttp.DefaultClient()
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Bad status:\nexpected: 200 OK\nactual: %s", resp.Status)
+	}
+
+	// Server should NOT accept arbitrary headers.
+	req.Header.Set("Access-Control-Request-Headers", "X-Vault-Token,X-Other-Header")
+
+	client = cleanhttp.DefaultClient()
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Bad status:\nexpected: 403 Forbidden\nactual: %s", resp.Status)
+	}
 }
 
 func TestHandler_CacheControlNoStore(t *testing.T) {
@@ -163,7 +191,8 @@ func TestSysMounts_headerAuth(t *testing.T) {
 					"max_lease_ttl":     json.Number("0"),
 					"force_no_cache":    false,
 				},
-				"local": false,
+				"local":     false,
+				"seal_wrap": false,
 			},
 			"sys/": map[string]interface{}{
 				"description": "system endpoints used for control, policy and debugging",
@@ -173,7 +202,8 @@ func TestSysMounts_headerAuth(t *testing.T) {
 					"max_lease_ttl":     json.Number("0"),
 					"force_no_cache":    false,
 				},
-				"local": false,
+				"local":     false,
+				"seal_wrap": false,
 			},
 			"cubbyhole/": map[string]interface{}{
 				"description": "per-token private secret storage",
@@ -183,7 +213,8 @@ func TestSysMounts_headerAuth(t *testing.T) {
 					"max_lease_ttl":     json.Number("0"),
 					"force_no_cache":    false,
 				},
-				"local": true,
+				"local":     true,
+				"seal_wrap": false,
 			},
 		},
 		"secret/": map[string]interface{}{
@@ -194,7 +225,8 @@ func TestSysMounts_headerAuth(t *testing.T) {
 				"max_lease_ttl":     json.Number("0"),
 				"force_no_cache":    false,
 			},
-			"local": false,
+			"local":     false,
+			"seal_wrap": false,
 		},
 		"sys/": map[string]interface{}{
 			"description": "system endpoints used for control, policy and debugging",
@@ -204,7 +236,8 @@ func TestSysMounts_headerAuth(t *testing.T) {
 				"max_lease_ttl":     json.Number("0"),
 				"force_no_cache":    false,
 			},
-			"local": false,
+			"local":     false,
+			"seal_wrap": false,
 		},
 		"cubbyhole/": map[string]interface{}{
 			"description": "per-token private secret storage",
@@ -214,7 +247,8 @@ func TestSysMounts_headerAuth(t *testing.T) {
 				"max_lease_ttl":     json.Number("0"),
 				"force_no_cache":    false,
 			},
-			"local": true,
+			"local":     true,
+			"seal_wrap": false,
 		},
 	}
 	testResponseStatus(t, resp, 200)
@@ -287,6 +321,33 @@ func TestSysMounts_headerAuth_Wrapped(t *testing.T) {
 	}
 }
 
+func TestHandler_unixSocket(t *testing.T) {
+	cluster := vault.NewTestClusterWithOptions(t, nil, &vault.TestClusterOptions{
+		UnixSockets: true,
+	})
+	defer cluster.Cleanup()
+	core := cluster.Cores[0]
+	core.Handler.Handle("/", Handler(core.Core))
+	cluster.StartListeners()
+	vault.TestWaitActive(t, core.Core)
+
+	config := api.DefaultConfig()
+	config.Address = "unix://" + core.SocketPath
+	client, err := api.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken(core.Root)
+
+	secret, err := client.Auth().Token().LookupSelf()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["id"] != core.Root {
+		t.Fatalf("bad: %#v", secret.Data)
+	}
+}
+
 func TestHandler_sealed(t *testing.T) {
 	core, _, token := vault.TestCoreUnsealed(t)
 	ln, addr := TestServer(t, core)
@@ -301,6 +362,47 @@ func TestHandler_sealed(t *testing.T) {
 	testResponseStatus(t, resp, 503)
 }
 
+func TestHandler_requestID(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	resp := testHttpPut(t, token, addr+"/v1/secret/foo", map[string]interface{}{
+		"data": "bar",
+	})
+	testResponseStatus(t, resp, 204)
+	if resp.Header.Get(RequestIDHeaderName) == "" {
+		t.Fatalf("no request ID: %#v", resp.Header)
+	}
+
+	// The ID of the response is that of the request
+	resp = testHttpGet(t, token, addr+"/v1/secret/foo")
+	testResponseStatus(t, resp, 200)
+	var actual map[string]interface{}
+	testResponseBody(t, resp, &actual)
+	if id := resp.Header.Get(RequestIDHeaderName); id == "" || actual["request_id"] != id {
+		t.Fatalf("bad: %q %#v", id, actual)
+	}
+
+	// Errors report it too, and clients cannot choose it
+	req, err := http.NewRequest("GET", addr+"/v1/secret/foo", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	req.Header.Set(AuthHeaderName, "invalid")
+	req.Header.Set(RequestIDHeaderName, "chosen")
+	resp, err = cleanhttp.DefaultClient().Do(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 403)
+	var errResp ErrorResponse
+	testResponseBody(t, resp, &errResp)
+	if id := resp.Header.Get(RequestIDHeaderName); id == "" || id == "chosen" || errResp.RequestID != id {
+		t.Fatalf("bad: %q %#v", id, errResp)
+	}
+}
+
 func TestHandler_error(t *testing.T) {
 	w := httptest.NewRecorder()
 
@@ -330,4 +432,15 @@ func TestHandler_error(t *testing.T) {
 		t.Fatalf("expected 503, got %d", w3.Code)
 	}
 
+	// Clients rejected by the client limits are told when to retry
+	w4 := httptest.NewRecorder()
+
+	respondError(w4, 500, vault.ErrClientLimit)
+
+	if w4.Code != 429 {
+		t.Fatalf("expected 429, got %d", w4.Code)
+	}
+	if w4.Header().Get("Retry-After") != "1" {
+		t.Fatalf("bad Retry-After: %q", w4.Header().Get("Retry-After"))
+	}
 }
@@ -31,7 +31,14 @@ func handleHelp(core *vault.Core, w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	id, err := requestID(req)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	lreq := requestAuth(core, req, &logical.Request{
+		ID:         id,
 		Operation:  logical.HelpOperation,
 		Path:       path,
 		Connection: getConnection(req),
@@ -4,12 +4,12 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/hashicorp/errwrap"
-	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/vault"
 )
@@ -28,6 +28,7 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 
 	// Determine the operation
 	var op logical.Operation
+	var data map[string]interface{}
 	switch r.Method {
 	case "DELETE":
 		op = logical.DeleteOperation
@@ -45,8 +46,13 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 				op = logical.ListOperation
 			}
 		}
+		if op == logical.ReadOperation {
+			data = parseQuery(queryVals)
+		}
 	case "POST", "PUT":
 		op = logical.UpdateOperation
+	case "PATCH":
+		op = logical.PatchOperation
 	case "LIST":
 		op = logical.ListOperation
 	case "OPTIONS":
@@ -61,20 +67,23 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 	}
 
 	// Parse the request if we can
-	var data map[string]interface{}
-	if op == logical.UpdateOperation {
+	if op == logical.UpdateOperation || op == logical.PatchOperation {
 		err := parseRequest(r, w, &data)
 		if err == io.EOF {
 			data = nil
 			err = nil
 		}
 		if err != nil {
+			// Bodies without a Content-Length are only found to exceed the
+			// maximum request size when read
+			if errwrap.Contains(err, "http: request body too large") {
+				auditRejectedRequest(core, r, err)
+			}
 			return nil, http.StatusBadRequest, err
 		}
 	}
 
-	var err error
-	request_id, err := uuid.GenerateUUID()
+	request_id, err := requestID(r)
 	if err != nil {
 		return nil, http.StatusBadRequest, errwrap.Wrapf("failed to generate identifier for the request: {{err}}", err)
 	}
@@ -92,10 +101,34 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 	if err != nil {
 		return nil, http.StatusBadRequest, errwrap.Wrapf("error parsing X-Vault-Wrap-TTL header: {{err}}", err)
 	}
+	req = requestMFACreds(r, req)
 
 	return req, 0, nil
 }
 
+// parseQuery returns the query parameters of a read request as its data,
+// or nil if there are none
+func parseQuery(values url.Values) map[string]interface{} {
+	data := map[string]interface{}{}
+	for k, v := range values {
+		// The help parameter is handled before the request is built
+		if k == "help" {
+			continue
+		}
+		switch len(v) {
+		case 0:
+		case 1:
+			data[k] = v[0]
+		default:
+			data[k] = v
+		}
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return data
+}
+
 func handleLogical(core *vault.Core, injectDataIntoTopLevel bool, prepareRequestCallback PrepareRequestFunc) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		req, statusCode, err := buildLogicalRequest(core, w, r)
@@ -259,6 +292,7 @@ func getConnection(r *http.Request) (connection *logical.Connection) {
 	connection = &logical.Connection{
 		RemoteAddr: remoteAddr,
 		ConnState:  r.TLS,
+		UserAgent:  r.UserAgent(),
 	}
 	return
 }
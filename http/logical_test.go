@@ -64,6 +64,41 @@ func TestLogical(t *testing.T) {
 	testResponseStatus(t, resp, 404)
 }
 
+func TestLogical_Patch(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpData(t, "PATCH", token, addr+"/v1/secret/foo", map[string]interface{}{
+		"data": "bar",
+	}, false)
+	testResponseStatus(t, resp, 400)
+
+	resp = testHttpPut(t, token, addr+"/v1/secret/foo", map[string]interface{}{
+		"data":  "bar",
+		"other": "baz",
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpData(t, "PATCH", token, addr+"/v1/secret/foo", map[string]interface{}{
+		"data":  "zip",
+		"other": nil,
+	}, false)
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpGet(t, token, addr+"/v1/secret/foo")
+	var actual map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	expected := map[string]interface{}{
+		"data": "zip",
+	}
+	if !reflect.DeepEqual(actual["data"], expected) {
+		t.Fatalf("bad: %#v", actual["data"])
+	}
+}
+
 func TestLogical_noExist(t *testing.T) {
 	core, _, token := vault.TestCoreUnsealed(t)
 	ln, addr := TestServer(t, core)
@@ -101,9 +136,8 @@ func TestLogical_StandbyRedirect(t *testing.T) {
 		}
 	}
 
-	// Attempt to fix raciness in this test by giving the first core a chance
-	// to grab the lock
-	time.Sleep(2 * time.Second)
+	// Give the first core a chance to grab the lock
+	vault.TestWaitActive(t, core1)
 
 	// Create a second HA Vault
 	conf2 := &vault.CoreConfig{
@@ -147,6 +181,7 @@ func TestLogical_StandbyRedirect(t *testing.T) {
 			"path":             "auth/token/root",
 			"policies":         []interface{}{"root"},
 			"display_name":     "root",
+			"entity":           "",
 			"orphan":           true,
 			"id":               root,
 			"ttl":              json.Number("0"),
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/vault"
+)
+
+// requestIDKey is the context key of the ID of the request
+const requestIDKey contextKey = "request_id"
+
+// withRequestID assigns the request its ID, unless it already has one: the
+// one given by the standby that forwarded it, or a new one. The ID is set on the request, so that it
+// is forwarded along with it, and on the response, so that clients can
+// correlate their errors with the audit entries and logs.
+func withRequestID(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	if _, ok := r.Context().Value(requestIDKey).(string); ok {
+		return r, nil
+	}
+
+	id := r.Header.Get(RequestIDHeaderName)
+	if id == "" || !vault.IsForwardedRequest(r) {
+		var err error
+		id, err = uuid.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r.Header.Set(RequestIDHeaderName, id)
+	w.Header().Set(RequestIDHeaderName, id)
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey, id)), nil
+}
+
+// requestID returns the ID of the request, or a new one for requests that
+// were not assigned one
+func requestID(r *http.Request) (string, error) {
+	if id, ok := r.Context().Value(requestIDKey).(string); ok {
+		return id, nil
+	}
+	return uuid.GenerateUUID()
+}
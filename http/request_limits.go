@@ -0,0 +1,177 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+type contextKey string
+
+// maxRequestSizeKey is the context key of the maximum request size of the
+// listener a request was received on
+const maxRequestSizeKey contextKey = "max_request_size"
+
+// maxRequestSize returns the maximum size of the body of the request: that
+// of its listener, or MaxRequestSize. A negative size disables the limit.
+func maxRequestSize(r *http.Request) int64 {
+	if size, ok := r.Context().Value(maxRequestSizeKey).(int64); ok && size != 0 {
+		return size
+	}
+	return MaxRequestSize
+}
+
+// WrapRequestLimitsHandler enforces the request size and duration limits of
+// a listener. Requests larger than maxSize bytes are rejected with a 413,
+// and requests not handled within maxDuration get a 504; both are logged to
+// the audit backends. A maxSize of 0 uses MaxRequestSize and a negative one
+// disables the limit; a maxDuration of 0 disables the timeout. The timeout
+// does not apply to sys/monitor and sys/pprof.
+func WrapRequestLimitsHandler(h http.Handler, core *vault.Core, maxSize int64, maxDuration time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if maxSize != 0 {
+			r = r.WithContext(context.WithValue(r.Context(), maxRequestSizeKey, maxSize))
+		}
+
+		// Assign the ID of the request now so that rejected requests are
+		// audited with the ID they are responded with
+		r, err := withRequestID(w, r)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		// Requests announcing a larger body are rejected before reading it;
+		// others are cut off when reading it
+		if limit := maxRequestSize(r); limit > 0 && r.ContentLength > limit {
+			err := fmt.Errorf("request size of %d bytes exceeds the maximum of %d bytes", r.ContentLength, limit)
+			auditRejectedRequest(core, r, err)
+			respondError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+
+		// Log streams last as long as the client wants, and profiles as long
+		// as requested
+		if maxDuration <= 0 || r.URL.Path == "/v1/sys/monitor" || strings.HasPrefix(r.URL.Path, "/v1/sys/pprof/") {
+			h.ServeHTTP(w, r)
+			return
+		}
+		serveWithTimeout(h, core, w, r, maxDuration)
+	})
+}
+
+// serveWithTimeout serves the request, and responds with a 504 if it is not
+// handled within the timeout. The core cannot abandon requests, so a
+// request that timed out may still be carried out.
+func serveWithTimeout(h http.Handler, core *vault.Core, w http.ResponseWriter, r *http.Request, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	tw := &timeoutWriter{header: make(http.Header)}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(tw, r)
+	}()
+
+	select {
+	case <-done:
+		tw.l.Lock()
+		defer tw.l.Unlock()
+		for k, v := range tw.header {
+			w.Header()[k] = v
+		}
+		if tw.code == 0 {
+			tw.code = http.StatusOK
+		}
+		w.WriteHeader(tw.code)
+		w.Write(tw.buf.Bytes())
+
+	case <-ctx.Done():
+		tw.l.Lock()
+		tw.timedOut = true
+		tw.l.Unlock()
+
+		err := fmt.Errorf("request exceeded the maximum duration of %s", timeout)
+		auditRejectedRequest(core, r, err)
+		respondError(w, http.StatusGatewayTimeout, err)
+	}
+}
+
+// timeoutWriter buffers the response of a request served with a timeout,
+// which is discarded if the timeout is reached first
+type timeoutWriter struct {
+	l        sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	code     int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.l.Lock()
+	defer tw.l.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.code == 0 {
+		tw.code = http.StatusOK
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.l.Lock()
+	defer tw.l.Unlock()
+	if tw.timedOut || tw.code != 0 {
+		return
+	}
+	tw.code = code
+}
+
+// auditRejectedRequest logs a request rejected for exceeding the limits of
+// its listener to the audit backends. The body of the request is not read.
+func auditRejectedRequest(core *vault.Core, r *http.Request, reason error) {
+	if !strings.HasPrefix(r.URL.Path, "/v1/") {
+		return
+	}
+
+	var op logical.Operation
+	switch r.Method {
+	case "GET":
+		op = logical.ReadOperation
+	case "DELETE":
+		op = logical.DeleteOperation
+	case "LIST":
+		op = logical.ListOperation
+	case "PATCH":
+		op = logical.PatchOperation
+	default:
+		op = logical.UpdateOperation
+	}
+
+	id, err := requestID(r)
+	if err != nil {
+		return
+	}
+	req := requestAuth(core, r, &logical.Request{
+		ID:         id,
+		Operation:  op,
+		Path:       r.URL.Path[len("/v1/"):],
+		Connection: getConnection(r),
+		Headers:    r.Header,
+	})
+	core.AuditRejectedRequest(req, reason)
+}
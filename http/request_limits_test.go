@@ -0,0 +1,77 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestHandler_RequestLimits_size(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+
+	write := func(handler http.Handler, body string, chunked bool) int {
+		r := httptest.NewRequest("PUT", "/v1/secret/foo", strings.NewReader(body))
+		r.Header.Set(AuthHeaderName, token)
+		if chunked {
+			r.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	body := `{"value": "` + strings.Repeat("a", 100) + `"}`
+	handler := WrapRequestLimitsHandler(Handler(core), core, 64, 0)
+	if code := write(handler, body, false); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("bad: %d", code)
+	}
+	// Bodies without a Content-Length are cut off when read
+	if code := write(handler, body, true); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("bad: %d", code)
+	}
+	if code := write(handler, `{"value": "a"}`, false); code != http.StatusNoContent {
+		t.Fatalf("bad: %d", code)
+	}
+
+	// The limit can be raised above the default, or disabled
+	large := `{"value": "` + strings.Repeat("a", MaxRequestSize) + `"}`
+	if code := write(Handler(core), large, false); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("bad: %d", code)
+	}
+	if code := write(WrapRequestLimitsHandler(Handler(core), core, -1, 0), large, false); code != http.StatusNoContent {
+		t.Fatalf("bad: %d", code)
+	}
+}
+
+func TestHandler_RequestLimits_duration(t *testing.T) {
+	core, _, _ := vault.TestCoreUnsealed(t)
+
+	release := make(chan struct{})
+	defer close(release)
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/slow" {
+			<-release
+		}
+		w.Header().Set("X-Test", "fast")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("done"))
+	})
+	handler := WrapRequestLimitsHandler(slow, core, 0, 50*time.Millisecond)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/v1/slow", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("bad: %d", w.Code)
+	}
+
+	// Responses within the limit are passed through
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/v1/fast", nil))
+	if w.Code != http.StatusTeapot || w.Header().Get("X-Test") != "fast" || w.Body.String() != "done" {
+		t.Fatalf("bad: %d %#v %q", w.Code, w.Header(), w.Body.String())
+	}
+}
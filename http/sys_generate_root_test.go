@@ -304,6 +304,7 @@ func TestSysGenerateRoot_Update_OTP(t *testing.T) {
 	expected = map[string]interface{}{
 		"id":               newRootToken,
 		"display_name":     "root",
+		"entity":           "",
 		"meta":             interface{}(nil),
 		"num_uses":         json.Number("0"),
 		"policies":         []interface{}{"root"},
@@ -394,6 +395,7 @@ func TestSysGenerateRoot_Update_PGP(t *testing.T) {
 	expected = map[string]interface{}{
 		"id":               newRootToken,
 		"display_name":     "root",
+		"entity":           "",
 		"meta":             interface{}(nil),
 		"num_uses":         json.Number("0"),
 		"policies":         []interface{}{"root"},
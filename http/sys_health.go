@@ -2,7 +2,6 @@ package http
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -24,17 +23,36 @@ func handleSysHealth(core *vault.Core) http.Handler {
 	})
 }
 
+// fetchStatusCode returns the status code given in the field of the query,
+// whether it was given, and false if it is not a valid status code
 func fetchStatusCode(r *http.Request, field string) (int, bool, bool) {
-	var err error
-	statusCode := http.StatusOK
-	if statusCodeStr, statusCodeOk := r.URL.Query()[field]; statusCodeOk {
-		statusCode, err = strconv.Atoi(statusCodeStr[0])
-		if err != nil || len(statusCodeStr) < 1 {
-			return http.StatusBadRequest, false, false
-		}
-		return statusCode, true, true
+	statusCodeStr, statusCodeOk := r.URL.Query()[field]
+	if !statusCodeOk || len(statusCodeStr) < 1 {
+		return http.StatusOK, false, true
+	}
+	statusCode, err := strconv.Atoi(statusCodeStr[0])
+	if err != nil || statusCode < 100 || statusCode > 599 {
+		return http.StatusBadRequest, false, false
 	}
-	return statusCode, false, true
+	return statusCode, true, true
+}
+
+// fetchBool returns the boolean given in the field of the query; a field
+// given without a value is true. The last value is false if it is not a
+// valid boolean.
+func fetchBool(r *http.Request, field string) (bool, bool) {
+	values, ok := r.URL.Query()[field]
+	if !ok {
+		return false, true
+	}
+	if len(values) < 1 || values[0] == "" {
+		return true, true
+	}
+	b, err := strconv.ParseBool(values[0])
+	if err != nil {
+		return false, false
+	}
+	return b, true
 }
 
 func handleSysHealthGet(core *vault.Core, w http.ResponseWriter, r *http.Request) {
@@ -71,7 +89,17 @@ func handleSysHealthHead(core *vault.Core, w http.ResponseWriter, r *http.Reques
 
 func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, error) {
 	// Check if being a standby is allowed for the purpose of a 200 OK
-	_, standbyOK := r.URL.Query()["standbyok"]
+	standbyOK, ok := fetchBool(r, "standbyok")
+	if !ok {
+		return http.StatusBadRequest, nil, nil
+	}
+
+	// Check if being a standby able to forward requests to the active node
+	// is allowed for the purpose of a 200 OK
+	perfStandbyOK, ok := fetchBool(r, "perfstandbyok")
+	if !ok {
+		return http.StatusBadRequest, nil, nil
+	}
 
 	uninitCode := http.StatusNotImplemented
 	if code, found, ok := fetchStatusCode(r, "uninitcode"); !ok {
@@ -101,14 +129,20 @@ func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, erro
 		activeCode = code
 	}
 
-	// Check system status
-	sealed, _ := core.Sealed()
-	standby, _ := core.Standby()
+	// Check system status. The cached status is used so that frequent health
+	// checks never wait on an unseal or step-down in progress.
+	status := core.StatusSnapshot()
+	sealed := status.Sealed
+	standby := status.Standby
 	init, err := core.Initialized()
 	if err != nil {
 		return http.StatusInternalServerError, nil, err
 	}
 
+	// A standby services requests by forwarding them to the active node,
+	// which it can once its forwarding connection is established
+	perfStandby := !sealed && standby && core.ForwardingReady()
+
 	// Determine the status code
 	code := activeCode
 	switch {
@@ -116,34 +150,25 @@ func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, erro
 		code = uninitCode
 	case sealed:
 		code = sealedCode
-	case !standbyOK && standby:
+	case standby && !standbyOK && !(perfStandby && perfStandbyOK):
 		code = standbyCode
 	}
 
-	// Fetch the local cluster name and identifier
-	var clusterName, clusterID string
-	if !sealed {
-		cluster, err := core.Cluster()
-		if err != nil {
-			return http.StatusInternalServerError, nil, err
-		}
-		if cluster == nil {
-			return http.StatusInternalServerError, nil, fmt.Errorf("failed to fetch cluster details")
-		}
-		clusterName = cluster.Name
-		clusterID = cluster.ID
-	}
-
 	// Format the body
 	body := &HealthResponse{
 		Initialized:   init,
 		Sealed:        sealed,
 		Standby:       standby,
+		PerfStandby:   perfStandby,
 		ServerTimeUTC: time.Now().UTC().Unix(),
 		Version:       version.GetVersion().VersionNumber(),
-		ClusterName:   clusterName,
-		ClusterID:     clusterID,
+		ClusterName:   status.ClusterName,
+		ClusterID:     status.ClusterID,
+	}
+	if init && !sealed && !standby {
+		body.QuarantinedPluginMounts = core.QuarantinedPluginMounts()
 	}
+	body.Warnings = core.HealthWarnings()
 	return code, body, nil
 }
 
@@ -151,8 +176,13 @@ type HealthResponse struct {
 	Initialized   bool   `json:"initialized"`
 	Sealed        bool   `json:"sealed"`
 	Standby       bool   `json:"standby"`
+	PerfStandby   bool   `json:"performance_standby,omitempty"`
 	ServerTimeUTC int64  `json:"server_time_utc"`
 	Version       string `json:"version"`
 	ClusterName   string `json:"cluster_name,omitempty"`
 	ClusterID     string `json:"cluster_id,omitempty"`
+
+	QuarantinedPluginMounts []string `json:"quarantined_plugin_mounts,omitempty"`
+
+	Warnings []string `json:"warnings,omitempty"`
 }
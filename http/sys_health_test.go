@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/vault/vault"
@@ -228,6 +229,8 @@ func TestSysHealth_head(t *testing.T) {
 		{"", 200},
 		{"?activecode=503", 503},
 		{"?activecode=notacode", 400},
+		{"?activecode=999", 400},
+		{"?standbyok=notabool", 400},
 	}
 
 	for _, tt := range testData {
@@ -253,3 +256,55 @@ func TestSysHealth_head(t *testing.T) {
 		}
 	}
 }
+
+func TestSysHealth_standby(t *testing.T) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{}, true)
+	defer cluster.Cleanup()
+	cluster.StartListeners()
+	cores := cluster.Cores
+
+	cores[0].Handler.Handle("/", Handler(cores[0].Core))
+	cores[1].Handler.Handle("/", Handler(cores[1].Core))
+	cores[2].Handler.Handle("/", Handler(cores[2].Core))
+
+	vault.TestWaitActive(t, cores[0].Core)
+	cluster.WaitForStandby(t, cores[1])
+
+	testData := []struct {
+		uri         string
+		code        int
+		perfStandby bool
+	}{
+		{"", 429, true},
+		{"?standbycode=299", 299, true},
+		{"?standbyok", 200, true},
+		{"?standbyok=false", 429, true},
+		{"?perfstandbyok=true", 200, true},
+		{"?perfstandbyok=true&activecode=202", 202, true},
+	}
+
+	for _, tt := range testData {
+		r := cores[1].Client.NewRequest("GET", "/v1/sys/health")
+		params, err := url.ParseQuery(strings.TrimPrefix(tt.uri, "?"))
+		if err != nil {
+			t.Fatalf("%q: err: %s", tt.uri, err)
+		}
+		r.Params = params
+		resp, _ := cores[1].Client.RawRequest(r)
+		if resp == nil {
+			t.Fatalf("%q: no response", tt.uri)
+		}
+		if resp.StatusCode != tt.code {
+			t.Fatalf("%q: expected code %d, got %d", tt.uri, tt.code, resp.StatusCode)
+		}
+
+		var actual HealthResponse
+		if err := resp.DecodeJSON(&actual); err != nil {
+			t.Fatalf("%q: err: %s", tt.uri, err)
+		}
+		resp.Body.Close()
+		if !actual.Standby || actual.PerfStandby != tt.perfStandby {
+			t.Fatalf("%q: bad: %#v", tt.uri, actual)
+		}
+	}
+}
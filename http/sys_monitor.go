@@ -0,0 +1,75 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/vault"
+)
+
+// handleSysMonitor streams the log of the server over a chunked response
+// until the client disconnects. It is served by the node the client is
+// connected to; standbys redirect to the active node.
+func handleSysMonitor(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			respondError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+			return
+		}
+
+		levelName := r.URL.Query().Get("log_level")
+		if levelName == "" {
+			levelName = "info"
+		}
+		level, err := logformat.ParseLevel(levelName)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		req, statusCode, err := buildLogicalRequest(core, w, r)
+		if err != nil || statusCode != 0 {
+			respondError(w, statusCode, err)
+			return
+		}
+
+		monitor, err := core.MonitorLogs(req, level)
+		if errwrap.Contains(err, consts.ErrStandby.Error()) {
+			respondStandby(core, w, r.URL)
+			return
+		}
+		if err != nil {
+			respondErrorCommon(w, req, nil, err)
+			return
+		}
+		defer monitor.Stop()
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case entry, ok := <-monitor.Entries():
+				if !ok {
+					return
+				}
+				if _, err := w.Write(entry); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	})
+}
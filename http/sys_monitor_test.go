@@ -0,0 +1,71 @@
+package http
+
+import (
+	"bufio"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysMonitor(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	resp := testHttpGet(t, token, addr+"/v1/sys/monitor?log_level=verbose")
+	testResponseStatus(t, resp, 400)
+
+	// Tokens need the sudo capability
+	resp = testHttpPost(t, token, addr+"/v1/auth/token/create", map[string]interface{}{
+		"policies": []string{"default"},
+	})
+	var created map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &created)
+	other := created["auth"].(map[string]interface{})["client_token"].(string)
+	resp = testHttpGet(t, other, addr+"/v1/sys/monitor")
+	testResponseStatus(t, resp, 403)
+
+	req, err := http.NewRequest("GET", addr+"/v1/sys/monitor?log_level=warn", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	req.Header.Set(AuthHeaderName, token)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer resp.Body.Close()
+	testResponseStatus(t, resp, 200)
+
+	// Only the entries at or above the level are streamed
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	core.Logger().Info("monitor test: info")
+	core.Logger().Warn("monitor test: warn")
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line := <-lines:
+			if !strings.Contains(line, "monitor test:") {
+				continue
+			}
+			if !strings.Contains(line, "[WARN ] monitor test: warn") {
+				t.Fatalf("bad: %q", line)
+			}
+			return
+		case <-timeout:
+			t.Fatal("no log entry streamed")
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/vault"
+)
+
+const (
+	// pprofDefaultCPUSeconds is how long the CPU is profiled by default
+	pprofDefaultCPUSeconds = 30
+
+	// pprofDefaultTraceSeconds is how long the execution is traced by
+	// default
+	pprofDefaultTraceSeconds = 1
+)
+
+// handleSysPprof serves the runtime profiles of the server: "cpu" and
+// "trace" profile the server for the given number of seconds, and the other
+// names are those of the profiles of runtime/pprof, such as "heap" and
+// "goroutine". Like sys/monitor, profiles are collected on the node the
+// client is connected to; standbys redirect to the active node.
+func handleSysPprof(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		name := strings.TrimPrefix(r.URL.Path, "/v1/sys/pprof/")
+		query := r.URL.Query()
+		seconds := pprofDefaultCPUSeconds
+		if name == "trace" {
+			seconds = pprofDefaultTraceSeconds
+		}
+		if raw := query.Get("seconds"); raw != "" {
+			var err error
+			seconds, err = strconv.Atoi(raw)
+			if err != nil || seconds <= 0 {
+				respondError(w, http.StatusBadRequest, fmt.Errorf("invalid seconds: %q", raw))
+				return
+			}
+		}
+		debug := 0
+		if raw := query.Get("debug"); raw != "" {
+			var err error
+			debug, err = strconv.Atoi(raw)
+			if err != nil || debug < 0 {
+				respondError(w, http.StatusBadRequest, fmt.Errorf("invalid debug: %q", raw))
+				return
+			}
+		}
+
+		req, statusCode, err := buildLogicalRequest(core, w, r)
+		if err != nil || statusCode != 0 {
+			respondError(w, statusCode, err)
+			return
+		}
+
+		err = core.AuthorizeProfiling(req)
+		if errwrap.Contains(err, consts.ErrStandby.Error()) {
+			respondStandby(core, w, r.URL)
+			return
+		}
+		if err != nil {
+			respondErrorCommon(w, req, nil, err)
+			return
+		}
+
+		duration := time.Duration(seconds) * time.Second
+		switch name {
+		case "cpu":
+			setPprofHeaders(w, name, 0)
+			if err := pprof.StartCPUProfile(w); err != nil {
+				respondError(w, http.StatusInternalServerError, errwrap.Wrapf("failed to start the CPU profile: {{err}}", err))
+				return
+			}
+			sleepPprof(r, duration)
+			pprof.StopCPUProfile()
+
+		case "trace":
+			setPprofHeaders(w, name, 0)
+			if err := trace.Start(w); err != nil {
+				respondError(w, http.StatusInternalServerError, errwrap.Wrapf("failed to start the trace: {{err}}", err))
+				return
+			}
+			sleepPprof(r, duration)
+			trace.Stop()
+
+		default:
+			profile := pprof.Lookup(name)
+			if profile == nil {
+				respondError(w, http.StatusNotFound, fmt.Errorf("unknown profile: %q", name))
+				return
+			}
+			if name == "heap" && query.Get("gc") != "" {
+				runtime.GC()
+			}
+			setPprofHeaders(w, name, debug)
+			profile.WriteTo(w, debug)
+		}
+	})
+}
+
+// setPprofHeaders sets the headers of the response for the profile, which
+// is binary unless written in the text format of the given debug level
+func setPprofHeaders(w http.ResponseWriter, name string, debug int) {
+	if debug > 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+}
+
+// sleepPprof waits while the server is profiled, for the given duration or
+// until the client disconnects
+func sleepPprof(r *http.Request, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-r.Context().Done():
+	}
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysPprof(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	resp := testHttpGet(t, token, addr+"/v1/sys/pprof/nope")
+	testResponseStatus(t, resp, 404)
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/pprof/cpu?seconds=-1")
+	testResponseStatus(t, resp, 400)
+
+	// Tokens need the sudo capability
+	resp = testHttpPost(t, token, addr+"/v1/auth/token/create", map[string]interface{}{
+		"policies": []string{"default"},
+	})
+	var created map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &created)
+	other := created["auth"].(map[string]interface{})["client_token"].(string)
+	resp = testHttpGet(t, other, addr+"/v1/sys/pprof/heap")
+	testResponseStatus(t, resp, 403)
+
+	// Binary profiles are gzipped protocol buffers
+	resp = testHttpGet(t, token, addr+"/v1/sys/pprof/heap")
+	testResponseStatus(t, resp, 200)
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		t.Fatalf("bad: %q", body)
+	}
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/pprof/goroutine?debug=1")
+	testResponseStatus(t, resp, 200)
+	body, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.HasPrefix(string(body), "goroutine profile:") {
+		t.Fatalf("bad: %q", body)
+	}
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/pprof/cpu?seconds=1")
+	testResponseStatus(t, resp, 200)
+	body, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(body) == 0 {
+		t.Fatal("empty CPU profile")
+	}
+}
@@ -87,6 +87,7 @@ func handleSysRekeyInitGet(core *vault.Core, recovery bool, w http.ResponseWrite
 		status.Started = true
 		status.T = rekeyConf.SecretThreshold
 		status.N = rekeyConf.SecretShares
+		status.VerificationRequired = rekeyConf.VerificationRequired
 		if rekeyConf.PGPKeys != nil && len(rekeyConf.PGPKeys) != 0 {
 			pgpFingerprints, err := pgpkeys.GetFingerprints(rekeyConf.PGPKeys, nil)
 			if err != nil {
@@ -133,6 +134,8 @@ func handleSysRekeyInitPut(core *vault.Core, recovery bool, w http.ResponseWrite
 		StoredShares:    req.StoredShares,
 		PGPKeys:         req.PGPKeys,
 		Backup:          req.Backup,
+
+		VerificationRequired: req.RequireVerification,
 	}, recovery)
 	if err != nil {
 		respondError(w, http.StatusBadRequest, err)
@@ -172,20 +175,10 @@ func handleSysRekeyUpdate(core *vault.Core, recovery bool) http.Handler {
 			return
 		}
 
-		// Decode the key, which is base64 or hex encoded
-		min, max := core.BarrierKeyLength()
-		key, err := hex.DecodeString(req.Key)
-		// We check min and max here to ensure that a string that is base64
-		// encoded but also valid hex will not be valid and we instead base64
-		// decode it
-		if err != nil || len(key) < min || len(key) > max {
-			key, err = base64.StdEncoding.DecodeString(req.Key)
-			if err != nil {
-				respondError(
-					w, http.StatusBadRequest,
-					errors.New("'key' must be a valid hex or base64 string"))
-				return
-			}
+		key, err := decodeRekeyKey(core, req.Key)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
 		}
 
 		// Use the key to make progress on rekey
@@ -202,6 +195,8 @@ func handleSysRekeyUpdate(core *vault.Core, recovery bool) http.Handler {
 			resp.Nonce = req.Nonce
 			resp.Backup = result.Backup
 			resp.PGPFingerprints = result.PGPFingerprints
+			resp.VerificationRequired = result.VerificationRequired
+			resp.VerificationNonce = result.VerificationNonce
 
 			// Encode the keys
 			keys := make([]string, 0, len(result.SecretShares))
@@ -219,12 +214,118 @@ func handleSysRekeyUpdate(core *vault.Core, recovery bool) http.Handler {
 	})
 }
 
+// decodeRekeyKey decodes a key share provided to a rekey, which is base64 or
+// hex encoded
+func decodeRekeyKey(core *vault.Core, encoded string) ([]byte, error) {
+	min, max := core.BarrierKeyLength()
+	key, err := hex.DecodeString(encoded)
+	// We check min and max here to ensure that a string that is base64
+	// encoded but also valid hex will not be valid and we instead base64
+	// decode it
+	if err != nil || len(key) < min || len(key) > max {
+		key, err = base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.New("'key' must be a valid hex or base64 string")
+		}
+	}
+	return key, nil
+}
+
+func handleSysRekeyRecoveryKeyVerify(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		standby, _ := core.Standby()
+		if standby {
+			respondStandby(core, w, r.URL)
+			return
+		}
+
+		switch {
+		case !core.SealAccess().RecoveryKeySupported():
+			respondError(w, http.StatusBadRequest, fmt.Errorf("recovery rekeying not supported"))
+		case r.Method == "GET":
+			handleSysRekeyRecoveryKeyVerifyGet(core, w, r)
+		case r.Method == "POST" || r.Method == "PUT":
+			handleSysRekeyRecoveryKeyVerifyPut(core, w, r)
+		case r.Method == "DELETE":
+			if err := core.RecoveryRekeyVerifyRestart(); err != nil {
+				respondError(w, http.StatusBadRequest, err)
+				return
+			}
+			handleSysRekeyRecoveryKeyVerifyGet(core, w, r)
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+		}
+	})
+}
+
+func handleSysRekeyRecoveryKeyVerifyGet(core *vault.Core, w http.ResponseWriter, r *http.Request) {
+	nonce, progress, err := core.RecoveryRekeyVerifyStatus()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	status := &RekeyVerificationStatusResponse{
+		Nonce:    nonce,
+		Started:  nonce != "",
+		Progress: progress,
+	}
+	if status.Started {
+		rekeyConf, err := core.RekeyConfig(true)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if rekeyConf != nil {
+			status.T = rekeyConf.SecretThreshold
+			status.N = rekeyConf.SecretShares
+		}
+	}
+	respondOk(w, status)
+}
+
+func handleSysRekeyRecoveryKeyVerifyPut(core *vault.Core, w http.ResponseWriter, r *http.Request) {
+	var req RekeyUpdateRequest
+	if err := parseRequest(r, w, &req); err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Key == "" {
+		respondError(
+			w, http.StatusBadRequest,
+			errors.New("'key' must be specified in request body as JSON"))
+		return
+	}
+
+	key, err := decodeRekeyKey(core, req.Key)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	complete, err := core.RecoveryRekeyVerify(key, req.Nonce)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !complete {
+		handleSysRekeyRecoveryKeyVerifyGet(core, w, r)
+		return
+	}
+	respondOk(w, &RekeyVerificationUpdateResponse{
+		Nonce:    req.Nonce,
+		Complete: true,
+	})
+}
+
 type RekeyRequest struct {
 	SecretShares    int      `json:"secret_shares"`
 	SecretThreshold int      `json:"secret_threshold"`
 	StoredShares    int      `json:"stored_shares"`
 	PGPKeys         []string `json:"pgp_keys"`
 	Backup          bool     `json:"backup"`
+
+	RequireVerification bool `json:"require_verification"`
 }
 
 type RekeyStatusResponse struct {
@@ -236,6 +337,8 @@ type RekeyStatusResponse struct {
 	Required        int      `json:"required"`
 	PGPFingerprints []string `json:"pgp_fingerprints"`
 	Backup          bool     `json:"backup"`
+
+	VerificationRequired bool `json:"verification_required"`
 }
 
 type RekeyUpdateRequest struct {
@@ -250,4 +353,20 @@ type RekeyUpdateResponse struct {
 	KeysB64         []string `json:"keys_base64"`
 	PGPFingerprints []string `json:"pgp_fingerprints"`
 	Backup          bool     `json:"backup"`
+
+	VerificationRequired bool   `json:"verification_required"`
+	VerificationNonce    string `json:"verification_nonce,omitempty"`
+}
+
+type RekeyVerificationStatusResponse struct {
+	Nonce    string `json:"nonce"`
+	Started  bool   `json:"started"`
+	T        int    `json:"t"`
+	N        int    `json:"n"`
+	Progress int    `json:"progress"`
+}
+
+type RekeyVerificationUpdateResponse struct {
+	Nonce    string `json:"nonce"`
+	Complete bool   `json:"complete"`
 }
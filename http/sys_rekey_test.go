@@ -40,14 +40,15 @@ func TestSysRekeyInit_Status(t *testing.T) {
 
 	var actual map[string]interface{}
 	expected := map[string]interface{}{
-		"started":          false,
-		"t":                json.Number("0"),
-		"n":                json.Number("0"),
-		"progress":         json.Number("0"),
-		"required":         json.Number("3"),
-		"pgp_fingerprints": interface{}(nil),
-		"backup":           false,
-		"nonce":            "",
+		"started":               false,
+		"t":                     json.Number("0"),
+		"n":                     json.Number("0"),
+		"progress":              json.Number("0"),
+		"required":              json.Number("3"),
+		"pgp_fingerprints":      interface{}(nil),
+		"backup":                false,
+		"verification_required": false,
+		"nonce":                 "",
 	}
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
@@ -70,13 +71,14 @@ func TestSysRekeyInit_Setup(t *testing.T) {
 
 	var actual map[string]interface{}
 	expected := map[string]interface{}{
-		"started":          true,
-		"t":                json.Number("3"),
-		"n":                json.Number("5"),
-		"progress":         json.Number("0"),
-		"required":         json.Number("3"),
-		"pgp_fingerprints": interface{}(nil),
-		"backup":           false,
+		"started":               true,
+		"t":                     json.Number("3"),
+		"n":                     json.Number("5"),
+		"progress":              json.Number("0"),
+		"required":              json.Number("3"),
+		"pgp_fingerprints":      interface{}(nil),
+		"backup":                false,
+		"verification_required": false,
 	}
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
@@ -92,13 +94,14 @@ func TestSysRekeyInit_Setup(t *testing.T) {
 
 	actual = map[string]interface{}{}
 	expected = map[string]interface{}{
-		"started":          true,
-		"t":                json.Number("3"),
-		"n":                json.Number("5"),
-		"progress":         json.Number("0"),
-		"required":         json.Number("3"),
-		"pgp_fingerprints": interface{}(nil),
-		"backup":           false,
+		"started":               true,
+		"t":                     json.Number("3"),
+		"n":                     json.Number("5"),
+		"progress":              json.Number("0"),
+		"required":              json.Number("3"),
+		"pgp_fingerprints":      interface{}(nil),
+		"backup":                false,
+		"verification_required": false,
 	}
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
@@ -136,14 +139,15 @@ func TestSysRekeyInit_Cancel(t *testing.T) {
 
 	var actual map[string]interface{}
 	expected := map[string]interface{}{
-		"started":          false,
-		"t":                json.Number("0"),
-		"n":                json.Number("0"),
-		"progress":         json.Number("0"),
-		"required":         json.Number("3"),
-		"pgp_fingerprints": interface{}(nil),
-		"backup":           false,
-		"nonce":            "",
+		"started":               false,
+		"t":                     json.Number("0"),
+		"n":                     json.Number("0"),
+		"progress":              json.Number("0"),
+		"required":              json.Number("3"),
+		"pgp_fingerprints":      interface{}(nil),
+		"backup":                false,
+		"verification_required": false,
+		"nonce":                 "",
 	}
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
@@ -189,14 +193,15 @@ func TestSysRekey_Update(t *testing.T) {
 
 		actual = map[string]interface{}{}
 		expected = map[string]interface{}{
-			"started":          true,
-			"nonce":            rekeyStatus["nonce"].(string),
-			"backup":           false,
-			"pgp_fingerprints": interface{}(nil),
-			"required":         json.Number("3"),
-			"t":                json.Number("3"),
-			"n":                json.Number("5"),
-			"progress":         json.Number(fmt.Sprintf("%d", i+1)),
+			"started":               true,
+			"nonce":                 rekeyStatus["nonce"].(string),
+			"backup":                false,
+			"verification_required": false,
+			"pgp_fingerprints":      interface{}(nil),
+			"required":              json.Number("3"),
+			"t":                     json.Number("3"),
+			"n":                     json.Number("5"),
+			"progress":              json.Number(fmt.Sprintf("%d", i+1)),
 		}
 		testResponseStatus(t, resp, 200)
 		testResponseBody(t, resp, &actual)
@@ -258,3 +263,77 @@ func TestSysRekey_ReInitUpdate(t *testing.T) {
 
 	testResponseStatus(t, resp, 400)
 }
+
+func TestSysRekeyRecoveryKey_Verify(t *testing.T) {
+	bc, rc := vault.TestSealDefConfigs()
+	bc.StoredShares = 0
+	core, _, recoveryKeys, token := vault.TestCoreUnsealedWithConfigs(t, bc, rc)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, token, addr+"/v1/sys/rekey-recovery-key/init", map[string]interface{}{
+		"secret_shares":        1,
+		"secret_threshold":     1,
+		"require_verification": true,
+	})
+	var status map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &status)
+	if status["verification_required"] != true {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	var actual map[string]interface{}
+	for _, key := range recoveryKeys[:rc.SecretThreshold] {
+		resp = testHttpPut(t, token, addr+"/v1/sys/rekey-recovery-key/update", map[string]interface{}{
+			"nonce": status["nonce"].(string),
+			"key":   hex.EncodeToString(key),
+		})
+		testResponseStatus(t, resp, 200)
+		actual = map[string]interface{}{}
+		testResponseBody(t, resp, &actual)
+	}
+	if actual["complete"] != true || actual["verification_required"] != true {
+		t.Fatalf("bad: %#v", actual)
+	}
+	newKey := actual["keys"].([]interface{})[0].(string)
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/rekey-recovery-key/verify")
+	var verification map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &verification)
+	expected := map[string]interface{}{
+		"nonce":    actual["verification_nonce"],
+		"started":  true,
+		"t":        json.Number("1"),
+		"n":        json.Number("1"),
+		"progress": json.Number("0"),
+	}
+	if !reflect.DeepEqual(verification, expected) {
+		t.Fatalf("\nexpected: %#v\nactual: %#v", expected, verification)
+	}
+
+	resp = testHttpPut(t, token, addr+"/v1/sys/rekey-recovery-key/verify", map[string]interface{}{
+		"nonce": actual["verification_nonce"],
+		"key":   newKey,
+	})
+	testResponseStatus(t, resp, 200)
+	verification = map[string]interface{}{}
+	testResponseBody(t, resp, &verification)
+	expected = map[string]interface{}{
+		"nonce":    actual["verification_nonce"],
+		"complete": true,
+	}
+	if !reflect.DeepEqual(verification, expected) {
+		t.Fatalf("\nexpected: %#v\nactual: %#v", expected, verification)
+	}
+
+	conf, err := core.SealAccess().RecoveryConfig()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if conf.SecretShares != 1 || conf.SecretThreshold != 1 {
+		t.Fatalf("bad: %#v", conf)
+	}
+}
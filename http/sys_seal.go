@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/helper/consts"
@@ -154,12 +155,6 @@ func handleSysSealStatus(core *vault.Core) http.Handler {
 }
 
 func handleSysSealStatusRaw(core *vault.Core, w http.ResponseWriter, r *http.Request) {
-	sealed, err := core.Sealed()
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, err)
-		return
-	}
-
 	sealConfig, err := core.SealAccess().BarrierConfig()
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err)
@@ -171,45 +166,95 @@ func handleSysSealStatusRaw(core *vault.Core, w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Fetch the local cluster name and identifier
-	var clusterName, clusterID string
-	if !sealed {
-		cluster, err := core.Cluster()
-		if err != nil {
-			respondError(w, http.StatusInternalServerError, err)
-			return
-		}
-		if cluster == nil {
-			respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to fetch cluster details"))
-			return
-		}
-		clusterName = cluster.Name
-		clusterID = cluster.ID
+	// The state lock is held for the whole of an unseal, so while one is in
+	// progress respond with its status rather than waiting for it to finish
+	unsealStatus := core.UnsealStatus()
+	if unsealStatus.InProgress {
+		respondOk(w, &SealStatusResponse{
+			Sealed:   true,
+			T:        sealConfig.SecretThreshold,
+			N:        sealConfig.SecretShares,
+			Progress: sealConfig.SecretThreshold,
+			Version:  version.GetVersion().VersionNumber(),
+			Unseal:   newUnsealStatusResponse(unsealStatus),
+		})
+		return
 	}
 
-	progress, nonce := core.SecretProgress()
-
-	respondOk(w, &SealStatusResponse{
-		Sealed:      sealed,
+	status := core.StatusSnapshot()
+	resp := &SealStatusResponse{
+		Sealed:      status.Sealed,
 		T:           sealConfig.SecretThreshold,
 		N:           sealConfig.SecretShares,
-		Progress:    progress,
-		Nonce:       nonce,
+		Progress:    status.Progress,
+		Nonce:       status.Nonce,
 		Version:     version.GetVersion().VersionNumber(),
-		ClusterName: clusterName,
-		ClusterID:   clusterID,
-	})
+		ClusterName: status.ClusterName,
+		ClusterID:   status.ClusterID,
+	}
+
+	// Experiments are only known once unsealed
+	if !status.Sealed {
+		resp.Experiments = core.EnabledExperiments()
+	}
+
+	// Report the phases of a failed unseal so that the failing one can be
+	// identified
+	if status.Sealed && unsealStatus.Error != "" {
+		resp.Unseal = newUnsealStatusResponse(unsealStatus)
+	}
+
+	respondOk(w, resp)
+}
+
+func newUnsealStatusResponse(status vault.UnsealStatus) *UnsealStatusResponse {
+	resp := &UnsealStatusResponse{
+		InProgress: status.InProgress,
+		StartTime:  status.StartTime.Format(time.RFC3339Nano),
+		Error:      status.Error,
+		Phases:     make([]UnsealPhaseResponse, 0, len(status.Phases)),
+	}
+	for _, phase := range status.Phases {
+		phaseResp := UnsealPhaseResponse{
+			Name:      phase.Name,
+			StartTime: phase.StartTime.Format(time.RFC3339Nano),
+			Completed: phase.Completed,
+			Total:     phase.Total,
+		}
+		if !phase.EndTime.IsZero() {
+			phaseResp.EndTime = phase.EndTime.Format(time.RFC3339Nano)
+		}
+		resp.Phases = append(resp.Phases, phaseResp)
+	}
+	return resp
 }
 
 type SealStatusResponse struct {
-	Sealed      bool   `json:"sealed"`
-	T           int    `json:"t"`
-	N           int    `json:"n"`
-	Progress    int    `json:"progress"`
-	Nonce       string `json:"nonce"`
-	Version     string `json:"version"`
-	ClusterName string `json:"cluster_name,omitempty"`
-	ClusterID   string `json:"cluster_id,omitempty"`
+	Sealed      bool                  `json:"sealed"`
+	T           int                   `json:"t"`
+	N           int                   `json:"n"`
+	Progress    int                   `json:"progress"`
+	Nonce       string                `json:"nonce"`
+	Version     string                `json:"version"`
+	ClusterName string                `json:"cluster_name,omitempty"`
+	ClusterID   string                `json:"cluster_id,omitempty"`
+	Experiments []string              `json:"experiments,omitempty"`
+	Unseal      *UnsealStatusResponse `json:"unseal,omitempty"`
+}
+
+type UnsealStatusResponse struct {
+	InProgress bool                  `json:"in_progress"`
+	StartTime  string                `json:"start_time"`
+	Error      string                `json:"error,omitempty"`
+	Phases     []UnsealPhaseResponse `json:"phases"`
+}
+
+type UnsealPhaseResponse struct {
+	Name      string `json:"name"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time,omitempty"`
+	Completed int    `json:"completed,omitempty"`
+	Total     int    `json:"total,omitempty"`
 }
 
 type UnsealRequest struct {
@@ -53,6 +53,83 @@ func TestSysSealStatus(t *testing.T) {
 	}
 }
 
+func TestSysSealStatus_standbyPromotion(t *testing.T) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{}, true)
+	cluster.StartListeners()
+	defer cluster.Cleanup()
+	cores := cluster.Cores
+
+	cores[0].Handler.Handle("/", Handler(cores[0].Core))
+	cores[1].Handler.Handle("/", Handler(cores[1].Core))
+	cores[2].Handler.Handle("/", Handler(cores[2].Core))
+
+	vault.TestWaitActive(t, cores[0].Core)
+
+	// Leave a single standby to be promoted
+	cores[2].SealForTest(t)
+	standby := cores[1]
+
+	stopCh := make(chan struct{})
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case <-stopCh:
+				errCh <- nil
+				return
+			default:
+			}
+			status, err := standby.Client.Sys().SealStatus()
+			if err == nil && status.Sealed {
+				errCh <- fmt.Errorf("standby reported as sealed: %#v", status)
+				return
+			}
+		}
+	}()
+
+	cores[0].SealForTest(t)
+	vault.TestWaitActive(t, standby.Core)
+	close(stopCh)
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the unseal of the standby itself was tracked
+	status := standby.UnsealStatus()
+	if status.InProgress || status.Error != "" {
+		t.Fatalf("bad: %#v", status)
+	}
+	for _, phase := range status.Phases {
+		if phase.Name != vault.UnsealPhaseKeyring {
+			t.Fatalf("bad phase: %#v", phase)
+		}
+	}
+}
+
+func TestSysSealStatus_experiments(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, token, addr+"/v1/sys/config/experiments", map[string]interface{}{
+		"enabled": []string{"events"},
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp, err := http.Get(addr + "/v1/sys/seal-status")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var actual map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	if !reflect.DeepEqual(actual["experiments"], []interface{}{"events"}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
 func TestSysSealStatus_uninit(t *testing.T) {
 	core := vault.TestCore(t)
 	ln, addr := TestServer(t, core)
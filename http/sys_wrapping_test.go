@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/api"
+	credAppRole "github.com/hashicorp/vault/builtin/credential/approle"
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/vault"
 )
 
@@ -18,7 +20,7 @@ func TestHTTP_Wrapping(t *testing.T) {
 	// Chicken-and-egg: Handler needs a core. So we create handlers first, then
 	// add routes chained to a Handler-created handler.
 	cluster := vault.NewTestCluster(t, coreConfig, true)
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 	cluster.StartListeners()
 	cores := cluster.Cores
 	cores[0].Handler.Handle("/", Handler(cores[0].Core))
@@ -346,3 +348,119 @@ func TestHTTP_Wrapping(t *testing.T) {
 		t.Fatalf("secret data did not match expected: %#v", secret.Data)
 	}
 }
+
+func TestHTTP_WrappingIntroduce(t *testing.T) {
+	coreConfig := &vault.CoreConfig{
+		CredentialBackends: map[string]logical.Factory{
+			"approle": credAppRole.Factory,
+		},
+	}
+
+	cluster := vault.NewTestCluster(t, coreConfig, true)
+	defer cluster.Cleanup()
+	cluster.StartListeners()
+	cores := cluster.Cores
+	cores[0].Handler.Handle("/", Handler(cores[0].Core))
+	cores[1].Handler.Handle("/", Handler(cores[1].Core))
+	cores[2].Handler.Handle("/", Handler(cores[2].Core))
+
+	core := cores[0].Core
+	vault.TestWaitActive(t, core)
+
+	client := cores[0].Client
+	client.SetToken(cores[0].Root)
+
+	if err := client.Sys().EnableAuth("approle", "approle", ""); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.Logical().Write("auth/approle/role/web", map[string]interface{}{
+		"policies": "default",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := client.Logical().Write("sys/wrapping/introduce", map[string]interface{}{
+		"role_name": "web",
+		"wrap_ttl":  "30s",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.WrapInfo == nil || secret.WrapInfo.Token == "" {
+		t.Fatalf("bad: %#v", secret)
+	}
+	if secret.WrapInfo.TTL != 30 {
+		t.Fatalf("bad: %d", secret.WrapInfo.TTL)
+	}
+	if secret.Data != nil {
+		t.Fatalf("bad: %#v", secret.Data)
+	}
+
+	secret, err = client.Logical().Unwrap(secret.WrapInfo.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roleID, _ := secret.Data["role_id"].(string)
+	secretID, _ := secret.Data["secret_id"].(string)
+	if roleID == "" || secretID == "" || secret.Data["secret_id_accessor"] == nil {
+		t.Fatalf("bad: %#v", secret.Data)
+	}
+
+	// The SecretID can be used once
+	login := map[string]interface{}{
+		"role_id":   roleID,
+		"secret_id": secretID,
+	}
+	secret, err = client.Logical().Write("auth/approle/login", login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Auth == nil || secret.Auth.ClientToken == "" {
+		t.Fatalf("bad: %#v", secret)
+	}
+	if _, err := client.Logical().Write("auth/approle/login", login); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Unknown mounts and roles are rejected
+	_, err = client.Logical().Write("sys/wrapping/introduce", map[string]interface{}{
+		"mount":     "foo",
+		"role_name": "web",
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	_, err = client.Logical().Write("sys/wrapping/introduce", map[string]interface{}{
+		"role_name": "foo",
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	// The caller needs access to the paths of the role
+	err = client.Sys().PutPolicy("introduce", `
+path "sys/wrapping/introduce" {
+	capabilities = ["update"]
+}
+path "auth/approle/role/web/role-id" {
+	capabilities = ["read"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err = client.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"introduce"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken(secret.Auth.ClientToken)
+	_, err = client.Logical().Write("sys/wrapping/introduce", map[string]interface{}{
+		"role_name": "web",
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -51,6 +51,10 @@ type Auth struct {
 
 	// Number of allowed uses of the issued token
 	NumUses int `json:"num_uses" mapstructure:"num_uses" structs:"num_uses"`
+
+	// BoundCIDRs restricts the use of the issued token to client addresses
+	// within these CIDR blocks
+	BoundCIDRs []string `json:"bound_cidrs" mapstructure:"bound_cidrs" structs:"bound_cidrs"`
 }
 
 func (a *Auth) GoString() string {
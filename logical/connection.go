@@ -12,4 +12,7 @@ type Connection struct {
 
 	// ConnState is the TLS connection state if applicable.
 	ConnState *tls.ConnectionState
+
+	// UserAgent is the user agent the client identified itself with, if any.
+	UserAgent string `json:"user_agent"`
 }
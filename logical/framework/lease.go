@@ -20,6 +20,12 @@ import (
 // systemView is the system view from the calling backend, used to determine
 // and/or correct default/max times.
 func LeaseExtend(backendIncrement, backendMax time.Duration, systemView logical.SystemView) OperationFunc {
+	return LeaseExtendClock(time.Now, backendIncrement, backendMax, systemView)
+}
+
+// LeaseExtendClock is LeaseExtend with the current time given by now, so
+// that callers controlling the passing of time, such as tests, can use it.
+func LeaseExtendClock(now func() time.Time, backendIncrement, backendMax time.Duration, systemView logical.SystemView) OperationFunc {
 	return func(req *logical.Request, data *FieldData) (*logical.Response, error) {
 		var leaseOpts *logical.LeaseOptions
 		switch {
@@ -48,7 +54,7 @@ func LeaseExtend(backendIncrement, backendMax time.Duration, systemView logical.
 		maxValidTime := leaseOpts.IssueTime.Add(max)
 
 		// Get the current time
-		now := time.Now()
+		now := now()
 
 		// If we are past the max TTL, we shouldn't be in this function...but
 		// fast path out if we are
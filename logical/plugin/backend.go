@@ -10,14 +10,28 @@ import (
 // BackendPlugin is the plugin.Plugin implementation
 type BackendPlugin struct {
 	Factory func(*logical.BackendConfig) (logical.Backend, error)
+
+	// Capabilities of the protocol this side of the plugin supports, of
+	// which those supported by both sides are used
+	Capabilities []string
 }
 
 // Server gets called when on plugin.Serve()
 func (b *BackendPlugin) Server(broker *plugin.MuxBroker) (interface{}, error) {
-	return &backendPluginServer{factory: b.Factory, broker: broker}, nil
+	return &backendPluginServer{
+		factory:               b.Factory,
+		broker:                broker,
+		supportedCapabilities: b.Capabilities,
+		protocolVersion:       1,
+	}, nil
 }
 
 // Client gets called on plugin.NewClient()
 func (b BackendPlugin) Client(broker *plugin.MuxBroker, c *rpc.Client) (interface{}, error) {
-	return &backendPluginClient{client: c, broker: broker}, nil
+	return &backendPluginClient{
+		client:                c,
+		broker:                broker,
+		supportedCapabilities: b.Capabilities,
+		protocolVersion:       1,
+	}, nil
 }
@@ -1,9 +1,11 @@
 package plugin
 
 import (
+	"fmt"
 	"net/rpc"
 
 	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 	log "github.com/mgutz/logxi/v1"
 )
@@ -17,6 +19,13 @@ type backendPluginClient struct {
 
 	system logical.SystemView
 	logger log.Logger
+
+	// supportedCapabilities are the capabilities of the protocol supported
+	// by the core, and protocolVersion and capabilities those agreed on with
+	// the plugin during Setup
+	supportedCapabilities []string
+	protocolVersion       uint
+	capabilities          []string
 }
 
 // HandleRequestArgs is the args for HandleRequest method.
@@ -166,6 +175,10 @@ func (b *backendPluginClient) InvalidateKey(key string) {
 }
 
 func (b *backendPluginClient) Setup(config *logical.BackendConfig) error {
+	if err := b.negotiate(); err != nil {
+		return err
+	}
+
 	// Shim logical.Storage
 	storageID := b.broker.NextId()
 	go b.broker.AcceptAndServe(storageID, &StorageServer{
@@ -232,3 +245,47 @@ func (b *backendPluginClient) RegisterLicense(license interface{}) error {
 
 	return nil
 }
+
+// negotiate agrees with the plugin on the protocol version and capabilities
+// to use. Plugins predating negotiation speak version 1 of the protocol,
+// without capabilities.
+func (b *backendPluginClient) negotiate() error {
+	args := &NegotiateArgs{
+		ProtocolVersion:    ProtocolVersion,
+		MinProtocolVersion: MinProtocolVersion,
+		Capabilities:       b.supportedCapabilities,
+	}
+	var reply NegotiateReply
+
+	err := b.client.Call("Plugin.Negotiate", args, &reply)
+	switch {
+	case isMissingMethod(err):
+		if MinProtocolVersion > 1 {
+			return fmt.Errorf("incompatible plugin protocol versions: core supports %d to %d, plugin supports 1",
+				MinProtocolVersion, ProtocolVersion)
+		}
+		b.protocolVersion = 1
+		b.capabilities = nil
+		return nil
+	case err != nil:
+		return err
+	case reply.Error != nil:
+		return reply.Error
+	}
+
+	b.protocolVersion = reply.ProtocolVersion
+	b.capabilities = reply.Capabilities
+	return nil
+}
+
+// ProtocolVersion returns the version of the protocol agreed on with the
+// plugin
+func (b *backendPluginClient) ProtocolVersion() uint {
+	return b.protocolVersion
+}
+
+// HasCapability returns whether both the core and the plugin support the
+// given capability of the protocol
+func (b *backendPluginClient) HasCapability(name string) bool {
+	return strutil.StrListContains(b.capabilities, name)
+}
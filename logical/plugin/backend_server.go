@@ -17,6 +17,14 @@ type backendPluginServer struct {
 	loggerClient  *rpc.Client
 	sysViewClient *rpc.Client
 	storageClient *rpc.Client
+
+	// supportedCapabilities are the capabilities of the protocol supported
+	// by the plugin. The version and capabilities agreed on with the core
+	// are those of version 1 of the protocol, without capabilities, until
+	// negotiated, since cores predating negotiation do not negotiate.
+	supportedCapabilities []string
+	protocolVersion       uint
+	capabilities          []string
 }
 
 func (b *backendPluginServer) HandleRequest(args *HandleRequestArgs, reply *HandleRequestReply) error {
@@ -154,3 +162,20 @@ func (b *backendPluginServer) RegisterLicense(args *RegisterLicenseArgs, reply *
 
 	return nil
 }
+
+// Negotiate agrees with the core on the protocol version and capabilities to
+// use
+func (b *backendPluginServer) Negotiate(args *NegotiateArgs, reply *NegotiateReply) error {
+	negotiated, err := negotiateProtocol(args, b.supportedCapabilities)
+	if err != nil {
+		*reply = NegotiateReply{
+			Error: plugin.NewBasicError(err),
+		}
+		return nil
+	}
+
+	b.protocolVersion = negotiated.ProtocolVersion
+	b.capabilities = negotiated.Capabilities
+	*reply = *negotiated
+	return nil
+}
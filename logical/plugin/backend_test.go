@@ -141,12 +141,107 @@ func TestBackendPlugin_Setup(t *testing.T) {
 	defer cleanup()
 }
 
+func TestBackendPlugin_Negotiate(t *testing.T) {
+	raw, cleanup := testBackendWithPlugins(t, &BackendPlugin{
+		Factory:      mock.Factory,
+		Capabilities: []string{CapabilityStreaming, CapabilityContextCancellation},
+	}, []string{CapabilityContextCancellation, "unknown"})
+	defer cleanup()
+
+	b := raw.(*backendPluginClient)
+	if v := b.ProtocolVersion(); v != ProtocolVersion {
+		t.Fatalf("bad: %d", v)
+	}
+	if !b.HasCapability(CapabilityContextCancellation) || b.HasCapability(CapabilityStreaming) || b.HasCapability("unknown") {
+		t.Fatalf("bad: %#v", b.capabilities)
+	}
+}
+
+// legacyBackendPlugin serves the backend without the Negotiate method, as
+// plugins predating negotiation do
+type legacyBackendPlugin struct {
+	BackendPlugin
+}
+
+type legacyBackendPluginServer struct {
+	*backendPluginServer
+}
+
+// Negotiate hides the method of the embedded server, since net/rpc does not
+// serve methods of this signature
+func (legacyBackendPluginServer) Negotiate() {}
+
+func (b *legacyBackendPlugin) Server(broker *gplugin.MuxBroker) (interface{}, error) {
+	server, _ := b.BackendPlugin.Server(broker)
+	return legacyBackendPluginServer{server.(*backendPluginServer)}, nil
+}
+
+func TestBackendPlugin_NegotiateLegacy(t *testing.T) {
+	raw, cleanup := testBackendWithPlugins(t, &legacyBackendPlugin{
+		BackendPlugin: BackendPlugin{
+			Factory:      mock.Factory,
+			Capabilities: []string{CapabilityStreaming},
+		},
+	}, []string{CapabilityStreaming})
+	defer cleanup()
+
+	b := raw.(*backendPluginClient)
+	if v := b.ProtocolVersion(); v != 1 {
+		t.Fatalf("bad: %d", v)
+	}
+	if b.HasCapability(CapabilityStreaming) {
+		t.Fatal("legacy plugins have no capabilities")
+	}
+
+	// The backend still works
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "test/ing",
+		Data:      map[string]interface{}{"value": "foo"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["value"] != "foo" {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestNegotiateProtocol(t *testing.T) {
+	// Newer cores speak the version of the plugin
+	reply, err := negotiateProtocol(&NegotiateArgs{
+		ProtocolVersion:    ProtocolVersion + 1,
+		MinProtocolVersion: 1,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.ProtocolVersion != ProtocolVersion {
+		t.Fatalf("bad: %d", reply.ProtocolVersion)
+	}
+
+	// Cores which dropped the versions of the plugin are refused
+	_, err = negotiateProtocol(&NegotiateArgs{
+		ProtocolVersion:    ProtocolVersion + 2,
+		MinProtocolVersion: ProtocolVersion + 1,
+	}, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
 func testBackend(t *testing.T) (logical.Backend, func()) {
+	return testBackendWithPlugins(t, &BackendPlugin{
+		Factory: mock.Factory,
+	}, nil)
+}
+
+// testBackendWithPlugins returns the backend served by the given plugin, to a
+// client supporting the given capabilities
+func testBackendWithPlugins(t *testing.T, server gplugin.Plugin, capabilities []string) (logical.Backend, func()) {
 	// Create a mock provider
 	pluginMap := map[string]gplugin.Plugin{
-		"backend": &BackendPlugin{
-			Factory: mock.Factory,
-		},
+		"backend": server,
 	}
 	client, _ := gplugin.TestPluginRPCConn(t, pluginMap)
 	cleanup := func() {
@@ -159,6 +254,7 @@ func testBackend(t *testing.T) (logical.Backend, func()) {
 		t.Fatal(err)
 	}
 	b := raw.(logical.Backend)
+	raw.(*backendPluginClient).supportedCapabilities = capabilities
 
 	err = b.Setup(&logical.BackendConfig{
 		Logger: logformat.NewVaultLogger(log.LevelTrace),
@@ -37,6 +37,12 @@ func (b *BackendPluginClient) Cleanup() {
 	b.client.Kill()
 }
 
+// Exited returns whether the plugin process has exited, typically because it
+// crashed
+func (b *BackendPluginClient) Exited() bool {
+	return b.client.Exited()
+}
+
 // NewBackend will return an instance of an RPC-based client implementation of the backend for
 // external plugins, or a concrete implementation of the backend if it is a builtin backend.
 // The backend is returned as a logical.Backend interface.
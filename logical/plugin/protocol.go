@@ -0,0 +1,86 @@
+package plugin
+
+import (
+	"fmt"
+	"net/rpc"
+	"strings"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/vault/helper/strutil"
+)
+
+const (
+	// ProtocolVersion is the version of the RPC protocol between Vault and
+	// backend plugins implemented by this build. It is negotiated once the
+	// plugin is dispensed rather than during the go-plugin handshake, whose
+	// protocol version stays at 1 so that cores and plugins built against
+	// different versions of this package can still connect.
+	//
+	// Version 1 is the protocol without negotiation, version 2 adds the
+	// Negotiate method.
+	ProtocolVersion = 2
+
+	// MinProtocolVersion is the oldest version of the protocol this build
+	// can speak
+	MinProtocolVersion = 1
+)
+
+const (
+	// CapabilityStreaming is the capability of streaming responses from the
+	// plugin to the core
+	CapabilityStreaming = "streaming"
+
+	// CapabilityContextCancellation is the capability of cancelling the
+	// requests in flight in the plugin
+	CapabilityContextCancellation = "context_cancellation"
+)
+
+// NegotiateArgs is the args for the Negotiate method, with the protocol
+// versions and capabilities supported by the core.
+type NegotiateArgs struct {
+	ProtocolVersion    uint
+	MinProtocolVersion uint
+	Capabilities       []string
+}
+
+// NegotiateReply is the reply for the Negotiate method, with the protocol
+// version and capabilities both sides agreed on.
+type NegotiateReply struct {
+	ProtocolVersion uint
+	Capabilities    []string
+	Error           *plugin.BasicError
+}
+
+// negotiateProtocol picks the highest protocol version supported by both sides, and
+// the capabilities supported by both. It fails if the ranges of versions
+// supported by the two sides do not overlap.
+func negotiateProtocol(args *NegotiateArgs, capabilities []string) (*NegotiateReply, error) {
+	version := args.ProtocolVersion
+	if version > ProtocolVersion {
+		version = ProtocolVersion
+	}
+	if version < args.MinProtocolVersion || version < MinProtocolVersion {
+		return nil, fmt.Errorf("incompatible plugin protocol versions: core supports %d to %d, plugin supports %d to %d",
+			args.MinProtocolVersion, args.ProtocolVersion, MinProtocolVersion, ProtocolVersion)
+	}
+
+	var common []string
+	for _, c := range capabilities {
+		if strutil.StrListContains(args.Capabilities, c) {
+			common = append(common, c)
+		}
+	}
+
+	return &NegotiateReply{
+		ProtocolVersion: version,
+		Capabilities:    common,
+	}, nil
+}
+
+// isMissingMethod returns whether the error is that of a call to a method
+// the plugin does not implement, typically because it was built against an
+// older version of this package
+func isMissingMethod(err error) bool {
+	serr, ok := err.(rpc.ServerError)
+	return ok && strings.HasPrefix(string(serr), "rpc: can't find method ")
+}
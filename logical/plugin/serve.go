@@ -34,6 +34,10 @@ func Serve(opts *ServeOpts) error {
 		return err
 	}
 
+	if err := pluginutil.OptionallyLimitMemory(); err != nil {
+		return err
+	}
+
 	plugin.Serve(&plugin.ServeConfig{
 		HandshakeConfig: handshakeConfig,
 		Plugins:         pluginMap,
@@ -119,6 +119,16 @@ func (s *SystemViewClient) MlockEnabled() bool {
 	return reply.MlockEnabled
 }
 
+func (s *SystemViewClient) ClockSkewTolerance() time.Duration {
+	var reply ClockSkewToleranceReply
+	err := s.client.Call("Plugin.ClockSkewTolerance", new(interface{}), &reply)
+	if err != nil {
+		return 0
+	}
+
+	return reply.ClockSkewTolerance
+}
+
 type SystemViewServer struct {
 	impl logical.SystemView
 }
@@ -202,6 +212,15 @@ func (s *SystemViewServer) MlockEnabled(_ interface{}, reply *MlockEnabledReply)
 	return nil
 }
 
+func (s *SystemViewServer) ClockSkewTolerance(_ interface{}, reply *ClockSkewToleranceReply) error {
+	tolerance := s.impl.ClockSkewTolerance()
+	*reply = ClockSkewToleranceReply{
+		ClockSkewTolerance: tolerance,
+	}
+
+	return nil
+}
+
 type DefaultLeaseTTLReply struct {
 	DefaultLeaseTTL time.Duration
 }
@@ -245,3 +264,7 @@ type ResponseWrapDataReply struct {
 type MlockEnabledReply struct {
 	MlockEnabled bool
 }
+
+type ClockSkewToleranceReply struct {
+	ClockSkewTolerance time.Duration
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"reflect"
+	"time"
 
 	plugin "github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/vault/helper/consts"
@@ -172,3 +173,23 @@ func TestSystem_mlockEnabled(t *testing.T) {
 		t.Fatalf("expected: %v, got: %v", expected, actual)
 	}
 }
+
+func TestSystem_clockSkewTolerance(t *testing.T) {
+	client, server := plugin.TestRPCConn(t)
+	defer client.Close()
+
+	sys := logical.TestSystemView()
+	sys.ClockSkewToleranceVal = 30 * time.Second
+
+	server.RegisterName("Plugin", &SystemViewServer{
+		impl: sys,
+	})
+
+	testSystemView := &SystemViewClient{client: client}
+
+	expected := sys.ClockSkewTolerance()
+	actual := testSystemView.ClockSkewTolerance()
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %v, got: %v", expected, actual)
+	}
+}
@@ -90,6 +90,20 @@ type Request struct {
 	// WrapInfo contains requested response wrapping parameters
 	WrapInfo *RequestWrapInfo `json:"wrap_info" structs:"wrap_info" mapstructure:"wrap_info"`
 
+	// MFACreds holds the passcodes given for MFA methods, keyed by method
+	// name. It is only used by the core and never passed to backends.
+	MFACreds map[string]string `json:"-" structs:"-" mapstructure:"-"`
+
+	// Canary is the name of the canary token tripped by the request, which
+	// the core sets to flag the request in the audit log
+	Canary string `json:"-" structs:"-" mapstructure:"-"`
+
+	// AuditNonHMACReqDataKeys and AuditNonHMACRespDataKeys are the keys of
+	// the request and response data that the audit backends log in
+	// cleartext, which the core sets from the configuration of the mount
+	AuditNonHMACReqDataKeys  []string `json:"-" structs:"-" mapstructure:"-"`
+	AuditNonHMACRespDataKeys []string `json:"-" structs:"-" mapstructure:"-"`
+
 	// ClientTokenRemainingUses represents the allowed number of uses left on the
 	// token supplied
 	ClientTokenRemainingUses int `json:"client_token_remaining_uses" structs:"client_token_remaining_uses" mapstructure:"client_token_remaining_uses"`
@@ -181,6 +195,10 @@ const (
 	ListOperation             = "list"
 	HelpOperation             = "help"
 
+	// PatchOperation updates some fields of the data at a path, leaving the
+	// others as they are
+	PatchOperation = "patch"
+
 	// The operations below are called globally, the path is less relevant.
 	RevokeOperation   Operation = "revoke"
 	RenewOperation              = "renew"
@@ -94,8 +94,11 @@ func RespondErrorCommon(req *Request, resp *Response, err error) (int, error) {
 // conditions in a way that can be shared across http's respondError and other
 // locations.
 func AdjustErrorStatusCode(status *int, err error) {
-	// Adjust status code when sealed
-	if errwrap.Contains(err, consts.ErrSealed.Error()) {
+	// Adjust status code when sealed, when re-acquiring the HA lock or when
+	// the plugin backing the mount is unavailable
+	if errwrap.Contains(err, consts.ErrSealed.Error()) ||
+		errwrap.Contains(err, consts.ErrLeadershipSuspended.Error()) ||
+		errwrap.Contains(err, consts.ErrPluginUnavailable.Error()) {
 		*status = http.StatusServiceUnavailable
 	}
 
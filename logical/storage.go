@@ -25,6 +25,10 @@ type Storage interface {
 type StorageEntry struct {
 	Key   string
 	Value []byte
+
+	// SealWrap requests the entry to be additionally encrypted by the seal
+	// of the server, if the seal supports it
+	SealWrap bool
 }
 
 // DecodeJSON decodes the 'Value' present in StorageEntry.
@@ -52,17 +52,23 @@ type SystemView interface {
 	// MlockEnabled returns the configuration setting for enabling mlock on
 	// plugins.
 	MlockEnabled() bool
+
+	// ClockSkewTolerance returns the leeway backends should give to the
+	// time checks of the credentials they validate, such as the validity
+	// period of certificates, to account for drifting clocks
+	ClockSkewTolerance() time.Duration
 }
 
 type StaticSystemView struct {
-	DefaultLeaseTTLVal  time.Duration
-	MaxLeaseTTLVal      time.Duration
-	SudoPrivilegeVal    bool
-	TaintedVal          bool
-	CachingDisabledVal  bool
-	Primary             bool
-	EnableMlock         bool
-	ReplicationStateVal consts.ReplicationState
+	DefaultLeaseTTLVal    time.Duration
+	MaxLeaseTTLVal        time.Duration
+	SudoPrivilegeVal      bool
+	TaintedVal            bool
+	CachingDisabledVal    bool
+	Primary               bool
+	EnableMlock           bool
+	ReplicationStateVal   consts.ReplicationState
+	ClockSkewToleranceVal time.Duration
 }
 
 func (d StaticSystemView) DefaultLeaseTTL() time.Duration {
@@ -100,3 +106,7 @@ func (d StaticSystemView) LookupPlugin(name string) (*pluginutil.PluginRunner, e
 func (d StaticSystemView) MlockEnabled() bool {
 	return d.EnableMlock
 }
+
+func (d StaticSystemView) ClockSkewTolerance() time.Duration {
+	return d.ClockSkewToleranceVal
+}
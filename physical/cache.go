@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/armon/go-metrics"
 	"github.com/hashicorp/golang-lru"
 	"github.com/hashicorp/vault/helper/locksutil"
 	log "github.com/mgutz/logxi/v1"
@@ -88,6 +89,7 @@ func (c *Cache) Get(key string) (*Entry, error) {
 
 	// Check the LRU first
 	if raw, ok := c.lru.Get(key); ok {
+		metrics.IncrCounter([]string{"cache", "hit"}, 1)
 		if raw == nil {
 			return nil, nil
 		} else {
@@ -95,6 +97,8 @@ func (c *Cache) Get(key string) (*Entry, error) {
 		}
 	}
 
+	metrics.IncrCounter([]string{"cache", "miss"}, 1)
+
 	// Read from the underlying backend
 	ent, err := c.backend.Get(key)
 	if err != nil {
@@ -128,6 +132,11 @@ func (c *Cache) List(prefix string) ([]string, error) {
 	return c.backend.List(prefix)
 }
 
+func (c *Cache) ListPage(prefix, after string, limit int) ([]string, error) {
+	// Always pass-through, like List
+	return ListPage(c.backend, prefix, after, limit)
+}
+
 func (c *Cache) Transaction(txns []TxnEntry) error {
 	if c.transactional == nil {
 		return fmt.Errorf("physical/cache: underlying backend does not support transactions")
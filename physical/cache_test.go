@@ -14,6 +14,7 @@ func TestCache(t *testing.T) {
 	cache := NewCache(inm, 0, logger)
 	testBackend(t, cache)
 	testBackend_ListPrefix(t, cache)
+	testBackend_ListPage(t, cache)
 }
 
 func TestCache_Purge(t *testing.T) {
@@ -417,6 +417,17 @@ func (c *ConsulBackend) List(prefix string) ([]string, error) {
 	return out, err
 }
 
+// ListPage is used to list a page of the keys under a given prefix. The KV
+// API of Consul cannot page listings, so the keys are still fetched at once,
+// but only a page of them is kept.
+func (c *ConsulBackend) ListPage(prefix, after string, limit int) ([]string, error) {
+	keys, err := c.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return PageKeys(keys, after, limit), nil
+}
+
 // Lock is used for mutual exclusion based on the given key.
 func (c *ConsulBackend) LockWith(key, value string) (Lock, error) {
 	// Create the lock
@@ -433,6 +444,7 @@ func (c *ConsulBackend) LockWith(key, value string) (Lock, error) {
 	cl := &ConsulLock{
 		client:          c.client,
 		key:             c.path + key,
+		value:           value,
 		lock:            lock,
 		consistencyMode: c.consistencyMode,
 	}
@@ -463,6 +475,7 @@ func (c *ConsulBackend) DetectHostAddr() (string, error) {
 type ConsulLock struct {
 	client          *api.Client
 	key             string
+	value           string
 	lock            *api.Lock
 	consistencyMode string
 }
@@ -475,6 +488,21 @@ func (c *ConsulLock) Unlock() error {
 	return c.lock.Unlock()
 }
 
+// FencingToken returns the lock index of the key, which Consul increments
+// each time the key is acquired by a session
+func (c *ConsulLock) FencingToken() (uint64, error) {
+	pair, _, err := c.client.KV().Get(c.key, &api.QueryOptions{
+		RequireConsistent: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if pair == nil || pair.Session == "" || string(pair.Value) != c.value {
+		return 0, fmt.Errorf("lock not held")
+	}
+	return pair.LockIndex, nil
+}
+
 func (c *ConsulLock) Value() (bool, string, error) {
 	kv := c.client.KV()
 
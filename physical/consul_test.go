@@ -423,6 +423,7 @@ func TestConsulBackend(t *testing.T) {
 
 	testBackend(t, b)
 	testBackend_ListPrefix(t, b)
+	testBackend_ListPage(t, b)
 }
 
 func TestConsulHABackend(t *testing.T) {
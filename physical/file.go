@@ -1,14 +1,18 @@
 package physical
 
 import (
+	"container/heap"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/armon/go-metrics"
 	log "github.com/mgutz/logxi/v1"
 
 	"github.com/hashicorp/vault/helper/consts"
@@ -64,6 +68,8 @@ func newTransactionalFileBackend(conf map[string]string, logger log.Logger) (Bac
 }
 
 func (b *FileBackend) Delete(path string) error {
+	defer metrics.MeasureSince([]string{"file", "delete"}, time.Now())
+
 	b.permitPool.Acquire()
 	defer b.permitPool.Release()
 
@@ -133,6 +139,8 @@ func (b *FileBackend) cleanupLogicalPath(path string) error {
 }
 
 func (b *FileBackend) Get(k string) (*Entry, error) {
+	defer metrics.MeasureSince([]string{"file", "get"}, time.Now())
+
 	b.permitPool.Acquire()
 	defer b.permitPool.Release()
 
@@ -171,6 +179,8 @@ func (b *FileBackend) GetInternal(k string) (*Entry, error) {
 }
 
 func (b *FileBackend) Put(entry *Entry) error {
+	defer metrics.MeasureSince([]string{"file", "put"}, time.Now())
+
 	b.permitPool.Acquire()
 	defer b.permitPool.Release()
 
@@ -208,6 +218,8 @@ func (b *FileBackend) PutInternal(entry *Entry) error {
 }
 
 func (b *FileBackend) List(prefix string) ([]string, error) {
+	defer metrics.MeasureSince([]string{"file", "list"}, time.Now())
+
 	b.permitPool.Acquire()
 	defer b.permitPool.Release()
 
@@ -246,16 +258,109 @@ func (b *FileBackend) ListInternal(prefix string) ([]string, error) {
 	}
 
 	for i, name := range names {
-		if name[0] == '_' {
-			names[i] = name[1:]
-		} else {
-			names[i] = name + "/"
-		}
+		names[i] = fileKeyName(name)
 	}
 
 	return names, nil
 }
 
+// fileListBatch is the number of directory entries read at once by ListPage
+const fileListBatch = 1024
+
+// ListPage lists a page of the keys under a given prefix. The directory is
+// read in batches, keeping only the smallest keys sorted after the given key,
+// so that large directories are not held in memory at once.
+func (b *FileBackend) ListPage(prefix, after string, limit int) ([]string, error) {
+	if limit <= 0 {
+		keys, err := b.List(prefix)
+		if err != nil {
+			return nil, err
+		}
+		return PageKeys(keys, after, limit), nil
+	}
+
+	b.permitPool.Acquire()
+	defer b.permitPool.Release()
+
+	b.RLock()
+	defer b.RUnlock()
+
+	if err := b.validatePath(prefix); err != nil {
+		return nil, err
+	}
+
+	path := b.path
+	if prefix != "" {
+		path = filepath.Join(path, prefix)
+	}
+
+	f, err := os.Open(path)
+	if f != nil {
+		defer f.Close()
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	page := &keyHeap{}
+	for {
+		names, err := f.Readdirnames(fileListBatch)
+		for _, name := range names {
+			key := fileKeyName(name)
+			switch {
+			case key <= after:
+			case page.Len() < limit:
+				heap.Push(page, key)
+			case key < (*page)[0]:
+				(*page)[0] = key
+				heap.Fix(page, 0)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	keys := []string(*page)
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// fileKeyName returns the key of a directory entry: entries are either
+// files prefixed with an underscore, or directories
+func fileKeyName(name string) string {
+	if name[0] == '_' {
+		return name[1:]
+	}
+	return name + "/"
+}
+
+// keyHeap is a max-heap of keys, used to keep the smallest keys of a listing
+type keyHeap []string
+
+func (h keyHeap) Len() int           { return len(h) }
+func (h keyHeap) Less(i, j int) bool { return h[i] > h[j] }
+func (h keyHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *keyHeap) Push(x interface{}) {
+	*h = append(*h, x.(string))
+}
+
+func (h *keyHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
 func (b *FileBackend) expandPath(k string) (string, string) {
 	path := filepath.Join(b.path, k)
 	key := filepath.Base(path)
@@ -173,4 +173,5 @@ func TestFileBackend(t *testing.T) {
 
 	testBackend(t, b)
 	testBackend_ListPrefix(t, b)
+	testBackend_ListPage(t, b)
 }
@@ -3,9 +3,11 @@ package physical
 import (
 	"strings"
 	"sync"
+	"time"
 
 	log "github.com/mgutz/logxi/v1"
 
+	"github.com/armon/go-metrics"
 	"github.com/armon/go-radix"
 )
 
@@ -48,6 +50,8 @@ func NewTransactionalInmem(logger log.Logger) *TransactionalInmemBackend {
 
 // Put is used to insert or update an entry
 func (i *InmemBackend) Put(entry *Entry) error {
+	defer metrics.MeasureSince([]string{"inmem", "put"}, time.Now())
+
 	i.permitPool.Acquire()
 	defer i.permitPool.Release()
 
@@ -64,6 +68,8 @@ func (i *InmemBackend) PutInternal(entry *Entry) error {
 
 // Get is used to fetch an entry
 func (i *InmemBackend) Get(key string) (*Entry, error) {
+	defer metrics.MeasureSince([]string{"inmem", "get"}, time.Now())
+
 	i.permitPool.Acquire()
 	defer i.permitPool.Release()
 
@@ -82,6 +88,8 @@ func (i *InmemBackend) GetInternal(key string) (*Entry, error) {
 
 // Delete is used to permanently delete an entry
 func (i *InmemBackend) Delete(key string) error {
+	defer metrics.MeasureSince([]string{"inmem", "delete"}, time.Now())
+
 	i.permitPool.Acquire()
 	defer i.permitPool.Release()
 
@@ -99,6 +107,8 @@ func (i *InmemBackend) DeleteInternal(key string) error {
 // List is used ot list all the keys under a given
 // prefix, up to the next prefix.
 func (i *InmemBackend) List(prefix string) ([]string, error) {
+	defer metrics.MeasureSince([]string{"inmem", "list"}, time.Now())
+
 	i.permitPool.Acquire()
 	defer i.permitPool.Release()
 
@@ -130,6 +140,38 @@ func (i *InmemBackend) ListInternal(prefix string) ([]string, error) {
 	return out, nil
 }
 
+// ListPage is used to list a page of the keys under a given prefix, walking
+// the keys in order and stopping once the page is full
+func (i *InmemBackend) ListPage(prefix, after string, limit int) ([]string, error) {
+	i.permitPool.Acquire()
+	defer i.permitPool.Release()
+
+	i.RLock()
+	defer i.RUnlock()
+
+	var out []string
+	walkFn := func(s string, v interface{}) bool {
+		trimmed := strings.TrimPrefix(s, prefix)
+		if sep := strings.Index(trimmed, "/"); sep != -1 {
+			trimmed = trimmed[:sep+1]
+		}
+
+		// The keys of a folder are contiguous, so it has already been seen
+		// if it is the last key listed
+		if trimmed <= after || (len(out) > 0 && out[len(out)-1] == trimmed) {
+			return false
+		}
+		if limit > 0 && len(out) == limit {
+			return true
+		}
+		out = append(out, trimmed)
+		return false
+	}
+	i.root.WalkPrefix(prefix, walkFn)
+
+	return out, nil
+}
+
 // Implements the transaction interface
 func (t *TransactionalInmemBackend) Transaction(txns []TxnEntry) error {
 	t.permitPool.Acquire()
@@ -0,0 +1,188 @@
+package physical
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/mgutz/logxi/v1"
+)
+
+// ErrInjectedFault is returned by the operations of a FaultyInmemBackend
+// failed by a fault without an error of its own
+var ErrInjectedFault = errors.New("injected fault")
+
+// FaultyInmemBackend is an in-memory physical backend whose operations can be
+// made to fail, slowed down, or return partial results, to test how their
+// callers handle storage failures.
+type FaultyInmemBackend struct {
+	*InmemBackend
+
+	l          sync.Mutex
+	faults     []*inmemFault
+	latency    map[Operation]time.Duration
+	listLimit  int
+	operations map[Operation]int
+}
+
+// inmemFault fails the operations on the keys under a prefix, starting with
+// the nth one, either once or until cleared
+type inmemFault struct {
+	op     Operation
+	prefix string
+	nth    int
+	once   bool
+	err    error
+}
+
+// NewInmemFaulty constructs a new in-memory backend without faults
+func NewInmemFaulty(logger log.Logger) *FaultyInmemBackend {
+	return &FaultyInmemBackend{
+		InmemBackend: NewInmem(logger),
+		latency:      make(map[Operation]time.Duration),
+		listLimit:    -1,
+		operations:   make(map[Operation]int),
+	}
+}
+
+// FailNth fails the nth operation from now on the keys under the prefix, 1
+// being the next one. The error is ErrInjectedFault if nil.
+func (f *FaultyInmemBackend) FailNth(op Operation, prefix string, n int, err error) {
+	f.addFault(op, prefix, n, true, err)
+}
+
+// Fail fails all the operations on the keys under the prefix until the
+// faults are cleared. The error is ErrInjectedFault if nil.
+func (f *FaultyInmemBackend) Fail(op Operation, prefix string, err error) {
+	f.addFault(op, prefix, 1, false, err)
+}
+
+func (f *FaultyInmemBackend) addFault(op Operation, prefix string, n int, once bool, err error) {
+	if err == nil {
+		err = ErrInjectedFault
+	}
+	f.l.Lock()
+	defer f.l.Unlock()
+	f.faults = append(f.faults, &inmemFault{
+		op:     op,
+		prefix: prefix,
+		nth:    n,
+		once:   once,
+		err:    err,
+	})
+}
+
+// SetLatency delays all the operations of the given type by the duration
+func (f *FaultyInmemBackend) SetLatency(op Operation, d time.Duration) {
+	f.l.Lock()
+	defer f.l.Unlock()
+	f.latency[op] = d
+}
+
+// TruncateLists makes the lists return at most n keys, as backends which
+// cannot list consistently may, or all of them if n is negative
+func (f *FaultyInmemBackend) TruncateLists(n int) {
+	f.l.Lock()
+	defer f.l.Unlock()
+	f.listLimit = n
+}
+
+// ClearFaults removes the faults, latencies and list truncation
+func (f *FaultyInmemBackend) ClearFaults() {
+	f.l.Lock()
+	defer f.l.Unlock()
+	f.faults = nil
+	f.latency = make(map[Operation]time.Duration)
+	f.listLimit = -1
+}
+
+// Operations returns the number of operations of the given type performed,
+// including those which failed
+func (f *FaultyInmemBackend) Operations(op Operation) int {
+	f.l.Lock()
+	defer f.l.Unlock()
+	return f.operations[op]
+}
+
+// inject counts the operation, waits for its latency, and returns the error
+// of the first fault failing it
+func (f *FaultyInmemBackend) inject(op Operation, key string) error {
+	f.l.Lock()
+	f.operations[op]++
+	latency := f.latency[op]
+	var err error
+	faults := f.faults[:0]
+	for _, fault := range f.faults {
+		if fault.op == op && strings.HasPrefix(key, fault.prefix) {
+			fault.nth--
+			if fault.nth <= 0 && err == nil {
+				err = fault.err
+				if fault.once {
+					continue
+				}
+			}
+		}
+		faults = append(faults, fault)
+	}
+	f.faults = faults
+	f.l.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	return err
+}
+
+// truncate applies the list truncation to the keys
+func (f *FaultyInmemBackend) truncate(keys []string) []string {
+	f.l.Lock()
+	defer f.l.Unlock()
+	if f.listLimit >= 0 && len(keys) > f.listLimit {
+		keys = keys[:f.listLimit]
+	}
+	return keys
+}
+
+func (f *FaultyInmemBackend) Put(entry *Entry) error {
+	if err := f.inject(PutOperation, entry.Key); err != nil {
+		return err
+	}
+	return f.InmemBackend.Put(entry)
+}
+
+func (f *FaultyInmemBackend) Get(key string) (*Entry, error) {
+	if err := f.inject(GetOperation, key); err != nil {
+		return nil, err
+	}
+	return f.InmemBackend.Get(key)
+}
+
+func (f *FaultyInmemBackend) Delete(key string) error {
+	if err := f.inject(DeleteOperation, key); err != nil {
+		return err
+	}
+	return f.InmemBackend.Delete(key)
+}
+
+func (f *FaultyInmemBackend) List(prefix string) ([]string, error) {
+	if err := f.inject(ListOperation, prefix); err != nil {
+		return nil, err
+	}
+	keys, err := f.InmemBackend.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return f.truncate(keys), nil
+}
+
+func (f *FaultyInmemBackend) ListPage(prefix, after string, limit int) ([]string, error) {
+	if err := f.inject(ListOperation, prefix); err != nil {
+		return nil, err
+	}
+	keys, err := f.InmemBackend.ListPage(prefix, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	return f.truncate(keys), nil
+}
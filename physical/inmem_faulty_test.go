@@ -0,0 +1,78 @@
+package physical
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestInmemFaulty(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	// Without faults it behaves as the in-memory backend
+	inm := NewInmemFaulty(logger)
+	testBackend(t, inm)
+	testBackend_ListPrefix(t, inm)
+	testBackend_ListPage(t, inm)
+}
+
+func TestInmemFaulty_Faults(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	inm := NewInmemFaulty(logger)
+
+	put := func(key string) error {
+		return inm.Put(&Entry{Key: key, Value: []byte("bar")})
+	}
+
+	// Only the nth operation on keys under the prefix fails
+	inm.FailNth(PutOperation, "foo/", 2, nil)
+	for i, key := range []string{"foo/a", "bar", "foo/b", "foo/c"} {
+		err := put(key)
+		if (i == 2) != (err == ErrInjectedFault) {
+			t.Fatalf("put %d: bad: %v", i, err)
+		}
+	}
+	if entry, _ := inm.Get("foo/b"); entry != nil {
+		t.Fatal("failed put stored")
+	}
+
+	// Persistent faults fail until cleared
+	errList := errors.New("list failed")
+	inm.Fail(ListOperation, "", errList)
+	for i := 0; i < 2; i++ {
+		if _, err := inm.List("foo/"); err != errList {
+			t.Fatalf("bad: %v", err)
+		}
+	}
+	inm.ClearFaults()
+	if keys, err := inm.List("foo/"); err != nil || len(keys) != 2 {
+		t.Fatalf("bad: %v %v", keys, err)
+	}
+
+	// Lists can be truncated
+	inm.TruncateLists(1)
+	if keys, err := inm.List("foo/"); err != nil || len(keys) != 1 {
+		t.Fatalf("bad: %v %v", keys, err)
+	}
+	if keys, err := inm.ListPage("foo/", "", 10); err != nil || len(keys) != 1 {
+		t.Fatalf("bad: %v %v", keys, err)
+	}
+	inm.ClearFaults()
+
+	// Operations can be slowed down
+	inm.SetLatency(GetOperation, 20*time.Millisecond)
+	start := time.Now()
+	if _, err := inm.Get("foo/a"); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatal("expected latency")
+	}
+
+	if n := inm.Operations(PutOperation); n != 4 {
+		t.Fatalf("bad: %d", n)
+	}
+}
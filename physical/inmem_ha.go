@@ -10,6 +10,8 @@ import (
 type InmemHABackend struct {
 	Backend
 	locks  map[string]string
+	tokens map[string]uint64
+	holder map[string]*InmemLock
 	l      sync.Mutex
 	cond   *sync.Cond
 	logger log.Logger
@@ -25,6 +27,8 @@ func NewInmemHA(logger log.Logger) *InmemHABackend {
 	in := &InmemHABackend{
 		Backend: NewInmem(logger),
 		locks:   make(map[string]string),
+		tokens:  make(map[string]uint64),
+		holder:  make(map[string]*InmemLock),
 		logger:  logger,
 	}
 	in.cond = sync.NewCond(&in.l)
@@ -36,6 +40,8 @@ func NewTransactionalInmemHA(logger log.Logger) *TransactionalInmemHABackend {
 	inmemHA := InmemHABackend{
 		Backend: transInmem,
 		locks:   make(map[string]string),
+		tokens:  make(map[string]uint64),
+		holder:  make(map[string]*InmemLock),
 		logger:  logger,
 	}
 
@@ -57,12 +63,59 @@ func (i *InmemHABackend) LockWith(key, value string) (Lock, error) {
 	return l, nil
 }
 
+// InvalidateLock makes the holder of the lock of the given key lose it, as
+// when the session backing a lock expires. This is only for testing.
+func (i *InmemHABackend) InvalidateLock(key string) {
+	i.l.Lock()
+	holder := i.holder[key]
+	delete(i.locks, key)
+	delete(i.holder, key)
+	i.l.Unlock()
+	i.cond.Broadcast()
+
+	if holder != nil {
+		holder.lost()
+	}
+}
+
+// TakeOverLock makes the holder of the lock of the given key lose it to a
+// new holder with the given value, as when another node acquires the lock
+// right after the session of the holder expired. The new holder is returned
+// so that it can release the lock. This is only for testing.
+func (i *InmemHABackend) TakeOverLock(key, value string) Lock {
+	l := &InmemLock{
+		in:       i,
+		key:      key,
+		value:    value,
+		held:     true,
+		leaderCh: make(chan struct{}),
+	}
+
+	i.l.Lock()
+	holder := i.holder[key]
+	i.locks[key] = value
+	i.tokens[key]++
+	i.holder[key] = l
+	l.token = i.tokens[key]
+	i.l.Unlock()
+
+	if holder != nil {
+		holder.lost()
+	}
+	return l
+}
+
 // LockMapSize is used in some tests to determine whether this backend has ever
 // been used for HA purposes rather than simply for storage
 func (i *InmemHABackend) LockMapSize() int {
 	return len(i.locks)
 }
 
+// ListPage lists a page of the keys of the underlying backend
+func (i *InmemHABackend) ListPage(prefix, after string, limit int) ([]string, error) {
+	return ListPage(i.Backend, prefix, after, limit)
+}
+
 // HAEnabled indicates whether the HA functionality should be exposed.
 // Currently always returns true.
 func (i *InmemHABackend) HAEnabled() bool {
@@ -76,6 +129,7 @@ type InmemLock struct {
 	value string
 
 	held     bool
+	token    uint64
 	leaderCh chan struct{}
 	l        sync.Mutex
 }
@@ -88,6 +142,7 @@ func (i *InmemLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
 	}
 
 	// Attempt an async acquisition
+	var token uint64
 	didLock := make(chan struct{})
 	releaseCh := make(chan bool, 1)
 	go func() {
@@ -99,6 +154,9 @@ func (i *InmemLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
 			_, ok = i.in.locks[i.key]
 		}
 		i.in.locks[i.key] = i.value
+		i.in.tokens[i.key]++
+		i.in.holder[i.key] = i
+		token = i.in.tokens[i.key]
 		i.in.l.Unlock()
 
 		// Signal that lock is held
@@ -109,6 +167,7 @@ func (i *InmemLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
 		if release {
 			i.in.l.Lock()
 			delete(i.in.locks, i.key)
+			delete(i.in.holder, i.key)
 			i.in.l.Unlock()
 			i.in.cond.Broadcast()
 		}
@@ -125,6 +184,7 @@ func (i *InmemLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
 
 	// Create the leader channel
 	i.held = true
+	i.token = token
 	i.leaderCh = make(chan struct{})
 	return i.leaderCh, nil
 }
@@ -143,11 +203,37 @@ func (i *InmemLock) Unlock() error {
 
 	i.in.l.Lock()
 	delete(i.in.locks, i.key)
+	delete(i.in.holder, i.key)
 	i.in.l.Unlock()
 	i.in.cond.Broadcast()
 	return nil
 }
 
+// lost closes the leader channel after the lock was invalidated
+func (i *InmemLock) lost() {
+	i.l.Lock()
+	defer i.l.Unlock()
+
+	if !i.held {
+		return
+	}
+	close(i.leaderCh)
+	i.leaderCh = nil
+	i.held = false
+}
+
+// FencingToken returns the number of times the lock was acquired, up to and
+// including the current acquisition
+func (i *InmemLock) FencingToken() (uint64, error) {
+	i.l.Lock()
+	defer i.l.Unlock()
+
+	if !i.held {
+		return 0, fmt.Errorf("lock not held")
+	}
+	return i.token, nil
+}
+
 func (i *InmemLock) Value() (bool, string, error) {
 	i.in.l.Lock()
 	val, ok := i.in.locks[i.key]
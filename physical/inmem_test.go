@@ -13,4 +13,8 @@ func TestInmem(t *testing.T) {
 	inm := NewInmem(logger)
 	testBackend(t, inm)
 	testBackend_ListPrefix(t, inm)
+	testBackend_ListPage(t, inm)
+
+	// Backends which cannot page are listed at once
+	testBackend_ListPage(t, struct{ Backend }{inm})
 }
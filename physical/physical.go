@@ -2,6 +2,7 @@ package physical
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	log "github.com/mgutz/logxi/v1"
@@ -43,6 +44,44 @@ type Backend interface {
 	List(prefix string) ([]string, error)
 }
 
+// ListPager is an optional interface for backends which can list the keys
+// under a prefix a page at a time, so that large prefixes do not have to be
+// listed at once
+type ListPager interface {
+	// ListPage lists the keys under the given prefix, up to the next prefix,
+	// like List. Only the keys sorted after the given key are returned, in
+	// lexical order, and at most limit of them if limit is positive.
+	ListPage(prefix, after string, limit int) ([]string, error)
+}
+
+// ListPage lists a page of the keys under the prefix of the backend. Backends
+// which are not ListPagers have the whole prefix listed and paged afterwards.
+func ListPage(b Backend, prefix, after string, limit int) ([]string, error) {
+	if pager, ok := b.(ListPager); ok {
+		return pager.ListPage(prefix, after, limit)
+	}
+
+	keys, err := b.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return PageKeys(keys, after, limit), nil
+}
+
+// PageKeys sorts the keys and returns at most limit of those sorted after the
+// given key, or all of them if limit is not positive
+func PageKeys(keys []string, after string, limit int) []string {
+	sort.Strings(keys)
+	i := sort.Search(len(keys), func(i int) bool {
+		return keys[i] > after
+	})
+	keys = keys[i:]
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys
+}
+
 // HABackend is an extensions to the standard physical
 // backend to support high-availability. Vault only expects to
 // use mutual exclusion to allow multiple instances to act as a
@@ -106,10 +145,26 @@ type Lock interface {
 	Value() (bool, string, error)
 }
 
+// FencedLock is an optional interface for locks which number their
+// acquisitions. The fencing token increases each time the lock is acquired,
+// by any holder, so that a holder which lost the lock and acquired it again
+// can tell whether anybody else held it in between.
+type FencedLock interface {
+	Lock
+
+	// FencingToken returns the fencing token of the current acquisition of
+	// the lock. It must only be called while the lock is held.
+	FencingToken() (uint64, error)
+}
+
 // Entry is used to represent data stored by the physical backend
 type Entry struct {
 	Key   string
 	Value []byte
+
+	// SealWrap is set if the value is, or is to be, additionally encrypted
+	// by the seal of the server, for seals which support it
+	SealWrap bool
 }
 
 // Factory is the factory function to create a physical backend.
@@ -286,6 +286,56 @@ func testBackend_ListPrefix(t *testing.T, b Backend) {
 	}
 }
 
+func testBackend_ListPage(t *testing.T, b Backend) {
+	keys := []string{"page/a", "page/b/x", "page/b/y", "page/b-c", "page/d"}
+	for _, key := range keys {
+		if err := b.Put(&Entry{Key: key, Value: []byte("test")}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	defer func() {
+		for _, key := range keys {
+			b.Delete(key)
+		}
+	}()
+
+	// Page through the prefix two keys at a time
+	var pages [][]string
+	after := ""
+	for {
+		page, err := ListPage(b, "page/", after, 2)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		pages = append(pages, page)
+		after = page[len(page)-1]
+	}
+	exp := [][]string{{"a", "b-c"}, {"b/", "d"}}
+	if !reflect.DeepEqual(pages, exp) {
+		t.Fatalf("bad: %v", pages)
+	}
+
+	// Without a limit, all the keys after the given one are listed
+	page, err := ListPage(b, "page/", "b-c", 0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if exp := []string{"b/", "d"}; !reflect.DeepEqual(page, exp) {
+		t.Fatalf("bad: %v", page)
+	}
+
+	page, err = ListPage(b, "page/b/", "", 10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if exp := []string{"x", "y"}; !reflect.DeepEqual(page, exp) {
+		t.Fatalf("bad: %v", page)
+	}
+}
+
 func testHABackend(t *testing.T, b HABackend, b2 HABackend) {
 	// Get the lock
 	lock, err := b.LockWith("foo", "bar")
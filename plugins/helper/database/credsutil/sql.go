@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/helper/usernametemplate"
 )
 
 // SQLCredentialsProducer implements CredentialsProducer and provides a generic credentials producer for most sql database types.
@@ -17,6 +18,10 @@ type SQLCredentialsProducer struct {
 }
 
 func (scp *SQLCredentialsProducer) GenerateUsername(config dbplugin.UsernameConfig) (string, error) {
+	if config.Template != "" {
+		return scp.generateTemplatedUsername(config)
+	}
+
 	displayName := config.DisplayName
 	if scp.DisplayNameLen > 0 && len(displayName) > scp.DisplayNameLen {
 		displayName = displayName[:scp.DisplayNameLen]
@@ -39,6 +44,23 @@ func (scp *SQLCredentialsProducer) GenerateUsername(config dbplugin.UsernameConf
 	return username, nil
 }
 
+// generateTemplatedUsername generates the username from the template of
+// the config. Templated usernames are never truncated, so as not to cut off
+// their random part; those too long for the database are rejected.
+func (scp *SQLCredentialsProducer) generateTemplatedUsername(config dbplugin.UsernameConfig) (string, error) {
+	username, err := usernametemplate.Generate(config.Template, usernametemplate.Data{
+		RoleName:    config.RoleName,
+		DisplayName: config.DisplayName,
+	})
+	if err != nil {
+		return "", err
+	}
+	if scp.UsernameLen > 0 && len(username) > scp.UsernameLen {
+		return "", fmt.Errorf("username %q generated from the template is longer than the maximum of %d characters", username, scp.UsernameLen)
+	}
+	return username, nil
+}
+
 func (scp *SQLCredentialsProducer) GeneratePassword() (string, error) {
 	password, err := RandomAlphaNumeric(20)
 	if err != nil {
@@ -0,0 +1,39 @@
+package credsutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+)
+
+func TestSQLCredentialsProducer_GenerateUsername_template(t *testing.T) {
+	scp := &SQLCredentialsProducer{
+		DisplayNameLen: 4,
+		RoleNameLen:    4,
+		UsernameLen:    20,
+		Separator:      "-",
+	}
+
+	username, err := scp.GenerateUsername(dbplugin.UsernameConfig{
+		DisplayName: "token",
+		RoleName:    "readonly",
+		Template:    `app_{{.RoleName}}_{{random 4}}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(username, "app_readonly_") || len(username) != 17 {
+		t.Fatalf("bad: %q", username)
+	}
+
+	// Templated usernames too long for the database are rejected rather
+	// than truncated
+	_, err = scp.GenerateUsername(dbplugin.UsernameConfig{
+		RoleName: "readonly",
+		Template: `app_{{.RoleName}}_{{random 8}}`,
+	})
+	if err == nil || !strings.Contains(err.Error(), "longer than the maximum of 20 characters") {
+		t.Fatalf("expected an error, got %v", err)
+	}
+}
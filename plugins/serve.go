@@ -21,6 +21,11 @@ func Serve(plugin interface{}, tlsConfig *api.TLSConfig) {
 		return
 	}
 
+	if err := pluginutil.OptionallyLimitMemory(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
 	switch p := plugin.(type) {
 	case dbplugin.Database:
 		dbplugin.Serve(p, tlsProvider)
@@ -1,8 +1,10 @@
 package vault
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/armon/go-radix"
 	"github.com/hashicorp/errwrap"
@@ -56,6 +58,8 @@ func NewACL(policies []*Policy) (*ACL, error) {
 				if err != nil {
 					return nil, errwrap.Wrapf("error cloning ACL permissions: {{err}}", err)
 				}
+				clonedPerms.CapabilitiesBitmap, clonedPerms.WindowedCapabilities = splitCapabilities(pc.Permissions)
+				clonedPerms.AllowedWindows = nil
 				tree.Insert(pc.Prefix, clonedPerms)
 				continue
 			}
@@ -74,12 +78,17 @@ func NewACL(policies []*Policy) (*ACL, error) {
 				existingPerms.CapabilitiesBitmap = DenyCapabilityInt
 				existingPerms.AllowedParameters = nil
 				existingPerms.DeniedParameters = nil
+				existingPerms.WindowedCapabilities = nil
 				goto INSERT
 
 			default:
 				// Insert the capabilities in this new policy into the existing
-				// value
-				existingPerms.CapabilitiesBitmap = existingPerms.CapabilitiesBitmap | pc.Permissions.CapabilitiesBitmap
+				// value. Capabilities restricted to allowed windows are kept
+				// with their windows, so that they do not apply to the
+				// capabilities of other policies.
+				capabilities, windowed := splitCapabilities(pc.Permissions)
+				existingPerms.CapabilitiesBitmap = existingPerms.CapabilitiesBitmap | capabilities
+				existingPerms.WindowedCapabilities = append(existingPerms.WindowedCapabilities, windowed...)
 			}
 
 			// Note: In these stanzas, we're preferring minimum lifetimes. So
@@ -148,6 +157,33 @@ func NewACL(policies []*Policy) (*ACL, error) {
 	return a, nil
 }
 
+// splitCapabilities returns the capabilities of the permissions granted at
+// all times, and those only granted within their allowed windows. Deny is
+// never restricted to windows.
+func splitCapabilities(p *Permissions) (uint32, []WindowedCapabilities) {
+	if len(p.AllowedWindows) == 0 || p.CapabilitiesBitmap&DenyCapabilityInt > 0 {
+		return p.CapabilitiesBitmap, nil
+	}
+	return 0, []WindowedCapabilities{
+		{
+			CapabilitiesBitmap: p.CapabilitiesBitmap,
+			AllowedWindows:     p.AllowedWindows,
+		},
+	}
+}
+
+// capabilitiesAt returns the capabilities the permissions grant at the given
+// time
+func (p *Permissions) capabilitiesAt(t time.Time) uint32 {
+	capabilities := p.CapabilitiesBitmap
+	for _, windowed := range p.WindowedCapabilities {
+		if windowed.AllowedWindows.Allows(t) {
+			capabilities |= windowed.CapabilitiesBitmap
+		}
+	}
+	return capabilities
+}
+
 func (a *ACL) Capabilities(path string) (pathCapabilities []string) {
 	// Fast-path root
 	if a.root {
@@ -158,9 +194,10 @@ func (a *ACL) Capabilities(path string) (pathCapabilities []string) {
 	var capabilities uint32
 	raw, ok := a.exactRules.Get(path)
 
+	var perm *Permissions
 	if ok {
-		perm := raw.(*Permissions)
-		capabilities = perm.CapabilitiesBitmap
+		perm = raw.(*Permissions)
+		capabilities = perm.capabilitiesAt(time.Now())
 		goto CHECK
 	}
 
@@ -169,8 +206,8 @@ func (a *ACL) Capabilities(path string) (pathCapabilities []string) {
 	if !ok {
 		return []string{DenyCapability}
 	} else {
-		perm := raw.(*Permissions)
-		capabilities = perm.CapabilitiesBitmap
+		perm = raw.(*Permissions)
+		capabilities = perm.capabilitiesAt(time.Now())
 	}
 
 CHECK:
@@ -192,6 +229,9 @@ CHECK:
 	if capabilities&CreateCapabilityInt > 0 {
 		pathCapabilities = append(pathCapabilities, CreateCapability)
 	}
+	if capabilities&ApproveCapabilityInt > 0 {
+		pathCapabilities = append(pathCapabilities, ApproveCapability)
+	}
 
 	// If "deny" is explicitly set or if the path has no capabilities at all,
 	// set the path capabilities to "deny"
@@ -201,20 +241,59 @@ CHECK:
 	return
 }
 
+// HasAccessUnder returns true if the ACL grants any capability on the given
+// path prefix or on a path below it. It is used to decide which mounts to
+// show a token, not to authorize requests.
+func (a *ACL) HasAccessUnder(prefix string) bool {
+	if a.root {
+		return true
+	}
+
+	now := time.Now()
+	grants := func(raw interface{}) bool {
+		capabilities := raw.(*Permissions).capabilitiesAt(now)
+		return capabilities&DenyCapabilityInt == 0 && capabilities != 0
+	}
+
+	// A glob rule covering the prefix itself
+	if _, raw, ok := a.globRules.LongestPrefix(prefix); ok && grants(raw) {
+		return true
+	}
+
+	// Any rule on a path below the prefix
+	found := false
+	walkFn := func(s string, raw interface{}) bool {
+		found = grants(raw)
+		return found
+	}
+	a.exactRules.WalkPrefix(prefix, walkFn)
+	if !found {
+		a.globRules.WalkPrefix(prefix, walkFn)
+	}
+	return found
+}
+
 // AllowOperation is used to check if the given operation is permitted. The
 // first bool indicates if an op is allowed, the second whether sudo priviliges
 // exist for that op and path.
 func (a *ACL) AllowOperation(req *logical.Request) (bool, bool) {
+	allowed, sudo, _ := a.allowOperation(req)
+	return allowed, sudo
+}
+
+// allowOperation is AllowOperation also returning the reason for which the
+// operation is allowed or denied
+func (a *ACL) allowOperation(req *logical.Request) (bool, bool, string) {
 	// Fast-path root
 	if a.root {
-		return true, true
+		return true, true, "the root policy grants every operation"
 	}
 	op := req.Operation
 	path := req.Path
 
 	// Help is always allowed
 	if op == logical.HelpOperation {
-		return true, false
+		return true, false, "help is always allowed"
 	}
 
 	var permissions *Permissions
@@ -224,17 +303,17 @@ func (a *ACL) AllowOperation(req *logical.Request) (bool, bool) {
 	raw, ok := a.exactRules.Get(path)
 	if ok {
 		permissions = raw.(*Permissions)
-		capabilities = permissions.CapabilitiesBitmap
+		capabilities = permissions.capabilitiesAt(time.Now())
 		goto CHECK
 	}
 
 	// Find a glob rule, default deny if no match
 	_, raw, ok = a.globRules.LongestPrefix(path)
 	if !ok {
-		return false, false
+		return false, false, "no rule matches the path"
 	} else {
 		permissions = raw.(*Permissions)
-		capabilities = permissions.CapabilitiesBitmap
+		capabilities = permissions.capabilitiesAt(time.Now())
 	}
 
 CHECK:
@@ -242,40 +321,48 @@ CHECK:
 	// If "deny" has been explicitly set, only deny will be in the map, so we
 	// only need to check for the existence of other values
 	sudo := capabilities&SudoCapabilityInt > 0
-	operationAllowed := false
+	var opCapability uint32
 	switch op {
 	case logical.ReadOperation:
-		operationAllowed = capabilities&ReadCapabilityInt > 0
+		opCapability = ReadCapabilityInt
 	case logical.ListOperation:
-		operationAllowed = capabilities&ListCapabilityInt > 0
+		opCapability = ListCapabilityInt
 	case logical.UpdateOperation:
-		operationAllowed = capabilities&UpdateCapabilityInt > 0
+		opCapability = UpdateCapabilityInt
 	case logical.DeleteOperation:
-		operationAllowed = capabilities&DeleteCapabilityInt > 0
+		opCapability = DeleteCapabilityInt
 	case logical.CreateOperation:
-		operationAllowed = capabilities&CreateCapabilityInt > 0
+		opCapability = CreateCapabilityInt
 
-	// These three re-use UpdateCapabilityInt since that's the most appropriate
+	// These re-use UpdateCapabilityInt since that's the most appropriate
 	// capability/operation mapping
-	case logical.RevokeOperation, logical.RenewOperation, logical.RollbackOperation:
-		operationAllowed = capabilities&UpdateCapabilityInt > 0
+	case logical.PatchOperation, logical.RevokeOperation, logical.RenewOperation, logical.RollbackOperation:
+		opCapability = UpdateCapabilityInt
 
 	default:
-		return false, false
+		return false, false, "the operation is not subject to policies"
 	}
 
-	if !operationAllowed {
-		return false, sudo
+	if capabilities&opCapability == 0 {
+		if capabilities&DenyCapabilityInt > 0 {
+			return false, sudo, "the rule denies the path"
+		}
+		for _, windowed := range permissions.WindowedCapabilities {
+			if windowed.CapabilitiesBitmap&opCapability > 0 {
+				return false, sudo, "the rule is outside of its allowed time windows"
+			}
+		}
+		return false, sudo, "the rule does not grant the capability of the operation"
 	}
 
 	if permissions.MaxWrappingTTL > 0 {
 		if req.WrapInfo == nil || req.WrapInfo.TTL > permissions.MaxWrappingTTL {
-			return false, sudo
+			return false, sudo, "the response wrapping TTL is above the maximum of the rule"
 		}
 	}
 	if permissions.MinWrappingTTL > 0 {
 		if req.WrapInfo == nil || req.WrapInfo.TTL < permissions.MinWrappingTTL {
-			return false, sudo
+			return false, sudo, "the response wrapping TTL is below the minimum of the rule"
 		}
 	}
 	// This situation can happen because of merging, even though in a single
@@ -283,15 +370,15 @@ CHECK:
 	if permissions.MinWrappingTTL != 0 &&
 		permissions.MaxWrappingTTL != 0 &&
 		permissions.MaxWrappingTTL < permissions.MinWrappingTTL {
-		return false, sudo
+		return false, sudo, "the merged wrapping TTL bounds of the rule conflict"
 	}
 
 	// Only check parameter permissions for operations that can modify
 	// parameters.
-	if op == logical.UpdateOperation || op == logical.CreateOperation {
+	if op == logical.UpdateOperation || op == logical.CreateOperation || op == logical.PatchOperation {
 		// If there are no data fields, allow
 		if len(req.Data) == 0 {
-			return true, sudo
+			return true, sudo, "the rule grants the operation"
 		}
 
 		if len(permissions.DeniedParameters) == 0 {
@@ -300,7 +387,7 @@ CHECK:
 
 		// Check if all parameters have been denied
 		if _, ok := permissions.DeniedParameters["*"]; ok {
-			return false, sudo
+			return false, sudo, "the rule denies all parameters"
 		}
 
 		for parameter, value := range req.Data {
@@ -308,7 +395,7 @@ CHECK:
 			if valueSlice, ok := permissions.DeniedParameters[strings.ToLower(parameter)]; ok {
 				// If the value exists in denied values slice, deny
 				if valueInParameterList(value, valueSlice) {
-					return false, sudo
+					return false, sudo, fmt.Sprintf("the rule denies parameter %q", parameter)
 				}
 			}
 		}
@@ -316,30 +403,30 @@ CHECK:
 	ALLOWED_PARAMETERS:
 		// If we don't have any allowed parameters set, allow
 		if len(permissions.AllowedParameters) == 0 {
-			return true, sudo
+			return true, sudo, "the rule grants the operation"
 		}
 
 		_, allowedAll := permissions.AllowedParameters["*"]
 		if len(permissions.AllowedParameters) == 1 && allowedAll {
-			return true, sudo
+			return true, sudo, "the rule grants the operation"
 		}
 
 		for parameter, value := range req.Data {
 			valueSlice, ok := permissions.AllowedParameters[strings.ToLower(parameter)]
 			// Requested parameter is not in allowed list
 			if !ok && !allowedAll {
-				return false, sudo
+				return false, sudo, fmt.Sprintf("the rule does not allow parameter %q", parameter)
 			}
 
 			// If the value doesn't exists in the allowed values slice,
 			// deny
 			if ok && !valueInParameterList(value, valueSlice) {
-				return false, sudo
+				return false, sudo, fmt.Sprintf("the rule does not allow the value of parameter %q", parameter)
 			}
 		}
 	}
 
-	return true, sudo
+	return true, sudo, "the rule grants the operation"
 }
 
 func valueInParameterList(v interface{}, list []interface{}) bool {
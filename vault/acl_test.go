@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"fmt"
 	"reflect"
 	"sync"
 	"testing"
@@ -51,6 +52,58 @@ func TestACL_Capabilities(t *testing.T) {
 		t.Fatalf("bad: path:%s\ngot\n%#v\nexpected\n%#v\n", "stage/aws/test", actual, expected)
 	}
 
+	// The approve capability is only granted explicitly
+	policies, err = Parse(`
+name = "approver"
+path "sys/control-group/*" {
+	capabilities = ["update", "approve"]
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	acl, err = NewACL([]*Policy{policies})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	actual = acl.Capabilities("sys/control-group/id/approve")
+	expected = []string{"update", "approve"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: path:%s\ngot\n%#v\nexpected\n%#v\n", "sys/control-group/id/approve", actual, expected)
+	}
+}
+
+func TestACL_HasAccessUnder(t *testing.T) {
+	policy, err := Parse(`
+path "secret/*" {
+	capabilities = ["read"]
+}
+path "kv/team/app" {
+	capabilities = ["read"]
+}
+path "denied/*" {
+	capabilities = ["deny"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acl, err := NewACL([]*Policy{policy})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for prefix, expected := range map[string]bool{
+		"secret/": true,
+		"kv/":     true,
+		"kv/ops/": false,
+		"denied/": false,
+		"sys/":    false,
+	} {
+		if actual := acl.HasAccessUnder(prefix); actual != expected {
+			t.Fatalf("%s: expected %t", prefix, expected)
+		}
+	}
 }
 
 func TestACL_Root(t *testing.T) {
@@ -106,6 +159,7 @@ func TestACL_Single(t *testing.T) {
 
 		{logical.ReadOperation, "dev/foo", true, true},
 		{logical.UpdateOperation, "dev/foo", true, true},
+		{logical.PatchOperation, "dev/foo", true, true},
 
 		{logical.DeleteOperation, "stage/foo", true, false},
 		{logical.ListOperation, "stage/aws/foo", true, true},
@@ -114,6 +168,7 @@ func TestACL_Single(t *testing.T) {
 
 		{logical.DeleteOperation, "prod/foo", false, false},
 		{logical.UpdateOperation, "prod/foo", false, false},
+		{logical.PatchOperation, "prod/foo", false, false},
 		{logical.ReadOperation, "prod/foo", true, false},
 		{logical.ListOperation, "prod/foo", true, false},
 		{logical.ReadOperation, "prod/aws/foo", false, false},
@@ -763,3 +818,100 @@ path "test/star" {
 	}
 }
 `
+
+func TestACL_AllowedWindows(t *testing.T) {
+	// A window that never contains the current time during the test
+	closed := fmt.Sprintf("* %d * * *", (time.Now().UTC().Hour()+12)%24)
+
+	restricted, err := Parse(fmt.Sprintf(`
+name = "restricted"
+path "secret/*" {
+	capabilities = ["read"]
+	allowed_windows = ["%s"]
+}
+path "secret/open" {
+	capabilities = ["read"]
+	allowed_windows = ["%s", "* * * * *"]
+}
+`, closed, closed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unrestricted, err := Parse(`
+name = "unrestricted"
+path "secret/*" {
+	capabilities = ["read"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acl, err := NewACL([]*Policy{restricted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "secret/foo",
+	}
+	if allowed, _ := acl.AllowOperation(request); allowed {
+		t.Fatal("expected request outside of the window to be denied")
+	}
+	if caps := acl.Capabilities("secret/foo"); !reflect.DeepEqual(caps, []string{DenyCapability}) {
+		t.Fatalf("bad: %v", caps)
+	}
+	request.Path = "secret/open"
+	if allowed, _ := acl.AllowOperation(request); !allowed {
+		t.Fatal("expected request within the window to be allowed")
+	}
+
+	// Granting the capabilities at all times lifts the restriction
+	acl, err = NewACL([]*Policy{restricted, unrestricted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Path = "secret/foo"
+	if allowed, _ := acl.AllowOperation(request); !allowed {
+		t.Fatal("expected request to be allowed")
+	}
+
+	// The windows of a policy only apply to its own capabilities: reading
+	// during the day and updating at night does not allow reading at night
+	now := time.Now().UTC().Hour()
+	day, err := Parse(fmt.Sprintf(`
+name = "day"
+path "secret/*" {
+	capabilities = ["read"]
+	allowed_windows = ["%s"]
+}
+`, closed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	night, err := Parse(fmt.Sprintf(`
+name = "night"
+path "secret/*" {
+	capabilities = ["update"]
+	allowed_windows = ["* %d * * *"]
+}
+`, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	acl, err = NewACL([]*Policy{day, night})
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Operation = logical.ReadOperation
+	if allowed, _, reason := acl.allowOperation(request); allowed || reason != "the rule is outside of its allowed time windows" {
+		t.Fatalf("expected read at night to be denied, got: %s", reason)
+	}
+	request.Operation = logical.UpdateOperation
+	if allowed, _ := acl.AllowOperation(request); !allowed {
+		t.Fatal("expected update at night to be allowed")
+	}
+	if caps := acl.Capabilities("secret/foo"); !reflect.DeepEqual(caps, []string{UpdateCapability}) {
+		t.Fatalf("bad: %v", caps)
+	}
+}
@@ -491,6 +491,22 @@ func (a *AuditBroker) GetHash(name string, input string) (string, error) {
 	return be.backend.GetHash(input)
 }
 
+// VerifyChain verifies the chained entries written by the given backend
+func (a *AuditBroker) VerifyChain(name string) (*audit.ChainVerification, error) {
+	a.RLock()
+	defer a.RUnlock()
+	be, ok := a.backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown audit backend %s", name)
+	}
+
+	verifier, ok := be.backend.(audit.ChainVerifier)
+	if !ok {
+		return nil, fmt.Errorf("audit backend %s does not support chain verification", name)
+	}
+	return verifier.VerifyChain()
+}
+
 // LogRequest is used to ensure all the audit backends have an opportunity to
 // log the given request and that *at least one* succeeds.
 func (a *AuditBroker) LogRequest(auth *logical.Auth, req *logical.Request, headersConfig *AuditedHeadersConfig, outerErr error) (ret error) {
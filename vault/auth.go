@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	"github.com/hashicorp/go-uuid"
-	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -53,6 +52,10 @@ func (c *Core) enableCredential(entry *MountEntry) error {
 		return fmt.Errorf("backend path must be specified")
 	}
 
+	if entry.SealWrap && !c.sealWrapSupported() {
+		return errSealWrapUnsupported
+	}
+
 	c.authLock.Lock()
 	defer c.authLock.Unlock()
 
@@ -94,11 +97,9 @@ func (c *Core) enableCredential(entry *MountEntry) error {
 	}
 	viewPath := credentialBarrierPrefix + entry.UUID + "/"
 	view := NewBarrierView(c.barrier, viewPath)
+	view.sealWrap = entry.SealWrap
 	sysView := c.mountEntrySysView(entry)
-	conf := make(map[string]string)
-	if entry.Config.PluginName != "" {
-		conf["plugin_name"] = entry.Config.PluginName
-	}
+	conf := entry.backendConfig()
 
 	// Create the new backend
 	backend, err := c.newCredentialBackend(entry.Type, sysView, view, conf)
@@ -114,8 +115,10 @@ func (c *Core) enableCredential(entry *MountEntry) error {
 	if entry.Type == "plugin" && backendType != logical.TypeCredential {
 		return fmt.Errorf("cannot mount '%s' of type '%s' as an auth backend", entry.Config.PluginName, backendType)
 	}
+	backend = c.supervisePluginBackend(entry, backend)
 
-	if err := backend.Initialize(); err != nil {
+	backend, err = c.initializeBackend(entry, backend)
+	if err != nil {
 		return err
 	}
 
@@ -250,42 +253,32 @@ func (c *Core) taintCredEntry(path string) error {
 
 // loadCredentials is invoked as part of postUnseal to load the auth table
 func (c *Core) loadCredentials() error {
-	authTable := &MountTable{}
-	localAuthTable := &MountTable{}
-
 	// Load the existing mount table
-	raw, err := c.barrier.Get(coreAuthConfigPath)
+	authTable, needMigration, err := c.loadMountTable(coreAuthConfigPath, coreAuthEntriesPrefix)
 	if err != nil {
-		c.logger.Error("core: failed to read auth table", "error", err)
+		c.logger.Error("core: failed to load auth table", "error", err)
 		return errLoadAuthFailed
 	}
-	rawLocal, err := c.barrier.Get(coreLocalAuthConfigPath)
+	localAuthTable, needLocalMigration, err := c.loadMountTable(coreLocalAuthConfigPath, coreLocalAuthEntriesPrefix)
 	if err != nil {
-		c.logger.Error("core: failed to read local auth table", "error", err)
+		c.logger.Error("core: failed to load local auth table", "error", err)
 		return errLoadAuthFailed
 	}
 
 	c.authLock.Lock()
 	defer c.authLock.Unlock()
 
-	if raw != nil {
-		if err := jsonutil.DecodeJSON(raw.Value, authTable); err != nil {
-			c.logger.Error("core: failed to decode auth table", "error", err)
-			return errLoadAuthFailed
-		}
+	if authTable != nil {
 		c.auth = authTable
 	}
-	if rawLocal != nil {
-		if err := jsonutil.DecodeJSON(rawLocal.Value, localAuthTable); err != nil {
-			c.logger.Error("core: failed to decode local auth table", "error", err)
-			return errLoadAuthFailed
-		}
+	if localAuthTable != nil {
 		c.auth.Entries = append(c.auth.Entries, localAuthTable.Entries...)
 	}
 
 	// Done if we have restored the auth table
 	if c.auth != nil {
-		needPersist := false
+		// Upgrade tables persisted as a whole to sharded ones
+		needPersist := needMigration || needLocalMigration
 
 		// Upgrade to typed auth table
 		if c.auth.Type == "" {
@@ -354,39 +347,14 @@ func (c *Core) persistAuth(table *MountTable, localOnly bool) error {
 	}
 
 	if !localOnly {
-		// Marshal the table
-		compressedBytes, err := jsonutil.EncodeJSONAndCompress(nonLocalAuth, nil)
-		if err != nil {
-			c.logger.Error("core: failed to encode and/or compress auth table", "error", err)
-			return err
-		}
-
-		// Create an entry
-		entry := &Entry{
-			Key:   coreAuthConfigPath,
-			Value: compressedBytes,
-		}
-
-		// Write to the physical backend
-		if err := c.barrier.Put(entry); err != nil {
+		if err := c.persistMountTable(coreAuthConfigPath, coreAuthEntriesPrefix, nonLocalAuth); err != nil {
 			c.logger.Error("core: failed to persist auth table", "error", err)
 			return err
 		}
 	}
 
 	// Repeat with local auth
-	compressedBytes, err := jsonutil.EncodeJSONAndCompress(localAuth, nil)
-	if err != nil {
-		c.logger.Error("core: failed to encode and/or compress local auth table", "error", err)
-		return err
-	}
-
-	entry := &Entry{
-		Key:   coreLocalAuthConfigPath,
-		Value: compressedBytes,
-	}
-
-	if err := c.barrier.Put(entry); err != nil {
+	if err := c.persistMountTable(coreLocalAuthConfigPath, coreLocalAuthEntriesPrefix, localAuth); err != nil {
 		c.logger.Error("core: failed to persist local auth table", "error", err)
 		return err
 	}
@@ -415,11 +383,9 @@ func (c *Core) setupCredentials() error {
 		// Create a barrier view using the UUID
 		viewPath := credentialBarrierPrefix + entry.UUID + "/"
 		view = NewBarrierView(c.barrier, viewPath)
+		view.sealWrap = entry.SealWrap
 		sysView := c.mountEntrySysView(entry)
-		conf := make(map[string]string)
-		if entry.Config.PluginName != "" {
-			conf["plugin_name"] = entry.Config.PluginName
-		}
+		conf := entry.backendConfig()
 
 		// Initialize the backend
 		backend, err = c.newCredentialBackend(entry.Type, sysView, view, conf)
@@ -436,8 +402,10 @@ func (c *Core) setupCredentials() error {
 		if entry.Type == "plugin" && backendType != logical.TypeCredential {
 			return fmt.Errorf("cannot mount '%s' of type '%s' as an auth backend", entry.Config.PluginName, backendType)
 		}
+		backend = c.supervisePluginBackend(entry, backend)
 
-		if err := backend.Initialize(); err != nil {
+		backend, err = c.initializeBackend(entry, backend)
+		if err != nil {
 			return err
 		}
 
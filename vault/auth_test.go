@@ -5,7 +5,6 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -121,17 +120,13 @@ func TestCore_EnableCredential_Local(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	rawLocal, err := c.barrier.Get(coreLocalAuthConfigPath)
+	localCredentialTable, _, err := c.loadMountTable(coreLocalAuthConfigPath, coreLocalAuthEntriesPrefix)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if rawLocal == nil {
+	if localCredentialTable == nil {
 		t.Fatal("expected non-nil local credential")
 	}
-	localCredentialTable := &MountTable{}
-	if err := jsonutil.DecodeJSON(rawLocal.Value, localCredentialTable); err != nil {
-		t.Fatal(err)
-	}
 	if len(localCredentialTable.Entries) > 0 {
 		t.Fatalf("expected no entries in local credential table, got %#v", localCredentialTable)
 	}
@@ -141,17 +136,13 @@ func TestCore_EnableCredential_Local(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	rawLocal, err = c.barrier.Get(coreLocalAuthConfigPath)
+	localCredentialTable, _, err = c.loadMountTable(coreLocalAuthConfigPath, coreLocalAuthEntriesPrefix)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if rawLocal == nil {
+	if localCredentialTable == nil {
 		t.Fatal("expected non-nil local credential")
 	}
-	localCredentialTable = &MountTable{}
-	if err := jsonutil.DecodeJSON(rawLocal.Value, localCredentialTable); err != nil {
-		t.Fatal(err)
-	}
 	if len(localCredentialTable.Entries) != 1 {
 		t.Fatalf("expected one entry in local credential table, got %#v", localCredentialTable)
 	}
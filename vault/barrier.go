@@ -124,6 +124,11 @@ type SecurityBarrier interface {
 	// Rekey is used to change the master key used to protect the keyring
 	Rekey([]byte) error
 
+	// Rewrap re-encrypts the entry at the given key with the active key if
+	// it was encrypted with the key of an older term, and returns whether
+	// it did
+	Rewrap(key string) (bool, error)
+
 	// For replication we must send over the keyring, so this must be available
 	Keyring() (*Keyring, error)
 
@@ -148,6 +153,10 @@ type BarrierStorage interface {
 	// List is used ot list all the keys under a given
 	// prefix, up to the next prefix.
 	List(prefix string) ([]string, error)
+
+	// ListPage is used to list at most limit of the keys under a given
+	// prefix sorted after the given key, in lexical order
+	ListPage(prefix, after string, limit int) ([]string, error)
 }
 
 // BarrierEncryptor is the in memory only interface that does not actually
@@ -160,15 +169,17 @@ type BarrierEncryptor interface {
 
 // Entry is used to represent data stored by the security barrier
 type Entry struct {
-	Key   string
-	Value []byte
+	Key      string
+	Value    []byte
+	SealWrap bool
 }
 
 // Logical turns the Entry into a logical storage entry.
 func (e *Entry) Logical() *logical.StorageEntry {
 	return &logical.StorageEntry{
-		Key:   e.Key,
-		Value: e.Value,
+		Key:      e.Key,
+		Value:    e.Value,
+		SealWrap: e.SealWrap,
 	}
 }
 
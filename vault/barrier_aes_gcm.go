@@ -13,6 +13,7 @@ import (
 
 	"github.com/armon/go-metrics"
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/locksutil"
 	"github.com/hashicorp/vault/physical"
 )
 
@@ -57,6 +58,10 @@ type AESGCMBarrier struct {
 	cache     map[uint32]cipher.AEAD
 	cacheLock sync.RWMutex
 
+	// locks serialize the writes of an entry with its rewrap, so that a
+	// rewrap never overwrites a newer value
+	locks []*locksutil.LockEntry
+
 	// currentAESGCMVersionByte is prefixed to a message to allow for
 	// future versioning of barrier implementations. It's var instead
 	// of const to allow for testing
@@ -70,6 +75,7 @@ func NewAESGCMBarrier(physical physical.Backend) (*AESGCMBarrier, error) {
 		backend: physical,
 		sealed:  true,
 		cache:   make(map[uint32]cipher.AEAD),
+		locks:   locksutil.CreateLocks(),
 		currentAESGCMVersionByte: byte(AESGCMVersion2),
 	}
 	return b, nil
@@ -641,9 +647,14 @@ func (b *AESGCMBarrier) Put(entry *Entry) error {
 		return err
 	}
 
+	lock := locksutil.LockForKey(b.locks, entry.Key)
+	lock.Lock()
+	defer lock.Unlock()
+
 	pe := &physical.Entry{
-		Key:   entry.Key,
-		Value: b.encrypt(entry.Key, term, primary, entry.Value),
+		Key:      entry.Key,
+		Value:    b.encrypt(entry.Key, term, primary, entry.Value),
+		SealWrap: entry.SealWrap,
 	}
 	return b.backend.Put(pe)
 }
@@ -673,8 +684,9 @@ func (b *AESGCMBarrier) Get(key string) (*Entry, error) {
 
 	// Wrap in a logical entry
 	entry := &Entry{
-		Key:   key,
-		Value: plain,
+		Key:      key,
+		Value:    plain,
+		SealWrap: pe.SealWrap,
 	}
 	return entry, nil
 }
@@ -688,9 +700,67 @@ func (b *AESGCMBarrier) Delete(key string) error {
 		return ErrBarrierSealed
 	}
 
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
 	return b.backend.Delete(key)
 }
 
+// Rewrap re-encrypts the entry at the given key with the active key if it
+// was encrypted with the key of an older term, and returns whether it did.
+// Entries not encrypted with the keyring, and the upgrade paths, which must
+// stay encrypted with the key of their term, are left as they are.
+func (b *AESGCMBarrier) Rewrap(key string) (bool, error) {
+	defer metrics.MeasureSince([]string{"barrier", "rewrap"}, time.Now())
+	b.l.RLock()
+	defer b.l.RUnlock()
+	if b.sealed {
+		return false, ErrBarrierSealed
+	}
+
+	if key == keyringPath || strings.HasPrefix(key, keyringUpgradePrefix) {
+		return false, nil
+	}
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	pe, err := b.backend.Get(key)
+	if err != nil {
+		return false, err
+	}
+	if pe == nil || len(pe.Value) < termSize+1 {
+		return false, nil
+	}
+
+	// Entries of a term without key are not encrypted by the barrier
+	activeTerm := b.keyring.ActiveTerm()
+	term := binary.BigEndian.Uint32(pe.Value[:termSize])
+	if term >= activeTerm || b.keyring.TermKey(term) == nil {
+		return false, nil
+	}
+	plain, err := b.decryptKeyring(key, pe.Value)
+	if err != nil {
+		return false, nil
+	}
+
+	primary, err := b.aeadForTerm(activeTerm)
+	if err != nil {
+		return false, err
+	}
+	pe = &physical.Entry{
+		Key:      key,
+		Value:    b.encrypt(key, activeTerm, primary, plain),
+		SealWrap: pe.SealWrap,
+	}
+	if err := b.backend.Put(pe); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // List is used ot list all the keys under a given
 // prefix, up to the next prefix.
 func (b *AESGCMBarrier) List(prefix string) ([]string, error) {
@@ -704,6 +774,19 @@ func (b *AESGCMBarrier) List(prefix string) ([]string, error) {
 	return b.backend.List(prefix)
 }
 
+// ListPage is used to list a page of the keys under a given prefix, which
+// the physical backend pages natively if it can
+func (b *AESGCMBarrier) ListPage(prefix, after string, limit int) ([]string, error) {
+	defer metrics.MeasureSince([]string{"barrier", "list_page"}, time.Now())
+	b.l.RLock()
+	defer b.l.RUnlock()
+	if b.sealed {
+		return nil, ErrBarrierSealed
+	}
+
+	return physical.ListPage(b.backend, prefix, after, limit)
+}
+
 // aeadForTerm returns the AES-GCM AEAD for the given term
 func (b *AESGCMBarrier) aeadForTerm(term uint32) (cipher.AEAD, error) {
 	// Check for the keyring
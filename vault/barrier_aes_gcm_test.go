@@ -2,6 +2,7 @@ package vault
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"testing"
 
@@ -50,6 +51,66 @@ func TestAESGCMBarrier_Rotate(t *testing.T) {
 	testBarrier_Rotate(t, b)
 }
 
+func TestAESGCMBarrier_Rewrap(t *testing.T) {
+	inm, b, _ := mockBarrier(t)
+
+	if err := b.Put(&Entry{Key: "test", Value: []byte("quick brown fox")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := inm.Put(&physical.Entry{Key: "plain", Value: []byte("not encrypted")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	term := func(key string) uint32 {
+		pe, err := inm.Get(key)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return binary.BigEndian.Uint32(pe.Value[:termSize])
+	}
+
+	// Entries of the active term are left as they are
+	if rewrapped, err := b.Rewrap("test"); err != nil || rewrapped {
+		t.Fatalf("bad: %v %v", rewrapped, err)
+	}
+
+	newTerm, err := b.Rotate()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := b.CreateUpgrade(newTerm); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if rewrapped, err := b.Rewrap("test"); err != nil || !rewrapped {
+		t.Fatalf("bad: %v %v", rewrapped, err)
+	}
+	if term("test") != newTerm {
+		t.Fatalf("bad: %d", term("test"))
+	}
+	entry, err := b.Get("test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if entry == nil || string(entry.Value) != "quick brown fox" {
+		t.Fatalf("bad: %#v", entry)
+	}
+	if rewrapped, err := b.Rewrap("test"); err != nil || rewrapped {
+		t.Fatalf("bad: %v %v", rewrapped, err)
+	}
+
+	// Neither entries not written by the barrier nor the upgrade paths are
+	// rewrapped
+	for _, key := range []string{"plain", "missing", keyringPath, keyringUpgradePrefix + "1"} {
+		if rewrapped, err := b.Rewrap(key); err != nil || rewrapped {
+			t.Fatalf("bad: %s: %v %v", key, rewrapped, err)
+		}
+	}
+	if term(keyringUpgradePrefix+"1") != newTerm-1 {
+		t.Fatalf("bad: %d", term(keyringUpgradePrefix+"1"))
+	}
+}
+
 func TestAESGCMBarrier_Upgrade(t *testing.T) {
 
 	inm := physical.NewInmem(logger)
@@ -0,0 +1,296 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/jsonutil"
+)
+
+const (
+	// barrierRewrapStatusPath is the path the status of the last rewrap of
+	// the barrier is stored at, so that an interrupted rewrap is resumed
+	// by the next active node
+	barrierRewrapStatusPath = "core/barrier-rewrap"
+
+	// barrierRewrapPageSize is the number of keys listed at once while
+	// walking the storage
+	barrierRewrapPageSize = 1000
+
+	// barrierRewrapPersistInterval is the number of entries scanned between
+	// two saves of the progress of a rewrap
+	barrierRewrapPersistInterval = 1000
+
+	// BarrierRewrapDefaultRate is the number of entries scanned per second
+	// by a rewrap by default
+	BarrierRewrapDefaultRate = 200
+
+	// barrierRewrapMaxRate is the maximum number of entries scanned per
+	// second by a rewrap
+	barrierRewrapMaxRate = 100000
+
+	// The states of a rewrap
+	BarrierRewrapRunning   = "running"
+	BarrierRewrapCompleted = "completed"
+	BarrierRewrapFailed    = "failed"
+)
+
+// errBarrierRewrapStopped stops the walk of the storage when the rewrap is
+// stopped
+var errBarrierRewrapStopped = errors.New("barrier rewrap stopped")
+
+// BarrierRewrapStatus is the progress of the rewrap of the barrier, which
+// re-encrypts the entries written with the keys of older terms with the
+// active key after a rotation, so that the data on disk no longer depends on
+// retired keys
+type BarrierRewrapStatus struct {
+	// Term is the term entries are re-encrypted to
+	Term uint32 `json:"term"`
+
+	// State is running, completed or failed. A rewrap interrupted by a
+	// seal or a loss of leadership stays running and is resumed by the
+	// next active node.
+	State string `json:"state"`
+
+	// Rate is the maximum number of entries scanned per second
+	Rate int `json:"rate"`
+
+	// Scanned and Rewrapped are the numbers of entries scanned and
+	// re-encrypted so far. A resumed rewrap scans the storage again.
+	Scanned   int `json:"scanned"`
+	Rewrapped int `json:"rewrapped"`
+
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time,omitempty"`
+
+	// Error is the error the rewrap failed with
+	Error string `json:"error,omitempty"`
+}
+
+// barrierRewrap is a running rewrap
+type barrierRewrap struct {
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// loadBarrierRewrap resumes the interrupted rewrap of the barrier, or starts
+// one if the keys were rotated since the last one completed
+func (c *Core) loadBarrierRewrap() error {
+	info, err := c.barrier.ActiveKeyInfo()
+	if err != nil {
+		return err
+	}
+
+	entry, err := c.barrier.Get(barrierRewrapStatusPath)
+	if err != nil {
+		return errwrap.Wrapf("failed to read the barrier rewrap status: {{err}}", err)
+	}
+	var status *BarrierRewrapStatus
+	if entry != nil {
+		status = new(BarrierRewrapStatus)
+		if err := jsonutil.DecodeJSON(entry.Value, status); err != nil {
+			return errwrap.Wrapf("failed to decode the barrier rewrap status: {{err}}", err)
+		}
+	}
+
+	c.barrierRewrapLock.Lock()
+	c.barrierRewrapStatus = status
+	c.barrierRewrapLock.Unlock()
+
+	switch {
+	case status == nil && info.Term > initialKeyTerm:
+		// The keys were rotated before rewraps existed
+		return c.startBarrierRewrap(BarrierRewrapDefaultRate)
+	case status == nil:
+		return nil
+	case status.State == BarrierRewrapRunning || status.Term < uint32(info.Term):
+		c.logger.Info("core: resuming barrier rewrap", "term", info.Term)
+		return c.startBarrierRewrap(status.Rate)
+	}
+	return nil
+}
+
+// startBarrierRewrap starts rewrapping the barrier to the active term,
+// scanning at most rate entries per second, in place of the running rewrap
+// if any
+func (c *Core) startBarrierRewrap(rate int) error {
+	if rate <= 0 {
+		rate = BarrierRewrapDefaultRate
+	}
+	info, err := c.barrier.ActiveKeyInfo()
+	if err != nil {
+		return err
+	}
+
+	c.barrierRewrapControlLock.Lock()
+	defer c.barrierRewrapControlLock.Unlock()
+	c.stopBarrierRewrapLocked()
+
+	c.barrierRewrapLock.Lock()
+	defer c.barrierRewrapLock.Unlock()
+	status := &BarrierRewrapStatus{
+		Term:      uint32(info.Term),
+		State:     BarrierRewrapRunning,
+		Rate:      rate,
+		StartTime: time.Now(),
+	}
+	if err := c.persistBarrierRewrapStatus(status); err != nil {
+		return err
+	}
+	c.barrierRewrapStatus = status
+
+	rewrap := &barrierRewrap{
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+	c.barrierRewrap = rewrap
+	go c.runBarrierRewrap(rewrap, status)
+	return nil
+}
+
+// stopBarrierRewrap stops the running rewrap, if any, and waits for it to
+// save its progress
+func (c *Core) stopBarrierRewrap() {
+	c.barrierRewrapControlLock.Lock()
+	defer c.barrierRewrapControlLock.Unlock()
+	c.stopBarrierRewrapLocked()
+}
+
+// stopBarrierRewrapLocked stops the running rewrap like stopBarrierRewrap.
+// The control lock must be held.
+func (c *Core) stopBarrierRewrapLocked() {
+	c.barrierRewrapLock.Lock()
+	rewrap := c.barrierRewrap
+	c.barrierRewrap = nil
+	c.barrierRewrapLock.Unlock()
+	if rewrap == nil {
+		return
+	}
+	close(rewrap.stopCh)
+	<-rewrap.doneCh
+}
+
+// BarrierRewrapStatus returns the progress of the last rewrap of the
+// barrier, or nil if the barrier was never rewrapped
+func (c *Core) BarrierRewrapStatus() *BarrierRewrapStatus {
+	c.barrierRewrapLock.Lock()
+	defer c.barrierRewrapLock.Unlock()
+	if c.barrierRewrapStatus == nil {
+		return nil
+	}
+	status := *c.barrierRewrapStatus
+	return &status
+}
+
+// runBarrierRewrap walks the storage, rewrapping the entries one by one at
+// the rate of the rewrap
+func (c *Core) runBarrierRewrap(rewrap *barrierRewrap, status *BarrierRewrapStatus) {
+	defer close(rewrap.doneCh)
+	c.logger.Info("core: starting barrier rewrap", "term", status.Term, "rate", status.Rate)
+
+	throttle := time.NewTicker(time.Second / time.Duration(status.Rate))
+	defer throttle.Stop()
+
+	err := c.walkBarrier("", rewrap.stopCh, func(key string) error {
+		select {
+		case <-throttle.C:
+		case <-rewrap.stopCh:
+			return errBarrierRewrapStopped
+		}
+
+		rewrapped, err := c.barrier.Rewrap(key)
+		if err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to rewrap %q: {{err}}", key), err)
+		}
+
+		metrics.IncrCounter([]string{"barrier", "rewrap", "scanned"}, 1)
+		if rewrapped {
+			metrics.IncrCounter([]string{"barrier", "rewrap", "rewrapped"}, 1)
+		}
+
+		c.barrierRewrapLock.Lock()
+		defer c.barrierRewrapLock.Unlock()
+		status.Scanned++
+		if rewrapped {
+			status.Rewrapped++
+		}
+		if status.Scanned%barrierRewrapPersistInterval == 0 {
+			if err := c.persistBarrierRewrapStatus(status); err != nil {
+				c.logger.Warn("core: failed to save barrier rewrap progress", "error", err)
+			}
+		}
+		return nil
+	})
+
+	c.barrierRewrapLock.Lock()
+	defer c.barrierRewrapLock.Unlock()
+	switch err {
+	case errBarrierRewrapStopped:
+		// The rewrap stays running to be resumed
+		c.logger.Info("core: barrier rewrap stopped", "scanned", status.Scanned, "rewrapped", status.Rewrapped)
+	case nil:
+		status.State = BarrierRewrapCompleted
+		status.EndTime = time.Now()
+		c.logger.Info("core: barrier rewrap completed", "scanned", status.Scanned, "rewrapped", status.Rewrapped)
+	default:
+		status.State = BarrierRewrapFailed
+		status.EndTime = time.Now()
+		status.Error = err.Error()
+		c.logger.Error("core: barrier rewrap failed", "error", err)
+	}
+	if err := c.persistBarrierRewrapStatus(status); err != nil {
+		c.logger.Error("core: failed to save barrier rewrap status", "error", err)
+	}
+}
+
+// walkBarrier calls fn with each key stored under the prefix, depth first
+// and in lexical order, until fn fails or the stop channel is closed
+func (c *Core) walkBarrier(prefix string, stopCh chan struct{}, fn func(key string) error) error {
+	after := ""
+	for {
+		keys, err := c.barrier.ListPage(prefix, after, barrierRewrapPageSize)
+		if err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to list %q: {{err}}", prefix), err)
+		}
+		for _, key := range keys {
+			select {
+			case <-stopCh:
+				return errBarrierRewrapStopped
+			default:
+			}
+
+			if strings.HasSuffix(key, "/") {
+				err = c.walkBarrier(prefix+key, stopCh, fn)
+			} else {
+				err = fn(prefix + key)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if len(keys) < barrierRewrapPageSize {
+			return nil
+		}
+		after = keys[len(keys)-1]
+	}
+}
+
+// persistBarrierRewrapStatus saves the status of the rewrap. The rewrap
+// lock must be held.
+func (c *Core) persistBarrierRewrapStatus(status *BarrierRewrapStatus) error {
+	buf, err := jsonutil.EncodeJSON(status)
+	if err != nil {
+		return err
+	}
+	if err := c.barrier.Put(&Entry{
+		Key:   barrierRewrapStatusPath,
+		Value: buf,
+	}); err != nil {
+		return errwrap.Wrapf("failed to save the barrier rewrap status: {{err}}", err)
+	}
+	return nil
+}
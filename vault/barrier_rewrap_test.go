@@ -0,0 +1,155 @@
+package vault
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// testWaitBarrierRewrap waits for the running rewrap of the barrier to end
+func testWaitBarrierRewrap(t *testing.T, c *Core) *BarrierRewrapStatus {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		status := c.BarrierRewrapStatus()
+		if status != nil && status.State != BarrierRewrapRunning {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("barrier rewrap did not end")
+	return nil
+}
+
+// testOldTermEntries returns the keys of the entries still encrypted with
+// the key of an older term than the given one
+func testOldTermEntries(t *testing.T, c *Core, prefix string, term uint32) []string {
+	keys, err := c.physical.List(prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var old []string
+	for _, key := range keys {
+		key = prefix + key
+		if strings.HasSuffix(key, "/") {
+			old = append(old, testOldTermEntries(t, c, key, term)...)
+			continue
+		}
+		if key == keyringPath || strings.HasPrefix(key, keyringUpgradePrefix) {
+			continue
+		}
+		pe, err := c.physical.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pe.Value) < termSize+1 || binary.BigEndian.Uint32(pe.Value[:termSize]) >= term {
+			continue
+		}
+		if _, err := c.barrier.Decrypt(key, pe.Value); err == nil {
+			old = append(old, key)
+		}
+	}
+	return old
+}
+
+func TestCore_BarrierRewrap(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	if status := c.BarrierRewrapStatus(); status != nil {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+	req.Data["bar"] = "baz"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/rotate")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	status := testWaitBarrierRewrap(t, c)
+	if status.State != BarrierRewrapCompleted || status.Term != 2 || status.Rate != BarrierRewrapDefaultRate {
+		t.Fatalf("bad: %#v", status)
+	}
+	if status.Rewrapped == 0 || status.Scanned < status.Rewrapped {
+		t.Fatalf("bad: %#v", status)
+	}
+	if old := testOldTermEntries(t, c, "", 2); len(old) != 0 {
+		t.Fatalf("bad: %v", old)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.Data["bar"] != "baz" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The rate is validated and kept for the next rewraps
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/rotate/rewrap")
+	req.Data["rate"] = 0
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("bad: %#v %v", resp, err)
+	}
+	req.Data["rate"] = 1000
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	testWaitBarrierRewrap(t, c)
+
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/rotate/rewrap")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["state"] != BarrierRewrapCompleted || resp.Data["rate"] != 1000 || resp.Data["rewrapped"] != 0 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestCore_BarrierRewrap_Resume(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+
+	// Rotate without the system backend, as if the keys had been rotated
+	// before rewraps existed
+	if _, err := c.barrier.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if old := testOldTermEntries(t, c, "", 2); len(old) == 0 {
+		t.Fatal("expected entries of the old term")
+	}
+
+	if err := c.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range keys {
+		unseal, err := TestCoreUnseal(c, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i+1 == len(keys) && !unseal {
+			t.Fatal("should be unsealed")
+		}
+	}
+
+	status := testWaitBarrierRewrap(t, c)
+	if status.State != BarrierRewrapCompleted || status.Term != 2 {
+		t.Fatalf("bad: %#v", status)
+	}
+	if old := testOldTermEntries(t, c, "", 2); len(old) != 0 {
+		t.Fatalf("bad: %v", old)
+	}
+}
@@ -18,6 +18,10 @@ type BarrierView struct {
 	barrier  BarrierStorage
 	prefix   string
 	readonly bool
+
+	// sealWrap seal-wraps every entry put through the view, for the views of
+	// the mounts with seal wrapping enabled
+	sealWrap bool
 }
 
 // NewBarrierView takes an underlying security barrier and returns
@@ -45,6 +49,14 @@ func (v *BarrierView) List(prefix string) ([]string, error) {
 	return v.barrier.List(v.expandKey(prefix))
 }
 
+// ListPage lists a page of the keys under the prefix of the view
+func (v *BarrierView) ListPage(prefix, after string, limit int) ([]string, error) {
+	if err := v.sanityCheck(prefix); err != nil {
+		return nil, err
+	}
+	return v.barrier.ListPage(v.expandKey(prefix), after, limit)
+}
+
 // logical.Storage impl.
 func (v *BarrierView) Get(key string) (*logical.StorageEntry, error) {
 	if err := v.sanityCheck(key); err != nil {
@@ -62,8 +74,9 @@ func (v *BarrierView) Get(key string) (*logical.StorageEntry, error) {
 	}
 
 	return &logical.StorageEntry{
-		Key:   entry.Key,
-		Value: entry.Value,
+		Key:      entry.Key,
+		Value:    entry.Value,
+		SealWrap: entry.SealWrap,
 	}, nil
 }
 
@@ -80,8 +93,9 @@ func (v *BarrierView) Put(entry *logical.StorageEntry) error {
 	}
 
 	nested := &Entry{
-		Key:   expandedKey,
-		Value: entry.Value,
+		Key:      expandedKey,
+		Value:    entry.Value,
+		SealWrap: entry.SealWrap || v.sealWrap,
 	}
 	return v.barrier.Put(nested)
 }
@@ -104,7 +118,7 @@ func (v *BarrierView) Delete(key string) error {
 // SubView constructs a nested sub-view using the given prefix
 func (v *BarrierView) SubView(prefix string) *BarrierView {
 	sub := v.expandKey(prefix)
-	return &BarrierView{barrier: v.barrier, prefix: sub, readonly: v.readonly}
+	return &BarrierView{barrier: v.barrier, prefix: sub, readonly: v.readonly, sealWrap: v.sealWrap}
 }
 
 // expandKey is used to expand to the full key path with the prefix
@@ -116,3 +130,55 @@ func (v *BarrierView) expandKey(suffix string) string {
 func (v *BarrierView) truncateKey(full string) string {
 	return strings.TrimPrefix(full, v.prefix)
 }
+
+// listPageSize is the number of keys listed at once when scanning prefixes
+// which may be large
+const listPageSize = 1000
+
+// pageLister lists keys a page at a time, like the barrier and its views
+type pageLister interface {
+	ListPage(prefix, after string, limit int) ([]string, error)
+}
+
+// listPages calls cb with the keys under the prefix, up to the next prefix,
+// listing at most listPageSize keys at a time. Listing stops at the first
+// error returned by cb.
+func listPages(l pageLister, prefix string, cb func(keys []string) error) error {
+	after := ""
+	for {
+		keys, err := l.ListPage(prefix, after, listPageSize)
+		if err != nil {
+			return fmt.Errorf("list failed at path '%s': %v", prefix, err)
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		if err := cb(keys); err != nil {
+			return err
+		}
+		if len(keys) < listPageSize {
+			return nil
+		}
+		after = keys[len(keys)-1]
+	}
+}
+
+// scanPages calls cb with each key under the prefix, recursively, like
+// logical.ScanView but without listing more than listPageSize keys at a
+// time. Scanning stops at the first error returned by cb.
+func scanPages(l pageLister, prefix string, cb func(key string) error) error {
+	return listPages(l, prefix, func(keys []string) error {
+		for _, key := range keys {
+			var err error
+			if strings.HasSuffix(key, "/") {
+				err = scanPages(l, prefix+key, cb)
+			} else {
+				err = cb(prefix + key)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
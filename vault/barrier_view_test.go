@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"fmt"
 	"reflect"
 	"sort"
 	"testing"
@@ -213,6 +214,47 @@ func TestBarrierView_Scan(t *testing.T) {
 	}
 }
 
+func TestBarrierView_ScanPages(t *testing.T) {
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "view/")
+
+	// More keys than fit in a page, at the top level and in a folder
+	var expect []string
+	for i := 0; i < listPageSize+5; i++ {
+		for _, key := range []string{fmt.Sprintf("%05d", i), fmt.Sprintf("sub/%05d", i)} {
+			expect = append(expect, key)
+			if err := view.Put(&logical.StorageEntry{Key: key, Value: []byte("test")}); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+		}
+	}
+
+	var out []string
+	err := scanPages(view, "", func(key string) error {
+		out = append(out, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	sort.Strings(out)
+	sort.Strings(expect)
+	if !reflect.DeepEqual(out, expect) {
+		t.Fatalf("bad: got %d keys, expected %d", len(out), len(expect))
+	}
+
+	// Errors of the callback stop the scan
+	count := 0
+	err = scanPages(view, "", func(key string) error {
+		count++
+		return fmt.Errorf("stop")
+	})
+	if err == nil || count != 1 {
+		t.Fatalf("bad: %d %v", count, err)
+	}
+}
+
 func TestBarrierView_CollectKeys(t *testing.T) {
 	_, barrier, _ := mockBarrier(t)
 	view := NewBarrierView(barrier, "view/")
@@ -0,0 +1,299 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// canaryHookTimeout is how long the delivery of a canary alert to a
+	// hook can take
+	canaryHookTimeout = 10 * time.Second
+)
+
+// CanaryHook is an endpoint alerted when a canary token is tripped. The URL
+// is posted the name and metadata of the canary token and the request that
+// tripped it.
+type CanaryHook struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+func (h *CanaryHook) toResponseData() map[string]interface{} {
+	return map[string]interface{}{
+		"name": h.Name,
+		"url":  h.URL,
+	}
+}
+
+// validate checks the settings of the hook
+func (h *CanaryHook) validate() error {
+	u, err := url.Parse(h.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("url must be an http or https URL")
+	}
+	return nil
+}
+
+// CanaryToken is a decoy token that is never valid. Its ID and accessor are
+// only kept salted, like those of real tokens; any request using, looking
+// up, renewing or revoking it trips it.
+type CanaryToken struct {
+	Name           string            `json:"name"`
+	SaltedID       string            `json:"salted_id"`
+	SaltedAccessor string            `json:"salted_accessor"`
+	Hooks          []string          `json:"hooks"`
+	Metadata       map[string]string `json:"metadata"`
+	CreationTime   time.Time         `json:"creation_time"`
+
+	// Trips is the number of requests which tripped the token
+	Trips       int       `json:"trips"`
+	LastTripped time.Time `json:"last_tripped"`
+}
+
+func (t *CanaryToken) toResponseData() map[string]interface{} {
+	data := map[string]interface{}{
+		"name":          t.Name,
+		"hooks":         t.Hooks,
+		"metadata":      t.Metadata,
+		"creation_time": t.CreationTime,
+		"trips":         t.Trips,
+	}
+	if !t.LastTripped.IsZero() {
+		data["last_tripped"] = t.LastTripped
+	}
+	return data
+}
+
+// CanaryConfig holds the canary hooks and tokens, keyed by name
+type CanaryConfig struct {
+	Hooks  map[string]*CanaryHook  `json:"hooks"`
+	Tokens map[string]*CanaryToken `json:"tokens"`
+}
+
+// clone returns a shallow copy of the config that can be modified without
+// affecting the requests being checked
+func (c *CanaryConfig) clone() *CanaryConfig {
+	clone := &CanaryConfig{
+		Hooks:  make(map[string]*CanaryHook, len(c.Hooks)),
+		Tokens: make(map[string]*CanaryToken, len(c.Tokens)),
+	}
+	for k, v := range c.Hooks {
+		clone.Hooks[k] = v
+	}
+	for k, v := range c.Tokens {
+		clone.Tokens[k] = v
+	}
+	return clone
+}
+
+// match returns the canary token with the given salted ID or accessor
+func (c *CanaryConfig) match(salted string) *CanaryToken {
+	for _, t := range c.Tokens {
+		if t.SaltedID == salted || t.SaltedAccessor == salted {
+			return t
+		}
+	}
+	return nil
+}
+
+// canaryConfig returns the current canary config
+func (c *Core) canaryConfig() *CanaryConfig {
+	if config, ok := c.canary.Load().(*CanaryConfig); ok {
+		return config
+	}
+	return &CanaryConfig{}
+}
+
+// setCanaryConfig persists the canary config and applies it. The canary
+// lock must be held.
+func (c *Core) setCanaryConfig(config *CanaryConfig) error {
+	view := c.systemBarrierView.SubView("config/")
+
+	entry, err := logical.StorageEntryJSON("canary", config)
+	if err != nil {
+		return fmt.Errorf("failed to create canary config entry: %v", err)
+	}
+	if err := view.Put(entry); err != nil {
+		return fmt.Errorf("failed to save canary config: %v", err)
+	}
+
+	c.canary.Store(config)
+	return nil
+}
+
+// This should only be called with the core state lock held for writing
+func (c *Core) loadCanaryConfig() error {
+	view := c.systemBarrierView.SubView("config/")
+
+	out, err := view.Get("canary")
+	if err != nil {
+		return fmt.Errorf("failed to read canary config: %v", err)
+	}
+
+	config := &CanaryConfig{}
+	if out != nil {
+		if err := out.DecodeJSON(config); err != nil {
+			return err
+		}
+	}
+
+	c.canary.Store(config)
+	return nil
+}
+
+// mintCanaryToken creates a canary token, returning its ID and accessor.
+// The canary lock must be held.
+func (c *Core) mintCanaryToken(config *CanaryConfig, token *CanaryToken) (string, string, error) {
+	id, err := c.tokenStore.generateID()
+	if err != nil {
+		return "", "", err
+	}
+	accessor, err := generateUUID(c.entropy)
+	if err != nil {
+		return "", "", err
+	}
+	if token.SaltedID, err = c.tokenStore.SaltID(id); err != nil {
+		return "", "", err
+	}
+	if token.SaltedAccessor, err = c.tokenStore.SaltID(accessor); err != nil {
+		return "", "", err
+	}
+	token.CreationTime = c.clock.Now().UTC()
+
+	config.Tokens[token.Name] = token
+	if err := c.setCanaryConfig(config); err != nil {
+		return "", "", err
+	}
+	return id, accessor, nil
+}
+
+// canaryCandidates returns the values of the request that may be canary
+// token IDs or accessors: its client token, and the token or accessor
+// looked up, renewed or revoked through the token store
+func canaryCandidates(req *logical.Request) []string {
+	var candidates []string
+	if req.ClientToken != "" {
+		candidates = append(candidates, req.ClientToken)
+	}
+	if strings.HasPrefix(req.Path, "auth/token/") {
+		for _, key := range []string{"token", "accessor"} {
+			if v := req.GetString(key); v != "" {
+				candidates = append(candidates, v)
+			}
+		}
+		// Tokens and accessors can also be given in the path, as in
+		// auth/token/lookup/:token
+		if parts := strings.Split(req.Path, "/"); len(parts) > 3 {
+			candidates = append(candidates, path.Base(req.Path))
+		}
+	}
+	return candidates
+}
+
+// checkCanary flags the request in the audit log and alerts the hooks of
+// the canary token if the request trips one
+func (c *Core) checkCanary(req *logical.Request) {
+	config := c.canaryConfig()
+	if len(config.Tokens) == 0 {
+		return
+	}
+
+	var token *CanaryToken
+	for _, candidate := range canaryCandidates(req) {
+		salted, err := c.tokenStore.SaltID(candidate)
+		if err != nil {
+			c.logger.Error("core: failed to salt canary candidate", "error", err)
+			return
+		}
+		if token = config.match(salted); token != nil {
+			break
+		}
+	}
+	if token == nil {
+		return
+	}
+
+	req.Canary = token.Name
+	metrics.IncrCounter([]string{"core", "canary", "tripped"}, 1)
+	c.logger.Warn("core: canary token tripped", "canary", token.Name, "path", req.Path, "operation", req.Operation, "remote_address", canaryRemoteAddr(req))
+
+	now := c.clock.Now().UTC()
+	var hooks []*CanaryHook
+	for _, name := range token.Hooks {
+		if hook := config.Hooks[name]; hook != nil {
+			hooks = append(hooks, hook)
+		}
+	}
+	alert := map[string]interface{}{
+		"canary":         token.Name,
+		"metadata":       token.Metadata,
+		"path":           req.Path,
+		"operation":      req.Operation,
+		"remote_address": canaryRemoteAddr(req),
+		"time":           now.Format(time.RFC3339),
+	}
+	go c.canaryTripped(token.Name, now, hooks, alert)
+}
+
+// canaryTripped records the trip of the canary token and alerts its hooks
+func (c *Core) canaryTripped(name string, now time.Time, hooks []*CanaryHook, alert map[string]interface{}) {
+	for _, hook := range hooks {
+		if err := postCanaryAlert(hook, alert); err != nil {
+			c.logger.Error("core: failed to alert canary hook", "canary", name, "hook", hook.Name, "error", err)
+		}
+	}
+
+	c.canaryLock.Lock()
+	defer c.canaryLock.Unlock()
+
+	config := c.canaryConfig().clone()
+	existing := config.Tokens[name]
+	if existing == nil {
+		return
+	}
+	token := *existing
+	token.Trips++
+	token.LastTripped = now
+	config.Tokens[name] = &token
+	if err := c.setCanaryConfig(config); err != nil {
+		c.logger.Error("core: failed to record canary trip", "canary", name, "error", err)
+	}
+}
+
+// postCanaryAlert posts the alert to the hook
+func postCanaryAlert(hook *CanaryHook, alert map[string]interface{}) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	client := cleanhttp.DefaultClient()
+	client.Timeout = canaryHookTimeout
+	resp, err := client.Post(hook.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("hook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func canaryRemoteAddr(req *logical.Request) string {
+	if req.Connection != nil {
+		return req.Connection.RemoteAddr
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+package vault
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestCore_CanaryToken(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	noop := &NoopAudit{}
+	c.auditBackends["noop"] = func(config *audit.BackendConfig) (audit.Backend, error) {
+		noop.Config = config
+		return noop, nil
+	}
+	if err := c.enableAudit(&MountEntry{
+		Table: auditTableType,
+		Path:  "noop/",
+		Type:  "noop",
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	alerts := make(chan map[string]interface{}, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("err: %v", err)
+		}
+		alerts <- alert
+	}))
+	defer ts.Close()
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/canary/hook/soc")
+	req.Data["url"] = ts.URL
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Tokens cannot use unknown hooks
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/canary/token/ci")
+	req.Data["hooks"] = "soc,pager"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); !errwrap.Contains(err, logical.ErrInvalidRequest.Error()) {
+		t.Fatalf("expected an invalid request, got %v", err)
+	}
+
+	req.Data["hooks"] = "soc"
+	req.Data["metadata"] = map[string]interface{}{
+		"planted": "ci/.env",
+	}
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	token := resp.Data["token"].(string)
+	accessor := resp.Data["accessor"].(string)
+	if token == "" || accessor == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	expectAlert := func(path string) {
+		select {
+		case alert := <-alerts:
+			if alert["canary"] != "ci" || alert["path"] != path {
+				t.Fatalf("bad: %#v", alert)
+			}
+			if metadata, _ := alert["metadata"].(map[string]interface{}); metadata["planted"] != "ci/.env" {
+				t.Fatalf("bad: %#v", alert)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no alert for %s", path)
+		}
+	}
+
+	// Using the canary token is denied, flagged and alerted
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = token
+	if _, err := c.HandleRequest(req); !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+	if last := noop.Req[len(noop.Req)-1]; last.Canary != "ci" {
+		t.Fatalf("expected the request to be flagged, got %q", last.Canary)
+	}
+	expectAlert("secret/foo")
+
+	// So is looking up its accessor
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/lookup-accessor")
+	req.Data["accessor"] = accessor
+	req.ClientToken = root
+	c.HandleRequest(req)
+	if last := noop.Req[len(noop.Req)-1]; last.Canary != "ci" {
+		t.Fatalf("expected the request to be flagged, got %q", last.Canary)
+	}
+	expectAlert("auth/token/lookup-accessor")
+
+	// Other requests are not flagged
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if last := noop.Req[len(noop.Req)-1]; last.Canary != "" {
+		t.Fatalf("expected the request not to be flagged, got %q", last.Canary)
+	}
+
+	// The trips are recorded once the hooks are alerted
+	var trips interface{}
+	for i := 0; i < 50; i++ {
+		req = logical.TestRequest(t, logical.ReadOperation, "sys/canary/token/ci")
+		req.ClientToken = root
+		resp, err = c.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if trips = resp.Data["trips"]; trips == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if trips != 2 {
+		t.Fatalf("expected 2 trips, got %v", trips)
+	}
+	if _, ok := resp.Data["token"]; ok {
+		t.Fatalf("canary token returned on read: %#v", resp.Data)
+	}
+
+	// Hooks used by tokens cannot be deleted
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/canary/hook/soc")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); !errwrap.Contains(err, logical.ErrInvalidRequest.Error()) {
+		t.Fatalf("expected an invalid request, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package vault
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// ClientLimitRetryAfter is the delay clients are asked to wait before
+// retrying a request rejected by the client limits
+const ClientLimitRetryAfter = 1 * time.Second
+
+// ErrClientLimit is returned for requests rejected because the client has
+// too many requests in flight
+var ErrClientLimit = logical.CodedError(http.StatusTooManyRequests, "too many concurrent requests from this client")
+
+// clientLimiter caps the number of requests in flight per token accessor and
+// per source IP, so that a single client cannot exhaust the file
+// descriptors or the request forwarding pool of the active node
+type clientLimiter struct {
+	maxPerToken int
+	maxPerIP    int
+
+	l       sync.Mutex
+	byToken map[string]int
+	byIP    map[string]int
+}
+
+func newClientLimiter(maxPerToken, maxPerIP int) *clientLimiter {
+	if maxPerToken <= 0 && maxPerIP <= 0 {
+		return nil
+	}
+	return &clientLimiter{
+		maxPerToken: maxPerToken,
+		maxPerIP:    maxPerIP,
+		byToken:     make(map[string]int),
+		byIP:        make(map[string]int),
+	}
+}
+
+// acquireIP takes a slot for a request from the given source IP, returning
+// the function releasing it, or ErrClientLimit if the IP has no slot left
+func (l *clientLimiter) acquireIP(ip string) (func(), error) {
+	if l == nil || l.maxPerIP <= 0 || ip == "" {
+		return func() {}, nil
+	}
+	return l.acquire(l.byIP, l.maxPerIP, ip, "ip")
+}
+
+// acquireToken takes a slot for a request made with the token of the given
+// accessor, returning the function releasing it, or ErrClientLimit if the
+// token has no slot left
+func (l *clientLimiter) acquireToken(accessor string) (func(), error) {
+	if l == nil || l.maxPerToken <= 0 || accessor == "" {
+		return func() {}, nil
+	}
+	return l.acquire(l.byToken, l.maxPerToken, accessor, "token")
+}
+
+func (l *clientLimiter) acquire(counts map[string]int, max int, key, kind string) (func(), error) {
+	l.l.Lock()
+	defer l.l.Unlock()
+
+	if counts[key] >= max {
+		metrics.IncrCounter([]string{"core", "client_limit", "rejected", kind}, 1)
+		return nil, ErrClientLimit
+	}
+	counts[key]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.l.Lock()
+			defer l.l.Unlock()
+			if counts[key] <= 1 {
+				delete(counts, key)
+			} else {
+				counts[key]--
+			}
+		})
+	}, nil
+}
+
+// clientIP returns the source IP of the request, if known
+func clientIP(req *logical.Request) string {
+	if req.Connection == nil {
+		return ""
+	}
+	return req.Connection.RemoteAddr
+}
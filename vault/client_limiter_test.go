@@ -0,0 +1,78 @@
+package vault
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestClientLimiter_disabled(t *testing.T) {
+	l := newClientLimiter(0, 0)
+	if l != nil {
+		t.Fatalf("expected no limiter without limits")
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := l.acquireIP("127.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := l.acquireToken("accessor"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCore_clientLimits(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	c.clientLimiter = newClientLimiter(1, 2)
+
+	te, err := c.tokenStore.Lookup(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := func(ip string) error {
+		req := logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+		req.ClientToken = root
+		req.Connection = &logical.Connection{RemoteAddr: ip}
+		_, err := c.HandleRequest(req)
+		return err
+	}
+
+	// The token has a request in flight
+	release, err := c.clientLimiter.acquireToken(te.Accessor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := read("127.0.0.1"); err != ErrClientLimit {
+		t.Fatalf("expected the token to be limited, got: %v", err)
+	}
+	release()
+	release()
+	if err := read("127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.clientLimiter.byToken) != 0 || len(c.clientLimiter.byIP) != 0 {
+		t.Fatalf("expected the slots to be released: %#v %#v", c.clientLimiter.byToken, c.clientLimiter.byIP)
+	}
+
+	// The IP has two requests in flight
+	for i := 0; i < 2; i++ {
+		release, err := c.clientLimiter.acquireIP("127.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer release()
+	}
+	if err := read("127.0.0.1"); err != ErrClientLimit {
+		t.Fatalf("expected the IP to be limited, got: %v", err)
+	}
+	if err := read("127.0.0.2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests without a token are still subject to the IP limit
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+	req.Connection = &logical.Connection{RemoteAddr: "127.0.0.1"}
+	if _, err := c.HandleRequest(req); err != ErrClientLimit {
+		t.Fatalf("expected the IP to be limited, got: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package vault
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	log "github.com/mgutz/logxi/v1"
+
+	"github.com/hashicorp/vault/helper/sntp"
+)
+
+const (
+	// clockDriftQueryTimeout bounds the queries to the NTP server
+	clockDriftQueryTimeout = 5 * time.Second
+
+	// defaultClockDriftThreshold is the drift above which a warning is
+	// reported when no clock skew tolerance is configured
+	defaultClockDriftThreshold = 1 * time.Second
+)
+
+// clockDriftCheckInterval is how often the clock is compared to the NTP
+// server
+var clockDriftCheckInterval = 1 * time.Minute
+
+// clockDriftMonitor periodically measures the offset of the local clock from
+// an NTP server, so that nodes running on VMs with drifting clocks report it
+// in their health before TTL and certificate checks start failing
+type clockDriftMonitor struct {
+	server    string
+	threshold time.Duration
+	logger    log.Logger
+
+	l       sync.RWMutex
+	offset  time.Duration
+	checked bool
+	err     error
+
+	// query returns the offset from the server; it is replaced in tests
+	query func(server string) (time.Duration, error)
+}
+
+func newClockDriftMonitor(server string, tolerance time.Duration, logger log.Logger) *clockDriftMonitor {
+	if server == "" {
+		return nil
+	}
+	threshold := tolerance
+	if threshold <= 0 {
+		threshold = defaultClockDriftThreshold
+	}
+	return &clockDriftMonitor{
+		server:    server,
+		threshold: threshold,
+		logger:    logger,
+		query: func(server string) (time.Duration, error) {
+			return sntp.Offset(server, clockDriftQueryTimeout)
+		},
+	}
+}
+
+// run measures the offset until stopCh is closed
+func (m *clockDriftMonitor) run(stopCh chan struct{}) {
+	m.check()
+	for {
+		select {
+		case <-time.After(clockDriftCheckInterval):
+			m.check()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// check measures the offset from the server and logs when it goes above or
+// back below the threshold
+func (m *clockDriftMonitor) check() {
+	offset, err := m.query(m.server)
+
+	m.l.Lock()
+	wasDrifting := m.drifting()
+	m.checked = true
+	m.offset, m.err = offset, err
+	drifting := m.drifting()
+	m.l.Unlock()
+
+	if err != nil {
+		m.logger.Warn("core: failed to measure clock drift", "server", m.server, "error", err)
+		return
+	}
+	metrics.SetGauge([]string{"core", "clock", "drift_ms"}, float32(offset/time.Millisecond))
+
+	switch {
+	case drifting && !wasDrifting:
+		m.logger.Warn("core: clock drift exceeds the clock skew tolerance", "server", m.server, "offset", offset, "threshold", m.threshold)
+	case !drifting && wasDrifting:
+		m.logger.Info("core: clock drift back within the clock skew tolerance", "server", m.server, "offset", offset)
+	}
+}
+
+// drifting returns true if the last offset measured is above the threshold;
+// the lock must be held
+func (m *clockDriftMonitor) drifting() bool {
+	if !m.checked || m.err != nil {
+		return false
+	}
+	offset := m.offset
+	if offset < 0 {
+		offset = -offset
+	}
+	return offset > m.threshold
+}
+
+// warning returns the warning to report in the health of the node, if any
+func (m *clockDriftMonitor) warning() string {
+	if m == nil {
+		return ""
+	}
+
+	m.l.RLock()
+	defer m.l.RUnlock()
+	switch {
+	case m.err != nil:
+		return fmt.Sprintf("clock drift could not be measured against %s: %v", m.server, m.err)
+	case m.drifting():
+		return fmt.Sprintf("clock is off by %s from %s, above the tolerance of %s", m.offset, m.server, m.threshold)
+	}
+	return ""
+}
+
+// startClockDriftMonitor starts measuring the clock drift if an NTP server is
+// configured
+func (c *Core) startClockDriftMonitor() {
+	if c.clockDrift == nil {
+		return
+	}
+	c.clockDriftCh = make(chan struct{})
+	go c.clockDrift.run(c.clockDriftCh)
+}
+
+// stopClockDriftMonitor stops measuring the clock drift
+func (c *Core) stopClockDriftMonitor() {
+	if c.clockDriftCh != nil {
+		close(c.clockDriftCh)
+		c.clockDriftCh = nil
+	}
+}
+
+// HealthWarnings returns the warnings to report in the health of the node
+func (c *Core) HealthWarnings() []string {
+	var warnings []string
+	if warning := c.clockDrift.warning(); warning != "" {
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+// ClockSkewTolerance returns the tolerance applied to the time checks of
+// tokens, leases, wrapping tokens and certificates
+func (c *Core) ClockSkewTolerance() time.Duration {
+	return c.clockSkewTolerance
+}
@@ -0,0 +1,66 @@
+package vault
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestClockDriftMonitor(t *testing.T) {
+	if newClockDriftMonitor("", time.Second, nil) != nil {
+		t.Fatal("expected no monitor without an NTP server")
+	}
+
+	m := newClockDriftMonitor("ntp.example.com", 0, logformat.NewVaultLogger(log.LevelTrace))
+	if m.threshold != defaultClockDriftThreshold {
+		t.Fatalf("bad: %v", m.threshold)
+	}
+
+	var offset time.Duration
+	var queryErr error
+	m.query = func(string) (time.Duration, error) {
+		return offset, queryErr
+	}
+
+	// Nothing is reported before the first check or within the threshold
+	if warning := m.warning(); warning != "" {
+		t.Fatalf("bad: %q", warning)
+	}
+	offset = -500 * time.Millisecond
+	m.check()
+	if warning := m.warning(); warning != "" {
+		t.Fatalf("bad: %q", warning)
+	}
+
+	offset = -2 * time.Second
+	m.check()
+	if warning := m.warning(); !strings.Contains(warning, "clock is off by -2s from ntp.example.com") {
+		t.Fatalf("bad: %q", warning)
+	}
+
+	queryErr = errors.New("timeout")
+	m.check()
+	if warning := m.warning(); !strings.Contains(warning, "could not be measured") {
+		t.Fatalf("bad: %q", warning)
+	}
+}
+
+func TestCore_HealthWarnings(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	if warnings := c.HealthWarnings(); len(warnings) != 0 {
+		t.Fatalf("bad: %v", warnings)
+	}
+
+	c.clockDrift = newClockDriftMonitor("ntp.example.com", 30*time.Second, c.logger)
+	c.clockDrift.query = func(string) (time.Duration, error) {
+		return time.Minute, nil
+	}
+	c.clockDrift.check()
+	if warnings := c.HealthWarnings(); len(warnings) != 1 || !strings.Contains(warnings[0], "above the tolerance of 30s") {
+		t.Fatalf("bad: %v", warnings)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"crypto/tls"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"os"
 	"testing"
 	"time"
 
@@ -87,7 +89,7 @@ func TestCluster_ListenForRequests(t *testing.T) {
 
 	cluster := NewTestCluster(t, nil, false)
 	cluster.StartListeners()
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 	cores := cluster.Cores
 
 	root := cores[0].Root
@@ -200,7 +202,7 @@ func testCluster_ForwardRequestsCommon(t *testing.T) {
 
 	cluster := NewTestCluster(t, nil, true)
 	cluster.StartListeners()
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 	cores := cluster.Cores
 	cores[0].Handler.HandleFunc("/core1", func(w http.ResponseWriter, req *http.Request) {
 		w.Header().Add("Content-Type", "application/json")
@@ -392,3 +394,222 @@ func testCluster_ForwardRequests(t *testing.T, c *TestClusterCore, remoteCoreID
 		}
 	}
 }
+
+func TestCluster_DisableTLS(t *testing.T) {
+	cluster := NewTestClusterWithOptions(t, nil, &TestClusterOptions{
+		DisableTLS: true,
+	})
+	for _, core := range cluster.Cores {
+		core.Handler.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+	cluster.StartListeners()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0]
+	if core.TLSConfig != nil || core.CACert != nil {
+		t.Fatal("expected no TLS material")
+	}
+	if addr := core.Client.Address(); addr != fmt.Sprintf("http://127.0.0.1:%d", core.Listeners[0].Address.Port) {
+		t.Fatalf("bad: %s", addr)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/", core.Listeners[0].Address.Port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("bad: %d", resp.StatusCode)
+	}
+
+	// Request forwarding between the cores still uses TLS
+	if _, err := core.ClusterTLSConfig(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCluster_ExternalTLS(t *testing.T) {
+	cluster := NewTestClusterWithOptions(t, nil, &TestClusterOptions{
+		CACert:     []byte(TestClusterCACert),
+		ServerCert: []byte(TestClusterServerCert),
+		ServerKey:  []byte(TestClusterServerKey),
+	})
+	core := cluster.Cores[0]
+	core.Handler.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	cluster.StartListeners()
+	defer cluster.Cleanup()
+
+	if core.CACert == nil || core.TLSConfig == nil {
+		t.Fatal("expected TLS material")
+	}
+
+	// The API client of the core trusts the provided CA
+	resp, err := core.Client.RawRequest(core.Client.NewRequest("GET", "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("bad: %d", resp.StatusCode)
+	}
+}
+
+func TestCluster_PerCoreConfig(t *testing.T) {
+	var redirectAddrs []string
+	cluster := NewTestClusterWithOptions(t, &CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"noop": PassthroughBackendFactory,
+		},
+	}, &TestClusterOptions{
+		PerCoreConfig: func(i int, config *CoreConfig) {
+			redirectAddrs = append(redirectAddrs, config.RedirectAddr)
+			if i == 2 {
+				config.DisableCache = true
+				delete(config.LogicalBackends, "noop")
+			}
+		},
+	})
+	defer cluster.Cleanup()
+
+	if len(redirectAddrs) != 3 || redirectAddrs[0] == redirectAddrs[1] || redirectAddrs[1] == redirectAddrs[2] {
+		t.Fatalf("bad: %v", redirectAddrs)
+	}
+
+	// Only the customized core is affected
+	for i, core := range cluster.Cores {
+		_, isCache := core.physical.(*physical.Cache)
+		if isCache != (i != 2) {
+			t.Fatalf("core %d: bad cache setting", i)
+		}
+		_, ok := core.logicalBackends["noop"]
+		if ok != (i != 2) {
+			t.Fatalf("core %d: bad backends", i)
+		}
+	}
+}
+
+func TestCluster_PhysicalFactory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-cluster-file")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Each core gets its own file backend over the shared directory, with
+	// a shared inmem HA lock
+	var ha physical.HABackend
+	cluster := NewTestClusterWithOptions(t, nil, &TestClusterOptions{
+		UnsealStandbys: true,
+		PhysicalFactory: func(i int, logger log.Logger) (physical.Backend, physical.HABackend, error) {
+			if ha == nil {
+				ha = physical.NewInmemHA(logger)
+			}
+			phys, err := physical.NewBackend("file", logger, map[string]string{
+				"path": dir,
+			})
+			return phys, ha, err
+		},
+	})
+	defer cluster.Cleanup()
+
+	for i, core := range cluster.Cores {
+		if core.ha != ha {
+			t.Fatalf("core %d: bad HA backend", i)
+		}
+		if i > 0 && core.physical == cluster.Cores[0].physical {
+			t.Fatalf("core %d: shares the physical backend of core 0", i)
+		}
+	}
+
+	active := cluster.Cores[0]
+	TestWaitActive(t, active.Core)
+	req := logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+	req.Data["bar"] = "baz"
+	req.ClientToken = active.Root
+	if _, err := active.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A standby takes over the lock and reads the data written through the
+	// file backend of the previous active core
+	if err := active.Seal(active.Root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	next := cluster.WaitForActiveNode(t)
+
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = active.Root
+	resp, err := next.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Data["bar"] != "baz" {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestCluster_SealUnsealHelpers(t *testing.T) {
+	cluster := NewTestClusterWithOptions(t, nil, &TestClusterOptions{
+		UnsealStandbys: true,
+	})
+	defer cluster.Cleanup()
+
+	cluster.EnsureCoresSealed(t)
+	for i, core := range cluster.Cores {
+		if sealed, _ := core.Sealed(); !sealed {
+			t.Fatalf("core %d: not sealed", i)
+		}
+	}
+
+	// The first core unsealed becomes active, the next one a standby
+	cluster.UnsealCore(t, cluster.Cores[1])
+	TestWaitActive(t, cluster.Cores[1].Core)
+	cluster.UnsealCore(t, cluster.Cores[2])
+	cluster.WaitForStandby(t, cluster.Cores[2])
+
+	// Sealing the active core promotes the standby
+	cluster.Cores[1].SealForTest(t)
+	if active := cluster.WaitForActiveNode(t); active != cluster.Cores[2] {
+		t.Fatal("core 2: not promoted")
+	}
+
+	// Standbys can be sealed too
+	cluster.UnsealCore(t, cluster.Cores[0])
+	cluster.Cores[0].SealForTest(t)
+	if sealed, _ := cluster.Cores[0].Sealed(); !sealed {
+		t.Fatal("core 0: not sealed")
+	}
+}
+
+func TestClusterCore_MountBackend(t *testing.T) {
+	cluster := NewTestCluster(t, &CoreConfig{
+		CredentialBackends: map[string]logical.Factory{
+			"noop": func(*logical.BackendConfig) (logical.Backend, error) {
+				return &NoopBackend{}, nil
+			},
+		},
+	}, false)
+	defer cluster.Cleanup()
+	core := cluster.Cores[0]
+	TestWaitActive(t, core.Core)
+
+	core.MountBackend(t, "foo", "generic", map[string]interface{}{
+		"default_lease_ttl": "1h",
+	})
+	me := core.router.MatchingMountEntry("foo/")
+	if me == nil || me.Type != "generic" || me.Config.DefaultLeaseTTL != time.Hour {
+		t.Fatalf("bad: %#v", me)
+	}
+
+	core.MountAuthBackend(t, "bar", "noop", map[string]interface{}{
+		"max_lease_ttl": "2h",
+	})
+	me = core.router.MatchingMountEntry("auth/bar/")
+	if me == nil || me.Type != "noop" || me.Config.MaxLeaseTTL != 2*time.Hour {
+		t.Fatalf("bad: %#v", me)
+	}
+}
@@ -0,0 +1,240 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// controlGroupPrefix is the storage prefix of the pending control group
+	// requests, relative to the system view
+	controlGroupPrefix = "control-group/"
+
+	// controlGroupRequestTTL is how long a control group request can wait
+	// for its approvals
+	controlGroupRequestTTL = 24 * time.Hour
+
+	controlGroupOperationMount = "mount"
+	controlGroupOperationAuth  = "auth"
+)
+
+var (
+	// ErrControlGroupRequestNotFound is returned for unknown or expired
+	// control group requests
+	ErrControlGroupRequestNotFound = errors.New("control group request not found")
+)
+
+// ControlGroupRequest is an operation that waits for the approval of a
+// number of distinct identities before it is carried out. It is used to gate
+// enabling secret and credential backends when the core is configured with
+// mount approvals, so that a single compromised admin token cannot add a
+// malicious mount on its own.
+type ControlGroupRequest struct {
+	ID        string      `json:"id"`
+	Operation string      `json:"operation"`
+	Entry     *MountEntry `json:"entry"`
+
+	// RequestedBy is the identity of the requester, which cannot approve
+	// its own request
+	RequestedBy string `json:"requested_by"`
+
+	// Approvals are the identities that approved the request
+	Approvals []string `json:"approvals"`
+
+	CreationTime time.Time `json:"creation_time"`
+}
+
+func (r *ControlGroupRequest) expired() bool {
+	return time.Now().After(r.CreationTime.Add(controlGroupRequestTTL))
+}
+
+func (r *ControlGroupRequest) toResponseData(required int) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                 r.ID,
+		"operation":          r.Operation,
+		"path":               r.Entry.Path,
+		"type":               r.Entry.Type,
+		"requested_by":       r.RequestedBy,
+		"approvals":          r.Approvals,
+		"approvals_required": required,
+		"creation_time":      r.CreationTime,
+		"expiration_time":    r.CreationTime.Add(controlGroupRequestTTL),
+	}
+}
+
+// controlGroupIdentity returns the identity of the client of the request
+// for the purpose of counting approvals. Tokens that were not obtained by
+// logging in to a credential backend, such as root tokens, have none.
+func (c *Core) controlGroupIdentity(req *logical.Request) (string, error) {
+	te, err := c.tokenStore.Lookup(req.ClientToken)
+	if err != nil {
+		return "", err
+	}
+	if te == nil {
+		return "", nil
+	}
+	return te.Entity, nil
+}
+
+// requestMountApproval stores a control group request for enabling the
+// mount entry instead of enabling it
+func (c *Core) requestMountApproval(req *logical.Request, operation string, me *MountEntry) (*logical.Response, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	requestedBy, err := c.controlGroupIdentity(req)
+	if err != nil {
+		return nil, err
+	}
+
+	cgr := &ControlGroupRequest{
+		ID:           id,
+		Operation:    operation,
+		Entry:        me,
+		RequestedBy:  requestedBy,
+		Approvals:    []string{},
+		CreationTime: time.Now(),
+	}
+	if err := c.putControlGroupRequest(cgr); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("core: mount awaiting approval", "id", id, "operation", operation, "path", me.Path)
+
+	resp := &logical.Response{
+		Data: cgr.toResponseData(c.mountApprovals),
+	}
+	resp.AddWarning(fmt.Sprintf(
+		"Enabling mounts requires %d approvals; the mount will be enabled once request %s is approved",
+		c.mountApprovals, id))
+	return resp, nil
+}
+
+// approveControlGroupRequest records the approval of the client of the
+// request, and carries out the operation once enough approvals have been
+// given. Approvers must be login identities listed in the configuration,
+// which cannot be created through the API, and hold the approve capability,
+// which neither root tokens nor sudo grant.
+func (c *Core) approveControlGroupRequest(req *logical.Request, id string) (*logical.Response, error) {
+	c.controlGroupLock.Lock()
+	defer c.controlGroupLock.Unlock()
+
+	cgr, err := c.getControlGroupRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	if cgr == nil {
+		return logical.ErrorResponse(ErrControlGroupRequestNotFound.Error()), logical.ErrInvalidRequest
+	}
+
+	acl, te, err := c.fetchACLandTokenEntry(req)
+	if err != nil {
+		return nil, err
+	}
+	approver := te.Entity
+	switch {
+	case approver == "":
+		return logical.ErrorResponse("approvals must be given with a token obtained by logging in to a credential backend"), logical.ErrPermissionDenied
+	case !strutil.StrListContains(c.mountApprovers, approver):
+		return logical.ErrorResponse(fmt.Sprintf("%q is not configured as a mount approver", approver)), logical.ErrPermissionDenied
+	case !strutil.StrListContains(acl.Capabilities("sys/control-group/"+id+"/approve"), ApproveCapability):
+		return logical.ErrorResponse(fmt.Sprintf("approvals require the %q capability", ApproveCapability)), logical.ErrPermissionDenied
+	case approver == cgr.RequestedBy:
+		return logical.ErrorResponse("requests cannot be approved by their requester"), logical.ErrPermissionDenied
+	}
+	for _, approval := range cgr.Approvals {
+		if approval == approver {
+			return logical.ErrorResponse(fmt.Sprintf("request already approved by %q", approver)), logical.ErrInvalidRequest
+		}
+	}
+	cgr.Approvals = append(cgr.Approvals, approver)
+
+	c.logger.Info("core: mount approved", "id", id, "approver", approver, "approvals", len(cgr.Approvals))
+
+	if len(cgr.Approvals) < c.mountApprovals {
+		if err := c.putControlGroupRequest(cgr); err != nil {
+			return nil, err
+		}
+		return &logical.Response{
+			Data: cgr.toResponseData(c.mountApprovals),
+		}, nil
+	}
+
+	// The request is approved; it is removed whether or not the operation
+	// succeeds so that it cannot be retried without new approvals
+	if err := c.systemBarrierView.Delete(controlGroupPrefix + id); err != nil {
+		return nil, err
+	}
+	switch cgr.Operation {
+	case controlGroupOperationMount:
+		err = c.mount(cgr.Entry)
+	case controlGroupOperationAuth:
+		err = c.enableCredential(cgr.Entry)
+	default:
+		err = fmt.Errorf("unknown control group operation %q", cgr.Operation)
+	}
+	if err != nil {
+		c.logger.Error("core: approved mount failed", "id", id, "path", cgr.Entry.Path, "error", err)
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+// getControlGroupRequest returns the control group request, or nil if it
+// does not exist or has expired
+func (c *Core) getControlGroupRequest(id string) (*ControlGroupRequest, error) {
+	entry, err := c.systemBarrierView.Get(controlGroupPrefix + id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var cgr ControlGroupRequest
+	if err := entry.DecodeJSON(&cgr); err != nil {
+		return nil, err
+	}
+	if cgr.expired() {
+		if err := c.systemBarrierView.Delete(controlGroupPrefix + id); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return &cgr, nil
+}
+
+func (c *Core) putControlGroupRequest(cgr *ControlGroupRequest) error {
+	entry, err := logical.StorageEntryJSON(controlGroupPrefix+cgr.ID, cgr)
+	if err != nil {
+		return err
+	}
+	return c.systemBarrierView.Put(entry)
+}
+
+// listControlGroupRequests returns the IDs of the pending control group
+// requests
+func (c *Core) listControlGroupRequests() ([]string, error) {
+	ids, err := c.systemBarrierView.List(controlGroupPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]string, 0, len(ids))
+	for _, id := range ids {
+		cgr, err := c.getControlGroupRequest(id)
+		if err != nil {
+			return nil, err
+		}
+		if cgr != nil {
+			pending = append(pending, id)
+		}
+	}
+	return pending, nil
+}
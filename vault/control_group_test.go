@@ -0,0 +1,197 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// testCoreControlGroup returns an unsealed core requiring two approvals of
+// alice, bob or carol to enable mounts, with a noop credential backend
+// mounted at auth/foo
+func testCoreControlGroup(t *testing.T) (*Core, *NoopBackend, string) {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+	}
+	c, _, root := TestCoreUnsealed(t)
+	c.credentialBackends["noop"] = func(conf *logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/auth/foo")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for _, rules := range []string{`
+name = "admin"
+path "sys/*" {
+	capabilities = ["create", "read", "update", "delete", "list", "sudo"]
+}
+`, `
+name = "approver"
+path "sys/control-group/*" {
+	capabilities = ["read", "update", "delete", "list", "sudo", "approve"]
+}
+`} {
+		policy, err := Parse(rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.policyStore.SetPolicy(policy); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c.mountApprovals = 2
+	c.mountApprovers = []string{"foo/alice", "foo/bob", "foo/carol"}
+	return c, noop, root
+}
+
+// testLoginControlGroup logs in as the given user with the admin policy and
+// the given ones
+func testLoginControlGroup(t *testing.T, c *Core, noop *NoopBackend, user string, policies ...string) string {
+	noop.Lock()
+	noop.Response = &logical.Response{
+		Auth: &logical.Auth{
+			Policies:    append([]string{"admin"}, policies...),
+			DisplayName: user,
+		},
+	}
+	noop.Unlock()
+
+	resp, err := c.HandleRequest(&logical.Request{
+		Path: "auth/foo/login",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Auth == nil || resp.Auth.ClientToken == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	return resp.Auth.ClientToken
+}
+
+func testApproveControlGroup(t *testing.T, c *Core, token, id string) (*logical.Response, error) {
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/control-group/"+id+"/approve")
+	req.ClientToken = token
+	return c.HandleRequest(req)
+}
+
+func TestControlGroup_Mount(t *testing.T) {
+	c, noop, root := testCoreControlGroup(t)
+	alice := testLoginControlGroup(t, c, noop, "alice", "approver")
+	bob := testLoginControlGroup(t, c, noop, "bob", "approver")
+	carol := testLoginControlGroup(t, c, noop, "carol", "approver")
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/gated")
+	req.Data["type"] = "generic"
+	req.ClientToken = alice
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Data["id"] == nil || len(resp.Warnings) == 0 {
+		t.Fatalf("bad: %#v", resp)
+	}
+	id := resp.Data["id"].(string)
+
+	if c.router.MatchingMount("gated/") != "" {
+		t.Fatal("mount should not be enabled before approval")
+	}
+
+	req = logical.TestRequest(t, logical.ListOperation, "sys/control-group/")
+	req.ClientToken = bob
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 || keys[0] != id {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Requesters cannot approve their own requests, and root tokens have no
+	// identity to count
+	if _, err := testApproveControlGroup(t, c, alice, id); err == nil {
+		t.Fatal("expected error approving own request")
+	}
+	if _, err := testApproveControlGroup(t, c, root, id); err == nil {
+		t.Fatal("expected error approving with a root token")
+	}
+
+	// Approvers must be configured, which identities created by logging in
+	// are not, and hold the approve capability, which sudo does not grant
+	dave := testLoginControlGroup(t, c, noop, "dave", "approver")
+	if resp, err := testApproveControlGroup(t, c, dave, id); err == nil || !strings.Contains(resp.Error().Error(), "not configured") {
+		t.Fatalf("expected error approving as an unconfigured identity, got resp: %#v, err: %v", resp, err)
+	}
+	sudoOnly := testLoginControlGroup(t, c, noop, "bob")
+	if resp, err := testApproveControlGroup(t, c, sudoOnly, id); err == nil || !strings.Contains(resp.Error().Error(), ApproveCapability) {
+		t.Fatalf("expected error approving without the approve capability, got resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = testApproveControlGroup(t, c, bob, id)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if approvals := resp.Data["approvals"].([]string); len(approvals) != 1 || approvals[0] != "foo/bob" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if _, err := testApproveControlGroup(t, c, bob, id); err == nil {
+		t.Fatal("expected error approving twice")
+	}
+	if c.router.MatchingMount("gated/") != "" {
+		t.Fatal("mount should not be enabled before all approvals")
+	}
+
+	if _, err := testApproveControlGroup(t, c, carol, id); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if c.router.MatchingMount("gated/") != "gated/" {
+		t.Fatal("mount should be enabled after approval")
+	}
+
+	// The request is gone once carried out
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/control-group/"+id)
+	req.ClientToken = bob
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestControlGroup_EnableAuthRejected(t *testing.T) {
+	c, noop, root := testCoreControlGroup(t)
+	alice := testLoginControlGroup(t, c, noop, "alice")
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/auth/gated")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	id := resp.Data["id"].(string)
+	if resp.Data["operation"] != controlGroupOperationAuth {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/control-group/"+id)
+	req.ClientToken = alice
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if _, err := testApproveControlGroup(t, c, alice, id); err == nil {
+		t.Fatal("expected error approving a rejected request")
+	}
+	if c.router.MatchingMount("auth/gated/") != "" {
+		t.Fatal("auth backend should not be enabled")
+	}
+}
@@ -3,15 +3,20 @@ package vault
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/armon/go-metrics"
@@ -24,11 +29,13 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/cidrutil"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/helper/logformat"
 	"github.com/hashicorp/vault/helper/mlock"
+	"github.com/hashicorp/vault/helper/tokenformat"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/physical"
 	"github.com/hashicorp/vault/shamir"
@@ -152,9 +159,23 @@ type Core struct {
 	// the generate-root process simply to talk to the new follower cluster.
 	devToken string
 
+	// devTLSCACert is the PEM-encoded CA certificate of the listener of a dev
+	// server using TLS, served unauthenticated at sys/dev/ca
+	devTLSCACert string
+
 	// HABackend may be available depending on the physical backend
 	ha physical.HABackend
 
+	// haLockGracePeriod is how long the active node tries to re-acquire a
+	// lost HA lock before stepping down
+	haLockGracePeriod time.Duration
+
+	// leadershipSuspended is set to 1 while the active node re-acquires its
+	// lost HA lock, and requests are rejected meanwhile. It is a flag rather
+	// than the state lock so that sealing, stepping down and shutting down
+	// are not held up by a slow storage backend.
+	leadershipSuspended uint32
+
 	// redirectAddr is the address we advertise as leader if held
 	redirectAddr string
 
@@ -194,6 +215,17 @@ type Core struct {
 	// unlockInfo has the keys provided to Unseal until the threshold number of parts is available, as well as the operation nonce
 	unlockInfo *unlockInformation
 
+	// unsealStatus tracks the phases of the unseal process once the
+	// threshold number of parts has been reached
+	unsealStatus unsealStatusTracker
+
+	// statusSnapshot holds a *StatusSnapshot, updated along with the state
+	// protected by stateLock
+	statusSnapshot atomic.Value
+
+	// stateEvents wakes up the waiters for changes of the state of the core
+	stateEvents *stateEvents
+
 	// generateRootProgress holds the shares until we reach enough
 	// to verify the master key
 	generateRootConfig   *GenerateRootConfig
@@ -207,7 +239,10 @@ type Core struct {
 	barrierRekeyProgress  [][]byte
 	recoveryRekeyConfig   *SealConfig
 	recoveryRekeyProgress [][]byte
-	rekeyLock             sync.RWMutex
+	// recoveryRekeyVerification holds the new recovery key of a rekey
+	// waiting for its shares to be verified
+	recoveryRekeyVerification *rekeyVerification
+	rekeyLock                 sync.RWMutex
 
 	// mounts is loaded after unseal since it is a protected
 	// configuration
@@ -225,6 +260,12 @@ type Core struct {
 	// change underneath a calling function
 	authLock sync.RWMutex
 
+	// persistedMountEntries holds the hashes of the entries of the mount
+	// and auth tables as last loaded or persisted, by storage key, so that
+	// only the entries which changed are written when persisting a table
+	persistedMountEntries     map[string][sha256.Size]byte
+	persistedMountEntriesLock sync.Mutex
+
 	// audit is loaded after unseal since it is a protected
 	// configuration
 	audit *MountTable
@@ -241,6 +282,19 @@ type Core struct {
 	// can be output in the audit logs
 	auditedHeaders *AuditedHeadersConfig
 
+	// auditSecretScanner looks for raw secrets in the audited requests
+	auditSecretScanner *audit.SecretScanner
+
+	// clock drives lease expiration, token TTLs and rollbacks
+	clock Clock
+
+	// entropy is the source of randomness for the IDs of tokens, accessors
+	// and leases
+	entropy io.Reader
+
+	// tokenFormat is the format of the IDs of new tokens
+	tokenFormat string
+
 	// systemBarrierView is the barrier view for the system backend
 	systemBarrierView *BarrierView
 
@@ -264,6 +318,60 @@ type Core struct {
 	// metrics emission and sealing leading to a nil pointer
 	metricsMutex sync.Mutex
 
+	// counters are the counters last collected by collectCounters
+	counters     *Counters
+	countersLock sync.RWMutex
+
+	// barrierRewrap is the running rewrap of the barrier, if any, and
+	// barrierRewrapStatus the progress of the last one. The control lock
+	// serializes starting and stopping rewraps, while the rewrap lock
+	// guards the fields and the status.
+	barrierRewrap            *barrierRewrap
+	barrierRewrapStatus      *BarrierRewrapStatus
+	barrierRewrapLock        sync.Mutex
+	barrierRewrapControlLock sync.Mutex
+
+	// memoryWatchdog sheds load when the memory limits are exceeded; it is
+	// nil if no limits are configured
+	memoryWatchdog *memoryWatchdog
+
+	// clientLimiter caps the requests in flight per token and per source
+	// IP; it is nil if no limit is configured
+	clientLimiter *clientLimiter
+
+	// memoryWatchdogCh is used to stop the memory watchdog
+	memoryWatchdogCh chan struct{}
+
+	// clockSkewTolerance is the leeway given to the time checks of leases,
+	// wrapping tokens and certificates
+	clockSkewTolerance time.Duration
+
+	// clockDrift measures the offset of the clock from an NTP server; it is
+	// nil if no server is configured. clockDriftCh is used to stop it.
+	clockDrift   *clockDriftMonitor
+	clockDriftCh chan struct{}
+
+	// requestJournal records request summaries on disk; it is nil if no
+	// journal is configured
+	requestJournal *requestJournal
+
+	// requestTracer receives the spans of the requests; it is nil if no
+	// tracer is configured
+	requestTracer RequestTracer
+
+	// logMonitors streams the log of the server to sys/monitor; it is nil
+	// if the logger cannot be monitored
+	logMonitors *logformat.LogMonitors
+
+	// mountApprovals is the number of approvals required to enable mounts
+	mountApprovals int
+
+	// mountApprovers are the login identities allowed to approve mounts
+	mountApprovers []string
+
+	// controlGroupLock serializes the approvals of control group requests
+	controlGroupLock sync.Mutex
+
 	defaultLeaseTTL time.Duration
 	maxLeaseTTL     time.Duration
 
@@ -330,10 +438,37 @@ type Core struct {
 	rpcClientConn *grpc.ClientConn
 	// The grpc forwarding client
 	rpcForwardingClient *forwardingClient
+	// Whether the forwarding client exchanged a heartbeat with the active
+	// node
+	rpcForwardingConnected bool
 
 	// CORS Information
 	corsConfig *CORSConfig
 
+	// tokenLimits holds the *TokenLimitsConfig capping the active tokens
+	// of entities
+	tokenLimits atomic.Value
+
+	// mfa holds the *MFAConfig with the MFA methods and enforcements
+	mfa atomic.Value
+
+	// experiments holds the *ExperimentsConfig with the experimental
+	// subsystems enabled on the cluster
+	experiments atomic.Value
+
+	// mfaLock serializes the changes to the MFA config
+	mfaLock sync.Mutex
+
+	// mfaUsedPasscodes holds the TOTP passcodes that were used recently so
+	// that they cannot be replayed
+	mfaUsedPasscodes *cache.Cache
+
+	// canary holds the *CanaryConfig with the canary hooks and tokens
+	canary atomic.Value
+
+	// canaryLock serializes the changes to the canary config
+	canaryLock sync.Mutex
+
 	// replicationState keeps the current replication state cached for quick
 	// lookup
 	replicationState consts.ReplicationState
@@ -347,6 +482,9 @@ type Core struct {
 	// pluginCatalog is used to manage plugin configurations
 	pluginCatalog *PluginCatalog
 
+	// pluginSupervisor restarts the plugins of mounts which crashed
+	pluginSupervisor *pluginSupervisor
+
 	enableMlock bool
 }
 
@@ -354,21 +492,38 @@ type Core struct {
 type CoreConfig struct {
 	DevToken string `json:"dev_token" structs:"dev_token" mapstructure:"dev_token"`
 
+	DevTLSCACert string `json:"dev_tls_ca_cert" structs:"dev_tls_ca_cert" mapstructure:"dev_tls_ca_cert"`
+
 	LogicalBackends map[string]logical.Factory `json:"logical_backends" structs:"logical_backends" mapstructure:"logical_backends"`
 
 	CredentialBackends map[string]logical.Factory `json:"credential_backends" structs:"credential_backends" mapstructure:"credential_backends"`
 
 	AuditBackends map[string]audit.Factory `json:"audit_backends" structs:"audit_backends" mapstructure:"audit_backends"`
 
+	// Detectors of raw secrets in audited requests, or nil for the default
+	// ones
+	AuditSecretDetectors []audit.SecretDetector `json:"audit_secret_detectors" structs:"audit_secret_detectors" mapstructure:"audit_secret_detectors"`
+
+	// Refuses the requests in which a detector found a raw secret
+	AuditBlockSecrets bool `json:"audit_block_secrets" structs:"audit_block_secrets" mapstructure:"audit_block_secrets"`
+
 	Physical physical.Backend `json:"physical" structs:"physical" mapstructure:"physical"`
 
 	// May be nil, which disables HA operations
 	HAPhysical physical.HABackend `json:"ha_physical" structs:"ha_physical" mapstructure:"ha_physical"`
 
+	// HALockGracePeriod is how long the active node tries to re-acquire the
+	// HA lock after losing it, such as during a restart of Consul, before
+	// stepping down. Zero steps down immediately.
+	HALockGracePeriod time.Duration `json:"ha_lock_grace_period" structs:"ha_lock_grace_period" mapstructure:"ha_lock_grace_period"`
+
 	Seal Seal `json:"seal" structs:"seal" mapstructure:"seal"`
 
 	Logger log.Logger `json:"logger" structs:"logger" mapstructure:"logger"`
 
+	// Streams the entries of Logger to sys/monitor, or nil to disable it
+	LogMonitors *logformat.LogMonitors `json:"-" structs:"-" mapstructure:"-"`
+
 	// Disables the LRU cache on the physical backend
 	DisableCache bool `json:"disable_cache" structs:"disable_cache" mapstructure:"disable_cache"`
 
@@ -378,6 +533,38 @@ type CoreConfig struct {
 	// Custom cache size for the LRU cache on the physical backend, or zero for default
 	CacheSize int `json:"cache_size" structs:"cache_size" mapstructure:"cache_size"`
 
+	// Heap usage in bytes above which load is shed, or zero to disable
+	MemorySoftLimit uint64 `json:"memory_soft_limit" structs:"memory_soft_limit" mapstructure:"memory_soft_limit"`
+
+	// Heap usage in bytes above which only sys/ requests are served, or zero
+	// to disable
+	MemoryHardLimit uint64 `json:"memory_hard_limit" structs:"memory_hard_limit" mapstructure:"memory_hard_limit"`
+
+	// Number of requests a token can have in flight, or zero for no limit
+	MaxConcurrentRequestsPerToken int `json:"max_concurrent_requests_per_token" structs:"max_concurrent_requests_per_token" mapstructure:"max_concurrent_requests_per_token"`
+
+	// Number of requests a source IP can have in flight, or zero for no
+	// limit
+	MaxConcurrentRequestsPerIP int `json:"max_concurrent_requests_per_ip" structs:"max_concurrent_requests_per_ip" mapstructure:"max_concurrent_requests_per_ip"`
+
+	// Path of the file recording request summaries, or empty to disable
+	RequestJournalPath string `json:"request_journal_path" structs:"request_journal_path" mapstructure:"request_journal_path"`
+
+	// Number of requests kept in the request journal, or zero for default
+	RequestJournalEntries int `json:"request_journal_entries" structs:"request_journal_entries" mapstructure:"request_journal_entries"`
+
+	// Receives the spans of the requests, or nil to disable tracing
+	RequestTracer RequestTracer `json:"-" structs:"-" mapstructure:"-"`
+
+	// Number of approvals required to enable secret and credential
+	// backends, or zero to enable them right away
+	MountApprovals int `json:"mount_approvals" structs:"mount_approvals" mapstructure:"mount_approvals"`
+
+	// Login identities allowed to approve enabling backends. They are only
+	// taken from the configuration so that they cannot be created through
+	// the API.
+	MountApprovers []string `json:"mount_approvers" structs:"mount_approvers" mapstructure:"mount_approvers"`
+
 	// Set as the leader address for HA
 	RedirectAddr string `json:"redirect_addr" structs:"redirect_addr" mapstructure:"redirect_addr"`
 
@@ -394,6 +581,28 @@ type CoreConfig struct {
 
 	PluginDirectory string `json:"plugin_directory" structs:"plugin_directory" mapstructure:"plugin_directory"`
 
+	// Clock used for lease expiration, token TTLs and rollbacks, or nil for
+	// the system clock. Only meant to be set by tests.
+	Clock Clock `json:"-" structs:"-" mapstructure:"-"`
+
+	// Entropy used to generate the IDs of tokens, accessors and leases, or
+	// nil for crypto/rand. Only meant to be set by tests, to get the same
+	// IDs from run to run.
+	Entropy io.Reader `json:"-" structs:"-" mapstructure:"-"`
+
+	// Format of the IDs of new tokens, or empty for the versioned format;
+	// see the tokenformat package
+	TokenFormat string `json:"token_format" structs:"token_format" mapstructure:"token_format"`
+
+	// Leeway given to the expiration checks of leases and wrapping tokens
+	// and to the validity period checks of certificates, for nodes whose
+	// clocks drift
+	ClockSkewTolerance time.Duration `json:"clock_skew_tolerance" structs:"clock_skew_tolerance" mapstructure:"clock_skew_tolerance"`
+
+	// NTP server the clock is periodically compared to, reporting a
+	// warning in the health of the node when it drifts. Disabled if empty.
+	NTPServer string `json:"ntp_server" structs:"ntp_server" mapstructure:"ntp_server"`
+
 	ReloadFuncs     *map[string][]ReloadFunc
 	ReloadFuncsLock *sync.RWMutex
 }
@@ -415,6 +624,15 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 	if conf.DefaultLeaseTTL > conf.MaxLeaseTTL {
 		return nil, fmt.Errorf("cannot have DefaultLeaseTTL larger than MaxLeaseTTL")
 	}
+	if conf.MemoryHardLimit > 0 && conf.MemorySoftLimit > conf.MemoryHardLimit {
+		return nil, fmt.Errorf("cannot have MemorySoftLimit larger than MemoryHardLimit")
+	}
+	if !tokenformat.ValidFormat(conf.TokenFormat) {
+		return nil, fmt.Errorf("invalid token format %q", conf.TokenFormat)
+	}
+	if conf.ClockSkewTolerance < 0 {
+		return nil, fmt.Errorf("cannot have a negative ClockSkewTolerance")
+	}
 
 	// Validate the advertise addr if its given to us
 	if conf.RedirectAddr != "" {
@@ -436,6 +654,7 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 	// Setup the core
 	c := &Core{
 		devToken:                         conf.DevToken,
+		devTLSCACert:                     conf.DevTLSCACert,
 		physical:                         conf.Physical,
 		redirectAddr:                     conf.RedirectAddr,
 		clusterAddr:                      conf.ClusterAddr,
@@ -448,18 +667,53 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		maxLeaseTTL:                      conf.MaxLeaseTTL,
 		cachingDisabled:                  conf.DisableCache,
 		clusterName:                      conf.ClusterName,
+		haLockGracePeriod:                conf.HALockGracePeriod,
 		clusterListenerShutdownCh:        make(chan struct{}),
 		clusterListenerShutdownSuccessCh: make(chan struct{}),
 		clusterPeerClusterAddrsCache:     cache.New(3*heartbeatInterval, time.Second),
 		enableMlock:                      !conf.DisableMlock,
+		memoryWatchdog:                   newMemoryWatchdog(conf.MemorySoftLimit, conf.MemoryHardLimit, conf.Logger),
+		clientLimiter:                    newClientLimiter(conf.MaxConcurrentRequestsPerToken, conf.MaxConcurrentRequestsPerIP),
+		mountApprovals:                   conf.MountApprovals,
+		mountApprovers:                   conf.MountApprovers,
+		requestTracer:                    conf.RequestTracer,
+		logMonitors:                      conf.LogMonitors,
+		clock:                            conf.Clock,
+		entropy:                          conf.Entropy,
+		tokenFormat:                      conf.TokenFormat,
+		clockSkewTolerance:               conf.ClockSkewTolerance,
+		clockDrift:                       newClockDriftMonitor(conf.NTPServer, conf.ClockSkewTolerance, conf.Logger),
+		mfaUsedPasscodes:                 cache.New(time.Minute, time.Minute),
+		pluginSupervisor:                 newPluginSupervisor(),
+		stateEvents:                      newStateEvents(),
+	}
+
+	if c.clock == nil {
+		c.clock = systemClock{}
+	}
+	if c.entropy == nil {
+		c.entropy = rand.Reader
 	}
 
 	// Load CORS config and provide core
 	c.corsConfig = &CORSConfig{core: c}
 
-	// Wrap the physical backend in a cache layer if enabled and not already wrapped
+	c.updateStatusSnapshot()
+
+	if conf.RequestJournalPath != "" {
+		journal, err := newRequestJournal(conf.RequestJournalPath, conf.RequestJournalEntries, conf.Logger)
+		if err != nil {
+			return nil, err
+		}
+		c.requestJournal = journal
+	}
+
+	// Wrap the physical backend in the seal wrapping layer, below the cache
+	// so that only the reads missing the cache are unwrapped by the seal, and
+	// in a cache layer if enabled and not already wrapped
+	c.physical = newSealWrapBackend(conf.Physical, conf.Seal)
 	if _, isCache := conf.Physical.(*physical.Cache); !conf.DisableCache && !isCache {
-		c.physical = physical.NewCache(conf.Physical, conf.CacheSize, conf.Logger)
+		c.physical = physical.NewCache(c.physical, conf.CacheSize, conf.Logger)
 	}
 
 	if !conf.DisableMlock {
@@ -538,6 +792,13 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 	}
 	c.auditBackends = auditBackends
 
+	detectors := conf.AuditSecretDetectors
+	if detectors == nil {
+		detectors = audit.DefaultSecretDetectors()
+	}
+	c.auditSecretScanner = audit.NewSecretScanner(detectors...)
+	c.auditSecretScanner.Block = conf.AuditBlockSecrets
+
 	if c.seal == nil {
 		c.seal = &DefaultSeal{}
 	}
@@ -547,6 +808,8 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 	// returns nil, otherwise returns nil or an error
 	storedKeyErr := c.UnsealWithStoredKeys()
 
+	c.startClockDriftMonitor()
+
 	return c, storedKeyErr
 }
 
@@ -557,6 +820,15 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 func (c *Core) Shutdown() error {
 	c.stateLock.Lock()
 	defer c.stateLock.Unlock()
+
+	c.stopClockDriftMonitor()
+
+	// No requests are in flight while the state lock is held, so the
+	// journal can be flushed and closed
+	if err := c.requestJournal.close(); err != nil {
+		c.logger.Error("core: failed to close request journal", "error", err)
+	}
+
 	if c.sealed {
 		return nil
 	}
@@ -621,6 +893,18 @@ func (c *Core) fetchACLandTokenEntry(req *logical.Request) (*ACL, *TokenEntry, e
 		return nil, nil, logical.ErrPermissionDenied
 	}
 
+	// Ensure the token is used from the addresses it is bound to
+	if len(te.BoundCIDRs) > 0 {
+		var remoteAddr string
+		if req.Connection != nil {
+			remoteAddr = req.Connection.RemoteAddr
+		}
+		if ok, _ := cidrutil.IPBelongsToCIDRBlocksSlice(remoteAddr, te.BoundCIDRs); !ok {
+			c.logger.Warn("core: token used outside of its bound CIDR blocks", "remote_address", remoteAddr, "path", req.Path)
+			return nil, nil, logical.ErrPermissionDenied
+		}
+	}
+
 	// Construct the corresponding ACL object
 	acl, err := c.policyStore.ACL(te.Policies...)
 	if err != nil {
@@ -838,6 +1122,7 @@ func (c *Core) ResetUnsealProcess() {
 		return
 	}
 	c.unlockInfo = nil
+	c.updateStatusSnapshot()
 }
 
 // Unseal is used to provide one of the key parts to unseal the Vault.
@@ -876,6 +1161,7 @@ func (c *Core) Unseal(key []byte) (bool, error) {
 	if !c.sealed {
 		return true, nil
 	}
+	defer c.updateStatusSnapshot()
 
 	masterKey, err := c.unsealPart(config, key)
 	if err != nil {
@@ -945,8 +1231,12 @@ func (c *Core) unsealPart(config *SealConfig, key []byte) ([]byte, error) {
 func (c *Core) unsealInternal(masterKey []byte) (bool, error) {
 	defer memzero(masterKey)
 
+	c.unsealStatus.start()
+	c.unsealStatus.startPhase(UnsealPhaseKeyring)
+
 	// Attempt to unlock
 	if err := c.barrier.Unseal(masterKey); err != nil {
+		c.unsealStatus.finish(err)
 		return false, err
 	}
 	if c.logger.IsInfo() {
@@ -958,6 +1248,7 @@ func (c *Core) unsealInternal(masterKey []byte) (bool, error) {
 		// We still need to set up cluster info even if it's not part of a
 		// cluster right now. This also populates the cached cluster object.
 		if err := c.setupCluster(); err != nil {
+			c.unsealStatus.finish(err)
 			c.logger.Error("core: cluster setup failed", "error", err)
 			c.barrier.Seal()
 			c.logger.Warn("core: vault is sealed")
@@ -978,6 +1269,9 @@ func (c *Core) unsealInternal(masterKey []byte) (bool, error) {
 		c.standbyStopCh = make(chan struct{})
 		c.manualStepDownCh = make(chan struct{})
 		go c.runStandby(c.standbyDoneCh, c.standbyStopCh, c.manualStepDownCh)
+
+		// The remaining phases are tracked once this node becomes active
+		c.unsealStatus.finish(nil)
 	}
 
 	// Success!
@@ -1211,6 +1505,7 @@ func (c *Core) StepDown(req *logical.Request) (retErr error) {
 func (c *Core) sealInternal() error {
 	// Enable that we are sealed to prevent furthur transactions
 	c.sealed = true
+	c.updateStatusSnapshot()
 
 	c.logger.Debug("core: marked as sealed")
 
@@ -1223,6 +1518,7 @@ func (c *Core) sealInternal() error {
 	if c.ha == nil {
 		// Even in a non-HA context we key off of this for some things
 		c.standby = true
+		c.updateStatusSnapshot()
 		if err := c.preSeal(); err != nil {
 			c.logger.Error("core: pre-seal teardown failed", "error", err)
 			return fmt.Errorf("internal error")
@@ -1270,6 +1566,13 @@ func (c *Core) postUnseal() (retErr error) {
 			c.preSeal()
 		}
 	}()
+
+	// The phases are only tracked when unsealing through unsealInternal, as
+	// becoming the active node or initializing does not seal the core
+	defer func() {
+		c.unsealStatus.finish(retErr)
+	}()
+
 	c.logger.Info("core: post-unseal setup starting")
 
 	// Clear forwarding clients; we're active
@@ -1294,6 +1597,7 @@ func (c *Core) postUnseal() (retErr error) {
 	if err := c.ensureWrappingKey(); err != nil {
 		return err
 	}
+	c.unsealStatus.startPhase(UnsealPhaseMounts)
 	if err := c.loadMounts(); err != nil {
 		return err
 	}
@@ -1303,21 +1607,37 @@ func (c *Core) postUnseal() (retErr error) {
 	if err := c.startRollback(); err != nil {
 		return err
 	}
+	c.unsealStatus.startPhase(UnsealPhasePolicies)
 	if err := c.setupPolicyStore(); err != nil {
 		return err
 	}
 	if err := c.loadCORSConfig(); err != nil {
 		return err
 	}
+	if err := c.loadTokenLimitsConfig(); err != nil {
+		return err
+	}
+	if err := c.loadMFAConfig(); err != nil {
+		return err
+	}
+	if err := c.loadCanaryConfig(); err != nil {
+		return err
+	}
+	if err := c.loadExperimentsConfig(); err != nil {
+		return err
+	}
+	c.unsealStatus.startPhase(UnsealPhaseCredentials)
 	if err := c.loadCredentials(); err != nil {
 		return err
 	}
 	if err := c.setupCredentials(); err != nil {
 		return err
 	}
+	c.unsealStatus.startPhase(UnsealPhaseExpiration)
 	if err := c.setupExpiration(); err != nil {
 		return err
 	}
+	c.unsealStatus.startPhase(UnsealPhaseAudit)
 	if err := c.loadAudits(); err != nil {
 		return err
 	}
@@ -1327,6 +1647,7 @@ func (c *Core) postUnseal() (retErr error) {
 	if err := c.setupAuditedHeadersConfig(); err != nil {
 		return err
 	}
+	c.unsealStatus.startPhase(UnsealPhasePlugins)
 	if err := c.setupPluginCatalog(); err != nil {
 		return err
 	}
@@ -1336,8 +1657,12 @@ func (c *Core) postUnseal() (retErr error) {
 			return err
 		}
 	}
+	if err := c.loadBarrierRewrap(); err != nil {
+		return err
+	}
 	c.metricsCh = make(chan struct{})
 	go c.emitMetrics(c.metricsCh)
+	c.startMemoryWatchdog()
 	c.logger.Info("core: post-unseal setup complete")
 	return nil
 }
@@ -1353,14 +1678,21 @@ func (c *Core) preSeal() error {
 	c.barrierRekeyProgress = nil
 	c.recoveryRekeyConfig = nil
 	c.recoveryRekeyProgress = nil
+	c.recoveryRekeyVerification = nil
 
 	if c.metricsCh != nil {
 		close(c.metricsCh)
 		c.metricsCh = nil
 	}
+	c.countersLock.Lock()
+	c.counters = nil
+	c.countersLock.Unlock()
+	c.stopBarrierRewrap()
+	c.stopMemoryWatchdog()
 	var result error
 
 	c.stopClusterListener()
+	c.pluginSupervisor.reset()
 
 	if err := c.teardownAudits(); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error tearing down audits: {{err}}", err))
@@ -1424,11 +1756,16 @@ func (c *Core) runStandby(doneCh, stopCh, manualStepDownCh chan struct{}) {
 	checkLeaderDone := make(chan struct{})
 	checkLeaderStop := make(chan struct{})
 	go c.periodicLeaderRefresh(checkLeaderDone, checkLeaderStop)
+	// Monitor the memory usage; once active, postUnseal takes over
+	var stopMemoryWatchdog func()
 	defer func() {
 		close(keyRotateStop)
 		<-keyRotateDone
 		close(checkLeaderStop)
 		<-checkLeaderDone
+		if stopMemoryWatchdog != nil {
+			stopMemoryWatchdog()
+		}
 	}()
 
 	for {
@@ -1439,6 +1776,10 @@ func (c *Core) runStandby(doneCh, stopCh, manualStepDownCh chan struct{}) {
 		default:
 		}
 
+		if stopMemoryWatchdog == nil {
+			stopMemoryWatchdog = c.startStandbyMemoryWatchdog()
+		}
+
 		// Create a lock
 		uuid, err := uuid.GenerateUUID()
 		if err != nil {
@@ -1459,6 +1800,12 @@ func (c *Core) runStandby(doneCh, stopCh, manualStepDownCh chan struct{}) {
 			return
 		}
 		c.logger.Info("core: acquired lock, enabling active operation")
+		stopMemoryWatchdog()
+		stopMemoryWatchdog = nil
+
+		// The fencing token of the acquisition allows re-acquiring the lock
+		// if it is lost
+		fencingToken, fenced := c.lockFencingToken(lock)
 
 		// This is used later to log a metrics event; this can be helpful to
 		// detect flapping
@@ -1520,6 +1867,7 @@ func (c *Core) runStandby(doneCh, stopCh, manualStepDownCh chan struct{}) {
 		err = c.postUnseal()
 		if err == nil {
 			c.standby = false
+			c.updateStatusSnapshot()
 		}
 		c.stateLock.Unlock()
 
@@ -1533,14 +1881,24 @@ func (c *Core) runStandby(doneCh, stopCh, manualStepDownCh chan struct{}) {
 
 		// Monitor a loss of leadership
 		var manualStepDown bool
-		select {
-		case <-leaderLostCh:
-			c.logger.Warn("core: leadership lost, stopping active operation")
-		case <-stopCh:
-			c.logger.Warn("core: stopping active operation")
-		case <-manualStepDownCh:
-			c.logger.Warn("core: stepping down from active operation to standby")
-			manualStepDown = true
+		for {
+			select {
+			case <-leaderLostCh:
+				if fenced {
+					newLostCh, newToken := c.reacquireLock(lock, fencingToken, stopCh)
+					if newLostCh != nil {
+						leaderLostCh, fencingToken = newLostCh, newToken
+						continue
+					}
+				}
+				c.logger.Warn("core: leadership lost, stopping active operation")
+			case <-stopCh:
+				c.logger.Warn("core: stopping active operation")
+			case <-manualStepDownCh:
+				c.logger.Warn("core: stepping down from active operation to standby")
+				manualStepDown = true
+			}
+			break
 		}
 
 		metrics.MeasureSince([]string{"core", "leadership_lost"}, activeTime)
@@ -1553,6 +1911,8 @@ func (c *Core) runStandby(doneCh, stopCh, manualStepDownCh chan struct{}) {
 		// Attempt the pre-seal process
 		c.stateLock.Lock()
 		c.standby = true
+		c.updateStatusSnapshot()
+		atomic.StoreUint32(&c.leadershipSuspended, 0)
 		preSealErr := c.preSeal()
 		c.stateLock.Unlock()
 
@@ -1578,6 +1938,11 @@ func (c *Core) runStandby(doneCh, stopCh, manualStepDownCh chan struct{}) {
 // the result.
 func (c *Core) periodicLeaderRefresh(doneCh, stopCh chan struct{}) {
 	defer close(doneCh)
+
+	// Connect to the active node right away rather than after the first
+	// interval
+	c.Leader()
+
 	for {
 		select {
 		case <-time.After(leaderCheckInterval):
@@ -1645,8 +2010,8 @@ func (c *Core) checkKeyUpgrades() error {
 // scheduleUpgradeCleanup is used to ensure that all the upgrade paths
 // are cleaned up in a timely manner if a leader failover takes place
 func (c *Core) scheduleUpgradeCleanup() error {
-	// List the upgrades
-	upgrades, err := c.barrier.List(keyringUpgradePrefix)
+	// Check for upgrades, without listing them all
+	upgrades, err := c.barrier.ListPage(keyringUpgradePrefix, "", 1)
 	if err != nil {
 		return fmt.Errorf("failed to list upgrades: %v", err)
 	}
@@ -1656,7 +2021,16 @@ func (c *Core) scheduleUpgradeCleanup() error {
 		return nil
 	}
 
-	// Schedule cleanup for all of them
+	// The upgrades present now are those to the terms up to the active one,
+	// stored under the previous term. Those of later rotations are left for
+	// their own cleanup.
+	info, err := c.barrier.ActiveKeyInfo()
+	if err != nil {
+		return fmt.Errorf("failed to read active key: %v", err)
+	}
+	activeTerm := uint64(info.Term)
+
+	// Schedule cleanup for all of them, listing them a page at a time
 	time.AfterFunc(keyRotateGracePeriod, func() {
 		sealed, err := c.barrier.Sealed()
 		if err != nil {
@@ -1667,11 +2041,20 @@ func (c *Core) scheduleUpgradeCleanup() error {
 			c.logger.Warn("core: barrier sealed at upgrade cleanup time")
 			return
 		}
-		for _, upgrade := range upgrades {
-			path := fmt.Sprintf("%s%s", keyringUpgradePrefix, upgrade)
-			if err := c.barrier.Delete(path); err != nil {
-				c.logger.Error("core: failed to cleanup upgrade", "path", path, "error", err)
+		err = listPages(c.barrier, keyringUpgradePrefix, func(upgrades []string) error {
+			for _, upgrade := range upgrades {
+				if term, err := strconv.ParseUint(upgrade, 10, 32); err != nil || term >= activeTerm {
+					continue
+				}
+				path := fmt.Sprintf("%s%s", keyringUpgradePrefix, upgrade)
+				if err := c.barrier.Delete(path); err != nil {
+					c.logger.Error("core: failed to cleanup upgrade", "path", path, "error", err)
+				}
 			}
+			return nil
+		})
+		if err != nil {
+			c.logger.Error("core: failed to list upgrades", "error", err)
 		}
 	})
 	return nil
@@ -1716,6 +2099,101 @@ func (c *Core) acquireLock(lock physical.Lock, stopCh <-chan struct{}) <-chan st
 	}
 }
 
+// lockFencingToken returns the fencing token of the current acquisition of
+// the lock, if the lock supports fencing and a grace period is configured to
+// re-acquire it
+func (c *Core) lockFencingToken(lock physical.Lock) (uint64, bool) {
+	if c.haLockGracePeriod <= 0 {
+		return 0, false
+	}
+	fenced, ok := lock.(physical.FencedLock)
+	if !ok {
+		return 0, false
+	}
+	token, err := fenced.FencingToken()
+	if err != nil {
+		c.logger.Error("core: failed to read lock fencing token, lock will not be re-acquired if lost", "error", err)
+		return 0, false
+	}
+	return token, true
+}
+
+// reacquireLock tries to acquire the lock again for up to the grace period
+// after it was lost, and returns the new leaderLostCh and fencing token.
+// Requests are rejected meanwhile so that none is served while another node
+// may be active. The lock is only kept if its fencing token shows that no
+// other node acquired it in between; otherwise it is released and nil is
+// returned, and the node must step down, which lifts the suspension.
+func (c *Core) reacquireLock(lock physical.Lock, token uint64, stopCh <-chan struct{}) (<-chan struct{}, uint64) {
+	c.logger.Warn("core: leadership lock lost, attempting to re-acquire it", "grace_period", c.haLockGracePeriod)
+	defer metrics.MeasureSince([]string{"core", "leadership_reacquire"}, time.Now())
+
+	atomic.StoreUint32(&c.leadershipSuspended, 1)
+
+	// Give up at the end of the grace period or on shutdown
+	lockStopCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	go func() {
+		defer close(lockStopCh)
+		select {
+		case <-time.After(c.haLockGracePeriod):
+		case <-stopCh:
+		case <-doneCh:
+		}
+	}()
+
+	// The lost lock may still be considered held by the backend
+	lock.Unlock()
+
+	var leaderLostCh <-chan struct{}
+	for leaderLostCh == nil {
+		var err error
+		leaderLostCh, err = lock.Lock(lockStopCh)
+		if err == nil && leaderLostCh == nil {
+			c.logger.Warn("core: failed to re-acquire lock within the grace period")
+			return nil, 0
+		}
+		if err != nil {
+			c.logger.Error("core: failed to re-acquire lock", "error", err)
+			select {
+			case <-time.After(lockRetryInterval):
+			case <-lockStopCh:
+				c.logger.Warn("core: failed to re-acquire lock within the grace period")
+				return nil, 0
+			}
+		}
+	}
+
+	newToken, err := lock.(physical.FencedLock).FencingToken()
+	if err != nil {
+		c.logger.Error("core: failed to read lock fencing token", "error", err)
+		lock.Unlock()
+		return nil, 0
+	}
+	if newToken != token+1 {
+		c.logger.Warn("core: lock was acquired by another node in the meantime", "fencing_token", token, "new_fencing_token", newToken)
+		lock.Unlock()
+		return nil, 0
+	}
+
+	// Resume under the state lock, unless the node started sealing or
+	// shutting down meanwhile
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+	select {
+	case <-stopCh:
+		lock.Unlock()
+		return nil, 0
+	default:
+	}
+	atomic.StoreUint32(&c.leadershipSuspended, 0)
+
+	c.logger.Info("core: re-acquired lock, resuming active operation")
+	metrics.IncrCounter([]string{"core", "leadership_reacquired"}, 1)
+	return leaderLostCh, newToken
+}
+
 // advertiseLeader is used to advertise the current node as leader
 func (c *Core) advertiseLeader(uuid string, leaderLostCh <-chan struct{}) error {
 	go c.cleanLeaderPrefix(uuid, leaderLostCh)
@@ -1805,20 +2283,42 @@ func (c *Core) clearLeader(uuid string) error {
 
 // emitMetrics is used to periodically expose metrics while runnig
 func (c *Core) emitMetrics(stopCh chan struct{}) {
+	countersTicker := time.NewTicker(countersInterval)
+	defer countersTicker.Stop()
+
 	for {
 		select {
+		case <-countersTicker.C:
+			c.emitCounters()
 		case <-time.After(time.Second):
 			c.metricsMutex.Lock()
 			if c.expiration != nil {
 				c.expiration.emitMetrics()
 			}
 			c.metricsMutex.Unlock()
+
+			// Gauges are only reported by the sinks when set, so the status
+			// is refreshed along with the other gauges
+			snapshot := c.StatusSnapshot()
+			emitStatusMetrics(&snapshot)
 		case <-stopCh:
 			return
 		}
 	}
 }
 
+// emitCounters collects and publishes the counters of the core
+func (c *Core) emitCounters() {
+	c.metricsMutex.Lock()
+	defer c.metricsMutex.Unlock()
+	if c.expiration == nil || c.tokenStore == nil {
+		return
+	}
+	if _, err := c.collectCounters(); err != nil {
+		c.logger.Error("core: failed to collect counters", "error", err)
+	}
+}
+
 func (c *Core) ReplicationState() consts.ReplicationState {
 	var state consts.ReplicationState
 	c.clusterParamsLock.RLock()
@@ -1847,6 +2347,12 @@ func (c *Core) AuditedHeadersConfig() *AuditedHeadersConfig {
 	return c.auditedHeaders
 }
 
+// AuditSecretScanner returns the scanner looking for raw secrets in the
+// audited requests, on which detectors can be registered
+func (c *Core) AuditSecretScanner() *audit.SecretScanner {
+	return c.auditSecretScanner
+}
+
 func lastRemoteWALImpl(c *Core) uint64 {
 	return 0
 }
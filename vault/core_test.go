@@ -1,7 +1,9 @@
 package vault
 
 import (
+	mathrand "math/rand"
 	"reflect"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -133,6 +135,44 @@ func TestCore_Unseal_MultiShare(t *testing.T) {
 	}
 }
 
+func TestCore_Unseal_StorageFailure(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	inm := physical.NewInmemFaulty(logger)
+	conf := testCoreConfig(t, inm, logger)
+	conf.DisableCache = true
+	c, err := NewCore(conf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	keys, _ := TestCoreInit(t, c)
+
+	// Failing to load the mount table leaves the core sealed
+	inm.Fail(physical.GetOperation, coreMountConfigPath, nil)
+	for _, key := range keys {
+		_, err = TestCoreUnseal(c, TestKeyCopy(key))
+	}
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if sealed, _ := c.Sealed(); !sealed {
+		t.Fatal("should be sealed")
+	}
+
+	// Unsealing again once the storage recovered succeeds
+	inm.ClearFaults()
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+	if sealed, _ := c.Sealed(); sealed {
+		t.Fatal("should not be sealed")
+	}
+	if match := c.router.MatchingMount("secret/foo"); match != "secret/" {
+		t.Fatalf("missing mount: %q", match)
+	}
+}
+
 func TestCore_Unseal_Single(t *testing.T) {
 	c := TestCore(t)
 
@@ -597,6 +637,140 @@ func TestCore_HandleRequest_NoClientToken(t *testing.T) {
 	}
 }
 
+func TestCore_HandleRequest_PassthroughRequestHeaders(t *testing.T) {
+	noop := &NoopBackend{
+		Response: &logical.Response{},
+	}
+	c, _, root := TestCoreUnsealed(t)
+	c.logicalBackends["noop"] = func(*logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/foo")
+	req.Data["type"] = "noop"
+	req.Data["config"] = map[string]interface{}{
+		"passthrough_request_headers": []interface{}{"x-custom"},
+	}
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	headers := map[string][]string{
+		"X-Custom":      []string{"foo"},
+		"X-Other":       []string{"bar"},
+		"X-Vault-Token": []string{root},
+	}
+	req = &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "foo/test",
+		ClientToken: root,
+		Headers:     headers,
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := map[string][]string{
+		"X-Custom": []string{"foo"},
+	}
+	if actual := noop.Requests[0].Headers; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Tuning the headers applies to the next requests
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/foo/tune")
+	req.Data["passthrough_request_headers"] = ""
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "foo/test",
+		ClientToken: root,
+		Headers:     headers,
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if actual := noop.Requests[1].Headers; actual != nil {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// The token header is never passed through
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/foo/tune")
+	req.Data["passthrough_request_headers"] = "x-vault-token"
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error, got resp: %#v, err: %v", resp, err)
+	}
+}
+
+func TestCore_HandleRequest_AuditNonHMACKeys(t *testing.T) {
+	noop := &NoopAudit{}
+	c, _, root := TestCoreUnsealed(t)
+	c.auditBackends["noop"] = func(config *audit.BackendConfig) (audit.Backend, error) {
+		noop = &NoopAudit{
+			Config: config,
+		}
+		return noop, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/audit/noop")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/secret/tune")
+	req.Data["audit_non_hmac_request_keys"] = "foo, bar,foo"
+	req.Data["audit_non_hmac_response_keys"] = "baz"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/mounts/secret/tune")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Data["audit_non_hmac_request_keys"], []string{"foo", "bar"}) ||
+		!reflect.DeepEqual(resp.Data["audit_non_hmac_response_keys"], []string{"baz"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The keys of the mount are given to the audit backends
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "secret/test",
+		Data: map[string]interface{}{
+			"foo": "bar",
+		},
+		ClientToken: root,
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	last := noop.Req[len(noop.Req)-1]
+	if !reflect.DeepEqual(last.AuditNonHMACReqDataKeys, []string{"foo", "bar"}) ||
+		!reflect.DeepEqual(last.AuditNonHMACRespDataKeys, []string{"baz"}) {
+		t.Fatalf("bad: %#v", last)
+	}
+
+	// Other mounts are not affected
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/mounts/secret/tune")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if last := noop.Req[len(noop.Req)-1]; last.AuditNonHMACReqDataKeys != nil {
+		t.Fatalf("bad: %#v", last)
+	}
+}
+
 func TestCore_HandleRequest_ConnOnLogin(t *testing.T) {
 	noop := &NoopBackend{
 		Login:    []string{"login"},
@@ -689,6 +863,7 @@ func TestCore_HandleLogin_Token(t *testing.T) {
 		DisplayName:  "foo-armon",
 		TTL:          time.Hour * 24,
 		CreationTime: te.CreationTime,
+		Entity:       "foo/armon",
 	}
 
 	if !reflect.DeepEqual(te, expect) {
@@ -765,6 +940,57 @@ func TestCore_HandleRequest_AuditTrail(t *testing.T) {
 	}
 }
 
+func TestCore_HandleRequest_AuditSecrets(t *testing.T) {
+	noop := &NoopAudit{}
+	c, _, root := TestCoreUnsealed(t)
+	c.auditBackends["noop"] = func(config *audit.BackendConfig) (audit.Backend, error) {
+		noop = &NoopAudit{
+			Config: config,
+		}
+		return noop, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/audit/noop")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	c.AuditSecretScanner().Block = true
+
+	// A request with the token of the client in its data is refused
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "secret/test",
+		Data: map[string]interface{}{
+			"foo": root,
+		},
+		ClientToken: root,
+	}
+	resp, err := c.HandleRequest(req)
+	if err == nil || !errwrap.Contains(err, logical.ErrInvalidRequest.Error()) || !resp.IsError() {
+		t.Fatalf("bad: %#v %v", resp, err)
+	}
+	if len(noop.ReqErrs) != 1 || noop.ReqErrs[0] == nil {
+		t.Fatalf("bad: %#v", noop.ReqErrs)
+	}
+
+	// The request was refused before reaching the backend
+	resp, err = c.HandleRequest(&logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "secret/test",
+		ClientToken: root,
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("bad: %#v %v", resp, err)
+	}
+
+	req.Data["foo"] = "bar"
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
 // Ensure we get a client token
 func TestCore_HandleLogin_AuditTrail(t *testing.T) {
 	// Create a badass credential backend that always logs in as armon
@@ -1448,6 +1674,90 @@ func TestCore_Standby_SeparateHA(t *testing.T) {
 	testCore_Standby_Common(t, physical.NewInmemHA(logger), physical.NewInmemHA(logger))
 }
 
+func TestCore_HALockGracePeriod(t *testing.T) {
+	logger = logformat.NewVaultLogger(log.LevelTrace)
+
+	inmha := physical.NewInmemHA(logger)
+	core, err := NewCore(&CoreConfig{
+		Physical:          inmha,
+		HAPhysical:        inmha,
+		RedirectAddr:      "http://127.0.0.1:8200",
+		HALockGracePeriod: 5 * time.Second,
+		DisableMlock:      true,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer core.Shutdown()
+	keys, root := TestCoreInit(t, core)
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(core, TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+	TestWaitActive(t, core)
+
+	// lockHolder waits for the lock to be held and returns the UUID of its
+	// holder, which changes each time the core becomes active
+	lockHolder := func() string {
+		lock, _ := inmha.LockWith(coreLockPath, "")
+		for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+			held, value, _ := lock.Value()
+			if standby, _ := core.Standby(); held && !standby {
+				return value
+			}
+		}
+		t.Fatal("core did not become active")
+		return ""
+	}
+	holder := lockHolder()
+
+	// The lock is re-acquired without stepping down when nobody held it
+	// meanwhile
+	inmha.InvalidateLock(coreLockPath)
+	if newHolder := lockHolder(); newHolder != holder {
+		t.Fatalf("core stepped down: %s != %s", newHolder, holder)
+	}
+
+	// The core steps down when another node held the lock meanwhile
+	other := inmha.TakeOverLock(coreLockPath, "other")
+	for start := time.Now(); atomic.LoadUint32(&core.leadershipSuspended) == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			other.Unlock()
+			t.Fatal("core did not try to re-acquire the lock")
+		}
+	}
+
+	// While it tries to, requests are rejected without holding the state
+	// lock
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = root
+	if _, err := core.HandleRequest(req); err != consts.ErrLeadershipSuspended {
+		other.Unlock()
+		t.Fatalf("expected leadership suspended error, got %v", err)
+	}
+	locked := make(chan struct{})
+	go func() {
+		core.stateLock.Lock()
+		core.stateLock.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		other.Unlock()
+		t.Fatal("state lock held while re-acquiring the lock")
+	}
+
+	other.Unlock()
+	if newHolder := lockHolder(); newHolder == holder {
+		t.Fatal("core did not step down")
+	}
+	if _, err := core.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
 func testCore_Standby_Common(t *testing.T, inm physical.Backend, inmha physical.HABackend) {
 	// Create the first core and initialize it
 	redirectOriginal := "http://127.0.0.1:8200"
@@ -2090,3 +2400,43 @@ func TestCore_Standby_Rotate(t *testing.T) {
 		t.Fatalf("bad: %#v", resp)
 	}
 }
+
+func TestCore_Entropy(t *testing.T) {
+	// tokens returns the root token and the ID and accessor of a child token
+	// of a core whose entropy is seeded with the given seed
+	tokens := func(seed int64) []string {
+		logger := logformat.NewVaultLogger(log.LevelTrace)
+		conf := testCoreConfig(t, physical.NewInmem(logger), logger)
+		conf.Entropy = mathrand.New(mathrand.NewSource(seed))
+		core, err := NewCore(conf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		keys, root := TestCoreInit(t, core)
+		for _, key := range keys {
+			if _, err := TestCoreUnseal(core, TestKeyCopy(key)); err != nil {
+				t.Fatalf("unseal err: %s", err)
+			}
+		}
+
+		resp, err := core.HandleRequest(&logical.Request{
+			Operation:   logical.UpdateOperation,
+			Path:        "auth/token/create",
+			ClientToken: root,
+		})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return []string{root, resp.Auth.ClientToken, resp.Auth.Accessor}
+	}
+
+	first, second, other := tokens(1), tokens(1), tokens(2)
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("expected the same tokens, got %v and %v", first, second)
+	}
+	for i := range first {
+		if first[i] == other[i] {
+			t.Fatalf("expected different tokens, got %v and %v", first, other)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package vault
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"sync"
 	"sync/atomic"
 
@@ -15,12 +16,26 @@ const (
 	CORSEnabled
 )
 
+// StdAllowedHeaders are the headers cross-origin requests can always set,
+// those the Vault API and its clients use
+var StdAllowedHeaders = []string{
+	"Content-Type",
+	"X-Requested-With",
+	"X-Vault-Aws-Iam-Server-Id",
+	"X-Vault-Mfa",
+	"X-Vault-No-Request-Forwarding",
+	"X-Vault-Token",
+	"X-Vault-Wrap-Format",
+	"X-Vault-Wrap-Ttl",
+}
+
 // CORSConfig stores the state of the CORS configuration.
 type CORSConfig struct {
 	sync.RWMutex   `json:"-"`
 	core           *Core
 	Enabled        uint32   `json:"enabled"`
 	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+	AllowedHeaders []string `json:"allowed_headers,omitempty"`
 }
 
 func (c *Core) saveCORSConfig() error {
@@ -31,6 +46,7 @@ func (c *Core) saveCORSConfig() error {
 	}
 	c.corsConfig.RLock()
 	localConfig.AllowedOrigins = c.corsConfig.AllowedOrigins
+	localConfig.AllowedHeaders = c.corsConfig.AllowedHeaders
 	c.corsConfig.RUnlock()
 
 	entry, err := logical.StorageEntryJSON("cors", localConfig)
@@ -71,8 +87,9 @@ func (c *Core) loadCORSConfig() error {
 }
 
 // Enable takes either a '*' or a comma-seprated list of URLs that can make
-// cross-origin requests to Vault.
-func (c *CORSConfig) Enable(urls []string) error {
+// cross-origin requests to Vault, and the headers these requests can set in
+// addition to StdAllowedHeaders.
+func (c *CORSConfig) Enable(urls []string, headers []string) error {
 	if len(urls) == 0 {
 		return errors.New("the list of allowed origins cannot be empty")
 	}
@@ -81,8 +98,20 @@ func (c *CORSConfig) Enable(urls []string) error {
 		return errors.New("to allow all origins the '*' must be the only value for allowed_origins")
 	}
 
+	allowedHeaders := append([]string(nil), StdAllowedHeaders...)
+	for _, header := range headers {
+		if header == "" {
+			continue
+		}
+		if header == "*" {
+			return errors.New("allowed_headers must list the headers, '*' is not supported")
+		}
+		allowedHeaders = strutil.AppendIfMissing(allowedHeaders, http.CanonicalHeaderKey(header))
+	}
+
 	c.Lock()
 	c.AllowedOrigins = urls
+	c.AllowedHeaders = allowedHeaders
 	c.Unlock()
 
 	atomic.StoreUint32(&c.Enabled, CORSEnabled)
@@ -100,6 +129,7 @@ func (c *CORSConfig) Disable() error {
 	atomic.StoreUint32(&c.Enabled, CORSDisabled)
 	c.Lock()
 	c.AllowedOrigins = []string(nil)
+	c.AllowedHeaders = []string(nil)
 	c.Unlock()
 	return c.core.saveCORSConfig()
 }
@@ -125,3 +155,21 @@ func (c *CORSConfig) IsValidOrigin(origin string) bool {
 
 	return strutil.StrListContains(c.AllowedOrigins, origin)
 }
+
+// AllowedHeadersList returns the headers cross-origin requests can set. It
+// is StdAllowedHeaders for configurations saved before headers could be
+// configured.
+func (c *CORSConfig) AllowedHeadersList() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if len(c.AllowedHeaders) == 0 {
+		return StdAllowedHeaders
+	}
+	return c.AllowedHeaders
+}
+
+// IsValidHeader determines if cross-origin requests can set the header
+func (c *CORSConfig) IsValidHeader(header string) bool {
+	return strutil.StrListContains(c.AllowedHeadersList(), http.CanonicalHeaderKey(header))
+}
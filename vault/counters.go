@@ -0,0 +1,132 @@
+package vault
+
+import (
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+)
+
+// countersInterval is how often the counters are collected and published
+var countersInterval = time.Minute
+
+// Counters are the number of tokens, leases and entities held by the core,
+// for capacity planning. Tokens are counted by their lease, so tokens
+// without a TTL, such as root tokens, are not included.
+type Counters struct {
+	// CollectedAt is the time the counters were collected
+	CollectedAt time.Time
+
+	// TokensByAuthMount is the number of tokens issued by each auth mount,
+	// such as "auth/userpass/"
+	TokensByAuthMount map[string]int
+
+	// LeasesBySecretMount is the number of leases of each secret mount
+	LeasesBySecretMount map[string]int
+
+	// Entities is the number of entities tokens count against for the
+	// token limits
+	Entities int
+}
+
+// TotalTokens returns the number of tokens of all auth mounts
+func (c *Counters) TotalTokens() int {
+	return sumCounts(c.TokensByAuthMount)
+}
+
+// TotalLeases returns the number of leases of all secret mounts
+func (c *Counters) TotalLeases() int {
+	return sumCounts(c.LeasesBySecretMount)
+}
+
+func sumCounts(counts map[string]int) int {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
+
+// Counters returns the counters last collected, collecting them first if
+// they have not been yet
+func (c *Core) Counters() (*Counters, error) {
+	c.countersLock.RLock()
+	counters := c.counters
+	c.countersLock.RUnlock()
+	if counters != nil {
+		return counters, nil
+	}
+	return c.collectCounters()
+}
+
+// collectCounters counts the tokens, leases and entities, and publishes
+// them as gauges
+func (c *Core) collectCounters() (*Counters, error) {
+	counters := &Counters{
+		CollectedAt:         time.Now(),
+		TokensByAuthMount:   make(map[string]int),
+		LeasesBySecretMount: make(map[string]int),
+	}
+
+	// Token leases are registered under the login path of their token, and
+	// other leases under the path of their secret. Leases of mounts being
+	// removed are not counted.
+	for _, leaseID := range c.expiration.leaseIDs() {
+		mount := c.router.MatchingMount(leaseID)
+		if mount == "" {
+			continue
+		}
+		if strings.HasPrefix(leaseID, credentialRoutePrefix) {
+			counters.TokensByAuthMount[mount]++
+		} else {
+			counters.LeasesBySecretMount[mount]++
+		}
+	}
+
+	entities, err := c.tokenStore.view.List(entityPrefix)
+	if err != nil {
+		return nil, err
+	}
+	counters.Entities = len(entities)
+
+	c.countersLock.Lock()
+	previous := c.counters
+	c.counters = counters
+	c.countersLock.Unlock()
+
+	publishCounters(previous, counters)
+	return counters, nil
+}
+
+// publishCounters publishes the counters as gauges. Mounts that no longer
+// hold any token or lease since the previous counters are reset to zero,
+// as sinks keep reporting the last value of a gauge.
+func publishCounters(previous, counters *Counters) {
+	emit := func(key []string, previous, counts map[string]int) {
+		for mount := range previous {
+			if _, ok := counts[mount]; !ok {
+				metrics.SetGauge(append(key, metricsMountName(mount)), 0)
+			}
+		}
+		for mount, count := range counts {
+			metrics.SetGauge(append(key, metricsMountName(mount)), float32(count))
+		}
+	}
+
+	var previousTokens, previousLeases map[string]int
+	if previous != nil {
+		previousTokens = previous.TokensByAuthMount
+		previousLeases = previous.LeasesBySecretMount
+	}
+	emit([]string{"token", "count", "by_auth"}, previousTokens, counters.TokensByAuthMount)
+	emit([]string{"expire", "leases", "by_mount"}, previousLeases, counters.LeasesBySecretMount)
+
+	metrics.SetGauge([]string{"token", "count", "total"}, float32(counters.TotalTokens()))
+	metrics.SetGauge([]string{"token", "count", "entities"}, float32(counters.Entities))
+}
+
+// metricsMountName returns the name of the mount in metric keys, with the
+// slashes replaced like in the route metrics
+func metricsMountName(mount string) string {
+	return strings.Replace(mount, "/", "-", -1)
+}
@@ -0,0 +1,100 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestCore_Counters(t *testing.T) {
+	c, noop, root := testCoreTokenLimits(t)
+
+	for i := 0; i < 2; i++ {
+		login, err := testLoginTokenLimits(t, c, noop)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			if _, err := testCreateTokenLimits(t, c, login); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	registerLease := func() {
+		_, err := c.expiration.Register(&logical.Request{
+			Operation:   logical.ReadOperation,
+			Path:        "secret/foo",
+			ClientToken: root,
+		}, &logical.Response{
+			Secret: &logical.Secret{
+				LeaseOptions: logical.LeaseOptions{
+					TTL: time.Hour,
+				},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	registerLease()
+
+	counters, err := c.Counters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedTokens := map[string]int{
+		"auth/foo/":   2,
+		"auth/token/": 1,
+	}
+	if !reflect.DeepEqual(counters.TokensByAuthMount, expectedTokens) {
+		t.Fatalf("bad: %#v", counters.TokensByAuthMount)
+	}
+	if counters.TotalTokens() != 3 {
+		t.Fatalf("bad: %d", counters.TotalTokens())
+	}
+	expectedLeases := map[string]int{
+		"secret/": 1,
+	}
+	if !reflect.DeepEqual(counters.LeasesBySecretMount, expectedLeases) {
+		t.Fatalf("bad: %#v", counters.LeasesBySecretMount)
+	}
+	if counters.Entities != 1 {
+		t.Fatalf("bad: %d", counters.Entities)
+	}
+
+	// The counters last collected are returned until collected again
+	registerLease()
+	if counters, err = c.Counters(); err != nil {
+		t.Fatal(err)
+	}
+	if counters.TotalLeases() != 1 {
+		t.Fatalf("bad: %d", counters.TotalLeases())
+	}
+	if _, err := c.collectCounters(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/internal/counters")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leases := resp.Data["leases"].(map[string]interface{})
+	if leases["total"] != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if !reflect.DeepEqual(leases["by_secret_mount"], map[string]int{"secret/": 2}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	tokens := resp.Data["tokens"].(map[string]interface{})
+	if tokens["total"] != 3 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp.Data["entities"] != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
@@ -62,11 +62,14 @@ func (d dynamicSystemView) fetchTTLs() (def, max time.Duration) {
 	def = d.core.defaultLeaseTTL
 	max = d.core.maxLeaseTTL
 
-	if d.mountEntry.Config.DefaultLeaseTTL != 0 {
-		def = d.mountEntry.Config.DefaultLeaseTTL
+	// The TTLs of the mount are read on every call so that tuning them
+	// applies to the running backend without remounting it
+	mountDef, mountMax := d.mountEntry.leaseTTLs()
+	if mountDef != 0 {
+		def = mountDef
 	}
-	if d.mountEntry.Config.MaxLeaseTTL != 0 {
-		max = d.mountEntry.Config.MaxLeaseTTL
+	if mountMax != 0 {
+		max = mountMax
 	}
 
 	return
@@ -136,3 +139,8 @@ func (d dynamicSystemView) LookupPlugin(name string) (*pluginutil.PluginRunner,
 func (d dynamicSystemView) MlockEnabled() bool {
 	return d.core.enableMlock
 }
+
+// ClockSkewTolerance returns the configured clock skew tolerance.
+func (d dynamicSystemView) ClockSkewTolerance() time.Duration {
+	return d.core.clockSkewTolerance
+}
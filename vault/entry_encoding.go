@@ -0,0 +1,176 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/storagepb"
+	"github.com/hashicorp/vault/logical"
+)
+
+// protobufEntryPrefix marks token and lease entries encoded with protobuf.
+// Entries written before protobuf was used are JSON objects, which never
+// start with this byte, so both formats can be read while entries are
+// migrated as they are rewritten.
+const protobufEntryPrefix byte = 0x01
+
+// isProtobufEntry returns whether the stored value is protobuf encoded
+func isProtobufEntry(buf []byte) bool {
+	return len(buf) > 0 && buf[0] == protobufEntryPrefix
+}
+
+func marshalProtobufEntry(msg proto.Message) ([]byte, error) {
+	buf, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{protobufEntryPrefix}, buf...), nil
+}
+
+// encodeTokenEntry encodes the token entry for storage
+func encodeTokenEntry(te *TokenEntry) ([]byte, error) {
+	return marshalProtobufEntry(&storagepb.TokenEntry{
+		Id:             te.ID,
+		Accessor:       te.Accessor,
+		Parent:         te.Parent,
+		Policies:       te.Policies,
+		Path:           te.Path,
+		Meta:           te.Meta,
+		DisplayName:    te.DisplayName,
+		NumUses:        int64(te.NumUses),
+		CreationTime:   te.CreationTime,
+		Ttl:            int64(te.TTL),
+		ExplicitMaxTtl: int64(te.ExplicitMaxTTL),
+		Role:           te.Role,
+		Period:         int64(te.Period),
+		Entity:         te.Entity,
+		BoundCidrs:     te.BoundCIDRs,
+	})
+}
+
+// decodeTokenEntry decodes a stored token entry in either format, returning
+// whether it is in the legacy JSON format
+func decodeTokenEntry(buf []byte) (*TokenEntry, bool, error) {
+	if !isProtobufEntry(buf) {
+		te := new(TokenEntry)
+		if err := jsonutil.DecodeJSON(buf, te); err != nil {
+			return nil, false, err
+		}
+		return te, true, nil
+	}
+
+	var pb storagepb.TokenEntry
+	if err := proto.Unmarshal(buf[1:], &pb); err != nil {
+		return nil, false, err
+	}
+
+	// Protobuf does not distinguish empty lists from missing ones; tokens
+	// are always created with a list of policies
+	if pb.Policies == nil {
+		pb.Policies = []string{}
+	}
+
+	return &TokenEntry{
+		ID:             pb.Id,
+		Accessor:       pb.Accessor,
+		Parent:         pb.Parent,
+		Policies:       pb.Policies,
+		Path:           pb.Path,
+		Meta:           pb.Meta,
+		DisplayName:    pb.DisplayName,
+		NumUses:        int(pb.NumUses),
+		CreationTime:   pb.CreationTime,
+		TTL:            time.Duration(pb.Ttl),
+		ExplicitMaxTTL: time.Duration(pb.ExplicitMaxTtl),
+		Role:           pb.Role,
+		Period:         time.Duration(pb.Period),
+		Entity:         pb.Entity,
+		BoundCIDRs:     pb.BoundCidrs,
+	}, false, nil
+}
+
+// encodeLeaseEntry encodes the lease entry for storage
+func encodeLeaseEntry(le *leaseEntry) ([]byte, error) {
+	pb := &storagepb.LeaseEntry{
+		LeaseId:         le.LeaseID,
+		ClientToken:     le.ClientToken,
+		Path:            le.Path,
+		IssueTime:       encodeEntryTime(le.IssueTime),
+		ExpireTime:      encodeEntryTime(le.ExpireTime),
+		LastRenewalTime: encodeEntryTime(le.LastRenewalTime),
+	}
+
+	var err error
+	if le.Data != nil {
+		if pb.Data, err = json.Marshal(le.Data); err != nil {
+			return nil, err
+		}
+	}
+	if le.Secret != nil {
+		if pb.Secret, err = json.Marshal(le.Secret); err != nil {
+			return nil, err
+		}
+	}
+	if le.Auth != nil {
+		if pb.Auth, err = json.Marshal(le.Auth); err != nil {
+			return nil, err
+		}
+	}
+
+	return marshalProtobufEntry(pb)
+}
+
+// decodeLeaseEntry decodes a stored lease entry in either format
+func decodeLeaseEntry(buf []byte) (*leaseEntry, error) {
+	out := new(leaseEntry)
+	if !isProtobufEntry(buf) {
+		return out, jsonutil.DecodeJSON(buf, out)
+	}
+
+	var pb storagepb.LeaseEntry
+	if err := proto.Unmarshal(buf[1:], &pb); err != nil {
+		return nil, err
+	}
+	out.LeaseID = pb.LeaseId
+	out.ClientToken = pb.ClientToken
+	out.Path = pb.Path
+	out.IssueTime = decodeEntryTime(pb.IssueTime)
+	out.ExpireTime = decodeEntryTime(pb.ExpireTime)
+	out.LastRenewalTime = decodeEntryTime(pb.LastRenewalTime)
+
+	if len(pb.Data) > 0 {
+		if err := jsonutil.DecodeJSON(pb.Data, &out.Data); err != nil {
+			return nil, fmt.Errorf("failed to decode lease data: %v", err)
+		}
+	}
+	if len(pb.Secret) > 0 {
+		out.Secret = new(logical.Secret)
+		if err := jsonutil.DecodeJSON(pb.Secret, out.Secret); err != nil {
+			return nil, fmt.Errorf("failed to decode lease secret: %v", err)
+		}
+	}
+	if len(pb.Auth) > 0 {
+		out.Auth = new(logical.Auth)
+		if err := jsonutil.DecodeJSON(pb.Auth, out.Auth); err != nil {
+			return nil, fmt.Errorf("failed to decode lease auth: %v", err)
+		}
+	}
+	return out, nil
+}
+
+func encodeEntryTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func decodeEntryTime(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
@@ -0,0 +1,106 @@
+package vault
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestTokenStore_LegacyJSONEntry(t *testing.T) {
+	_, ts, _, _ := TestCoreWithTokenStore(t)
+
+	ent := &TokenEntry{
+		ID:           "legacy-token",
+		Accessor:     "legacy-accessor",
+		Path:         "auth/token/create",
+		Policies:     []string{"dev"},
+		Meta:         map[string]string{"user": "armon"},
+		DisplayName:  "token",
+		CreationTime: time.Now().Unix(),
+		TTL:          time.Hour,
+	}
+	enc, err := json.Marshal(ent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saltedID, err := ts.SaltID(ent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.view.Put(&logical.StorageEntry{Key: lookupPrefix + saltedID, Value: enc}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := ts.Lookup(ent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, ent) {
+		t.Fatalf("bad: expected:%#v\nactual:%#v", ent, out)
+	}
+
+	// The lookup migrates the entry
+	raw, err := ts.view.Get(lookupPrefix + saltedID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isProtobufEntry(raw.Value) {
+		t.Fatalf("entry not migrated: %q", raw.Value)
+	}
+	if len(raw.Value) >= len(enc) {
+		t.Fatalf("bad: protobuf size %d, JSON size %d", len(raw.Value), len(enc))
+	}
+
+	out, err = ts.Lookup(ent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, ent) {
+		t.Fatalf("bad: expected:%#v\nactual:%#v", ent, out)
+	}
+}
+
+func TestLeaseEntry_legacyJSON(t *testing.T) {
+	le := &leaseEntry{
+		LeaseID:     "foo/bar/1234",
+		ClientToken: "token",
+		Path:        "foo/bar",
+		Data: map[string]interface{}{
+			"testing": true,
+		},
+		Auth: &logical.Auth{
+			ClientToken: "token",
+			Policies:    []string{"default"},
+		},
+		IssueTime:  time.Now(),
+		ExpireTime: time.Now().Add(time.Hour),
+	}
+
+	legacy, err := json.Marshal(le)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := le.encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, buf := range [][]byte{legacy, enc} {
+		out, err := decodeLeaseEntry(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.LeaseID != le.LeaseID || out.ClientToken != le.ClientToken || out.Secret != nil {
+			t.Fatalf("bad: %#v", out)
+		}
+		if !out.IssueTime.Equal(le.IssueTime) || !out.ExpireTime.Equal(le.ExpireTime) || !out.LastRenewalTime.IsZero() {
+			t.Fatalf("bad: %#v", out)
+		}
+		if !reflect.DeepEqual(out.Data, le.Data) || !reflect.DeepEqual(out.Auth.Policies, le.Auth.Policies) {
+			t.Fatalf("bad: %#v", out)
+		}
+	}
+}
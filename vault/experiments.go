@@ -0,0 +1,121 @@
+package vault
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// ExperimentEvents enables the event notification subsystem
+	ExperimentEvents = "events"
+
+	// ExperimentNewCache enables the new physical storage cache
+	ExperimentNewCache = "new_cache"
+
+	// ExperimentBatchTokens enables batch tokens
+	ExperimentBatchTokens = "batch_tokens"
+)
+
+// experiments maps the experimental subsystems that can be enabled to their
+// description
+var experiments = map[string]string{
+	ExperimentEvents:      "Event notification subsystem",
+	ExperimentNewCache:    "New physical storage cache",
+	ExperimentBatchTokens: "Batch tokens",
+}
+
+// ExperimentsConfig lists the experimental subsystems enabled on the
+// cluster. It is persisted so that all the nodes of the cluster, and the
+// node itself after a restart, enable the same subsystems.
+type ExperimentsConfig struct {
+	Enabled []string `json:"enabled"`
+}
+
+// validate checks that the enabled experiments exist, and dedupes and sorts
+// them
+func (c *ExperimentsConfig) validate() error {
+	c.Enabled = strutil.RemoveDuplicates(c.Enabled, true)
+	for _, name := range c.Enabled {
+		if _, ok := experiments[name]; !ok {
+			return fmt.Errorf("unknown experiment %q", name)
+		}
+	}
+	return nil
+}
+
+// ExperimentEnabled returns whether the given experimental subsystem is
+// enabled. Subsystems check it when used, so that toggling them takes effect
+// without a restart.
+func (c *Core) ExperimentEnabled(name string) bool {
+	return strutil.StrListContains(c.experimentsConfig().Enabled, name)
+}
+
+// EnabledExperiments returns the enabled experimental subsystems
+func (c *Core) EnabledExperiments() []string {
+	return c.experimentsConfig().Enabled
+}
+
+// experimentsConfig returns the current experiments configuration
+func (c *Core) experimentsConfig() *ExperimentsConfig {
+	if config, ok := c.experiments.Load().(*ExperimentsConfig); ok {
+		return config
+	}
+	return &ExperimentsConfig{}
+}
+
+// setExperimentsConfig persists the enabled experiments and applies them
+func (c *Core) setExperimentsConfig(config *ExperimentsConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+
+	view := c.systemBarrierView.SubView("config/")
+
+	entry, err := logical.StorageEntryJSON("experiments", config)
+	if err != nil {
+		return fmt.Errorf("failed to create experiments entry: %v", err)
+	}
+	if err := view.Put(entry); err != nil {
+		return fmt.Errorf("failed to save experiments: %v", err)
+	}
+
+	c.experiments.Store(config)
+	if c.logger.IsInfo() {
+		c.logger.Info("core: experiments updated", "enabled", config.Enabled)
+	}
+	return nil
+}
+
+// This should only be called with the core state lock held for writing
+func (c *Core) loadExperimentsConfig() error {
+	view := c.systemBarrierView.SubView("config/")
+
+	out, err := view.Get("experiments")
+	if err != nil {
+		return fmt.Errorf("failed to read experiments: %v", err)
+	}
+
+	config := &ExperimentsConfig{}
+	if out != nil {
+		if err := out.DecodeJSON(config); err != nil {
+			return err
+		}
+	}
+
+	// Experiments removed since they were enabled are ignored rather than
+	// failing the unseal
+	enabled := config.Enabled[:0]
+	for _, name := range config.Enabled {
+		if _, ok := experiments[name]; ok {
+			enabled = append(enabled, name)
+		} else {
+			c.logger.Warn("core: ignoring unknown experiment", "name", name)
+		}
+	}
+	config.Enabled = enabled
+
+	c.experiments.Store(config)
+	return nil
+}
@@ -0,0 +1,64 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestSystemBackend_experiments(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/config/experiments")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if enabled := resp.Data["enabled"].([]string); len(enabled) != 0 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if _, ok := resp.Data["available"].(map[string]string)[ExperimentEvents]; !ok {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Unknown experiments are rejected
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/config/experiments")
+	req.ClientToken = root
+	req.Data["enabled"] = "events,bogus"
+	resp, err = c.HandleRequest(req)
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected error: %#v", resp)
+	}
+
+	req.Data["enabled"] = "new_cache,events,events"
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !c.ExperimentEnabled(ExperimentEvents) || c.ExperimentEnabled(ExperimentBatchTokens) {
+		t.Fatalf("bad: %v", c.EnabledExperiments())
+	}
+
+	// The experiments are persisted
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+	if exp := []string{"events", "new_cache"}; !reflect.DeepEqual(c.EnabledExperiments(), exp) {
+		t.Fatalf("bad: %v", c.EnabledExperiments())
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/config/experiments")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if c.ExperimentEnabled(ExperimentEvents) {
+		t.Fatalf("bad: %v", c.EnabledExperiments())
+	}
+}
@@ -1,8 +1,9 @@
 package vault
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"path"
 	"strings"
 	"sync"
@@ -14,9 +15,7 @@ import (
 
 	"github.com/hashicorp/errwrap"
 	multierror "github.com/hashicorp/go-multierror"
-	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/consts"
-	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/helper/locksutil"
 	"github.com/hashicorp/vault/logical"
 )
@@ -46,39 +45,89 @@ const (
 
 	// defaultLeaseDuration is the default lease duration used when no lease is specified
 	defaultLeaseTTL = maxLeaseTTL
+
+	// leaseWALKey is the key, in the expiration view, of the log of the
+	// recent lease mutations
+	leaseWALKey = "wal"
+
+	// leaseWALWindow is how long the lease mutations are kept in the log
+	leaseWALWindow = 30 * time.Second
+
+	// leaseWALMaxRecords caps the number of mutations kept in the log under
+	// heavy load
+	leaseWALMaxRecords = 4096
 )
 
+const (
+	leaseWALPut    = "put"
+	leaseWALDelete = "delete"
+)
+
+// leaseWALRecord is a lease mutation in the log
+type leaseWALRecord struct {
+	LeaseID string    `json:"lease_id"`
+	Op      string    `json:"op"`
+	Time    time.Time `json:"time"`
+}
+
 // ExpirationManager is used by the Core to manage leases. Secrets
 // can provide a lease, meaning that they can be renewed or revoked.
 // If a secret is not renewed in timely manner, it may be expired, and
 // the ExpirationManager will handle doing automatic revocation.
 type ExpirationManager struct {
 	router     *Router
+	view       *BarrierView
 	idView     *BarrierView
 	tokenView  *BarrierView
 	tokenStore *TokenStore
 	logger     log.Logger
+	clock      Clock
+	entropy    io.Reader
 
-	pending     map[string]*time.Timer
+	pending     map[string]Timer
 	pendingLock sync.Mutex
 
 	tidyLock int64
+
+	// wal holds the lease mutations of the last leaseWALWindow, which are
+	// also persisted as a single entry. Storage backends listing keys with
+	// eventual consistency may not list the leases created just before a
+	// failover; the new active node replays the log after restoring the
+	// leases so that they are all known before it handles requests.
+	wal     []leaseWALRecord
+	walLock sync.Mutex
+
+	// restoreProgress, if set, is called periodically during Restore with
+	// the number of leases processed so far and the total
+	restoreProgress func(completed, total int)
+
+	// clockSkewTolerance is how long after their expiration time leases
+	// not revoked yet can still be renewed
+	clockSkewTolerance time.Duration
 }
 
 // NewExpirationManager creates a new ExpirationManager that is backed
-// using a given view, and uses the provided router for revocation.
-func NewExpirationManager(router *Router, view *BarrierView, ts *TokenStore, logger log.Logger) *ExpirationManager {
+// using a given view, and uses the provided router for revocation. Leases
+// expire according to the given clock, or the system clock if nil, and their
+// IDs are generated from the given entropy, or crypto/rand if nil.
+func NewExpirationManager(router *Router, view *BarrierView, ts *TokenStore, logger log.Logger, clock Clock, entropy io.Reader) *ExpirationManager {
 	if logger == nil {
 		logger = log.New("expiration_manager")
 
 	}
+	if clock == nil {
+		clock = systemClock{}
+	}
 	exp := &ExpirationManager{
 		router:     router,
+		view:       view,
 		idView:     view.SubView(leaseViewPrefix),
 		tokenView:  view.SubView(tokenViewPrefix),
 		tokenStore: ts,
 		logger:     logger,
-		pending:    make(map[string]*time.Timer),
+		clock:      clock,
+		entropy:    entropy,
+		pending:    make(map[string]Timer),
 	}
 	return exp
 }
@@ -92,12 +141,17 @@ func (c *Core) setupExpiration() error {
 	view := c.systemBarrierView.SubView(expirationSubPath)
 
 	// Create the manager
-	mgr := NewExpirationManager(c.router, view, c.tokenStore, c.logger)
+	mgr := NewExpirationManager(c.router, view, c.tokenStore, c.logger, c.clock, c.entropy)
 	c.expiration = mgr
 
 	// Link the token store to this
 	c.tokenStore.SetExpirationManager(mgr)
 
+	// Report the progress of the restore as part of the unseal status
+	mgr.restoreProgress = c.unsealStatus.setPhaseProgress
+
+	mgr.clockSkewTolerance = c.clockSkewTolerance
+
 	// Restore the existing state
 	c.logger.Info("expiration: restoring leases")
 	if err := c.expiration.Restore(); err != nil {
@@ -237,20 +291,34 @@ func (m *ExpirationManager) Restore() error {
 	m.pendingLock.Lock()
 	defer m.pendingLock.Unlock()
 
-	// Accumulate existing leases
-	m.logger.Debug("expiration: collecting leases")
-	existing, err := logical.CollectKeys(m.idView)
+	// Count the existing leases to report progress. The leases are listed a
+	// page at a time, here and below, so that they are never all held in
+	// memory.
+	m.logger.Debug("expiration: counting leases")
+	total := 0
+	err := scanPages(m.idView, "", func(string) error {
+		total++
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to scan for leases: %v", err)
 	}
-	m.logger.Debug("expiration: leases collected", "num_existing", len(existing))
+	m.logger.Debug("expiration: leases counted", "num_existing", total)
+	m.reportRestoreProgress(0, total)
 
 	// Make the channels used for the worker pool
 	broker := make(chan string)
-	quit := make(chan bool)
-	// Buffer these channels to prevent deadlocks
-	errs := make(chan error, len(existing))
-	result := make(chan *leaseEntry, len(existing))
+	quit := make(chan struct{})
+	errs := make(chan error, 1)
+	result := make(chan *leaseEntry)
+
+	// sendErr reports the first error, which stops the restore
+	sendErr := func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
 
 	// Use a wait group
 	wg := &sync.WaitGroup{}
@@ -271,12 +339,16 @@ func (m *ExpirationManager) Restore() error {
 
 					le, err := m.loadEntry(leaseID)
 					if err != nil {
-						errs <- err
+						sendErr(err)
 						continue
 					}
 
 					// Write results out to the result channel
-					result <- le
+					select {
+					case result <- le:
+					case <-quit:
+						return
+					}
 
 				// quit early
 				case <-quit:
@@ -286,38 +358,56 @@ func (m *ExpirationManager) Restore() error {
 		}()
 	}
 
-	// Distribute the collected keys to the workers in a go routine
+	// Distribute the keys to the workers in a go routine as they are listed
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		for i, leaseID := range existing {
+
+		// Close the broker, causing worker routines to exit
+		defer close(broker)
+
+		i := 0
+		err := scanPages(m.idView, "", func(leaseID string) error {
 			if i%500 == 0 {
 				m.logger.Trace("expiration: leases loading", "progress", i)
 			}
+			i++
 
 			select {
 			case <-quit:
-				return
-
-			default:
-				broker <- leaseID
+				return errRestoreQuit
+			case broker <- leaseID:
+				return nil
 			}
+		})
+		if err != nil && err != errRestoreQuit {
+			sendErr(fmt.Errorf("failed to scan for leases: %v", err))
 		}
+	}()
 
-		// Close the broker, causing worker routines to exit
-		close(broker)
+	// Let all go routines finish
+	doneCh := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(doneCh)
 	}()
 
 	// Restore each key by pulling from the result chan
-	for i := 0; i < len(existing); i++ {
+	restored := 0
+	for done := false; !done; {
 		select {
 		case err := <-errs:
 			// Close all go routines
 			close(quit)
+			<-doneCh
 
 			return err
 
 		case le := <-result:
+			restored++
+			if restored%100 == 0 {
+				m.reportRestoreProgress(restored, total)
+			}
 
 			// If there is no entry, nothing to restore
 			if le == nil {
@@ -330,20 +420,33 @@ func (m *ExpirationManager) Restore() error {
 			}
 
 			// Determine the remaining time to expiration
-			expires := le.ExpireTime.Sub(time.Now())
+			expires := le.ExpireTime.Sub(m.clock.Now())
 			if expires <= 0 {
 				expires = minRevokeDelay
 			}
 
 			// Setup revocation timer
-			m.pending[le.LeaseID] = time.AfterFunc(expires, func() {
+			m.pending[le.LeaseID] = m.clock.AfterFunc(expires, func() {
 				m.expireID(le.LeaseID)
 			})
+
+		case <-doneCh:
+			done = true
 		}
 	}
 
-	// Let all go routines finish
-	wg.Wait()
+	// An error may have been reported by the last worker to finish
+	select {
+	case err := <-errs:
+		return err
+	default:
+	}
+
+	// Catch up with the mutations the scan may have missed
+	if err := m.replayWAL(); err != nil {
+		return err
+	}
+	m.reportRestoreProgress(restored, restored)
 
 	if len(m.pending) > 0 {
 		if m.logger.IsInfo() {
@@ -354,6 +457,15 @@ func (m *ExpirationManager) Restore() error {
 	return nil
 }
 
+// errRestoreQuit stops the scan of the leases when the restore fails
+var errRestoreQuit = errors.New("restore stopped")
+
+func (m *ExpirationManager) reportRestoreProgress(completed, total int) {
+	if m.restoreProgress != nil {
+		m.restoreProgress(completed, total)
+	}
+}
+
 // Stop is used to prevent further automatic revocations.
 // This must be called before sealing the view.
 func (m *ExpirationManager) Stop() error {
@@ -362,7 +474,7 @@ func (m *ExpirationManager) Stop() error {
 	for _, timer := range m.pending {
 		timer.Stop()
 	}
-	m.pending = make(map[string]*time.Timer)
+	m.pending = make(map[string]Timer)
 	m.pendingLock.Unlock()
 	return nil
 }
@@ -481,6 +593,39 @@ func (m *ExpirationManager) RevokeByToken(te *TokenEntry) error {
 	return nil
 }
 
+// moveLeasesByToken moves the leases of a token to another token, so that
+// they are revoked along with it rather than with the original token
+func (m *ExpirationManager) moveLeasesByToken(from, to string) error {
+	existing, err := m.lookupByToken(from)
+	if err != nil {
+		return fmt.Errorf("failed to scan for leases: %v", err)
+	}
+
+	for _, leaseID := range existing {
+		le, err := m.loadEntry(leaseID)
+		if err != nil {
+			return err
+		}
+		if le == nil {
+			continue
+		}
+
+		// Index the lease under the new token first, so that a failure
+		// leaves it revocable through one of the tokens
+		if err := m.createIndexByToken(to, leaseID); err != nil {
+			return err
+		}
+		le.ClientToken = to
+		if err := m.persistEntry(le); err != nil {
+			return err
+		}
+		if err := m.removeIndexByToken(from, leaseID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *ExpirationManager) revokePrefixCommon(prefix string, force bool) error {
 	// Ensure there is a trailing slash
 	if !strings.HasSuffix(prefix, "/") {
@@ -516,7 +661,7 @@ func (m *ExpirationManager) Renew(leaseID string, increment time.Duration) (*log
 	}
 
 	// Check if the lease is renewable
-	if _, err := le.renewable(); err != nil {
+	if _, err := le.renewable(m.renewTime()); err != nil {
 		return nil, err
 	}
 
@@ -549,8 +694,8 @@ func (m *ExpirationManager) Renew(leaseID string, increment time.Duration) (*log
 	// Update the lease entry
 	le.Data = resp.Data
 	le.Secret = resp.Secret
-	le.ExpireTime = resp.Secret.ExpirationTime()
-	le.LastRenewalTime = time.Now()
+	le.ExpireTime = m.expirationTime(&resp.Secret.LeaseOptions)
+	le.LastRenewalTime = m.clock.Now()
 	if err := m.persistEntry(le); err != nil {
 		return nil, err
 	}
@@ -562,6 +707,12 @@ func (m *ExpirationManager) Renew(leaseID string, increment time.Duration) (*log
 	return resp, nil
 }
 
+// renewTime is the time against which the expiration of leases is checked
+// on renewal, allowing for the clock skew tolerance
+func (m *ExpirationManager) renewTime() time.Time {
+	return m.clock.Now().Add(-m.clockSkewTolerance)
+}
+
 // RenewToken is used to renew a token which does not need to
 // invoke a logical backend.
 func (m *ExpirationManager) RenewToken(req *logical.Request, source string, token string,
@@ -582,7 +733,7 @@ func (m *ExpirationManager) RenewToken(req *logical.Request, source string, toke
 
 	// Check if the lease is renewable. Note that this also checks for a nil
 	// lease and errors in that case as well.
-	if _, err := le.renewable(); err != nil {
+	if _, err := le.renewable(m.renewTime()); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
@@ -614,8 +765,8 @@ func (m *ExpirationManager) RenewToken(req *logical.Request, source string, toke
 
 	// Update the lease entry
 	le.Auth = resp.Auth
-	le.ExpireTime = resp.Auth.ExpirationTime()
-	le.LastRenewalTime = time.Now()
+	le.ExpireTime = m.expirationTime(&resp.Auth.LeaseOptions)
+	le.LastRenewalTime = m.clock.Now()
 	if err := m.persistEntry(le); err != nil {
 		return nil, err
 	}
@@ -648,7 +799,7 @@ func (m *ExpirationManager) Register(req *logical.Request, resp *logical.Respons
 	}
 
 	// Create a lease entry
-	leaseUUID, err := uuid.GenerateUUID()
+	leaseUUID, err := generateUUID(m.entropy)
 	if err != nil {
 		return "", err
 	}
@@ -684,8 +835,8 @@ func (m *ExpirationManager) Register(req *logical.Request, resp *logical.Respons
 		Path:        req.Path,
 		Data:        resp.Data,
 		Secret:      resp.Secret,
-		IssueTime:   time.Now(),
-		ExpireTime:  resp.Secret.ExpirationTime(),
+		IssueTime:   m.clock.Now(),
+		ExpireTime:  m.expirationTime(&resp.Secret.LeaseOptions),
 	}
 
 	// Encode the entry
@@ -700,6 +851,7 @@ func (m *ExpirationManager) Register(req *logical.Request, resp *logical.Respons
 
 	// Setup revocation timer if there is a lease
 	m.updatePending(&le, resp.Secret.LeaseTotal())
+	metrics.IncrCounter([]string{"expire", "leases", "created"}, 1)
 
 	// Done
 	return le.LeaseID, nil
@@ -730,8 +882,8 @@ func (m *ExpirationManager) RegisterAuth(source string, auth *logical.Auth) erro
 		ClientToken: auth.ClientToken,
 		Auth:        auth,
 		Path:        source,
-		IssueTime:   time.Now(),
-		ExpireTime:  auth.ExpirationTime(),
+		IssueTime:   m.clock.Now(),
+		ExpireTime:  m.expirationTime(&auth.LeaseOptions),
 	}
 
 	// Encode the entry
@@ -741,6 +893,7 @@ func (m *ExpirationManager) RegisterAuth(source string, auth *logical.Auth) erro
 
 	// Setup revocation timer
 	m.updatePending(&le, auth.LeaseTotal())
+	metrics.IncrCounter([]string{"expire", "auth-leases", "created"}, 1)
 	return nil
 }
 
@@ -802,7 +955,7 @@ func (m *ExpirationManager) updatePending(le *leaseEntry, leaseTotal time.Durati
 
 	// Create entry if it does not exist
 	if !ok && leaseTotal > 0 {
-		timer := time.AfterFunc(leaseTotal, func() {
+		timer := m.clock.AfterFunc(leaseTotal, func() {
 			m.expireID(le.LeaseID)
 		})
 		m.pending[le.LeaseID] = timer
@@ -822,6 +975,15 @@ func (m *ExpirationManager) updatePending(le *leaseEntry, leaseTotal time.Durati
 	}
 }
 
+// expirationTime returns when a lease with the given options expires, or
+// the zero time if it does not
+func (m *ExpirationManager) expirationTime(opts *logical.LeaseOptions) time.Time {
+	if !opts.LeaseEnabled() {
+		return time.Time{}
+	}
+	return m.clock.Now().Add(opts.LeaseTotal())
+}
+
 // expireID is invoked when a given ID is expired
 func (m *ExpirationManager) expireID(leaseID string) {
 	// Clear from the pending expiration
@@ -932,7 +1094,7 @@ func (m *ExpirationManager) persistEntry(le *leaseEntry) error {
 	if err := m.idView.Put(&ent); err != nil {
 		return fmt.Errorf("failed to persist lease entry: %v", err)
 	}
-	return nil
+	return m.appendWAL(le.LeaseID, leaseWALPut)
 }
 
 // deleteEntry is used to delete a lease entry
@@ -940,6 +1102,105 @@ func (m *ExpirationManager) deleteEntry(leaseID string) error {
 	if err := m.idView.Delete(leaseID); err != nil {
 		return fmt.Errorf("failed to delete lease entry: %v", err)
 	}
+	return m.appendWAL(leaseID, leaseWALDelete)
+}
+
+// appendWAL records a lease mutation in the log, dropping the mutations
+// older than leaseWALWindow
+func (m *ExpirationManager) appendWAL(leaseID, op string) error {
+	m.walLock.Lock()
+	defer m.walLock.Unlock()
+
+	now := m.clock.Now()
+	wal := make([]leaseWALRecord, 0, len(m.wal)+1)
+	for _, r := range m.wal {
+		if now.Sub(r.Time) < leaseWALWindow {
+			wal = append(wal, r)
+		}
+	}
+	wal = append(wal, leaseWALRecord{
+		LeaseID: leaseID,
+		Op:      op,
+		Time:    now,
+	})
+	if len(wal) > leaseWALMaxRecords {
+		wal = wal[len(wal)-leaseWALMaxRecords:]
+	}
+
+	entry, err := logical.StorageEntryJSON(leaseWALKey, wal)
+	if err != nil {
+		return fmt.Errorf("failed to encode lease log: %v", err)
+	}
+	if err := m.view.Put(entry); err != nil {
+		return fmt.Errorf("failed to persist lease log: %v", err)
+	}
+	m.wal = wal
+	return nil
+}
+
+// replayWAL applies the logged lease mutations to the pending expirations:
+// the timers of the leases last put are set from their persisted entry, and
+// those of the leases last deleted are stopped. The pending lock must be
+// held.
+func (m *ExpirationManager) replayWAL() error {
+	m.walLock.Lock()
+	defer m.walLock.Unlock()
+
+	out, err := m.view.Get(leaseWALKey)
+	if err != nil {
+		return fmt.Errorf("failed to read lease log: %v", err)
+	}
+	var wal []leaseWALRecord
+	if out != nil {
+		if err := out.DecodeJSON(&wal); err != nil {
+			return fmt.Errorf("failed to decode lease log: %v", err)
+		}
+	}
+	m.wal = wal
+
+	// Only the last mutation of each lease matters
+	last := make(map[string]string, len(wal))
+	for _, r := range wal {
+		last[r.LeaseID] = r.Op
+	}
+
+	replayed := 0
+	for leaseID, op := range last {
+		var le *leaseEntry
+		if op == leaseWALPut {
+			if le, err = m.loadEntry(leaseID); err != nil {
+				return err
+			}
+		}
+
+		timer, known := m.pending[leaseID]
+		if le == nil || le.ExpireTime.IsZero() {
+			if known {
+				timer.Stop()
+				delete(m.pending, leaseID)
+				replayed++
+			}
+			continue
+		}
+		if known {
+			timer.Stop()
+		} else {
+			replayed++
+		}
+
+		expires := le.ExpireTime.Sub(m.clock.Now())
+		if expires <= 0 {
+			expires = minRevokeDelay
+		}
+		id := leaseID
+		m.pending[id] = m.clock.AfterFunc(expires, func() {
+			m.expireID(id)
+		})
+	}
+
+	if replayed > 0 && m.logger.IsInfo() {
+		m.logger.Info("expire: replayed lease log", "missed_lease_count", replayed)
+	}
 	return nil
 }
 
@@ -1041,6 +1302,17 @@ func (m *ExpirationManager) emitMetrics() {
 	metrics.SetGauge([]string{"expire", "num_leases"}, float32(num))
 }
 
+// leaseIDs returns the IDs of the leases pending expiration
+func (m *ExpirationManager) leaseIDs() []string {
+	m.pendingLock.Lock()
+	defer m.pendingLock.Unlock()
+	leaseIDs := make([]string, 0, len(m.pending))
+	for leaseID := range m.pending {
+		leaseIDs = append(leaseIDs, leaseID)
+	}
+	return leaseIDs
+}
+
 // leaseEntry is used to structure the values the expiration
 // manager stores. This is used to handle renew and revocation.
 type leaseEntry struct {
@@ -1055,19 +1327,19 @@ type leaseEntry struct {
 	LastRenewalTime time.Time              `json:"last_renewal_time"`
 }
 
-// encode is used to JSON encode the lease entry
+// encode is used to encode the lease entry for storage
 func (le *leaseEntry) encode() ([]byte, error) {
-	return json.Marshal(le)
+	return encodeLeaseEntry(le)
 }
 
-func (le *leaseEntry) renewable() (bool, error) {
+func (le *leaseEntry) renewable(now time.Time) (bool, error) {
 	var err error
 	switch {
 	// If there is no entry, cannot review
 	case le == nil || le.ExpireTime.IsZero():
 		err = fmt.Errorf("lease not found or lease is not renewable")
 	// Determine if the lease is expired
-	case le.ExpireTime.Before(time.Now()):
+	case le.ExpireTime.Before(now):
 		err = fmt.Errorf("lease expired")
 	// Determine if the lease is renewable
 	case le.Secret != nil && !le.Secret.Renewable:
@@ -1082,12 +1354,6 @@ func (le *leaseEntry) renewable() (bool, error) {
 	return true, nil
 }
 
-func (le *leaseEntry) ttl() int64 {
-	return int64(le.ExpireTime.Sub(time.Now().Round(time.Second)).Seconds())
-}
-
-// decodeLeaseEntry is used to reverse encode and return a new entry
-func decodeLeaseEntry(buf []byte) (*leaseEntry, error) {
-	out := new(leaseEntry)
-	return out, jsonutil.DecodeJSON(buf, out)
+func (le *leaseEntry) ttl(now time.Time) int64 {
+	return int64(le.ExpireTime.Sub(now.Round(time.Second)).Seconds())
 }
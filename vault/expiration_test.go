@@ -412,6 +412,77 @@ func TestExpiration_Restore(t *testing.T) {
 	}
 }
 
+func TestExpiration_Restore_WAL(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	inm := physical.NewInmemFaulty(logger)
+	c, exp := mockBackendExpiration(t, inm)
+
+	noop := &NoopBackend{}
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "logical/")
+	if err := exp.router.Mount(noop, "prod/aws/", &MountEntry{Path: "prod/aws/", Type: "noop", UUID: "wal-uuid", Accessor: "noop-accessor"}, view); err != nil {
+		t.Fatal(err)
+	}
+
+	register := func(path string) string {
+		leaseID, err := exp.Register(&logical.Request{
+			Operation:   logical.ReadOperation,
+			Path:        path,
+			ClientToken: "foobar",
+		}, &logical.Response{
+			Secret: &logical.Secret{
+				LeaseOptions: logical.LeaseOptions{
+					TTL: time.Hour,
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return leaseID
+	}
+	kept := register("prod/aws/foo")
+	revoked := register("prod/aws/bar")
+	if err := exp.Revoke(revoked); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The new active node does not see the leases in the listings of
+	// storage, as with backends listing with eventual consistency
+	inm.TruncateLists(0)
+	restore := func() map[string]Timer {
+		exp := NewExpirationManager(c.router, c.systemBarrierView.SubView(expirationSubPath), c.tokenStore, logger, nil, nil)
+		if err := exp.Restore(); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		defer exp.Stop()
+		exp.pendingLock.Lock()
+		defer exp.pendingLock.Unlock()
+		pending := make(map[string]Timer, len(exp.pending))
+		for k, v := range exp.pending {
+			pending[k] = v
+		}
+		return pending
+	}
+	pending := restore()
+	if _, ok := pending[kept]; !ok || len(pending) != 1 {
+		t.Fatalf("expected %s to be pending, got %v", kept, pending)
+	}
+
+	// The log only keeps the recent mutations
+	clock := NewTestClock(time.Now())
+	exp.clock = clock
+	clock.Advance(leaseWALWindow)
+	register("prod/aws/zip")
+	if len(exp.wal) != 1 {
+		t.Fatalf("bad: %#v", exp.wal)
+	}
+	pending = restore()
+	if _, ok := pending[kept]; ok || len(pending) != 1 {
+		t.Fatalf("expected only the last lease to be pending, got %v", pending)
+	}
+}
+
 func TestExpiration_Register(t *testing.T) {
 	exp := mockExpiration(t)
 	req := &logical.Request{
@@ -882,6 +953,137 @@ func TestExpiration_Renew(t *testing.T) {
 	}
 }
 
+func TestExpiration_Renew_clockSkewTolerance(t *testing.T) {
+	exp := mockExpiration(t)
+	noop := &NoopBackend{}
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "logical/")
+	meUUID, err := uuid.GenerateUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exp.router.Mount(noop, "prod/aws/", &MountEntry{Path: "prod/aws/", Type: "noop", UUID: meUUID, Accessor: "noop-accessor"}, view)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "prod/aws/foo",
+		ClientToken: "foobar",
+	}
+	resp := &logical.Response{
+		Secret: &logical.Secret{
+			LeaseOptions: logical.LeaseOptions{
+				TTL:       time.Hour,
+				Renewable: true,
+			},
+		},
+	}
+	id, err := exp.Register(req, resp)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	noop.Response = &logical.Response{
+		Secret: &logical.Secret{
+			LeaseOptions: logical.LeaseOptions{
+				TTL: time.Hour,
+			},
+		},
+	}
+
+	// The clock of the node is ahead of the one the lease was issued with,
+	// making it look expired before its revocation
+	exp.clock = NewTestClock(time.Now().Add(time.Hour + 10*time.Second))
+	if _, err := exp.Renew(id, 0); err == nil || !strings.Contains(err.Error(), "lease expired") {
+		t.Fatalf("expected the renewal to fail, got %v", err)
+	}
+
+	exp.clockSkewTolerance = time.Minute
+	if _, err := exp.Renew(id, 0); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestExpiration_Clock(t *testing.T) {
+	clock := NewTestClock(time.Unix(time.Now().Unix(), 0))
+	c, _, _ := TestCoreUnsealedWithClock(t, clock)
+	exp := c.expiration
+
+	noop := &NoopBackend{}
+	view := NewBarrierView(c.barrier, "logical/")
+	meUUID, err := uuid.GenerateUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exp.router.Mount(noop, "prod/aws/", &MountEntry{Path: "prod/aws/", Type: "noop", UUID: meUUID, Accessor: "noop-accessor"}, view)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "prod/aws/foo",
+		ClientToken: "foobar",
+	}
+	resp := &logical.Response{
+		Secret: &logical.Secret{
+			LeaseOptions: logical.LeaseOptions{
+				TTL:       time.Hour,
+				Renewable: true,
+			},
+		},
+	}
+	id, err := exp.Register(req, resp)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	clock.Advance(30 * time.Minute)
+	le, err := exp.FetchLeaseTimes(id)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if ttl := le.ttl(clock.Now()); ttl != 1800 {
+		t.Fatalf("bad: %d", ttl)
+	}
+
+	// Renewing pushes the expiration back
+	noop.Response = &logical.Response{
+		Secret: &logical.Secret{
+			LeaseOptions: logical.LeaseOptions{
+				TTL: time.Hour,
+			},
+		},
+	}
+	if _, err := exp.Renew(id, 0); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	le, err = exp.FetchLeaseTimes(id)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !le.LastRenewalTime.Equal(clock.Now()) || !le.ExpireTime.Equal(clock.Now().Add(time.Hour)) {
+		t.Fatalf("bad: %#v", le)
+	}
+
+	clock.Advance(59 * time.Minute)
+	if le, err := exp.FetchLeaseTimes(id); err != nil || le == nil {
+		t.Fatalf("lease expired early: %v", err)
+	}
+
+	clock.Advance(time.Minute)
+	if le, err := exp.FetchLeaseTimes(id); err != nil || le != nil {
+		t.Fatalf("lease not expired: %v", err)
+	}
+
+	noop.Lock()
+	defer noop.Unlock()
+	if len(noop.Requests) != 2 || noop.Requests[1].Operation != logical.RevokeOperation {
+		t.Fatalf("bad: %#v", noop.Requests)
+	}
+}
+
 func TestExpiration_Renew_NotRenewable(t *testing.T) {
 	exp := mockExpiration(t)
 	noop := &NoopBackend{}
@@ -1350,19 +1552,19 @@ func TestLeaseEntry(t *testing.T) {
 
 	// Test renewability
 	le.ExpireTime = time.Time{}
-	if r, _ := le.renewable(); r {
+	if r, _ := le.renewable(time.Now()); r {
 		t.Fatal("lease with zero expire time is not renewable")
 	}
 	le.ExpireTime = time.Now().Add(-1 * time.Hour)
-	if r, _ := le.renewable(); r {
+	if r, _ := le.renewable(time.Now()); r {
 		t.Fatal("lease with expire time in the past is not renewable")
 	}
 	le.ExpireTime = time.Now().Add(1 * time.Hour)
-	if r, err := le.renewable(); !r {
+	if r, err := le.renewable(time.Now()); !r {
 		t.Fatalf("lease with future expire time is renewable, err: %v", err)
 	}
 	le.Secret.LeaseOptions.Renewable = false
-	if r, _ := le.renewable(); r {
+	if r, _ := le.renewable(time.Now()); r {
 		t.Fatal("secret is set to not be renewable but returns as renewable")
 	}
 	le.Secret = nil
@@ -1371,11 +1573,11 @@ func TestLeaseEntry(t *testing.T) {
 			Renewable: true,
 		},
 	}
-	if r, err := le.renewable(); !r {
+	if r, err := le.renewable(time.Now()); !r {
 		t.Fatalf("auth is renewable but is set to not be, err: %v", err)
 	}
 	le.Auth.LeaseOptions.Renewable = false
-	if r, _ := le.renewable(); r {
+	if r, _ := le.renewable(time.Now()); r {
 		t.Fatal("auth is set to not be renewable but returns as renewable")
 	}
 }
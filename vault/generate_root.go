@@ -238,7 +238,15 @@ func (c *Core) GenerateRootUpdate(key []byte, nonce string) (*GenerateRootResult
 		}
 	}
 
-	te, err := c.tokenStore.rootToken()
+	// The token is a UUID whatever the configured format, since the OTP
+	// encoding of the result, and the clients decoding it, expect the 16
+	// bytes of a UUID
+	id, err := generateUUID(c.entropy)
+	if err != nil {
+		c.logger.Error("core: root token generation failed", "error", err)
+		return nil, err
+	}
+	te, err := c.tokenStore.rootTokenWithID(id)
 	if err != nil {
 		c.logger.Error("core: root token generation failed", "error", err)
 		return nil, err
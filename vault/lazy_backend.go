@@ -0,0 +1,87 @@
+package vault
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	log "github.com/mgutz/logxi/v1"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// lazyInitBackend wraps a backend whose Initialize call is deferred until it
+// handles its first request, so that a backend that is slow to initialize,
+// for instance because a server it connects to is unreachable, does not slow
+// down unsealing or becoming the active node. A failed initialization is
+// retried on the next request.
+type lazyInitBackend struct {
+	logical.Backend
+
+	path   string
+	logger log.Logger
+
+	initialized uint32
+	l           sync.Mutex
+}
+
+// initializeBackend initializes the backend created for the given entry, or
+// wraps it so that it is initialized on first use if the entry asks for it.
+func (c *Core) initializeBackend(entry *MountEntry, backend logical.Backend) (logical.Backend, error) {
+	if !entry.Config.LazyInit {
+		if err := backend.Initialize(); err != nil {
+			return nil, err
+		}
+		return backend, nil
+	}
+
+	return &lazyInitBackend{
+		Backend: backend,
+		path:    entry.Path,
+		logger:  c.logger,
+	}, nil
+}
+
+// Initialize is a no-op; the wrapped backend is initialized by the first
+// request it handles
+func (b *lazyInitBackend) Initialize() error {
+	return nil
+}
+
+func (b *lazyInitBackend) ensureInitialized() error {
+	if atomic.LoadUint32(&b.initialized) == 1 {
+		return nil
+	}
+
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if b.initialized == 1 {
+		return nil
+	}
+
+	if err := b.Backend.Initialize(); err != nil {
+		b.logger.Error("core: failed to initialize backend", "path", b.path, "error", err)
+		return fmt.Errorf("failed to initialize backend: %v", err)
+	}
+	if b.logger.IsDebug() {
+		b.logger.Debug("core: initialized backend on first use", "path", b.path)
+	}
+
+	atomic.StoreUint32(&b.initialized, 1)
+	return nil
+}
+
+func (b *lazyInitBackend) HandleRequest(req *logical.Request) (*logical.Response, error) {
+	if err := b.ensureInitialized(); err != nil {
+		return nil, err
+	}
+	return b.Backend.HandleRequest(req)
+}
+
+func (b *lazyInitBackend) HandleExistenceCheck(req *logical.Request) (bool, bool, error) {
+	if err := b.ensureInitialized(); err != nil {
+		return false, false, err
+	}
+	return b.Backend.HandleExistenceCheck(req)
+}
@@ -0,0 +1,97 @@
+package vault
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// initCountingBackend counts the calls to Initialize, which fail while err
+// is set
+type initCountingBackend struct {
+	NoopBackend
+	inits int
+	err   error
+}
+
+func (b *initCountingBackend) Initialize() error {
+	b.inits++
+	return b.err
+}
+
+func TestCore_EnableCredential_LazyInit(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	backend := &initCountingBackend{
+		err: fmt.Errorf("server unreachable"),
+	}
+	c.credentialBackends["noop"] = func(*logical.BackendConfig) (logical.Backend, error) {
+		return backend, nil
+	}
+
+	me := &MountEntry{
+		Table: credentialTableType,
+		Path:  "foo",
+		Type:  "noop",
+		Config: MountConfig{
+			LazyInit: true,
+		},
+	}
+	if err := c.enableCredential(me); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if backend.inits != 0 {
+		t.Fatalf("backend initialized on mount")
+	}
+
+	// A failed initialization fails the request and is retried
+	req := logical.TestRequest(t, logical.ReadOperation, "auth/foo/bar")
+	if _, err := c.router.Route(req); err == nil {
+		t.Fatalf("expected error")
+	}
+	if backend.inits != 1 {
+		t.Fatalf("bad: %d", backend.inits)
+	}
+
+	backend.err = nil
+	for i := 0; i < 2; i++ {
+		req = logical.TestRequest(t, logical.ReadOperation, "auth/foo/bar")
+		if _, err := c.router.Route(req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if backend.inits != 2 {
+		t.Fatalf("bad: %d", backend.inits)
+	}
+	if len(backend.Paths) != 2 {
+		t.Fatalf("bad: %v", backend.Paths)
+	}
+}
+
+func TestSystemBackend_enableAuth_lazyInit(t *testing.T) {
+	c, b, _ := testCoreSystemBackend(t)
+	backend := &initCountingBackend{}
+	c.credentialBackends["noop"] = func(*logical.BackendConfig) (logical.Backend, error) {
+		return backend, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/foo")
+	req.Data["type"] = "noop"
+	req.Data["lazy_init"] = true
+	if _, err := b.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if backend.inits != 0 {
+		t.Fatalf("backend initialized on mount")
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "auth")
+	resp, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	config := resp.Data["foo/"].(map[string]interface{})["config"].(map[string]interface{})
+	if config["lazy_init"] != true {
+		t.Fatalf("bad: %#v", config)
+	}
+}
@@ -1,14 +1,32 @@
 package vault
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/locksutil"
 	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/secretcheck"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	// passthroughDeletionIndexPrefix is the reserved prefix under which the
+	// deletion times of the entries written with delete_after are indexed,
+	// so that they can be loaded without reading every entry of the mount
+	passthroughDeletionIndexPrefix = ".deletion-index/"
+
+	// passthroughMetadataPrefix is the reserved prefix under which the
+	// metadata of the entries is stored, apart from their data
+	passthroughMetadataPrefix = ".metadata/"
 )
 
 // PassthroughBackendFactory returns a PassthroughBackend
@@ -28,9 +46,15 @@ func LeasedPassthroughBackendFactory(conf *logical.BackendConfig) (logical.Backe
 func LeaseSwitchedPassthroughBackend(conf *logical.BackendConfig, leases bool) (logical.Backend, error) {
 	var b PassthroughBackend
 	b.generateLeases = leases
+	b.locks = locksutil.CreateLocks()
+	b.deletionTimes = make(map[string]time.Time)
+	b.staleDeletionTimes = make(map[string]struct{})
 	b.Backend = &framework.Backend{
 		Help: strings.TrimSpace(passthroughHelp),
 
+		PeriodicFunc: b.expireEntries,
+		Invalidate:   b.invalidate,
+
 		Paths: []*framework.Path{
 			&framework.Path{
 				Pattern: ".*",
@@ -39,6 +63,7 @@ func LeaseSwitchedPassthroughBackend(conf *logical.BackendConfig, leases bool) (
 					logical.ReadOperation:   b.handleRead,
 					logical.CreateOperation: b.handleWrite,
 					logical.UpdateOperation: b.handleWrite,
+					logical.PatchOperation:  b.handlePatch,
 					logical.DeleteOperation: b.handleDelete,
 					logical.ListOperation:   b.handleList,
 				},
@@ -63,6 +88,24 @@ func LeaseSwitchedPassthroughBackend(conf *logical.BackendConfig, leases bool) (
 	if conf == nil {
 		return nil, fmt.Errorf("Configuation passed into backend is nil")
 	}
+
+	var validators []string
+	if conf.Config["validators"] != "" {
+		validators = strings.Split(conf.Config["validators"], ",")
+	}
+	checker, err := secretcheck.NewChecker(validators, conf.Config["deny_pattern"])
+	if err != nil {
+		return nil, err
+	}
+	b.checker = checker
+
+	if conf.Config["cas_required"] != "" {
+		b.casRequired, err = strconv.ParseBool(conf.Config["cas_required"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cas_required value: %v", err)
+		}
+	}
+
 	b.Backend.Setup(conf)
 
 	return &b, nil
@@ -75,6 +118,404 @@ func LeaseSwitchedPassthroughBackend(conf *logical.BackendConfig, leases bool) (
 type PassthroughBackend struct {
 	*framework.Backend
 	generateLeases bool
+
+	// checker rejects writes of values that match the validators configured
+	// through the mount's options
+	checker *secretcheck.Checker
+
+	// casRequired rejects writes that do not give the version of the secret
+	// they replace
+	casRequired bool
+
+	// locks serializes writes and deletes of a given key, both with each
+	// other for write-once checks and with the deletion of the key once it
+	// has expired
+	locks []*locksutil.LockEntry
+
+	// deletionTimes tracks the keys that were written with a delete_after
+	// value. Writes keep it up to date; the deletion index is merged into it
+	// on the first run of the periodic function, and the keys whose index
+	// entries were modified by another node are listed in
+	// staleDeletionTimes until the next run re-reads them.
+	deletionTimes       map[string]time.Time
+	deletionTimesLoaded bool
+	staleDeletionTimes  map[string]struct{}
+	deletionTimesLock   sync.Mutex
+}
+
+// passthroughDeletionIndexEntry is stored in the deletion index for every
+// entry that was written with a delete_after value
+type passthroughDeletionIndexEntry struct {
+	DeletionTime time.Time `json:"deletion_time"`
+}
+
+// passthroughEntryMetadata is stored under the metadata prefix for the
+// entries that have any, so that the data of an entry is only what its
+// writer gave
+type passthroughEntryMetadata struct {
+	// DeletionTime is set for entries written with a delete_after option
+	DeletionTime time.Time `json:"deletion_time"`
+
+	// CASVersion is the check-and-set version of the entry, recorded once it
+	// is written with check-and-set
+	CASVersion int `json:"cas_version,omitempty"`
+
+	// WriteOnce is set for entries written with the write_once option. It is
+	// kept once the entry is deleted, so that the path cannot be written
+	// again.
+	WriteOnce bool `json:"write_once,omitempty"`
+}
+
+// isZero returns true if there is no metadata to store
+func (m *passthroughEntryMetadata) isZero() bool {
+	return m.DeletionTime.IsZero() && m.CASVersion == 0 && !m.WriteOnce
+}
+
+// tombstone returns the metadata kept once the entry is deleted
+func (m *passthroughEntryMetadata) tombstone() *passthroughEntryMetadata {
+	return &passthroughEntryMetadata{
+		WriteOnce: m.WriteOnce,
+	}
+}
+
+// responseData returns the metadata as returned on reads of metadata
+func (m *passthroughEntryMetadata) responseData() map[string]interface{} {
+	data := map[string]interface{}{
+		"deletion_time": "",
+		"cas_version":   m.CASVersion,
+		"write_once":    m.WriteOnce,
+	}
+	if !m.DeletionTime.IsZero() {
+		data["deletion_time"] = m.DeletionTime.Format(time.RFC3339Nano)
+	}
+	return data
+}
+
+// passthroughWriteOptions are the controls of a write, given in its options
+// field so that they are never mistaken for the data of the secret
+type passthroughWriteOptions struct {
+	deleteAfter time.Duration
+	writeOnce   bool
+
+	// cas is the version the entry must be at for the write to succeed, or
+	// -1 if not given
+	cas int
+}
+
+// parsePassthroughWriteOptions parses the options field of a write, which is
+// an object if given
+func parsePassthroughWriteOptions(raw interface{}) (*passthroughWriteOptions, error) {
+	opts := &passthroughWriteOptions{
+		cas: -1,
+	}
+	if raw == nil {
+		return opts, nil
+	}
+	options, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("options must be an object")
+	}
+
+	for name, value := range options {
+		switch name {
+		case "delete_after":
+			deleteAfter, err := parseutil.ParseDurationSecond(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid delete_after: %v", err)
+			}
+			if deleteAfter <= 0 {
+				return nil, fmt.Errorf("delete_after must be greater than zero")
+			}
+			opts.deleteAfter = deleteAfter
+		case "write_once":
+			writeOnce, err := parseutil.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid write_once: %v", err)
+			}
+			opts.writeOnce = writeOnce
+		case "cas":
+			if err := mapstructure.WeakDecode(value, &opts.cas); err != nil {
+				return nil, fmt.Errorf("invalid cas: %v", err)
+			}
+			if opts.cas < 0 {
+				return nil, fmt.Errorf("cas cannot be negative")
+			}
+		default:
+			return nil, fmt.Errorf("unknown option %q", name)
+		}
+	}
+	return opts, nil
+}
+
+// passthroughReservedPath returns true if the path is part of the deletion
+// index or of the metadata, which cannot be accessed through requests
+func passthroughReservedPath(path string) bool {
+	return strings.HasPrefix(path+"/", passthroughDeletionIndexPrefix) ||
+		strings.HasPrefix(path+"/", passthroughMetadataPrefix)
+}
+
+// deletionIndexKey returns the key of the index entry of the given key. Keys
+// are encoded so that the index can be loaded with a single list.
+func deletionIndexKey(key string) string {
+	return passthroughDeletionIndexPrefix + base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// metadataKey returns the key of the metadata of the given key
+func metadataKey(key string) string {
+	return passthroughMetadataPrefix + base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// setDeletionTime updates the tracked deletion time of the given key; a zero
+// deletion time stops tracking the key
+func (b *PassthroughBackend) setDeletionTime(key string, deletionTime time.Time) {
+	b.deletionTimesLock.Lock()
+	defer b.deletionTimesLock.Unlock()
+
+	if deletionTime.IsZero() {
+		delete(b.deletionTimes, key)
+		return
+	}
+	b.deletionTimes[key] = deletionTime
+}
+
+func (b *PassthroughBackend) invalidate(key string) {
+	// Another node modified the deletion index; re-read the modified entry
+	// on the next periodic run
+	if !strings.HasPrefix(key, passthroughDeletionIndexPrefix) {
+		return
+	}
+	name, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(key, passthroughDeletionIndexPrefix))
+	if err != nil {
+		return
+	}
+
+	b.deletionTimesLock.Lock()
+	b.staleDeletionTimes[string(name)] = struct{}{}
+	b.deletionTimesLock.Unlock()
+}
+
+// loadDeletionTimes reads the deletion index
+func (b *PassthroughBackend) loadDeletionTimes(s logical.Storage) (map[string]time.Time, error) {
+	names, err := s.List(passthroughDeletionIndexPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	deletionTimes := make(map[string]time.Time, len(names))
+	for _, name := range names {
+		key, err := base64.RawURLEncoding.DecodeString(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deletion index entry %q", name)
+		}
+		deletionTime, err := b.readDeletionIndex(s, string(key))
+		if err != nil {
+			return nil, err
+		}
+		if !deletionTime.IsZero() {
+			deletionTimes[string(key)] = deletionTime
+		}
+	}
+
+	return deletionTimes, nil
+}
+
+// readDeletionIndex returns the deletion time indexed for the given key, or
+// the zero time if the key is not indexed
+func (b *PassthroughBackend) readDeletionIndex(s logical.Storage, key string) (time.Time, error) {
+	out, err := s.Get(deletionIndexKey(key))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read deletion index: %v", err)
+	}
+	if out == nil {
+		return time.Time{}, nil
+	}
+
+	var indexEntry passthroughDeletionIndexEntry
+	if err := out.DecodeJSON(&indexEntry); err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode deletion index: %v", err)
+	}
+	return indexEntry.DeletionTime, nil
+}
+
+// writeDeletionIndex indexes the deletion time of the given key; a zero
+// deletion time removes the key from the index
+func (b *PassthroughBackend) writeDeletionIndex(s logical.Storage, key string, deletionTime time.Time) error {
+	if deletionTime.IsZero() {
+		if err := s.Delete(deletionIndexKey(key)); err != nil {
+			return fmt.Errorf("failed to update deletion index: %v", err)
+		}
+		return nil
+	}
+
+	entry, err := logical.StorageEntryJSON(deletionIndexKey(key), &passthroughDeletionIndexEntry{
+		DeletionTime: deletionTime,
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.Put(entry); err != nil {
+		return fmt.Errorf("failed to update deletion index: %v", err)
+	}
+	return nil
+}
+
+// readMetadata returns the metadata of the given key, which is empty if
+// none is stored
+func (b *PassthroughBackend) readMetadata(s logical.Storage, key string) (*passthroughEntryMetadata, error) {
+	metadata := &passthroughEntryMetadata{}
+	out, err := s.Get(metadataKey(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %v", err)
+	}
+	if out == nil {
+		return metadata, nil
+	}
+	if err := out.DecodeJSON(metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %v", err)
+	}
+	return metadata, nil
+}
+
+// writeMetadata stores the metadata of the given key, or removes it if it is
+// empty
+func (b *PassthroughBackend) writeMetadata(s logical.Storage, key string, metadata *passthroughEntryMetadata) error {
+	if metadata.isZero() {
+		if err := s.Delete(metadataKey(key)); err != nil {
+			return fmt.Errorf("failed to delete metadata: %v", err)
+		}
+		return nil
+	}
+
+	entry, err := logical.StorageEntryJSON(metadataKey(key), metadata)
+	if err != nil {
+		return err
+	}
+	if err := s.Put(entry); err != nil {
+		return fmt.Errorf("failed to write metadata: %v", err)
+	}
+	return nil
+}
+
+// readLiveEntry returns the decoded data stored at the given key and its
+// metadata. The data is nil and the metadata only its tombstone if there is
+// no entry or the entry is past its deletion time.
+func (b *PassthroughBackend) readLiveEntry(s logical.Storage, key string) (map[string]interface{}, *passthroughEntryMetadata, error) {
+	out, err := s.Get(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read failed: %v", err)
+	}
+
+	metadata, err := b.readMetadata(s, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if out == nil {
+		return nil, metadata.tombstone(), nil
+	}
+	if !metadata.DeletionTime.IsZero() && !time.Now().Before(metadata.DeletionTime) {
+		return nil, metadata.tombstone(), nil
+	}
+
+	var rawData map[string]interface{}
+	if err := jsonutil.DecodeJSON(out.Value, &rawData); err != nil {
+		return nil, nil, fmt.Errorf("json decoding failed: %v", err)
+	}
+
+	return rawData, metadata, nil
+}
+
+// expireEntries is invoked periodically and deletes every entry whose
+// deletion time has passed
+func (b *PassthroughBackend) expireEntries(req *logical.Request) error {
+	// Storage is read without holding the lock so that writes are not held
+	// up. Deletion times tracked in the meantime take precedence over the
+	// ones read; any of them that is stale is corrected by expireEntry.
+	b.deletionTimesLock.Lock()
+	loaded := b.deletionTimesLoaded
+	stale := b.staleDeletionTimes
+	b.staleDeletionTimes = make(map[string]struct{})
+	b.deletionTimesLock.Unlock()
+
+	if !loaded {
+		deletionTimes, err := b.loadDeletionTimes(req.Storage)
+		if err != nil {
+			return err
+		}
+		b.deletionTimesLock.Lock()
+		for key, deletionTime := range deletionTimes {
+			if _, ok := b.deletionTimes[key]; !ok {
+				b.deletionTimes[key] = deletionTime
+			}
+		}
+		b.deletionTimesLoaded = true
+		b.deletionTimesLock.Unlock()
+	}
+
+	for key := range stale {
+		deletionTime, err := b.readDeletionIndex(req.Storage, key)
+		if err != nil {
+			return err
+		}
+		b.setDeletionTime(key, deletionTime)
+	}
+
+	now := time.Now()
+	var expired []string
+	b.deletionTimesLock.Lock()
+	for key, deletionTime := range b.deletionTimes {
+		if !now.Before(deletionTime) {
+			expired = append(expired, key)
+		}
+	}
+	b.deletionTimesLock.Unlock()
+
+	for _, key := range expired {
+		if err := b.expireEntry(req.Storage, key, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *PassthroughBackend) expireEntry(s logical.Storage, key string, now time.Time) error {
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// The entry may have been rewritten since it was tracked, so check the
+	// stored deletion time before deleting it
+	metadata, err := b.readMetadata(s, key)
+	if err != nil {
+		return err
+	}
+	deletionTime := metadata.DeletionTime
+	if deletionTime.IsZero() {
+		// The entry was deleted or rewritten without a deletion time; only
+		// its index entry may be left
+		b.setDeletionTime(key, time.Time{})
+		return b.writeDeletionIndex(s, key, time.Time{})
+	}
+	if now.Before(deletionTime) {
+		b.setDeletionTime(key, deletionTime)
+		return nil
+	}
+
+	if err := s.Delete(key); err != nil {
+		return err
+	}
+	if err := b.writeMetadata(s, key, metadata.tombstone()); err != nil {
+		return err
+	}
+	if err := b.writeDeletionIndex(s, key, time.Time{}); err != nil {
+		return err
+	}
+	b.setDeletionTime(key, time.Time{})
+
+	if b.Logger().IsDebug() {
+		b.Logger().Debug("generic: deleted expired entry", "key", key)
+	}
+	return nil
 }
 
 func (b *PassthroughBackend) handleRevoke(
@@ -95,23 +536,36 @@ func (b *PassthroughBackend) handleExistenceCheck(
 
 func (b *PassthroughBackend) handleRead(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	// Read the path
-	out, err := req.Storage.Get(req.Path)
+	if passthroughReservedPath(req.Path) {
+		return logical.ErrorResponse("path is reserved"), logical.ErrInvalidRequest
+	}
+
+	// Read the path; entries past their deletion time are treated as
+	// deleted, even if they have not been cleaned up yet
+	rawData, metadata, err := b.readLiveEntry(req.Storage, req.Path)
 	if err != nil {
-		return nil, fmt.Errorf("read failed: %v", err)
+		return nil, err
 	}
 
 	// Fast-path the no data case
-	if out == nil {
+	if rawData == nil {
 		return nil, nil
 	}
 
-	// Decode the data
-	var rawData map[string]interface{}
-
-	if err := jsonutil.DecodeJSON(out.Value, &rawData); err != nil {
-		return nil, fmt.Errorf("json decoding failed: %v", err)
+	// Reads with the metadata parameter return the metadata of the secret
+	// instead of its data
+	if req.Operation == logical.ReadOperation && req.Data["metadata"] != nil {
+		metadataOnly, err := parseutil.ParseBool(req.Data["metadata"])
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid metadata: %v", err)), logical.ErrInvalidRequest
+		}
+		if metadataOnly {
+			return &logical.Response{
+				Data: metadata.responseData(),
+			}, nil
+		}
 	}
+	deletionTime := metadata.DeletionTime
 
 	var resp *logical.Response
 	if b.generateLeases {
@@ -142,6 +596,13 @@ func (b *PassthroughBackend) handleRead(
 		}
 	}
 
+	// Never hint that the secret can be used past its deletion
+	if !deletionTime.IsZero() {
+		if remaining := deletionTime.Sub(time.Now()); remaining < ttlDuration {
+			ttlDuration = remaining
+		}
+	}
+
 	resp.Secret.TTL = ttlDuration
 
 	return resp, nil
@@ -153,17 +614,132 @@ func (b *PassthroughBackend) GeneratesLeases() bool {
 
 func (b *PassthroughBackend) handleWrite(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.writeEntry(req, false)
+}
+
+func (b *PassthroughBackend) handlePatch(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.writeEntry(req, true)
+}
+
+// writeEntry writes the data of the request at its path. If patch is set, the
+// data is merged into the existing secret instead of replacing it, under the
+// same lock so that concurrent patches of different fields are all applied.
+func (b *PassthroughBackend) writeEntry(req *logical.Request, patch bool) (*logical.Response, error) {
+	if passthroughReservedPath(req.Path) {
+		return logical.ErrorResponse("path is reserved"), logical.ErrInvalidRequest
+	}
+
 	// Check that some fields are given
 	if len(req.Data) == 0 {
 		return logical.ErrorResponse("missing data fields"), nil
 	}
 
+	// The options of the write are not part of the secret
+	opts, err := parsePassthroughWriteOptions(req.Data["options"])
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	input := make(map[string]interface{}, len(req.Data))
+	for k, v := range req.Data {
+		if k != "options" {
+			input[k] = v
+		}
+	}
+
+	lock := locksutil.LockForKey(b.locks, req.Path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	existing, existingMetadata, err := b.readLiveEntry(req.Storage, req.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch {
+		if existing == nil {
+			return logical.ErrorResponse("no secret to patch at this path"), logical.ErrInvalidRequest
+		}
+		input = passthroughMergePatch(existing, input)
+	}
+
+	if err := b.checker.Check(input); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	if len(input) == 0 {
+		return logical.ErrorResponse("missing data fields"), nil
+	}
+
+	cas := opts.cas
+	if cas < 0 && b.casRequired {
+		return logical.ErrorResponse("check-and-set parameter required for this call"), logical.ErrInvalidRequest
+	}
+
+	// Patches without a delete_after option keep the deletion time of the
+	// secret
+	metadata := &passthroughEntryMetadata{}
+	switch {
+	case opts.deleteAfter > 0:
+		metadata.DeletionTime = time.Now().UTC().Add(opts.deleteAfter)
+	case patch:
+		metadata.DeletionTime = existingMetadata.DeletionTime
+	}
+	deletionTime := metadata.DeletionTime
+
+	// Write-once entries can never be updated, nor written again once
+	// deleted, and an entry can only be made write-once when it is first
+	// created
+	if existingMetadata.WriteOnce {
+		return logical.ErrorResponse("the secret is write-once and cannot be updated"), logical.ErrInvalidRequest
+	}
+	if existing != nil && opts.writeOnce {
+		return logical.ErrorResponse("write_once can only be set when the secret is created"), logical.ErrInvalidRequest
+	}
+	metadata.WriteOnce = opts.writeOnce
+
+	// The check-and-set version is only recorded once a secret is written
+	// with check-and-set, so that it does not show up in other secrets; from
+	// then on every write increments it
+	version := existingMetadata.CASVersion
+	if cas >= 0 && cas != version {
+		return logical.ErrorResponse(fmt.Sprintf("check-and-set parameter did not match the current version %d", version)), logical.ErrInvalidRequest
+	}
+	var resp *logical.Response
+	if cas >= 0 || b.casRequired || version > 0 {
+		metadata.CASVersion = version + 1
+		resp = &logical.Response{
+			Data: map[string]interface{}{
+				"cas_version": version + 1,
+			},
+		}
+	}
+	if !deletionTime.IsZero() {
+		if resp == nil {
+			resp = &logical.Response{
+				Data: map[string]interface{}{},
+			}
+		}
+		resp.Data["deletion_time"] = deletionTime.Format(time.RFC3339Nano)
+	}
+
 	// JSON encode the data
-	buf, err := json.Marshal(req.Data)
+	buf, err := json.Marshal(input)
 	if err != nil {
 		return nil, fmt.Errorf("json encoding failed: %v", err)
 	}
 
+	// Index the deletion time and write the metadata before writing the
+	// entry, so that an entry with a deletion time is always indexed
+	if !deletionTime.IsZero() {
+		if err := b.writeDeletionIndex(req.Storage, req.Path, deletionTime); err != nil {
+			return nil, err
+		}
+	}
+	if err := b.writeMetadata(req.Storage, req.Path, metadata); err != nil {
+		return nil, err
+	}
+
 	// Write out a new key
 	entry := &logical.StorageEntry{
 		Key:   req.Path,
@@ -173,15 +749,65 @@ func (b *PassthroughBackend) handleWrite(
 		return nil, fmt.Errorf("failed to write: %v", err)
 	}
 
-	return nil, nil
+	if deletionTime.IsZero() && !existingMetadata.DeletionTime.IsZero() {
+		if err := b.writeDeletionIndex(req.Storage, req.Path, time.Time{}); err != nil {
+			return nil, err
+		}
+	}
+	b.setDeletionTime(req.Path, deletionTime)
+
+	return resp, nil
+}
+
+// passthroughMergePatch returns the data with the patch applied following
+// JSON merge patch (RFC 7396): null values remove the field and objects are
+// merged recursively, anything else replaces the field
+func passthroughMergePatch(data, patch map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(data)+len(patch))
+	for k, v := range data {
+		merged[k] = v
+	}
+	for k, v := range patch {
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		if patchMap, ok := v.(map[string]interface{}); ok {
+			dataMap, _ := merged[k].(map[string]interface{})
+			merged[k] = passthroughMergePatch(dataMap, patchMap)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
 }
 
 func (b *PassthroughBackend) handleDelete(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	// Delete the key at the request path
+	if passthroughReservedPath(req.Path) {
+		return logical.ErrorResponse("path is reserved"), logical.ErrInvalidRequest
+	}
+
+	lock := locksutil.LockForKey(b.locks, req.Path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Delete the key at the request path. The metadata of write-once entries
+	// is kept as a tombstone so that the path cannot be written again.
+	metadata, err := b.readMetadata(req.Storage, req.Path)
+	if err != nil {
+		return nil, err
+	}
 	if err := req.Storage.Delete(req.Path); err != nil {
 		return nil, err
 	}
+	if err := b.writeMetadata(req.Storage, req.Path, metadata.tombstone()); err != nil {
+		return nil, err
+	}
+	if err := b.writeDeletionIndex(req.Storage, req.Path, time.Time{}); err != nil {
+		return nil, err
+	}
+	b.setDeletionTime(req.Path, time.Time{})
 
 	return nil, nil
 }
@@ -195,6 +821,9 @@ func (b *PassthroughBackend) handleList(
 	if path != "" && !strings.HasSuffix(path, "/") {
 		path = path + "/"
 	}
+	if passthroughReservedPath(path) {
+		return logical.ErrorResponse("path is reserved"), logical.ErrInvalidRequest
+	}
 
 	// List the keys at the prefix given by the request
 	keys, err := req.Storage.List(path)
@@ -202,6 +831,17 @@ func (b *PassthroughBackend) handleList(
 		return nil, err
 	}
 
+	// Hide the deletion index and the metadata
+	if path == "" {
+		visible := keys[:0]
+		for _, key := range keys {
+			if key != passthroughDeletionIndexPrefix && key != passthroughMetadataPrefix {
+				visible = append(visible, key)
+			}
+		}
+		keys = visible
+	}
+
 	// Generate the response
 	return logical.ListResponse(keys), nil
 }
@@ -215,6 +855,12 @@ see the unencrypted value.
 TTLs can be set on a per-secret basis. These TTLs will be sent down
 when that secret is read, and it is assumed that some outside process will
 revoke and/or replace the secret at that path.
+
+Secrets can also be written with a "delete_after" option, in which case
+Vault deletes them itself once that duration has passed, and can be made
+write-once with a "write_once" option so that they can never be replaced. Writes given
+a "cas" option only succeed if the secret is still at that version, and
+patches update some fields of a secret while keeping the others.
 `
 
 const passthroughHelpSynopsis = `
@@ -232,4 +878,38 @@ can be used as a hint from the writer of a secret to the consumer of a secret
 that the consumer should re-read the value before the TTL has expired.
 However, any revocation must be handled by the user of this backend; the lease
 duration does not affect the provided data in any way.
+
+Controls of a write are given in its "options" object, which is not stored
+with the secret; every other field is stored as given.
+
+A "delete_after" duration can be given in the options when writing to have the
+secret removed automatically. The computed deletion time is returned in the
+"deletion_time" field of the write response, and when the secret is read with
+"metadata=true"; once it has passed, the secret can no longer be read and is
+deleted from storage shortly after. Metadata and deletion times are kept under
+the reserved ".metadata/" and ".deletion-index/" paths, which cannot be
+accessed through requests.
+
+If the "write_once" option is set to true when a secret is created, any later
+write to the same path is rejected. The secret can still be deleted, but the
+path cannot be written again afterwards. Setting "write_once" on a path that
+already holds a secret is rejected as well.
+
+Writes can be checked against the formats of well-known credentials by
+setting the "validators" mount option to a comma-separated list of validator
+names, and against an arbitrary regular expression by setting the
+"deny_pattern" mount option. Any string value that matches is rejected.
+
+Concurrent writers can avoid overwriting each other's changes with
+check-and-set: a write with the "cas" option set only succeeds if the version
+of the secret is still that value, 0 meaning that the secret has never been
+written with check-and-set or does not exist. Such a write records the new
+version in the metadata of the secret and returns it in "cas_version"; every
+later write increments it. Setting the "cas_required" mount option to true
+rejects writes that do not set the "cas" option.
+
+A patch request updates a secret following JSON merge patch: the given fields
+replace those of the secret, fields given as null are removed and the other
+fields are kept, without the caller having to read them. Patching a path that
+holds no secret is rejected.
 `
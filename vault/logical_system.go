@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -12,6 +14,7 @@ import (
 	"github.com/fatih/structs"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/helper/wrapping"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -63,9 +66,19 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				"replication/primary/secondary-token",
 				"replication/reindex",
 				"rotate",
+				"rotate/root",
+				"rotate/rewrap",
 				"config/cors",
+				"monitor",
+				"pprof/*",
+				"config/token-limits",
+				"config/experiments",
 				"config/auditing/*",
+				"control-group/*",
+				"mfa/*",
+				"canary/*",
 				"plugins/catalog/*",
+				"plugins/reload/backend",
 				"revoke-prefix/*",
 				"revoke-force/*",
 				"leases/revoke-prefix/*",
@@ -76,6 +89,7 @@ func NewSystemBackend(core *Core) *SystemBackend {
 			Unauthenticated: []string{
 				"wrapping/pubkey",
 				"replication/status",
+				"internal/ui/mounts",
 			},
 		},
 
@@ -114,6 +128,10 @@ func NewSystemBackend(core *Core) *SystemBackend {
 						Type:        framework.TypeCommaStringSlice,
 						Description: "A comma-separated string or array of strings indicating origins that may make cross-origin requests.",
 					},
+					"allowed_headers": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: "A comma-separated string or array of strings indicating headers that cross-origin requests may set, in addition to the standard ones.",
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -126,6 +144,348 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpSynopsis:    strings.TrimSpace(sysHelp["config/cors"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "config/token-limits$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"max_tokens_per_entity": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "Maximum number of active tokens of an entity, including the tokens created with its tokens. Zero means unlimited.",
+					},
+					"max_tokens_per_alias": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "Maximum number of active tokens an entity obtained by logging in. Zero means unlimited.",
+					},
+					"overrides": &framework.FieldSchema{
+						Type:        framework.TypeMap,
+						Description: "Map of entities to the limit replacing both limits for them. Zero exempts the entity.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleTokenLimitsRead,
+					logical.UpdateOperation: b.handleTokenLimitsUpdate,
+					logical.DeleteOperation: b.handleTokenLimitsDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["config/token-limits"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["config/token-limits"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "config/experiments$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"enabled": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: "The experimental subsystems to enable. Those not listed are disabled.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleExperimentsRead,
+					logical.UpdateOperation: b.handleExperimentsUpdate,
+					logical.DeleteOperation: b.handleExperimentsDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["config/experiments"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["config/experiments"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "control-group/?$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: b.handleControlGroupList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["control-group"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["control-group"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "control-group/(?P<id>[^/]+)/approve$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"id": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "ID of the control group request.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleControlGroupApprove,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["control-group/approve"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["control-group/approve"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "control-group/(?P<id>[^/]+)$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"id": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "ID of the control group request.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleControlGroupRead,
+					logical.DeleteOperation: b.handleControlGroupDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["control-group"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["control-group"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "mfa/method/?$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: b.handleMFAMethodList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["mfa/method"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["mfa/method"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "mfa/method/(?P<name>[^/]+)$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Name of the MFA method.",
+					},
+					"type": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Type of the MFA method: totp, duo or webhook.",
+					},
+					"issuer": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "TOTP: issuer shown in authenticator apps.",
+					},
+					"period": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Default:     30,
+						Description: "TOTP: number of seconds a passcode is valid for.",
+					},
+					"digits": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Default:     6,
+						Description: "TOTP: number of digits of the passcodes, 6 or 8.",
+					},
+					"algorithm": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Default:     "SHA1",
+						Description: "TOTP: hash algorithm, SHA1, SHA256 or SHA512.",
+					},
+					"skew": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Default:     1,
+						Description: "TOTP: number of periods before and after the current one whose passcodes are accepted.",
+					},
+					"integration_key": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Duo: integration key of the Auth API application.",
+					},
+					"secret_key": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Duo: secret key of the Auth API application.",
+					},
+					"api_hostname": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Duo: API hostname of the Auth API application.",
+					},
+					"push_info": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Duo: URL-encoded key/value pairs shown in the Duo Mobile app on pushes.",
+					},
+					"username_format": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Duo: format string given the username to create the Duo username (default '%s').",
+					},
+					"url": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Webhook: URL approving requests by answering with a 2xx status.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleMFAMethodRead,
+					logical.UpdateOperation: b.handleMFAMethodUpdate,
+					logical.DeleteOperation: b.handleMFAMethodDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["mfa/method"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["mfa/method"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "mfa/method/(?P<name>[^/]+)/enrollment/?$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Name of the MFA method.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: b.handleMFAEnrollmentList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["mfa/enrollment"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["mfa/enrollment"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "mfa/method/(?P<name>[^/]+)/enrollment/(?P<entity>.+)$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Name of the MFA method.",
+					},
+					"entity": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Entity to enroll, such as \"userpass/bob\".",
+					},
+					"username": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Duo: username replacing the name of the entity.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleMFAEnrollmentRead,
+					logical.UpdateOperation: b.handleMFAEnrollmentUpdate,
+					logical.DeleteOperation: b.handleMFAEnrollmentDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["mfa/enrollment"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["mfa/enrollment"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "mfa/enforcement/?$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: b.handleMFAEnforcementList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["mfa/enforcement"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["mfa/enforcement"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "mfa/enforcement/(?P<name>[^/]+)$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Name of the MFA enforcement.",
+					},
+					"methods": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: "MFA methods satisfying the enforcement.",
+					},
+					"auth_mounts": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: "Paths of the auth mounts whose logins require MFA, such as \"userpass\".",
+					},
+					"paths": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: "Request paths requiring MFA; a trailing \"*\" matches any suffix.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleMFAEnforcementRead,
+					logical.UpdateOperation: b.handleMFAEnforcementUpdate,
+					logical.DeleteOperation: b.handleMFAEnforcementDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["mfa/enforcement"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["mfa/enforcement"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "canary/hook/?$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: b.handleCanaryHookList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["canary/hook"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["canary/hook"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "canary/hook/(?P<name>[^/]+)$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Name of the canary hook.",
+					},
+					"url": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "URL posted the alerts of the canary tokens using the hook.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleCanaryHookRead,
+					logical.UpdateOperation: b.handleCanaryHookUpdate,
+					logical.DeleteOperation: b.handleCanaryHookDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["canary/hook"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["canary/hook"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "canary/token/?$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: b.handleCanaryTokenList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["canary/token"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["canary/token"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "canary/token/(?P<name>[^/]+)$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Name of the canary token.",
+					},
+					"hooks": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: "Names of the hooks alerted when the canary token is tripped.",
+					},
+					"metadata": &framework.FieldSchema{
+						Type:        framework.TypeMap,
+						Description: "Metadata included in the alerts, such as where the canary token was planted.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleCanaryTokenRead,
+					logical.UpdateOperation: b.handleCanaryTokenUpdate,
+					logical.DeleteOperation: b.handleCanaryTokenDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["canary/token"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["canary/token"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "capabilities$",
 
@@ -225,6 +585,26 @@ func NewSystemBackend(core *Core) *SystemBackend {
 						Type:        framework.TypeString,
 						Description: strings.TrimSpace(sysHelp["tune_max_lease_ttl"][0]),
 					},
+					"listing_visibility": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["listing_visibility"][0]),
+					},
+					"passthrough_request_headers": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: strings.TrimSpace(sysHelp["passthrough_request_headers"][0]),
+					},
+					"audit_non_hmac_request_keys": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: strings.TrimSpace(sysHelp["audit_non_hmac_request_keys"][0]),
+					},
+					"audit_non_hmac_response_keys": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: strings.TrimSpace(sysHelp["audit_non_hmac_response_keys"][0]),
+					},
+					"description": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["tune_description"][0]),
+					},
 				},
 				Callbacks: map[logical.Operation]framework.OperationFunc{
 					logical.ReadOperation:   b.handleAuthTuneRead,
@@ -250,6 +630,26 @@ func NewSystemBackend(core *Core) *SystemBackend {
 						Type:        framework.TypeString,
 						Description: strings.TrimSpace(sysHelp["tune_max_lease_ttl"][0]),
 					},
+					"listing_visibility": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["listing_visibility"][0]),
+					},
+					"passthrough_request_headers": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: strings.TrimSpace(sysHelp["passthrough_request_headers"][0]),
+					},
+					"audit_non_hmac_request_keys": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: strings.TrimSpace(sysHelp["audit_non_hmac_request_keys"][0]),
+					},
+					"audit_non_hmac_response_keys": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: strings.TrimSpace(sysHelp["audit_non_hmac_response_keys"][0]),
+					},
+					"description": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["tune_description"][0]),
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -261,6 +661,62 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["mount_tune"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "mounts/(?P<path>.+?)/export$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["mount_path"][0]),
+					},
+					"key": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["mount_archive_key"][0]),
+					},
+					"include_leases": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: strings.TrimSpace(sysHelp["mount_archive_include_leases"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleMountExport,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["mount_export"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["mount_export"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "mounts/(?P<path>.+?)/import$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["mount_path"][0]),
+					},
+					"archive": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Base64 encoded archive returned by the export endpoint.",
+					},
+					"key": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["mount_archive_key"][0]),
+					},
+					"include_leases": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: strings.TrimSpace(sysHelp["mount_archive_include_leases"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleMountImport,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["mount_import"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["mount_import"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "mounts/(?P<path>.+?)",
 
@@ -281,11 +737,20 @@ func NewSystemBackend(core *Core) *SystemBackend {
 						Type:        framework.TypeMap,
 						Description: strings.TrimSpace(sysHelp["mount_config"][0]),
 					},
+					"options": &framework.FieldSchema{
+						Type:        framework.TypeMap,
+						Description: strings.TrimSpace(sysHelp["mount_options"][0]),
+					},
 					"local": &framework.FieldSchema{
 						Type:        framework.TypeBool,
 						Default:     false,
 						Description: strings.TrimSpace(sysHelp["mount_local"][0]),
 					},
+					"seal_wrap": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Default:     false,
+						Description: strings.TrimSpace(sysHelp["mount_seal_wrap"][0]),
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -300,6 +765,21 @@ func NewSystemBackend(core *Core) *SystemBackend {
 			&framework.Path{
 				Pattern: "mounts$",
 
+				Fields: map[string]*framework.FieldSchema{
+					"detailed": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: strings.TrimSpace(sysHelp["mounts-detailed"][0]),
+					},
+					"after": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["mounts-after"][0]),
+					},
+					"limit": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: strings.TrimSpace(sysHelp["mounts-limit"][0]),
+					},
+				},
+
 				Callbacks: map[logical.Operation]framework.OperationFunc{
 					logical.ReadOperation: b.handleMountTable,
 				},
@@ -308,6 +788,69 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["mounts"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "internal/counters$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleInternalCountersRead,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["internal-counters"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["internal-counters"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "internal/ui/mounts$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleInternalUIMountsRead,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["internal-ui-mounts"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["internal-ui-mounts"][1]),
+			},
+
+			// The log is streamed by the HTTP handler of sys/monitor, this
+			// path only documents it
+			&framework.Path{
+				Pattern: "monitor$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"log_level": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Default:     "info",
+						Description: "Level of the streamed log entries: trace, debug, info, notice, warn or err.",
+					},
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["monitor"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["monitor"][1]),
+			},
+
+			// The profiles are served by the HTTP handler of sys/pprof, this
+			// path only documents them
+			&framework.Path{
+				Pattern: "pprof/(?P<profile>.+)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"profile": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Name of the profile: cpu, trace, or a runtime profile such as heap or goroutine.",
+					},
+					"seconds": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "Duration of the cpu profile and trace, in seconds. Defaults to 30 for cpu and 1 for trace.",
+					},
+					"debug": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "When greater than zero, the runtime profiles are written in text rather than in the binary pprof format.",
+					},
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["pprof"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["pprof"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "remount",
 
@@ -464,6 +1007,21 @@ func NewSystemBackend(core *Core) *SystemBackend {
 			&framework.Path{
 				Pattern: "auth$",
 
+				Fields: map[string]*framework.FieldSchema{
+					"detailed": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: strings.TrimSpace(sysHelp["mounts-detailed"][0]),
+					},
+					"after": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["mounts-after"][0]),
+					},
+					"limit": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: strings.TrimSpace(sysHelp["mounts-limit"][0]),
+					},
+				},
+
 				Callbacks: map[logical.Operation]framework.OperationFunc{
 					logical.ReadOperation: b.handleAuthTable,
 				},
@@ -492,11 +1050,21 @@ func NewSystemBackend(core *Core) *SystemBackend {
 						Type:        framework.TypeString,
 						Description: strings.TrimSpace(sysHelp["auth_plugin"][0]),
 					},
+					"lazy_init": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Default:     false,
+						Description: strings.TrimSpace(sysHelp["auth_lazy_init"][0]),
+					},
 					"local": &framework.FieldSchema{
 						Type:        framework.TypeBool,
 						Default:     false,
 						Description: strings.TrimSpace(sysHelp["mount_local"][0]),
 					},
+					"seal_wrap": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Default:     false,
+						Description: strings.TrimSpace(sysHelp["mount_seal_wrap"][0]),
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -520,6 +1088,37 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["policy-list"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "policy/explain$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Path of the request to explain.",
+					},
+					"operation": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Default:     "read",
+						Description: "Operation of the request to explain: create, read, update, delete or list.",
+					},
+					"data": &framework.FieldSchema{
+						Type:        framework.TypeMap,
+						Description: "Parameters of the request to explain, checked against the allowed and denied parameters of the rules.",
+					},
+					"policies": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: "Names of the policies to evaluate the request against. Defaults to the policies of the token of the request.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handlePolicyExplain,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["policy-explain"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["policy-explain"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "policy/(?P<name>.+)",
 
@@ -544,6 +1143,32 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["policy"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "policies/analyze$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"policies": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: "Names of stored policies to analyze.",
+					},
+					"rules": &framework.FieldSchema{
+						Type:        framework.TypeMap,
+						Description: "Map of policy names to rules to analyze, which take precedence over stored policies of the same name.",
+					},
+					"paths": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: "Paths on which to report the effective capabilities of the policies.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handlePoliciesAnalyze,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["policies-analyze"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["policies-analyze"][1]),
+			},
+
 			&framework.Path{
 				Pattern:         "seal-status$",
 				HelpSynopsis:    strings.TrimSpace(sysHelp["seal-status"][0]),
@@ -584,6 +1209,24 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["audit-hash"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "audit-verify/(?P<path>.+)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["audit_path"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleAuditVerify,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["audit-verify"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["audit-verify"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "audit$",
 
@@ -672,6 +1315,43 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["rotate"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "rotate/rewrap$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"rate": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: strings.TrimSpace(sysHelp["rotate-rewrap-rate"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleRotateRewrapRead,
+					logical.UpdateOperation: b.handleRotateRewrapUpdate,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["rotate-rewrap"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["rotate-rewrap"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "rotate/root$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"accessor": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["rotate-root-accessor"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleRotateRoot,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["rotate-root"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["rotate-root"][1]),
+			},
+
 			/*
 				// Disabled for the moment as we don't support this externally
 				&framework.Path{
@@ -748,6 +1428,47 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["rewrap"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "wrapping/introduce$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"mount": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Default:     "approle",
+						Description: "Path of the AppRole auth backend of the role.",
+					},
+					"role_name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Name of the role.",
+					},
+					"wrap_ttl": &framework.FieldSchema{
+						Type:        framework.TypeDurationSecond,
+						Default:     60,
+						Description: "TTL of the wrapping token of the credentials.",
+					},
+					"num_uses": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Default:     1,
+						Description: "Number of uses of the SecretID. 0 uses the 'secret_id_num_uses' of the role.",
+					},
+					"metadata": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Metadata to be tied to the SecretID, as a JSON formatted string.",
+					},
+					"cidr_list": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Comma separated list of CIDR blocks the SecretID can be used from.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleWrappingIntroduce,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["wrapintroduce"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["wrapintroduce"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "config/auditing/request-headers/(?P<header>.+)",
 
@@ -806,24 +1527,73 @@ func NewSystemBackend(core *Core) *SystemBackend {
 					},
 					"command": &framework.FieldSchema{
 						Type:        framework.TypeString,
-						Description: strings.TrimSpace(sysHelp["plugin-catalog_command"][0]),
+						Description: strings.TrimSpace(sysHelp["plugin-catalog_command"][0]),
+					},
+					"max_memory": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: strings.TrimSpace(sysHelp["plugin-catalog_max-memory"][0]),
+					},
+					"max_restarts": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: strings.TrimSpace(sysHelp["plugin-catalog_max-restarts"][0]),
+					},
+					"restart_window": &framework.FieldSchema{
+						Type:        framework.TypeDurationSecond,
+						Description: strings.TrimSpace(sysHelp["plugin-catalog_restart-window"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handlePluginCatalogUpdate,
+					logical.DeleteOperation: b.handlePluginCatalogDelete,
+					logical.ReadOperation:   b.handlePluginCatalogRead,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["plugin-catalog"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["plugin-catalog"][1]),
+			},
+			&framework.Path{
+				Pattern: "plugins/reload/backend$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"plugin": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["plugin-backend-reload-plugin"][0]),
+					},
+					"mounts": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: strings.TrimSpace(sysHelp["plugin-backend-reload-mounts"][0]),
 					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.UpdateOperation: b.handlePluginCatalogUpdate,
-					logical.DeleteOperation: b.handlePluginCatalogDelete,
-					logical.ReadOperation:   b.handlePluginCatalogRead,
+					logical.UpdateOperation: b.handlePluginReloadUpdate,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["plugin-catalog"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["plugin-catalog"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["plugin-reload"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["plugin-reload"][1]),
 			},
 		},
 	}
 
 	b.Backend.Paths = append(b.Backend.Paths, replicationPaths(b)...)
 
+	// The CA of a dev server using TLS is served to unauthenticated clients,
+	// so that they can trust it; the path only exists in that mode
+	if core.devTLSCACert != "" {
+		b.Backend.PathsSpecial.Unauthenticated = append(b.Backend.PathsSpecial.Unauthenticated, "dev/ca")
+		b.Backend.Paths = append(b.Backend.Paths, &framework.Path{
+			Pattern: "dev/ca$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleDevCARead,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["dev-ca"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["dev-ca"][1]),
+		})
+	}
+
 	b.Backend.Invalidate = b.invalidate
 
 	return b
@@ -853,17 +1623,20 @@ func (b *SystemBackend) handleCORSRead(req *logical.Request, d *framework.FieldD
 		corsConf.RLock()
 		resp.Data["allowed_origins"] = corsConf.AllowedOrigins
 		corsConf.RUnlock()
+		resp.Data["allowed_headers"] = corsConf.AllowedHeadersList()
 	}
 
 	return resp, nil
 }
 
 // handleCORSUpdate sets the list of origins that are allowed to make
-// cross-origin requests and sets the CORS enabled flag to true
+// cross-origin requests and the headers they can set, and sets the CORS
+// enabled flag to true
 func (b *SystemBackend) handleCORSUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	origins := d.Get("allowed_origins").([]string)
+	headers := d.Get("allowed_headers").([]string)
 
-	return nil, b.Core.corsConfig.Enable(origins)
+	return nil, b.Core.corsConfig.Enable(origins, headers)
 }
 
 // handleCORSDelete clears the allowed origins and sets the CORS enabled flag
@@ -872,6 +1645,511 @@ func (b *SystemBackend) handleCORSDelete(req *logical.Request, d *framework.Fiel
 	return nil, b.Core.corsConfig.Disable()
 }
 
+// handleTokenLimitsRead returns the current token limits
+func (b *SystemBackend) handleTokenLimitsRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config := b.Core.tokenLimitsConfig()
+
+	overrides := config.Overrides
+	if overrides == nil {
+		overrides = map[string]int{}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"max_tokens_per_entity": config.MaxTokensPerEntity,
+			"max_tokens_per_alias":  config.MaxTokensPerAlias,
+			"overrides":             overrides,
+		},
+	}, nil
+}
+
+// handleTokenLimitsUpdate updates the given token limits
+func (b *SystemBackend) handleTokenLimitsUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	// Copy the current config since it is read without locking
+	current := b.Core.tokenLimitsConfig()
+	config := &TokenLimitsConfig{
+		MaxTokensPerEntity: current.MaxTokensPerEntity,
+		MaxTokensPerAlias:  current.MaxTokensPerAlias,
+		Overrides:          current.Overrides,
+	}
+
+	if v, ok := d.GetOk("max_tokens_per_entity"); ok {
+		config.MaxTokensPerEntity = v.(int)
+	}
+	if v, ok := d.GetOk("max_tokens_per_alias"); ok {
+		config.MaxTokensPerAlias = v.(int)
+	}
+	if v, ok := d.GetOk("overrides"); ok {
+		var overrides map[string]int
+		if err := mapstructure.WeakDecode(v, &overrides); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid overrides: %v", err)), logical.ErrInvalidRequest
+		}
+		config.Overrides = overrides
+	}
+
+	if config.MaxTokensPerEntity < 0 || config.MaxTokensPerAlias < 0 {
+		return logical.ErrorResponse("token limits cannot be negative"), logical.ErrInvalidRequest
+	}
+	for entity, max := range config.Overrides {
+		if max < 0 {
+			return logical.ErrorResponse(fmt.Sprintf("token limit of %q cannot be negative", entity)), logical.ErrInvalidRequest
+		}
+	}
+
+	return nil, b.Core.setTokenLimitsConfig(config)
+}
+
+// handleTokenLimitsDelete removes all token limits
+func (b *SystemBackend) handleTokenLimitsDelete(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return nil, b.Core.setTokenLimitsConfig(&TokenLimitsConfig{})
+}
+
+// handleExperimentsRead returns the enabled experiments, and the description
+// of the available ones
+func (b *SystemBackend) handleExperimentsRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	enabled := b.Core.EnabledExperiments()
+	if enabled == nil {
+		enabled = []string{}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":   enabled,
+			"available": experiments,
+		},
+	}, nil
+}
+
+// handleExperimentsUpdate sets the enabled experiments
+func (b *SystemBackend) handleExperimentsUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config := &ExperimentsConfig{
+		Enabled: d.Get("enabled").([]string),
+	}
+	if err := config.validate(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	return nil, b.Core.setExperimentsConfig(config)
+}
+
+// handleExperimentsDelete disables all experiments
+func (b *SystemBackend) handleExperimentsDelete(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return nil, b.Core.setExperimentsConfig(&ExperimentsConfig{})
+}
+
+// handleControlGroupList lists the pending control group requests
+func (b *SystemBackend) handleControlGroupList(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	ids, err := b.Core.listControlGroupRequests()
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(ids), nil
+}
+
+// handleControlGroupRead returns a pending control group request
+func (b *SystemBackend) handleControlGroupRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	cgr, err := b.Core.getControlGroupRequest(d.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if cgr == nil {
+		return nil, nil
+	}
+	return &logical.Response{
+		Data: cgr.toResponseData(b.Core.mountApprovals),
+	}, nil
+}
+
+// handleControlGroupApprove approves a pending control group request
+func (b *SystemBackend) handleControlGroupApprove(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return b.Core.approveControlGroupRequest(req, d.Get("id").(string))
+}
+
+// handleControlGroupDelete rejects a pending control group request
+func (b *SystemBackend) handleControlGroupDelete(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.Core.controlGroupLock.Lock()
+	defer b.Core.controlGroupLock.Unlock()
+	return nil, b.Core.systemBarrierView.Delete(controlGroupPrefix + d.Get("id").(string))
+}
+
+// handleMFAMethodList lists the MFA methods
+func (b *SystemBackend) handleMFAMethodList(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config := b.Core.mfaConfig()
+	names := make([]string, 0, len(config.Methods))
+	for name := range config.Methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return logical.ListResponse(names), nil
+}
+
+// handleMFAMethodRead returns an MFA method without its secrets
+func (b *SystemBackend) handleMFAMethodRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	method := b.Core.mfaConfig().Methods[d.Get("name").(string)]
+	if method == nil {
+		return nil, nil
+	}
+	return &logical.Response{
+		Data: method.toResponseData(),
+	}, nil
+}
+
+// handleMFAMethodUpdate creates or updates an MFA method
+func (b *SystemBackend) handleMFAMethodUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.Core.mfaLock.Lock()
+	defer b.Core.mfaLock.Unlock()
+
+	name := d.Get("name").(string)
+	config := b.Core.mfaConfig().clone()
+
+	// Copy the existing method since it is read without locking
+	method := &MFAMethod{
+		Name:      name,
+		Type:      d.Get("type").(string),
+		Period:    uint(d.Get("period").(int)),
+		Digits:    d.Get("digits").(int),
+		Algorithm: d.Get("algorithm").(string),
+		Skew:      uint(d.Get("skew").(int)),
+	}
+	if existing := config.Methods[name]; existing != nil {
+		*method = *existing
+		if v, ok := d.GetOk("type"); ok && v.(string) != existing.Type {
+			return logical.ErrorResponse("the type of an MFA method cannot be changed"), logical.ErrInvalidRequest
+		}
+		if v, ok := d.GetOk("period"); ok {
+			method.Period = uint(v.(int))
+		}
+		if v, ok := d.GetOk("digits"); ok {
+			method.Digits = v.(int)
+		}
+		if v, ok := d.GetOk("algorithm"); ok {
+			method.Algorithm = v.(string)
+		}
+		if v, ok := d.GetOk("skew"); ok {
+			method.Skew = uint(v.(int))
+		}
+	}
+	if v, ok := d.GetOk("issuer"); ok {
+		method.Issuer = v.(string)
+	}
+	if v, ok := d.GetOk("integration_key"); ok {
+		method.IntegrationKey = v.(string)
+	}
+	if v, ok := d.GetOk("secret_key"); ok {
+		method.SecretKey = v.(string)
+	}
+	if v, ok := d.GetOk("api_hostname"); ok {
+		method.APIHostname = v.(string)
+	}
+	if v, ok := d.GetOk("push_info"); ok {
+		method.PushInfo = v.(string)
+	}
+	if v, ok := d.GetOk("username_format"); ok {
+		method.UsernameFormat = v.(string)
+	}
+	if v, ok := d.GetOk("url"); ok {
+		method.URL = v.(string)
+	}
+
+	if err := method.validate(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	config.Methods[name] = method
+	return nil, b.Core.setMFAConfig(config)
+}
+
+// handleMFAMethodDelete deletes an MFA method along with its enrollments
+func (b *SystemBackend) handleMFAMethodDelete(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.Core.mfaLock.Lock()
+	defer b.Core.mfaLock.Unlock()
+
+	name := d.Get("name").(string)
+	config := b.Core.mfaConfig().clone()
+	if config.Methods[name] == nil {
+		return nil, nil
+	}
+	for _, e := range config.Enforcements {
+		if strutil.StrListContains(e.Methods, name) {
+			return logical.ErrorResponse(fmt.Sprintf("MFA method is used by enforcement %q", e.Name)), logical.ErrInvalidRequest
+		}
+	}
+
+	delete(config.Methods, name)
+	if err := b.Core.setMFAConfig(config); err != nil {
+		return nil, err
+	}
+
+	view := b.Core.systemBarrierView.SubView(mfaEnrollmentPrefix + name + "/")
+	if err := logical.ClearView(view); err != nil {
+		return nil, fmt.Errorf("failed to delete MFA enrollments: %v", err)
+	}
+	return nil, nil
+}
+
+// handleMFAEnrollmentList lists the entities enrolled in an MFA method
+func (b *SystemBackend) handleMFAEnrollmentList(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entities, err := b.Core.listMFAEnrollments(d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(entities), nil
+}
+
+// handleMFAEnrollmentRead returns whether an entity is enrolled in an MFA
+// method, without its TOTP secret
+func (b *SystemBackend) handleMFAEnrollmentRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	enrollment, err := b.Core.getMFAEnrollment(d.Get("name").(string), d.Get("entity").(string))
+	if err != nil {
+		return nil, err
+	}
+	if enrollment == nil {
+		return nil, nil
+	}
+
+	data := map[string]interface{}{
+		"method": d.Get("name").(string),
+		"entity": d.Get("entity").(string),
+	}
+	if enrollment.DuoUsername != "" {
+		data["username"] = enrollment.DuoUsername
+	}
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+// handleMFAEnrollmentUpdate enrolls an entity in an MFA method, returning
+// the new TOTP secret for TOTP methods
+func (b *SystemBackend) handleMFAEnrollmentUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	method := b.Core.mfaConfig().Methods[d.Get("name").(string)]
+	if method == nil {
+		return logical.ErrorResponse("unknown MFA method"), logical.ErrInvalidRequest
+	}
+
+	entity := d.Get("entity").(string)
+	enrollment, err := b.Core.enrollMFA(method, entity, d.Get("username").(string))
+	if err != nil {
+		return nil, err
+	}
+	if enrollment.TOTPKey == "" {
+		return nil, nil
+	}
+
+	barcode, err := totpBarcode(enrollment.TOTPKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"url":     enrollment.TOTPKey,
+			"barcode": barcode,
+		},
+	}, nil
+}
+
+// handleMFAEnrollmentDelete removes an entity from an MFA method
+func (b *SystemBackend) handleMFAEnrollmentDelete(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return nil, b.Core.systemBarrierView.Delete(mfaEnrollmentKey(d.Get("name").(string), d.Get("entity").(string)))
+}
+
+// handleMFAEnforcementList lists the MFA enforcements
+func (b *SystemBackend) handleMFAEnforcementList(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config := b.Core.mfaConfig()
+	names := make([]string, 0, len(config.Enforcements))
+	for name := range config.Enforcements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return logical.ListResponse(names), nil
+}
+
+// handleMFAEnforcementRead returns an MFA enforcement
+func (b *SystemBackend) handleMFAEnforcementRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	e := b.Core.mfaConfig().Enforcements[d.Get("name").(string)]
+	if e == nil {
+		return nil, nil
+	}
+	return &logical.Response{
+		Data: e.toResponseData(),
+	}, nil
+}
+
+// handleMFAEnforcementUpdate creates or replaces an MFA enforcement
+func (b *SystemBackend) handleMFAEnforcementUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.Core.mfaLock.Lock()
+	defer b.Core.mfaLock.Unlock()
+
+	config := b.Core.mfaConfig().clone()
+	e := &MFAEnforcement{
+		Name:       d.Get("name").(string),
+		Methods:    d.Get("methods").([]string),
+		AuthMounts: []string{},
+		Paths:      d.Get("paths").([]string),
+	}
+
+	if len(e.Methods) == 0 {
+		return logical.ErrorResponse("at least one MFA method is required"), logical.ErrInvalidRequest
+	}
+	for _, name := range e.Methods {
+		if config.Methods[name] == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown MFA method %q", name)), logical.ErrInvalidRequest
+		}
+	}
+	for _, mount := range d.Get("auth_mounts").([]string) {
+		mount = strings.Trim(strings.TrimPrefix(mount, credentialRoutePrefix), "/")
+		e.AuthMounts = append(e.AuthMounts, credentialRoutePrefix+mount+"/")
+	}
+	if len(e.AuthMounts) == 0 && len(e.Paths) == 0 {
+		return logical.ErrorResponse("at least one auth mount or path is required"), logical.ErrInvalidRequest
+	}
+
+	config.Enforcements[e.Name] = e
+	return nil, b.Core.setMFAConfig(config)
+}
+
+// handleMFAEnforcementDelete deletes an MFA enforcement
+func (b *SystemBackend) handleMFAEnforcementDelete(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.Core.mfaLock.Lock()
+	defer b.Core.mfaLock.Unlock()
+
+	config := b.Core.mfaConfig().clone()
+	delete(config.Enforcements, d.Get("name").(string))
+	return nil, b.Core.setMFAConfig(config)
+}
+
+// handleCanaryHookList lists the canary hooks
+func (b *SystemBackend) handleCanaryHookList(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config := b.Core.canaryConfig()
+	names := make([]string, 0, len(config.Hooks))
+	for name := range config.Hooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return logical.ListResponse(names), nil
+}
+
+// handleCanaryHookRead returns a canary hook
+func (b *SystemBackend) handleCanaryHookRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	hook := b.Core.canaryConfig().Hooks[d.Get("name").(string)]
+	if hook == nil {
+		return nil, nil
+	}
+	return &logical.Response{
+		Data: hook.toResponseData(),
+	}, nil
+}
+
+// handleCanaryHookUpdate creates or updates a canary hook
+func (b *SystemBackend) handleCanaryHookUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.Core.canaryLock.Lock()
+	defer b.Core.canaryLock.Unlock()
+
+	hook := &CanaryHook{
+		Name: d.Get("name").(string),
+		URL:  d.Get("url").(string),
+	}
+	if err := hook.validate(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	config := b.Core.canaryConfig().clone()
+	config.Hooks[hook.Name] = hook
+	return nil, b.Core.setCanaryConfig(config)
+}
+
+// handleCanaryHookDelete deletes a canary hook unless canary tokens use it
+func (b *SystemBackend) handleCanaryHookDelete(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.Core.canaryLock.Lock()
+	defer b.Core.canaryLock.Unlock()
+
+	name := d.Get("name").(string)
+	config := b.Core.canaryConfig().clone()
+	if config.Hooks[name] == nil {
+		return nil, nil
+	}
+	for _, t := range config.Tokens {
+		if strutil.StrListContains(t.Hooks, name) {
+			return logical.ErrorResponse(fmt.Sprintf("canary hook is used by canary token %q", t.Name)), logical.ErrInvalidRequest
+		}
+	}
+
+	delete(config.Hooks, name)
+	return nil, b.Core.setCanaryConfig(config)
+}
+
+// handleCanaryTokenList lists the canary tokens
+func (b *SystemBackend) handleCanaryTokenList(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config := b.Core.canaryConfig()
+	names := make([]string, 0, len(config.Tokens))
+	for name := range config.Tokens {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return logical.ListResponse(names), nil
+}
+
+// handleCanaryTokenRead returns a canary token without its ID and accessor
+func (b *SystemBackend) handleCanaryTokenRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	token := b.Core.canaryConfig().Tokens[d.Get("name").(string)]
+	if token == nil {
+		return nil, nil
+	}
+	return &logical.Response{
+		Data: token.toResponseData(),
+	}, nil
+}
+
+// handleCanaryTokenUpdate mints a canary token, returning its ID and
+// accessor. They are only returned once; writing to an existing canary
+// token mints a new one in its place.
+func (b *SystemBackend) handleCanaryTokenUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.Core.canaryLock.Lock()
+	defer b.Core.canaryLock.Unlock()
+
+	config := b.Core.canaryConfig().clone()
+	token := &CanaryToken{
+		Name:     d.Get("name").(string),
+		Hooks:    d.Get("hooks").([]string),
+		Metadata: make(map[string]string),
+	}
+	for _, name := range token.Hooks {
+		if config.Hooks[name] == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown canary hook %q", name)), logical.ErrInvalidRequest
+		}
+	}
+	for k, v := range d.Get("metadata").(map[string]interface{}) {
+		s, ok := v.(string)
+		if !ok {
+			return logical.ErrorResponse(fmt.Sprintf("metadata %q must be a string", k)), logical.ErrInvalidRequest
+		}
+		token.Metadata[k] = s
+	}
+
+	id, accessor, err := b.Core.mintCanaryToken(config, token)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
+		Data: token.toResponseData(),
+	}
+	resp.Data["token"] = id
+	resp.Data["accessor"] = accessor
+	return resp, nil
+}
+
+// handleCanaryTokenDelete deletes a canary token
+func (b *SystemBackend) handleCanaryTokenDelete(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.Core.canaryLock.Lock()
+	defer b.Core.canaryLock.Unlock()
+
+	config := b.Core.canaryConfig().clone()
+	delete(config.Tokens, d.Get("name").(string))
+	return nil, b.Core.setCanaryConfig(config)
+}
+
 func (b *SystemBackend) handleTidyLeases(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	err := b.Core.expiration.Tidy()
 	if err != nil {
@@ -925,7 +2203,19 @@ func (b *SystemBackend) handlePluginCatalogUpdate(req *logical.Request, d *frame
 		return logical.ErrorResponse("Could not decode SHA-256 value from Hex"), err
 	}
 
-	err = b.Core.pluginCatalog.Set(pluginName, command, sha256Bytes)
+	maxMemory := d.Get("max_memory").(int)
+	maxRestarts := d.Get("max_restarts").(int)
+	restartWindow := d.Get("restart_window").(int)
+	if maxMemory < 0 || maxRestarts < 0 || restartWindow < 0 {
+		return logical.ErrorResponse("max_memory, max_restarts and restart_window cannot be negative"), nil
+	}
+	limits := PluginLimits{
+		MaxMemory:     uint64(maxMemory),
+		MaxRestarts:   maxRestarts,
+		RestartWindow: time.Duration(restartWindow) * time.Second,
+	}
+
+	err = b.Core.pluginCatalog.Set(pluginName, command, sha256Bytes, limits)
 	if err != nil {
 		return nil, err
 	}
@@ -948,6 +2238,10 @@ func (b *SystemBackend) handlePluginCatalogRead(req *logical.Request, d *framewo
 
 	// Create a map of data to be returned and remove sensitive information from it
 	data := structs.New(plugin).Map()
+	data["restart_window"] = int64(plugin.RestartWindow.Seconds())
+
+	// Report the mounts of the plugin which crashed
+	data["mounts"] = b.Core.pluginMountStatuses(pluginName)
 
 	return &logical.Response{
 		Data: data,
@@ -967,6 +2261,42 @@ func (b *SystemBackend) handlePluginCatalogDelete(req *logical.Request, d *frame
 	return nil, nil
 }
 
+// handlePluginReloadUpdate restarts the plugin processes backing either the
+// given mounts or every mount of the given plugin
+func (b *SystemBackend) handlePluginReloadUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	pluginName := d.Get("plugin").(string)
+	pluginMounts := d.Get("mounts").([]string)
+
+	if pluginName != "" && len(pluginMounts) > 0 {
+		return logical.ErrorResponse("plugin and mounts cannot be set at the same time"), nil
+	}
+	if pluginName == "" && len(pluginMounts) == 0 {
+		return logical.ErrorResponse("plugin or mounts must be provided"), nil
+	}
+
+	if pluginName != "" {
+		if err := b.Core.reloadMatchingPlugin(pluginName); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := b.Core.reloadMatchingPluginMounts(pluginMounts); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// handleDevCARead returns the CA certificate of the listener of a dev server
+// using TLS
+func (b *SystemBackend) handleDevCARead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"certificate": b.Core.devTLSCACert,
+		},
+	}, nil
+}
+
 // handleAuditedHeaderUpdate creates or overwrites a header entry
 func (b *SystemBackend) handleAuditedHeaderUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	header := d.Get("header").(string)
@@ -1150,6 +2480,61 @@ func (b *SystemBackend) handleRekeyDeleteRecovery(
 	return b.handleRekeyDelete(req, data, true)
 }
 
+// mountListing returns the entries of a mount table listed by the "mounts"
+// and "auth" endpoints, sorted by path and paginated with the "after" and
+// "limit" parameters. The response gets the sorted paths under "keys" and,
+// if entries are left, the cursor of the next page under "next" when any of
+// the listing parameters is given.
+func mountListing(entries []*MountEntry, data *framework.FieldData, resp *logical.Response) ([]*MountEntry, bool, error) {
+	detailed := data.Get("detailed").(bool)
+	after := data.Get("after").(string)
+	limit := data.Get("limit").(int)
+	if limit < 0 {
+		return nil, false, logical.CodedError(400, "limit cannot be negative")
+	}
+
+	sorted := make([]*MountEntry, len(entries))
+	copy(sorted, entries)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Path < sorted[j].Path
+	})
+
+	if after != "" {
+		i := sort.Search(len(sorted), func(i int) bool {
+			return sorted[i].Path > after
+		})
+		sorted = sorted[i:]
+	}
+	if limit > 0 && len(sorted) > limit {
+		resp.Data["next"] = sorted[limit-1].Path
+		sorted = sorted[:limit]
+	}
+
+	if detailed || after != "" || limit > 0 {
+		keys := make([]string, 0, len(sorted))
+		for _, entry := range sorted {
+			keys = append(keys, entry.Path)
+		}
+		resp.Data["keys"] = keys
+	}
+	return sorted, detailed, nil
+}
+
+// addMountDetails adds the fields of the detailed listing of the mounts
+func (b *SystemBackend) addMountDetails(entry *MountEntry, info map[string]interface{}) {
+	options := entry.Options
+	if options == nil {
+		options = map[string]string{}
+	}
+	info["options"] = options
+	if entry.Config.PluginName != "" {
+		info["plugin_name"] = entry.Config.PluginName
+	}
+	if version := b.Core.runningPluginVersion(entry); version != "" {
+		info["running_plugin_version"] = version
+	}
+}
+
 // handleMountTable handles the "mounts" endpoint to provide the mount table
 func (b *SystemBackend) handleMountTable(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -1160,7 +2545,11 @@ func (b *SystemBackend) handleMountTable(
 		Data: make(map[string]interface{}),
 	}
 
-	for _, entry := range b.Core.mounts.Entries {
+	entries, detailed, err := mountListing(b.Core.mounts.Entries, data, resp)
+	if err != nil {
+		return handleError(err)
+	}
+	for _, entry := range entries {
 		// Populate mount info
 		structConfig := structs.New(entry.Config).Map()
 		structConfig["default_lease_ttl"] = int64(structConfig["default_lease_ttl"].(time.Duration).Seconds())
@@ -1171,6 +2560,13 @@ func (b *SystemBackend) handleMountTable(
 			"accessor":    entry.Accessor,
 			"config":      structConfig,
 			"local":       entry.Local,
+			"seal_wrap":   entry.SealWrap,
+		}
+		if len(entry.Options) > 0 {
+			info["options"] = entry.Options
+		}
+		if detailed {
+			b.addMountDetails(entry, info)
 		}
 		resp.Data[entry.Path] = info
 	}
@@ -1178,6 +2574,92 @@ func (b *SystemBackend) handleMountTable(
 	return resp, nil
 }
 
+// handleInternalCountersRead returns the counters last collected by the
+// core
+func (b *SystemBackend) handleInternalCountersRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	counters, err := b.Core.Counters()
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"collected_at": counters.CollectedAt.Format(time.RFC3339Nano),
+			"tokens": map[string]interface{}{
+				"total":         counters.TotalTokens(),
+				"by_auth_mount": counters.TokensByAuthMount,
+			},
+			"leases": map[string]interface{}{
+				"total":           counters.TotalLeases(),
+				"by_secret_mount": counters.LeasesBySecretMount,
+			},
+			"entities": counters.Entities,
+		},
+	}, nil
+}
+
+// handleInternalUIMountsRead lists the secret and auth backends the token of
+// the request can access. The path is unauthenticated so that tokens need no
+// policy for it, so the token is checked here.
+func (b *SystemBackend) handleInternalUIMountsRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if req.ClientToken == "" {
+		return nil, logical.ErrPermissionDenied
+	}
+	acl, _, err := b.Core.fetchACLandTokenEntry(req)
+	if err != nil {
+		return nil, err
+	}
+
+	mountInfo := func(entry *MountEntry) map[string]interface{} {
+		info := map[string]interface{}{
+			"type":               entry.Type,
+			"description":        entry.Description,
+			"accessor":           entry.Accessor,
+			"listing_visibility": entry.Config.ListingVisibility,
+		}
+		if len(entry.Options) > 0 {
+			info["options"] = entry.Options
+		}
+		return info
+	}
+	listed := func(entry *MountEntry, path string) bool {
+		switch entry.Config.ListingVisibility {
+		case ListingVisibilityHidden:
+			return false
+		case ListingVisibilityPublic:
+			return true
+		}
+		return acl.HasAccessUnder(path)
+	}
+
+	secretMounts := make(map[string]interface{})
+	b.Core.mountsLock.RLock()
+	for _, entry := range b.Core.mounts.Entries {
+		if listed(entry, entry.Path) {
+			secretMounts[entry.Path] = mountInfo(entry)
+		}
+	}
+	b.Core.mountsLock.RUnlock()
+
+	authMounts := make(map[string]interface{})
+	b.Core.authLock.RLock()
+	for _, entry := range b.Core.auth.Entries {
+		if listed(entry, credentialRoutePrefix+entry.Path) {
+			authMounts[entry.Path] = mountInfo(entry)
+		}
+	}
+	b.Core.authLock.RUnlock()
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"secret": secretMounts,
+			"auth":   authMounts,
+		},
+	}, nil
+}
+
 // handleMount is used to mount a new path
 func (b *SystemBackend) handleMount(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -1258,20 +2740,53 @@ func (b *SystemBackend) handleMount(
 		config.ForceNoCache = true
 	}
 
+	visibility, err := parseListingVisibility(apiConfig.ListingVisibility)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	config.ListingVisibility = visibility
+
+	config.PassthroughRequestHeaders, err = parsePassthroughRequestHeaders(apiConfig.PassthroughRequestHeaders)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	config.AuditNonHMACRequestKeys = parseAuditNonHMACKeys(apiConfig.AuditNonHMACRequestKeys)
+	config.AuditNonHMACResponseKeys = parseAuditNonHMACKeys(apiConfig.AuditNonHMACResponseKeys)
+
 	if logicalType == "" {
 		return logical.ErrorResponse(
 				"backend type must be specified as a string"),
 			logical.ErrInvalidRequest
 	}
 
+	options := data.Get("options").(map[string]interface{})
+	var optionMap map[string]string
+	if len(options) != 0 {
+		optionMap = make(map[string]string, len(options))
+		for k, v := range options {
+			vStr, ok := v.(string)
+			if !ok {
+				return logical.ErrorResponse("options must be string valued"),
+					logical.ErrInvalidRequest
+			}
+			optionMap[k] = vStr
+		}
+	}
+
 	// Create the mount entry
 	me := &MountEntry{
 		Table:       mountTableType,
 		Path:        path,
 		Type:        logicalType,
 		Description: description,
+		Options:     optionMap,
 		Config:      config,
 		Local:       local,
+		SealWrap:    data.Get("seal_wrap").(bool),
+	}
+
+	if b.Core.mountApprovals > 0 {
+		return b.Core.requestMountApproval(req, controlGroupOperationMount, me)
 	}
 
 	// Attempt mount
@@ -1283,6 +2798,84 @@ func (b *SystemBackend) handleMount(
 	return nil, nil
 }
 
+// handleMountExport returns an encrypted archive of the storage of a mount,
+// and optionally of its leases. A key is generated and returned if none is
+// given.
+func (b *SystemBackend) handleMountExport(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	path := sanitizeMountPath(d.Get("path").(string))
+
+	var key []byte
+	if raw := d.Get("key").(string); raw != "" {
+		var err error
+		if key, err = base64.StdEncoding.DecodeString(raw); err != nil {
+			return logical.ErrorResponse("key must be base64 encoded"), logical.ErrInvalidRequest
+		}
+	} else {
+		key = make([]byte, mountArchiveKeySize)
+		if _, err := io.ReadFull(b.Core.entropy, key); err != nil {
+			return nil, err
+		}
+	}
+
+	ciphertext, archive, err := b.Core.exportMount(path, d.Get("include_leases").(bool), key)
+	if err != nil {
+		b.Backend.Logger().Error("sys: mount export failed", "path", path, "error", err)
+		return handleError(err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"archive": base64.StdEncoding.EncodeToString(ciphertext),
+			"key":     base64.StdEncoding.EncodeToString(key),
+			"type":    archive.Type,
+			"entries": len(archive.Storage),
+			"leases":  len(archive.Leases),
+		},
+	}, nil
+}
+
+// handleMountImport mounts the content of an archive at the given path,
+// which must not be in use
+func (b *SystemBackend) handleMountImport(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	path := sanitizeMountPath(d.Get("path").(string))
+
+	// The content of the mount is only known once decrypted, so imports
+	// cannot go through the control group of mount approvals
+	if b.Core.mountApprovals > 0 {
+		return logical.ErrorResponse("mounts cannot be imported when mount approvals are required"), logical.ErrInvalidRequest
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(d.Get("archive").(string))
+	if err != nil || len(ciphertext) == 0 {
+		return logical.ErrorResponse("archive must be base64 encoded"), logical.ErrInvalidRequest
+	}
+	key, err := base64.StdEncoding.DecodeString(d.Get("key").(string))
+	if err != nil {
+		return logical.ErrorResponse("key must be base64 encoded"), logical.ErrInvalidRequest
+	}
+
+	archive, err := b.Core.importMount(path, ciphertext, key, d.Get("include_leases").(bool))
+	switch {
+	case err == ErrMountArchiveDecrypt:
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	case err != nil:
+		b.Backend.Logger().Error("sys: mount import failed", "path", path, "error", err)
+		return handleError(err)
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"type":    archive.Type,
+			"entries": len(archive.Storage),
+			"leases":  len(archive.Leases),
+		},
+	}
+	if !d.Get("include_leases").(bool) && len(archive.Leases) > 0 {
+		resp.AddWarning("The leases in the archive were not imported")
+	}
+	return resp, nil
+}
+
 // used to intercept an HTTPCodedError so it goes back to callee
 func handleError(
 	err error) (*logical.Response, error) {
@@ -1404,9 +2997,21 @@ func (b *SystemBackend) handleTuneReadCommon(path string) (*logical.Response, er
 			"default_lease_ttl": int(sysView.DefaultLeaseTTL().Seconds()),
 			"max_lease_ttl":     int(sysView.MaxLeaseTTL().Seconds()),
 			"force_no_cache":    mountEntry.Config.ForceNoCache,
+			"description":       mountEntry.Description,
 		},
 	}
-
+	if mountEntry.Config.ListingVisibility != ListingVisibilityDefault {
+		resp.Data["listing_visibility"] = mountEntry.Config.ListingVisibility
+	}
+	if len(mountEntry.Config.PassthroughRequestHeaders) > 0 {
+		resp.Data["passthrough_request_headers"] = mountEntry.Config.PassthroughRequestHeaders
+	}
+	if len(mountEntry.Config.AuditNonHMACRequestKeys) > 0 {
+		resp.Data["audit_non_hmac_request_keys"] = mountEntry.Config.AuditNonHMACRequestKeys
+	}
+	if len(mountEntry.Config.AuditNonHMACResponseKeys) > 0 {
+		resp.Data["audit_non_hmac_response_keys"] = mountEntry.Config.AuditNonHMACResponseKeys
+	}
 	return resp, nil
 }
 
@@ -1469,6 +3074,66 @@ func (b *SystemBackend) handleTuneWriteCommon(
 		lock = &b.Core.mountsLock
 	}
 
+	if raw, ok := data.GetOk("listing_visibility"); ok {
+		visibility, err := parseListingVisibility(raw.(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		lock.Lock()
+		err = b.tuneMountVisibility(path, mountEntry, visibility)
+		lock.Unlock()
+		if err != nil {
+			b.Backend.Logger().Error("sys: tuning failed", "path", path, "error", err)
+			return handleError(err)
+		}
+	}
+
+	if raw, ok := data.GetOk("passthrough_request_headers"); ok {
+		headers, err := parsePassthroughRequestHeaders(raw.([]string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		lock.Lock()
+		err = b.tuneMountPassthroughRequestHeaders(path, mountEntry, headers)
+		lock.Unlock()
+		if err != nil {
+			b.Backend.Logger().Error("sys: tuning failed", "path", path, "error", err)
+			return handleError(err)
+		}
+	}
+
+	if raw, ok := data.GetOk("audit_non_hmac_request_keys"); ok {
+		lock.Lock()
+		err := b.tuneMountAuditNonHMACKeys(path, mountEntry, true, parseAuditNonHMACKeys(raw.([]string)))
+		lock.Unlock()
+		if err != nil {
+			b.Backend.Logger().Error("sys: tuning failed", "path", path, "error", err)
+			return handleError(err)
+		}
+	}
+
+	if raw, ok := data.GetOk("audit_non_hmac_response_keys"); ok {
+		lock.Lock()
+		err := b.tuneMountAuditNonHMACKeys(path, mountEntry, false, parseAuditNonHMACKeys(raw.([]string)))
+		lock.Unlock()
+		if err != nil {
+			b.Backend.Logger().Error("sys: tuning failed", "path", path, "error", err)
+			return handleError(err)
+		}
+	}
+
+	if raw, ok := data.GetOk("description"); ok {
+		lock.Lock()
+		err := b.tuneMountDescription(path, mountEntry, raw.(string))
+		lock.Unlock()
+		if err != nil {
+			b.Backend.Logger().Error("sys: tuning failed", "path", path, "error", err)
+			return handleError(err)
+		}
+	}
+
 	// Timing configuration parameters
 	{
 		var newDefault, newMax *time.Duration
@@ -1541,7 +3206,7 @@ func (b *SystemBackend) handleLeaseLookup(
 			"ttl":          int64(0),
 		},
 	}
-	renewable, _ := leaseTimes.renewable()
+	renewable, _ := leaseTimes.renewable(b.Core.clock.Now())
 	resp.Data["renewable"] = renewable
 
 	if !leaseTimes.LastRenewalTime.IsZero() {
@@ -1549,7 +3214,7 @@ func (b *SystemBackend) handleLeaseLookup(
 	}
 	if !leaseTimes.ExpireTime.IsZero() {
 		resp.Data["expire_time"] = leaseTimes.ExpireTime
-		resp.Data["ttl"] = leaseTimes.ttl()
+		resp.Data["ttl"] = leaseTimes.ttl(b.Core.clock.Now())
 	}
 	return resp, nil
 }
@@ -1657,7 +3322,11 @@ func (b *SystemBackend) handleAuthTable(
 	resp := &logical.Response{
 		Data: make(map[string]interface{}),
 	}
-	for _, entry := range b.Core.auth.Entries {
+	entries, detailed, err := mountListing(b.Core.auth.Entries, data, resp)
+	if err != nil {
+		return handleError(err)
+	}
+	for _, entry := range entries {
 		info := map[string]interface{}{
 			"type":        entry.Type,
 			"description": entry.Description,
@@ -1666,7 +3335,23 @@ func (b *SystemBackend) handleAuthTable(
 				"default_lease_ttl": int64(entry.Config.DefaultLeaseTTL.Seconds()),
 				"max_lease_ttl":     int64(entry.Config.MaxLeaseTTL.Seconds()),
 			},
-			"local": entry.Local,
+			"local":     entry.Local,
+			"seal_wrap": entry.SealWrap,
+		}
+		if entry.Config.LazyInit {
+			info["config"].(map[string]interface{})["lazy_init"] = true
+		}
+		if len(entry.Config.PassthroughRequestHeaders) > 0 {
+			info["config"].(map[string]interface{})["passthrough_request_headers"] = entry.Config.PassthroughRequestHeaders
+		}
+		if len(entry.Config.AuditNonHMACRequestKeys) > 0 {
+			info["config"].(map[string]interface{})["audit_non_hmac_request_keys"] = entry.Config.AuditNonHMACRequestKeys
+		}
+		if len(entry.Config.AuditNonHMACResponseKeys) > 0 {
+			info["config"].(map[string]interface{})["audit_non_hmac_response_keys"] = entry.Config.AuditNonHMACResponseKeys
+		}
+		if detailed {
+			b.addMountDetails(entry, info)
 		}
 		resp.Data[entry.Path] = info
 	}
@@ -1698,6 +3383,8 @@ func (b *SystemBackend) handleEnableAuth(
 		config.PluginName = pluginName
 	}
 
+	config.LazyInit = data.Get("lazy_init").(bool)
+
 	if logicalType == "" {
 		return logical.ErrorResponse(
 				"backend type must be specified as a string"),
@@ -1714,6 +3401,11 @@ func (b *SystemBackend) handleEnableAuth(
 		Description: description,
 		Config:      config,
 		Local:       local,
+		SealWrap:    data.Get("seal_wrap").(bool),
+	}
+
+	if b.Core.mountApprovals > 0 {
+		return b.Core.requestMountApproval(req, controlGroupOperationAuth, me)
 	}
 
 	// Attempt enabling
@@ -1832,6 +3524,144 @@ func (b *SystemBackend) handlePolicyDelete(
 	return nil, nil
 }
 
+// handlePolicyExplain handles the "policy/explain" endpoint to explain how a
+// set of policies evaluates a request
+func (b *SystemBackend) handlePolicyExplain(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := strings.TrimPrefix(data.Get("path").(string), "/")
+	if path == "" {
+		return logical.ErrorResponse("missing path"), logical.ErrInvalidRequest
+	}
+
+	op := logical.Operation(strings.ToLower(data.Get("operation").(string)))
+	switch op {
+	case logical.CreateOperation, logical.ReadOperation, logical.UpdateOperation,
+		logical.DeleteOperation, logical.ListOperation:
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported operation %q", op)), logical.ErrInvalidRequest
+	}
+
+	names := data.Get("policies").([]string)
+	if len(names) == 0 {
+		te, err := b.Core.tokenStore.Lookup(req.ClientToken)
+		if err != nil {
+			return handleError(err)
+		}
+		if te == nil {
+			return logical.ErrorResponse("no policies given and no token to take them from"), logical.ErrInvalidRequest
+		}
+		names = te.Policies
+	}
+
+	policies := make([]*Policy, 0, len(names))
+	for _, name := range names {
+		policy, err := b.Core.policyStore.GetPolicy(name)
+		if err != nil {
+			return handleError(err)
+		}
+		if policy == nil {
+			return logical.ErrorResponse(fmt.Sprintf("policy %q not found", name)), logical.ErrInvalidRequest
+		}
+		policies = append(policies, policy)
+	}
+
+	explanation, err := b.Core.ExplainPolicies(policies, &logical.Request{
+		Operation: op,
+		Path:      path,
+		Data:      data.Get("data").(map[string]interface{}),
+	})
+	if err != nil {
+		return handleError(err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"allowed":       explanation.Allowed,
+			"reason":        explanation.Reason,
+			"capabilities":  explanation.Capabilities,
+			"rule":          explanation.Rule,
+			"policies":      explanation.Policies,
+			"grants":        explanation.Grants,
+			"sudo_required": explanation.SudoRequired,
+			"sudo":          explanation.Sudo,
+		},
+	}, nil
+}
+
+// handlePoliciesAnalyze handles the "policies/analyze" endpoint to statically
+// analyze a set of policies
+func (b *SystemBackend) handlePoliciesAnalyze(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	rules := data.Get("rules").(map[string]interface{})
+
+	var policies []*Policy
+	for _, name := range data.Get("policies").([]string) {
+		if _, ok := rules[name]; ok {
+			continue
+		}
+		policy, err := b.Core.policyStore.GetPolicy(name)
+		if err != nil {
+			return handleError(err)
+		}
+		if policy == nil {
+			return logical.ErrorResponse(fmt.Sprintf("policy %q not found", name)), logical.ErrInvalidRequest
+		}
+		policies = append(policies, policy)
+	}
+
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		raw, ok := rules[name].(string)
+		if !ok {
+			return logical.ErrorResponse(fmt.Sprintf("rules of policy %q must be a string", name)), logical.ErrInvalidRequest
+		}
+		policy, err := Parse(raw)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("policy %q: %v", name, err)), logical.ErrInvalidRequest
+		}
+		policy.Name = strings.ToLower(name)
+		policies = append(policies, policy)
+	}
+
+	if len(policies) == 0 {
+		return logical.ErrorResponse("no policies or rules to analyze"), logical.ErrInvalidRequest
+	}
+
+	analysis, err := b.Core.AnalyzePolicies(policies, data.Get("paths").([]string))
+	if err != nil {
+		return handleError(err)
+	}
+
+	paths := make(map[string]interface{}, len(analysis.Paths))
+	for path, pa := range analysis.Paths {
+		paths[path] = map[string]interface{}{
+			"capabilities": pa.Capabilities,
+			"rule":         pa.Rule,
+			"policies":     pa.Policies,
+		}
+	}
+	findings := make([]map[string]interface{}, 0, len(analysis.Findings))
+	for _, finding := range analysis.Findings {
+		findings = append(findings, map[string]interface{}{
+			"type":     finding.Type,
+			"rule":     finding.Rule,
+			"policies": finding.Policies,
+			"message":  finding.Message,
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"paths":    paths,
+			"findings": findings,
+		},
+	}, nil
+}
+
 // handleAuditTable handles the "audit" endpoint to provide the audit table
 func (b *SystemBackend) handleAuditTable(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -1878,6 +3708,31 @@ func (b *SystemBackend) handleAuditHash(
 	}, nil
 }
 
+// handleAuditVerify verifies the chained entries of an audit backend
+func (b *SystemBackend) handleAuditVerify(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := sanitizeMountPath(data.Get("path").(string))
+
+	result, err := b.Core.auditBroker.VerifyChain(path)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"valid":       result.Valid,
+			"entries":     result.Entries,
+			"signatures":  result.Signatures,
+			"last_signed": result.LastSigned,
+		},
+	}
+	if !result.Valid {
+		resp.Data["line"] = result.Line
+		resp.Data["reason"] = result.Error
+	}
+	return resp, nil
+}
+
 // handleEnableAudit is used to enable a new audit backend
 func (b *SystemBackend) handleEnableAudit(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -2069,9 +3924,95 @@ func (b *SystemBackend) handleRotate(
 		return nil, fmt.Errorf("failed to save keyring canary: %v", err)
 	}
 
+	// Re-encrypt the existing data with the new key in the background, at
+	// the rate of the previous rewrap
+	rate := BarrierRewrapDefaultRate
+	if status := b.Core.BarrierRewrapStatus(); status != nil {
+		rate = status.Rate
+	}
+	if err := b.Core.startBarrierRewrap(rate); err != nil {
+		b.Backend.Logger().Error("sys: failed to start barrier rewrap", "term", newTerm, "error", err)
+	}
+
+	return nil, nil
+}
+
+// handleRotateRewrapRead returns the progress of the last rewrap of the
+// barrier
+func (b *SystemBackend) handleRotateRewrapRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	status := b.Core.BarrierRewrapStatus()
+	if status == nil {
+		return nil, nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"term":       status.Term,
+			"state":      status.State,
+			"rate":       status.Rate,
+			"scanned":    status.Scanned,
+			"rewrapped":  status.Rewrapped,
+			"start_time": status.StartTime.Format(time.RFC3339Nano),
+		},
+	}
+	if !status.EndTime.IsZero() {
+		resp.Data["end_time"] = status.EndTime.Format(time.RFC3339Nano)
+	}
+	if status.Error != "" {
+		resp.Data["error"] = status.Error
+	}
+	return resp, nil
+}
+
+// handleRotateRewrapUpdate starts a new rewrap of the barrier in place of
+// the running one, e.g. to change its rate or retry a failed one
+func (b *SystemBackend) handleRotateRewrapUpdate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	rate := BarrierRewrapDefaultRate
+	if status := b.Core.BarrierRewrapStatus(); status != nil {
+		rate = status.Rate
+	}
+	if raw, ok := data.GetOk("rate"); ok {
+		rate = raw.(int)
+	}
+	if rate <= 0 || rate > barrierRewrapMaxRate {
+		return logical.ErrorResponse(fmt.Sprintf("rate must be between 1 and %d", barrierRewrapMaxRate)), logical.ErrInvalidRequest
+	}
+
+	if err := b.Core.startBarrierRewrap(rate); err != nil {
+		return handleError(err)
+	}
 	return nil, nil
 }
 
+// handleRotateRoot replaces the root token of the request, or the one with
+// the given accessor, with a new root token
+func (b *SystemBackend) handleRotateRoot(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	id := req.ClientToken
+	if accessor := data.Get("accessor").(string); accessor != "" {
+		aEntry, err := b.Core.tokenStore.lookupByAccessor(accessor, false)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		id = aEntry.TokenID
+	}
+
+	old, te, err := b.Core.rotateRootToken(id)
+	if err != nil {
+		return handleError(err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"root_token":   te.ID,
+			"accessor":     te.Accessor,
+			"old_accessor": old.Accessor,
+		},
+	}, nil
+}
+
 func (b *SystemBackend) handleWrappingPubkey(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	x, _ := b.Core.wrappingJWTKey.X.MarshalText()
@@ -2309,6 +4250,105 @@ func (b *SystemBackend) handleWrappingRewrap(
 	}, nil
 }
 
+// handleWrappingIntroduce issues the RoleID and a new SecretID of an AppRole
+// role in a single response-wrapped response, so that they can be handed to
+// the application to introduce without being seen by the caller
+func (b *SystemBackend) handleWrappingIntroduce(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	mount := strings.Trim(data.Get("mount").(string), "/")
+	roleName := data.Get("role_name").(string)
+	if mount == "" {
+		return logical.ErrorResponse("missing mount"), logical.ErrInvalidRequest
+	}
+	if roleName == "" {
+		return logical.ErrorResponse("missing role_name"), logical.ErrInvalidRequest
+	}
+	wrapTTL := time.Duration(data.Get("wrap_ttl").(int)) * time.Second
+	if wrapTTL <= 0 {
+		return logical.ErrorResponse("wrap_ttl must be positive"), logical.ErrInvalidRequest
+	}
+
+	rolePath := credentialRoutePrefix + mount + "/role/" + roleName
+	entry := b.Core.router.MatchingMountEntry(rolePath)
+	if entry == nil || entry.Type != "approle" {
+		return logical.ErrorResponse(fmt.Sprintf("no AppRole auth backend mounted at %q", mount)), logical.ErrInvalidRequest
+	}
+
+	roleIDReq := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      rolePath + "/role-id",
+	}
+	secretIDData := map[string]interface{}{
+		"metadata":  data.Get("metadata").(string),
+		"cidr_list": data.Get("cidr_list").(string),
+	}
+	if numUses := data.Get("num_uses").(int); numUses != 0 {
+		secretIDData["num_uses"] = numUses
+	}
+	secretIDReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      rolePath + "/secret-id",
+		Data:      secretIDData,
+	}
+
+	// The caller needs the permissions of the requests made on its behalf;
+	// all of them are checked before any is made, so that no SecretID is
+	// issued unless the whole bundle can be returned
+	acl, _, err := b.Core.fetchACLandTokenEntry(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, subReq := range []*logical.Request{roleIDReq, secretIDReq} {
+		if allowed, _ := acl.AllowOperation(subReq); !allowed {
+			return nil, logical.ErrPermissionDenied
+		}
+	}
+
+	route := func(subReq *logical.Request) (*logical.Response, error) {
+		subReq.ClientToken = req.ClientToken
+		subReq.DisplayName = req.DisplayName
+		subReq.Connection = req.Connection
+		resp, err := b.Core.router.Route(subReq)
+		switch {
+		case err != nil && resp != nil && resp.IsError():
+			return resp, logical.ErrInvalidRequest
+		case err != nil:
+			return nil, err
+		case resp == nil || resp.Data == nil:
+			return nil, fmt.Errorf("no data returned by %q", subReq.Path)
+		case resp.IsError():
+			return resp, logical.ErrInvalidRequest
+		}
+		return resp, nil
+	}
+
+	roleIDResp, err := route(roleIDReq)
+	if err != nil {
+		return roleIDResp, err
+	}
+	secretIDResp, err := route(secretIDReq)
+	if err != nil {
+		return secretIDResp, err
+	}
+
+	// The wrapping TTL of the SecretIDs of the role, if any, is honored
+	if secretIDResp.WrapInfo != nil && secretIDResp.WrapInfo.TTL > 0 && secretIDResp.WrapInfo.TTL < wrapTTL {
+		wrapTTL = secretIDResp.WrapInfo.TTL
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"role_id":            roleIDResp.Data["role_id"],
+			"secret_id":          secretIDResp.Data["secret_id"],
+			"secret_id_accessor": secretIDResp.Data["secret_id_accessor"],
+		},
+		WrapInfo: &wrapping.ResponseWrapInfo{
+			TTL:    wrapTTL,
+			Format: "uuid",
+		},
+	}, nil
+}
+
 func sanitizeMountPath(path string) string {
 	if !strings.HasSuffix(path, "/") {
 		path += "/"
@@ -2338,12 +4378,211 @@ This path responds to the following HTTP methods.
         Returns the configuration of the CORS setting.
 
     POST /
-        Sets the comma-separated list of origins that can make cross-origin requests.
+        Sets the comma-separated list of origins that can make cross-origin requests,
+        and the headers these requests can set in addition to the standard ones.
 
     DELETE /
         Clears the CORS configuration and disables acceptance of CORS requests.
 		`,
 	},
+	"control-group": {
+		"Lists, reads, or rejects pending control group requests.",
+		`
+This path responds to the following HTTP methods.
+
+    LIST /
+        Lists the IDs of the pending control group requests.
+
+    GET /<id>
+        Returns the operation, requester and approvals of a request.
+
+    DELETE /<id>
+        Rejects a request.
+
+When the server is configured with mount_approvals, enabling secret or
+credential backends creates a control group request instead of enabling them.
+The mount is enabled once the request has been approved by the configured
+number of distinct identities other than the requester. Requests expire after
+24 hours.
+		`,
+	},
+
+	"control-group/approve": {
+		"Approves a pending control group request.",
+		`
+Approvals are counted per login identity, so they must be given with tokens
+obtained by logging in to a credential backend. Only the identities listed in
+the mount_approvers configuration can approve requests, with tokens that have
+the "approve" capability on this path. The requester cannot approve its own
+request. The operation is carried out with the last approval.
+		`,
+	},
+
+	"mfa/method": {
+		"Configures the MFA methods.",
+		`
+This path responds to the following HTTP methods.
+
+    LIST /
+        Lists the names of the MFA methods.
+
+    GET /<name>
+        Returns the settings of a method, without its secrets.
+
+    POST /<name>
+        Creates or updates a method.
+
+    DELETE /<name>
+        Deletes a method that is not used by any enforcement, along with
+        the enrollments of its entities.
+
+MFA methods are second factors that enforcements can require: "totp" passcodes,
+"duo" passcodes or pushes, or a "webhook" approving requests. Clients provide
+them with the X-Vault-MFA header, in the form "method:passcode".
+		`,
+	},
+
+	"mfa/enrollment": {
+		"Enrolls entities in MFA methods.",
+		`
+This path responds to the following HTTP methods.
+
+    LIST /
+        Lists the entities enrolled in the method.
+
+    GET /<entity>
+        Returns whether the entity is enrolled.
+
+    POST /<entity>
+        Enrolls the entity. TOTP methods return a new secret as an otpauth
+        URL and a QR code.
+
+    DELETE /<entity>
+        Removes the enrollment of the entity.
+
+An entity is the login identity of a token, such as "userpass/bob". Entities
+must be enrolled in TOTP methods to satisfy them; Duo enrollments only set the
+Duo username of the entity.
+		`,
+	},
+
+	"canary/hook": {
+		"Configures the endpoints alerted when canary tokens are tripped.",
+		`
+This path responds to the following HTTP methods.
+
+    LIST /
+        Lists the names of the canary hooks.
+
+    GET /<name>
+        Returns a canary hook.
+
+    POST /<name>
+        Creates or replaces a canary hook.
+
+    DELETE /<name>
+        Deletes a canary hook, unless canary tokens use it.
+
+When a canary token is tripped, the URL of each of its hooks is posted the
+name and metadata of the canary token along with the path, operation and
+remote address of the request that tripped it.
+		`,
+	},
+
+	"canary/token": {
+		"Mints decoy tokens which raise alerts when used.",
+		`
+This path responds to the following HTTP methods.
+
+    LIST /
+        Lists the names of the canary tokens.
+
+    GET /<name>
+        Returns a canary token, with the number of times it was tripped.
+
+    POST /<name>
+        Mints a canary token, returning its ID and accessor. Writing to an
+        existing canary token replaces it with a new one.
+
+    DELETE /<name>
+        Deletes a canary token.
+
+Canary tokens look like real tokens but are never valid. They are meant to be
+planted where an attacker would look for credentials. Any request using a
+canary token as its client token, or looking up, renewing or revoking it or its
+accessor through the token store trips it: the request is flagged with the name
+of the canary token in the audit log and the hooks of the canary token are
+alerted.
+		`,
+	},
+
+	"mfa/enforcement": {
+		"Configures where MFA is required.",
+		`
+This path responds to the following HTTP methods.
+
+    LIST /
+        Lists the names of the MFA enforcements.
+
+    GET /<name>
+        Returns an enforcement.
+
+    POST /<name>
+        Creates or replaces an enforcement.
+
+    DELETE /<name>
+        Deletes an enforcement.
+
+An enforcement requires one of its methods to be satisfied when logging in
+through its auth mounts, and when calling its paths. Root tokens are exempt
+from path enforcements; other tokens without an entity are denied.
+		`,
+	},
+
+	"config/experiments": {
+		"Configures or returns the experimental subsystems enabled on the cluster.",
+		`
+This path responds to the following HTTP methods.
+
+    GET /
+        Returns the enabled experiments and the description of the
+        available ones.
+
+    POST /
+        Sets the enabled experiments; the experiments not listed are
+        disabled.
+
+    DELETE /
+        Disables all experiments.
+
+Experiments are subsystems that are not yet ready to be enabled by default.
+They are enabled for the whole cluster, take effect without a restart and
+are reported by the seal status endpoint.
+		`,
+	},
+
+	"config/token-limits": {
+		"Configures or returns the limits on the active tokens of entities.",
+		`
+This path responds to the following HTTP methods.
+
+    GET /
+        Returns the token limits.
+
+    POST /
+        Sets the maximum number of active tokens per entity and per alias,
+        and the overrides of specific entities.
+
+    DELETE /
+        Removes all token limits.
+
+An entity is the login identity a token was issued to: the auth mount and
+the display name returned by its backend, such as "userpass/bob". Tokens
+created with a token count against the entity of their parent. The limit per
+alias only counts the tokens obtained by logging in. Creating a token beyond
+a limit fails until tokens of the entity expire or are revoked.
+		`,
+	},
 	"init": {
 		"Initializes or returns the initialization status of the Vault.",
 		`
@@ -2411,7 +4650,10 @@ This path responds to the following HTTP methods.
 This path responds to the following HTTP methods.
 
     GET /
-        Lists all the mounted secret backends.
+        Lists all the mounted secret backends. The "detailed", "after" and
+        "limit" query parameters return a page of the backends sorted by
+        path, along with the sorted paths under "keys" and the cursor of the
+        next page under "next".
 
     GET /<mount point>
         Get information about the mount at the specified path.
@@ -2427,6 +4669,21 @@ This path responds to the following HTTP methods.
 		`,
 	},
 
+	"mounts-detailed": {
+		`Include the options, plugin name and running plugin version of each backend.`,
+		"",
+	},
+
+	"mounts-after": {
+		`Only list the backends whose path sorts after this one, to fetch the next page.`,
+		"",
+	},
+
+	"mounts-limit": {
+		`Maximum number of backends to list, or 0 for all of them.`,
+		"",
+	},
+
 	"mount": {
 		`Mount a new backend at a new path.`,
 		`
@@ -2457,11 +4714,22 @@ west coast.
 and max_lease_ttl.`,
 	},
 
+	"mount_options": {
+		`Backend-specific options for this mount, passed to the backend
+when it is created. Values must be strings.`,
+	},
+
 	"mount_local": {
 		`Mark the mount as a local mount, which is not replicated
 and is unaffected by replication.`,
 	},
 
+	"mount_seal_wrap": {
+		`Whether to seal-wrap the storage of the mount, which is additionally
+encrypted by the seal of the server. Only seals backed by a KMS or an HSM
+support seal wrapping. This cannot be changed once the mount is enabled.`,
+	},
+
 	"tune_default_lease_ttl": {
 		`The default lease TTL for this mount.`,
 	},
@@ -2470,6 +4738,87 @@ and is unaffected by replication.`,
 		`The max lease TTL for this mount.`,
 	},
 
+	"listing_visibility": {
+		`Whether sys/internal/ui/mounts lists this mount: "default" to list it to
+the tokens that can access it, "hidden" to never list it or "public" to list it to
+every token.`,
+	},
+
+	"passthrough_request_headers": {
+		`Comma-separated list of the HTTP headers of the requests that are passed to
+the backend of this mount. Other headers are hidden from the backend.`,
+	},
+
+	"audit_non_hmac_request_keys": {
+		`Comma-separated list of the keys of the request data that the audit
+backends log in cleartext instead of HMAC'ing them.`,
+	},
+
+	"audit_non_hmac_response_keys": {
+		`Comma-separated list of the keys of the response data that the audit
+backends log in cleartext instead of HMAC'ing them.`,
+	},
+
+	"tune_description": {
+		`The description of the mount. Changing it does not require remounting.`,
+	},
+
+	"monitor": {
+		"Stream the log of the server.",
+		`
+This path responds to the following HTTP methods.
+
+    GET /
+        Streams the entries of the log of the server at or above the
+        log_level, which cannot be more verbose than the log level of the
+        server, until the client disconnects. Requires sudo capability.
+		`,
+	},
+
+	"pprof": {
+		"Collect a runtime profile of the server.",
+		`
+This path responds to the following HTTP methods.
+
+    GET /<profile>
+        Returns the profile of the server in the pprof format, to be read
+        with "go tool pprof" or, for the trace, "go tool trace". The cpu
+        profile and the trace are collected for the given number of
+        seconds; the other profiles, such as heap, goroutine, allocs, block,
+        mutex and threadcreate, are returned at once. Requires sudo
+        capability.
+		`,
+	},
+
+	"internal-counters": {
+		"Count the tokens, leases and entities of the cluster.",
+		`
+This path responds to the following HTTP methods.
+
+    GET /
+        Returns the number of tokens by auth mount, the number of leases by
+        secret mount and the number of entities, as last collected by the
+        active node. The counters are collected every minute and published
+        as telemetry gauges as well. Tokens are counted by their lease, so
+        tokens without a TTL such as root tokens are not included.
+		`,
+	},
+
+	"internal-ui-mounts": {
+		"List the mounts the token of the request can access.",
+		`
+This path responds to the following HTTP methods.
+
+    GET /
+        Lists the secret and auth backends the token of the request can
+        access at least one path of, along with their listing visibility,
+        for the web UI and tab completion. Unlike sys/mounts and sys/auth,
+        it does not need a policy granting access to it. Backends tuned with
+        a listing_visibility of "hidden" are never listed, and those tuned
+        with "public" are listed to every token.
+		`,
+	},
+
 	"remount": {
 		"Move the mount point of an already-mounted backend.",
 		`
@@ -2486,6 +4835,34 @@ This path responds to the following HTTP methods.
 the auth path.`,
 	},
 
+	"mount_export": {
+		"Export an encrypted archive of a mount.",
+		`
+Returns an archive of the storage of the mount, and optionally of its leases,
+encrypted with AES-256-GCM. The key is generated and returned along with the
+archive unless one is given; it should be kept apart from the archive.
+		`,
+	},
+
+	"mount_import": {
+		"Import an archive of a mount.",
+		`
+Mounts the content of an archive returned by the export endpoint at the given
+path, which must not be in use, so that mounts can be restored or cloned into
+other clusters. The leases in the archive are only imported if requested:
+imported leases are revoked by the backend when they expire, which may revoke
+the credentials of the original cluster when cloning.
+		`,
+	},
+
+	"mount_archive_key": {
+		`Base64 encoded 256-bit key of the archive.`,
+	},
+
+	"mount_archive_include_leases": {
+		`Whether to export or import the leases of the mount along with its storage.`,
+	},
+
 	"mount_tune": {
 		"Tune backend configuration parameters for this mount.",
 		`Read and write the 'default-lease-ttl' and 'max-lease-ttl' values of
@@ -2564,7 +4941,10 @@ This path responds to the following HTTP methods.
     GET /
         List the currently enabled credential backends: the name, the type of
         the backend, and a user friendly description of the purpose for the
-        credential backend.
+        credential backend. The "detailed", "after" and "limit" query
+        parameters return a page of the backends sorted by path, along with
+        the sorted paths under "keys" and the cursor of the next page under
+        "next".
 
     POST /<mount point>
         Enable a new auth backend.
@@ -2603,6 +4983,12 @@ Example: you might have an OAuth backend for GitHub, and one for Google Apps.
 		"",
 	},
 
+	"auth_lazy_init": {
+		`If set, the backend is initialized when it handles its first request
+rather than when it is mounted or Vault is unsealed.`,
+		"",
+	},
+
 	"policy-list": {
 		`List the configured access control policies.`,
 		`
@@ -2640,11 +5026,50 @@ or delete a policy.
 		"",
 	},
 
+	"policy-explain": {
+		`Explain how a set of policies evaluates a request.`,
+		`
+This path responds to the following HTTP methods.
+
+    PUT /
+        Report whether the given policies, or those of the token of the
+        request, allow the given operation on the given path, the rule
+        deciding it and the policies defining that rule, and whether the
+        path requires sudo. A deny in any of the policies defining the rule
+        overrides the capabilities granted by the others.
+		`,
+	},
+
+	"policies-analyze": {
+		`Statically analyze a set of policies.`,
+		`
+This path responds to the following HTTP methods.
+
+    PUT /
+        Report the effective capabilities of the given policies on the
+        given paths, the rules shadowed by more specific rules or which can
+        never grant their capabilities, and the glob rules granting
+        capabilities on too large a part of the namespace.
+		`,
+	},
+
 	"audit-hash": {
 		"The hash of the given string via the given audit backend",
 		"",
 	},
 
+	"audit-verify": {
+		"Verifies the chained entries written by the given audit backend.",
+		`
+Audit backends enabled with the chain option link each entry to the previous
+one with its hash, and sign the link every chain_sign_interval entries with
+the HMAC key of the backend. This path reads the entries back and reports
+whether the chain is intact, along with the number of entries and signatures.
+A signature covers the entries before it; entries written after the last
+signature are reported but not yet covered.
+		`,
+	},
+
 	"audit-table": {
 		"List the currently enabled audit backends.",
 		`
@@ -2700,10 +5125,54 @@ Enable a new audit backend or disable an existing backend.
 		`
 		Rotate generates a new encryption key which is used to encrypt all
 		data going to the storage backend. The old encryption keys are kept so
-		that data encrypted using those keys can still be decrypted.
+		that data encrypted using those keys can still be decrypted, while
+		that data is re-encrypted with the new key in the background; see
+		sys/rotate/rewrap.
+		`,
+	},
+
+	"rotate-rewrap": {
+		"Re-encrypts the stored data with the active encryption key.",
+		`
+This path responds to the following HTTP methods.
+
+    GET /
+        Returns the progress of the last rewrap.
+
+    PUT /
+        Starts a new rewrap in place of the running one, to change its rate
+        or retry a failed one.
+
+After a rotation, the active node rewraps the storage in the background:
+the entries encrypted with the keys of older terms are re-encrypted with the
+active key, at most "rate" entries being scanned per second. A rewrap
+interrupted by a seal or a loss of leadership is resumed by the next active
+node.
+		`,
+	},
+
+	"rotate-rewrap-rate": {
+		fmt.Sprintf("The maximum number of entries scanned per second, from 1 to %d. Defaults to the rate of the previous rewrap, or %d.", barrierRewrapMaxRate, BarrierRewrapDefaultRate),
+		"",
+	},
+
+	"rotate-root": {
+		"Replaces a root token with a new one.",
+		`
+This path responds to the following HTTP methods.
+		PUT /
+			Mint a new root token replacing the root token of the request, or
+			the one with the given accessor. The child tokens and the leases
+			of the old token are moved to the new one, and the old token is
+			then revoked. If any step fails, the old token is left in place.
 		`,
 	},
 
+	"rotate-root-accessor": {
+		"Accessor of the root token to rotate, defaults to the token of the request.",
+		"",
+	},
+
 	"rekey_backup": {
 		"Allows fetching or deleting the backup of the rotated unseal keys.",
 		"",
@@ -2753,6 +5222,15 @@ it.`,
 		string, the returned response is the exact same as the contained wrapped response.`,
 	},
 
+	"wrapintroduce": {
+		"Issues response-wrapped AppRole credentials.",
+		`Reads the RoleID and generates a new SecretID of an AppRole role, and
+returns both in a single response-wrapped token. The caller needs the
+permission to read the 'role-id' path and to update the 'secret-id' path of
+the role. By default the SecretID can be used once and the token expires after
+60 seconds.`,
+	},
+
 	"wraplookup": {
 		"Looks up the properties of a response-wrapped token.",
 		`Returns the creation TTL and creation time of a response-wrapped token.`,
@@ -2814,6 +5292,50 @@ executable defined in this command must exist in vault's
 plugin directory.`,
 		"",
 	},
+	"plugin-catalog_max-memory": {
+		`The maximum memory, in bytes, the plugin process can
+use, where the platform supports limiting it. 0 means no limit.`,
+		"",
+	},
+	"plugin-catalog_max-restarts": {
+		`The number of times the plugin can crash within the
+restart window and be restarted before its mounts are quarantined.
+Defaults to 5.`,
+		"",
+	},
+	"plugin-catalog_restart-window": {
+		`The window, in seconds or as a duration string, over
+which the crashes of the plugin are counted. Defaults to 10m.`,
+		"",
+	},
+	"plugin-reload": {
+		"Reload mounts that use a particular backend plugin.",
+		`
+This path responds to the following HTTP methods.
+		PUT /
+			Restart the plugin process behind each matching mount. Mounts,
+			their configuration and their leases are preserved.
+		`,
+	},
+	"dev-ca": {
+		"Read the CA certificate of a dev server using TLS.",
+		`
+This path responds to the following HTTP methods.
+		GET /
+			Return the PEM-encoded certificate of the CA, generated in
+			memory, that issued the certificate of the listener. It is only
+			available in dev mode with TLS, and requires no token.
+		`,
+	},
+	"plugin-backend-reload-plugin": {
+		`The name of the plugin to reload, as registered in the plugin catalog.`,
+		"",
+	},
+	"plugin-backend-reload-mounts": {
+		`The mount paths of the plugin backends to reload. Auth mounts must be
+prefixed with "auth/".`,
+		"",
+	},
 	"leases": {
 		`View or list lease metadata.`,
 		`
@@ -6,64 +6,105 @@ import (
 	"time"
 )
 
-// tuneMount is used to set config on a mount point
+// tuneMountTTLs is used to set the default and max lease TTLs of a mount
+// point, nil leaving a TTL unchanged and zero resetting it to the system one
 func (b *SystemBackend) tuneMountTTLs(path string, me *MountEntry, newDefault, newMax *time.Duration) error {
-	meConfig := &me.Config
+	origDefault, origMax := me.leaseTTLs()
 
-	if newDefault == nil && newMax == nil {
-		return nil
+	def, max := origDefault, origMax
+	if newDefault != nil {
+		def = *newDefault
 	}
-	if newDefault == nil && newMax != nil &&
-		*newMax == meConfig.MaxLeaseTTL {
-		return nil
+	if newMax != nil {
+		max = *newMax
 	}
-	if newMax == nil && newDefault != nil &&
-		*newDefault == meConfig.DefaultLeaseTTL {
+	if def == origDefault && max == origMax {
 		return nil
 	}
-	if newMax != nil && newDefault != nil &&
-		*newDefault == meConfig.DefaultLeaseTTL &&
-		*newMax == meConfig.MaxLeaseTTL {
+
+	// The default cannot exceed the max the mount ends up with, which is the
+	// system one if unset
+	switch {
+	case max != 0 && def > max:
+		return fmt.Errorf("backend default lease TTL of %d greater than backend max lease TTL of %d",
+			int(def.Seconds()), int(max.Seconds()))
+	case max == 0 && def > b.Core.maxLeaseTTL:
+		return fmt.Errorf("backend default lease TTL of %d greater than system max lease TTL of %d",
+			int(def.Seconds()), int(b.Core.maxLeaseTTL.Seconds()))
+	}
+
+	me.setLeaseTTLs(def, max)
+	if err := b.persistTunedMount(path, me); err != nil {
+		me.setLeaseTTLs(origDefault, origMax)
+		return fmt.Errorf("failed to update mount table, rolling back TTL changes")
+	}
+	return nil
+}
+
+// tuneMountVisibility is used to set the listing visibility of a mount
+func (b *SystemBackend) tuneMountVisibility(path string, me *MountEntry, visibility string) error {
+	if me.Config.ListingVisibility == visibility {
 		return nil
 	}
 
-	if newMax != nil && newDefault != nil && *newMax < *newDefault {
-		return fmt.Errorf("new backend max lease TTL of %d less than new backend default lease TTL of %d",
-			int(newMax.Seconds()), int(newDefault.Seconds()))
+	origVisibility := me.Config.ListingVisibility
+	me.Config.ListingVisibility = visibility
+
+	if err := b.persistTunedMount(path, me); err != nil {
+		me.Config.ListingVisibility = origVisibility
+		return fmt.Errorf("failed to update mount table, rolling back listing visibility change")
 	}
+	return nil
+}
 
-	if newMax != nil && newDefault == nil {
-		if meConfig.DefaultLeaseTTL != 0 && *newMax < meConfig.DefaultLeaseTTL {
-			return fmt.Errorf("new backend max lease TTL of %d less than backend default lease TTL of %d",
-				int(newMax.Seconds()), int(meConfig.DefaultLeaseTTL.Seconds()))
-		}
+// tuneMountPassthroughRequestHeaders is used to set the headers of the
+// requests passed to the backend of a mount
+func (b *SystemBackend) tuneMountPassthroughRequestHeaders(path string, me *MountEntry, headers []string) error {
+	origHeaders := me.Config.PassthroughRequestHeaders
+	me.Config.PassthroughRequestHeaders = headers
+
+	if err := b.persistTunedMount(path, me); err != nil {
+		me.Config.PassthroughRequestHeaders = origHeaders
+		return fmt.Errorf("failed to update mount table, rolling back passthrough request headers change")
 	}
+	return nil
+}
 
-	if newDefault != nil {
-		if meConfig.MaxLeaseTTL == 0 {
-			if newMax == nil && *newDefault > b.Core.maxLeaseTTL {
-				return fmt.Errorf("new backend default lease TTL of %d greater than system max lease TTL of %d",
-					int(newDefault.Seconds()), int(b.Core.maxLeaseTTL.Seconds()))
-			}
-		} else {
-			if newMax == nil && *newDefault > meConfig.MaxLeaseTTL {
-				return fmt.Errorf("new backend default lease TTL of %d greater than backend max lease TTL of %d",
-					int(newDefault.Seconds()), int(meConfig.MaxLeaseTTL.Seconds()))
-			}
-		}
+// tuneMountAuditNonHMACKeys is used to set the keys of the request or
+// response data that the audit backends log in cleartext for a mount
+func (b *SystemBackend) tuneMountAuditNonHMACKeys(path string, me *MountEntry, request bool, keys []string) error {
+	field := &me.Config.AuditNonHMACResponseKeys
+	if request {
+		field = &me.Config.AuditNonHMACRequestKeys
 	}
+	origKeys := *field
+	*field = keys
 
-	origMax := meConfig.MaxLeaseTTL
-	origDefault := meConfig.DefaultLeaseTTL
+	if err := b.persistTunedMount(path, me); err != nil {
+		*field = origKeys
+		return fmt.Errorf("failed to update mount table, rolling back audit non-HMAC keys change")
+	}
+	return nil
+}
 
-	if newMax != nil {
-		meConfig.MaxLeaseTTL = *newMax
+// tuneMountDescription is used to set the description of a mount
+func (b *SystemBackend) tuneMountDescription(path string, me *MountEntry, description string) error {
+	if me.Description == description {
+		return nil
 	}
-	if newDefault != nil {
-		meConfig.DefaultLeaseTTL = *newDefault
+
+	origDescription := me.Description
+	me.Description = description
+
+	if err := b.persistTunedMount(path, me); err != nil {
+		me.Description = origDescription
+		return fmt.Errorf("failed to update mount table, rolling back description change")
 	}
+	return nil
+}
 
-	// Update the mount table
+// persistTunedMount persists the table of a mount whose entry was tuned
+func (b *SystemBackend) persistTunedMount(path string, me *MountEntry) error {
 	var err error
 	switch {
 	case strings.HasPrefix(path, "auth/"):
@@ -72,14 +113,11 @@ func (b *SystemBackend) tuneMountTTLs(path string, me *MountEntry, newDefault, n
 		err = b.Core.persistMounts(b.Core.mounts, me.Local)
 	}
 	if err != nil {
-		meConfig.MaxLeaseTTL = origMax
-		meConfig.DefaultLeaseTTL = origDefault
-		return fmt.Errorf("failed to update mount table, rolling back TTL changes")
+		return err
 	}
 
 	if b.Core.logger.IsInfo() {
 		b.Core.logger.Info("core: mount tuning successful", "path", path)
 	}
-
 	return nil
 }
@@ -3,6 +3,7 @@ package vault_test
 import (
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -26,7 +27,7 @@ func TestSystemBackend_enableAuth_plugin(t *testing.T) {
 
 	cluster := vault.NewTestCluster(t, coreConfig, true)
 	cluster.StartListeners()
-	defer cluster.CloseListeners()
+	defer cluster.Cleanup()
 	cores := cluster.Cores
 
 	cores[0].Handler.Handle("/", http.Handler(cores[0].Core))
@@ -63,6 +64,132 @@ func TestSystemBackend_enableAuth_plugin(t *testing.T) {
 	if resp != nil {
 		t.Fatalf("bad: %v", resp)
 	}
+
+	// The detailed listing reports the version of the running plugin
+	req = logical.TestRequest(t, logical.ReadOperation, "auth")
+	req.Data["detailed"] = true
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	info := resp.Data["mock-plugin/"].(map[string]interface{})
+	if info["plugin_name"] != "mock-plugin" || !strings.HasPrefix(info["running_plugin_version"].(string), "sha256:") {
+		t.Fatalf("bad: %#v", info)
+	}
+}
+
+func TestSystemBackend_reloadPlugin(t *testing.T) {
+	coreConfig := &vault.CoreConfig{
+		CredentialBackends: map[string]logical.Factory{
+			"plugin": plugin.Factory,
+		},
+	}
+
+	cluster := vault.NewTestCluster(t, coreConfig, true)
+	cluster.StartListeners()
+	defer cluster.Cleanup()
+	cores := cluster.Cores
+
+	cores[0].Handler.Handle("/", http.Handler(cores[0].Core))
+	cores[1].Handler.Handle("/", http.Handler(cores[1].Core))
+	cores[2].Handler.Handle("/", http.Handler(cores[2].Core))
+
+	core := cores[0]
+
+	b := vault.NewSystemBackend(core.Core)
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	bc := &logical.BackendConfig{
+		Logger: logger,
+		System: logical.StaticSystemView{
+			DefaultLeaseTTLVal: time.Hour * 24,
+			MaxLeaseTTLVal:     time.Hour * 24 * 32,
+		},
+	}
+
+	err := b.Backend.Setup(bc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vault.TestAddTestPlugin(t, core.Core, "mock-plugin", "TestBackend_PluginMain")
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/mock-plugin")
+	req.Data["type"] = "plugin"
+	req.Data["plugin_name"] = "mock-plugin"
+	resp, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %v", resp)
+	}
+
+	readInternal := func() {
+		req := logical.TestRequest(t, logical.ReadOperation, "auth/mock-plugin/internal")
+		req.ClientToken = core.Root
+		resp, err := core.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp == nil || resp.Data["value"] != "bar" {
+			t.Fatalf("bad: %#v", resp)
+		}
+	}
+	readInternal()
+
+	// Neither or both of plugin and mounts should be rejected
+	req = logical.TestRequest(t, logical.UpdateOperation, "plugins/reload/backend")
+	resp, err = b.HandleRequest(req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error response, got resp: %#v, err: %v", resp, err)
+	}
+	req.Data["plugin"] = "mock-plugin"
+	req.Data["mounts"] = "auth/mock-plugin"
+	resp, err = b.HandleRequest(req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error response, got resp: %#v, err: %v", resp, err)
+	}
+
+	// Reload by plugin name
+	req = logical.TestRequest(t, logical.UpdateOperation, "plugins/reload/backend")
+	req.Data["plugin"] = "mock-plugin"
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %v", resp)
+	}
+	readInternal()
+
+	// Reload by mount path
+	req = logical.TestRequest(t, logical.UpdateOperation, "plugins/reload/backend")
+	req.Data["mounts"] = "auth/mock-plugin"
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %v", resp)
+	}
+	readInternal()
+
+	// Paths within a mount or sharing a prefix with it do not match it
+	for _, mount := range []string{"auth/mock-plugin/internal", "auth/mock"} {
+		req = logical.TestRequest(t, logical.UpdateOperation, "plugins/reload/backend")
+		req.Data["mounts"] = mount
+		if _, err := b.HandleRequest(req); err == nil {
+			t.Fatalf("expected error reloading %q", mount)
+		}
+	}
+
+	// Non-plugin mounts cannot be reloaded
+	req = logical.TestRequest(t, logical.UpdateOperation, "plugins/reload/backend")
+	req.Data["mounts"] = "auth/token"
+	_, err = b.HandleRequest(req)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
 }
 
 func TestBackend_PluginMain(t *testing.T) {
@@ -0,0 +1,223 @@
+package vault
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/armon/go-metrics"
+	log "github.com/mgutz/logxi/v1"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// MemoryShedRequestSize is the body size above which requests are rejected
+// while the soft memory limit is exceeded
+const MemoryShedRequestSize = 32 * 1024
+
+const (
+	memoryPressureNone uint32 = iota
+	memoryPressureSoft
+	memoryPressureHard
+)
+
+var (
+	// memoryCheckInterval is how often the heap usage is sampled
+	memoryCheckInterval = 5 * time.Second
+
+	// ErrMemoryPressure is returned for requests that are shed because the
+	// memory limits are exceeded
+	ErrMemoryPressure = logical.CodedError(http.StatusServiceUnavailable, "request rejected due to memory pressure")
+)
+
+// memoryWatchdog samples the heap usage of the process and sheds load once
+// the configured limits are exceeded, so that memory is released before the
+// process is killed and Vault has to go through a full unseal again.
+//
+// Above the soft limit, list requests and requests with large bodies are
+// rejected and the caches are purged. Above the hard limit, all requests
+// outside of sys/ are rejected and memory is returned to the OS.
+type memoryWatchdog struct {
+	softLimit uint64
+	hardLimit uint64
+	logger    log.Logger
+
+	// pressure is the current pressure level; it is accessed atomically
+	pressure uint32
+
+	// heapInUse returns the current heap usage; it is replaced in tests
+	heapInUse func() uint64
+}
+
+func newMemoryWatchdog(softLimit, hardLimit uint64, logger log.Logger) *memoryWatchdog {
+	if softLimit == 0 && hardLimit == 0 {
+		return nil
+	}
+	return &memoryWatchdog{
+		softLimit: softLimit,
+		hardLimit: hardLimit,
+		logger:    logger,
+		heapInUse: func() uint64 {
+			var stats runtime.MemStats
+			runtime.ReadMemStats(&stats)
+			return stats.HeapInuse
+		},
+	}
+}
+
+// run samples the heap usage until stopCh is closed, at which point the
+// pressure is reset. The purge functions release cached data when the
+// pressure increases.
+func (w *memoryWatchdog) run(stopCh chan struct{}, purgeFuncs []func()) {
+	for {
+		select {
+		case <-time.After(memoryCheckInterval):
+			w.check(purgeFuncs)
+		case <-stopCh:
+			atomic.StoreUint32(&w.pressure, memoryPressureNone)
+			return
+		}
+	}
+}
+
+// check samples the heap usage and updates the pressure level accordingly
+func (w *memoryWatchdog) check(purgeFuncs []func()) {
+	inUse := w.heapInUse()
+	metrics.SetGauge([]string{"core", "memory", "heap_in_use"}, float32(inUse))
+
+	pressure := memoryPressureNone
+	switch {
+	case w.hardLimit > 0 && inUse >= w.hardLimit:
+		pressure = memoryPressureHard
+	case w.softLimit > 0 && inUse >= w.softLimit:
+		pressure = memoryPressureSoft
+	}
+
+	old := atomic.SwapUint32(&w.pressure, pressure)
+	if pressure == old {
+		return
+	}
+
+	if pressure < old {
+		w.logger.Info("core: memory pressure decreased", "heap_in_use", inUse, "level", memoryPressureName(pressure))
+		return
+	}
+
+	w.logger.Warn("core: memory pressure increased, shedding load", "heap_in_use", inUse, "level", memoryPressureName(pressure))
+	metrics.IncrCounter([]string{"core", "memory", "pressure_increase"}, 1)
+	for _, purge := range purgeFuncs {
+		purge()
+	}
+	if pressure == memoryPressureHard {
+		debug.FreeOSMemory()
+	}
+}
+
+// shedRequest returns ErrMemoryPressure if the request must be rejected at
+// the current pressure level
+func (w *memoryWatchdog) shedRequest(req *logical.Request) error {
+	if w == nil {
+		return nil
+	}
+
+	switch atomic.LoadUint32(&w.pressure) {
+	case memoryPressureHard:
+		// Keep sys/ available so that operators can still check the health
+		// of the node, step it down or seal it
+		if !strings.HasPrefix(req.Path, "sys/") {
+			return ErrMemoryPressure
+		}
+	case memoryPressureSoft:
+		if req.Operation == logical.ListOperation {
+			return ErrMemoryPressure
+		}
+	}
+	return nil
+}
+
+// shedRequestSize returns ErrMemoryPressure if a request with a body of the
+// given size must be rejected at the current pressure level
+func (w *memoryWatchdog) shedRequestSize(size int64) error {
+	if w == nil || atomic.LoadUint32(&w.pressure) == memoryPressureNone {
+		return nil
+	}
+	if size > MemoryShedRequestSize {
+		return ErrMemoryPressure
+	}
+	return nil
+}
+
+func memoryPressureName(pressure uint32) string {
+	switch pressure {
+	case memoryPressureSoft:
+		return "soft"
+	case memoryPressureHard:
+		return "hard"
+	default:
+		return "none"
+	}
+}
+
+// startMemoryWatchdog starts sampling the heap usage if memory limits are
+// configured, purging the caches of the current unseal when under pressure
+func (c *Core) startMemoryWatchdog() {
+	if c.memoryWatchdog == nil {
+		return
+	}
+
+	purgeFuncs := c.physicalPurgeFuncs()
+	if c.policyStore != nil && c.policyStore.lru != nil {
+		purgeFuncs = append(purgeFuncs, c.policyStore.lru.Purge)
+	}
+	c.memoryWatchdogCh = make(chan struct{})
+	go c.memoryWatchdog.run(c.memoryWatchdogCh, purgeFuncs)
+}
+
+// startStandbyMemoryWatchdog starts sampling the heap usage while the core is
+// a standby, which buffers the requests it forwards and so can run out of
+// memory as well. Only the physical cache is purged under pressure, as the
+// other caches are set up by postUnseal. The returned function stops the
+// sampling and waits for it to finish.
+func (c *Core) startStandbyMemoryWatchdog() func() {
+	if c.memoryWatchdog == nil {
+		return func() {}
+	}
+
+	stopCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		c.memoryWatchdog.run(stopCh, c.physicalPurgeFuncs())
+	}()
+	return func() {
+		close(stopCh)
+		<-doneCh
+	}
+}
+
+// physicalPurgeFuncs returns the function purging the physical cache, if any
+func (c *Core) physicalPurgeFuncs() []func() {
+	if cache, ok := c.physical.(interface {
+		Purge()
+	}); ok {
+		return []func(){cache.Purge}
+	}
+	return nil
+}
+
+// stopMemoryWatchdog stops sampling the heap usage
+func (c *Core) stopMemoryWatchdog() {
+	if c.memoryWatchdogCh != nil {
+		close(c.memoryWatchdogCh)
+		c.memoryWatchdogCh = nil
+	}
+}
+
+// CheckRequestSize returns an error if a request with a body of the given
+// size must be rejected to relieve memory pressure
+func (c *Core) CheckRequestSize(size int64) error {
+	return c.memoryWatchdog.shedRequestSize(size)
+}
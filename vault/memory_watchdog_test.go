@@ -0,0 +1,159 @@
+package vault
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestMemoryWatchdog_check(t *testing.T) {
+	w := newMemoryWatchdog(100, 200, logformat.NewVaultLogger(log.LevelTrace))
+
+	var inUse uint64
+	w.heapInUse = func() uint64 { return inUse }
+	purges := 0
+	purgeFuncs := []func(){func() { purges++ }}
+
+	steps := []struct {
+		inUse    uint64
+		pressure uint32
+		purges   int
+	}{
+		{50, memoryPressureNone, 0},
+		{150, memoryPressureSoft, 1},
+		{160, memoryPressureSoft, 1},
+		{250, memoryPressureHard, 2},
+		{150, memoryPressureSoft, 2},
+		{50, memoryPressureNone, 2},
+	}
+	for i, step := range steps {
+		inUse = step.inUse
+		w.check(purgeFuncs)
+		if pressure := atomic.LoadUint32(&w.pressure); pressure != step.pressure {
+			t.Fatalf("step %d: bad pressure: %d", i, pressure)
+		}
+		if purges != step.purges {
+			t.Fatalf("step %d: bad purges: %d", i, purges)
+		}
+	}
+}
+
+func TestMemoryWatchdog_disabled(t *testing.T) {
+	w := newMemoryWatchdog(0, 0, logformat.NewVaultLogger(log.LevelTrace))
+	if w != nil {
+		t.Fatalf("expected no watchdog without limits")
+	}
+	if err := w.shedRequest(&logical.Request{Operation: logical.ListOperation, Path: "secret/"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.shedRequestSize(MemoryShedRequestSize * 10); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCore_memoryShedding(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	c.memoryWatchdog = newMemoryWatchdog(100, 200, c.logger)
+
+	list := &logical.Request{
+		Operation:   logical.ListOperation,
+		Path:        "secret/",
+		ClientToken: root,
+	}
+	read := &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "secret/foo",
+		ClientToken: root,
+	}
+	mounts := &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "sys/mounts",
+		ClientToken: root,
+	}
+
+	atomic.StoreUint32(&c.memoryWatchdog.pressure, memoryPressureSoft)
+	if _, err := c.HandleRequest(list); err != ErrMemoryPressure {
+		t.Fatalf("expected list to be shed, got: %v", err)
+	}
+	if _, err := c.HandleRequest(read); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CheckRequestSize(MemoryShedRequestSize + 1); err != ErrMemoryPressure {
+		t.Fatalf("expected large request to be shed, got: %v", err)
+	}
+	if err := c.CheckRequestSize(MemoryShedRequestSize); err != nil {
+		t.Fatal(err)
+	}
+
+	atomic.StoreUint32(&c.memoryWatchdog.pressure, memoryPressureHard)
+	if _, err := c.HandleRequest(read); err != ErrMemoryPressure {
+		t.Fatalf("expected read to be shed, got: %v", err)
+	}
+	if _, err := c.HandleRequest(mounts); err != nil {
+		t.Fatal(err)
+	}
+
+	atomic.StoreUint32(&c.memoryWatchdog.pressure, memoryPressureNone)
+	if _, err := c.HandleRequest(list); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CheckRequestSize(MemoryShedRequestSize * 10); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCore_memoryWatchdogStandby(t *testing.T) {
+	oldInterval := memoryCheckInterval
+	memoryCheckInterval = 10 * time.Millisecond
+	defer func() { memoryCheckInterval = oldInterval }()
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	inmha := physical.NewInmemHA(logger)
+	newCore := func(redirectAddr string) *Core {
+		core, err := NewCore(&CoreConfig{
+			Physical:        inmha,
+			HAPhysical:      inmha,
+			RedirectAddr:    redirectAddr,
+			DisableMlock:    true,
+			MemorySoftLimit: 100,
+		})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		core.memoryWatchdog.heapInUse = func() uint64 { return 150 }
+		return core
+	}
+
+	active := newCore("http://127.0.0.1:8200")
+	defer active.Shutdown()
+	keys, _ := TestCoreInit(t, active)
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(active, TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+	TestWaitActive(t, active)
+
+	standby := newCore("http://127.0.0.1:8201")
+	defer standby.Shutdown()
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(standby, TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+	if isStandby, _ := standby.Standby(); !isStandby {
+		t.Fatal("should be a standby")
+	}
+
+	// The standby samples its heap usage and sheds large requests
+	for start := time.Now(); standby.CheckRequestSize(MemoryShedRequestSize+1) == nil; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("standby did not shed load")
+		}
+	}
+}
@@ -0,0 +1,557 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/duosecurity/duo_api_golang"
+	"github.com/duosecurity/duo_api_golang/authapi"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	// mfaEnrollmentPrefix is the storage prefix of the enrollments of the
+	// entities in the MFA methods, relative to the system view
+	mfaEnrollmentPrefix = "mfa-enrollment/"
+
+	// mfaWebhookTimeout is how long a webhook method waits for the
+	// verdict of its endpoint
+	mfaWebhookTimeout = 30 * time.Second
+
+	MFATypeTOTP    = "totp"
+	MFATypeDuo     = "duo"
+	MFATypeWebhook = "webhook"
+)
+
+var (
+	// ErrMFARequired is returned when a request is subject to an MFA
+	// enforcement that the credentials of the request do not satisfy
+	ErrMFARequired = errors.New("multi-factor authentication required")
+)
+
+// MFAMethod is a second factor that entities can be required to provide,
+// along with the settings of its type
+type MFAMethod struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// TOTP settings; the period is in seconds and the skew is the number of
+	// periods before and after the current one that are accepted
+	Issuer    string `json:"issuer,omitempty"`
+	Period    uint   `json:"period,omitempty"`
+	Digits    int    `json:"digits,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Skew      uint   `json:"skew,omitempty"`
+
+	// Duo settings; the username format is given the entity name, or the
+	// username the entity is enrolled with
+	IntegrationKey string `json:"integration_key,omitempty"`
+	SecretKey      string `json:"secret_key,omitempty"`
+	APIHostname    string `json:"api_hostname,omitempty"`
+	PushInfo       string `json:"push_info,omitempty"`
+	UsernameFormat string `json:"username_format,omitempty"`
+
+	// Webhook settings; the URL is posted the entity, the path and the
+	// passcode of the request, and approves it by answering with a 2xx
+	URL string `json:"url,omitempty"`
+}
+
+func (m *MFAMethod) toResponseData() map[string]interface{} {
+	data := map[string]interface{}{
+		"name": m.Name,
+		"type": m.Type,
+	}
+	switch m.Type {
+	case MFATypeTOTP:
+		data["issuer"] = m.Issuer
+		data["period"] = m.Period
+		data["digits"] = m.Digits
+		data["algorithm"] = m.Algorithm
+		data["skew"] = m.Skew
+	case MFATypeDuo:
+		data["integration_key"] = m.IntegrationKey
+		data["api_hostname"] = m.APIHostname
+		data["push_info"] = m.PushInfo
+		data["username_format"] = m.UsernameFormat
+	case MFATypeWebhook:
+		data["url"] = m.URL
+	}
+	return data
+}
+
+// validate checks the settings of the method and fills in the defaults
+func (m *MFAMethod) validate() error {
+	switch m.Type {
+	case MFATypeTOTP:
+		if m.Issuer == "" {
+			m.Issuer = "Vault"
+		}
+		if m.Period == 0 {
+			m.Period = 30
+		}
+		switch m.Digits {
+		case 0:
+			m.Digits = 6
+		case 6, 8:
+		default:
+			return fmt.Errorf("digits must be 6 or 8")
+		}
+		switch strings.ToUpper(m.Algorithm) {
+		case "":
+			m.Algorithm = "SHA1"
+		case "SHA1", "SHA256", "SHA512":
+			m.Algorithm = strings.ToUpper(m.Algorithm)
+		default:
+			return fmt.Errorf("unsupported algorithm %q", m.Algorithm)
+		}
+	case MFATypeDuo:
+		if m.IntegrationKey == "" || m.SecretKey == "" || m.APIHostname == "" {
+			return fmt.Errorf("integration_key, secret_key and api_hostname are required")
+		}
+		if m.UsernameFormat == "" {
+			m.UsernameFormat = "%s"
+		}
+		if !strings.Contains(m.UsernameFormat, "%s") {
+			return fmt.Errorf("username_format must include username ('%%s')")
+		}
+	case MFATypeWebhook:
+		u, err := url.Parse(m.URL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("url must be an http or https URL")
+		}
+	default:
+		return fmt.Errorf("unknown MFA method type %q", m.Type)
+	}
+	return nil
+}
+
+func (m *MFAMethod) totpOpts() totp.ValidateOpts {
+	opts := totp.ValidateOpts{
+		Period: m.Period,
+		Skew:   m.Skew,
+		Digits: otp.DigitsSix,
+	}
+	if m.Digits == 8 {
+		opts.Digits = otp.DigitsEight
+	}
+	switch m.Algorithm {
+	case "SHA256":
+		opts.Algorithm = otp.AlgorithmSHA256
+	case "SHA512":
+		opts.Algorithm = otp.AlgorithmSHA512
+	default:
+		opts.Algorithm = otp.AlgorithmSHA1
+	}
+	return opts
+}
+
+// MFAEnforcement requires one of its methods to be satisfied when logging
+// in through one of its auth mounts, or when calling one of its paths
+type MFAEnforcement struct {
+	Name    string   `json:"name"`
+	Methods []string `json:"methods"`
+
+	// AuthMounts are the routing paths of the credential backends, such as
+	// "auth/userpass/"
+	AuthMounts []string `json:"auth_mounts"`
+
+	// Paths are request paths, matched as prefixes if they end with "*"
+	Paths []string `json:"paths"`
+}
+
+func (e *MFAEnforcement) toResponseData() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        e.Name,
+		"methods":     e.Methods,
+		"auth_mounts": e.AuthMounts,
+		"paths":       e.Paths,
+	}
+}
+
+func (e *MFAEnforcement) matchesPath(path string) bool {
+	for _, p := range e.Paths {
+		if strings.HasSuffix(p, "*") {
+			if strings.HasPrefix(path, strings.TrimSuffix(p, "*")) {
+				return true
+			}
+		} else if path == p {
+			return true
+		}
+	}
+	return false
+}
+
+// MFAConfig holds the configured MFA methods and enforcements, keyed by
+// name
+type MFAConfig struct {
+	Methods      map[string]*MFAMethod      `json:"methods"`
+	Enforcements map[string]*MFAEnforcement `json:"enforcements"`
+}
+
+// clone returns a shallow copy of the config that can be modified without
+// affecting the requests being enforced
+func (c *MFAConfig) clone() *MFAConfig {
+	clone := &MFAConfig{
+		Methods:      make(map[string]*MFAMethod, len(c.Methods)),
+		Enforcements: make(map[string]*MFAEnforcement, len(c.Enforcements)),
+	}
+	for k, v := range c.Methods {
+		clone.Methods[k] = v
+	}
+	for k, v := range c.Enforcements {
+		clone.Enforcements[k] = v
+	}
+	return clone
+}
+
+// loginEnforcements returns the enforcements of the credential backend
+// mounted at the given routing path
+func (c *MFAConfig) loginEnforcements(mount string) []*MFAEnforcement {
+	var result []*MFAEnforcement
+	for _, e := range c.Enforcements {
+		if strutil.StrListContains(e.AuthMounts, mount) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// pathEnforcements returns the enforcements of the request path
+func (c *MFAConfig) pathEnforcements(path string) []*MFAEnforcement {
+	var result []*MFAEnforcement
+	for _, e := range c.Enforcements {
+		if e.matchesPath(path) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// MFAEnrollment binds an entity to an MFA method
+type MFAEnrollment struct {
+	// TOTPKey is the otpauth URL holding the TOTP secret of the entity
+	TOTPKey string `json:"totp_key,omitempty"`
+
+	// DuoUsername replaces the entity name as the Duo username
+	DuoUsername string `json:"duo_username,omitempty"`
+}
+
+// mfaConfig returns the current MFA config
+func (c *Core) mfaConfig() *MFAConfig {
+	if config, ok := c.mfa.Load().(*MFAConfig); ok {
+		return config
+	}
+	return &MFAConfig{}
+}
+
+// setMFAConfig persists the MFA config and applies it. The MFA lock must
+// be held.
+func (c *Core) setMFAConfig(config *MFAConfig) error {
+	view := c.systemBarrierView.SubView("config/")
+
+	entry, err := logical.StorageEntryJSON("mfa", config)
+	if err != nil {
+		return fmt.Errorf("failed to create MFA config entry: %v", err)
+	}
+	if err := view.Put(entry); err != nil {
+		return fmt.Errorf("failed to save MFA config: %v", err)
+	}
+
+	c.mfa.Store(config)
+	return nil
+}
+
+// This should only be called with the core state lock held for writing
+func (c *Core) loadMFAConfig() error {
+	view := c.systemBarrierView.SubView("config/")
+
+	out, err := view.Get("mfa")
+	if err != nil {
+		return fmt.Errorf("failed to read MFA config: %v", err)
+	}
+
+	config := &MFAConfig{}
+	if out != nil {
+		if err := out.DecodeJSON(config); err != nil {
+			return err
+		}
+	}
+
+	c.mfa.Store(config)
+	return nil
+}
+
+// mfaEnrollmentKey returns the storage key of the enrollment of the entity
+// in the method. Entities contain slashes, so they are encoded to keep one
+// level per method.
+func mfaEnrollmentKey(method, entity string) string {
+	return mfaEnrollmentPrefix + method + "/" + base64.RawURLEncoding.EncodeToString([]byte(entity))
+}
+
+// getMFAEnrollment returns the enrollment of the entity in the method, or
+// nil if it is not enrolled
+func (c *Core) getMFAEnrollment(method, entity string) (*MFAEnrollment, error) {
+	entry, err := c.systemBarrierView.Get(mfaEnrollmentKey(method, entity))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var enrollment MFAEnrollment
+	if err := entry.DecodeJSON(&enrollment); err != nil {
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+func (c *Core) putMFAEnrollment(method, entity string, enrollment *MFAEnrollment) error {
+	entry, err := logical.StorageEntryJSON(mfaEnrollmentKey(method, entity), enrollment)
+	if err != nil {
+		return err
+	}
+	return c.systemBarrierView.Put(entry)
+}
+
+// listMFAEnrollments returns the entities enrolled in the method
+func (c *Core) listMFAEnrollments(method string) ([]string, error) {
+	keys, err := c.systemBarrierView.List(mfaEnrollmentPrefix + method + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make([]string, 0, len(keys))
+	for _, key := range keys {
+		entity, err := base64.RawURLEncoding.DecodeString(key)
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, string(entity))
+	}
+	return entities, nil
+}
+
+// enrollMFA binds the entity to the method. TOTP methods generate a new
+// secret for the entity, which is returned as an otpauth URL.
+func (c *Core) enrollMFA(method *MFAMethod, entity, duoUsername string) (*MFAEnrollment, error) {
+	enrollment := &MFAEnrollment{}
+	switch method.Type {
+	case MFATypeTOTP:
+		key, err := totp.Generate(totp.GenerateOpts{
+			Issuer:      method.Issuer,
+			AccountName: entity,
+			Period:      method.Period,
+			Digits:      method.totpOpts().Digits,
+			Algorithm:   method.totpOpts().Algorithm,
+		})
+		if err != nil {
+			return nil, err
+		}
+		enrollment.TOTPKey = key.String()
+	case MFATypeDuo:
+		enrollment.DuoUsername = duoUsername
+	}
+
+	if err := c.putMFAEnrollment(method.Name, entity, enrollment); err != nil {
+		return nil, err
+	}
+	return enrollment, nil
+}
+
+// totpBarcode returns the base64-encoded PNG QR code of the otpauth URL, for
+// scanning with authenticator apps
+func totpBarcode(keyURL string) (string, error) {
+	key, err := otp.NewKeyFromURL(keyURL)
+	if err != nil {
+		return "", err
+	}
+	img, err := key.Image(200, 200)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// enforceMFA returns ErrMFARequired unless the MFA credentials of the
+// request satisfy one of the methods of each of the enforcements on behalf
+// of the entity
+func (c *Core) enforceMFA(req *logical.Request, entity string, enforcements []*MFAEnforcement) error {
+	if len(enforcements) == 0 {
+		return nil
+	}
+	if entity == "" {
+		return ErrMFARequired
+	}
+
+	config := c.mfaConfig()
+	verified := make(map[string]bool)
+	for _, e := range enforcements {
+		satisfied := false
+		for _, name := range e.Methods {
+			passcode, ok := req.MFACreds[name]
+			if !ok {
+				continue
+			}
+			if _, ok := verified[name]; !ok {
+				method := config.Methods[name]
+				if method == nil {
+					continue
+				}
+				err := c.verifyMFA(method, req, entity, passcode)
+				if err != nil {
+					c.logger.Warn("core: MFA verification failed", "method", name, "entity", entity, "error", err)
+				}
+				verified[name] = err == nil
+			}
+			if verified[name] {
+				satisfied = true
+				break
+			}
+		}
+		if !satisfied {
+			return ErrMFARequired
+		}
+	}
+	return nil
+}
+
+// verifyMFA verifies the passcode given for the method on behalf of the
+// entity
+func (c *Core) verifyMFA(method *MFAMethod, req *logical.Request, entity, passcode string) error {
+	enrollment, err := c.getMFAEnrollment(method.Name, entity)
+	if err != nil {
+		return err
+	}
+
+	switch method.Type {
+	case MFATypeTOTP:
+		if enrollment == nil {
+			return fmt.Errorf("entity is not enrolled")
+		}
+		return c.verifyTOTP(method, enrollment, entity, passcode)
+	case MFATypeDuo:
+		username := entity[strings.LastIndex(entity, "/")+1:]
+		if enrollment != nil && enrollment.DuoUsername != "" {
+			username = enrollment.DuoUsername
+		}
+		return verifyDuo(method, req, username, passcode)
+	case MFATypeWebhook:
+		return verifyWebhook(method, req, entity, passcode)
+	default:
+		return fmt.Errorf("unknown MFA method type %q", method.Type)
+	}
+}
+
+func (c *Core) verifyTOTP(method *MFAMethod, enrollment *MFAEnrollment, entity, passcode string) error {
+	key, err := otp.NewKeyFromURL(enrollment.TOTPKey)
+	if err != nil {
+		return err
+	}
+
+	// Passcodes are valid for as long as the skew allows; remember the
+	// used ones for that long so that they cannot be replayed
+	usedKey := method.Name + "/" + entity + "/" + passcode
+	if _, used := c.mfaUsedPasscodes.Get(usedKey); used {
+		return fmt.Errorf("passcode already used")
+	}
+
+	valid, err := totp.ValidateCustom(passcode, key.Secret(), time.Now().UTC(), method.totpOpts())
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("invalid passcode")
+	}
+
+	validity := time.Duration(method.Period*(2*method.Skew+1)) * time.Second
+	c.mfaUsedPasscodes.Set(usedKey, struct{}{}, validity)
+	return nil
+}
+
+// verifyDuo authenticates the user with Duo, with the passcode if one is
+// given or with a push otherwise
+func verifyDuo(method *MFAMethod, req *logical.Request, username, passcode string) error {
+	client := authapi.NewAuthApi(*duoapi.NewDuoApi(
+		method.IntegrationKey,
+		method.SecretKey,
+		method.APIHostname,
+		"vault",
+	))
+	duoUser := fmt.Sprintf(method.UsernameFormat, username)
+
+	factor := "push"
+	options := []func(*url.Values){authapi.AuthUsername(duoUser)}
+	if req.Connection != nil && req.Connection.RemoteAddr != "" {
+		options = append(options, authapi.AuthIpAddr(req.Connection.RemoteAddr))
+	}
+	if passcode != "" {
+		factor = "passcode"
+		options = append(options, authapi.AuthPasscode(passcode))
+	} else {
+		options = append(options, authapi.AuthDevice("auto"))
+		if method.PushInfo != "" {
+			options = append(options, authapi.AuthPushinfo(method.PushInfo))
+		}
+	}
+
+	result, err := client.Auth(factor, options...)
+	if err != nil {
+		return err
+	}
+	if result.StatResult.Stat != "OK" {
+		msg := "could not authenticate Duo user"
+		if result.StatResult.Message != nil {
+			msg = msg + ": " + *result.StatResult.Message
+		}
+		return errors.New(msg)
+	}
+	if result.Response.Result != "allow" {
+		return fmt.Errorf("Duo denied the request: %s", result.Response.Status_Msg)
+	}
+	return nil
+}
+
+// verifyWebhook asks the endpoint of the method whether to allow the
+// request
+func verifyWebhook(method *MFAMethod, req *logical.Request, entity, passcode string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"method":    method.Name,
+		"entity":    entity,
+		"path":      req.Path,
+		"operation": req.Operation,
+		"passcode":  passcode,
+	})
+	if err != nil {
+		return err
+	}
+
+	client := cleanhttp.DefaultClient()
+	client.Timeout = mfaWebhookTimeout
+	resp, err := client.Post(method.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package vault
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// testCoreMFA returns an unsealed core with a noop credential backend
+// mounted at auth/foo logging in as bob with the admin policy
+func testCoreMFA(t *testing.T) (*Core, string) {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+		Response: &logical.Response{
+			Auth: &logical.Auth{
+				Policies:    []string{"admin"},
+				DisplayName: "bob",
+			},
+		},
+	}
+	c, _, root := TestCoreUnsealed(t)
+	c.credentialBackends["noop"] = func(conf *logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/auth/foo")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	policy, err := Parse(`
+name = "admin"
+path "secret/*" {
+	capabilities = ["create", "read", "update", "delete", "list"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.policyStore.SetPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	return c, root
+}
+
+func testMFARequest(t *testing.T, c *Core, token string, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	req := logical.TestRequest(t, op, path)
+	req.ClientToken = token
+	req.Data = data
+	resp, err := c.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	return resp
+}
+
+func testMFALogin(c *Core, creds map[string]string) (*logical.Response, error) {
+	return c.HandleRequest(&logical.Request{
+		Path:     "auth/foo/login",
+		MFACreds: creds,
+	})
+}
+
+func TestMFA_TOTPLogin(t *testing.T) {
+	c, root := testCoreMFA(t)
+
+	testMFARequest(t, c, root, logical.UpdateOperation, "sys/mfa/method/otp", map[string]interface{}{
+		"type": "totp",
+	})
+	resp := testMFARequest(t, c, root, logical.UpdateOperation, "sys/mfa/method/otp/enrollment/foo/bob", nil)
+	key, err := otp.NewKeyFromURL(resp.Data["url"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["barcode"] == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	testMFARequest(t, c, root, logical.UpdateOperation, "sys/mfa/enforcement/login", map[string]interface{}{
+		"methods":     "otp",
+		"auth_mounts": "foo",
+	})
+
+	// Logging in without a passcode or with a wrong one fails
+	if _, err := testMFALogin(c, nil); !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+	if _, err := testMFALogin(c, map[string]string{"otp": "000000"}); !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	passcode, err := totp.GenerateCode(key.Secret(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = testMFALogin(c, map[string]string{"otp": passcode})
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	// The passcode cannot be replayed
+	if _, err := testMFALogin(c, map[string]string{"otp": passcode}); !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	// The method cannot be deleted while enforced
+	req := logical.TestRequest(t, logical.DeleteOperation, "sys/mfa/method/otp")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); !errwrap.Contains(err, logical.ErrInvalidRequest.Error()) {
+		t.Fatalf("expected invalid request, got %v", err)
+	}
+}
+
+func TestMFA_WebhookPath(t *testing.T) {
+	c, root := testCoreMFA(t)
+
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = nil
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got["passcode"] != "approved" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	testMFARequest(t, c, root, logical.UpdateOperation, "sys/mfa/method/hook", map[string]interface{}{
+		"type": "webhook",
+		"url":  server.URL,
+	})
+	testMFARequest(t, c, root, logical.UpdateOperation, "sys/mfa/enforcement/secrets", map[string]interface{}{
+		"methods": "hook",
+		"paths":   "secret/prod/*",
+	})
+
+	resp, err := testMFALogin(c, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	token := resp.Auth.ClientToken
+
+	// Paths outside of the enforcement are not affected
+	testMFARequest(t, c, token, logical.UpdateOperation, "secret/dev/foo", map[string]interface{}{"a": "b"})
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "secret/prod/foo")
+	req.ClientToken = token
+	req.Data["a"] = "b"
+	req.MFACreds = map[string]string{"hook": "denied"}
+	if _, err := c.HandleRequest(req); !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+	if got["entity"] != "foo/bob" || got["path"] != "secret/prod/foo" {
+		t.Fatalf("bad: %#v", got)
+	}
+
+	req.MFACreds = map[string]string{"hook": "approved"}
+	if resp, err := c.HandleRequest(req); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	// Root tokens are exempt
+	testMFARequest(t, c, root, logical.UpdateOperation, "secret/prod/foo", map[string]interface{}{"a": "b"})
+}
+
+func TestMFA_ConfigPersisted(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/mfa/method/otp")
+	req.ClientToken = root
+	req.Data["type"] = "totp"
+	req.Data["digits"] = 7
+	if _, err := c.HandleRequest(req); !errwrap.Contains(err, logical.ErrInvalidRequest.Error()) {
+		t.Fatalf("expected invalid request, got %v", err)
+	}
+	req.Data["digits"] = 8
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := c.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range keys {
+		unseal, err := TestCoreUnseal(c, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i+1 == len(keys) && !unseal {
+			t.Fatalf("should be unsealed")
+		}
+	}
+
+	method := c.mfaConfig().Methods["otp"]
+	if method == nil || method.Digits != 8 || method.Period != 30 || method.Skew != 1 {
+		t.Fatalf("bad: %#v", method)
+	}
+}
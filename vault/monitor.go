@@ -0,0 +1,36 @@
+package vault
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/logical"
+)
+
+// MonitorLogs starts streaming the entries of the log of the server at or
+// above the given level, once the token of the request is found to have the
+// sudo capability on sys/monitor. The monitor must be stopped when done.
+func (c *Core) MonitorLogs(req *logical.Request, level int) (*logformat.LogMonitor, error) {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed {
+		return nil, consts.ErrSealed
+	}
+	if c.standby {
+		return nil, consts.ErrStandby
+	}
+
+	if err := c.authorizeHandlerRequest(req); err != nil {
+		return nil, err
+	}
+
+	if c.logMonitors == nil {
+		return nil, logical.CodedError(http.StatusNotImplemented, "the log of the server cannot be streamed in its log format")
+	}
+	monitor, err := c.logMonitors.Monitor(level)
+	if err != nil {
+		return nil, logical.CodedError(http.StatusBadRequest, err.Error())
+	}
+	return monitor, nil
+}
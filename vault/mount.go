@@ -5,13 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/consts"
-	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 )
@@ -159,7 +160,42 @@ type MountEntry struct {
 	Config      MountConfig       `json:"config"`            // Configuration related to this mount (but not backend-derived)
 	Options     map[string]string `json:"options"`           // Backend options
 	Local       bool              `json:"local"`             // Local mounts are not replicated or affected by replication
+	SealWrap    bool              `json:"seal_wrap"`         // Whether the storage of the mount is seal-wrapped
 	Tainted     bool              `json:"tainted,omitempty"` // Set as a Write-Ahead flag for unmount/remount
+
+	// ttlLock guards the lease TTLs of the config, which the running
+	// backend reads through its system view while the mount is tuned
+	ttlLock sync.RWMutex
+}
+
+// leaseTTLs returns the default and max lease TTLs of the mount, zero meaning
+// the system ones
+func (e *MountEntry) leaseTTLs() (def, max time.Duration) {
+	e.ttlLock.RLock()
+	defer e.ttlLock.RUnlock()
+	return e.Config.DefaultLeaseTTL, e.Config.MaxLeaseTTL
+}
+
+// setLeaseTTLs sets the default and max lease TTLs of the mount, which apply
+// to the running backend right away
+func (e *MountEntry) setLeaseTTLs(def, max time.Duration) {
+	e.ttlLock.Lock()
+	defer e.ttlLock.Unlock()
+	e.Config.DefaultLeaseTTL = def
+	e.Config.MaxLeaseTTL = max
+}
+
+// backendConfig returns the configuration passed to the factory of the
+// entry's backend: the mount options, plus the plugin name for plugins
+func (e *MountEntry) backendConfig() map[string]string {
+	conf := make(map[string]string, len(e.Options)+1)
+	for k, v := range e.Options {
+		conf[k] = v
+	}
+	if e.Config.PluginName != "" {
+		conf["plugin_name"] = e.Config.PluginName
+	}
+	return conf
 }
 
 // MountConfig is used to hold settable options
@@ -168,6 +204,21 @@ type MountConfig struct {
 	MaxLeaseTTL     time.Duration `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`             // Override for global default
 	ForceNoCache    bool          `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`          // Override for global default
 	PluginName      string        `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+	LazyInit        bool          `json:"lazy_init,omitempty" structs:"lazy_init,omitempty" mapstructure:"lazy_init"` // Defer backend initialization until first use
+
+	// ListingVisibility controls whether the mount is listed by
+	// sys/internal/ui/mounts
+	ListingVisibility string `json:"listing_visibility,omitempty" structs:"listing_visibility,omitempty" mapstructure:"listing_visibility"`
+
+	// PassthroughRequestHeaders are the HTTP headers of the requests that
+	// are passed to the backend; all the others are hidden from it
+	PassthroughRequestHeaders []string `json:"passthrough_request_headers,omitempty" structs:"passthrough_request_headers,omitempty" mapstructure:"passthrough_request_headers"`
+
+	// AuditNonHMACRequestKeys and AuditNonHMACResponseKeys are the keys of
+	// the request and response data that the audit backends log in
+	// cleartext for the requests to the mount
+	AuditNonHMACRequestKeys  []string `json:"audit_non_hmac_request_keys,omitempty" structs:"audit_non_hmac_request_keys,omitempty" mapstructure:"audit_non_hmac_request_keys"`
+	AuditNonHMACResponseKeys []string `json:"audit_non_hmac_response_keys,omitempty" structs:"audit_non_hmac_response_keys,omitempty" mapstructure:"audit_non_hmac_response_keys"`
 }
 
 // APIMountConfig is an embedded struct of api.MountConfigInput
@@ -176,6 +227,75 @@ type APIMountConfig struct {
 	MaxLeaseTTL     string `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`
 	ForceNoCache    bool   `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`
 	PluginName      string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+
+	ListingVisibility string `json:"listing_visibility,omitempty" structs:"listing_visibility,omitempty" mapstructure:"listing_visibility"`
+
+	PassthroughRequestHeaders []string `json:"passthrough_request_headers,omitempty" structs:"passthrough_request_headers,omitempty" mapstructure:"passthrough_request_headers"`
+
+	AuditNonHMACRequestKeys  []string `json:"audit_non_hmac_request_keys,omitempty" structs:"audit_non_hmac_request_keys,omitempty" mapstructure:"audit_non_hmac_request_keys"`
+	AuditNonHMACResponseKeys []string `json:"audit_non_hmac_response_keys,omitempty" structs:"audit_non_hmac_response_keys,omitempty" mapstructure:"audit_non_hmac_response_keys"`
+}
+
+const (
+	// ListingVisibilityDefault lists the mount to the tokens that can
+	// access at least one path under it
+	ListingVisibilityDefault = ""
+
+	// ListingVisibilityHidden never lists the mount
+	ListingVisibilityHidden = "hidden"
+
+	// ListingVisibilityPublic lists the mount to every token
+	ListingVisibilityPublic = "public"
+)
+
+// parseListingVisibility checks the listing visibility of a mount, which can
+// be given as "default" to reset it
+func parseListingVisibility(visibility string) (string, error) {
+	switch visibility {
+	case "default":
+		return ListingVisibilityDefault, nil
+	case ListingVisibilityDefault, ListingVisibilityHidden, ListingVisibilityPublic:
+		return visibility, nil
+	}
+	return "", fmt.Errorf("invalid listing_visibility %q, must be one of \"default\", %q or %q", visibility, ListingVisibilityHidden, ListingVisibilityPublic)
+}
+
+// parsePassthroughRequestHeaders checks and canonicalizes the names of the
+// headers passed to the backend of a mount. The token header is never passed,
+// as backends must not see the token of the request.
+func parsePassthroughRequestHeaders(headers []string) ([]string, error) {
+	var parsed []string
+	seen := make(map[string]bool, len(headers))
+	for _, header := range headers {
+		header = http.CanonicalHeaderKey(strings.TrimSpace(header))
+		switch {
+		case header == "":
+			return nil, fmt.Errorf("passthrough_request_headers cannot contain empty header names")
+		case header == "X-Vault-Token":
+			return nil, fmt.Errorf("the X-Vault-Token header cannot be passed to backends")
+		case seen[header]:
+			continue
+		}
+		seen[header] = true
+		parsed = append(parsed, header)
+	}
+	return parsed, nil
+}
+
+// parseAuditNonHMACKeys trims and dedupes the keys of the data that the audit
+// backends log in cleartext for the requests to a mount
+func parseAuditNonHMACKeys(keys []string) []string {
+	var parsed []string
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		parsed = append(parsed, key)
+	}
+	return parsed
 }
 
 // Mount is used to mount a new backend to the mount table.
@@ -199,6 +319,10 @@ func (c *Core) mount(entry *MountEntry) error {
 		}
 	}
 
+	if entry.SealWrap && !c.sealWrapSupported() {
+		return errSealWrapUnsupported
+	}
+
 	c.mountsLock.Lock()
 	defer c.mountsLock.Unlock()
 
@@ -224,11 +348,9 @@ func (c *Core) mount(entry *MountEntry) error {
 	}
 	viewPath := backendBarrierPrefix + entry.UUID + "/"
 	view := NewBarrierView(c.barrier, viewPath)
+	view.sealWrap = entry.SealWrap
 	sysView := c.mountEntrySysView(entry)
-	conf := make(map[string]string)
-	if entry.Config.PluginName != "" {
-		conf["plugin_name"] = entry.Config.PluginName
-	}
+	conf := entry.backendConfig()
 
 	// Consider having plugin name under entry.Options
 	backend, err := c.newLogicalBackend(entry.Type, sysView, view, conf)
@@ -244,6 +366,7 @@ func (c *Core) mount(entry *MountEntry) error {
 	if entry.Type == "plugin" && backendType != logical.TypeLogical {
 		return fmt.Errorf("cannot mount '%s' of type '%s' as a logical backend", entry.Config.PluginName, backendType)
 	}
+	backend = c.supervisePluginBackend(entry, backend)
 
 	// Call initialize; this takes care of init tasks that must be run after
 	// the ignore paths are collected.
@@ -481,38 +604,25 @@ func (c *Core) remount(src, dst string) error {
 
 // loadMounts is invoked as part of postUnseal to load the mount table
 func (c *Core) loadMounts() error {
-	mountTable := &MountTable{}
-	localMountTable := &MountTable{}
 	// Load the existing mount table
-	raw, err := c.barrier.Get(coreMountConfigPath)
+	mountTable, needMigration, err := c.loadMountTable(coreMountConfigPath, coreMountEntriesPrefix)
 	if err != nil {
-		c.logger.Error("core: failed to read mount table", "error", err)
+		c.logger.Error("core: failed to load mount table", "error", err)
 		return errLoadMountsFailed
 	}
-	rawLocal, err := c.barrier.Get(coreLocalMountConfigPath)
+	localMountTable, needLocalMigration, err := c.loadMountTable(coreLocalMountConfigPath, coreLocalMountEntriesPrefix)
 	if err != nil {
-		c.logger.Error("core: failed to read local mount table", "error", err)
+		c.logger.Error("core: failed to load local mount table", "error", err)
 		return errLoadMountsFailed
 	}
 
 	c.mountsLock.Lock()
 	defer c.mountsLock.Unlock()
 
-	if raw != nil {
-		// Check if the persisted value has canary in the beginning. If
-		// yes, decompress the table and then JSON decode it. If not,
-		// simply JSON decode it.
-		if err := jsonutil.DecodeJSON(raw.Value, mountTable); err != nil {
-			c.logger.Error("core: failed to decompress and/or decode the mount table", "error", err)
-			return err
-		}
+	if mountTable != nil {
 		c.mounts = mountTable
 	}
-	if rawLocal != nil {
-		if err := jsonutil.DecodeJSON(rawLocal.Value, localMountTable); err != nil {
-			c.logger.Error("core: failed to decompress and/or decode the local mount table", "error", err)
-			return err
-		}
+	if localMountTable != nil {
 		c.mounts.Entries = append(c.mounts.Entries, localMountTable.Entries...)
 	}
 
@@ -521,7 +631,8 @@ func (c *Core) loadMounts() error {
 	// is only designed to work with singletons, as it checks
 	// by type only.
 	if c.mounts != nil {
-		needPersist := false
+		// Upgrade tables persisted as a whole to sharded ones
+		needPersist := needMigration || needLocalMigration
 
 		// Upgrade to typed mount table
 		if c.mounts.Type == "" {
@@ -618,39 +729,14 @@ func (c *Core) persistMounts(table *MountTable, localOnly bool) error {
 	}
 
 	if !localOnly {
-		// Encode the mount table into JSON and compress it (lzw).
-		compressedBytes, err := jsonutil.EncodeJSONAndCompress(nonLocalMounts, nil)
-		if err != nil {
-			c.logger.Error("core: failed to encode and/or compress the mount table", "error", err)
-			return err
-		}
-
-		// Create an entry
-		entry := &Entry{
-			Key:   coreMountConfigPath,
-			Value: compressedBytes,
-		}
-
-		// Write to the physical backend
-		if err := c.barrier.Put(entry); err != nil {
+		if err := c.persistMountTable(coreMountConfigPath, coreMountEntriesPrefix, nonLocalMounts); err != nil {
 			c.logger.Error("core: failed to persist mount table", "error", err)
 			return err
 		}
 	}
 
 	// Repeat with local mounts
-	compressedBytes, err := jsonutil.EncodeJSONAndCompress(localMounts, nil)
-	if err != nil {
-		c.logger.Error("core: failed to encode and/or compress the local mount table", "error", err)
-		return err
-	}
-
-	entry := &Entry{
-		Key:   coreLocalMountConfigPath,
-		Value: compressedBytes,
-	}
-
-	if err := c.barrier.Put(entry); err != nil {
+	if err := c.persistMountTable(coreLocalMountConfigPath, coreLocalMountEntriesPrefix, localMounts); err != nil {
 		c.logger.Error("core: failed to persist local mount table", "error", err)
 		return err
 	}
@@ -677,12 +763,9 @@ func (c *Core) setupMounts() error {
 
 		// Create a barrier view using the UUID
 		view = NewBarrierView(c.barrier, barrierPath)
+		view.sealWrap = entry.SealWrap
 		sysView := c.mountEntrySysView(entry)
-		// Set up conf to pass in plugin_name
-		conf := make(map[string]string)
-		if entry.Config.PluginName != "" {
-			conf["plugin_name"] = entry.Config.PluginName
-		}
+		conf := entry.backendConfig()
 		// Create the new backend
 		backend, err = c.newLogicalBackend(entry.Type, sysView, view, conf)
 		if err != nil {
@@ -698,6 +781,7 @@ func (c *Core) setupMounts() error {
 		if entry.Type == "plugin" && backendType != logical.TypeLogical {
 			return fmt.Errorf("cannot mount '%s' of type '%s' as a logical backend", entry.Config.PluginName, backendType)
 		}
+		backend = c.supervisePluginBackend(entry, backend)
 
 		if err := backend.Initialize(); err != nil {
 			return err
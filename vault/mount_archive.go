@@ -0,0 +1,262 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/compressutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// mountArchiveVersion is the version of the format of mount archives
+	mountArchiveVersion = 1
+
+	// mountArchiveAAD is the additional data authenticated along with the
+	// archives, so that other ciphertexts encrypted with the same key cannot
+	// be imported
+	mountArchiveAAD = "vault-mount-archive"
+
+	// mountArchiveKeySize is the size of the AES-256 key of the archives
+	mountArchiveKeySize = 32
+)
+
+// ErrMountArchiveDecrypt is returned when a mount archive cannot be
+// decrypted with the given key
+var ErrMountArchiveDecrypt = errors.New("failed to decrypt mount archive: wrong key or corrupted archive")
+
+// mountArchive is the content of a mount archive: the settings of the mount,
+// the entries stored under its barrier view, and optionally its leases
+type mountArchive struct {
+	Version      int                     `json:"version"`
+	Path         string                  `json:"path"`
+	Type         string                  `json:"type"`
+	Description  string                  `json:"description"`
+	Config       MountConfig             `json:"config"`
+	Options      map[string]string       `json:"options"`
+	Local        bool                    `json:"local"`
+	SealWrap     bool                    `json:"seal_wrap,omitempty"`
+	Storage      []*logical.StorageEntry `json:"storage"`
+	Leases       [][]byte                `json:"leases,omitempty"`
+	CreationTime time.Time               `json:"creation_time"`
+}
+
+// exportMount archives the mount at the given path, and its leases if
+// includeLeases is set, and encrypts the archive with the AES-256 key
+func (c *Core) exportMount(path string, includeLeases bool, key []byte) ([]byte, *mountArchive, error) {
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+
+	c.mountsLock.RLock()
+	defer c.mountsLock.RUnlock()
+
+	entry := c.router.MatchingMountEntry(path)
+	if entry == nil || entry.Path != path || entry.Table != mountTableType {
+		return nil, nil, logical.CodedError(404, fmt.Sprintf("no mount at '%s'", path))
+	}
+	for _, p := range protectedMounts {
+		if strings.HasPrefix(path, p) {
+			return nil, nil, logical.CodedError(400, fmt.Sprintf("cannot export '%s'", path))
+		}
+	}
+
+	archive := &mountArchive{
+		Version:      mountArchiveVersion,
+		Path:         entry.Path,
+		Type:         entry.Type,
+		Description:  entry.Description,
+		Config:       entry.Config,
+		Options:      entry.Options,
+		Local:        entry.Local,
+		SealWrap:     entry.SealWrap,
+		CreationTime: time.Now().UTC(),
+	}
+
+	view := NewBarrierView(c.barrier, backendBarrierPrefix+entry.UUID+"/")
+	err := scanPages(view, "", func(key string) error {
+		se, err := view.Get(key)
+		if err != nil {
+			return err
+		}
+		if se != nil {
+			archive.Storage = append(archive.Storage, se)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read mount storage: %v", err)
+	}
+
+	if includeLeases {
+		err := scanPages(c.expiration.idView, path, func(leaseID string) error {
+			le, err := c.expiration.loadEntry(leaseID)
+			if err != nil {
+				return err
+			}
+			if le == nil {
+				return nil
+			}
+			buf, err := le.encode()
+			if err != nil {
+				return err
+			}
+			archive.Leases = append(archive.Leases, buf)
+			return nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read mount leases: %v", err)
+		}
+	}
+
+	plaintext, err := json.Marshal(archive)
+	if err != nil {
+		return nil, nil, err
+	}
+	compressed, err := compressutil.Compress(plaintext, &compressutil.CompressionConfig{
+		Type: compressutil.CompressionTypeGzip,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gcm, err := mountArchiveAEAD(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(c.entropy, nonce); err != nil {
+		return nil, nil, err
+	}
+	return gcm.Seal(nonce, nonce, compressed, []byte(mountArchiveAAD)), archive, nil
+}
+
+// importMount decrypts the archive with the AES-256 key and mounts its
+// content at the given path, which must not be in use. The leases in the
+// archive are only restored if includeLeases is set.
+func (c *Core) importMount(path string, ciphertext, key []byte, includeLeases bool) (*mountArchive, error) {
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+
+	gcm, err := mountArchiveAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, ErrMountArchiveDecrypt
+	}
+	nonce := ciphertext[:gcm.NonceSize()]
+	compressed, err := gcm.Open(nil, nonce, ciphertext[gcm.NonceSize():], []byte(mountArchiveAAD))
+	if err != nil {
+		return nil, ErrMountArchiveDecrypt
+	}
+	plaintext, _, err := compressutil.Decompress(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress mount archive: %v", err)
+	}
+	archive := &mountArchive{}
+	if err := json.Unmarshal(plaintext, archive); err != nil {
+		return nil, fmt.Errorf("failed to decode mount archive: %v", err)
+	}
+	if archive.Version != mountArchiveVersion {
+		return nil, fmt.Errorf("unsupported mount archive version %d", archive.Version)
+	}
+
+	// Write the storage of the mount before mounting it, so that the
+	// backend sees it when it is set up
+	entryUUID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	view := NewBarrierView(c.barrier, backendBarrierPrefix+entryUUID+"/")
+	view.sealWrap = archive.SealWrap
+	for _, se := range archive.Storage {
+		if err := view.Put(se); err != nil {
+			logical.ClearView(view)
+			return nil, fmt.Errorf("failed to write mount storage: %v", err)
+		}
+	}
+
+	me := &MountEntry{
+		Table:       mountTableType,
+		Path:        path,
+		Type:        archive.Type,
+		Description: archive.Description,
+		UUID:        entryUUID,
+		Config:      archive.Config,
+		Options:     archive.Options,
+		Local:       archive.Local,
+		SealWrap:    archive.SealWrap,
+	}
+	if err := c.mount(me); err != nil {
+		logical.ClearView(view)
+		return nil, err
+	}
+
+	if includeLeases {
+		if err := c.importMountLeases(archive, me.Path); err != nil {
+			// Revoke the leases restored so far along with the mount, so
+			// that the import can be retried
+			if unmountErr := c.unmount(me.Path); unmountErr != nil {
+				return nil, multierror.Append(err, fmt.Errorf("failed to remove partially imported mount: %v", unmountErr))
+			}
+			return nil, err
+		}
+	}
+	return archive, nil
+}
+
+// importMountLeases restores the leases of the archive, moving them to the
+// given mount path
+func (c *Core) importMountLeases(archive *mountArchive, path string) error {
+	m := c.expiration
+	for _, buf := range archive.Leases {
+		le, err := decodeLeaseEntry(buf)
+		if err != nil {
+			return fmt.Errorf("failed to decode lease entry: %v", err)
+		}
+		if !strings.HasPrefix(le.LeaseID, archive.Path) || !strings.HasPrefix(le.Path, archive.Path) {
+			return fmt.Errorf("lease %q is not under the archived mount", le.LeaseID)
+		}
+		le.LeaseID = path + strings.TrimPrefix(le.LeaseID, archive.Path)
+		le.Path = path + strings.TrimPrefix(le.Path, archive.Path)
+
+		if err := m.persistEntry(le); err != nil {
+			return err
+		}
+		if le.ClientToken != "" {
+			if err := m.createIndexByToken(le.ClientToken, le.LeaseID); err != nil {
+				return err
+			}
+		}
+		if !le.ExpireTime.IsZero() {
+			expires := le.ExpireTime.Sub(m.clock.Now())
+			if expires <= 0 {
+				expires = minRevokeDelay
+			}
+			m.updatePending(le, expires)
+		}
+	}
+	return nil
+}
+
+// mountArchiveAEAD returns the AES-GCM cipher of mount archives
+func mountArchiveAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != mountArchiveKeySize {
+		return nil, fmt.Errorf("key must be %d bytes", mountArchiveKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,195 @@
+package vault
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/compressutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestCore_MountArchive(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/kv")
+	req.Data["type"] = "generic"
+	req.Data["description"] = "prod secrets"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range []string{"foo", "bar/baz"} {
+		req = logical.TestRequest(t, logical.UpdateOperation, "kv/"+key)
+		req.Data["value"] = key
+		req.ClientToken = root
+		if _, err := c.HandleRequest(req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	leaseID, err := c.expiration.Register(&logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "kv/foo",
+		ClientToken: root,
+	}, &logical.Response{
+		Secret: &logical.Secret{
+			LeaseOptions: logical.LeaseOptions{
+				TTL: time.Hour,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/kv/export")
+	req.Data["include_leases"] = true
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["entries"] != 2 || resp.Data["leases"] != 1 || resp.Data["type"] != "generic" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	archive, key := resp.Data["archive"].(string), resp.Data["key"].(string)
+
+	importMount := func(path, key string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/"+path+"/import")
+		req.Data["archive"] = archive
+		req.Data["key"] = key
+		req.Data["include_leases"] = true
+		req.ClientToken = root
+		return c.HandleRequest(req)
+	}
+
+	// The archive cannot be imported with another key, or over a mount
+	if _, err := importMount("staging", strings.Repeat("A", len(key))); !errwrap.Contains(err, logical.ErrInvalidRequest.Error()) {
+		t.Fatalf("expected an invalid request, got %v", err)
+	}
+	if _, err := importMount("kv", key); err == nil {
+		t.Fatalf("expected the import over an existing mount to fail")
+	}
+
+	if _, err := importMount("staging", key); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range []string{"foo", "bar/baz"} {
+		req = logical.TestRequest(t, logical.ReadOperation, "staging/"+key)
+		req.ClientToken = root
+		resp, err := c.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp == nil || resp.Data["value"] != key {
+			t.Fatalf("bad: %#v", resp)
+		}
+	}
+	if entry := c.router.MatchingMountEntry("staging/"); entry == nil || entry.Description != "prod secrets" {
+		t.Fatalf("bad: %#v", entry)
+	}
+
+	// The lease is moved to the new mount, leaving the original untouched
+	le, err := c.expiration.loadEntry("staging/" + strings.TrimPrefix(leaseID, "kv/"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if le == nil || le.Path != "staging/foo" {
+		t.Fatalf("bad: %#v", le)
+	}
+	if le, err := c.expiration.loadEntry(leaseID); err != nil || le == nil {
+		t.Fatalf("expected the original lease to remain: %v", err)
+	}
+
+	// Protected mounts cannot be exported
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/cubbyhole/export")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatalf("expected the export of cubbyhole to fail")
+	}
+}
+
+func TestCore_MountArchive_ImportLeasesFailure(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	leaseID, err := c.expiration.Register(&logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "secret/foo",
+		ClientToken: root,
+	}, &logical.Response{
+		Secret: &logical.Secret{
+			LeaseOptions: logical.LeaseOptions{
+				TTL: time.Hour,
+			},
+			InternalData: map[string]interface{}{
+				"secret_type": "generic",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	key := make([]byte, mountArchiveKeySize)
+	_, archive, err := c.exportMount("secret", true, key)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Append a lease from another mount, so that the import fails after
+	// the first lease is restored
+	le, err := decodeLeaseEntry(archive.Leases[0])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	le.LeaseID = "other/foo/bar"
+	buf, err := le.encode()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	archive.Leases = append(archive.Leases, buf)
+
+	plaintext, err := json.Marshal(archive)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	compressed, err := compressutil.Compress(plaintext, &compressutil.CompressionConfig{
+		Type: compressutil.CompressionTypeGzip,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	gcm, err := mountArchiveAEAD(key)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	ciphertext := gcm.Seal(nonce, nonce, compressed, []byte(mountArchiveAAD))
+
+	if _, err := c.importMount("staging", ciphertext, key, true); err == nil {
+		t.Fatalf("expected the import to fail")
+	}
+
+	// The mount and the lease restored before the failure are removed
+	if entry := c.router.MatchingMountEntry("staging/"); entry != nil {
+		t.Fatalf("expected the mount to be removed: %#v", entry)
+	}
+	restoredID := "staging/" + strings.TrimPrefix(leaseID, "secret/")
+	if le, err := c.expiration.loadEntry(restoredID); err != nil || le != nil {
+		t.Fatalf("expected the restored lease to be revoked: %#v %v", le, err)
+	}
+
+	// so the import can be retried
+	ciphertext, _, err = c.exportMount("secret", true, key)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := c.importMount("staging", ciphertext, key, true); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if le, err := c.expiration.loadEntry(restoredID); err != nil || le == nil {
+		t.Fatalf("expected the lease to be restored: %v", err)
+	}
+}
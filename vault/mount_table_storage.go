@@ -0,0 +1,170 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+)
+
+const (
+	// coreMountEntriesPrefix, coreLocalMountEntriesPrefix,
+	// coreAuthEntriesPrefix and coreLocalAuthEntriesPrefix are the prefixes
+	// under which the entries of the mount and auth tables are each persisted
+	coreMountEntriesPrefix      = "core/mount-entries/"
+	coreLocalMountEntriesPrefix = "core/local-mount-entries/"
+	coreAuthEntriesPrefix       = "core/auth-entries/"
+	coreLocalAuthEntriesPrefix  = "core/local-auth-entries/"
+)
+
+// persistedMountTable is what is persisted at the path of a mount table.
+// Tables used to be persisted as a whole in Entries, which breaks on storage
+// backends limiting the size of values once there are many mounts; they are
+// now sharded: each entry is persisted under the entry prefix of the table,
+// and only the keys of the entries are listed here.
+type persistedMountTable struct {
+	Type    string        `json:"type"`
+	Entries []*MountEntry `json:"entries,omitempty"`
+
+	Sharded   bool     `json:"sharded,omitempty"`
+	EntryKeys []string `json:"entry_keys,omitempty"`
+}
+
+// loadMountTable reads the table persisted at the path, with its entries
+// under the prefix. The returned bool is set if the table was persisted as a
+// whole, in which case it should be persisted again to be sharded. A nil table
+// is returned if none is persisted.
+func (c *Core) loadMountTable(path, prefix string) (*MountTable, bool, error) {
+	raw, err := c.barrier.Get(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if raw == nil {
+		return nil, false, nil
+	}
+
+	persisted := &persistedMountTable{}
+	if err := jsonutil.DecodeJSON(raw.Value, persisted); err != nil {
+		return nil, false, fmt.Errorf("failed to decompress and/or decode the table: %v", err)
+	}
+	if !persisted.Sharded {
+		c.resetPersistedMountEntries(prefix, nil)
+		return &MountTable{
+			Type:    persisted.Type,
+			Entries: persisted.Entries,
+		}, true, nil
+	}
+
+	hashes := make(map[string][sha256.Size]byte, len(persisted.EntryKeys))
+	table := &MountTable{
+		Type: persisted.Type,
+	}
+	for _, key := range persisted.EntryKeys {
+		rawEntry, err := c.barrier.Get(prefix + key)
+		if err != nil {
+			return nil, false, err
+		}
+		if rawEntry == nil {
+			return nil, false, fmt.Errorf("missing entry %q of the table", key)
+		}
+		entry := &MountEntry{}
+		if err := jsonutil.DecodeJSON(rawEntry.Value, entry); err != nil {
+			return nil, false, fmt.Errorf("failed to decompress and/or decode entry %q of the table: %v", key, err)
+		}
+		table.Entries = append(table.Entries, entry)
+		hashes[prefix+key] = sha256.Sum256(rawEntry.Value)
+	}
+
+	c.resetPersistedMountEntries(prefix, hashes)
+	return table, false, nil
+}
+
+// persistMountTable persists the table at the path, with its entries under
+// the prefix. Only the entries that changed since they were last loaded or
+// persisted are written, then the list of entries is, and finally the entries
+// that are no longer in the table are deleted, so that the persisted table is
+// consistent at every step.
+func (c *Core) persistMountTable(path, prefix string, table *MountTable) error {
+	c.persistedMountEntriesLock.Lock()
+	defer c.persistedMountEntriesLock.Unlock()
+	if c.persistedMountEntries == nil {
+		c.persistedMountEntries = make(map[string][sha256.Size]byte)
+	}
+
+	persisted := &persistedMountTable{
+		Type:      table.Type,
+		Sharded:   true,
+		EntryKeys: make([]string, 0, len(table.Entries)),
+	}
+	keys := make(map[string]bool, len(table.Entries))
+	for _, entry := range table.Entries {
+		if entry.UUID == "" {
+			return fmt.Errorf("entry %q has no UUID", entry.Path)
+		}
+		key := prefix + entry.UUID
+		if keys[key] {
+			return fmt.Errorf("duplicate UUID %q in the table", entry.UUID)
+		}
+		keys[key] = true
+		persisted.EntryKeys = append(persisted.EntryKeys, entry.UUID)
+
+		compressedBytes, err := jsonutil.EncodeJSONAndCompress(entry, nil)
+		if err != nil {
+			return fmt.Errorf("failed to encode and/or compress entry %q: %v", entry.Path, err)
+		}
+		hash := sha256.Sum256(compressedBytes)
+		if lastHash, ok := c.persistedMountEntries[key]; ok && lastHash == hash {
+			continue
+		}
+		if err := c.barrier.Put(&Entry{
+			Key:   key,
+			Value: compressedBytes,
+		}); err != nil {
+			return err
+		}
+		c.persistedMountEntries[key] = hash
+	}
+
+	compressedBytes, err := jsonutil.EncodeJSONAndCompress(persisted, nil)
+	if err != nil {
+		return fmt.Errorf("failed to encode and/or compress the table: %v", err)
+	}
+	if err := c.barrier.Put(&Entry{
+		Key:   path,
+		Value: compressedBytes,
+	}); err != nil {
+		return err
+	}
+
+	for key := range c.persistedMountEntries {
+		if !strings.HasPrefix(key, prefix) || keys[key] {
+			continue
+		}
+		if err := c.barrier.Delete(key); err != nil {
+			return err
+		}
+		delete(c.persistedMountEntries, key)
+	}
+
+	return nil
+}
+
+// resetPersistedMountEntries replaces the hashes of the entries persisted
+// under the prefix with the given ones, which were just loaded
+func (c *Core) resetPersistedMountEntries(prefix string, hashes map[string][sha256.Size]byte) {
+	c.persistedMountEntriesLock.Lock()
+	defer c.persistedMountEntriesLock.Unlock()
+	if c.persistedMountEntries == nil {
+		c.persistedMountEntries = make(map[string][sha256.Size]byte)
+	}
+
+	for key := range c.persistedMountEntries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.persistedMountEntries, key)
+		}
+	}
+	for key, hash := range hashes {
+		c.persistedMountEntries[key] = hash
+	}
+}
@@ -0,0 +1,144 @@
+package vault
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestCore_MountTable_Sharded(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	inm := physical.NewInmemFaulty(logger)
+	conf := testCoreConfig(t, inm, logger)
+	c, err := NewCore(conf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	keys, _ := TestCoreInit(t, c)
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+
+	readIndex := func() *persistedMountTable {
+		raw, err := c.barrier.Get(coreMountConfigPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		persisted := &persistedMountTable{}
+		if err := jsonutil.DecodeJSON(raw.Value, persisted); err != nil {
+			t.Fatal(err)
+		}
+		return persisted
+	}
+
+	// Each entry is persisted on its own
+	persisted := readIndex()
+	if !persisted.Sharded || len(persisted.Entries) != 0 {
+		t.Fatalf("bad: %#v", persisted)
+	}
+	var secretUUID string
+	for _, entry := range c.mounts.Entries {
+		if entry.Path == "secret/" {
+			secretUUID = entry.UUID
+		}
+	}
+	if secretUUID == "" {
+		t.Fatal("missing secret mount")
+	}
+	for _, key := range persisted.EntryKeys {
+		raw, err := c.barrier.Get(coreMountEntriesPrefix + key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if raw == nil {
+			t.Fatalf("missing entry %q", key)
+		}
+	}
+
+	// Only the new entry is written when mounting
+	inm.Fail(physical.PutOperation, coreMountEntriesPrefix+secretUUID, nil)
+	me := &MountEntry{
+		Table: mountTableType,
+		Path:  "foo",
+		Type:  "generic",
+	}
+	if err := c.mount(me); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	inm.ClearFaults()
+	if raw, err := c.barrier.Get(coreMountEntriesPrefix + me.UUID); err != nil || raw == nil {
+		t.Fatalf("missing entry: %v", err)
+	}
+
+	// The entry is deleted when unmounting
+	if err := c.unmount("foo"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if raw, err := c.barrier.Get(coreMountEntriesPrefix + me.UUID); err != nil || raw != nil {
+		t.Fatalf("entry not deleted: %v", err)
+	}
+
+	// A table persisted as a whole is loaded, then sharded
+	var nonLocal []*MountEntry
+	for _, entry := range c.mounts.Entries {
+		if !entry.Local {
+			nonLocal = append(nonLocal, entry)
+		}
+	}
+	expected, err := json.Marshal(nonLocal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := jsonutil.EncodeJSONAndCompress(&MountTable{
+		Type:    mountTableType,
+		Entries: nonLocal,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.barrier.Put(&Entry{
+		Key:   coreMountConfigPath,
+		Value: legacy,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range nonLocal {
+		if err := c.barrier.Delete(coreMountEntriesPrefix + entry.UUID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.persistedMountEntries = nil
+
+	if err := c.loadMounts(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if persisted := readIndex(); !persisted.Sharded || len(persisted.EntryKeys) != len(nonLocal) {
+		t.Fatalf("bad: %#v", persisted)
+	}
+	loaded, _, err := c.loadMountTable(coreMountConfigPath, coreMountEntriesPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := json.Marshal(loaded.Entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: expected\n%s\nactual\n%s", expected, actual)
+	}
+
+	// A missing entry fails loading the table
+	if err := c.barrier.Delete(coreMountEntriesPrefix + secretUUID); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadMounts(); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -9,8 +9,10 @@ import (
 
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/compressutil"
-	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/logformat"
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
 )
 
 func TestCore_DefaultMountTable(t *testing.T) {
@@ -83,6 +85,49 @@ func TestCore_Mount(t *testing.T) {
 	}
 }
 
+func TestCore_Mount_PersistFailure(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	inm := physical.NewInmemFaulty(logger)
+	conf := testCoreConfig(t, inm, logger)
+	c, err := NewCore(conf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	keys, _ := TestCoreInit(t, c)
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+
+	// Failing to persist the mount table leaves the mount out
+	me := &MountEntry{
+		Table: mountTableType,
+		Path:  "foo",
+		Type:  "generic",
+	}
+	inm.FailNth(physical.PutOperation, coreMountConfigPath, 1, nil)
+	if err := c.mount(me); err == nil {
+		t.Fatal("expected error")
+	}
+	if match := c.router.MatchingMount("foo/bar"); match != "" {
+		t.Fatalf("unexpected mount: %q", match)
+	}
+	for _, entry := range c.mounts.Entries {
+		if entry.Path == "foo/" {
+			t.Fatal("failed mount in the mount table")
+		}
+	}
+
+	// Mounting again once the storage recovered succeeds
+	if err := c.mount(me); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if match := c.router.MatchingMount("foo/bar"); match != "foo/" {
+		t.Fatalf("missing mount")
+	}
+}
+
 // Test that the local table actually gets populated as expected with local
 // entries, and that upon reading the entries from both are recombined
 // correctly
@@ -118,17 +163,13 @@ func TestCore_Mount_Local(t *testing.T) {
 		t.Fatalf("expected two entries, got %d", len(c.mounts.Entries))
 	}
 
-	rawLocal, err := c.barrier.Get(coreLocalMountConfigPath)
+	localMountsTable, _, err := c.loadMountTable(coreLocalMountConfigPath, coreLocalMountEntriesPrefix)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if rawLocal == nil {
+	if localMountsTable == nil {
 		t.Fatal("expected non-nil local mounts")
 	}
-	localMountsTable := &MountTable{}
-	if err := jsonutil.DecodeJSON(rawLocal.Value, localMountsTable); err != nil {
-		t.Fatal(err)
-	}
 	if len(localMountsTable.Entries) != 1 || localMountsTable.Entries[0].Type != "cubbyhole" {
 		t.Fatalf("expected only cubbyhole entry in local mount table, got %#v", localMountsTable)
 	}
@@ -138,17 +179,13 @@ func TestCore_Mount_Local(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	rawLocal, err = c.barrier.Get(coreLocalMountConfigPath)
+	localMountsTable, _, err = c.loadMountTable(coreLocalMountConfigPath, coreLocalMountEntriesPrefix)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if rawLocal == nil {
+	if localMountsTable == nil {
 		t.Fatal("expected non-nil local mount")
 	}
-	localMountsTable = &MountTable{}
-	if err := jsonutil.DecodeJSON(rawLocal.Value, localMountsTable); err != nil {
-		t.Fatal(err)
-	}
 	// This requires some explanation: because we're directly munging the mount
 	// table, the table initially when core unseals contains cubbyhole as per
 	// above, but then we overwrite it with our own table with one local entry,
@@ -476,17 +513,19 @@ func testCore_MountTable_UpgradeToTyped_Common(
 	c *Core,
 	testType string) {
 
-	var path string
+	var path, prefix string
 	var mt *MountTable
 	switch testType {
 	case "mounts":
 		path = coreMountConfigPath
+		prefix = coreMountEntriesPrefix
 		mt = c.mounts
 	case "audits":
 		path = coreAuditConfigPath
 		mt = c.audit
 	case "credentials":
 		path = coreAuthConfigPath
+		prefix = coreAuthEntriesPrefix
 		mt = c.auth
 	}
 
@@ -551,19 +590,35 @@ func testCore_MountTable_UpgradeToTyped_Common(
 		t.Fatal(err)
 	}
 
-	entry, err = c.barrier.Get(path)
-	if err != nil {
-		t.Fatal(err)
-	}
+	var actual []byte
+	if prefix != "" {
+		// The mount and auth tables are migrated to sharded ones
+		loaded, needMigration, err := c.loadMountTable(path, prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if needMigration {
+			t.Fatal("expected the table to be sharded")
+		}
+		actual, err = json.Marshal(loaded)
+		if err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		entry, err = c.barrier.Get(path)
+		if err != nil {
+			t.Fatal(err)
+		}
 
-	decompressedBytes, uncompressed, err := compressutil.Decompress(entry.Value)
-	if err != nil {
-		t.Fatal(err)
-	}
+		decompressedBytes, uncompressed, err := compressutil.Decompress(entry.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
 
-	actual := decompressedBytes
-	if uncompressed {
-		actual = entry.Value
+		actual = decompressedBytes
+		if uncompressed {
+			actual = entry.Value
+		}
 	}
 
 	if strings.TrimSpace(string(actual)) != strings.TrimSpace(string(goodJson)) {
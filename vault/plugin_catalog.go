@@ -8,6 +8,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/vault/helper/builtinplugins"
 	"github.com/hashicorp/vault/helper/consts"
@@ -31,6 +32,19 @@ type PluginCatalog struct {
 	lock sync.RWMutex
 }
 
+// PluginLimits are the limits on the resources of an external plugin
+type PluginLimits struct {
+	// MaxMemory is the maximum memory, in bytes, the plugin process can use,
+	// or 0 for no limit
+	MaxMemory uint64
+
+	// MaxRestarts is the number of times the plugin can crash within the
+	// RestartWindow and be restarted before its mounts are quarantined, or 0
+	// for the default
+	MaxRestarts   int
+	RestartWindow time.Duration
+}
+
 func (c *Core) setupPluginCatalog() error {
 	c.pluginCatalog = &PluginCatalog{
 		catalogView: NewBarrierView(c.barrier, pluginCatalogPath),
@@ -79,8 +93,9 @@ func (c *PluginCatalog) Get(name string) (*pluginutil.PluginRunner, error) {
 }
 
 // Set registers a new external plugin with the catalog, or updates an existing
-// external plugin. It takes the name, command and SHA256 of the plugin.
-func (c *PluginCatalog) Set(name, command string, sha256 []byte) error {
+// external plugin. It takes the name, command, SHA256 and resource limits of
+// the plugin.
+func (c *PluginCatalog) Set(name, command string, sha256 []byte, limits PluginLimits) error {
 	if c.directory == "" {
 		return ErrDirectoryNotConfigured
 	}
@@ -119,6 +134,10 @@ func (c *PluginCatalog) Set(name, command string, sha256 []byte) error {
 		Args:    parts[1:],
 		Sha256:  sha256,
 		Builtin: false,
+
+		MaxMemory:     limits.MaxMemory,
+		MaxRestarts:   limits.MaxRestarts,
+		RestartWindow: limits.RestartWindow,
 	}
 
 	buf, err := json.Marshal(entry)
@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/helper/builtinplugins"
 	"github.com/hashicorp/vault/helper/pluginutil"
@@ -51,7 +52,11 @@ func TestPluginCatalog_CRUD(t *testing.T) {
 	defer file.Close()
 
 	command := fmt.Sprintf("%s --test", filepath.Base(file.Name()))
-	err = core.pluginCatalog.Set("mysql-database-plugin", command, []byte{'1'})
+	err = core.pluginCatalog.Set("mysql-database-plugin", command, []byte{'1'}, PluginLimits{
+		MaxMemory:     1 << 30,
+		MaxRestarts:   3,
+		RestartWindow: time.Minute,
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -68,6 +73,10 @@ func TestPluginCatalog_CRUD(t *testing.T) {
 		Args:    []string{"--test"},
 		Sha256:  []byte{'1'},
 		Builtin: false,
+
+		MaxMemory:     1 << 30,
+		MaxRestarts:   3,
+		RestartWindow: time.Minute,
 	}
 
 	if !reflect.DeepEqual(p, expected) {
@@ -140,13 +149,13 @@ func TestPluginCatalog_List(t *testing.T) {
 	defer file.Close()
 
 	command := fmt.Sprintf("%s --test", filepath.Base(file.Name()))
-	err = core.pluginCatalog.Set("mysql-database-plugin", command, []byte{'1'})
+	err = core.pluginCatalog.Set("mysql-database-plugin", command, []byte{'1'}, PluginLimits{})
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Set another plugin
-	err = core.pluginCatalog.Set("aaaaaaa", command, []byte{'1'})
+	err = core.pluginCatalog.Set("aaaaaaa", command, []byte{'1'}, PluginLimits{})
 	if err != nil {
 		t.Fatal(err)
 	}
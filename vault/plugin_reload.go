@@ -0,0 +1,136 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/logical"
+)
+
+// reloadMatchingPluginMounts reloads the plugin backends mounted at the given
+// paths. Auth mounts are specified with their "auth/" prefix.
+func (c *Core) reloadMatchingPluginMounts(mounts []string) error {
+	c.mountsLock.RLock()
+	defer c.mountsLock.RUnlock()
+	c.authLock.RLock()
+	defer c.authLock.RUnlock()
+
+	var retErr *multierror.Error
+	for _, mount := range mounts {
+		mount = strings.TrimPrefix(mount, "/")
+		if !strings.HasSuffix(mount, "/") {
+			mount += "/"
+		}
+
+		// The router matches the longest mount prefixing the path, so only
+		// accept the mount at exactly that path; paths within a mount or
+		// sharing a prefix with another one must not reload it
+		entry := c.router.MatchingMountEntry(mount)
+		if entry == nil || entry.routePath() != mount {
+			retErr = multierror.Append(retErr, fmt.Errorf("no mount found at '%s'", mount))
+			continue
+		}
+		if entry.Type != "plugin" {
+			retErr = multierror.Append(retErr, fmt.Errorf("mount at '%s' is not a plugin", mount))
+			continue
+		}
+
+		if err := c.reloadPluginBackend(entry); err != nil {
+			retErr = multierror.Append(retErr, err)
+			continue
+		}
+		c.pluginSupervisor.clear(entry.routePath())
+	}
+
+	return retErr.ErrorOrNil()
+}
+
+// reloadMatchingPlugin reloads every backend, logical or credential, that is
+// running the named plugin.
+func (c *Core) reloadMatchingPlugin(pluginName string) error {
+	c.mountsLock.RLock()
+	defer c.mountsLock.RUnlock()
+	c.authLock.RLock()
+	defer c.authLock.RUnlock()
+
+	var retErr *multierror.Error
+	for _, table := range []*MountTable{c.mounts, c.auth} {
+		if table == nil {
+			continue
+		}
+		for _, entry := range table.Entries {
+			if entry.Type != "plugin" || entry.Config.PluginName != pluginName {
+				continue
+			}
+			if err := c.reloadPluginBackend(entry); err != nil {
+				retErr = multierror.Append(retErr, err)
+				continue
+			}
+			c.pluginSupervisor.clear(entry.routePath())
+		}
+	}
+
+	return retErr.ErrorOrNil()
+}
+
+// reloadPluginBackend creates a new instance of the backend for the given
+// entry, which starts a new plugin process, and swaps it into the router.
+// The mount entry, its storage and its leases are left untouched. The
+// appropriate table lock must be held by the caller.
+func (c *Core) reloadPluginBackend(entry *MountEntry) error {
+	path := entry.routePath()
+
+	view := c.router.MatchingStorageView(path)
+	if view == nil {
+		return fmt.Errorf("no storage view found for '%s'", path)
+	}
+
+	sysView := c.mountEntrySysView(entry)
+	conf := entry.backendConfig()
+
+	var backend logical.Backend
+	var err error
+	switch entry.Table {
+	case credentialTableType:
+		backend, err = c.newCredentialBackend(entry.Type, sysView, view, conf)
+	default:
+		backend, err = c.newLogicalBackend(entry.Type, sysView, view, conf)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create backend for '%s': %v", path, err)
+	}
+	if backend == nil {
+		return fmt.Errorf("nil backend of type %q returned from creation function", entry.Type)
+	}
+
+	// Check for the correct backend type
+	backendType := backend.Type()
+	switch {
+	case entry.Table == credentialTableType && backendType != logical.TypeCredential:
+		backend.Cleanup()
+		return fmt.Errorf("cannot mount '%s' of type '%s' as an auth backend", entry.Config.PluginName, backendType)
+	case entry.Table != credentialTableType && backendType != logical.TypeLogical:
+		backend.Cleanup()
+		return fmt.Errorf("cannot mount '%s' of type '%s' as a logical backend", entry.Config.PluginName, backendType)
+	}
+
+	backend = c.supervisePluginBackend(entry, backend)
+
+	initialized, err := c.initializeBackend(entry, backend)
+	if err != nil {
+		backend.Cleanup()
+		return err
+	}
+	backend = initialized
+
+	if err := c.router.Reload(path, backend); err != nil {
+		backend.Cleanup()
+		return err
+	}
+
+	if c.logger.IsInfo() {
+		c.logger.Info("core: successfully reloaded plugin", "plugin", entry.Config.PluginName, "path", path)
+	}
+	return nil
+}
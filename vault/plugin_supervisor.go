@@ -0,0 +1,370 @@
+package vault
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/rpc"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// defaultPluginMaxRestarts and defaultPluginRestartWindow are the number
+	// of crashes within the window after which the mounts of a plugin are
+	// quarantined, if not set in the catalog
+	defaultPluginMaxRestarts   = 5
+	defaultPluginRestartWindow = 10 * time.Minute
+
+	// pluginRestartBackoff is the delay before restarting a plugin after its
+	// first crash, doubled for each crash within the restart window up to
+	// pluginRestartMaxBackoff
+	pluginRestartBackoff    = time.Second
+	pluginRestartMaxBackoff = time.Minute
+)
+
+const (
+	pluginStateRunning     = "running"
+	pluginStateRestarting  = "restarting"
+	pluginStateQuarantined = "quarantined"
+)
+
+// pluginMountStatus tracks the crashes of the plugin process backing a mount
+type pluginMountStatus struct {
+	entry     *MountEntry
+	state     string
+	crashes   []time.Time
+	restarts  int
+	lastError string
+	timer     Timer
+}
+
+// pluginSupervisor restarts the plugin processes backing mounts when they
+// crash, backing off as they keep crashing, and quarantines the mounts of
+// plugins crashing in a loop until they are reloaded
+type pluginSupervisor struct {
+	sync.Mutex
+
+	// mounts is keyed by the route path of the mounts, with the "auth/"
+	// prefix for auth mounts
+	mounts map[string]*pluginMountStatus
+}
+
+func newPluginSupervisor() *pluginSupervisor {
+	return &pluginSupervisor{
+		mounts: make(map[string]*pluginMountStatus),
+	}
+}
+
+// reset stops the pending restarts and forgets the crashes
+func (s *pluginSupervisor) reset() {
+	s.Lock()
+	defer s.Unlock()
+	for _, status := range s.mounts {
+		if status.timer != nil {
+			status.timer.Stop()
+		}
+	}
+	s.mounts = make(map[string]*pluginMountStatus)
+}
+
+// clear forgets the crashes of the plugin at the given path, after it was
+// reloaded manually
+func (s *pluginSupervisor) clear(path string) {
+	s.Lock()
+	defer s.Unlock()
+	if status, ok := s.mounts[path]; ok {
+		if status.timer != nil {
+			status.timer.Stop()
+		}
+		delete(s.mounts, path)
+	}
+}
+
+// check returns an error if the plugin at the given path is not running
+func (s *pluginSupervisor) check(path string) error {
+	s.Lock()
+	defer s.Unlock()
+	status, ok := s.mounts[path]
+	if !ok {
+		return nil
+	}
+	switch status.state {
+	case pluginStateRestarting:
+		return pluginUnavailableError("plugin backing '%s' crashed and is restarting", path)
+	case pluginStateQuarantined:
+		return pluginUnavailableError("plugin backing '%s' is quarantined after crashing %d times, reload it to lift the quarantine", path, len(status.crashes))
+	}
+	return nil
+}
+
+// pluginUnavailableError returns an error wrapping ErrPluginUnavailable, so
+// that it is responded to with a 503
+func pluginUnavailableError(format string, args ...interface{}) error {
+	return errwrap.Wrap(fmt.Errorf(format, args...), consts.ErrPluginUnavailable)
+}
+
+// routePath returns the path the entry is routed at
+func (e *MountEntry) routePath() string {
+	if e.Table == credentialTableType {
+		return credentialRoutePrefix + e.Path
+	}
+	return e.Path
+}
+
+// supervisePluginBackend wraps the backends of plugin mounts so that the
+// crashes of their plugin process are detected
+func (c *Core) supervisePluginBackend(entry *MountEntry, backend logical.Backend) logical.Backend {
+	if entry.Type != "plugin" {
+		return backend
+	}
+	return &supervisedBackend{
+		Backend: backend,
+		core:    c,
+		entry:   entry,
+		version: c.pluginVersion(entry.Config.PluginName),
+	}
+}
+
+// pluginVersion returns the version of the plugin registered in the catalog
+// under the given name: "builtin" for builtin plugins, or the SHA256 of the
+// binary for external ones
+func (c *Core) pluginVersion(name string) string {
+	if c.pluginCatalog == nil {
+		return ""
+	}
+	runner, err := c.pluginCatalog.Get(name)
+	if err != nil || runner == nil {
+		return ""
+	}
+	if runner.Builtin {
+		return "builtin"
+	}
+	return "sha256:" + hex.EncodeToString(runner.Sha256)
+}
+
+// runningPluginVersion returns the version of the plugin the backend of the
+// entry was started from, which differs from the one in the catalog if the
+// plugin was registered again since, or "" if the entry is not a plugin
+func (c *Core) runningPluginVersion(entry *MountEntry) string {
+	backend := c.router.MatchingBackend(entry.routePath())
+	if lazy, ok := backend.(*lazyInitBackend); ok {
+		backend = lazy.Backend
+	}
+	if supervised, ok := backend.(*supervisedBackend); ok {
+		return supervised.version
+	}
+	return ""
+}
+
+// pluginCrashed schedules the restart of the plugin backing the entry, or
+// quarantines the mount if the plugin crashed too many times
+func (c *Core) pluginCrashed(entry *MountEntry, err error) {
+	maxRestarts, window := defaultPluginMaxRestarts, defaultPluginRestartWindow
+	if c.pluginCatalog != nil {
+		if runner, _ := c.pluginCatalog.Get(entry.Config.PluginName); runner != nil {
+			if runner.MaxRestarts > 0 {
+				maxRestarts = runner.MaxRestarts
+			}
+			if runner.RestartWindow > 0 {
+				window = runner.RestartWindow
+			}
+		}
+	}
+
+	s := c.pluginSupervisor
+	s.Lock()
+	defer s.Unlock()
+
+	path := entry.routePath()
+	status, ok := s.mounts[path]
+	if !ok {
+		status = &pluginMountStatus{
+			entry: entry,
+			state: pluginStateRunning,
+		}
+		s.mounts[path] = status
+	}
+	if status.state != pluginStateRunning {
+		return
+	}
+
+	// Only the crashes within the window count
+	now := c.clock.Now()
+	crashes := status.crashes[:0]
+	for _, t := range status.crashes {
+		if now.Sub(t) < window {
+			crashes = append(crashes, t)
+		}
+	}
+	status.crashes = append(crashes, now)
+	status.lastError = err.Error()
+
+	if len(status.crashes) > maxRestarts {
+		status.state = pluginStateQuarantined
+		c.logger.Error("core: plugin crashed too many times, quarantining mount", "plugin", entry.Config.PluginName, "path", path, "crashes", len(status.crashes), "error", err)
+		return
+	}
+
+	backoff := pluginRestartBackoff << uint(len(status.crashes)-1)
+	if backoff > pluginRestartMaxBackoff || backoff <= 0 {
+		backoff = pluginRestartMaxBackoff
+	}
+	status.state = pluginStateRestarting
+	status.timer = c.clock.AfterFunc(backoff, func() {
+		c.restartPlugin(path, status)
+	})
+	c.logger.Warn("core: plugin crashed, restarting", "plugin", entry.Config.PluginName, "path", path, "backoff", backoff, "error", err)
+}
+
+// restartPlugin restarts the plugin backing the given path after a crash
+func (c *Core) restartPlugin(path string, status *pluginMountStatus) {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed || c.standby {
+		return
+	}
+
+	c.mountsLock.RLock()
+	defer c.mountsLock.RUnlock()
+	c.authLock.RLock()
+	defer c.authLock.RUnlock()
+
+	s := c.pluginSupervisor
+	s.Lock()
+	if s.mounts[path] != status {
+		// Reloaded or reset in the meantime
+		s.Unlock()
+		return
+	}
+	s.Unlock()
+
+	// The mount may have been removed in the meantime
+	entry := c.router.MatchingMountEntry(path)
+	if entry == nil || entry.UUID != status.entry.UUID {
+		s.clear(path)
+		return
+	}
+
+	err := c.reloadPluginBackend(entry)
+
+	s.Lock()
+	if s.mounts[path] != status {
+		s.Unlock()
+		return
+	}
+	status.timer = nil
+	status.state = pluginStateRunning
+	if err == nil {
+		status.restarts++
+	}
+	s.Unlock()
+
+	// Failing to restart counts as another crash
+	if err != nil {
+		c.pluginCrashed(entry, err)
+	}
+}
+
+// pluginMountStatuses returns the status of the mounts of the given plugin
+// which crashed
+func (c *Core) pluginMountStatuses(pluginName string) []map[string]interface{} {
+	s := c.pluginSupervisor
+	s.Lock()
+	defer s.Unlock()
+
+	var paths []string
+	for path, status := range s.mounts {
+		if status.entry.Config.PluginName == pluginName {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	ret := make([]map[string]interface{}, 0, len(paths))
+	for _, path := range paths {
+		status := s.mounts[path]
+		var lastCrash time.Time
+		if len(status.crashes) > 0 {
+			lastCrash = status.crashes[len(status.crashes)-1]
+		}
+		ret = append(ret, map[string]interface{}{
+			"path":       path,
+			"state":      status.state,
+			"crashes":    len(status.crashes),
+			"restarts":   status.restarts,
+			"last_crash": lastCrash,
+			"last_error": status.lastError,
+		})
+	}
+	return ret
+}
+
+// QuarantinedPluginMounts returns the paths of the mounts quarantined because
+// their plugin crashed too many times
+func (c *Core) QuarantinedPluginMounts() []string {
+	s := c.pluginSupervisor
+	s.Lock()
+	defer s.Unlock()
+
+	var paths []string
+	for path, status := range s.mounts {
+		if status.state == pluginStateQuarantined {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// supervisedBackend reports the crashes of the plugin process of a backend
+type supervisedBackend struct {
+	logical.Backend
+
+	core  *Core
+	entry *MountEntry
+
+	// version is the version of the plugin when the backend was started
+	version string
+}
+
+func (b *supervisedBackend) HandleRequest(req *logical.Request) (*logical.Response, error) {
+	if err := b.core.pluginSupervisor.check(b.entry.routePath()); err != nil {
+		return nil, err
+	}
+	resp, err := b.Backend.HandleRequest(req)
+	if err != nil && b.crashed(err) {
+		b.core.pluginCrashed(b.entry, err)
+		return nil, pluginUnavailableError("plugin backing '%s' crashed: %v", b.entry.routePath(), err)
+	}
+	return resp, err
+}
+
+func (b *supervisedBackend) HandleExistenceCheck(req *logical.Request) (bool, bool, error) {
+	if err := b.core.pluginSupervisor.check(b.entry.routePath()); err != nil {
+		return false, false, err
+	}
+	checkFound, exists, err := b.Backend.HandleExistenceCheck(req)
+	if err != nil && b.crashed(err) {
+		b.core.pluginCrashed(b.entry, err)
+		return false, false, pluginUnavailableError("plugin backing '%s' crashed: %v", b.entry.routePath(), err)
+	}
+	return checkFound, exists, err
+}
+
+// crashed returns whether the error of a call is due to the plugin process
+// having exited. The connection can be seen closed before the exit of the
+// process is noticed.
+func (b *supervisedBackend) crashed(err error) bool {
+	if exiter, ok := b.Backend.(interface {
+		Exited() bool
+	}); ok && exiter.Exited() {
+		return true
+	}
+	return err == rpc.ErrShutdown || err == io.ErrUnexpectedEOF
+}
@@ -0,0 +1,145 @@
+package vault
+
+import (
+	"net/rpc"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// crashingBackend fails its requests the way a plugin backend whose process
+// exited does, while crashing is set
+type crashingBackend struct {
+	NoopBackend
+
+	crashing *int32
+}
+
+func (b *crashingBackend) HandleRequest(req *logical.Request) (*logical.Response, error) {
+	if atomic.LoadInt32(b.crashing) == 1 {
+		return nil, rpc.ErrShutdown
+	}
+	return b.NoopBackend.HandleRequest(req)
+}
+
+func TestCore_PluginSupervisor(t *testing.T) {
+	var crashing int32
+	var started int32
+
+	clock := NewTestClock(time.Unix(time.Now().Unix(), 0))
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	conf := testCoreConfig(t, physical.NewInmem(logger), logger)
+	conf.Clock = clock
+	conf.LogicalBackends["plugin"] = func(config *logical.BackendConfig) (logical.Backend, error) {
+		atomic.AddInt32(&started, 1)
+		return &crashingBackend{crashing: &crashing}, nil
+	}
+	c, err := NewCore(conf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	keys, root := TestCoreInit(t, c)
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+
+	me := &MountEntry{
+		Table: mountTableType,
+		Path:  "fake/",
+		Type:  "plugin",
+		Config: MountConfig{
+			PluginName: "fake",
+		},
+	}
+	if err := c.mount(me); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	read := func() error {
+		_, err := c.HandleRequest(&logical.Request{
+			Operation:   logical.ReadOperation,
+			Path:        "fake/foo",
+			ClientToken: root,
+		})
+		return err
+	}
+	assertUnavailable := func(err error) {
+		if !errwrap.Contains(err, consts.ErrPluginUnavailable.Error()) {
+			t.Fatalf("expected the plugin to be unavailable, got %v", err)
+		}
+	}
+
+	// A crash makes the mount unavailable until the plugin is restarted
+	atomic.StoreInt32(&crashing, 1)
+	assertUnavailable(read())
+	assertUnavailable(read())
+	if n := atomic.LoadInt32(&started); n != 1 {
+		t.Fatalf("expected the plugin to be started once, got %d", n)
+	}
+
+	atomic.StoreInt32(&crashing, 0)
+	clock.Advance(pluginRestartBackoff)
+	if n := atomic.LoadInt32(&started); n != 2 {
+		t.Fatalf("expected the plugin to be restarted, got %d starts", n)
+	}
+	if err := read(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	statuses := c.pluginMountStatuses("fake")
+	if len(statuses) != 1 || statuses[0]["state"] != pluginStateRunning || statuses[0]["restarts"] != 1 {
+		t.Fatalf("bad: %#v", statuses)
+	}
+
+	// Crashing in a loop quarantines the mount, with the backoff doubling
+	// after each crash
+	atomic.StoreInt32(&crashing, 1)
+	backoff := 2 * pluginRestartBackoff
+	for i := 1; i < defaultPluginMaxRestarts; i++ {
+		assertUnavailable(read())
+		clock.Advance(backoff - time.Millisecond)
+		assertUnavailable(read())
+		clock.Advance(time.Millisecond)
+		backoff *= 2
+	}
+	assertUnavailable(read())
+
+	if q := c.QuarantinedPluginMounts(); !reflect.DeepEqual(q, []string{"fake/"}) {
+		t.Fatalf("bad: %v", q)
+	}
+	atomic.StoreInt32(&crashing, 0)
+	clock.Advance(time.Hour)
+	assertUnavailable(read())
+
+	// Reloading the plugin lifts the quarantine
+	resp, err := c.HandleRequest(&logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "sys/plugins/reload/backend",
+		ClientToken: root,
+		Data: map[string]interface{}{
+			"mounts": "fake/",
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+	if q := c.QuarantinedPluginMounts(); len(q) != 0 {
+		t.Fatalf("bad: %v", q)
+	}
+	if err := read(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if statuses := c.pluginMountStatuses("fake"); len(statuses) != 0 {
+		t.Fatalf("bad: %#v", statuses)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
 	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/schedule"
 	"github.com/mitchellh/copystructure"
 )
 
@@ -24,6 +25,10 @@ const (
 	SudoCapability   = "sudo"
 	RootCapability   = "root"
 
+	// ApproveCapability allows approving control group requests. It is never
+	// implied by other capabilities, nor by root tokens.
+	ApproveCapability = "approve"
+
 	// Backwards compatibility
 	OldDenyPathPolicy  = "deny"
 	OldReadPathPolicy  = "read"
@@ -39,17 +44,19 @@ const (
 	DeleteCapabilityInt
 	ListCapabilityInt
 	SudoCapabilityInt
+	ApproveCapabilityInt
 )
 
 var (
 	cap2Int = map[string]uint32{
-		DenyCapability:   DenyCapabilityInt,
-		CreateCapability: CreateCapabilityInt,
-		ReadCapability:   ReadCapabilityInt,
-		UpdateCapability: UpdateCapabilityInt,
-		DeleteCapability: DeleteCapabilityInt,
-		ListCapability:   ListCapabilityInt,
-		SudoCapability:   SudoCapabilityInt,
+		DenyCapability:    DenyCapabilityInt,
+		CreateCapability:  CreateCapabilityInt,
+		ReadCapability:    ReadCapabilityInt,
+		UpdateCapability:  UpdateCapabilityInt,
+		DeleteCapability:  DeleteCapabilityInt,
+		ListCapability:    ListCapabilityInt,
+		SudoCapability:    SudoCapabilityInt,
+		ApproveCapability: ApproveCapabilityInt,
 	}
 )
 
@@ -75,6 +82,7 @@ type PathCapabilities struct {
 	MaxWrappingTTLHCL    interface{}              `hcl:"max_wrapping_ttl"`
 	AllowedParametersHCL map[string][]interface{} `hcl:"allowed_parameters"`
 	DeniedParametersHCL  map[string][]interface{} `hcl:"denied_parameters"`
+	AllowedWindowsHCL    []string                 `hcl:"allowed_windows"`
 }
 
 type Permissions struct {
@@ -83,6 +91,21 @@ type Permissions struct {
 	MaxWrappingTTL     time.Duration
 	AllowedParameters  map[string][]interface{}
 	DeniedParameters   map[string][]interface{}
+
+	// AllowedWindows restricts the times at which the capabilities can be
+	// used; if empty, they can be used at all times
+	AllowedWindows schedule.Windows
+
+	// WindowedCapabilities holds, in ACLs, the capabilities of each merged
+	// policy that set allowed windows, which are only granted within the
+	// windows of that policy
+	WindowedCapabilities []WindowedCapabilities
+}
+
+// WindowedCapabilities are capabilities only granted within allowed windows
+type WindowedCapabilities struct {
+	CapabilitiesBitmap uint32
+	AllowedWindows     schedule.Windows
 }
 
 func (p *Permissions) Clone() (*Permissions, error) {
@@ -92,6 +115,13 @@ func (p *Permissions) Clone() (*Permissions, error) {
 		MaxWrappingTTL:     p.MaxWrappingTTL,
 	}
 
+	if len(p.AllowedWindows) > 0 {
+		ret.AllowedWindows = append(schedule.Windows(nil), p.AllowedWindows...)
+	}
+	if len(p.WindowedCapabilities) > 0 {
+		ret.WindowedCapabilities = append([]WindowedCapabilities(nil), p.WindowedCapabilities...)
+	}
+
 	switch {
 	case p.AllowedParameters == nil:
 	case len(p.AllowedParameters) == 0:
@@ -174,6 +204,7 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 			"denied_parameters",
 			"min_wrapping_ttl",
 			"max_wrapping_ttl",
+			"allowed_windows",
 		}
 		if err := checkHCLKeys(item.Val, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("path %q:", key))
@@ -225,7 +256,7 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 				pc.Capabilities = []string{DenyCapability}
 				pc.Permissions.CapabilitiesBitmap = DenyCapabilityInt
 				goto PathFinished
-			case CreateCapability, ReadCapability, UpdateCapability, DeleteCapability, ListCapability, SudoCapability:
+			case CreateCapability, ReadCapability, UpdateCapability, DeleteCapability, ListCapability, SudoCapability, ApproveCapability:
 				pc.Permissions.CapabilitiesBitmap |= cap2Int[cap]
 			default:
 				return fmt.Errorf("path %q: invalid capability '%s'", key, cap)
@@ -264,6 +295,13 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 			pc.Permissions.MaxWrappingTTL < pc.Permissions.MinWrappingTTL {
 			return errors.New("max_wrapping_ttl cannot be less than min_wrapping_ttl")
 		}
+		if len(pc.AllowedWindowsHCL) > 0 {
+			windows, err := schedule.ParseWindows(pc.AllowedWindowsHCL)
+			if err != nil {
+				return errwrap.Wrapf("error parsing allowed_windows: {{err}}", err)
+			}
+			pc.Permissions.AllowedWindows = windows
+		}
 
 	PathFinished:
 		paths = append(paths, &pc)
@@ -0,0 +1,309 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// PolicyFindingShadowed is reported for a glob rule whose capabilities
+	// do not apply to some of the paths it matches because a more specific
+	// rule takes precedence
+	PolicyFindingShadowed = "shadowed"
+
+	// PolicyFindingUnreachable is reported for a rule that can never grant
+	// its capabilities
+	PolicyFindingUnreachable = "unreachable"
+
+	// PolicyFindingBroadGlob is reported for a glob rule granting
+	// capabilities on an overly large part of the namespace
+	PolicyFindingBroadGlob = "broad_glob"
+)
+
+// PolicyAnalysis is the result of the static analysis of a set of policies
+type PolicyAnalysis struct {
+	// Paths holds the effective capabilities on each of the analyzed paths
+	Paths map[string]*PathAnalysis
+
+	// Findings lists the issues found in the rules of the policies
+	Findings []*PolicyFinding
+}
+
+// PathAnalysis holds the effective capabilities of a set of policies on a
+// path and the rule granting them
+type PathAnalysis struct {
+	Capabilities []string
+
+	// Rule is the path of the rule matching the path, with a trailing * for
+	// glob rules, and Policies the policies defining it. Rule is empty if
+	// no rule matches.
+	Rule     string
+	Policies []string
+}
+
+// PolicyFinding is an issue found in the rules of a set of policies
+type PolicyFinding struct {
+	Type string
+
+	// Rule is the path of the offending rule, and Policies the policies
+	// defining it
+	Rule     string
+	Policies []string
+
+	Message string
+}
+
+// analyzedRule is the rule for a path, merged across the policies of a set
+// in the same way as in the ACL
+type analyzedRule struct {
+	prefix string
+	glob   bool
+
+	// capabilities is the merged capabilities bitmap, and grants the
+	// bitmap given by each policy
+	capabilities uint32
+	grants       map[string]uint32
+	policies     []string
+}
+
+func (r *analyzedRule) String() string {
+	if r.glob {
+		return r.prefix + "*"
+	}
+	return r.prefix
+}
+
+func (r *analyzedRule) denies() bool {
+	return r.capabilities&DenyCapabilityInt > 0
+}
+
+// AnalyzePolicies statically analyzes a set of policies: it reports the
+// effective capabilities they grant on the given paths, the rules that are
+// shadowed by more specific ones or cannot grant anything, and the glob
+// rules granting capabilities on too large a part of the namespace.
+func (c *Core) AnalyzePolicies(policies []*Policy, paths []string) (*PolicyAnalysis, error) {
+	acl, err := NewACL(policies)
+	if err != nil {
+		return nil, err
+	}
+	rules := analyzeRules(policies)
+
+	result := &PolicyAnalysis{
+		Paths: make(map[string]*PathAnalysis, len(paths)),
+	}
+	for _, path := range paths {
+		capabilities := acl.Capabilities(path)
+		sort.Strings(capabilities)
+		pa := &PathAnalysis{
+			Capabilities: capabilities,
+		}
+		if !acl.root {
+			if rule := matchingRule(rules, path); rule != nil {
+				pa.Rule = rule.String()
+				pa.Policies = rule.policies
+			}
+		}
+		result.Paths[path] = pa
+	}
+
+	for _, rule := range rules {
+		result.Findings = append(result.Findings, c.unreachableFindings(rule)...)
+		if reason := broadGlob(rule); reason != "" {
+			result.Findings = append(result.Findings, &PolicyFinding{
+				Type:     PolicyFindingBroadGlob,
+				Rule:     rule.String(),
+				Policies: rule.policies,
+				Message:  reason,
+			})
+		}
+	}
+	for _, rule := range rules {
+		if finding := shadowedFinding(rules, rule); finding != nil {
+			result.Findings = append(result.Findings, finding)
+		}
+	}
+
+	return result, nil
+}
+
+// analyzeRules merges the rules of the policies, sorted by path
+func analyzeRules(policies []*Policy) []*analyzedRule {
+	byKey := make(map[string]*analyzedRule)
+	var rules []*analyzedRule
+	for _, policy := range policies {
+		if policy == nil {
+			continue
+		}
+		for _, pc := range policy.Paths {
+			key := pc.Prefix
+			if pc.Glob {
+				key += "*"
+			}
+			rule, ok := byKey[key]
+			if !ok {
+				rule = &analyzedRule{
+					prefix: pc.Prefix,
+					glob:   pc.Glob,
+					grants: make(map[string]uint32),
+				}
+				byKey[key] = rule
+				rules = append(rules, rule)
+			}
+
+			bitmap := pc.Permissions.CapabilitiesBitmap
+			if _, ok := rule.grants[policy.Name]; !ok {
+				rule.policies = append(rule.policies, policy.Name)
+			}
+			rule.grants[policy.Name] |= bitmap
+
+			switch {
+			case rule.denies():
+			case bitmap&DenyCapabilityInt > 0:
+				rule.capabilities = DenyCapabilityInt
+			default:
+				rule.capabilities |= bitmap
+			}
+		}
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].String() < rules[j].String()
+	})
+	return rules
+}
+
+// matchingRule returns the rule applying to the path: the exact rule if any,
+// or the glob rule with the longest prefix
+func matchingRule(rules []*analyzedRule, path string) *analyzedRule {
+	for _, rule := range rules {
+		if !rule.glob && rule.prefix == path {
+			return rule
+		}
+	}
+	return enclosingGlob(rules, path, false)
+}
+
+// enclosingGlob returns the glob rule with the longest prefix of the path. If
+// strict is set, the glob rule of the path itself is ignored.
+func enclosingGlob(rules []*analyzedRule, path string, strict bool) *analyzedRule {
+	var match *analyzedRule
+	for _, rule := range rules {
+		if !rule.glob || (strict && rule.prefix == path) {
+			continue
+		}
+		if strings.HasPrefix(path, rule.prefix) && (match == nil || len(rule.prefix) > len(match.prefix)) {
+			match = rule
+		}
+	}
+	return match
+}
+
+// unreachableFindings reports the capabilities of a rule overridden by a
+// deny in another policy, and rules on paths where nothing is mounted
+func (c *Core) unreachableFindings(rule *analyzedRule) []*PolicyFinding {
+	var findings []*PolicyFinding
+
+	if rule.denies() {
+		var denying []string
+		for _, name := range rule.policies {
+			if rule.grants[name]&DenyCapabilityInt > 0 {
+				denying = append(denying, name)
+			}
+		}
+		for _, name := range rule.policies {
+			if granted := rule.grants[name]; granted != 0 && granted&DenyCapabilityInt == 0 {
+				findings = append(findings, &PolicyFinding{
+					Type:     PolicyFindingUnreachable,
+					Rule:     rule.String(),
+					Policies: []string{name},
+					Message: fmt.Sprintf("capabilities %s are overridden by the deny in policies %s",
+						strings.Join(capabilityNames(granted), ", "), strings.Join(denying, ", ")),
+				})
+			}
+		}
+	}
+
+	mounted := c.router.MatchingMount(rule.prefix) != ""
+	if !mounted && rule.glob {
+		mounted = c.router.HasMountUnder(rule.prefix)
+	}
+	if !mounted {
+		findings = append(findings, &PolicyFinding{
+			Type:     PolicyFindingUnreachable,
+			Rule:     rule.String(),
+			Policies: rule.policies,
+			Message:  "no backend is mounted at a path matching the rule",
+		})
+	}
+
+	return findings
+}
+
+// shadowedFinding reports the capabilities of the glob rule enclosing a rule
+// that do not apply to the paths matching the rule, which takes precedence
+func shadowedFinding(rules []*analyzedRule, rule *analyzedRule) *PolicyFinding {
+	if rule.denies() {
+		return nil
+	}
+	enclosing := enclosingGlob(rules, rule.prefix, rule.glob)
+	if enclosing == nil {
+		return nil
+	}
+
+	var message string
+	switch {
+	case enclosing.denies():
+		message = fmt.Sprintf("the deny does not apply to paths matching %q, which grants %s",
+			rule.String(), strings.Join(capabilityNames(rule.capabilities), ", "))
+	case enclosing.capabilities&^rule.capabilities != 0:
+		message = fmt.Sprintf("capabilities %s do not apply to paths matching %q",
+			strings.Join(capabilityNames(enclosing.capabilities&^rule.capabilities), ", "), rule.String())
+	default:
+		return nil
+	}
+
+	return &PolicyFinding{
+		Type:     PolicyFindingShadowed,
+		Rule:     enclosing.String(),
+		Policies: enclosing.policies,
+		Message:  message,
+	}
+}
+
+// broadGlob returns why a glob rule grants capabilities on too large a part
+// of the namespace, or an empty string if it does not
+func broadGlob(rule *analyzedRule) string {
+	if !rule.glob || rule.denies() || rule.capabilities == 0 {
+		return ""
+	}
+
+	prefix := rule.prefix
+	switch {
+	case prefix == "":
+		return "the rule matches every path"
+	case strings.HasPrefix("sys/", prefix):
+		return "the rule matches every path of the system backend"
+	case strings.HasPrefix("auth/", prefix):
+		return "the rule matches every path of all the auth backends"
+	case !strings.Contains(prefix, "/"):
+		return "the rule matches the paths of every mount starting with " + prefix
+	case strings.HasPrefix("auth/token/", prefix):
+		return "the rule matches every path of the token store"
+	case strings.HasPrefix(prefix, "sys/") && rule.capabilities&SudoCapabilityInt > 0:
+		return "the rule grants sudo on every path under " + prefix
+	}
+	return ""
+}
+
+// capabilityNames returns the names of the capabilities of a bitmap
+func capabilityNames(bitmap uint32) []string {
+	var names []string
+	for _, name := range []string{DenyCapability, CreateCapability, ReadCapability,
+		UpdateCapability, DeleteCapability, ListCapability, SudoCapability} {
+		if bitmap&cap2Int[name] > 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
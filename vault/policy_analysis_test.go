@@ -0,0 +1,164 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestCore_AnalyzePolicies(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	dev, err := Parse(`
+path "secret/*" {
+	capabilities = ["deny"]
+}
+path "secret/dev/*" {
+	capabilities = ["read", "list"]
+}
+path "secret/shared" {
+	capabilities = ["read"]
+}
+path "sys/*" {
+	capabilities = ["read"]
+}
+path "nothing/here" {
+	capabilities = ["read"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev.Name = "dev"
+
+	ops, err := Parse(`
+path "secret/shared" {
+	capabilities = ["deny"]
+}
+path "cubbyhole/*" {
+	capabilities = ["create", "read", "update"]
+}
+path "cubbyhole/ro/*" {
+	capabilities = ["read"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops.Name = "ops"
+
+	analysis, err := c.AnalyzePolicies([]*Policy{dev, ops}, []string{"secret/dev/foo", "secret/shared", "cubbyhole/ro/x", "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expPaths := map[string]*PathAnalysis{
+		"secret/dev/foo": &PathAnalysis{
+			Capabilities: []string{"list", "read"},
+			Rule:         "secret/dev/*",
+			Policies:     []string{"dev"},
+		},
+		"secret/shared": &PathAnalysis{
+			Capabilities: []string{"deny"},
+			Rule:         "secret/shared",
+			Policies:     []string{"dev", "ops"},
+		},
+		"cubbyhole/ro/x": &PathAnalysis{
+			Capabilities: []string{"read"},
+			Rule:         "cubbyhole/ro/*",
+			Policies:     []string{"ops"},
+		},
+		"other": &PathAnalysis{
+			Capabilities: []string{"deny"},
+		},
+	}
+	if !reflect.DeepEqual(analysis.Paths, expPaths) {
+		for path, pa := range analysis.Paths {
+			t.Logf("%s: %#v", path, pa)
+		}
+		t.Fatal("bad paths")
+	}
+
+	expFindings := []*PolicyFinding{
+		&PolicyFinding{
+			Type:     PolicyFindingUnreachable,
+			Rule:     "nothing/here",
+			Policies: []string{"dev"},
+			Message:  "no backend is mounted at a path matching the rule",
+		},
+		&PolicyFinding{
+			Type:     PolicyFindingUnreachable,
+			Rule:     "secret/shared",
+			Policies: []string{"dev"},
+			Message:  "capabilities read are overridden by the deny in policies ops",
+		},
+		&PolicyFinding{
+			Type:     PolicyFindingBroadGlob,
+			Rule:     "sys/*",
+			Policies: []string{"dev"},
+			Message:  "the rule matches every path of the system backend",
+		},
+		&PolicyFinding{
+			Type:     PolicyFindingShadowed,
+			Rule:     "cubbyhole/*",
+			Policies: []string{"ops"},
+			Message:  `capabilities create, update do not apply to paths matching "cubbyhole/ro/*"`,
+		},
+		&PolicyFinding{
+			Type:     PolicyFindingShadowed,
+			Rule:     "secret/*",
+			Policies: []string{"dev"},
+			Message:  `the deny does not apply to paths matching "secret/dev/*", which grants read, list`,
+		},
+	}
+	if !reflect.DeepEqual(analysis.Findings, expFindings) {
+		for _, finding := range analysis.Findings {
+			t.Logf("%#v", finding)
+		}
+		t.Fatal("bad findings")
+	}
+}
+
+func TestSystemBackend_policiesAnalyze(t *testing.T) {
+	_, b, _ := testCoreSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "policy/broad")
+	req.Data["rules"] = `path "*" { capabilities = ["read"] }`
+	if _, err := b.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "policies/analyze")
+	req.Data["policies"] = "broad,default"
+	req.Data["rules"] = map[string]interface{}{
+		"default": `path "secret/foo" { capabilities = ["update"] }`,
+	}
+	req.Data["paths"] = "secret/foo,secret/bar"
+	resp, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := resp.Data["paths"].(map[string]interface{})
+	foo := paths["secret/foo"].(map[string]interface{})
+	if !reflect.DeepEqual(foo["capabilities"], []string{"update"}) || foo["rule"] != "secret/foo" {
+		t.Fatalf("bad: %#v", foo)
+	}
+	bar := paths["secret/bar"].(map[string]interface{})
+	if !reflect.DeepEqual(bar["capabilities"], []string{"read"}) || !reflect.DeepEqual(bar["policies"], []string{"broad"}) {
+		t.Fatalf("bad: %#v", bar)
+	}
+
+	findings := resp.Data["findings"].([]map[string]interface{})
+	if len(findings) != 2 || findings[0]["type"] != PolicyFindingBroadGlob || findings[1]["type"] != PolicyFindingShadowed {
+		t.Fatalf("bad: %#v", findings)
+	}
+
+	// Unknown policies are rejected
+	req.Data["policies"] = "missing"
+	resp, err = b.HandleRequest(req)
+	if err != logical.ErrInvalidRequest || !resp.IsError() {
+		t.Fatalf("bad: %#v %v", resp, err)
+	}
+}
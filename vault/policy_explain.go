@@ -0,0 +1,72 @@
+package vault
+
+import (
+	"sort"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// PolicyExplanation describes how a set of policies evaluates a request
+type PolicyExplanation struct {
+	Allowed bool
+
+	// Reason explains why the request is allowed or denied
+	Reason string
+
+	// Capabilities holds the capabilities the policies grant on the path
+	Capabilities []string
+
+	// Rule is the path of the rule matching the path, with a trailing * for
+	// glob rules, and Policies the policies defining it. Grants holds the
+	// capabilities each of these policies gives the rule on its own; a deny
+	// in any of them overrides the grants of the others.
+	Rule     string
+	Policies []string
+	Grants   map[string][]string
+
+	// SudoRequired is set if the path is root protected, and Sudo if the
+	// policies grant sudo on it
+	SudoRequired bool
+	Sudo         bool
+}
+
+// ExplainPolicies evaluates the request against a set of policies in the
+// same way as a request made with a token holding them, and reports the
+// rule deciding the outcome and the policies defining it. The operation of
+// the request is taken as is: no existence check turns an update into a
+// create.
+func (c *Core) ExplainPolicies(policies []*Policy, req *logical.Request) (*PolicyExplanation, error) {
+	acl, err := NewACL(policies)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, sudo, reason := acl.allowOperation(req)
+	capabilities := acl.Capabilities(req.Path)
+	sort.Strings(capabilities)
+
+	result := &PolicyExplanation{
+		Allowed:      allowed,
+		Reason:       reason,
+		Capabilities: capabilities,
+		SudoRequired: c.router.RootPath(req.Path),
+		Sudo:         sudo,
+	}
+	if allowed && result.SudoRequired && !sudo {
+		result.Allowed = false
+		result.Reason = "the path requires sudo, which the rule does not grant"
+	}
+
+	if !acl.root {
+		if rule := matchingRule(analyzeRules(policies), req.Path); rule != nil {
+			result.Rule = rule.String()
+			result.Policies = rule.policies
+			result.Grants = make(map[string][]string, len(rule.grants))
+			for name, bitmap := range rule.grants {
+				result.Grants[name] = capabilityNames(bitmap)
+			}
+		}
+	}
+
+	return result, nil
+}
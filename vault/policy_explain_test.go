@@ -0,0 +1,164 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestCore_ExplainPolicies(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	dev, err := Parse(`
+path "secret/*" {
+	capabilities = ["create", "read", "update"]
+	denied_parameters = {
+		"admin" = []
+	}
+}
+path "secret/shared" {
+	capabilities = ["read", "update"]
+}
+path "sys/rotate" {
+	capabilities = ["update"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev.Name = "dev"
+
+	ops, err := Parse(`
+path "secret/shared" {
+	capabilities = ["deny"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops.Name = "ops"
+
+	policies := []*Policy{dev, ops}
+	cases := []struct {
+		op   logical.Operation
+		path string
+		data map[string]interface{}
+		exp  *PolicyExplanation
+	}{
+		{
+			logical.ReadOperation, "secret/foo", nil,
+			&PolicyExplanation{
+				Allowed:      true,
+				Reason:       "the rule grants the operation",
+				Capabilities: []string{"create", "read", "update"},
+				Rule:         "secret/*",
+				Policies:     []string{"dev"},
+				Grants:       map[string][]string{"dev": []string{"create", "read", "update"}},
+			},
+		},
+		{
+			logical.UpdateOperation, "secret/foo", map[string]interface{}{"admin": true},
+			&PolicyExplanation{
+				Allowed:      false,
+				Reason:       `the rule denies parameter "admin"`,
+				Capabilities: []string{"create", "read", "update"},
+				Rule:         "secret/*",
+				Policies:     []string{"dev"},
+				Grants:       map[string][]string{"dev": []string{"create", "read", "update"}},
+			},
+		},
+		{
+			// The deny of one policy overrides the grants of the other
+			logical.ReadOperation, "secret/shared", nil,
+			&PolicyExplanation{
+				Allowed:      false,
+				Reason:       "the rule denies the path",
+				Capabilities: []string{"deny"},
+				Rule:         "secret/shared",
+				Policies:     []string{"dev", "ops"},
+				Grants: map[string][]string{
+					"dev": []string{"read", "update"},
+					"ops": []string{"deny"},
+				},
+			},
+		},
+		{
+			logical.UpdateOperation, "sys/rotate", nil,
+			&PolicyExplanation{
+				Allowed:      false,
+				Reason:       "the path requires sudo, which the rule does not grant",
+				Capabilities: []string{"update"},
+				Rule:         "sys/rotate",
+				Policies:     []string{"dev"},
+				Grants:       map[string][]string{"dev": []string{"update"}},
+				SudoRequired: true,
+			},
+		},
+		{
+			logical.ReadOperation, "other", nil,
+			&PolicyExplanation{
+				Allowed:      false,
+				Reason:       "no rule matches the path",
+				Capabilities: []string{"deny"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		explanation, err := c.ExplainPolicies(policies, &logical.Request{
+			Operation: tc.op,
+			Path:      tc.path,
+			Data:      tc.data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(explanation, tc.exp) {
+			t.Fatalf("%s %s: bad: %#v", tc.op, tc.path, explanation)
+		}
+	}
+}
+
+func TestSystemBackend_policyExplain(t *testing.T) {
+	c, b, root := testCoreSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "policy/dev")
+	req.Data["rules"] = `path "secret/*" { capabilities = ["read", "sudo"] }`
+	if _, err := b.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "policy/explain")
+	req.Data["path"] = "secret/foo"
+	req.Data["policies"] = "dev,default"
+	resp, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["allowed"] != true || resp.Data["rule"] != "secret/*" || resp.Data["sudo"] != true ||
+		!reflect.DeepEqual(resp.Data["policies"], []string{"dev"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The policies of the token of the request are used by default
+	testMakeToken(t, c.tokenStore, root, "client", "", []string{"dev"})
+	req = logical.TestRequest(t, logical.UpdateOperation, "policy/explain")
+	req.Data["path"] = "secret/foo"
+	req.Data["operation"] = "update"
+	req.ClientToken = "client"
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["allowed"] != false || resp.Data["reason"] != "the rule does not grant the capability of the operation" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Unknown operations are rejected
+	req.Data["operation"] = "sudo"
+	resp, err = b.HandleRequest(req)
+	if err != logical.ErrInvalidRequest || !resp.IsError() {
+		t.Fatalf("bad: %#v %v", resp, err)
+	}
+}
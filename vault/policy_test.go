@@ -312,3 +312,19 @@ path "/" {
 		t.Errorf("bad error: %s", err)
 	}
 }
+
+func TestPolicy_ParseBadAllowedWindows(t *testing.T) {
+	_, err := Parse(strings.TrimSpace(`
+path "/" {
+	capabilities = ["read"]
+	allowed_windows = ["* 25 * * *"]
+}
+`))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	if !strings.Contains(err.Error(), `error parsing allowed_windows`) {
+		t.Errorf("bad error: %s", err)
+	}
+}
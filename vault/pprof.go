@@ -0,0 +1,22 @@
+package vault
+
+import (
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/logical"
+)
+
+// AuthorizeProfiling returns an error unless the token of the request has
+// the sudo capability on the sys/pprof path of the request, which collects
+// a runtime profile of the server
+func (c *Core) AuthorizeProfiling(req *logical.Request) error {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed {
+		return consts.ErrSealed
+	}
+	if c.standby {
+		return consts.ErrStandby
+	}
+
+	return c.authorizeHandlerRequest(req)
+}
@@ -2,6 +2,7 @@ package vault
 
 import (
 	"bytes"
+	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -33,6 +34,20 @@ type RekeyResult struct {
 	PGPFingerprints []string
 	Backup          bool
 	RecoveryKey     bool
+
+	// VerificationRequired is set when the new key only takes effect once
+	// a threshold of the shares has been provided back with the
+	// VerificationNonce
+	VerificationRequired bool
+	VerificationNonce    string
+}
+
+// rekeyVerification holds the new key of a rekey until a threshold of its
+// shares has been provided back, proving they were received
+type rekeyVerification struct {
+	nonce    string
+	key      []byte
+	progress [][]byte
 }
 
 // RekeyBackup stores the backup copy of PGP-encrypted keys
@@ -126,6 +141,9 @@ func (c *Core) RekeyInit(config *SealConfig, recovery bool) error {
 
 // BarrierRekeyInit is used to initialize the rekey settings for the barrier key
 func (c *Core) BarrierRekeyInit(config *SealConfig) error {
+	if config.VerificationRequired {
+		return fmt.Errorf("verification is only supported when rekeying the recovery key")
+	}
 	if config.StoredShares > 0 {
 		if !c.seal.StoredKeysSupported() {
 			return fmt.Errorf("storing keys not supported by barrier seal")
@@ -472,6 +490,9 @@ func (c *Core) RecoveryRekeyUpdate(key []byte, nonce string) (*RekeyResult, erro
 	if c.recoveryRekeyConfig == nil {
 		return nil, fmt.Errorf("no rekey in progress")
 	}
+	if c.recoveryRekeyVerification != nil {
+		return nil, fmt.Errorf("rekey verification in progress")
+	}
 
 	if nonce != c.recoveryRekeyConfig.Nonce {
 		return nil, fmt.Errorf("incorrect nonce supplied; nonce for this rekey operation is %s", c.recoveryRekeyConfig.Nonce)
@@ -480,7 +501,7 @@ func (c *Core) RecoveryRekeyUpdate(key []byte, nonce string) (*RekeyResult, erro
 	// Check if we already have this piece
 	for _, existing := range c.recoveryRekeyProgress {
 		if bytes.Equal(existing, key) {
-			return nil, nil
+			return nil, fmt.Errorf("given key has already been provided during this generation operation")
 		}
 	}
 
@@ -579,14 +600,41 @@ func (c *Core) RecoveryRekeyUpdate(key []byte, nonce string) (*RekeyResult, erro
 		}
 	}
 
+	// Hold the new key until its shares are verified if required
+	if c.recoveryRekeyConfig.VerificationRequired {
+		nonce, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+		c.recoveryRekeyVerification = &rekeyVerification{
+			nonce: nonce,
+			key:   newMasterKey,
+		}
+		results.VerificationRequired = true
+		results.VerificationNonce = nonce
+		if c.logger.IsInfo() {
+			c.logger.Info("core: recovery rekey waiting for verification", "verification_nonce", nonce)
+		}
+		return results, nil
+	}
+
+	if err := c.recoveryRekeyCommit(newMasterKey); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// recoveryRekeyCommit sets the new recovery key and configuration of the
+// recovery rekey in progress, and ends it
+func (c *Core) recoveryRekeyCommit(newMasterKey []byte) error {
 	if err := c.seal.SetRecoveryKey(newMasterKey); err != nil {
 		c.logger.Error("core: failed to set recovery key", "error", err)
-		return nil, fmt.Errorf("failed to set recovery key: %v", err)
+		return fmt.Errorf("failed to set recovery key: %v", err)
 	}
 
 	if err := c.seal.SetRecoveryConfig(c.recoveryRekeyConfig); err != nil {
 		c.logger.Error("core: error saving rekey seal configuration", "error", err)
-		return nil, fmt.Errorf("failed to save rekey seal configuration: %v", err)
+		return fmt.Errorf("failed to save rekey seal configuration: %v", err)
 	}
 
 	// Write to the canary path, which will force a synchronous truing during
@@ -596,13 +644,142 @@ func (c *Core) RecoveryRekeyUpdate(key []byte, nonce string) (*RekeyResult, erro
 		Value: []byte(c.recoveryRekeyConfig.Nonce),
 	}); err != nil {
 		c.logger.Error("core: error saving keyring canary", "error", err)
-		return nil, fmt.Errorf("failed to save keyring canary: %v", err)
+		return fmt.Errorf("failed to save keyring canary: %v", err)
+	}
+	if c.logger.IsInfo() {
+		c.logger.Info("core: recovery key rekeyed", "shares", c.recoveryRekeyConfig.SecretShares, "threshold", c.recoveryRekeyConfig.SecretThreshold)
 	}
 
 	// Done!
 	c.recoveryRekeyProgress = nil
 	c.recoveryRekeyConfig = nil
-	return results, nil
+	c.recoveryRekeyVerification = nil
+	return nil
+}
+
+// RecoveryRekeyVerifyStatus returns the nonce and the number of shares
+// provided of the verification of the new recovery key, or an empty nonce if
+// no verification is in progress
+func (c *Core) RecoveryRekeyVerifyStatus() (string, int, error) {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed {
+		return "", 0, consts.ErrSealed
+	}
+	if c.standby {
+		return "", 0, consts.ErrStandby
+	}
+
+	c.rekeyLock.RLock()
+	defer c.rekeyLock.RUnlock()
+
+	if c.recoveryRekeyVerification == nil {
+		return "", 0, nil
+	}
+	return c.recoveryRekeyVerification.nonce, len(c.recoveryRekeyVerification.progress), nil
+}
+
+// RecoveryRekeyVerify is used to provide a share of the new recovery key
+// of a rekey requiring verification. Once a threshold of the shares has been
+// provided, the new recovery key takes effect and true is returned.
+func (c *Core) RecoveryRekeyVerify(key []byte, nonce string) (bool, error) {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed {
+		return false, consts.ErrSealed
+	}
+	if c.standby {
+		return false, consts.ErrStandby
+	}
+
+	// Verify the key length
+	min, max := c.barrier.KeyLength()
+	max += shamir.ShareOverhead
+	if len(key) < min {
+		return false, &ErrInvalidKey{fmt.Sprintf("key is shorter than minimum %d bytes", min)}
+	}
+	if len(key) > max {
+		return false, &ErrInvalidKey{fmt.Sprintf("key is longer than maximum %d bytes", max)}
+	}
+
+	c.rekeyLock.Lock()
+	defer c.rekeyLock.Unlock()
+
+	verification := c.recoveryRekeyVerification
+	if verification == nil {
+		return false, fmt.Errorf("no rekey verification in progress")
+	}
+	if nonce != verification.nonce {
+		return false, fmt.Errorf("incorrect nonce supplied; nonce for this verification operation is %s", verification.nonce)
+	}
+
+	// Check if we already have this piece
+	for _, existing := range verification.progress {
+		if bytes.Equal(existing, key) {
+			return false, fmt.Errorf("given key has already been provided during this verification operation")
+		}
+	}
+
+	// Store this key
+	verification.progress = append(verification.progress, key)
+
+	// Check if we don't have enough keys to verify
+	threshold := c.recoveryRekeyConfig.SecretThreshold
+	if len(verification.progress) < threshold {
+		if c.logger.IsDebug() {
+			c.logger.Debug("core: cannot verify rekey yet, not enough keys", "keys", len(verification.progress), "threshold", threshold)
+		}
+		return false, nil
+	}
+
+	// Recover the new recovery key
+	var recoveryKey []byte
+	var err error
+	if threshold == 1 {
+		recoveryKey = verification.progress[0]
+	} else {
+		recoveryKey, err = shamir.Combine(verification.progress)
+	}
+	verification.progress = nil
+	if err != nil {
+		return false, fmt.Errorf("failed to compute recovery key: %v", err)
+	}
+	if subtle.ConstantTimeCompare(recoveryKey, verification.key) != 1 {
+		c.logger.Error("core: rekey verification failed, the keys do not match the new recovery key")
+		return false, fmt.Errorf("rekey verification failed, the keys do not match the new recovery key")
+	}
+
+	if err := c.recoveryRekeyCommit(verification.key); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RecoveryRekeyVerifyRestart discards the shares provided to the
+// verification of the new recovery key and generates a new nonce for it
+func (c *Core) RecoveryRekeyVerifyRestart() error {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed {
+		return consts.ErrSealed
+	}
+	if c.standby {
+		return consts.ErrStandby
+	}
+
+	c.rekeyLock.Lock()
+	defer c.rekeyLock.Unlock()
+
+	if c.recoveryRekeyVerification == nil {
+		return fmt.Errorf("no rekey verification in progress")
+	}
+	nonce, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+	c.recoveryRekeyVerification.nonce = nonce
+	c.recoveryRekeyVerification.progress = nil
+	return nil
 }
 
 // RekeyCancel is used to cancel an inprogress rekey
@@ -623,6 +800,7 @@ func (c *Core) RekeyCancel(recovery bool) error {
 	if recovery {
 		c.recoveryRekeyConfig = nil
 		c.recoveryRekeyProgress = nil
+		c.recoveryRekeyVerification = nil
 	} else {
 		c.barrierRekeyConfig = nil
 		c.barrierRekeyProgress = nil
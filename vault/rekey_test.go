@@ -9,6 +9,7 @@ import (
 
 	"github.com/hashicorp/vault/helper/logformat"
 	"github.com/hashicorp/vault/physical"
+	"github.com/hashicorp/vault/shamir"
 )
 
 func TestCore_Rekey_Lifecycle(t *testing.T) {
@@ -481,3 +482,111 @@ func TestCore_Standby_Rekey(t *testing.T) {
 		t.Fatalf("rekey failed")
 	}
 }
+
+func TestCore_RecoveryRekey_Verification(t *testing.T) {
+	bc, rc := TestSealDefConfigs()
+	bc.StoredShares = 0
+	c, _, recoveryKeys, _ := TestCoreUnsealedWithConfigs(t, bc, rc)
+
+	// Verification is only supported for the recovery key
+	if err := c.RekeyInit(&SealConfig{SecretShares: 1, SecretThreshold: 1, VerificationRequired: true}, false); err == nil {
+		t.Fatal("expected error")
+	}
+
+	newConf := &SealConfig{
+		SecretShares:         3,
+		SecretThreshold:      2,
+		VerificationRequired: true,
+	}
+	if err := c.RekeyInit(newConf, true); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	rkconf, err := c.RekeyConfig(true)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var result *RekeyResult
+	for _, key := range recoveryKeys {
+		result, err = c.RekeyUpdate(TestKeyCopy(key), rkconf.Nonce, true)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if result != nil {
+			break
+		}
+	}
+	if result == nil || !result.VerificationRequired || result.VerificationNonce == "" || len(result.SecretShares) != 3 {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// The previous recovery key remains in effect until verified
+	if _, err := c.RekeyUpdate(TestKeyCopy(recoveryKeys[0]), rkconf.Nonce, true); err == nil {
+		t.Fatal("expected error while verification is in progress")
+	}
+	conf, err := c.seal.RecoveryConfig()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if conf.SecretShares != rc.SecretShares {
+		t.Fatalf("bad: %#v", conf)
+	}
+
+	nonce, progress, err := c.RecoveryRekeyVerifyStatus()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if nonce != result.VerificationNonce || progress != 0 {
+		t.Fatalf("bad: %q %d", nonce, progress)
+	}
+	if _, err := c.RecoveryRekeyVerify(TestKeyCopy(result.SecretShares[0]), "bad"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Mixing in a share of the previous key fails the verification
+	if complete, err := c.RecoveryRekeyVerify(TestKeyCopy(result.SecretShares[0]), nonce); err != nil || complete {
+		t.Fatalf("bad: %v %v", complete, err)
+	}
+	if _, err := c.RecoveryRekeyVerify(TestKeyCopy(recoveryKeys[1]), nonce); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Restarting the verification changes the nonce
+	if err := c.RecoveryRekeyVerifyRestart(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	newNonce, progress, err := c.RecoveryRekeyVerifyStatus()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if newNonce == nonce || progress != 0 {
+		t.Fatalf("bad: %q %d", newNonce, progress)
+	}
+
+	for i, key := range result.SecretShares[1:] {
+		complete, err := c.RecoveryRekeyVerify(TestKeyCopy(key), newNonce)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if complete != (i == 1) {
+			t.Fatalf("bad: %d %v", i, complete)
+		}
+	}
+
+	conf, err = c.seal.RecoveryConfig()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if conf.SecretShares != 3 || conf.SecretThreshold != 2 {
+		t.Fatalf("bad: %#v", conf)
+	}
+	recoveryKey, err := shamir.Combine(result.SecretShares[:2])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := c.seal.VerifyRecoveryKey(recoveryKey); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if nonce, _, _ := c.RecoveryRekeyVerifyStatus(); nonce != "" {
+		t.Fatal("verification should be complete")
+	}
+}
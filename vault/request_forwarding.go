@@ -265,6 +265,10 @@ func (c *Core) clearForwardingClients() {
 
 	c.rpcClientConnContext = nil
 	c.rpcForwardingClient = nil
+	if c.rpcForwardingConnected {
+		c.rpcForwardingConnected = false
+		c.stateEvents.notify()
+	}
 }
 
 // ForwardRequest forwards a given request to the active node and returns the
@@ -334,6 +338,18 @@ func (c *Core) getGRPCDialer(alpnProto, serverName string, caCert *x509.Certific
 	}
 }
 
+// forwardedRequestKey is the context key marking the requests forwarded by
+// a standby
+type forwardedRequestKey struct{}
+
+// IsForwardedRequest returns whether the request was forwarded to this node
+// by a standby over the cluster connection, so that what the standby set on
+// the request, such as its ID, can be trusted
+func IsForwardedRequest(r *http.Request) bool {
+	forwarded, _ := r.Context().Value(forwardedRequestKey{}).(bool)
+	return forwarded
+}
+
 type forwardedRequestRPCServer struct {
 	core    *Core
 	handler http.Handler
@@ -347,6 +363,7 @@ func (s *forwardedRequestRPCServer) ForwardRequest(ctx context.Context, freq *fo
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(context.WithValue(req.Context(), forwardedRequestKey{}, true))
 
 	// A very dummy response writer that doesn't follow normal semantics, just
 	// lets you write a status code (last written wins) and a body. But it
@@ -430,6 +447,7 @@ func (c *forwardingClient) startHeartbeat() {
 				return
 			}
 			c.core.logger.Trace("forwarding: successful heartbeat")
+			c.core.forwardingConnected(c)
 		}
 
 		tick()
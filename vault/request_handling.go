@@ -3,10 +3,12 @@ package vault
 import (
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/armon/go-metrics"
 	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/helper/policyutil"
@@ -25,6 +27,32 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 	if c.standby {
 		return nil, consts.ErrStandby
 	}
+	if atomic.LoadUint32(&c.leadershipSuspended) == 1 {
+		return nil, consts.ErrLeadershipSuspended
+	}
+
+	span := c.startSpan("core.handle_request", req)
+	defer func() {
+		if resp != nil && resp.IsError() {
+			span.SetTag("error_response", true)
+		}
+		span.Finish(err)
+	}()
+
+	if err := c.memoryWatchdog.shedRequest(req); err != nil {
+		return nil, err
+	}
+
+	releaseIP, err := c.clientLimiter.acquireIP(clientIP(req))
+	if err != nil {
+		return nil, err
+	}
+	defer releaseIP()
+
+	journalDone := c.requestJournal.begin(req)
+	defer func() {
+		journalDone(err != nil || (resp != nil && resp.IsError()))
+	}()
 
 	// Allowing writing to a path ending in / makes it extremely difficult to
 	// understand user intent for the filesystem-like backends (generic,
@@ -34,10 +62,18 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 	// backends. Basically, it's all just terrible, so don't allow it.
 	if strings.HasSuffix(req.Path, "/") &&
 		(req.Operation == logical.UpdateOperation ||
-			req.Operation == logical.CreateOperation) {
+			req.Operation == logical.CreateOperation ||
+			req.Operation == logical.PatchOperation) {
 		return logical.ErrorResponse("cannot write to a path ending in '/'"), nil
 	}
 
+	// Have the audit backends log the data keys configured on the mount in
+	// cleartext
+	if entry := c.router.MatchingMountEntry(req.Path); entry != nil {
+		req.AuditNonHMACReqDataKeys = entry.Config.AuditNonHMACRequestKeys
+		req.AuditNonHMACRespDataKeys = entry.Config.AuditNonHMACResponseKeys
+	}
+
 	var auth *logical.Auth
 	if c.router.LoginPath(req.Path) {
 		resp, auth, err = c.handleLoginRequest(req)
@@ -96,7 +132,7 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 		httpResp := &logical.HTTPResponse{}
 		err := jsonutil.DecodeJSON(resp.Data[logical.HTTPRawBody].([]byte), httpResp)
 		if err != nil {
-			c.logger.Error("core: failed to unmarshal wrapped HTTP response for audit logging", "error", err)
+			c.logger.Error("core: failed to unmarshal wrapped HTTP response for audit logging", "request_id", req.ID, "error", err)
 			return nil, ErrInternalError
 		}
 
@@ -105,7 +141,7 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 
 	// Create an audit trail of the response
 	if auditErr := c.auditBroker.LogResponse(auth, req, auditResp, c.auditedHeaders, err); auditErr != nil {
-		c.logger.Error("core: failed to audit response", "request_path", req.Path, "error", auditErr)
+		c.logger.Error("core: failed to audit response", "request_id", req.ID, "request_path", req.Path, "error", auditErr)
 		return nil, ErrInternalError
 	}
 
@@ -115,8 +151,25 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, retAuth *logical.Auth, retErr error) {
 	defer metrics.MeasureSince([]string{"core", "handle_request"}, time.Now())
 
+	// Flag the requests tripping canary tokens before validating the token,
+	// since canary tokens are never valid
+	c.checkCanary(req)
+
 	// Validate the token
+	tokenSpan := c.startSpan("core.check_token", req)
 	auth, te, ctErr := c.checkToken(req)
+	tokenSpan.Finish(ctErr)
+
+	// Reject the request before it uses the token if the token already has
+	// too many requests in flight
+	if te != nil {
+		releaseToken, err := c.clientLimiter.acquireToken(te.Accessor)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer releaseToken()
+	}
+
 	// We run this logic first because we want to decrement the use count even in the case of an error
 	if te != nil {
 		// Attempt to use the token (decrement NumUses)
@@ -165,7 +218,7 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 		}
 
 		if err := c.auditBroker.LogRequest(auth, req, c.auditedHeaders, ctErr); err != nil {
-			c.logger.Error("core: failed to audit request", "path", req.Path, "error", err)
+			c.logger.Error("core: failed to audit request", "request_id", req.ID, "path", req.Path, "error", err)
 		}
 
 		if errType != nil {
@@ -177,15 +230,40 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 	// Attach the display name
 	req.DisplayName = auth.DisplayName
 
+	// Require the MFA methods enforced on the path; root tokens are exempt
+	// so that a misconfigured enforcement cannot lock operators out
+	if te != nil && !strutil.StrListContains(te.Policies, "root") {
+		enforcements := c.mfaConfig().pathEnforcements(req.Path)
+		if err := c.enforceMFA(req, te.Entity, enforcements); err != nil {
+			if err := c.auditBroker.LogRequest(auth, req, c.auditedHeaders, err); err != nil {
+				c.logger.Error("core: failed to audit request", "request_id", req.ID, "path", req.Path, "error", err)
+			}
+			retErr = multierror.Append(retErr, logical.ErrPermissionDenied)
+			return logical.ErrorResponse(err.Error()), auth, retErr
+		}
+	}
+
+	// Refuse requests carrying raw secrets, which the audit backends would
+	// log as is
+	if err := c.scanRequestSecrets(req); err != nil {
+		if err := c.auditBroker.LogRequest(auth, req, c.auditedHeaders, err); err != nil {
+			c.logger.Error("core: failed to audit request", "request_id", req.ID, "path", req.Path, "error", err)
+		}
+		retErr = multierror.Append(retErr, logical.ErrInvalidRequest)
+		return logical.ErrorResponse(err.Error()), auth, retErr
+	}
+
 	// Create an audit trail of the request
 	if err := c.auditBroker.LogRequest(auth, req, c.auditedHeaders, nil); err != nil {
-		c.logger.Error("core: failed to audit request", "path", req.Path, "error", err)
+		c.logger.Error("core: failed to audit request", "request_id", req.ID, "path", req.Path, "error", err)
 		retErr = multierror.Append(retErr, ErrInternalError)
 		return nil, auth, retErr
 	}
 
 	// Route the request
+	routeSpan := c.startSpan("router.route", req)
 	resp, routeErr := c.router.Route(req)
+	routeSpan.Finish(routeErr)
 	if resp != nil {
 		// If wrapping is used, use the shortest between the request and response
 		var wrapTTL time.Duration
@@ -266,7 +344,7 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 		if registerLease {
 			leaseID, err := c.expiration.Register(req, resp)
 			if err != nil {
-				c.logger.Error("core: failed to register lease", "request_path", req.Path, "error", err)
+				c.logger.Error("core: failed to register lease", "request_id", req.ID, "request_path", req.Path, "error", err)
 				retErr = multierror.Append(retErr, ErrInternalError)
 				return nil, auth, retErr
 			}
@@ -279,7 +357,7 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 	// since it does not need to be re-registered
 	if resp != nil && resp.Auth != nil && !strings.HasPrefix(req.Path, "auth/token/renew") {
 		if !strings.HasPrefix(req.Path, "auth/token/") {
-			c.logger.Error("core: unexpected Auth response for non-token backend", "request_path", req.Path)
+			c.logger.Error("core: unexpected Auth response for non-token backend", "request_id", req.ID, "request_path", req.Path)
 			retErr = multierror.Append(retErr, ErrInternalError)
 			return nil, auth, retErr
 		}
@@ -295,7 +373,7 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 
 		if err := c.expiration.RegisterAuth(te.Path, resp.Auth); err != nil {
 			c.tokenStore.Revoke(te.ID)
-			c.logger.Error("core: failed to register token lease", "request_path", req.Path, "error", err)
+			c.logger.Error("core: failed to register token lease", "request_id", req.ID, "request_path", req.Path, "error", err)
 			retErr = multierror.Append(retErr, ErrInternalError)
 			return nil, auth, retErr
 		}
@@ -320,21 +398,32 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *logical.Auth, error) {
 	defer metrics.MeasureSince([]string{"core", "handle_login_request"}, time.Now())
 
+	// Refuse requests carrying raw secrets, which the audit backends would
+	// log as is
+	if err := c.scanRequestSecrets(req); err != nil {
+		if err := c.auditBroker.LogRequest(nil, req, c.auditedHeaders, err); err != nil {
+			c.logger.Error("core: failed to audit request", "request_id", req.ID, "path", req.Path, "error", err)
+		}
+		return logical.ErrorResponse(err.Error()), nil, logical.ErrInvalidRequest
+	}
+
 	// Create an audit trail of the request, auth is not available on login requests
 	if err := c.auditBroker.LogRequest(nil, req, c.auditedHeaders, nil); err != nil {
-		c.logger.Error("core: failed to audit request", "path", req.Path, "error", err)
+		c.logger.Error("core: failed to audit request", "request_id", req.ID, "path", req.Path, "error", err)
 		return nil, nil, ErrInternalError
 	}
 
 	// The token store uses authentication even when creating a new token,
 	// so it's handled in handleRequest. It should not be reached here.
 	if strings.HasPrefix(req.Path, "auth/token/") {
-		c.logger.Error("core: unexpected login request for token backend", "request_path", req.Path)
+		c.logger.Error("core: unexpected login request for token backend", "request_id", req.ID, "request_path", req.Path)
 		return nil, nil, ErrInternalError
 	}
 
 	// Route the request
+	routeSpan := c.startSpan("router.route", req)
 	resp, routeErr := c.router.Route(req)
+	routeSpan.Finish(routeErr)
 	if resp != nil {
 		// If wrapping is used, use the shortest between the request and response
 		var wrapTTL time.Duration
@@ -373,7 +462,7 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 
 	// A login request should never return a secret!
 	if resp != nil && resp.Secret != nil {
-		c.logger.Error("core: unexpected Secret response for login path", "request_path", req.Path)
+		c.logger.Error("core: unexpected Secret response for login path", "request_id", req.ID, "request_path", req.Path)
 		return nil, nil, ErrInternalError
 	}
 
@@ -387,16 +476,22 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 		}
 
 		// Determine the source of the login
-		source := c.router.MatchingMount(req.Path)
-		source = strings.TrimPrefix(source, credentialRoutePrefix)
+		mount := c.router.MatchingMount(req.Path)
+		entity := loginEntity(mount, auth.DisplayName)
+		source := strings.TrimPrefix(mount, credentialRoutePrefix)
 		source = strings.Replace(source, "/", "-", -1)
 
+		// Require the MFA methods enforced on the mount
+		if err := c.enforceMFA(req, entity, c.mfaConfig().loginEnforcements(mount)); err != nil {
+			return logical.ErrorResponse(err.Error()), nil, logical.ErrPermissionDenied
+		}
+
 		// Prepend the source to the display name
 		auth.DisplayName = strings.TrimSuffix(source+auth.DisplayName, "-")
 
 		sysView := c.router.MatchingSystemView(req.Path)
 		if sysView == nil {
-			c.logger.Error("core: unable to look up sys view for login path", "request_path", req.Path)
+			c.logger.Error("core: unable to look up sys view for login path", "request_id", req.ID, "request_path", req.Path)
 			return nil, nil, ErrInternalError
 		}
 
@@ -419,6 +514,8 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 			CreationTime: time.Now().Unix(),
 			TTL:          auth.TTL,
 			NumUses:      auth.NumUses,
+			Entity:       entity,
+			BoundCIDRs:   auth.BoundCIDRs,
 		}
 
 		te.Policies = policyutil.SanitizePolicies(te.Policies, true)
@@ -431,6 +528,10 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 		}
 
 		if err := c.tokenStore.create(&te); err != nil {
+			if err == ErrTokenLimitReached {
+				c.logger.Warn("core: token limit reached", "entity", entity)
+				return logical.ErrorResponse(fmt.Sprintf("%s for %q", err, entity)), nil, logical.ErrInvalidRequest
+			}
 			c.logger.Error("core: failed to create token", "error", err)
 			return nil, auth, ErrInternalError
 		}
@@ -443,7 +544,7 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 		// Register with the expiration manager
 		if err := c.expiration.RegisterAuth(te.Path, auth); err != nil {
 			c.tokenStore.Revoke(te.ID)
-			c.logger.Error("core: failed to register token lease", "request_path", req.Path, "error", err)
+			c.logger.Error("core: failed to register token lease", "request_id", req.ID, "request_path", req.Path, "error", err)
 			return nil, auth, ErrInternalError
 		}
 
@@ -453,3 +554,97 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 
 	return resp, auth, routeErr
 }
+
+// AuditRejectedRequest logs to the audit backends a request the HTTP layer
+// rejected before handing it to the core, e.g. for exceeding the request
+// size or duration limits of its listener, along with the reason it was
+// rejected
+func (c *Core) AuditRejectedRequest(req *logical.Request, reason error) error {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+
+	// Only the active node has audit backends set up
+	if c.sealed || c.auditBroker == nil {
+		return nil
+	}
+
+	if err := c.auditBroker.LogRequest(nil, req, c.auditedHeaders, reason); err != nil {
+		c.logger.Error("core: failed to audit rejected request", "request_id", req.ID, "path", req.Path, "error", err)
+		return ErrInternalError
+	}
+	return nil
+}
+
+// authorizeHandlerRequest checks the token of a request served by an HTTP
+// handler of its own rather than routed to a backend, such as sys/monitor,
+// logs the request to the audit backends and uses the token. The state lock
+// must be held, and the core must be unsealed and active.
+func (c *Core) authorizeHandlerRequest(req *logical.Request) error {
+	auth, te, err := c.checkToken(req)
+	if auditErr := c.auditBroker.LogRequest(auth, req, c.auditedHeaders, err); auditErr != nil {
+		c.logger.Error("core: failed to audit request", "request_id", req.ID, "path", req.Path, "error", auditErr)
+		return ErrInternalError
+	}
+	if err != nil {
+		return err
+	}
+
+	// Attempt to use the token (decrement num_uses)
+	if te != nil {
+		te, err = c.tokenStore.UseToken(te)
+		if err != nil {
+			c.logger.Error("core: failed to use token", "error", err)
+			return ErrInternalError
+		}
+		if te == nil {
+			return logical.ErrPermissionDenied
+		}
+		if te.NumUses == -1 {
+			if err := c.tokenStore.Revoke(te.ID); err != nil {
+				c.logger.Error("core: failed to revoke token", "error", err)
+				return ErrInternalError
+			}
+		}
+	}
+	return nil
+}
+
+// requestSecretsError is returned for requests refused because raw secrets
+// were found in them
+type requestSecretsError struct {
+	findings []*audit.SecretFinding
+}
+
+func (e *requestSecretsError) Error() string {
+	found := make([]string, 0, len(e.findings))
+	for _, f := range e.findings {
+		found = append(found, f.String())
+	}
+	return fmt.Sprintf("raw secrets found in the request (%s); secrets must not be passed in paths, headers or data keys, and tokens must be passed in the X-Vault-Token header",
+		strings.Join(found, ", "))
+}
+
+// scanRequestSecrets looks for raw secrets in the request, which must be
+// done before routing so that blocked requests never reach the backends.
+// The secrets found are reported, and an error is returned if the request
+// must be refused.
+func (c *Core) scanRequestSecrets(req *logical.Request) error {
+	if c.auditSecretScanner == nil {
+		return nil
+	}
+	findings := c.auditSecretScanner.Scan(req)
+	if len(findings) == 0 {
+		return nil
+	}
+
+	found := make([]string, 0, len(findings))
+	for _, f := range findings {
+		metrics.IncrCounter([]string{"audit", "secret_detected", f.Detector}, 1.0)
+		found = append(found, f.String())
+	}
+	c.logger.Warn("core: raw secrets found in request", "request_id", req.ID, "findings", strings.Join(found, ", "), "blocked", c.auditSecretScanner.Block)
+	if !c.auditSecretScanner.Block {
+		return nil
+	}
+	return &requestSecretsError{findings: findings}
+}
@@ -1,10 +1,14 @@
 package vault
 
 import (
+	"errors"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/audit"
 	credUserpass "github.com/hashicorp/vault/builtin/credential/userpass"
 	"github.com/hashicorp/vault/logical"
 )
@@ -140,3 +144,126 @@ func TestRequestHandling_LoginWrapping(t *testing.T) {
 		t.Fatalf("bad: %#v", resp)
 	}
 }
+
+func TestRequestHandling_AuditRejectedRequest(t *testing.T) {
+	noop := &NoopAudit{}
+	c, _, root := TestCoreUnsealed(t)
+	c.auditBackends["noop"] = func(config *audit.BackendConfig) (audit.Backend, error) {
+		noop = &NoopAudit{
+			Config: config,
+		}
+		return noop, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/audit/noop")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	reason := errors.New("request too large")
+	req = &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "secret/large",
+		ClientToken: root,
+	}
+	if err := c.AuditRejectedRequest(req, reason); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(noop.Req) != 1 || noop.Req[0].Path != "secret/large" || noop.ReqErrs[0] != reason {
+		t.Fatalf("bad: %#v", noop)
+	}
+
+	// Nothing is logged once sealed
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	logged := len(noop.Req)
+	if err := c.AuditRejectedRequest(req, reason); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(noop.Req) != logged {
+		t.Fatalf("bad: %#v", noop)
+	}
+}
+
+type testRequestTracer struct {
+	l     sync.Mutex
+	spans []*testRequestSpan
+}
+
+type testRequestSpan struct {
+	operation string
+	tags      map[string]interface{}
+	finished  bool
+	err       error
+}
+
+func (t *testRequestTracer) StartSpan(operation string, req *logical.Request) RequestSpan {
+	t.l.Lock()
+	defer t.l.Unlock()
+	span := &testRequestSpan{operation: operation, tags: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return span
+}
+
+func (s *testRequestSpan) SetTag(key string, value interface{}) {
+	s.tags[key] = value
+}
+
+func (s *testRequestSpan) Finish(err error) {
+	s.finished = true
+	s.err = err
+}
+
+func TestRequestHandling_tracer(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	tracer := &testRequestTracer{}
+	c.requestTracer = tracer
+
+	req := &logical.Request{
+		ID:          "test-request-id",
+		Operation:   logical.UpdateOperation,
+		Path:        "secret/foo",
+		Data:        map[string]interface{}{"value": "bar"},
+		ClientToken: root,
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var operations []string
+	for _, span := range tracer.spans {
+		operations = append(operations, span.operation)
+		if !span.finished || span.err != nil {
+			t.Fatalf("bad: %#v", span)
+		}
+		if span.tags["request_id"] != "test-request-id" || span.tags["path"] != "secret/foo" {
+			t.Fatalf("bad: %#v", span)
+		}
+	}
+	// The write is routed as a create once the existence check ran
+	if tracer.spans[0].tags["operation"] != "update" || tracer.spans[2].tags["operation"] != "create" {
+		t.Fatalf("bad: %#v", tracer.spans)
+	}
+	expected := []string{"core.handle_request", "core.check_token", "router.route"}
+	if !reflect.DeepEqual(operations, expected) {
+		t.Fatalf("bad: %v", operations)
+	}
+
+	// Failed requests are flagged
+	tracer.spans = nil
+	req = &logical.Request{
+		ID:          "test-request-id",
+		Operation:   logical.ReadOperation,
+		Path:        "secret/foo",
+		ClientToken: "invalid",
+	}
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(tracer.spans) != 2 || tracer.spans[0].err == nil || tracer.spans[1].err == nil {
+		t.Fatalf("bad: %#v", tracer.spans)
+	}
+}
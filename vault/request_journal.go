@@ -0,0 +1,284 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/armon/go-metrics"
+	log "github.com/mgutz/logxi/v1"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// defaultRequestJournalEntries is the number of entries kept in the
+	// request journal when none is configured
+	defaultRequestJournalEntries = 4096
+
+	// requestJournalSlotSize is the size of each entry on disk. Entries are
+	// newline-terminated JSON objects padded with spaces, so that the
+	// journal can be read with standard text tools.
+	requestJournalSlotSize = 512
+
+	// requestJournalQueueSize bounds the entries waiting to be written;
+	// entries are dropped rather than blocking requests when it is full
+	requestJournalQueueSize = 1024
+)
+
+// requestJournalSyncInterval is how often the journal is synced to disk
+var requestJournalSyncInterval = time.Second
+
+// requestJournalEntry summarizes a request. An entry is written when the
+// request starts and overwritten in place when it completes, so entries
+// without a duration are requests that were in flight.
+type requestJournalEntry struct {
+	Seq        uint64 `json:"seq"`
+	Time       string `json:"time"`
+	Operation  string `json:"operation"`
+	Path       string `json:"path"`
+	Accessor   string `json:"accessor,omitempty"`
+	RemoteAddr string `json:"remote_addr,omitempty"`
+	Duration   string `json:"duration,omitempty"`
+	Error      bool   `json:"error,omitempty"`
+}
+
+// requestJournal is a bounded on-disk ring of request summaries, written
+// asynchronously so that requests are never slowed down by the disk. After a
+// crash it shows what the node was doing in its final seconds.
+type requestJournal struct {
+	file   *os.File
+	slots  uint64
+	logger log.Logger
+
+	// seq is the sequence number of the last entry; it is accessed
+	// atomically
+	seq uint64
+
+	// queueLock guards queue against sends after close
+	queueLock sync.RWMutex
+	queue     chan requestJournalEntry
+	closed    bool
+	doneCh    chan struct{}
+}
+
+// newRequestJournal opens the journal at the given path, creating it if
+// needed. Numbering continues after the last entry of an existing journal so
+// that entries from before a restart are overwritten oldest first.
+func newRequestJournal(path string, entries int, logger log.Logger) (*requestJournal, error) {
+	if entries <= 0 {
+		entries = defaultRequestJournalEntries
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open request journal: %v", err)
+	}
+
+	existing, err := readRequestJournal(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Truncate(int64(entries) * requestJournalSlotSize); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to size request journal: %v", err)
+	}
+
+	j := &requestJournal{
+		file:   file,
+		slots:  uint64(entries),
+		logger: logger,
+		queue:  make(chan requestJournalEntry, requestJournalQueueSize),
+		doneCh: make(chan struct{}),
+	}
+	if len(existing) > 0 {
+		j.seq = existing[len(existing)-1].Seq
+	}
+
+	go j.run()
+	return j, nil
+}
+
+// begin records the start of the request and returns a function recording
+// its completion
+func (j *requestJournal) begin(req *logical.Request) func(failed bool) {
+	if j == nil {
+		return func(bool) {}
+	}
+
+	start := time.Now()
+	entry := requestJournalEntry{
+		Seq:       atomic.AddUint64(&j.seq, 1),
+		Time:      start.UTC().Format(time.RFC3339Nano),
+		Operation: string(req.Operation),
+		Path:      req.Path,
+		Accessor:  req.ClientTokenAccessor,
+	}
+	if req.Connection != nil {
+		entry.RemoteAddr = req.Connection.RemoteAddr
+	}
+	j.enqueue(entry)
+
+	return func(failed bool) {
+		entry.Duration = time.Since(start).String()
+		entry.Error = failed
+		j.enqueue(entry)
+	}
+}
+
+func (j *requestJournal) enqueue(entry requestJournalEntry) {
+	j.queueLock.RLock()
+	defer j.queueLock.RUnlock()
+	if j.closed {
+		return
+	}
+
+	select {
+	case j.queue <- entry:
+	default:
+		metrics.IncrCounter([]string{"core", "request_journal", "dropped"}, 1)
+	}
+}
+
+// run writes the queued entries and periodically syncs them to disk
+func (j *requestJournal) run() {
+	defer close(j.doneCh)
+
+	ticker := time.NewTicker(requestJournalSyncInterval)
+	defer ticker.Stop()
+
+	dirty := false
+	for {
+		select {
+		case entry, ok := <-j.queue:
+			if !ok {
+				if dirty {
+					j.sync()
+				}
+				return
+			}
+			if err := j.write(entry); err != nil {
+				j.logger.Error("core: failed to write request journal entry", "error", err)
+				continue
+			}
+			dirty = true
+		case <-ticker.C:
+			if dirty {
+				j.sync()
+				dirty = false
+			}
+		}
+	}
+}
+
+func (j *requestJournal) write(entry requestJournalEntry) error {
+	// The slot of a request that ran for longer than it took to wrap
+	// around the journal now belongs to a newer request
+	if atomic.LoadUint64(&j.seq)-entry.Seq >= j.slots {
+		return nil
+	}
+
+	buf, err := encodeRequestJournalEntry(entry)
+	if err != nil {
+		return err
+	}
+	_, err = j.file.WriteAt(buf, int64(entry.Seq%j.slots)*requestJournalSlotSize)
+	return err
+}
+
+func (j *requestJournal) sync() {
+	if err := j.file.Sync(); err != nil {
+		j.logger.Error("core: failed to sync request journal", "error", err)
+	}
+}
+
+// close writes the queued entries and closes the journal
+func (j *requestJournal) close() error {
+	if j == nil {
+		return nil
+	}
+
+	j.queueLock.Lock()
+	if j.closed {
+		j.queueLock.Unlock()
+		return nil
+	}
+	j.closed = true
+	close(j.queue)
+	j.queueLock.Unlock()
+
+	<-j.doneCh
+	return j.file.Close()
+}
+
+// encodeRequestJournalEntry encodes the entry into a full slot, truncating
+// the path if the entry would not fit otherwise
+func encodeRequestJournalEntry(entry requestJournalEntry) ([]byte, error) {
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	if over := len(buf) - (requestJournalSlotSize - 1); over > 0 {
+		// Escaping may make the path longer once encoded, in which case it
+		// is dropped altogether
+		if over < len(entry.Path) {
+			entry.Path = entry.Path[:len(entry.Path)-over]
+			buf, err = json.Marshal(entry)
+		}
+		if err == nil && len(buf) > requestJournalSlotSize-1 {
+			entry.Path = ""
+			buf, err = json.Marshal(entry)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(buf) > requestJournalSlotSize-1 {
+			return nil, fmt.Errorf("request journal entry too large")
+		}
+	}
+
+	slot := bytes.Repeat([]byte{' '}, requestJournalSlotSize)
+	copy(slot, buf)
+	slot[requestJournalSlotSize-1] = '\n'
+	return slot, nil
+}
+
+// readRequestJournal returns the entries of the journal ordered by sequence
+// number
+func readRequestJournal(file *os.File) ([]requestJournalEntry, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []requestJournalEntry
+	slot := make([]byte, requestJournalSlotSize)
+	for offset := int64(0); offset+requestJournalSlotSize <= info.Size(); offset += requestJournalSlotSize {
+		if _, err := file.ReadAt(slot, offset); err != nil {
+			return nil, fmt.Errorf("failed to read request journal: %v", err)
+		}
+
+		// Slots that were never written are zeroed
+		line := bytes.Trim(slot, " \n\x00")
+		if len(line) == 0 {
+			continue
+		}
+		var entry requestJournalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			// A torn write; the rest of the journal is still usable
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Seq < entries[j].Seq
+	})
+	return entries, nil
+}
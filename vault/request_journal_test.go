@@ -0,0 +1,166 @@
+package vault
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/logical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func testRequestJournalEntries(t *testing.T, path string) []requestJournalEntry {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	entries, err := readRequestJournal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestRequestJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "journal")
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	j, err := newRequestJournal(path, 4, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := j.begin(&logical.Request{
+		Operation:           logical.ReadOperation,
+		Path:                "secret/foo",
+		ClientTokenAccessor: "accessor",
+	})
+	done(false)
+	j.begin(&logical.Request{Operation: logical.UpdateOperation, Path: "secret/bar"})
+	if err := j.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := testRequestJournalEntries(t, path)
+	if len(entries) != 2 {
+		t.Fatalf("bad: %#v", entries)
+	}
+	if e := entries[0]; e.Seq != 1 || e.Path != "secret/foo" || e.Accessor != "accessor" || e.Duration == "" || e.Error {
+		t.Fatalf("bad: %#v", e)
+	}
+
+	// The second request never completed
+	if e := entries[1]; e.Seq != 2 || e.Path != "secret/bar" || e.Duration != "" {
+		t.Fatalf("bad: %#v", e)
+	}
+
+	// Numbering continues after a restart and the oldest entries are
+	// overwritten
+	j, err = newRequestJournal(path, 4, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		j.begin(&logical.Request{Operation: logical.ListOperation, Path: "secret/"})(true)
+	}
+	if err := j.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries = testRequestJournalEntries(t, path)
+	if len(entries) != 4 {
+		t.Fatalf("bad: %#v", entries)
+	}
+	for i, e := range entries {
+		if e.Seq != uint64(i+2) {
+			t.Fatalf("bad: %#v", entries)
+		}
+	}
+	if e := entries[3]; e.Operation != "list" || !e.Error {
+		t.Fatalf("bad: %#v", e)
+	}
+
+	// Entries after close are ignored
+	j.begin(&logical.Request{Operation: logical.ReadOperation, Path: "secret/foo"})(false)
+}
+
+func TestRequestJournal_longPath(t *testing.T) {
+	entry := requestJournalEntry{
+		Seq:  1,
+		Path: strings.Repeat("a", 2*requestJournalSlotSize),
+	}
+	buf, err := encodeRequestJournalEntry(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != requestJournalSlotSize || buf[len(buf)-1] != '\n' {
+		t.Fatalf("bad: %q", buf)
+	}
+
+	entry.Path = strings.Repeat("\x01", requestJournalSlotSize)
+	buf, err = encodeRequestJournalEntry(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != requestJournalSlotSize {
+		t.Fatalf("bad: %d", len(buf))
+	}
+}
+
+func TestCore_requestJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "journal")
+
+	c, _, root := TestCoreUnsealed(t)
+	c.requestJournal, err = newRequestJournal(path, 16, c.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "secret/foo",
+		Data:        map[string]interface{}{"foo": "bar"},
+		ClientToken: root,
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	req = &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "sys/mounts",
+		ClientToken: "bogus",
+	}
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected permission denied")
+	}
+
+	if err := c.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := testRequestJournalEntries(t, path)
+	if len(entries) != 2 {
+		t.Fatalf("bad: %#v", entries)
+	}
+	if e := entries[0]; e.Path != "secret/foo" || e.Operation != "update" || e.Duration == "" || e.Error {
+		t.Fatalf("bad: %#v", e)
+	}
+	if e := entries[1]; e.Path != "sys/mounts" || !e.Error {
+		t.Fatalf("bad: %#v", e)
+	}
+}
@@ -0,0 +1,43 @@
+package vault
+
+import (
+	"github.com/hashicorp/vault/logical"
+)
+
+// RequestTracer receives the spans of the requests handled by the core, for
+// instance to report them to a distributed tracing system. The spans of a
+// request are correlated with its audit entries and logs by the ID of the
+// request. Implementations must be safe for concurrent use.
+type RequestTracer interface {
+	// StartSpan starts the span of the given operation of the request
+	StartSpan(operation string, req *logical.Request) RequestSpan
+}
+
+// RequestSpan is a span started by a RequestTracer
+type RequestSpan interface {
+	// SetTag annotates the span
+	SetTag(key string, value interface{})
+
+	// Finish ends the span, with the error the operation failed with, if
+	// any
+	Finish(err error)
+}
+
+// noopRequestSpan is the span of the requests when no tracer is configured
+type noopRequestSpan struct{}
+
+func (noopRequestSpan) SetTag(string, interface{}) {}
+func (noopRequestSpan) Finish(error)               {}
+
+// startSpan starts the span of the given operation of the request with the
+// tracer of the core, if any
+func (c *Core) startSpan(operation string, req *logical.Request) RequestSpan {
+	if c.requestTracer == nil {
+		return noopRequestSpan{}
+	}
+	span := c.requestTracer.StartSpan(operation, req)
+	span.SetTag("request_id", req.ID)
+	span.SetTag("path", req.Path)
+	span.SetTag("operation", string(req.Operation))
+	return span
+}
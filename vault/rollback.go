@@ -38,6 +38,7 @@ type RollbackManager struct {
 
 	router *Router
 	period time.Duration
+	clock  Clock
 
 	inflightAll  sync.WaitGroup
 	inflight     map[string]*rollbackState
@@ -55,13 +56,18 @@ type rollbackState struct {
 	sync.WaitGroup
 }
 
-// NewRollbackManager is used to create a new rollback manager
-func NewRollbackManager(logger log.Logger, backendsFunc func() []*MountEntry, router *Router) *RollbackManager {
+// NewRollbackManager is used to create a new rollback manager. Rollbacks
+// are triggered according to the given clock, or the system clock if nil.
+func NewRollbackManager(logger log.Logger, backendsFunc func() []*MountEntry, router *Router, clock Clock) *RollbackManager {
+	if clock == nil {
+		clock = systemClock{}
+	}
 	r := &RollbackManager{
 		logger:     logger,
 		backends:   backendsFunc,
 		router:     router,
 		period:     rollbackPeriod,
+		clock:      clock,
 		inflight:   make(map[string]*rollbackState),
 		doneCh:     make(chan struct{}),
 		shutdownCh: make(chan struct{}),
@@ -90,12 +96,19 @@ func (m *RollbackManager) Stop() {
 // run is a long running routine to periodically invoke rollback
 func (m *RollbackManager) run() {
 	m.logger.Info("rollback: starting rollback manager")
-	tick := time.NewTicker(m.period)
+	tickCh := make(chan struct{}, 1)
+	tick := m.clock.AfterFunc(m.period, func() {
+		select {
+		case tickCh <- struct{}{}:
+		default:
+		}
+	})
 	defer tick.Stop()
 	defer close(m.doneCh)
 	for {
 		select {
-		case <-tick.C:
+		case <-tickCh:
+			tick.Reset(m.period)
 			m.triggerRollbacks()
 
 		case <-m.shutdownCh:
@@ -222,7 +235,7 @@ func (c *Core) startRollback() error {
 		}
 		return ret
 	}
-	c.rollback = NewRollbackManager(c.logger, backendsFunc, c.router)
+	c.rollback = NewRollbackManager(c.logger, backendsFunc, c.router, c.clock)
 	c.rollback.Start()
 	return nil
 }
@@ -39,7 +39,7 @@ func mockRollback(t *testing.T) (*RollbackManager, *NoopBackend) {
 
 	logger := logformat.NewVaultLogger(log.LevelTrace)
 
-	rb := NewRollbackManager(logger, mountsFunc, router)
+	rb := NewRollbackManager(logger, mountsFunc, router, nil)
 	rb.period = 10 * time.Millisecond
 	return rb, backend
 }
@@ -69,6 +69,44 @@ func TestRollbackManager(t *testing.T) {
 	}
 }
 
+func TestRollbackManager_Clock(t *testing.T) {
+	m, backend := mockRollback(t)
+	clock := NewTestClock(time.Now())
+	m.clock = clock
+	m.period = time.Hour
+
+	m.Start()
+	defer m.Stop()
+
+	// rolledBack reports whether the backend was rolled back the given
+	// number of times, with no rollback in flight
+	rolledBack := func(n int) bool {
+		backend.Lock()
+		count := len(backend.Paths)
+		backend.Unlock()
+		m.inflightLock.RLock()
+		defer m.inflightLock.RUnlock()
+		return count == n && len(m.inflight) == 0
+	}
+
+	// Rollbacks only happen once the period has passed on the clock
+	time.Sleep(50 * time.Millisecond)
+	if !rolledBack(0) {
+		t.Fatalf("bad: %#v", backend)
+	}
+
+	for i := 1; i <= 2; i++ {
+		clock.Advance(time.Hour)
+		deadline := time.Now().Add(5 * time.Second)
+		for !rolledBack(i) {
+			if time.Now().After(deadline) {
+				t.Fatalf("rollback %d not triggered", i)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
 func TestRollbackManager_Join(t *testing.T) {
 	m, backend := mockRollback(t)
 	if len(backend.Paths) > 0 {
@@ -0,0 +1,72 @@
+package vault
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+// rotateRootToken replaces the given root token with a new one with the same
+// properties. The child tokens and the leases of the old token are moved to
+// the new one before the old one is revoked, so that nothing created with it
+// is revoked along with it. If a step fails, what was moved is moved back
+// and the new token is revoked, leaving the old one in place.
+func (c *Core) rotateRootToken(id string) (*TokenEntry, *TokenEntry, error) {
+	ts := c.tokenStore
+
+	old, err := ts.Lookup(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if old == nil {
+		return nil, nil, logical.CodedError(400, "token not found")
+	}
+	if !strutil.StrListContains(old.Policies, "root") {
+		return nil, nil, logical.CodedError(400, "token is not a root token")
+	}
+	if old.TTL != 0 || old.ExplicitMaxTTL != 0 {
+		return nil, nil, logical.CodedError(400, "root tokens with a TTL cannot be rotated, they expire instead")
+	}
+
+	te := &TokenEntry{
+		Parent:       old.Parent,
+		Policies:     old.Policies,
+		Path:         old.Path,
+		Meta:         old.Meta,
+		DisplayName:  old.DisplayName,
+		CreationTime: ts.clock.Now().Unix(),
+		BoundCIDRs:   old.BoundCIDRs,
+	}
+	if err := ts.create(te); err != nil {
+		return nil, nil, fmt.Errorf("failed to create root token: %v", err)
+	}
+
+	if err := c.moveRootToken(old.ID, te.ID); err != nil {
+		c.logger.Error("core: root token rotation failed, rolling back", "error", err)
+		if err := c.moveRootToken(te.ID, old.ID); err != nil {
+			c.logger.Error("core: failed to move children and leases back to the old root token", "error", err)
+		} else if err := ts.Revoke(te.ID); err != nil {
+			c.logger.Error("core: failed to revoke the new root token", "error", err)
+		}
+		return nil, nil, err
+	}
+
+	if err := ts.Revoke(old.ID); err != nil {
+		return nil, nil, fmt.Errorf("failed to revoke the old root token: %v", err)
+	}
+
+	c.logger.Info("core: rotated root token", "old_accessor", old.Accessor, "accessor", te.Accessor)
+	return old, te, nil
+}
+
+// moveRootToken moves the child tokens and the leases of a token to another
+func (c *Core) moveRootToken(from, to string) error {
+	if err := c.tokenStore.moveChildren(from, to); err != nil {
+		return fmt.Errorf("failed to move child tokens: %v", err)
+	}
+	if err := c.expiration.moveLeasesByToken(from, to); err != nil {
+		return fmt.Errorf("failed to move leases: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestCore_RotateRootToken(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	// The root token has a child token and a lease
+	testCoreMakeToken(t, c, root, "child", "", []string{"foo"})
+	req := logical.TestRequest(t, logical.UpdateOperation, "secret/test")
+	req.Data["foo"] = "bar"
+	req.Data["lease"] = "1h"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/test")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	leaseID := resp.Secret.LeaseID
+
+	// Non-root tokens cannot be rotated
+	child, err := c.tokenStore.Lookup("child")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/rotate/root")
+	req.Data["accessor"] = child.Accessor
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil || !strings.Contains(err.Error(), "not a root token") {
+		t.Fatalf("expected the rotation of a non-root token to fail, got %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/rotate/root")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	newRoot := resp.Data["root_token"].(string)
+	if newRoot == "" || newRoot == root || resp.Data["old_accessor"] == resp.Data["accessor"] {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The old root token is revoked, the new one works
+	if te, err := c.tokenStore.Lookup(root); err != nil || te != nil {
+		t.Fatalf("expected the old root token to be revoked: %v %v", te, err)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/test")
+	req.ClientToken = newRoot
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The child token and the lease moved to the new root token
+	child, err = c.tokenStore.Lookup("child")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if child == nil || child.Parent != newRoot {
+		t.Fatalf("bad: %#v", child)
+	}
+	le, err := c.expiration.loadEntry(leaseID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if le == nil || le.ClientToken != newRoot {
+		t.Fatalf("bad: %#v", le)
+	}
+
+	// Revoking the new root token revokes them
+	if err := c.tokenStore.RevokeTree(newRoot); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te, err := c.tokenStore.Lookup("child"); err != nil || te != nil {
+		t.Fatalf("expected the child token to be revoked: %v %v", te, err)
+	}
+	if le, err := c.expiration.loadEntry(leaseID); err != nil || le != nil {
+		t.Fatalf("expected the lease to be revoked: %v %v", le, err)
+	}
+}
@@ -39,6 +39,9 @@ func NewRouter() *Router {
 
 // routeEntry is used to represent a mount point in the router
 type routeEntry struct {
+	// l protects backend, rootPaths and loginPaths, which are swapped out
+	// when a backend is reloaded in place
+	l           sync.RWMutex
 	tainted     bool
 	backend     logical.Backend
 	mountEntry  *MountEntry
@@ -122,6 +125,35 @@ func (r *Router) Unmount(prefix string) error {
 	return nil
 }
 
+// Reload is used to swap the backend serving a given prefix for a freshly
+// created one, without touching the mount entry or storage view. In-flight
+// requests against the old backend are allowed to finish before its Cleanup
+// routine is called.
+func (r *Router) Reload(prefix string, backend logical.Backend) error {
+	r.l.RLock()
+	raw, ok := r.root.Get(prefix)
+	r.l.RUnlock()
+	if !ok {
+		return fmt.Errorf("no mount at '%s'", prefix)
+	}
+	re := raw.(*routeEntry)
+
+	paths := backend.SpecialPaths()
+	if paths == nil {
+		paths = new(logical.Paths)
+	}
+
+	re.l.Lock()
+	defer re.l.Unlock()
+
+	re.backend.Cleanup()
+	re.backend = backend
+	re.rootPaths = pathsToRadix(paths.Root)
+	re.loginPaths = pathsToRadix(paths.Unauthenticated)
+
+	return nil
+}
+
 // Remount is used to change the mount location of a logical backend
 func (r *Router) Remount(src, dst string) error {
 	r.l.Lock()
@@ -205,6 +237,19 @@ func (r *Router) MatchingMount(path string) string {
 	return mount
 }
 
+// HasMountUnder returns whether a backend is mounted at a path starting with
+// the given prefix
+func (r *Router) HasMountUnder(prefix string) bool {
+	found := false
+	r.l.RLock()
+	r.root.WalkPrefix(prefix, func(string, interface{}) bool {
+		found = true
+		return true
+	})
+	r.l.RUnlock()
+	return found
+}
+
 // MatchingView returns the view used for a path
 func (r *Router) MatchingStorageView(path string) *BarrierView {
 	r.l.RLock()
@@ -235,7 +280,10 @@ func (r *Router) MatchingBackend(path string) logical.Backend {
 	if !ok {
 		return nil
 	}
-	return raw.(*routeEntry).backend
+	re := raw.(*routeEntry)
+	re.l.RLock()
+	defer re.l.RUnlock()
+	return re.backend
 }
 
 // MatchingSystemView returns the SystemView used for a path
@@ -246,7 +294,10 @@ func (r *Router) MatchingSystemView(path string) logical.SystemView {
 	if !ok {
 		return nil
 	}
-	return raw.(*routeEntry).backend.System()
+	re := raw.(*routeEntry)
+	re.l.RLock()
+	defer re.l.RUnlock()
+	return re.backend.System()
 }
 
 // MatchingStoragePrefix returns the mount path matching and storage prefix
@@ -351,9 +402,22 @@ func (r *Router) routeCommon(req *logical.Request, existenceCheck bool) (*logica
 	originalClientTokenRemainingUses := req.ClientTokenRemainingUses
 	req.ClientTokenRemainingUses = 0
 
-	// Cache the headers and hide them from backends
+	// Cache the headers and hide them from backends, except for those the
+	// mount passes through
 	headers := req.Headers
 	req.Headers = nil
+	if passthrough := re.mountEntry.Config.PassthroughRequestHeaders; len(passthrough) > 0 && headers != nil {
+		req.Headers = make(map[string][]string, len(passthrough))
+		for _, header := range passthrough {
+			if values, ok := headers[header]; ok {
+				req.Headers[header] = values
+			}
+		}
+	}
+
+	// Cache the MFA credentials and hide them from backends
+	mfaCreds := req.MFACreds
+	req.MFACreds = nil
 
 	// Cache the wrap info of the request
 	var wrapInfo *logical.RequestWrapInfo
@@ -376,12 +440,16 @@ func (r *Router) routeCommon(req *logical.Request, existenceCheck bool) (*logica
 		req.ClientTokenRemainingUses = originalClientTokenRemainingUses
 		req.WrapInfo = wrapInfo
 		req.Headers = headers
+		req.MFACreds = mfaCreds
 		// This is only set in one place, after routing, so should never be set
 		// by a backend
 		req.SetLastRemoteWAL(0)
 	}()
 
-	// Invoke the backend
+	// Invoke the backend, holding off any reload of it until the request
+	// has been handled
+	re.l.RLock()
+	defer re.l.RUnlock()
 	if existenceCheck {
 		ok, exists, err := re.backend.HandleExistenceCheck(req)
 		return nil, ok, exists, err
@@ -405,7 +473,9 @@ func (r *Router) RootPath(path string) bool {
 	remain := strings.TrimPrefix(path, mount)
 
 	// Check the rootPaths of this backend
+	re.l.RLock()
 	match, raw, ok := re.rootPaths.LongestPrefix(remain)
+	re.l.RUnlock()
 	if !ok {
 		return false
 	}
@@ -434,7 +504,9 @@ func (r *Router) LoginPath(path string) bool {
 	remain := strings.TrimPrefix(path, mount)
 
 	// Check the loginPaths of this backend
+	re.l.RLock()
 	match, raw, ok := re.loginPaths.LongestPrefix(remain)
+	re.l.RUnlock()
 	if !ok {
 		return false
 	}
@@ -333,6 +333,63 @@ func TestRouter_Remount(t *testing.T) {
 	}
 }
 
+func TestRouter_Reload(t *testing.T) {
+	r := NewRouter()
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "logical/")
+
+	meUUID, err := uuid.GenerateUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &NoopBackend{}
+	me := &MountEntry{Path: "prod/aws/", UUID: meUUID, Accessor: "awsaccessor"}
+	err = r.Mount(n, "prod/aws/", me, view)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	n2 := &NoopBackend{Root: []string{"root"}}
+	err = r.Reload("prod/aws/", n2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err = r.Reload("stage/aws/", n2)
+	if err == nil || !strings.Contains(err.Error(), "no mount at") {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &logical.Request{
+		Path: "prod/aws/foo",
+	}
+	_, err = r.Route(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Only the new backend should have seen the request
+	if len(n.Paths) != 0 {
+		t.Fatalf("bad: %v", n.Paths)
+	}
+	if len(n2.Paths) != 1 || n2.Paths[0] != "foo" {
+		t.Fatalf("bad: %v", n2.Paths)
+	}
+
+	// The special paths of the new backend should be in effect
+	if !r.RootPath("prod/aws/root") {
+		t.Fatalf("expected root path")
+	}
+
+	// The mount entry and view must be untouched
+	if e := r.MatchingMountEntry("prod/aws/foo"); e != me {
+		t.Fatalf("bad: %#v", e)
+	}
+	if v := r.MatchingStorageView("prod/aws/foo"); v != view {
+		t.Fatalf("bad: %v", v)
+	}
+}
+
 func TestRouter_RootPath(t *testing.T) {
 	r := NewRouter()
 	_, barrier, _ := mockBarrier(t)
@@ -21,7 +21,8 @@ const (
 	barrierSealConfigPath = "core/seal-config"
 
 	// recoverySealConfigPath is the path to the recovery key seal
-	// configuration. It is inside the barrier.
+	// configuration. Like the recovery key, it is stored outside the barrier
+	// so that it can be read with the Vault sealed, and is seal-wrapped.
 	recoverySealConfigPath = "core/recovery-seal-config"
 
 	// recoveryKeyPath is the path to the recovery key
@@ -241,6 +242,11 @@ type SealConfig struct {
 
 	// How many keys to store, for seals that support storage.
 	StoredShares int `json:"stored_shares"`
+
+	// VerificationRequired indicates whether the new shares of a rekey
+	// must be verified before the new key takes effect. It is only used
+	// while rekeying and never stored.
+	VerificationRequired bool `json:"-"`
 }
 
 // Validate is used to sanity check the seal configuration
@@ -292,6 +298,8 @@ func (s *SealConfig) Clone() *SealConfig {
 		Nonce:           s.Nonce,
 		Backup:          s.Backup,
 		StoredShares:    s.StoredShares,
+
+		VerificationRequired: s.VerificationRequired,
 	}
 	if len(s.PGPKeys) > 0 {
 		ret.PGPKeys = make([]string, len(s.PGPKeys))
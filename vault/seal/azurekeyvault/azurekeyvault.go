@@ -0,0 +1,217 @@
+package azurekeyvault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/vault/seal"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// SealType is the type of the seals backed by Azure Key Vault
+	SealType = "azurekeyvault"
+
+	keyVaultAPIVersion = "2016-10-01"
+
+	// wrapAlgorithm is the algorithm the data keys are wrapped with by the
+	// RSA key of the vault
+	wrapAlgorithm = "RSA-OAEP-256"
+)
+
+// AzureKeyVault encrypts the data keys of an auto-unseal seal with a key of
+// an Azure Key Vault
+type AzureKeyVault struct {
+	client *http.Client
+
+	// keyURL is the URL of the key, https://<vault>.<suffix>/keys/<key>
+	keyURL  string
+	keyName string
+
+	tokenLock sync.Mutex
+	token     *adal.ServicePrincipalToken
+}
+
+// wrappedKey is a data key wrapped by the key, with the version of the key
+// it was wrapped with
+type wrappedKey struct {
+	Version string `json:"version"`
+	Value   []byte `json:"value"`
+}
+
+// New returns the Key Vault client for the key of the configuration. Each
+// parameter is read from the environment first, then from the
+// configuration. The client authenticates as the service principal of the
+// client ID and secret if they are given, or with the managed service
+// identity of the host otherwise.
+func New(conf map[string]string, logger log.Logger) (*AzureKeyVault, error) {
+	tenantID := seal.EnvOrConfig("AZURE_TENANT_ID", conf, "tenant_id")
+	if tenantID == "" {
+		return nil, fmt.Errorf("env var AZURE_TENANT_ID or configuration parameter 'tenant_id' must be set")
+	}
+	vaultName := seal.EnvOrConfig("VAULT_AZUREKEYVAULT_VAULT_NAME", conf, "vault_name")
+	if vaultName == "" {
+		return nil, fmt.Errorf("env var VAULT_AZUREKEYVAULT_VAULT_NAME or configuration parameter 'vault_name' must be set")
+	}
+	keyName := seal.EnvOrConfig("VAULT_AZUREKEYVAULT_KEY_NAME", conf, "key_name")
+	if keyName == "" {
+		return nil, fmt.Errorf("env var VAULT_AZUREKEYVAULT_KEY_NAME or configuration parameter 'key_name' must be set")
+	}
+
+	environment := azure.PublicCloud
+	if name := seal.EnvOrConfig("AZURE_ENVIRONMENT", conf, "environment"); name != "" {
+		var err error
+		environment, err = azure.EnvironmentFromName(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid environment %q: %v", name, err)
+		}
+	}
+
+	oauthConfig, err := adal.NewOAuthConfig(environment.ActiveDirectoryEndpoint, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure authentication: %v", err)
+	}
+	resource := strings.TrimSuffix(environment.KeyVaultEndpoint, "/")
+	clientID := seal.EnvOrConfig("AZURE_CLIENT_ID", conf, "client_id")
+	clientSecret := seal.EnvOrConfig("AZURE_CLIENT_SECRET", conf, "client_secret")
+	var token *adal.ServicePrincipalToken
+	if clientID != "" && clientSecret != "" {
+		token, err = adal.NewServicePrincipalToken(*oauthConfig, clientID, clientSecret, resource)
+	} else {
+		token, err = adal.NewServicePrincipalTokenFromMSI(*oauthConfig, resource)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load credentials: %v", err)
+	}
+
+	k := &AzureKeyVault{
+		client:  cleanhttp.DefaultPooledClient(),
+		keyURL:  fmt.Sprintf("https://%s.%s/keys/%s", vaultName, environment.KeyVaultDNSSuffix, url.PathEscape(keyName)),
+		keyName: keyName,
+		token:   token,
+	}
+
+	// Check that the key can be used
+	if err := k.do("GET", "", nil, nil); err != nil {
+		return nil, fmt.Errorf("failed to access key %q: %v", keyName, err)
+	}
+	if logger.IsDebug() {
+		logger.Debug("seal/azurekeyvault: using key", "key", k.keyURL)
+	}
+	return k, nil
+}
+
+func (k *AzureKeyVault) SealType() string {
+	return SealType
+}
+
+func (k *AzureKeyVault) KeyID() string {
+	return k.keyName
+}
+
+// Encrypt wraps the plaintext with the current version of the key
+func (k *AzureKeyVault) Encrypt(plaintext []byte) ([]byte, error) {
+	var out struct {
+		KID   string `json:"kid"`
+		Value string `json:"value"`
+	}
+	if err := k.do("POST", "/wrapkey", k.keyOperation(plaintext), &out); err != nil {
+		return nil, err
+	}
+	value, err := base64.RawURLEncoding.DecodeString(out.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode wrapped key: %v", err)
+	}
+	return json.Marshal(&wrappedKey{
+		Version: out.KID[strings.LastIndex(out.KID, "/")+1:],
+		Value:   value,
+	})
+}
+
+// Decrypt unwraps a ciphertext of Encrypt with the version of the key it was
+// wrapped with
+func (k *AzureKeyVault) Decrypt(ciphertext []byte) ([]byte, error) {
+	var wrapped wrappedKey
+	if err := json.Unmarshal(ciphertext, &wrapped); err != nil {
+		return nil, fmt.Errorf("failed to decode wrapped key: %v", err)
+	}
+	var out struct {
+		Value string `json:"value"`
+	}
+	if err := k.do("POST", "/"+url.PathEscape(wrapped.Version)+"/unwrapkey", k.keyOperation(wrapped.Value), &out); err != nil {
+		return nil, err
+	}
+	return base64.RawURLEncoding.DecodeString(out.Value)
+}
+
+func (k *AzureKeyVault) keyOperation(value []byte) map[string]interface{} {
+	return map[string]interface{}{
+		"alg":   wrapAlgorithm,
+		"value": base64.RawURLEncoding.EncodeToString(value),
+	}
+}
+
+// do calls the operation of the key with a fresh access token
+func (k *AzureKeyVault) do(method, suffix string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		buf, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequest(method, k.keyURL+suffix+"?api-version="+keyVaultAPIVersion, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	k.tokenLock.Lock()
+	err = k.token.EnsureFresh()
+	accessToken := k.token.OAuthToken()
+	k.tokenLock.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to refresh access token: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var errResp struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(respBody, &errResp)
+		return fmt.Errorf("keyvault: error %d: %s", resp.StatusCode, errResp.Error.Message)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
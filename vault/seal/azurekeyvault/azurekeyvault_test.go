@@ -0,0 +1,100 @@
+package azurekeyvault
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/hashicorp/vault/helper/logformat"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestAzureKeyVault_New_MissingConfig(t *testing.T) {
+	os.Unsetenv("AZURE_TENANT_ID")
+	os.Unsetenv("VAULT_AZUREKEYVAULT_VAULT_NAME")
+	os.Unsetenv("VAULT_AZUREKEYVAULT_KEY_NAME")
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	for _, conf := range []map[string]string{
+		{},
+		{"tenant_id": "t"},
+		{"tenant_id": "t", "vault_name": "v"},
+		{"tenant_id": "t", "vault_name": "v", "key_name": "k", "environment": "bad"},
+	} {
+		if _, err := New(conf, logger); err == nil {
+			t.Fatalf("expected error for %#v", conf)
+		}
+	}
+}
+
+func TestAzureKeyVault_EncryptDecrypt(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" || r.URL.Query().Get("api-version") != keyVaultAPIVersion {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var in struct {
+			Alg   string `json:"alg"`
+			Value string `json:"value"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Alg != wrapAlgorithm {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// The fake vault wraps the keys by prefixing them
+		switch r.URL.Path {
+		case "/keys/k/wrapkey":
+			json.NewEncoder(w).Encode(map[string]string{
+				"kid":   "https://v.vault.azure.net/keys/k/v1",
+				"value": "wrap" + in.Value,
+			})
+		case "/keys/k/v1/unwrapkey":
+			json.NewEncoder(w).Encode(map[string]string{
+				"value": in.Value[len("wrap"):],
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"message":"not found"}}`))
+		}
+	}))
+	defer ts.Close()
+
+	oauthConfig, err := adal.NewOAuthConfig("https://login.microsoftonline.com/", "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := adal.NewServicePrincipalTokenFromManualToken(*oauthConfig, "c", "r", adal.Token{
+		AccessToken: "token",
+		ExpiresOn:   strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k := &AzureKeyVault{
+		client:  ts.Client(),
+		keyURL:  ts.URL + "/keys/k",
+		keyName: "k",
+		token:   token,
+	}
+	ciphertext, err := k.Encrypt([]byte("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := k.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != "foo" {
+		t.Fatalf("bad: %q", plaintext)
+	}
+
+	if _, err := k.Decrypt([]byte(`{"version":"v2","value":"Zm9v"}`)); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,179 @@
+package gcpckms
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/hashicorp/vault/vault/seal"
+	log "github.com/mgutz/logxi/v1"
+
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	// SealType is the type of the seals backed by Cloud KMS
+	SealType = "gcpckms"
+
+	cloudKMSScope    = "https://www.googleapis.com/auth/cloudkms"
+	cloudKMSEndpoint = "https://cloudkms.googleapis.com/v1/"
+)
+
+// GCPCKMS encrypts the data keys of an auto-unseal seal with a Google Cloud
+// KMS crypto key
+type GCPCKMS struct {
+	client   *http.Client
+	endpoint string
+
+	// keyName is the resource name of the crypto key,
+	// projects/<project>/locations/<region>/keyRings/<ring>/cryptoKeys/<key>
+	keyName string
+}
+
+// New returns the Cloud KMS client for the crypto key of the configuration.
+// Each parameter is read from the environment first, then from the
+// configuration. The credentials are those of the service account file
+// given by GOOGLE_APPLICATION_CREDENTIALS or the credentials parameter, or
+// the application default credentials of the host otherwise, such as the
+// service account of a GCE instance.
+func New(conf map[string]string, logger log.Logger) (*GCPCKMS, error) {
+	project := seal.EnvOrConfig("GOOGLE_PROJECT", conf, "project")
+	if project == "" {
+		return nil, fmt.Errorf("env var GOOGLE_PROJECT or configuration parameter 'project' must be set")
+	}
+	region := seal.EnvOrConfig("GOOGLE_REGION", conf, "region")
+	if region == "" {
+		region = "global"
+	}
+	keyRing := seal.EnvOrConfig("VAULT_GCPCKMS_SEAL_KEY_RING", conf, "key_ring")
+	if keyRing == "" {
+		return nil, fmt.Errorf("env var VAULT_GCPCKMS_SEAL_KEY_RING or configuration parameter 'key_ring' must be set")
+	}
+	cryptoKey := seal.EnvOrConfig("VAULT_GCPCKMS_SEAL_CRYPTO_KEY", conf, "crypto_key")
+	if cryptoKey == "" {
+		return nil, fmt.Errorf("env var VAULT_GCPCKMS_SEAL_CRYPTO_KEY or configuration parameter 'crypto_key' must be set")
+	}
+
+	ctx := context.Background()
+	var client *http.Client
+	credentialsFile := seal.EnvOrConfig("GOOGLE_APPLICATION_CREDENTIALS", conf, "credentials")
+	if credentialsFile == "" {
+		var err error
+		client, err = google.DefaultClient(ctx, cloudKMSScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load default credentials: %v", err)
+		}
+	} else {
+		credentials, err := ioutil.ReadFile(credentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read credentials file: %v", err)
+		}
+		jwtConfig, err := google.JWTConfigFromJSON(credentials, cloudKMSScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse credentials: %v", err)
+		}
+		client = jwtConfig.Client(ctx)
+	}
+
+	k := &GCPCKMS{
+		client:   client,
+		endpoint: cloudKMSEndpoint,
+		keyName:  fmt.Sprintf("projects/%s/locations/%s/keyRings/%s/cryptoKeys/%s", project, region, keyRing, cryptoKey),
+	}
+
+	// Check that the crypto key can be used
+	if err := k.do("GET", "", nil, nil); err != nil {
+		return nil, fmt.Errorf("failed to access crypto key %q: %v", k.keyName, err)
+	}
+	if logger.IsDebug() {
+		logger.Debug("seal/gcpckms: using crypto key", "key", k.keyName)
+	}
+	return k, nil
+}
+
+func (k *GCPCKMS) SealType() string {
+	return SealType
+}
+
+func (k *GCPCKMS) KeyID() string {
+	return k.keyName
+}
+
+// Encrypt encrypts the plaintext with the primary version of the crypto key
+func (k *GCPCKMS) Encrypt(plaintext []byte) ([]byte, error) {
+	in := map[string]interface{}{
+		"plaintext": plaintext,
+	}
+	var out struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	if err := k.do("POST", ":encrypt", in, &out); err != nil {
+		return nil, err
+	}
+	return out.Ciphertext, nil
+}
+
+// Decrypt decrypts a ciphertext of Encrypt, with the version of the crypto
+// key it was encrypted with
+func (k *GCPCKMS) Decrypt(ciphertext []byte) ([]byte, error) {
+	in := map[string]interface{}{
+		"ciphertext": ciphertext,
+	}
+	var out struct {
+		Plaintext []byte `json:"plaintext"`
+	}
+	if err := k.do("POST", ":decrypt", in, &out); err != nil {
+		return nil, err
+	}
+	return out.Plaintext, nil
+}
+
+// do calls the method of the crypto key. The byte slices of the request and
+// the response are base64 encoded, as the API expects.
+func (k *GCPCKMS) do(method, suffix string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		buf, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequest(method, k.endpoint+k.keyName+suffix, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var errResp struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(respBody, &errResp)
+		return fmt.Errorf("cloudkms: error %d: %s", resp.StatusCode, errResp.Error.Message)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
@@ -0,0 +1,82 @@
+package gcpckms
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestGCPCKMS_New_MissingConfig(t *testing.T) {
+	os.Unsetenv("GOOGLE_PROJECT")
+	os.Unsetenv("VAULT_GCPCKMS_SEAL_KEY_RING")
+	os.Unsetenv("VAULT_GCPCKMS_SEAL_CRYPTO_KEY")
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	for _, conf := range []map[string]string{
+		{},
+		{"project": "p"},
+		{"project": "p", "key_ring": "r"},
+	} {
+		if _, err := New(conf, logger); err == nil {
+			t.Fatalf("expected error for %#v", conf)
+		}
+	}
+}
+
+func TestGCPCKMS_EncryptDecrypt(t *testing.T) {
+	keyName := "projects/p/locations/global/keyRings/r/cryptoKeys/k"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in map[string][]byte
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			t.Fatal(err)
+		}
+		// The fake KMS reverses the bytes
+		reverse := func(b []byte) []byte {
+			out := make([]byte, len(b))
+			for i := range b {
+				out[len(b)-1-i] = b[i]
+			}
+			return out
+		}
+		switch r.URL.Path {
+		case "/" + keyName + ":encrypt":
+			json.NewEncoder(w).Encode(map[string][]byte{"ciphertext": reverse(in["plaintext"])})
+		case "/" + keyName + ":decrypt":
+			json.NewEncoder(w).Encode(map[string][]byte{"plaintext": reverse(in["ciphertext"])})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"message":"not found"}}`))
+		}
+	}))
+	defer ts.Close()
+
+	k := &GCPCKMS{
+		client:   ts.Client(),
+		endpoint: ts.URL + "/",
+		keyName:  keyName,
+	}
+	ciphertext, err := k.Encrypt([]byte("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ciphertext) != "oof" {
+		t.Fatalf("bad: %q", ciphertext)
+	}
+	plaintext, err := k.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != "foo" {
+		t.Fatalf("bad: %q", plaintext)
+	}
+
+	k.keyName = "projects/p/locations/global/keyRings/r/cryptoKeys/missing"
+	if _, err := k.Encrypt([]byte("foo")); err == nil {
+		t.Fatal("expected error")
+	}
+}
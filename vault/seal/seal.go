@@ -0,0 +1,14 @@
+// Package seal holds the helpers shared by the auto-unseal seal
+// implementations in its subpackages.
+package seal
+
+import "os"
+
+// EnvOrConfig returns the value of the environment variable, or the value
+// of the configuration parameter if the variable is not set
+func EnvOrConfig(env string, conf map[string]string, key string) string {
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	return conf[key]
+}
@@ -0,0 +1,232 @@
+package transit
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/vault/seal"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// SealType is the type of the seals backed by the transit backend of
+	// another Vault
+	SealType = "transit"
+)
+
+// Transit encrypts the data keys of an auto-unseal seal with a key of the
+// transit backend of another Vault, so that a Vault can unseal itself with a
+// central, hardened one
+type Transit struct {
+	client    *api.Client
+	logger    log.Logger
+	mountPath string
+	keyName   string
+
+	renewerLock sync.Mutex
+	renewer     *api.Renewer
+}
+
+// New returns the transit client for the key of the configuration. Each
+// parameter is read from the environment first, then from the
+// configuration, with the environment variables of the Vault CLI for the
+// address, the token and the TLS parameters.
+func New(conf map[string]string, logger log.Logger) (*Transit, error) {
+	mountPath := seal.EnvOrConfig("VAULT_TRANSIT_SEAL_MOUNT_PATH", conf, "mount_path")
+	if mountPath == "" {
+		return nil, fmt.Errorf("env var VAULT_TRANSIT_SEAL_MOUNT_PATH or configuration parameter 'mount_path' must be set")
+	}
+	keyName := seal.EnvOrConfig("VAULT_TRANSIT_SEAL_KEY_NAME", conf, "key_name")
+	if keyName == "" {
+		return nil, fmt.Errorf("env var VAULT_TRANSIT_SEAL_KEY_NAME or configuration parameter 'key_name' must be set")
+	}
+	token := seal.EnvOrConfig(api.EnvVaultToken, conf, "token")
+	if token == "" {
+		return nil, fmt.Errorf("env var %s or configuration parameter 'token' must be set", api.EnvVaultToken)
+	}
+
+	clientConfig := api.DefaultConfig()
+	if address := seal.EnvOrConfig(api.EnvVaultAddress, conf, "address"); address != "" {
+		clientConfig.Address = address
+	}
+	tlsConfig := &api.TLSConfig{
+		CACert:        seal.EnvOrConfig(api.EnvVaultCACert, conf, "tls_ca_cert"),
+		ClientCert:    seal.EnvOrConfig(api.EnvVaultClientCert, conf, "tls_client_cert"),
+		ClientKey:     seal.EnvOrConfig(api.EnvVaultClientKey, conf, "tls_client_key"),
+		TLSServerName: seal.EnvOrConfig(api.EnvVaultTLSServerName, conf, "tls_server_name"),
+	}
+	if v := seal.EnvOrConfig(api.EnvVaultInsecure, conf, "tls_skip_verify"); v != "" {
+		insecure, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for 'tls_skip_verify': %v", err)
+		}
+		tlsConfig.Insecure = insecure
+	}
+	if err := clientConfig.ConfigureTLS(tlsConfig); err != nil {
+		return nil, fmt.Errorf("failed to configure TLS: %v", err)
+	}
+	client, err := api.NewClient(clientConfig)
+	if err != nil {
+		return nil, err
+	}
+	client.SetToken(token)
+
+	disableRenewal := false
+	if v := conf["disable_renewal"]; v != "" {
+		disableRenewal, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for 'disable_renewal': %v", err)
+		}
+	}
+
+	return newTransit(client, mountPath, keyName, disableRenewal, logger)
+}
+
+// newTransit returns the transit client of the key with the given client,
+// after checking that the key can be read, and starts renewing the token of
+// the client unless disabled
+func newTransit(client *api.Client, mountPath, keyName string, disableRenewal bool, logger log.Logger) (*Transit, error) {
+	k := &Transit{
+		client:    client,
+		logger:    logger,
+		mountPath: strings.Trim(mountPath, "/"),
+		keyName:   keyName,
+	}
+
+	// Check that the key can be used
+	secret, err := client.Logical().Read(k.path("keys"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %q: %v", keyName, err)
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("key %q not found at %q", keyName, k.mountPath)
+	}
+
+	if !disableRenewal {
+		if err := k.startRenewer(); err != nil {
+			return nil, err
+		}
+	}
+	if logger.IsDebug() {
+		logger.Debug("seal/transit: using key", "address", client.Address(), "mount_path", k.mountPath, "key", keyName)
+	}
+	return k, nil
+}
+
+// startRenewer renews the token of the client in the background if it is
+// renewable. The renewal stops when the token cannot be renewed anymore, at
+// which point the seal stops working once the token expires.
+func (k *Transit) startRenewer() error {
+	secret, err := k.client.Auth().Token().LookupSelf()
+	if err != nil {
+		return fmt.Errorf("failed to look up the token: %v", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return fmt.Errorf("no data returned when looking up the token")
+	}
+	if renewable, _ := secret.Data["renewable"].(bool); !renewable {
+		return nil
+	}
+
+	renewer, err := k.client.NewRenewer(&api.RenewerInput{
+		Secret: &api.Secret{
+			Auth: &api.SecretAuth{
+				ClientToken: k.client.Token(),
+				Renewable:   true,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to renew the token: %v", err)
+	}
+
+	k.renewerLock.Lock()
+	k.renewer = renewer
+	k.renewerLock.Unlock()
+
+	go renewer.Renew()
+	go func() {
+		for {
+			select {
+			case err := <-renewer.DoneCh():
+				if err != nil {
+					k.logger.Error("seal/transit: failed to renew the token", "error", err)
+				} else {
+					k.logger.Warn("seal/transit: stopped renewing the token")
+				}
+				return
+			case renewal := <-renewer.RenewCh():
+				if k.logger.IsTrace() {
+					k.logger.Trace("seal/transit: renewed the token", "renewed_at", renewal.RenewedAt)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Finalize stops renewing the token
+func (k *Transit) Finalize() error {
+	k.renewerLock.Lock()
+	defer k.renewerLock.Unlock()
+	if k.renewer != nil {
+		k.renewer.Stop()
+		k.renewer = nil
+	}
+	return nil
+}
+
+func (k *Transit) SealType() string {
+	return SealType
+}
+
+func (k *Transit) KeyID() string {
+	return k.mountPath + "/" + k.keyName
+}
+
+// Encrypt encrypts the plaintext with the latest version of the key
+func (k *Transit) Encrypt(plaintext []byte) ([]byte, error) {
+	secret, err := k.client.Logical().Write(k.path("encrypt"), map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data["ciphertext"] == nil {
+		return nil, fmt.Errorf("no ciphertext returned")
+	}
+	ciphertext, ok := secret.Data["ciphertext"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid ciphertext returned")
+	}
+	return []byte(ciphertext), nil
+}
+
+// Decrypt decrypts a ciphertext of Encrypt, with the version of the key it
+// was encrypted with
+func (k *Transit) Decrypt(ciphertext []byte) ([]byte, error) {
+	secret, err := k.client.Logical().Write(k.path("decrypt"), map[string]interface{}{
+		"ciphertext": string(ciphertext),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data["plaintext"] == nil {
+		return nil, fmt.Errorf("no plaintext returned")
+	}
+	plaintext, ok := secret.Data["plaintext"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid plaintext returned")
+	}
+	return base64.StdEncoding.DecodeString(plaintext)
+}
+
+// path returns the path of the operation on the key
+func (k *Transit) path(operation string) string {
+	return path.Join(k.mountPath, operation, k.keyName)
+}
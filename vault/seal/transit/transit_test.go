@@ -0,0 +1,145 @@
+package transit
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/builtin/logical/transit"
+	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// testTransitCluster returns a Vault cluster with the transit backend
+// mounted at "transit" and a key named "unseal", and the root client of its
+// active node
+func testTransitCluster(t *testing.T) (*vault.TestCluster, *api.Client) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		DisableMlock: true,
+		DisableCache: true,
+		Logger:       log.NullLog,
+		LogicalBackends: map[string]logical.Factory{
+			"transit": transit.Factory,
+		},
+	}, true)
+	cluster.StartListeners()
+	for _, core := range cluster.Cores {
+		core.Handler.Handle("/", vaulthttp.Handler(core.Core))
+	}
+	vault.TestWaitActive(t, cluster.Cores[0].Core)
+
+	client := cluster.Cores[0].Client
+	client.SetToken(cluster.Cores[0].Root)
+	if err := client.Sys().Mount("transit", &api.MountInput{Type: "transit"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("transit/keys/unseal", nil); err != nil {
+		t.Fatal(err)
+	}
+	return cluster, client
+}
+
+func TestTransit_New_MissingConfig(t *testing.T) {
+	os.Unsetenv("VAULT_TRANSIT_SEAL_MOUNT_PATH")
+	os.Unsetenv("VAULT_TRANSIT_SEAL_KEY_NAME")
+	os.Unsetenv(api.EnvVaultToken)
+
+	for _, conf := range []map[string]string{
+		{},
+		{"mount_path": "transit"},
+		{"mount_path": "transit", "key_name": "unseal"},
+		{"mount_path": "transit", "key_name": "unseal", "token": "foo", "tls_skip_verify": "maybe"},
+	} {
+		if _, err := New(conf, log.NullLog); err == nil {
+			t.Fatalf("expected error for %#v", conf)
+		}
+	}
+}
+
+func TestTransit_EncryptDecrypt(t *testing.T) {
+	cluster, client := testTransitCluster(t)
+	defer cluster.Cleanup()
+
+	if _, err := newTransit(client, "transit", "missing", true, log.NullLog); err == nil {
+		t.Fatal("expected error")
+	}
+
+	k, err := newTransit(client, "/transit/", "unseal", true, log.NullLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.KeyID() != "transit/unseal" {
+		t.Fatalf("bad: %q", k.KeyID())
+	}
+
+	// Values stay readable after rotating the key
+	seal := vault.NewAutoSeal(k)
+	wrapped, err := seal.SealWrap([]byte("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("transit/keys/unseal/rotate", nil); err != nil {
+		t.Fatal(err)
+	}
+	plain, err := seal.SealUnwrap(wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plain) != "foo" {
+		t.Fatalf("bad: %q", plain)
+	}
+}
+
+func TestTransit_Renewal(t *testing.T) {
+	cluster, client := testTransitCluster(t)
+	defer cluster.Cleanup()
+
+	// The root token is not renewable
+	k, err := newTransit(client, "transit", "unseal", false, log.NullLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.renewer != nil {
+		t.Fatal("should not renew the root token")
+	}
+
+	secret, err := client.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"root"},
+		TTL:      "1h",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenClient := cluster.Cores[1].Client
+	tokenClient.SetToken(secret.Auth.ClientToken)
+	k, err = newTransit(tokenClient, "transit", "unseal", false, log.NullLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.renewer == nil {
+		t.Fatal("should renew the token")
+	}
+
+	// The token is renewed as soon as the renewal starts
+	renewed := false
+	for i := 0; i < 50 && !renewed; i++ {
+		lookup, err := client.Auth().Token().Lookup(secret.Auth.ClientToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		renewed = lookup.Data["last_renewal_time"] != nil
+		time.Sleep(100 * time.Millisecond)
+	}
+	if !renewed {
+		t.Fatal("the token was not renewed")
+	}
+	if err := k.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	if k.renewer != nil {
+		t.Fatal("should have stopped renewing")
+	}
+}
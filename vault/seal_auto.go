@@ -0,0 +1,353 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/physical"
+)
+
+const (
+	// autoSealStoredKeysPath is the path the unseal keys stored by an
+	// AutoSeal are kept at, seal-wrapped
+	autoSealStoredKeysPath = "core/hsm/barrier-unseal-keys"
+)
+
+// KMS is a key management service an AutoSeal protects the master key
+// with, such as a cloud KMS. The KMS only encrypts the random data keys the
+// values are encrypted with, so it never sees the values themselves.
+type KMS interface {
+	// SealType is the type of the seal, such as "gcpckms"
+	SealType() string
+
+	// KeyID identifies the key of the KMS values are encrypted with
+	KeyID() string
+
+	// Encrypt and Decrypt encrypt and decrypt a data key with the key of
+	// the KMS
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// KMSFinalizer is an optional interface for the KMSs which hold resources
+// to release when the seal is finalized, such as a token being renewed
+type KMSFinalizer interface {
+	Finalize() error
+}
+
+// autoSealBlob is a value encrypted by an AutoSeal
+type autoSealBlob struct {
+	KeyID      string `json:"key_id"`
+	WrappedKey []byte `json:"wrapped_key"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// AutoSeal is a seal which stores the unseal keys encrypted by a KMS, so
+// that Vault unseals itself when the KMS is available, as well as the
+// recovery key used in place of the unseal keys for the operations which
+// require them, such as generating a root token. It seal-wraps the critical
+// entries of the storage with the KMS.
+type AutoSeal struct {
+	kms  KMS
+	core *Core
+
+	config         *SealConfig
+	recoveryConfig *SealConfig
+}
+
+// NewAutoSeal returns a seal protecting the master key with the KMS
+func NewAutoSeal(kms KMS) *AutoSeal {
+	return &AutoSeal{
+		kms: kms,
+	}
+}
+
+func (a *AutoSeal) checkCore() error {
+	if a.core == nil {
+		return fmt.Errorf("seal does not have a core set")
+	}
+	return nil
+}
+
+func (a *AutoSeal) SetCore(core *Core) {
+	a.core = core
+}
+
+func (a *AutoSeal) Init() error {
+	return nil
+}
+
+func (a *AutoSeal) Finalize() error {
+	if finalizer, ok := a.kms.(KMSFinalizer); ok {
+		return finalizer.Finalize()
+	}
+	return nil
+}
+
+func (a *AutoSeal) BarrierType() string {
+	return a.kms.SealType()
+}
+
+func (a *AutoSeal) StoredKeysSupported() bool {
+	return true
+}
+
+func (a *AutoSeal) RecoveryKeySupported() bool {
+	return true
+}
+
+func (a *AutoSeal) SetStoredKeys(keys [][]byte) error {
+	if err := a.checkCore(); err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("failed to encode stored keys: %v", err)
+	}
+	if err := a.core.physical.Put(&physical.Entry{
+		Key:      autoSealStoredKeysPath,
+		Value:    buf,
+		SealWrap: true,
+	}); err != nil {
+		return fmt.Errorf("failed to write stored keys: %v", err)
+	}
+	return nil
+}
+
+func (a *AutoSeal) GetStoredKeys() ([][]byte, error) {
+	if err := a.checkCore(); err != nil {
+		return nil, err
+	}
+
+	pe, err := a.core.physical.Get(autoSealStoredKeysPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored keys: %v", err)
+	}
+	if pe == nil {
+		return nil, nil
+	}
+
+	var keys [][]byte
+	if err := jsonutil.DecodeJSON(pe.Value, &keys); err != nil {
+		return nil, fmt.Errorf("failed to decode stored keys: %v", err)
+	}
+	return keys, nil
+}
+
+func (a *AutoSeal) BarrierConfig() (*SealConfig, error) {
+	if a.config != nil {
+		return a.config.Clone(), nil
+	}
+
+	conf, err := a.readConfig(barrierSealConfigPath, a.BarrierType())
+	if err != nil {
+		return nil, err
+	}
+	a.config = conf
+	if conf == nil {
+		a.core.logger.Info("core: seal configuration missing, not initialized")
+		return nil, nil
+	}
+	return conf.Clone(), nil
+}
+
+func (a *AutoSeal) SetBarrierConfig(config *SealConfig) error {
+	if config == nil {
+		a.config = nil
+		return nil
+	}
+
+	config.Type = a.BarrierType()
+	if err := a.writeConfig(barrierSealConfigPath, config); err != nil {
+		return err
+	}
+	a.config = config.Clone()
+	return nil
+}
+
+func (a *AutoSeal) RecoveryType() string {
+	return "shamir"
+}
+
+func (a *AutoSeal) RecoveryConfig() (*SealConfig, error) {
+	if a.recoveryConfig != nil {
+		return a.recoveryConfig.Clone(), nil
+	}
+
+	conf, err := a.readConfig(recoverySealConfigPath, a.RecoveryType())
+	if err != nil {
+		return nil, err
+	}
+	a.recoveryConfig = conf
+	if conf == nil {
+		return nil, nil
+	}
+	return conf.Clone(), nil
+}
+
+func (a *AutoSeal) SetRecoveryConfig(config *SealConfig) error {
+	if config == nil {
+		a.recoveryConfig = nil
+		return nil
+	}
+
+	config.Type = a.RecoveryType()
+	if err := a.writeConfig(recoverySealConfigPath, config); err != nil {
+		return err
+	}
+	a.recoveryConfig = config.Clone()
+	return nil
+}
+
+func (a *AutoSeal) SetRecoveryKey(key []byte) error {
+	if err := a.checkCore(); err != nil {
+		return err
+	}
+
+	if err := a.core.physical.Put(&physical.Entry{
+		Key:      recoveryKeyPath,
+		Value:    key,
+		SealWrap: true,
+	}); err != nil {
+		return fmt.Errorf("failed to write recovery key: %v", err)
+	}
+	return nil
+}
+
+func (a *AutoSeal) VerifyRecoveryKey(key []byte) error {
+	if err := a.checkCore(); err != nil {
+		return err
+	}
+
+	pe, err := a.core.physical.Get(recoveryKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read recovery key: %v", err)
+	}
+	if pe == nil {
+		return fmt.Errorf("no recovery key found")
+	}
+	if subtle.ConstantTimeCompare(pe.Value, key) != 1 {
+		return fmt.Errorf("recovery key verification failed")
+	}
+	return nil
+}
+
+// readConfig reads the seal configuration stored at the path, or returns
+// nil if there is none
+func (a *AutoSeal) readConfig(path, sealType string) (*SealConfig, error) {
+	if err := a.checkCore(); err != nil {
+		return nil, err
+	}
+
+	pe, err := a.core.physical.Get(path)
+	if err != nil {
+		a.core.logger.Error("core: failed to read seal configuration", "path", path, "error", err)
+		return nil, fmt.Errorf("failed to check seal configuration: %v", err)
+	}
+	if pe == nil {
+		return nil, nil
+	}
+
+	var conf SealConfig
+	if err := jsonutil.DecodeJSON(pe.Value, &conf); err != nil {
+		a.core.logger.Error("core: failed to decode seal configuration", "path", path, "error", err)
+		return nil, fmt.Errorf("failed to decode seal configuration: %v", err)
+	}
+	if conf.Type != sealType {
+		a.core.logger.Error("core: seal type does not match loaded type", "seal_type", conf.Type, "loaded_seal_type", sealType)
+		return nil, fmt.Errorf("seal type of %s does not match loaded type of %s", conf.Type, sealType)
+	}
+	if err := conf.Validate(); err != nil {
+		a.core.logger.Error("core: invalid seal configuration", "path", path, "error", err)
+		return nil, fmt.Errorf("seal validation failed: %v", err)
+	}
+	return &conf, nil
+}
+
+// writeConfig stores the seal configuration at the path
+func (a *AutoSeal) writeConfig(path string, config *SealConfig) error {
+	if err := a.checkCore(); err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode seal configuration: %v", err)
+	}
+	if err := a.core.physical.Put(&physical.Entry{
+		Key:   path,
+		Value: buf,
+	}); err != nil {
+		a.core.logger.Error("core: failed to write seal configuration", "path", path, "error", err)
+		return fmt.Errorf("failed to write seal configuration: %v", err)
+	}
+	return nil
+}
+
+// SealWrap encrypts the value with a random data key, which is encrypted by
+// the KMS
+func (a *AutoSeal) SealWrap(plaintext []byte) ([]byte, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	defer memzero(dataKey)
+
+	gcm, err := autoSealAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	wrappedKey, err := a.kms.Encrypt(dataKey)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to encrypt the data key: {{err}}", err)
+	}
+	return json.Marshal(&autoSealBlob{
+		KeyID:      a.kms.KeyID(),
+		WrappedKey: wrappedKey,
+		Ciphertext: gcm.Seal(nonce, nonce, plaintext, nil),
+	})
+}
+
+// SealUnwrap decrypts a value encrypted by SealWrap
+func (a *AutoSeal) SealUnwrap(ciphertext []byte) ([]byte, error) {
+	var blob autoSealBlob
+	if err := jsonutil.DecodeJSON(ciphertext, &blob); err != nil {
+		return nil, fmt.Errorf("failed to decode the seal-wrapped value: %v", err)
+	}
+
+	dataKey, err := a.kms.Decrypt(blob.WrappedKey)
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("failed to decrypt the data key with key %q: {{err}}", blob.KeyID), err)
+	}
+	defer memzero(dataKey)
+
+	gcm, err := autoSealAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(blob.Ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid seal-wrapped value")
+	}
+	nonce := blob.Ciphertext[:gcm.NonceSize()]
+	return gcm.Open(nil, nonce, blob.Ciphertext[gcm.NonceSize():], nil)
+}
+
+func autoSealAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,137 @@
+package vault
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// testKMS is a KMS encrypting the data keys with an in-memory AES key
+type testKMS struct {
+	key []byte
+}
+
+func newTestKMS(t *testing.T) *testKMS {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return &testKMS{key: key}
+}
+
+func (k *testKMS) SealType() string { return "test-kms" }
+
+func (k *testKMS) KeyID() string { return "test-key" }
+
+func (k *testKMS) Encrypt(plaintext []byte) ([]byte, error) {
+	gcm, err := autoSealAEAD(k.key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (k *testKMS) Decrypt(ciphertext []byte) ([]byte, error) {
+	gcm, err := autoSealAEAD(k.key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := ciphertext[:gcm.NonceSize()]
+	return gcm.Open(nil, nonce, ciphertext[gcm.NonceSize():], nil)
+}
+
+func TestAutoSeal_SealWrap(t *testing.T) {
+	seal := NewAutoSeal(newTestKMS(t))
+	wrapped, err := seal.SealWrap([]byte("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := seal.SealUnwrap(wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plain) != "foo" {
+		t.Fatalf("bad: %q", plain)
+	}
+
+	// A different key of the KMS cannot unwrap the value
+	if _, err := NewAutoSeal(newTestKMS(t)).SealUnwrap(wrapped); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCore_AutoSeal(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	inm := physical.NewInmem(logger)
+	kms := newTestKMS(t)
+
+	newCore := func() *Core {
+		conf := testCoreConfig(t, inm, logger)
+		conf.Seal = NewAutoSeal(kms)
+		c, err := NewCore(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	c := newCore()
+	result, err := c.Initialize(&InitParams{
+		BarrierConfig: &SealConfig{
+			SecretShares:    1,
+			SecretThreshold: 1,
+			StoredShares:    1,
+		},
+		RecoveryConfig: &SealConfig{
+			SecretShares:    1,
+			SecretThreshold: 1,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.SecretShares) != 0 || len(result.RecoveryShares) != 1 {
+		t.Fatalf("bad: %#v", result)
+	}
+	if err := c.UnsealWithStoredKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := c.Sealed(); sealed {
+		t.Fatal("should not be sealed")
+	}
+
+	for _, key := range []string{autoSealStoredKeysPath, keyringPath, masterKeyPath, recoveryKeyPath} {
+		if !testSealWrapped(t, inm, key) {
+			t.Fatalf("%q is not seal wrapped", key)
+		}
+	}
+	if err := c.seal.VerifyRecoveryKey(result.RecoveryShares[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.seal.VerifyRecoveryKey([]byte("bad")); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// A new core unseals itself with the same KMS
+	c2 := newCore()
+	if err := c2.UnsealWithStoredKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := c2.Sealed(); sealed {
+		t.Fatal("should not be sealed")
+	}
+	conf, err := c2.seal.BarrierConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Type != "test-kms" {
+		t.Fatalf("bad: %#v", conf)
+	}
+}
@@ -2,6 +2,9 @@ package vault
 
 import (
 	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
 	"fmt"
 	"testing"
 )
@@ -18,6 +21,10 @@ type TestSeal struct {
 	recoveryConfig       *SealConfig
 	storedKeysDisabled   bool
 	recoveryKeysDisabled bool
+
+	// wrapKey is the key entries are seal-wrapped with, standing for the
+	// key of a KMS
+	wrapKey []byte
 }
 
 func newTestSeal(t *testing.T) Seal {
@@ -34,6 +41,12 @@ func (d *TestSeal) checkCore() error {
 func (d *TestSeal) SetCore(core *Core) {
 	d.defseal = &DefaultSeal{}
 	d.defseal.core = core
+	if d.wrapKey == nil {
+		d.wrapKey = make([]byte, 32)
+		if _, err := rand.Read(d.wrapKey); err != nil {
+			panic(err)
+		}
+	}
 }
 
 func (d *TestSeal) Init() error {
@@ -105,6 +118,38 @@ func (d *TestSeal) SetRecoveryKey(key []byte) error {
 	return nil
 }
 
+func (d *TestSeal) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(d.wrapKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (d *TestSeal) SealWrap(plaintext []byte) ([]byte, error) {
+	gcm, err := d.aead()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (d *TestSeal) SealUnwrap(ciphertext []byte) ([]byte, error) {
+	gcm, err := d.aead()
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid ciphertext length")
+	}
+	nonce := ciphertext[:gcm.NonceSize()]
+	return gcm.Open(nil, nonce, ciphertext[gcm.NonceSize():], nil)
+}
+
 func testCoreUnsealedWithConfigs(t *testing.T, barrierConf, recoveryConf *SealConfig) (*Core, [][]byte, [][]byte, string) {
 	seal := &TestSeal{}
 	core := TestCoreWithSeal(t, seal)
@@ -0,0 +1,145 @@
+package vault
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+)
+
+// errSealWrapUnsupported is returned when enabling seal wrapping for a mount
+// while the seal does not support it
+var errSealWrapUnsupported = logical.CodedError(400, "seal wrapping is not supported by the seal")
+
+// sealWrapHeader prefixes the values seal-wrapped in the physical backend.
+// Values encrypted by the barrier start with their key term, which never
+// reaches this header, and those stored in plaintext are JSON.
+var sealWrapHeader = []byte("\xffsealwrap\x01")
+
+// sealWrappedPaths are the paths of the critical entries which are always
+// seal-wrapped if the seal supports it: the keyring, the master key, and
+// the recovery key and its configuration
+var sealWrappedPaths = map[string]bool{
+	keyringPath:            true,
+	masterKeyPath:          true,
+	recoverySealConfigPath: true,
+	recoveryKeyPath:        true,
+}
+
+// SealWrapper is an optional interface for seals which can encrypt values
+// with the key they protect the master key with, such as the seals of a KMS
+// or an HSM. The entries flagged for seal wrapping are encrypted by the seal
+// on top of the barrier, so that their plaintext is protected by the key of
+// the seal, as required by FIPS 140-2 for critical security parameters.
+type SealWrapper interface {
+	// SealWrap encrypts the value with the key of the seal
+	SealWrap(plaintext []byte) ([]byte, error)
+
+	// SealUnwrap decrypts a value encrypted by SealWrap
+	SealUnwrap(ciphertext []byte) ([]byte, error)
+}
+
+// sealWrapBackend wraps the physical backend of the core, seal-wrapping
+// the values of the entries flagged for it when put and unwrapping them when
+// read back. Without a SealWrapper the values are stored as they are, and the
+// seal-wrapped values which were already stored cannot be read.
+type sealWrapBackend struct {
+	physical.Backend
+	wrapper SealWrapper
+}
+
+// newSealWrapBackend returns the backend wrapping the values stored in the
+// given backend with the seal, if the seal is a SealWrapper
+func newSealWrapBackend(b physical.Backend, seal Seal) *sealWrapBackend {
+	wrapper, _ := seal.(SealWrapper)
+	return &sealWrapBackend{
+		Backend: b,
+		wrapper: wrapper,
+	}
+}
+
+func (s *sealWrapBackend) Put(entry *physical.Entry) error {
+	entry, err := s.wrap(entry)
+	if err != nil {
+		return err
+	}
+	return s.Backend.Put(entry)
+}
+
+// wrap returns the entry to store for the given one, seal-wrapped if it is
+// flagged for it and the seal supports it
+func (s *sealWrapBackend) wrap(entry *physical.Entry) (*physical.Entry, error) {
+	if s.wrapper == nil || !(entry.SealWrap || sealWrappedPaths[entry.Key]) {
+		return entry, nil
+	}
+
+	wrapped, err := s.wrapper.SealWrap(entry.Value)
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("failed to seal-wrap %q: {{err}}", entry.Key), err)
+	}
+	return &physical.Entry{
+		Key:      entry.Key,
+		Value:    append(append([]byte{}, sealWrapHeader...), wrapped...),
+		SealWrap: true,
+	}, nil
+}
+
+func (s *sealWrapBackend) Get(key string) (*physical.Entry, error) {
+	pe, err := s.Backend.Get(key)
+	if err != nil || pe == nil || !bytes.HasPrefix(pe.Value, sealWrapHeader) {
+		return pe, err
+	}
+
+	if s.wrapper == nil {
+		return nil, fmt.Errorf("%q is seal-wrapped but the seal does not support seal wrapping", key)
+	}
+	plain, err := s.wrapper.SealUnwrap(pe.Value[len(sealWrapHeader):])
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("failed to unwrap %q: {{err}}", key), err)
+	}
+	return &physical.Entry{
+		Key:      key,
+		Value:    plain,
+		SealWrap: true,
+	}, nil
+}
+
+func (s *sealWrapBackend) ListPage(prefix, after string, limit int) ([]string, error) {
+	return physical.ListPage(s.Backend, prefix, after, limit)
+}
+
+// Purge purges the underlying backend if it is Purgable
+func (s *sealWrapBackend) Purge() {
+	if purgable, ok := s.Backend.(physical.Purgable); ok {
+		purgable.Purge()
+	}
+}
+
+func (s *sealWrapBackend) Transaction(txns []physical.TxnEntry) error {
+	txnl, ok := s.Backend.(physical.Transactional)
+	if !ok {
+		return fmt.Errorf("seal wrap: underlying backend does not support transactions")
+	}
+
+	wrapped := make([]physical.TxnEntry, 0, len(txns))
+	for _, txn := range txns {
+		if txn.Operation == physical.PutOperation {
+			entry, err := s.wrap(txn.Entry)
+			if err != nil {
+				return err
+			}
+			txn.Entry = entry
+		}
+		wrapped = append(wrapped, txn)
+	}
+	return txnl.Transaction(wrapped)
+}
+
+// sealWrapSupported returns whether the seal of the core can seal-wrap
+// entries
+func (c *Core) sealWrapSupported() bool {
+	_, ok := c.seal.(SealWrapper)
+	return ok
+}
@@ -0,0 +1,175 @@
+package vault
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// testSealWrapped returns whether the raw value of the key is seal-wrapped
+func testSealWrapped(t *testing.T, b physical.Backend, key string) bool {
+	pe, err := b.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pe == nil {
+		t.Fatalf("no entry at %q", key)
+	}
+	return bytes.HasPrefix(pe.Value, sealWrapHeader)
+}
+
+func TestSealWrapBackend(t *testing.T) {
+	inm := physical.NewInmem(logformat.NewVaultLogger(log.LevelTrace))
+	seal := &TestSeal{}
+	seal.SetCore(nil)
+	b := newSealWrapBackend(inm, seal)
+
+	entries := []*physical.Entry{
+		&physical.Entry{Key: "foo", Value: []byte("bar"), SealWrap: true},
+		&physical.Entry{Key: "plain", Value: []byte("bar")},
+		&physical.Entry{Key: keyringPath, Value: []byte("bar")},
+	}
+	for _, entry := range entries {
+		if err := b.Put(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, entry := range entries {
+		wrapped := entry.Key != "plain"
+		if testSealWrapped(t, inm, entry.Key) != wrapped {
+			t.Fatalf("%q: expected seal wrapped %v", entry.Key, wrapped)
+		}
+		pe, err := b.Get(entry.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(pe.Value) != "bar" || pe.SealWrap != wrapped {
+			t.Fatalf("bad: %#v", pe)
+		}
+	}
+
+	// Seal-wrapped values cannot be read without a seal supporting it
+	b = newSealWrapBackend(inm, &DefaultSeal{})
+	if _, err := b.Get("foo"); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := b.Put(&physical.Entry{Key: "foo", Value: []byte("baz"), SealWrap: true}); err != nil {
+		t.Fatal(err)
+	}
+	if testSealWrapped(t, inm, "foo") {
+		t.Fatal("should not be seal wrapped")
+	}
+}
+
+func TestCore_SealWrap(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	inm := physical.NewInmem(logger)
+	conf := testCoreConfig(t, inm, logger)
+	conf.Seal = &TestSeal{}
+	c, err := NewCore(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	barrierConf, recoveryConf := testSealDefConfigs()
+	result, err := c.Initialize(&InitParams{
+		BarrierConfig:  barrierConf,
+		RecoveryConfig: recoveryConf,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unseal := func() {
+		for _, key := range result.SecretShares {
+			if _, err := c.Unseal(TestKeyCopy(key)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if sealed, _ := c.Sealed(); sealed {
+			t.Fatal("should not be sealed")
+		}
+	}
+	unseal()
+	root := result.RootToken
+
+	for _, key := range []string{keyringPath, masterKeyPath} {
+		if !testSealWrapped(t, inm, key) {
+			t.Fatalf("%q is not seal wrapped", key)
+		}
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/wrapped")
+	req.Data["type"] = "generic"
+	req.Data["seal_wrap"] = true
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"wrapped/foo", "secret/foo"} {
+		req = logical.TestRequest(t, logical.UpdateOperation, path)
+		req.Data["bar"] = "baz"
+		req.ClientToken = root
+		if _, err := c.HandleRequest(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wrapped := c.router.MatchingMountEntry("wrapped/")
+	if !testSealWrapped(t, inm, backendBarrierPrefix+wrapped.UUID+"/foo") {
+		t.Fatal("the entries of the mount are not seal wrapped")
+	}
+	secret := c.router.MatchingMountEntry("secret/")
+	if testSealWrapped(t, inm, backendBarrierPrefix+secret.UUID+"/foo") {
+		t.Fatal("the entries of other mounts should not be seal wrapped")
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["wrapped/"].(map[string]interface{})["seal_wrap"] != true {
+		t.Fatalf("bad: %#v", resp.Data["wrapped/"])
+	}
+
+	// The seal-wrapped keyring is read back when unsealing
+	if err := c.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+	unseal()
+	req = logical.TestRequest(t, logical.ReadOperation, "wrapped/foo")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.Data["bar"] != "baz" {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestCore_SealWrap_Unsupported(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/wrapped")
+	req.Data["type"] = "generic"
+	req.Data["seal_wrap"] = true
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("bad: %#v %v", resp, err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/auth/wrapped")
+	req.Data["type"] = "noop"
+	req.Data["seal_wrap"] = true
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("bad: %#v %v", resp, err)
+	}
+}
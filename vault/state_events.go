@@ -0,0 +1,81 @@
+package vault
+
+import (
+	"sync"
+)
+
+// stateEvents wakes up the goroutines waiting for the state of the core to
+// change, e.g. for it to become active, to become a standby, or for the
+// forwarding connection of a standby to the active node to be established.
+// Waiters check the state they are waiting for and, if it is not reached,
+// wait for the channel returned by changed, which is closed on the next
+// change; the channel must be fetched before checking the state so that no
+// change is missed.
+type stateEvents struct {
+	lock sync.Mutex
+	ch   chan struct{}
+}
+
+func newStateEvents() *stateEvents {
+	return &stateEvents{
+		ch: make(chan struct{}),
+	}
+}
+
+// notify wakes up the current waiters
+func (e *stateEvents) notify() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	close(e.ch)
+	e.ch = make(chan struct{})
+}
+
+// changed returns a channel closed on the next change of state
+func (e *stateEvents) changed() <-chan struct{} {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.ch
+}
+
+// StateChanged returns a channel closed on the next change of the state of
+// the core: being sealed or unsealed, becoming active or a standby, or the
+// forwarding connection of a standby to the active node being established
+// or cleared.
+func (c *Core) StateChanged() <-chan struct{} {
+	return c.stateEvents.changed()
+}
+
+// activeReady returns whether the core is unsealed and active
+func (c *Core) activeReady() bool {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	return !c.sealed && !c.standby
+}
+
+// standbyReady returns whether the core is an unsealed standby
+func (c *Core) standbyReady() bool {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	return !c.sealed && c.standby && c.ha != nil
+}
+
+// ForwardingReady returns whether the forwarding connection of the core to
+// the active node is established, i.e. whether the current forwarding client
+// successfully exchanged a heartbeat with it
+func (c *Core) ForwardingReady() bool {
+	c.requestForwardingConnectionLock.RLock()
+	defer c.requestForwardingConnectionLock.RUnlock()
+	return c.rpcForwardingConnected
+}
+
+// forwardingConnected records that the forwarding client exchanged a
+// heartbeat with the active node, if it is still the current client
+func (c *Core) forwardingConnected(client *forwardingClient) {
+	c.requestForwardingConnectionLock.Lock()
+	defer c.requestForwardingConnectionLock.Unlock()
+	if c.rpcForwardingClient != client || c.rpcForwardingConnected {
+		return
+	}
+	c.rpcForwardingConnected = true
+	c.stateEvents.notify()
+}
@@ -0,0 +1,68 @@
+package vault
+
+import (
+	"github.com/armon/go-metrics"
+)
+
+// StatusSnapshot is the state of the core as reported by the health and seal
+// status endpoints
+type StatusSnapshot struct {
+	Sealed  bool
+	Standby bool
+
+	// Progress and Nonce describe the unseal keys provided so far
+	Progress int
+	Nonce    string
+
+	// ClusterName and ClusterID are only set while unsealed
+	ClusterName string
+	ClusterID   string
+}
+
+// StatusSnapshot returns the most recent status of the core. It is updated
+// whenever the status changes and read without taking the state lock, so
+// that frequent health checks never contend with unsealing or stepping down.
+func (c *Core) StatusSnapshot() StatusSnapshot {
+	return *c.statusSnapshot.Load().(*StatusSnapshot)
+}
+
+// updateStatusSnapshot records the current status of the core and wakes up
+// the waiters for changes of state. This must be called with the state lock
+// held.
+func (c *Core) updateStatusSnapshot() {
+	snapshot := &StatusSnapshot{
+		Sealed:  c.sealed,
+		Standby: c.standby,
+	}
+	if c.unlockInfo != nil {
+		snapshot.Progress = len(c.unlockInfo.Parts)
+		snapshot.Nonce = c.unlockInfo.Nonce
+	}
+
+	if !c.sealed {
+		cluster, err := c.Cluster()
+		if err != nil {
+			c.logger.Error("core: failed to fetch cluster details for status", "error", err)
+		} else if cluster != nil {
+			snapshot.ClusterName = cluster.Name
+			snapshot.ClusterID = cluster.ID
+		}
+	}
+
+	c.statusSnapshot.Store(snapshot)
+	c.stateEvents.notify()
+	emitStatusMetrics(snapshot)
+}
+
+// emitStatusMetrics sets the gauges of the seal and HA status of the core
+func emitStatusMetrics(snapshot *StatusSnapshot) {
+	var unsealed, active float32
+	if !snapshot.Sealed {
+		unsealed = 1
+		if !snapshot.Standby {
+			active = 1
+		}
+	}
+	metrics.SetGauge([]string{"core", "unsealed"}, unsealed)
+	metrics.SetGauge([]string{"core", "active"}, active)
+}
@@ -0,0 +1,59 @@
+package vault
+
+import (
+	"testing"
+)
+
+func TestCore_StatusSnapshot(t *testing.T) {
+	c := TestCore(t)
+	if status := c.StatusSnapshot(); !status.Sealed || !status.Standby {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	res, err := c.Initialize(&InitParams{
+		BarrierConfig: &SealConfig{
+			SecretShares:    3,
+			SecretThreshold: 2,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := TestCoreUnseal(c, TestKeyCopy(res.SecretShares[0])); err != nil {
+		t.Fatal(err)
+	}
+	status := c.StatusSnapshot()
+	if !status.Sealed || status.Progress != 1 || status.Nonce == "" {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	c.ResetUnsealProcess()
+	if status := c.StatusSnapshot(); status.Progress != 0 || status.Nonce != "" {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(res.SecretShares[i])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	status = c.StatusSnapshot()
+	if status.Sealed || status.Standby || status.Progress != 0 || status.ClusterID == "" {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	// The status is available while the state lock is held
+	c.stateLock.Lock()
+	if status := c.StatusSnapshot(); status.Sealed {
+		t.Fatalf("bad: %#v", status)
+	}
+	c.stateLock.Unlock()
+
+	if err := c.Seal(res.RootToken); err != nil {
+		t.Fatal(err)
+	}
+	if status := c.StatusSnapshot(); !status.Sealed || !status.Standby || status.ClusterID != "" {
+		t.Fatalf("bad: %#v", status)
+	}
+}
@@ -9,11 +9,13 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -87,10 +89,11 @@ func TestCoreNewSeal(t testing.TB) *Core {
 // TestCoreWithSeal returns a pure in-memory, uninitialized core with the
 // specified seal for testing.
 func TestCoreWithSeal(t testing.TB, testSeal Seal) *Core {
-	logger := logformat.NewVaultLogger(log.LevelTrace)
+	logger, logMonitors := logformat.NewMonitoredVaultLogger(os.Stdout, log.LevelTrace)
 	physicalBackend := physical.NewInmem(logger)
 
 	conf := testCoreConfig(t, physicalBackend, logger)
+	conf.LogMonitors = logMonitors
 
 	if testSeal != nil {
 		conf.Seal = testSeal
@@ -231,6 +234,123 @@ func TestCoreUnsealedBackend(t testing.TB, backend physical.Backend) (*Core, [][
 	return core, keys, token
 }
 
+// TestCoreUnsealedWithClock returns a pure in-memory core that is already
+// initialized and unsealed, whose leases and tokens expire according to the
+// given clock.
+func TestCoreUnsealedWithClock(t testing.TB, clock Clock) (*Core, [][]byte, string) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	conf := testCoreConfig(t, physical.NewInmem(logger), logger)
+	conf.Clock = clock
+
+	core, err := NewCore(conf)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	keys, token := TestCoreInit(t, core)
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(core, TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+
+	sealed, err := core.Sealed()
+	if err != nil {
+		t.Fatalf("err checking seal status: %s", err)
+	}
+	if sealed {
+		t.Fatal("should not be sealed")
+	}
+
+	return core, keys, token
+}
+
+// TestClock is a Clock whose time only passes when Advance is called, so
+// that tests of expiration do not have to sleep
+type TestClock struct {
+	sync.Mutex
+	now    time.Time
+	timers map[*testTimer]struct{}
+}
+
+// NewTestClock returns a TestClock set at the given time
+func NewTestClock(now time.Time) *TestClock {
+	return &TestClock{
+		now:    now,
+		timers: make(map[*testTimer]struct{}),
+	}
+}
+
+func (c *TestClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *TestClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.Lock()
+	defer c.Unlock()
+	t := &testTimer{
+		clock: c,
+		when:  c.now.Add(d),
+		f:     f,
+	}
+	c.timers[t] = struct{}{}
+	return t
+}
+
+// Advance moves the time forward by the given duration. The functions of the
+// timers firing in the meantime are called in order before it returns.
+func (c *TestClock) Advance(d time.Duration) {
+	c.Lock()
+	end := c.now.Add(d)
+	c.Unlock()
+
+	for {
+		c.Lock()
+		var next *testTimer
+		for t := range c.timers {
+			if !t.when.After(end) && (next == nil || t.when.Before(next.when)) {
+				next = t
+			}
+		}
+		if next == nil {
+			c.now = end
+			c.Unlock()
+			return
+		}
+		delete(c.timers, next)
+		c.now = next.when
+		c.Unlock()
+
+		next.f()
+	}
+}
+
+// testTimer is a Timer of a TestClock
+type testTimer struct {
+	clock *TestClock
+	when  time.Time
+	f     func()
+}
+
+func (t *testTimer) Stop() bool {
+	t.clock.Lock()
+	defer t.clock.Unlock()
+	_, active := t.clock.timers[t]
+	delete(t.clock.timers, t)
+	return active
+}
+
+func (t *testTimer) Reset(d time.Duration) bool {
+	t.clock.Lock()
+	defer t.clock.Unlock()
+	_, active := t.clock.timers[t]
+	t.when = t.clock.now.Add(d)
+	t.clock.timers[t] = struct{}{}
+	return active
+}
+
 func testTokenStore(t testing.TB, c *Core) *TokenStore {
 	me := &MountEntry{
 		Table:       credentialTableType,
@@ -263,7 +383,7 @@ func testTokenStore(t testing.TB, c *Core) *TokenStore {
 	subview := c.systemBarrierView.SubView(expirationSubPath)
 	logger := logformat.NewVaultLogger(log.LevelTrace)
 
-	exp := NewExpirationManager(router, subview, ts, logger)
+	exp := NewExpirationManager(router, subview, ts, logger, c.clock, c.entropy)
 	ts.SetExpirationManager(exp)
 
 	return ts
@@ -329,7 +449,7 @@ func TestAddTestPlugin(t testing.TB, c *Core, name, testFunc string) {
 	c.pluginCatalog.directory = filepath.Dir(c.pluginCatalog.directory)
 
 	command := fmt.Sprintf("%s --test.run=%s", filepath.Base(os.Args[0]), testFunc)
-	err = c.pluginCatalog.Set(name, command, sum)
+	err = c.pluginCatalog.Set(name, command, sum, PluginLimits{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -572,37 +692,80 @@ func GenerateRandBytes(length int) ([]byte, error) {
 }
 
 func TestWaitActive(t testing.TB, core *Core) {
-	start := time.Now()
-	var standby bool
-	var err error
-	for time.Now().Sub(start) < time.Second {
-		standby, err = core.Standby()
-		if err != nil {
-			t.Fatalf("err: %v", err)
+	if !testWaitState(core, core.activeReady) {
+		t.Fatalf("should not be in standby mode")
+	}
+}
+
+// TestWaitStandby waits for the core to be an unsealed standby
+func TestWaitStandby(t testing.TB, core *Core) {
+	if !testWaitState(core, core.standbyReady) {
+		t.Fatalf("should be in standby mode")
+	}
+}
+
+// TestWaitForwarding waits for the forwarding connection of the standby to
+// the active node to be established
+func TestWaitForwarding(t testing.TB, core *Core) {
+	if !testWaitState(core, core.ForwardingReady) {
+		t.Fatalf("should be connected to the active node")
+	}
+}
+
+// testWaitStateTimeout is how long the test helpers wait for the state of a
+// core to change
+const testWaitStateTimeout = 10 * time.Second
+
+// testWaitState blocks until the given state of the core is reached,
+// returning false if it is not before testWaitStateTimeout
+func testWaitState(core *Core, reached func() bool) bool {
+	timeout := time.After(testWaitStateTimeout)
+	for {
+		changed := core.StateChanged()
+		if reached() {
+			return true
 		}
-		if !standby {
-			break
+		select {
+		case <-changed:
+		case <-timeout:
+			return false
 		}
 	}
-	if standby {
-		t.Fatalf("should not be in standby mode")
-	}
 }
 
 type TestCluster struct {
 	Cores []*TestClusterCore
+
+	// serveWg tracks the goroutines serving the listeners
+	serveWg sync.WaitGroup
+
+	// socketDir holds the Unix domain sockets of the cores
+	socketDir string
 }
 
 func (t *TestCluster) StartListeners() {
 	for _, core := range t.Cores {
 		if core.Server != nil {
 			for _, ln := range core.Listeners {
-				go core.Server.Serve(ln)
+				t.serveWg.Add(1)
+				go func(server *http.Server, ln *TestListener) {
+					defer t.serveWg.Done()
+					server.Serve(ln)
+				}(core.Server, ln)
+			}
+			if core.SocketListener != nil {
+				t.serveWg.Add(1)
+				go func(server *http.Server, ln net.Listener) {
+					defer t.serveWg.Done()
+					server.Serve(ln)
+				}(core.Server, core.SocketListener)
 			}
 		}
 	}
 }
 
+// CloseListeners closes the listeners of the cores and waits for them to
+// stop serving
 func (t *TestCluster) CloseListeners() {
 	for _, core := range t.Cores {
 		if core.Listeners != nil {
@@ -610,9 +773,106 @@ func (t *TestCluster) CloseListeners() {
 				ln.Close()
 			}
 		}
+		if core.SocketListener != nil {
+			core.SocketListener.Close()
+		}
+	}
+	t.serveWg.Wait()
+}
+
+// Cleanup shuts down the cores, releasing their HA lock and stopping their
+// background goroutines and cluster listeners, and then closes the
+// listeners. Standbys are shut down first so that they do not take over when
+// the active core steps down.
+func (t *TestCluster) Cleanup() {
+	var active []*TestClusterCore
+	for _, core := range t.Cores {
+		if standby, _ := core.Standby(); !standby {
+			active = append(active, core)
+			continue
+		}
+		if err := core.Shutdown(); err != nil {
+			core.logger.Error("testing: failed to shut down core", "error", err)
+		}
+	}
+	for _, core := range active {
+		if err := core.Shutdown(); err != nil {
+			core.logger.Error("testing: failed to shut down core", "error", err)
+		}
+	}
+
+	t.CloseListeners()
+	if t.socketDir != "" {
+		os.RemoveAll(t.socketDir)
+	}
+}
+
+// WaitForActiveNode waits for one of the cores of the cluster to be active
+// and returns it
+func (c *TestCluster) WaitForActiveNode(t testing.TB) *TestClusterCore {
+	timeout := time.After(testWaitStateTimeout)
+	for {
+		// Wait for a change of the state of any of the cores, or the timeout
+		cases := make([]reflect.SelectCase, 0, len(c.Cores)+1)
+		for _, core := range c.Cores {
+			cases = append(cases, reflect.SelectCase{
+				Dir:  reflect.SelectRecv,
+				Chan: reflect.ValueOf(core.StateChanged()),
+			})
+		}
+		cases = append(cases, reflect.SelectCase{
+			Dir:  reflect.SelectRecv,
+			Chan: reflect.ValueOf(timeout),
+		})
+
+		for _, core := range c.Cores {
+			if core.activeReady() {
+				return core
+			}
+		}
+		if chosen, _, _ := reflect.Select(cases); chosen == len(c.Cores) {
+			t.Fatal("no core became active")
+		}
+	}
+}
+
+// WaitForStandby waits for the given core of the cluster to be an unsealed
+// standby and for its forwarding connection to the active node to be
+// established
+func (c *TestCluster) WaitForStandby(t testing.TB, core *TestClusterCore) {
+	TestWaitStandby(t, core.Core)
+	TestWaitForwarding(t, core.Core)
+}
+
+// UnsealCore unseals the given core of the cluster with its barrier keys
+func (c *TestCluster) UnsealCore(t testing.TB, core *TestClusterCore) {
+	for _, key := range core.BarrierKeys {
+		if _, err := core.Core.Unseal(TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+	if sealed, err := core.Sealed(); err != nil {
+		t.Fatalf("err checking seal status: %s", err)
+	} else if sealed {
+		t.Fatal("should not be sealed")
+	}
+}
+
+// EnsureCoresSealed seals the cores of the cluster which are unsealed.
+// Standbys are sealed first so that they do not take over when the active
+// core is sealed.
+func (c *TestCluster) EnsureCoresSealed(t testing.TB) {
+	var active []*TestClusterCore
+	for _, core := range c.Cores {
+		if standby, _ := core.Standby(); !standby {
+			active = append(active, core)
+			continue
+		}
+		core.SealForTest(t)
+	}
+	for _, core := range active {
+		core.SealForTest(t)
 	}
-	// Give time to actually shut down/clean up before the next test
-	time.Sleep(time.Second)
 }
 
 type TestListener struct {
@@ -632,67 +892,123 @@ type TestClusterCore struct {
 	TLSConfig   *tls.Config
 	ClusterID   string
 	Client      *api.Client
+
+	// SocketPath is the Unix domain socket the API is also served on over
+	// plain HTTP when the cluster is created with UnixSockets
+	SocketPath     string
+	SocketListener net.Listener
 }
 
-func NewTestCluster(t testing.TB, base *CoreConfig, unsealStandbys bool) *TestCluster {
-	//
-	// TLS setup
-	//
-	block, _ := pem.Decode([]byte(TestClusterCACert))
-	if block == nil {
-		t.Fatal("error decoding cluster CA cert")
+// SealForTest seals the core, whether it is active or a standby, without a
+// token. Unlike Core.Seal it needs no root token and also seals standbys.
+func (c *TestClusterCore) SealForTest(t testing.TB) {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+	if c.sealed {
+		return
 	}
-	caBytes := block.Bytes
-	caCert, err := x509.ParseCertificate(caBytes)
-	if err != nil {
-		t.Fatal(err)
+	if err := c.sealInternal(); err != nil {
+		t.Fatalf("seal err: %s", err)
 	}
+}
 
-	serverCert, err := tls.X509KeyPair([]byte(TestClusterServerCert), []byte(TestClusterServerKey))
-	if err != nil {
-		t.Fatal(err)
-	}
+// MountBackend mounts a logical backend of the given type at the given path
+// through sys/mounts, with the given mount configuration such as
+// default_lease_ttl, which may be nil. The core must be active.
+func (c *TestClusterCore) MountBackend(t testing.TB, path, backendType string, config map[string]interface{}) {
+	c.testSysRequest(t, "mounts/"+path, map[string]interface{}{
+		"type":   backendType,
+		"config": config,
+	})
+}
 
-	rootCAs := x509.NewCertPool()
-	rootCAs.AppendCertsFromPEM([]byte(TestClusterCACert))
-	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{serverCert},
-		RootCAs:      rootCAs,
-		ClientCAs:    rootCAs,
-		ClientAuth:   tls.VerifyClientCertIfGiven,
+// MountAuthBackend enables a credential backend of the given type at the
+// given path through sys/auth, and tunes it with the given configuration,
+// which may be nil. The core must be active.
+func (c *TestClusterCore) MountAuthBackend(t testing.TB, path, backendType string, config map[string]interface{}) {
+	c.testSysRequest(t, "auth/"+path, map[string]interface{}{
+		"type": backendType,
+	})
+	if len(config) > 0 {
+		c.testSysRequest(t, "auth/"+path+"/tune", config)
 	}
-	tlsConfig.BuildNameToCertificate()
+}
 
-	// Sanity checking
-	block, _ = pem.Decode([]byte(TestClusterServerCert))
-	if block == nil {
-		t.Fatal(err)
+// testSysRequest performs an update of the given system path with the root
+// token
+func (c *TestClusterCore) testSysRequest(t testing.TB, path string, data map[string]interface{}) {
+	req := &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "sys/" + path,
+		Data:        data,
+		ClientToken: c.Root,
 	}
-	parsedServerCert, err := x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		t.Fatal(err)
+	resp, err := c.HandleRequest(req)
+	if err == nil && resp.IsError() {
+		err = resp.Error()
 	}
-	chains, err := parsedServerCert.Verify(x509.VerifyOptions{
-		DNSName:   "127.0.0.1",
-		Roots:     rootCAs,
-		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-	})
 	if err != nil {
-		t.Fatal(err)
-	}
-	if chains == nil || len(chains) == 0 {
-		t.Fatal("no verified chains for server auth")
+		t.Fatalf("error updating sys/%s: %v", path, err)
 	}
-	chains, err = parsedServerCert.Verify(x509.VerifyOptions{
-		DNSName:   "127.0.0.1",
-		Roots:     rootCAs,
-		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+}
+
+// TestClusterOptions changes the setup of a test cluster
+type TestClusterOptions struct {
+	// UnsealStandbys unseals the second and third cores, which then become
+	// standbys of the first
+	UnsealStandbys bool
+
+	// DisableTLS serves the API over plain HTTP rather than HTTPS, for
+	// tests putting proxies or load balancers in front of the cluster
+	DisableTLS bool
+
+	// CACert, ServerCert and ServerKey are the PEM encoded TLS material the
+	// listeners use instead of the generated test certificates. The server
+	// certificate must be signed by the CA certificate, which the API
+	// clients of the cores trust.
+	CACert     []byte
+	ServerCert []byte
+	ServerKey  []byte
+
+	// PerCoreConfig is called with the index and the config of each core
+	// before the core is created, so that tests can customize some of the
+	// nodes, e.g. give one a different seal or cache size. Each core gets
+	// its own copy of the config.
+	PerCoreConfig func(i int, config *CoreConfig)
+
+	// PhysicalFactory is called with the index of each core to create its
+	// own physical backend and, if it returns one, HA backend, so that
+	// tests can run the storage and HA configurations used in production,
+	// e.g. a file backend per core over a shared directory along with a
+	// shared HA backend. It takes precedence over the physical backend of
+	// the base config; cores for which it returns no HA backend share the
+	// HA backend of the base config, or an inmem one.
+	PhysicalFactory func(i int, logger log.Logger) (physical.Backend, physical.HABackend, error)
+
+	// UnixSockets also serves the API of each core over plain HTTP on a Unix
+	// domain socket, whose path is the SocketPath of the core
+	UnixSockets bool
+}
+
+func NewTestCluster(t testing.TB, base *CoreConfig, unsealStandbys bool) *TestCluster {
+	return NewTestClusterWithOptions(t, base, &TestClusterOptions{
+		UnsealStandbys: unsealStandbys,
 	})
-	if err != nil {
-		t.Fatal(err)
+}
+
+func NewTestClusterWithOptions(t testing.TB, base *CoreConfig, opts *TestClusterOptions) *TestCluster {
+	if opts == nil {
+		opts = &TestClusterOptions{}
 	}
-	if chains == nil || len(chains) == 0 {
-		t.Fatal("no verified chains for chains auth")
+
+	scheme := "https"
+	var caBytes []byte
+	var caCert *x509.Certificate
+	var tlsConfig *tls.Config
+	if opts.DisableTLS {
+		scheme = "http"
+	} else {
+		caBytes, caCert, tlsConfig = testClusterTLSSetup(t, opts)
 	}
 
 	logger := logformat.NewVaultLogger(log.LevelTrace)
@@ -708,7 +1024,7 @@ func NewTestCluster(t testing.TB, base *CoreConfig, unsealStandbys bool) *TestCl
 		t.Fatal(err)
 	}
 	c1lns := []*TestListener{&TestListener{
-		Listener: tls.NewListener(ln, tlsConfig),
+		Listener: testClusterListener(ln, tlsConfig),
 		Address:  ln.Addr().(*net.TCPAddr),
 	},
 	}
@@ -720,7 +1036,7 @@ func NewTestCluster(t testing.TB, base *CoreConfig, unsealStandbys bool) *TestCl
 		t.Fatal(err)
 	}
 	c1lns = append(c1lns, &TestListener{
-		Listener: tls.NewListener(ln, tlsConfig),
+		Listener: testClusterListener(ln, tlsConfig),
 		Address:  ln.Addr().(*net.TCPAddr),
 	})
 	handler1 := http.NewServeMux()
@@ -739,7 +1055,7 @@ func NewTestCluster(t testing.TB, base *CoreConfig, unsealStandbys bool) *TestCl
 		t.Fatal(err)
 	}
 	c2lns := []*TestListener{&TestListener{
-		Listener: tls.NewListener(ln, tlsConfig),
+		Listener: testClusterListener(ln, tlsConfig),
 		Address:  ln.Addr().(*net.TCPAddr),
 	},
 	}
@@ -759,7 +1075,7 @@ func NewTestCluster(t testing.TB, base *CoreConfig, unsealStandbys bool) *TestCl
 		t.Fatal(err)
 	}
 	c3lns := []*TestListener{&TestListener{
-		Listener: tls.NewListener(ln, tlsConfig),
+		Listener: testClusterListener(ln, tlsConfig),
 		Address:  ln.Addr().(*net.TCPAddr),
 	},
 	}
@@ -781,7 +1097,7 @@ func NewTestCluster(t testing.TB, base *CoreConfig, unsealStandbys bool) *TestCl
 		LogicalBackends:    make(map[string]logical.Factory),
 		CredentialBackends: make(map[string]logical.Factory),
 		AuditBackends:      make(map[string]audit.Factory),
-		RedirectAddr:       fmt.Sprintf("https://127.0.0.1:%d", c1lns[0].Address.Port),
+		RedirectAddr:       fmt.Sprintf("%s://127.0.0.1:%d", scheme, c1lns[0].Address.Port),
 		ClusterAddr:        fmt.Sprintf("https://127.0.0.1:%d", c1lns[0].Address.Port+100),
 		DisableMlock:       true,
 	}
@@ -822,6 +1138,9 @@ func NewTestCluster(t testing.TB, base *CoreConfig, unsealStandbys bool) *TestCl
 		if base.Logger != nil {
 			coreConfig.Logger = base.Logger
 		}
+
+		coreConfig.Clock = base.Clock
+		coreConfig.Entropy = base.Entropy
 	}
 
 	if coreConfig.Physical == nil {
@@ -831,28 +1150,37 @@ func NewTestCluster(t testing.TB, base *CoreConfig, unsealStandbys bool) *TestCl
 		coreConfig.HAPhysical = physical.NewInmemHA(logger)
 	}
 
-	c1, err := NewCore(coreConfig)
-	if err != nil {
-		t.Fatalf("err: %v", err)
-	}
-
-	coreConfig.RedirectAddr = fmt.Sprintf("https://127.0.0.1:%d", c2lns[0].Address.Port)
-	if coreConfig.ClusterAddr != "" {
-		coreConfig.ClusterAddr = fmt.Sprintf("https://127.0.0.1:%d", c2lns[0].Address.Port+100)
-	}
-	c2, err := NewCore(coreConfig)
-	if err != nil {
-		t.Fatalf("err: %v", err)
-	}
-
-	coreConfig.RedirectAddr = fmt.Sprintf("https://127.0.0.1:%d", c3lns[0].Address.Port)
-	if coreConfig.ClusterAddr != "" {
-		coreConfig.ClusterAddr = fmt.Sprintf("https://127.0.0.1:%d", c3lns[0].Address.Port+100)
-	}
-	c3, err := NewCore(coreConfig)
-	if err != nil {
-		t.Fatalf("err: %v", err)
+	newCore := func(i int, lns []*TestListener) *Core {
+		// The addresses of the first core are those of the shared config
+		config := coreConfig.clone()
+		if opts.PhysicalFactory != nil {
+			phys, ha, err := opts.PhysicalFactory(i, logger)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			config.Physical = phys
+			if ha != nil {
+				config.HAPhysical = ha
+			}
+		}
+		if i > 0 {
+			config.RedirectAddr = fmt.Sprintf("%s://127.0.0.1:%d", scheme, lns[0].Address.Port)
+			if config.ClusterAddr != "" {
+				config.ClusterAddr = fmt.Sprintf("https://127.0.0.1:%d", lns[0].Address.Port+100)
+			}
+		}
+		if opts.PerCoreConfig != nil {
+			opts.PerCoreConfig(i, config)
+		}
+		core, err := NewCore(config)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return core
 	}
+	c1 := newCore(0, c1lns)
+	c2 := newCore(1, c2lns)
+	c3 := newCore(2, c3lns)
 
 	//
 	// Clustering setup
@@ -890,7 +1218,7 @@ func NewTestCluster(t testing.TB, base *CoreConfig, unsealStandbys bool) *TestCl
 
 	TestWaitActive(t, c1)
 
-	if unsealStandbys {
+	if opts.UnsealStandbys {
 		for _, key := range keys {
 			if _, err := c2.Unseal(TestKeyCopy(key)); err != nil {
 				t.Fatalf("unseal err: %s", err)
@@ -903,7 +1231,8 @@ func NewTestCluster(t testing.TB, base *CoreConfig, unsealStandbys bool) *TestCl
 		}
 
 		// Let them come fully up to standby
-		time.Sleep(2 * time.Second)
+		TestWaitStandby(t, c2)
+		TestWaitStandby(t, c3)
 
 		// Ensure cluster connection info is populated
 		isLeader, _, err := c2.Leader()
@@ -929,7 +1258,9 @@ func NewTestCluster(t testing.TB, base *CoreConfig, unsealStandbys bool) *TestCl
 
 	getAPIClient := func(port int) *api.Client {
 		transport := cleanhttp.DefaultPooledTransport()
-		transport.TLSClientConfig = tlsConfig
+		if tlsConfig != nil {
+			transport.TLSClientConfig = tlsConfig
+		}
 		client := &http.Client{
 			Transport: transport,
 			CheckRedirect: func(*http.Request, []*http.Request) error {
@@ -938,7 +1269,7 @@ func NewTestCluster(t testing.TB, base *CoreConfig, unsealStandbys bool) *TestCl
 			},
 		}
 		config := api.DefaultConfig()
-		config.Address = fmt.Sprintf("https://127.0.0.1:%d", port)
+		config.Address = fmt.Sprintf("%s://127.0.0.1:%d", scheme, port)
 		config.HttpClient = client
 		apiClient, err := api.NewClient(config)
 		if err != nil {
@@ -994,7 +1325,129 @@ func NewTestCluster(t testing.TB, base *CoreConfig, unsealStandbys bool) *TestCl
 		Client:      getAPIClient(c3lns[0].Address.Port),
 	})
 
-	return &TestCluster{Cores: ret}
+	testCluster := &TestCluster{Cores: ret}
+	if opts.UnixSockets {
+		dir, err := ioutil.TempDir("", "vault-test-sockets")
+		if err != nil {
+			t.Fatal(err)
+		}
+		testCluster.socketDir = dir
+		for i, core := range ret {
+			core.SocketPath = filepath.Join(dir, fmt.Sprintf("core%d.sock", i))
+			core.SocketListener, err = net.Listen("unix", core.SocketPath)
+			if err != nil {
+				os.RemoveAll(dir)
+				t.Fatal(err)
+			}
+		}
+	}
+
+	return testCluster
+}
+
+// testClusterTLSSetup parses the TLS material of the test cluster, and
+// returns the DER encoded CA certificate, the parsed CA certificate, and the
+// TLS configuration shared by the listeners and the API clients
+// clone returns a copy of the config, with copies of the backend maps so
+// that the backends of a core can be changed without affecting the others.
+// Physical backends and seals are shared.
+func (c *CoreConfig) clone() *CoreConfig {
+	config := *c
+	config.LogicalBackends = make(map[string]logical.Factory, len(c.LogicalBackends))
+	for k, v := range c.LogicalBackends {
+		config.LogicalBackends[k] = v
+	}
+	config.CredentialBackends = make(map[string]logical.Factory, len(c.CredentialBackends))
+	for k, v := range c.CredentialBackends {
+		config.CredentialBackends[k] = v
+	}
+	config.AuditBackends = make(map[string]audit.Factory, len(c.AuditBackends))
+	for k, v := range c.AuditBackends {
+		config.AuditBackends[k] = v
+	}
+	config.AuditSecretDetectors = append([]audit.SecretDetector(nil), c.AuditSecretDetectors...)
+	return &config
+}
+
+func testClusterTLSSetup(t testing.TB, opts *TestClusterOptions) ([]byte, *x509.Certificate, *tls.Config) {
+	caPEM := []byte(TestClusterCACert)
+	certPEM := []byte(TestClusterServerCert)
+	keyPEM := []byte(TestClusterServerKey)
+	external := opts.CACert != nil || opts.ServerCert != nil || opts.ServerKey != nil
+	if external {
+		caPEM, certPEM, keyPEM = opts.CACert, opts.ServerCert, opts.ServerKey
+	}
+
+	block, _ := pem.Decode(caPEM)
+	if block == nil {
+		t.Fatal("error decoding cluster CA cert")
+	}
+	caBytes := block.Bytes
+	caCert, err := x509.ParseCertificate(caBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(caCert)
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		RootCAs:      rootCAs,
+		ClientCAs:    rootCAs,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+	}
+	tlsConfig.BuildNameToCertificate()
+
+	// Sanity checking of the generated material; externally provided
+	// material may be issued for the names of a proxy rather than 127.0.0.1
+	if external {
+		return caBytes, caCert, tlsConfig
+	}
+	block, _ = pem.Decode(certPEM)
+	if block == nil {
+		t.Fatal("error decoding cluster server cert")
+	}
+	parsedServerCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chains, err := parsedServerCert.Verify(x509.VerifyOptions{
+		DNSName:   "127.0.0.1",
+		Roots:     rootCAs,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chains == nil || len(chains) == 0 {
+		t.Fatal("no verified chains for server auth")
+	}
+	chains, err = parsedServerCert.Verify(x509.VerifyOptions{
+		DNSName:   "127.0.0.1",
+		Roots:     rootCAs,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chains == nil || len(chains) == 0 {
+		t.Fatal("no verified chains for chains auth")
+	}
+
+	return caBytes, caCert, tlsConfig
+}
+
+// testClusterListener wraps the listener with TLS, unless TLS is disabled
+func testClusterListener(ln net.Listener, tlsConfig *tls.Config) net.Listener {
+	if tlsConfig == nil {
+		return ln
+	}
+	return tls.NewListener(ln, tlsConfig)
 }
 
 const (
@@ -0,0 +1,331 @@
+package vault
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// testExternalWaitTimeout bounds the waits for the nodes of an external test
+// cluster to start, unseal and become active
+const testExternalWaitTimeout = 30 * time.Second
+
+// TestExternalClusterOptions configures a test cluster whose nodes are vault
+// server processes, e.g. to run a previous release against some storage and
+// then upgrade the nodes to the binary under test
+type TestExternalClusterOptions struct {
+	// BinaryPath is the path of the vault binary the nodes run
+	BinaryPath string
+
+	// NumNodes is the number of nodes, 1 by default. Running more than one
+	// node requires a storage backend supporting HA.
+	NumNodes int
+
+	// Storage is the storage stanza of the configuration of the nodes. By
+	// default the nodes use a file backend in the directory of the cluster.
+	// It uses the "backend" keyword, which all releases accept.
+	Storage string
+
+	// Dir holds the configurations, logs and default storage of the nodes.
+	// A temporary directory is created, and removed on cleanup, if not set.
+	Dir string
+
+	// Env is added to the environment of the nodes
+	Env []string
+
+	// Command, if set, returns the command running a node with the given
+	// binary and arguments, e.g. to run it in a container. The command's
+	// output is redirected to the log of the node.
+	Command func(i int, binaryPath string, args []string) *exec.Cmd
+}
+
+// TestExternalCluster is a cluster of vault server processes, initialized
+// with a single unseal key
+type TestExternalCluster struct {
+	Nodes []*TestExternalNode
+
+	// Root is the root token and Keys the base64 unseal keys
+	Root string
+	Keys []string
+
+	opts       *TestExternalClusterOptions
+	removeDir  bool
+	binaryPath string
+}
+
+// TestExternalNode is a node of a TestExternalCluster
+type TestExternalNode struct {
+	// Addr is the API address of the node, and Client an API client for it
+	// authenticated with the root token
+	Addr   string
+	Client *api.Client
+
+	ConfigPath string
+	LogPath    string
+
+	cmd    *exec.Cmd
+	exited chan struct{}
+}
+
+// NewTestExternalCluster starts the nodes, initializes the cluster through
+// the first node, unseals every node and waits for one to become active
+func NewTestExternalCluster(t testing.TB, opts *TestExternalClusterOptions) *TestExternalCluster {
+	if opts == nil || opts.BinaryPath == "" {
+		t.Fatal("a vault binary is required")
+	}
+	if opts.NumNodes == 0 {
+		opts.NumNodes = 1
+	}
+
+	c := &TestExternalCluster{
+		opts:       opts,
+		binaryPath: opts.BinaryPath,
+	}
+	if opts.Dir == "" {
+		dir, err := ioutil.TempDir("", "vault-external-cluster")
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.Dir = dir
+		c.removeDir = true
+	}
+	storage := opts.Storage
+	if storage == "" {
+		storage = fmt.Sprintf("backend \"file\" {\n  path = %q\n}\n", filepath.Join(opts.Dir, "storage"))
+	}
+
+	for i := 0; i < opts.NumNodes; i++ {
+		port, clusterPort := testExternalFreePort(t), testExternalFreePort(t)
+		node := &TestExternalNode{
+			Addr:       fmt.Sprintf("http://127.0.0.1:%d", port),
+			ConfigPath: filepath.Join(opts.Dir, fmt.Sprintf("node%d.hcl", i)),
+			LogPath:    filepath.Join(opts.Dir, fmt.Sprintf("node%d.log", i)),
+		}
+		config := fmt.Sprintf(`%s
+listener "tcp" {
+  address = "127.0.0.1:%d"
+  cluster_address = "127.0.0.1:%d"
+  tls_disable = 1
+}
+
+disable_mlock = true
+`, storage, port, clusterPort)
+		if err := ioutil.WriteFile(node.ConfigPath, []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		client, err := api.NewClient(&api.Config{Address: node.Addr})
+		if err != nil {
+			t.Fatal(err)
+		}
+		node.Client = client
+		c.Nodes = append(c.Nodes, node)
+	}
+
+	for i := range c.Nodes {
+		c.StartNode(t, i, "")
+	}
+
+	init, err := c.Nodes[0].Client.Sys().Init(&api.InitRequest{
+		SecretShares:    1,
+		SecretThreshold: 1,
+	})
+	if err != nil {
+		c.Cleanup()
+		t.Fatalf("init err: %v", err)
+	}
+	c.Root = init.RootToken
+	c.Keys = init.KeysB64
+	for _, node := range c.Nodes {
+		node.Client.SetToken(c.Root)
+	}
+
+	for i := range c.Nodes {
+		c.UnsealNode(t, i)
+	}
+	c.WaitForActiveNode(t)
+	return c
+}
+
+// StartNode starts the node with the given binary, or the last binary the
+// cluster ran if empty, and waits for it to serve requests. The node is
+// left sealed.
+func (c *TestExternalCluster) StartNode(t testing.TB, i int, binaryPath string) {
+	if binaryPath != "" {
+		c.binaryPath = binaryPath
+	}
+	node := c.Nodes[i]
+
+	logFile, err := os.OpenFile(node.LogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logFile.Close()
+
+	args := []string{"server", "-config=" + node.ConfigPath, "-log-level=trace"}
+	var cmd *exec.Cmd
+	if c.opts.Command != nil {
+		cmd = c.opts.Command(i, c.binaryPath, args)
+	} else {
+		cmd = exec.Command(c.binaryPath, args...)
+	}
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.Env = append(os.Environ(), "VAULT_REDIRECT_ADDR="+node.Addr)
+	cmd.Env = append(cmd.Env, c.opts.Env...)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start node %d: %v", i, err)
+	}
+	node.cmd = cmd
+	node.exited = make(chan struct{})
+	go func(exited chan struct{}) {
+		cmd.Wait()
+		close(exited)
+	}(node.exited)
+
+	c.waitNode(t, i, "serve requests", func() (bool, error) {
+		_, err := node.Client.Sys().InitStatus()
+		return err == nil, nil
+	})
+}
+
+// StopNode interrupts the node and waits for it to exit, killing it if it
+// does not shut down in time
+func (c *TestExternalCluster) StopNode(t testing.TB, i int) {
+	node := c.Nodes[i]
+	if node.cmd == nil {
+		return
+	}
+	node.cmd.Process.Signal(os.Interrupt)
+	select {
+	case <-node.exited:
+	case <-time.After(testExternalWaitTimeout):
+		node.cmd.Process.Kill()
+		<-node.exited
+	}
+	node.cmd = nil
+}
+
+// UnsealNode unseals the node with the keys of the cluster
+func (c *TestExternalCluster) UnsealNode(t testing.TB, i int) {
+	node := c.Nodes[i]
+	for _, key := range c.Keys {
+		if _, err := node.Client.Sys().Unseal(key); err != nil {
+			t.Fatalf("unseal err on node %d: %v", i, err)
+		}
+	}
+	c.waitNode(t, i, "unseal", func() (bool, error) {
+		status, err := node.Client.Sys().SealStatus()
+		if err != nil {
+			return false, err
+		}
+		return !status.Sealed, nil
+	})
+}
+
+// Upgrade restarts the nodes one at a time with the given binary, unsealing
+// each, and waits for one of them to become active. Standbys are upgraded
+// before the active node, as in a rolling upgrade.
+func (c *TestExternalCluster) Upgrade(t testing.TB, binaryPath string) {
+	active := c.WaitForActiveNode(t)
+	order := make([]int, 0, len(c.Nodes))
+	for i, node := range c.Nodes {
+		if node != active {
+			order = append(order, i)
+		}
+	}
+	for i, node := range c.Nodes {
+		if node == active {
+			order = append(order, i)
+		}
+	}
+
+	for _, i := range order {
+		c.StopNode(t, i)
+		c.StartNode(t, i, binaryPath)
+		c.UnsealNode(t, i)
+	}
+	c.WaitForActiveNode(t)
+}
+
+// WaitForActiveNode waits for a node to become active and returns it
+func (c *TestExternalCluster) WaitForActiveNode(t testing.TB) *TestExternalNode {
+	deadline := time.Now().Add(testExternalWaitTimeout)
+	for time.Now().Before(deadline) {
+		for _, node := range c.Nodes {
+			if node.cmd == nil {
+				continue
+			}
+			leader, err := node.Client.Sys().Leader()
+			if err != nil {
+				continue
+			}
+			if !leader.HAEnabled || leader.IsSelf {
+				status, err := node.Client.Sys().SealStatus()
+				if err == nil && !status.Sealed {
+					return node
+				}
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatal("no node became active")
+	return nil
+}
+
+// Cleanup stops the nodes and removes the directory of the cluster if it
+// was created by NewTestExternalCluster
+func (c *TestExternalCluster) Cleanup() {
+	for _, node := range c.Nodes {
+		if node.cmd == nil {
+			continue
+		}
+		node.cmd.Process.Kill()
+		<-node.exited
+		node.cmd = nil
+	}
+	if c.removeDir {
+		os.RemoveAll(c.opts.Dir)
+	}
+}
+
+// waitNode polls the node until the condition holds, failing if the node
+// exits or the condition does not hold in time
+func (c *TestExternalCluster) waitNode(t testing.TB, i int, what string, cond func() (bool, error)) {
+	node := c.Nodes[i]
+	deadline := time.Now().Add(testExternalWaitTimeout)
+	var lastErr error
+	for time.Now().Before(deadline) {
+		select {
+		case <-node.exited:
+			logs, _ := ioutil.ReadFile(node.LogPath)
+			t.Fatalf("node %d exited while waiting for it to %s:\n%s", i, what, logs)
+		default:
+		}
+
+		ok, err := cond()
+		if ok {
+			return
+		}
+		lastErr = err
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("node %d did not %s in time: %v", i, what, lastErr)
+}
+
+// testExternalFreePort returns a port that is free at the time of the call
+func testExternalFreePort(t testing.TB) int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
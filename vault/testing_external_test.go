@@ -0,0 +1,39 @@
+package vault
+
+import (
+	"os"
+	"testing"
+)
+
+// TestExternalCluster_Upgrade runs the binary in VAULT_TEST_BINARY, writes a
+// secret and upgrades the node to the binary in VAULT_TEST_UPGRADE_BINARY,
+// or restarts it with the same binary if not set, on the same storage
+func TestExternalCluster_Upgrade(t *testing.T) {
+	binary := os.Getenv("VAULT_TEST_BINARY")
+	if binary == "" {
+		t.Skip("VAULT_TEST_BINARY must be set to run external cluster tests")
+	}
+	upgrade := os.Getenv("VAULT_TEST_UPGRADE_BINARY")
+
+	cluster := NewTestExternalCluster(t, &TestExternalClusterOptions{
+		BinaryPath: binary,
+	})
+	defer cluster.Cleanup()
+
+	client := cluster.WaitForActiveNode(t).Client
+	if _, err := client.Logical().Write("secret/foo", map[string]interface{}{
+		"value": "bar",
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	cluster.Upgrade(t, upgrade)
+
+	secret, err := cluster.WaitForActiveNode(t).Client.Logical().Read("secret/foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if secret == nil || secret.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", secret)
+	}
+}
@@ -0,0 +1,237 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// entityPrefix is the prefix used to index all tokens of an entity
+	entityPrefix = "entity/"
+
+	// aliasPrefix is the prefix used to index the tokens an entity obtained
+	// by logging in
+	aliasPrefix = "alias/"
+)
+
+// ErrTokenLimitReached is returned when creating a token would exceed the
+// number of active tokens allowed for its entity
+var ErrTokenLimitReached = errors.New("maximum number of active tokens reached")
+
+// TokenLimitsConfig caps the number of simultaneously active tokens of the
+// login identities, or entities, that tokens are issued to. An entity is the
+// auth mount and display name returned by its backend, such as
+// "userpass/bob"; tokens created by a token count against the entity of
+// their parent. Zero means unlimited.
+type TokenLimitsConfig struct {
+	// MaxTokensPerEntity caps all active tokens of an entity, including
+	// the child tokens created with its tokens
+	MaxTokensPerEntity int `json:"max_tokens_per_entity"`
+
+	// MaxTokensPerAlias caps the active tokens an entity obtained by logging
+	// in
+	MaxTokensPerAlias int `json:"max_tokens_per_alias"`
+
+	// Overrides replaces both limits for the given entities
+	Overrides map[string]int `json:"overrides,omitempty"`
+}
+
+// limits returns the per-entity and per-alias limits of the entity
+func (c *TokenLimitsConfig) limits(entity string) (int, int) {
+	if max, ok := c.Overrides[entity]; ok {
+		return max, max
+	}
+	return c.MaxTokensPerEntity, c.MaxTokensPerAlias
+}
+
+// loginEntity returns the entity of a token issued by logging in at the
+// given mount, or an empty string if the backend returned no display name
+func loginEntity(mount, displayName string) string {
+	if displayName == "" {
+		return ""
+	}
+	return strings.TrimPrefix(mount, credentialRoutePrefix) + displayName
+}
+
+// tokenLimitsConfig returns the current token limits
+func (c *Core) tokenLimitsConfig() *TokenLimitsConfig {
+	if config, ok := c.tokenLimits.Load().(*TokenLimitsConfig); ok {
+		return config
+	}
+	return &TokenLimitsConfig{}
+}
+
+// setTokenLimitsConfig persists the token limits and applies them
+func (c *Core) setTokenLimitsConfig(config *TokenLimitsConfig) error {
+	view := c.systemBarrierView.SubView("config/")
+
+	entry, err := logical.StorageEntryJSON("token-limits", config)
+	if err != nil {
+		return fmt.Errorf("failed to create token limits entry: %v", err)
+	}
+	if err := view.Put(entry); err != nil {
+		return fmt.Errorf("failed to save token limits: %v", err)
+	}
+
+	c.tokenLimits.Store(config)
+	return nil
+}
+
+// This should only be called with the core state lock held for writing
+func (c *Core) loadTokenLimitsConfig() error {
+	view := c.systemBarrierView.SubView("config/")
+
+	out, err := view.Get("token-limits")
+	if err != nil {
+		return fmt.Errorf("failed to read token limits: %v", err)
+	}
+
+	config := &TokenLimitsConfig{}
+	if out != nil {
+		if err := out.DecodeJSON(config); err != nil {
+			return err
+		}
+	}
+
+	c.tokenLimits.Store(config)
+	return nil
+}
+
+// isLoginToken returns whether the token was issued by logging in rather
+// than created by another token
+func isLoginToken(entry *TokenEntry) bool {
+	return !strings.HasPrefix(entry.Path, "auth/token/")
+}
+
+// entityIndexPaths returns the paths of the entity and alias indexes of the
+// token, or nothing if the token has no entity
+func (ts *TokenStore) entityIndexPaths(entry *TokenEntry, saltedID string) ([]string, error) {
+	if entry.Entity == "" {
+		return nil, nil
+	}
+
+	saltedEntity, err := ts.SaltID(entry.Entity)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{entityPrefix + saltedEntity + "/" + saltedID}
+	if isLoginToken(entry) {
+		paths = append(paths, aliasPrefix+saltedEntity+"/"+saltedID)
+	}
+	return paths, nil
+}
+
+// checkTokenLimits returns ErrTokenLimitReached if the entity of the token
+// already has as many active tokens as it is allowed. The entity lock must
+// be held.
+func (ts *TokenStore) checkTokenLimits(entry *TokenEntry) error {
+	if entry.Entity == "" || ts.tokenLimitsFunc == nil {
+		return nil
+	}
+
+	maxEntity, maxAlias := ts.tokenLimitsFunc().limits(entry.Entity)
+	if maxEntity <= 0 && (maxAlias <= 0 || !isLoginToken(entry)) {
+		return nil
+	}
+
+	saltedEntity, err := ts.SaltID(entry.Entity)
+	if err != nil {
+		return err
+	}
+
+	if maxEntity > 0 {
+		tokens, err := ts.view.List(entityPrefix + saltedEntity + "/")
+		if err != nil {
+			return fmt.Errorf("failed to read entity index: %v", err)
+		}
+		if len(tokens) >= maxEntity {
+			return ErrTokenLimitReached
+		}
+	}
+
+	if maxAlias > 0 && isLoginToken(entry) {
+		tokens, err := ts.view.List(aliasPrefix + saltedEntity + "/")
+		if err != nil {
+			return fmt.Errorf("failed to read alias index: %v", err)
+		}
+		if len(tokens) >= maxAlias {
+			return ErrTokenLimitReached
+		}
+	}
+
+	return nil
+}
+
+// writeEntityIndexes adds the token to the indexes of its entity
+func (ts *TokenStore) writeEntityIndexes(entry *TokenEntry, saltedID string) error {
+	paths, err := ts.entityIndexPaths(entry, saltedID)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := ts.view.Put(&logical.StorageEntry{Key: path}); err != nil {
+			return fmt.Errorf("failed to persist entity index: %v", err)
+		}
+	}
+	return nil
+}
+
+// deleteEntityIndexes removes the token from the indexes of its entity
+func (ts *TokenStore) deleteEntityIndexes(entry *TokenEntry, saltedID string) error {
+	paths, err := ts.entityIndexPaths(entry, saltedID)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := ts.view.Delete(path); err != nil {
+			return fmt.Errorf("failed to delete entity index: %v", err)
+		}
+	}
+	return nil
+}
+
+// tidyEntityIndexes removes the entries of the entity and alias indexes
+// that refer to tokens that no longer exist, returning how many were
+// scanned and deleted
+func (ts *TokenStore) tidyEntityIndexes() (int64, int64, error) {
+	var tidyErrors *multierror.Error
+	var scanned, deleted int64
+
+	for _, prefix := range []string{entityPrefix, aliasPrefix} {
+		entities, err := ts.view.List(prefix)
+		if err != nil {
+			return scanned, deleted, fmt.Errorf("failed to fetch entity index entries: %v", err)
+		}
+
+		for _, entity := range entities {
+			tokens, err := ts.view.List(prefix + entity)
+			if err != nil {
+				tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to read entity index: %v", err))
+				continue
+			}
+
+			for _, saltedID := range tokens {
+				scanned++
+				te, _ := ts.lookupSalted(saltedID, true)
+				if te != nil {
+					continue
+				}
+
+				index := prefix + entity + saltedID
+				ts.logger.Trace("token: deleting invalid entity index", "index", index)
+				if err := ts.view.Delete(index); err != nil {
+					tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to delete entity index: %v", err))
+					continue
+				}
+				deleted++
+			}
+		}
+	}
+
+	return scanned, deleted, tidyErrors.ErrorOrNil()
+}
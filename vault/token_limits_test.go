@@ -0,0 +1,238 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// testCoreTokenLimits returns an unsealed core with a noop credential
+// backend mounted at auth/foo, and a policy "foo" allowing to create tokens
+func testCoreTokenLimits(t *testing.T) (*Core, *NoopBackend, string) {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+	}
+	c, _, root := TestCoreUnsealed(t)
+	c.credentialBackends["noop"] = func(conf *logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/auth/foo")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	policy, err := Parse(`
+name = "foo"
+path "auth/token/create" {
+	capabilities = ["update"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.policyStore.SetPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	return c, noop, root
+}
+
+// testLoginTokenLimits logs in as "armon"
+func testLoginTokenLimits(t *testing.T, c *Core, noop *NoopBackend) (string, error) {
+	// The response is modified by the core, so each login needs its own
+	noop.Lock()
+	noop.Response = &logical.Response{
+		Auth: &logical.Auth{
+			Policies:    []string{"foo"},
+			DisplayName: "armon",
+		},
+	}
+	noop.Unlock()
+
+	resp, err := c.HandleRequest(&logical.Request{
+		Path: "auth/foo/login",
+	})
+	if err != nil {
+		if resp != nil && resp.IsError() {
+			return "", resp.Error()
+		}
+		return "", err
+	}
+	if resp == nil || resp.Auth == nil || resp.Auth.ClientToken == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	return resp.Auth.ClientToken, nil
+}
+
+func testCreateTokenLimits(t *testing.T, c *Core, parent string) (string, error) {
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+	req.ClientToken = parent
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		if resp != nil && resp.IsError() {
+			return "", resp.Error()
+		}
+		return "", err
+	}
+	if resp == nil || resp.Auth == nil || resp.Auth.ClientToken == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	return resp.Auth.ClientToken, nil
+}
+
+func testTokenLimitReached(t *testing.T, err error) {
+	if err == nil || !strings.Contains(err.Error(), ErrTokenLimitReached.Error()) {
+		t.Fatalf("expected limit to be reached, got: %v", err)
+	}
+}
+
+func TestTokenLimits_Alias(t *testing.T) {
+	c, noop, _ := testCoreTokenLimits(t)
+
+	if err := c.setTokenLimitsConfig(&TokenLimitsConfig{MaxTokensPerAlias: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := testLoginTokenLimits(t, c, noop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	te, err := c.tokenStore.Lookup(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if te.Entity != "foo/armon" {
+		t.Fatalf("bad: %#v", te)
+	}
+
+	if _, err := testLoginTokenLimits(t, c, noop); err != nil {
+		t.Fatal(err)
+	}
+	_, err = testLoginTokenLimits(t, c, noop)
+	testTokenLimitReached(t, err)
+
+	// Child tokens do not count against the limit per alias
+	if _, err := testCreateTokenLimits(t, c, first); err != nil {
+		t.Fatal(err)
+	}
+
+	// Revoking a token frees its place
+	if err := c.tokenStore.Revoke(first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testLoginTokenLimits(t, c, noop); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTokenLimits_Entity(t *testing.T) {
+	c, noop, _ := testCoreTokenLimits(t)
+
+	if err := c.setTokenLimitsConfig(&TokenLimitsConfig{MaxTokensPerEntity: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	login, err := testLoginTokenLimits(t, c, noop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := testCreateTokenLimits(t, c, login)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Children of children inherit the entity as well
+	if _, err := testCreateTokenLimits(t, c, child); err != nil {
+		t.Fatal(err)
+	}
+	_, err = testCreateTokenLimits(t, c, child)
+	testTokenLimitReached(t, err)
+	_, err = testLoginTokenLimits(t, c, noop)
+	testTokenLimitReached(t, err)
+
+	// Revoking the tree frees all the places
+	if err := c.tokenStore.RevokeTree(login); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testLoginTokenLimits(t, c, noop); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTokenLimits_Overrides(t *testing.T) {
+	c, noop, root := testCoreTokenLimits(t)
+
+	config := &TokenLimitsConfig{
+		MaxTokensPerEntity: 1,
+		MaxTokensPerAlias:  1,
+		Overrides: map[string]int{
+			"foo/armon": 0,
+		},
+	}
+	if err := c.setTokenLimitsConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := testLoginTokenLimits(t, c, noop); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Tokens without an entity, such as those of root, are never limited
+	for i := 0; i < 3; i++ {
+		if _, err := testCreateTokenLimits(t, c, root); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTokenStore_TidyEntityIndexes(t *testing.T) {
+	c, noop, _ := testCoreTokenLimits(t)
+	ts := c.tokenStore
+
+	token, err := testLoginTokenLimits(t, c, noop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	te, err := ts.Lookup(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Leave the indexes of the token behind as if revocation had failed
+	saltedID, err := ts.SaltID(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.view.Delete(lookupPrefix + saltedID); err != nil {
+		t.Fatal(err)
+	}
+	paths, err := ts.entityIndexPaths(te, saltedID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("bad: %#v", paths)
+	}
+
+	scanned, deleted, err := ts.tidyEntityIndexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scanned != 2 || deleted != 2 {
+		t.Fatalf("bad: scanned: %d, deleted: %d", scanned, deleted)
+	}
+	for _, path := range paths {
+		entry, err := ts.view.Get(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry != nil {
+			t.Fatalf("index %q was not deleted", path)
+		}
+	}
+}
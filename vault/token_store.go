@@ -1,12 +1,13 @@
 package vault
 
 import (
-	"encoding/json"
 	"fmt"
+	"io"
 	"sync"
 	"sync/atomic"
 
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -14,14 +15,16 @@ import (
 
 	"github.com/armon/go-metrics"
 	"github.com/hashicorp/go-multierror"
-	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/cidrutil"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/helper/locksutil"
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/hashicorp/vault/helper/policyutil"
 	"github.com/hashicorp/vault/helper/salt"
+	"github.com/hashicorp/vault/helper/schedule"
 	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/helper/tokenformat"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 	"github.com/mitchellh/mapstructure"
@@ -36,6 +39,14 @@ const (
 	// Accessor to Token ID
 	accessorPrefix = "accessor/"
 
+	// accessorShardLength is the number of leading characters of a salted
+	// accessor used to shard the accessor index
+	accessorShardLength = 2
+
+	// accessorScanWorkers is the number of accessor index shards listed in
+	// parallel
+	accessorScanWorkers = 16
+
 	// parentPrefix is the prefix used to store tokens for their
 	// secondar parent based index
 	parentPrefix = "parent/"
@@ -94,10 +105,23 @@ type TokenStore struct {
 
 	tokenLocks []*locksutil.LockEntry
 
+	entityLocks []*locksutil.LockEntry
+
+	tokenLimitsFunc func() *TokenLimitsConfig
+
 	cubbyholeDestroyer func(*TokenStore, string) error
 
 	logger log.Logger
 
+	// clock gives the time of creation of tokens and drives their renewals
+	clock Clock
+
+	// entropy is used to generate the IDs and accessors of tokens
+	entropy io.Reader
+
+	// tokenFormat is the format of the IDs of new tokens
+	tokenFormat string
+
 	saltLock   sync.RWMutex
 	salt       *salt.Salt
 	saltConfig *salt.Config
@@ -116,7 +140,12 @@ func NewTokenStore(c *Core, config *logical.BackendConfig) (*TokenStore, error)
 		view:               view,
 		cubbyholeDestroyer: destroyCubbyhole,
 		logger:             c.logger,
+		clock:              c.clock,
+		entropy:            c.entropy,
+		tokenFormat:        c.tokenFormat,
 		tokenLocks:         locksutil.CreateLocks(),
+		entityLocks:        locksutil.CreateLocks(),
+		tokenLimitsFunc:    c.tokenLimitsConfig,
 		saltLock:           sync.RWMutex{},
 	}
 
@@ -140,6 +169,8 @@ func NewTokenStore(c *Core, config *logical.BackendConfig) (*TokenStore, error)
 				lookupPrefix,
 				accessorPrefix,
 				parentPrefix,
+				entityPrefix,
+				aliasPrefix,
 				salt.DefaultLocation,
 			},
 		},
@@ -216,6 +247,16 @@ func NewTokenStore(c *Core, config *logical.BackendConfig) (*TokenStore, error)
 						Default:     true,
 						Description: tokenRenewableHelp,
 					},
+
+					"allowed_windows": &framework.FieldSchema{
+						Type:        framework.TypeStringSlice,
+						Description: tokenAllowedWindowsHelp,
+					},
+
+					"token_bound_cidrs": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: tokenBoundCIDRsHelp,
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -357,6 +398,24 @@ func NewTokenStore(c *Core, config *logical.BackendConfig) (*TokenStore, error)
 				HelpDescription: strings.TrimSpace(tokenRevokeAccessorHelp),
 			},
 
+			&framework.Path{
+				Pattern: "revoke-accessors$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"accessors": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: "Accessors of the tokens to revoke",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: t.handleUpdateRevokeAccessors,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(tokenRevokeAccessorsHelp),
+				HelpDescription: strings.TrimSpace(tokenRevokeAccessorsDesc),
+			},
+
 			&framework.Path{
 				Pattern: "revoke-self$",
 
@@ -576,6 +635,14 @@ type TokenEntry struct {
 	// backends are subject to those renewal rules.
 	Period time.Duration `json:"period" mapstructure:"period" structs:"period"`
 
+	// The login identity the token counts against for token limits, such as
+	// "userpass/bob". Child tokens inherit the entity of their parent.
+	Entity string `json:"entity" mapstructure:"entity" structs:"entity"`
+
+	// If set, the token can only be used from client addresses within these
+	// CIDR blocks
+	BoundCIDRs []string `json:"bound_cidrs" mapstructure:"bound_cidrs" structs:"bound_cidrs"`
+
 	// These are the deprecated fields
 	DisplayNameDeprecated    string        `json:"DisplayName" mapstructure:"DisplayName" structs:"DisplayName"`
 	NumUsesDeprecated        int           `json:"NumUses" mapstructure:"NumUses" structs:"NumUses"`
@@ -583,6 +650,46 @@ type TokenEntry struct {
 	ExplicitMaxTTLDeprecated time.Duration `json:"ExplicitMaxTTL" mapstructure:"ExplicitMaxTTL" structs:"ExplicitMaxTTL"`
 }
 
+// upgradeDeprecatedFields moves the values of the deprecated fields to the
+// current ones, returning whether any were set
+func (te *TokenEntry) upgradeDeprecatedFields() bool {
+	upgraded := false
+
+	if te.DisplayNameDeprecated != "" {
+		if te.DisplayName == "" {
+			te.DisplayName = te.DisplayNameDeprecated
+		}
+		te.DisplayNameDeprecated = ""
+		upgraded = true
+	}
+
+	if te.CreationTimeDeprecated != 0 {
+		if te.CreationTime == 0 {
+			te.CreationTime = te.CreationTimeDeprecated
+		}
+		te.CreationTimeDeprecated = 0
+		upgraded = true
+	}
+
+	if te.ExplicitMaxTTLDeprecated != 0 {
+		if te.ExplicitMaxTTL == 0 {
+			te.ExplicitMaxTTL = te.ExplicitMaxTTLDeprecated
+		}
+		te.ExplicitMaxTTLDeprecated = 0
+		upgraded = true
+	}
+
+	if te.NumUsesDeprecated != 0 {
+		if te.NumUses == 0 || te.NumUsesDeprecated < te.NumUses {
+			te.NumUses = te.NumUsesDeprecated
+		}
+		te.NumUsesDeprecated = 0
+		upgraded = true
+	}
+
+	return upgraded
+}
+
 // tsRoleEntry contains token store role information
 type tsRoleEntry struct {
 	// The name of the role. Embedded so it can be used for pathing
@@ -612,6 +719,14 @@ type tsRoleEntry struct {
 	// If set, the token entry will have an explicit maximum TTL set, rather
 	// than deferring to role/mount values
 	ExplicitMaxTTL time.Duration `json:"explicit_max_ttl" mapstructure:"explicit_max_ttl" structs:"explicit_max_ttl"`
+
+	// If set, tokens can only be created using this role in these windows
+	// of time
+	AllowedWindows []string `json:"allowed_windows" mapstructure:"allowed_windows" structs:"allowed_windows"`
+
+	// If set, tokens created using this role can only be used from client
+	// addresses within these CIDR blocks
+	BoundCIDRs []string `json:"bound_cidrs" mapstructure:"bound_cidrs" structs:"bound_cidrs"`
 }
 
 type accessorEntry struct {
@@ -638,11 +753,18 @@ func (ts *TokenStore) SaltID(id string) (string, error) {
 
 // RootToken is used to generate a new token with root privileges and no parent
 func (ts *TokenStore) rootToken() (*TokenEntry, error) {
+	return ts.rootTokenWithID("")
+}
+
+// rootTokenWithID creates a root token with the given ID, or a generated one
+// if empty
+func (ts *TokenStore) rootTokenWithID(id string) (*TokenEntry, error) {
 	te := &TokenEntry{
+		ID:           id,
 		Policies:     []string{"root"},
 		Path:         "auth/token/root",
 		DisplayName:  "root",
-		CreationTime: time.Now().Unix(),
+		CreationTime: ts.clock.Now().Unix(),
 	}
 	if err := ts.create(te); err != nil {
 		return nil, err
@@ -652,7 +774,7 @@ func (ts *TokenStore) rootToken() (*TokenEntry, error) {
 
 func (ts *TokenStore) tokenStoreAccessorList(
 	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	entries, err := ts.view.List(accessorPrefix)
+	entries, err := ts.listSaltedAccessors()
 	if err != nil {
 		return nil, err
 	}
@@ -685,7 +807,7 @@ func (ts *TokenStore) createAccessor(entry *TokenEntry) error {
 	defer metrics.MeasureSince([]string{"token", "createAccessor"}, time.Now())
 
 	// Create a random accessor
-	accessorUUID, err := uuid.GenerateUUID()
+	accessorUUID, err := generateUUID(ts.entropy)
 	if err != nil {
 		return err
 	}
@@ -696,7 +818,7 @@ func (ts *TokenStore) createAccessor(entry *TokenEntry) error {
 	if err != nil {
 		return err
 	}
-	path := accessorPrefix + saltID
+	path := accessorIndexPath(saltID)
 
 	aEntry := &accessorEntry{
 		TokenID:    entry.ID,
@@ -714,17 +836,22 @@ func (ts *TokenStore) createAccessor(entry *TokenEntry) error {
 	return nil
 }
 
+// generateID returns a new token ID in the configured format
+func (ts *TokenStore) generateID() (string, error) {
+	return tokenformat.Generate(ts.tokenFormat, ts.entropy)
+}
+
 // Create is used to create a new token entry. The entry is assigned
 // a newly generated ID if not provided.
 func (ts *TokenStore) create(entry *TokenEntry) error {
 	defer metrics.MeasureSince([]string{"token", "create"}, time.Now())
 	// Generate an ID if necessary
 	if entry.ID == "" {
-		entryUUID, err := uuid.GenerateUUID()
+		id, err := ts.generateID()
 		if err != nil {
 			return err
 		}
-		entry.ID = entryUUID
+		entry.ID = id
 	}
 
 	saltedId, err := ts.SaltID(entry.ID)
@@ -738,12 +865,33 @@ func (ts *TokenStore) create(entry *TokenEntry) error {
 
 	entry.Policies = policyutil.SanitizePolicies(entry.Policies, policyutil.DoNotAddDefaultPolicy)
 
+	// Counting and indexing the tokens of the entity must not interleave
+	// with the creation of its other tokens, or the limits could be exceeded.
+	// The indexes are written first so a failure cannot leave a token that
+	// escapes them.
+	if entry.Entity != "" {
+		lock := locksutil.LockForKey(ts.entityLocks, entry.Entity)
+		lock.Lock()
+		defer lock.Unlock()
+
+		if err := ts.checkTokenLimits(entry); err != nil {
+			return err
+		}
+		if err := ts.writeEntityIndexes(entry, saltedId); err != nil {
+			return err
+		}
+	}
+
 	err = ts.createAccessor(entry)
 	if err != nil {
 		return err
 	}
 
-	return ts.storeCommon(entry, true)
+	if err := ts.storeCommon(entry, true); err != nil {
+		return err
+	}
+	metrics.IncrCounter([]string{"token", "creation"}, 1)
+	return nil
 }
 
 // Store is used to store an updated token entry without writing the
@@ -761,8 +909,9 @@ func (ts *TokenStore) storeCommon(entry *TokenEntry, writeSecondary bool) error
 		return err
 	}
 
-	// Marshal the entry
-	enc, err := json.Marshal(entry)
+	// Marshal the entry; the storage format has no deprecated fields
+	entry.upgradeDeprecatedFields()
+	enc, err := encodeTokenEntry(entry)
 	if err != nil {
 		return fmt.Errorf("failed to encode entry: %v", err)
 	}
@@ -908,8 +1057,8 @@ func (ts *TokenStore) lookupSalted(saltedId string, tainted bool) (*TokenEntry,
 	}
 
 	// Unmarshal the token
-	entry := new(TokenEntry)
-	if err := jsonutil.DecodeJSON(raw.Value, entry); err != nil {
+	entry, legacy, err := decodeTokenEntry(raw.Value)
+	if err != nil {
 		return nil, fmt.Errorf("failed to decode entry: %v", err)
 	}
 
@@ -918,38 +1067,11 @@ func (ts *TokenStore) lookupSalted(saltedId string, tainted bool) (*TokenEntry,
 		return nil, nil
 	}
 
-	persistNeeded := false
+	// Migrate entries stored as JSON
+	persistNeeded := legacy
 
 	// Upgrade the deprecated fields
-	if entry.DisplayNameDeprecated != "" {
-		if entry.DisplayName == "" {
-			entry.DisplayName = entry.DisplayNameDeprecated
-		}
-		entry.DisplayNameDeprecated = ""
-		persistNeeded = true
-	}
-
-	if entry.CreationTimeDeprecated != 0 {
-		if entry.CreationTime == 0 {
-			entry.CreationTime = entry.CreationTimeDeprecated
-		}
-		entry.CreationTimeDeprecated = 0
-		persistNeeded = true
-	}
-
-	if entry.ExplicitMaxTTLDeprecated != 0 {
-		if entry.ExplicitMaxTTL == 0 {
-			entry.ExplicitMaxTTL = entry.ExplicitMaxTTLDeprecated
-		}
-		entry.ExplicitMaxTTLDeprecated = 0
-		persistNeeded = true
-	}
-
-	if entry.NumUsesDeprecated != 0 {
-		if entry.NumUses == 0 || entry.NumUsesDeprecated < entry.NumUses {
-			entry.NumUses = entry.NumUsesDeprecated
-		}
-		entry.NumUsesDeprecated = 0
+	if entry.upgradeDeprecatedFields() {
 		persistNeeded = true
 	}
 
@@ -1076,6 +1198,11 @@ func (ts *TokenStore) revokeSalted(saltedId string) (ret error) {
 		}
 	}
 
+	// Clear the entity indexes if any
+	if err = ts.deleteEntityIndexes(entry, saltedId); err != nil {
+		return err
+	}
+
 	// Clear the accessor index if any
 	if entry.Accessor != "" {
 		accessorSaltedID, err := ts.SaltID(entry.Accessor)
@@ -1083,8 +1210,7 @@ func (ts *TokenStore) revokeSalted(saltedId string) (ret error) {
 			return err
 		}
 
-		path := accessorPrefix + accessorSaltedID
-		if err = ts.view.Delete(path); err != nil {
+		if err = ts.deleteAccessorIndex(accessorSaltedID); err != nil {
 			return fmt.Errorf("failed to delete entry: %v", err)
 		}
 	}
@@ -1146,6 +1272,66 @@ func (ts *TokenStore) revokeTreeSalted(saltedId string) error {
 	return nil
 }
 
+// moveChildren re-parents the child tokens of a token under another token,
+// so that they are revoked along with it rather than with the original token
+func (ts *TokenStore) moveChildren(from, to string) error {
+	fromSaltedID, err := ts.SaltID(from)
+	if err != nil {
+		return err
+	}
+	toSaltedID, err := ts.SaltID(to)
+	if err != nil {
+		return err
+	}
+
+	children, err := ts.view.List(parentPrefix + fromSaltedID + "/")
+	if err != nil {
+		return fmt.Errorf("failed to scan for children: %v", err)
+	}
+	for _, child := range children {
+		if err := ts.moveChild(child, fromSaltedID, toSaltedID, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveChild re-parents the child token with the given salted ID. The index
+// of the new parent is written first, so that a failure never leaves the
+// child out of the revocation chain.
+func (ts *TokenStore) moveChild(saltedChild, fromSaltedID, toSaltedID, to string) error {
+	entry, err := ts.lookupSalted(saltedChild, true)
+	if err != nil {
+		return err
+	}
+	if entry != nil {
+		lock := locksutil.LockForKey(ts.tokenLocks, entry.ID)
+		lock.Lock()
+		defer lock.Unlock()
+
+		entry, err = ts.lookupSalted(saltedChild, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	if entry != nil {
+		le := &logical.StorageEntry{Key: parentPrefix + toSaltedID + "/" + saltedChild}
+		if err := ts.view.Put(le); err != nil {
+			return fmt.Errorf("failed to persist entry: %v", err)
+		}
+		entry.Parent = to
+		if err := ts.storeCommon(entry, false); err != nil {
+			return err
+		}
+	}
+
+	if err := ts.view.Delete(parentPrefix + fromSaltedID + "/" + saltedChild); err != nil {
+		return fmt.Errorf("failed to delete entry: %v", err)
+	}
+	return nil
+}
+
 // handleCreateAgainstRole handles the auth/token/create path for a role
 func (ts *TokenStore) handleCreateAgainstRole(
 	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
@@ -1158,6 +1344,15 @@ func (ts *TokenStore) handleCreateAgainstRole(
 		return logical.ErrorResponse(fmt.Sprintf("unknown role %s", name)), nil
 	}
 
+	windows, err := schedule.ParseWindows(roleEntry.AllowedWindows)
+	if err != nil {
+		return nil, err
+	}
+	if !windows.Allows(ts.clock.Now()) {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"role %s does not allow creating tokens at this time", name)), logical.ErrPermissionDenied
+	}
+
 	return ts.handleCreateCommon(req, d, false, roleEntry)
 }
 
@@ -1170,12 +1365,19 @@ func (ts *TokenStore) lookupByAccessor(accessor string, tainted bool) (accessorE
 }
 
 func (ts *TokenStore) lookupBySaltedAccessor(saltedAccessor string, tainted bool) (accessorEntry, error) {
-	entry, err := ts.view.Get(accessorPrefix + saltedAccessor)
+	entry, err := ts.view.Get(accessorIndexPath(saltedAccessor))
 	var aEntry accessorEntry
 
 	if err != nil {
 		return aEntry, fmt.Errorf("failed to read index using accessor: %s", err)
 	}
+	if entry == nil {
+		// Fall back to the flat index used before the index was sharded
+		entry, err = ts.view.Get(accessorPrefix + saltedAccessor)
+		if err != nil {
+			return aEntry, fmt.Errorf("failed to read index using accessor: %s", err)
+		}
+	}
 	if entry == nil {
 		return aEntry, &logical.StatusBadRequest{Err: "invalid accessor"}
 	}
@@ -1206,6 +1408,94 @@ func (ts *TokenStore) lookupBySaltedAccessor(saltedAccessor string, tainted bool
 	return aEntry, nil
 }
 
+// accessorIndexPath returns the storage path of the accessor index entry for
+// the salted accessor. Entries are sharded by the leading characters of the
+// salted accessor so that no single prefix has to hold every accessor.
+func accessorIndexPath(saltedAccessor string) string {
+	if len(saltedAccessor) <= accessorShardLength {
+		return accessorPrefix + saltedAccessor
+	}
+	return accessorPrefix + saltedAccessor[:accessorShardLength] + "/" + saltedAccessor
+}
+
+// deleteAccessorIndex removes the accessor index entry for the salted
+// accessor, including any entry left in the flat index used before sharding
+func (ts *TokenStore) deleteAccessorIndex(saltedAccessor string) error {
+	if err := ts.view.Delete(accessorIndexPath(saltedAccessor)); err != nil {
+		return err
+	}
+	return ts.view.Delete(accessorPrefix + saltedAccessor)
+}
+
+// listSaltedAccessors returns the salted accessors in the accessor index.
+// The shards are listed in parallel; entries still in the flat index used
+// before sharding are included as well.
+func (ts *TokenStore) listSaltedAccessors() ([]string, error) {
+	keys, err := ts.view.List(accessorPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var shards []string
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if strings.HasSuffix(key, "/") {
+			shards = append(shards, key)
+			continue
+		}
+		seen[key] = struct{}{}
+	}
+
+	var wg sync.WaitGroup
+	var l sync.Mutex
+	var scanErr *multierror.Error
+	shardCh := make(chan string)
+	for i := 0; i < accessorScanWorkers && i < len(shards); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for shard := range shardCh {
+				entries, err := ts.view.List(accessorPrefix + shard)
+
+				l.Lock()
+				if err != nil {
+					scanErr = multierror.Append(scanErr, fmt.Errorf("failed to list accessor index shard %q: %v", shard, err))
+				}
+				for _, entry := range entries {
+					seen[entry] = struct{}{}
+				}
+				l.Unlock()
+			}
+		}()
+	}
+	for _, shard := range shards {
+		shardCh <- shard
+	}
+	close(shardCh)
+	wg.Wait()
+
+	if err := scanErr.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+
+	saltedAccessors := make([]string, 0, len(seen))
+	for saltedAccessor := range seen {
+		saltedAccessors = append(saltedAccessors, saltedAccessor)
+	}
+	sort.Strings(saltedAccessors)
+	return saltedAccessors, nil
+}
+
+// scanSaltedAccessors calls cb with each salted accessor in the accessor
+// index, which is listed a page at a time. Scanning stops at the first error
+// returned by cb. Accessors still in the flat index used before sharding may
+// be passed twice.
+func (ts *TokenStore) scanSaltedAccessors(cb func(saltedAccessor string) error) error {
+	return scanPages(ts.view, accessorPrefix, func(key string) error {
+		return cb(key[strings.LastIndex(key, "/")+1:])
+	})
+}
+
 // handleTidy handles the cleaning up of leaked accessor storage entries and
 // cleaning up of leases that are associated to tokens that are expired.
 func (ts *TokenStore) handleTidy(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -1221,50 +1511,75 @@ func (ts *TokenStore) handleTidy(req *logical.Request, data *framework.FieldData
 	ts.logger.Info("token: beginning tidy operation on tokens")
 	defer ts.logger.Info("token: finished tidy operation on tokens")
 
-	// List out all the accessors
-	saltedAccessorList, err := ts.view.List(accessorPrefix)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch accessor index entries: %v", err)
-	}
-
-	// First, clean up secondary index entries that are no longer valid
-	parentList, err := ts.view.List(parentPrefix)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch secondary index entries: %v", err)
-	}
-
-	var countParentList, deletedCountParentList int64
-
-	// Scan through the secondary index entries; if there is an entry
-	// with the token's salt ID at the end, remove it
-	for _, parent := range parentList {
-		children, err := ts.view.List(parentPrefix + parent)
-		if err != nil {
-			tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to read secondary index: %v", err))
-			continue
-		}
-
-		for _, child := range children {
-			countParentList++
-			if countParentList%500 == 0 {
-				ts.logger.Info("token: checking validity of tokens in secondary index list", "progress", countParentList)
+	var countParentList, deletedCountParentList, deletedCountOrphanedParentList int64
+
+	// First, clean up secondary index entries that are no longer valid. The
+	// indexes are listed a page at a time, here and below, so that large
+	// indexes are not held in memory.
+	err := listPages(ts.view, parentPrefix, func(parentList []string) error {
+		for _, parent := range parentList {
+			// The children of a parent which no longer exists are not
+			// revoked along with it any more, e.g. after revoke-orphan, so
+			// its whole index is dangling
+			parentEntry, err := ts.lookupSalted(strings.TrimSuffix(parent, "/"), true)
+			if err != nil {
+				tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to lookup parent token: %v", err))
+				continue
 			}
-
-			// Look up tainted entries so we can be sure that if this isn't
-			// found, it doesn't exist. Doing the following without locking
-			// since appropriate locks cannot be held with salted token IDs.
-			te, _ := ts.lookupSalted(child, true)
-			if te == nil {
-				index := parentPrefix + parent + child
-				ts.logger.Trace("token: deleting invalid secondary index", "index", index)
-				err = ts.view.Delete(index)
-				if err != nil {
-					tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to delete secondary index: %v", err))
+			orphaned := parentEntry == nil
+
+			err = listPages(ts.view, parentPrefix+parent, func(children []string) error {
+				for _, child := range children {
+					countParentList++
+					if countParentList%500 == 0 {
+						ts.logger.Info("token: checking validity of tokens in secondary index list", "progress", countParentList)
+						metrics.SetGauge([]string{"token", "tidy", "parent_index", "scanned"}, float32(countParentList))
+					}
+
+					if orphaned {
+						index := parentPrefix + parent + child
+						ts.logger.Trace("token: deleting secondary index of invalid parent", "index", index)
+						if err := ts.view.Delete(index); err != nil {
+							tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to delete secondary index: %v", err))
+						}
+						deletedCountOrphanedParentList++
+						metrics.IncrCounter([]string{"token", "tidy", "parent_index", "orphaned_deleted"}, 1)
+						continue
+					}
+
+					// Look up tainted entries so we can be sure that if this isn't
+					// found, it doesn't exist. Doing the following without locking
+					// since appropriate locks cannot be held with salted token IDs.
+					te, _ := ts.lookupSalted(child, true)
+					if te == nil {
+						index := parentPrefix + parent + child
+						ts.logger.Trace("token: deleting invalid secondary index", "index", index)
+						if err := ts.view.Delete(index); err != nil {
+							tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to delete secondary index: %v", err))
+						}
+						deletedCountParentList++
+						metrics.IncrCounter([]string{"token", "tidy", "parent_index", "deleted"}, 1)
+					}
 				}
-				deletedCountParentList++
+				return nil
+			})
+			if err != nil {
+				tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to read secondary index: %v", err))
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch secondary index entries: %v", err)
+	}
+
+	// Clean up the entity indexes in the same way, so that stale entries do
+	// not count against the token limits
+	countEntityList, deletedCountEntityList, err := ts.tidyEntityIndexes()
+	if err != nil {
+		tidyErrors = multierror.Append(tidyErrors, err)
 	}
+	metrics.IncrCounter([]string{"token", "tidy", "entity_index", "deleted"}, float32(deletedCountEntityList))
 
 	var countAccessorList,
 		deletedCountAccessorEmptyToken,
@@ -1274,31 +1589,38 @@ func (ts *TokenStore) handleTidy(req *logical.Request, data *framework.FieldData
 	// For each of the accessor, see if the token ID associated with it is
 	// a valid one. If not, delete the leases associated with that token
 	// and delete the accessor as well.
-	for _, saltedAccessor := range saltedAccessorList {
+	err = ts.scanSaltedAccessors(func(saltedAccessor string) error {
 		countAccessorList++
 		if countAccessorList%500 == 0 {
 			ts.logger.Info("token: checking if accessors contain valid tokens", "progress", countAccessorList)
+			metrics.SetGauge([]string{"token", "tidy", "accessors", "scanned"}, float32(countAccessorList))
 		}
 
 		accessorEntry, err := ts.lookupBySaltedAccessor(saltedAccessor, true)
+		if _, ok := err.(*logical.StatusBadRequest); ok {
+			// The accessor was removed since it was listed, such as an
+			// entry of the flat index tidied along with its sharded entry
+			return nil
+		}
 		if err != nil {
 			tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to read the accessor index: %v", err))
-			continue
+			return nil
 		}
 
 		// A valid accessor storage entry should always have a token ID
 		// in it. If not, it is an invalid accessor entry and needs to
 		// be deleted.
 		if accessorEntry.TokenID == "" {
-			index := accessorPrefix + saltedAccessor
 			// If deletion of accessor fails, move on to the next
 			// item since this is just a best-effort operation
-			err = ts.view.Delete(index)
+			err = ts.deleteAccessorIndex(saltedAccessor)
 			if err != nil {
 				tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to delete the accessor index: %v", err))
-				continue
+				return nil
 			}
 			deletedCountAccessorEmptyToken++
+			metrics.IncrCounter([]string{"token", "tidy", "accessors", "deleted"}, 1)
+			return nil
 		}
 
 		lock := locksutil.LockForKey(ts.tokenLocks, accessorEntry.TokenID)
@@ -1310,13 +1632,13 @@ func (ts *TokenStore) handleTidy(req *logical.Request, data *framework.FieldData
 		if err != nil {
 			tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to read salt id: %v", err))
 			lock.RUnlock()
-			continue
+			return nil
 		}
 		te, err := ts.lookupSalted(saltedId, true)
 		if err != nil {
 			tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to lookup tainted ID: %v", err))
 			lock.RUnlock()
-			continue
+			return nil
 		}
 
 		lock.RUnlock()
@@ -1339,27 +1661,38 @@ func (ts *TokenStore) handleTidy(req *logical.Request, data *framework.FieldData
 			err := ts.expiration.RevokeByToken(tokenEntry)
 			if err != nil {
 				tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to revoke leases of expired token: %v", err))
-				continue
+				return nil
 			}
 			deletedCountInvalidTokenInAccessor++
-
-			index := accessorPrefix + saltedAccessor
+			metrics.IncrCounter([]string{"token", "tidy", "tokens", "revoked"}, 1)
 
 			// If deletion of accessor fails, move on to the next item since
 			// this is just a best-effort operation. We do this last so that on
 			// next run if something above failed we still have the accessor
 			// entry to try again.
-			err = ts.view.Delete(index)
+			err = ts.deleteAccessorIndex(saltedAccessor)
 			if err != nil {
 				tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to delete accessor entry: %v", err))
-				continue
+				return nil
 			}
 			deletedCountAccessorInvalidToken++
+			metrics.IncrCounter([]string{"token", "tidy", "accessors", "deleted"}, 1)
 		}
+		return nil
+	})
+	if err != nil {
+		tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to fetch accessor index entries: %v", err))
 	}
+	metrics.SetGauge([]string{"token", "tidy", "accessors", "total"}, float32(countAccessorList))
+
+	metrics.SetGauge([]string{"token", "tidy", "parent_index", "scanned"}, float32(countParentList))
+	metrics.SetGauge([]string{"token", "tidy", "accessors", "scanned"}, float32(countAccessorList))
 
 	ts.logger.Debug("token: number of tokens scanned in parent index list", "count", countParentList)
 	ts.logger.Debug("token: number of tokens revoked in parent index list", "count", deletedCountParentList)
+	ts.logger.Debug("token: number of entries deleted from parent index lists of invalid parents", "count", deletedCountOrphanedParentList)
+	ts.logger.Debug("token: number of tokens scanned in entity index lists", "count", countEntityList)
+	ts.logger.Debug("token: number of invalid entries deleted from entity index lists", "count", deletedCountEntityList)
 	ts.logger.Debug("token: number of accessors scanned", "count", countAccessorList)
 	ts.logger.Debug("token: number of deleted accessors which had empty tokens", "count", deletedCountAccessorEmptyToken)
 	ts.logger.Debug("token: number of revoked tokens which were invalid but present in accessors", "count", deletedCountInvalidTokenInAccessor)
@@ -1454,6 +1787,40 @@ func (ts *TokenStore) handleUpdateRevokeAccessor(req *logical.Request, data *fra
 	return nil, nil
 }
 
+// handleUpdateRevokeAccessors handles the auth/token/revoke-accessors path
+// for revoking the tokens, and their children, of a batch of accessors. The
+// revocation of each token is attempted even if others fail.
+func (ts *TokenStore) handleUpdateRevokeAccessors(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	accessors := strutil.RemoveDuplicates(data.Get("accessors").([]string), false)
+	if len(accessors) == 0 {
+		return logical.ErrorResponse("missing accessors"), logical.ErrInvalidRequest
+	}
+
+	revoked := make([]string, 0, len(accessors))
+	failed := make(map[string]interface{})
+	for _, accessor := range accessors {
+		aEntry, err := ts.lookupByAccessor(accessor, true)
+		if err != nil {
+			failed[accessor] = err.Error()
+			continue
+		}
+		if err := ts.RevokeTree(aEntry.TokenID); err != nil {
+			failed[accessor] = err.Error()
+			continue
+		}
+		revoked = append(revoked, accessor)
+	}
+
+	ts.logger.Info("token: revoked tokens by accessor", "revoked", len(revoked), "failed", len(failed))
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"revoked": revoked,
+			"failed":  failed,
+		},
+	}, nil
+}
+
 // handleCreate handles the auth/token/create path for creation of new orphan
 // tokens
 func (ts *TokenStore) handleCreateOrphan(
@@ -1468,6 +1835,33 @@ func (ts *TokenStore) handleCreate(
 	return ts.handleCreateCommon(req, d, false, nil)
 }
 
+// parseTokenBoundCIDRs parses and validates the CIDR blocks tokens are bound
+// to, given as a comma separated string or a list
+func parseTokenBoundCIDRs(raw interface{}) ([]string, error) {
+	d := &framework.FieldData{
+		Raw: map[string]interface{}{
+			"token_bound_cidrs": raw,
+		},
+		Schema: map[string]*framework.FieldSchema{
+			"token_bound_cidrs": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+			},
+		},
+	}
+	boundCIDRsRaw, _, err := d.GetOkErr("token_bound_cidrs")
+	if err != nil {
+		return nil, fmt.Errorf("invalid token_bound_cidrs: %v", err)
+	}
+	boundCIDRs, _ := boundCIDRsRaw.([]string)
+	if len(boundCIDRs) == 0 {
+		return nil, nil
+	}
+	if _, err := cidrutil.ValidateCIDRListSlice(boundCIDRs); err != nil {
+		return nil, fmt.Errorf("invalid token_bound_cidrs: %v", err)
+	}
+	return boundCIDRs, nil
+}
+
 // handleCreateCommon handles the auth/token/create path for creation of new tokens
 func (ts *TokenStore) handleCreateCommon(
 	req *logical.Request, d *framework.FieldData, orphan bool, role *tsRoleEntry) (*logical.Response, error) {
@@ -1517,6 +1911,10 @@ func (ts *TokenStore) handleCreateCommon(
 	te := TokenEntry{
 		Parent: req.ClientToken,
 
+		// Child tokens count against the limits of the entity of their
+		// parent, even when they are orphans
+		Entity: parent.Entity,
+
 		// The mount point is always the same since we have only one token
 		// store; using req.MountPoint causes trouble in tests since they don't
 		// have an official mount
@@ -1525,7 +1923,7 @@ func (ts *TokenStore) handleCreateCommon(
 		Meta:         data.Metadata,
 		DisplayName:  "token",
 		NumUses:      data.NumUses,
-		CreationTime: time.Now().Unix(),
+		CreationTime: ts.clock.Now().Unix(),
 	}
 
 	renewable := true
@@ -1728,6 +2126,36 @@ func (ts *TokenStore) handleCreateCommon(
 		te.ExplicitMaxTTL = dur
 	}
 
+	// Bind the token to the given CIDR blocks. They must be within those of
+	// the role and of the parent, which are used if none are given, so that
+	// the binding cannot be escaped by creating child tokens.
+	if raw, ok := req.Data["token_bound_cidrs"]; ok {
+		boundCIDRs, err := parseTokenBoundCIDRs(raw)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		te.BoundCIDRs = boundCIDRs
+	}
+	var roleBoundCIDRs []string
+	if role != nil {
+		roleBoundCIDRs = role.BoundCIDRs
+	}
+	for _, bound := range [][]string{roleBoundCIDRs, parent.BoundCIDRs} {
+		switch {
+		case len(bound) == 0:
+		case len(te.BoundCIDRs) == 0:
+			te.BoundCIDRs = bound
+		default:
+			subset, err := cidrutil.SubsetBlocks(bound, te.BoundCIDRs)
+			if err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+			if !subset {
+				return logical.ErrorResponse(fmt.Sprintf("token_bound_cidrs (%v) must be within %v", te.BoundCIDRs, bound)), logical.ErrInvalidRequest
+			}
+		}
+	}
+
 	var periodToUse time.Duration
 	if data.Period != "" {
 		if !isSudo {
@@ -2017,6 +2445,7 @@ func (ts *TokenStore) handleLookup(
 			"path":             out.Path,
 			"meta":             out.Meta,
 			"display_name":     out.DisplayName,
+			"entity":           out.Entity,
 			"num_uses":         out.NumUses,
 			"orphan":           false,
 			"creation_time":    int64(out.CreationTime),
@@ -2027,6 +2456,10 @@ func (ts *TokenStore) handleLookup(
 		},
 	}
 
+	if len(out.BoundCIDRs) > 0 {
+		resp.Data["bound_cidrs"] = out.BoundCIDRs
+	}
+
 	if out.Parent == "" {
 		resp.Data["orphan"] = true
 	}
@@ -2050,9 +2483,9 @@ func (ts *TokenStore) handleLookup(
 		}
 		if !leaseTimes.ExpireTime.IsZero() {
 			resp.Data["expire_time"] = leaseTimes.ExpireTime
-			resp.Data["ttl"] = leaseTimes.ttl()
+			resp.Data["ttl"] = leaseTimes.ttl(ts.clock.Now())
 		}
-		renewable, _ := leaseTimes.renewable()
+		renewable, _ := leaseTimes.renewable(ts.clock.Now())
 		resp.Data["renewable"] = renewable
 		resp.Data["issue_time"] = leaseTimes.IssueTime
 	}
@@ -2131,7 +2564,7 @@ func (ts *TokenStore) authRenew(
 		return nil, fmt.Errorf("no token entry found during lookup")
 	}
 
-	f := framework.LeaseExtend(req.Auth.Increment, te.ExplicitMaxTTL, ts.System())
+	f := framework.LeaseExtendClock(ts.clock.Now, req.Auth.Increment, te.ExplicitMaxTTL, ts.System())
 
 	// If (te/role).Period is not zero, this is a periodic token. The TTL for a
 	// periodic token is always the same (the period value). It is not subject
@@ -2152,8 +2585,8 @@ func (ts *TokenStore) authRenew(
 				return &logical.Response{Auth: req.Auth}, nil
 			} else {
 				maxTime := time.Unix(te.CreationTime, 0).Add(te.ExplicitMaxTTL)
-				if time.Now().Add(te.Period).After(maxTime) {
-					req.Auth.TTL = maxTime.Sub(time.Now())
+				if now := ts.clock.Now(); now.Add(te.Period).After(maxTime) {
+					req.Auth.TTL = maxTime.Sub(now)
 				} else {
 					req.Auth.TTL = te.Period
 				}
@@ -2183,8 +2616,8 @@ func (ts *TokenStore) authRenew(
 			return &logical.Response{Auth: req.Auth}, nil
 		} else {
 			maxTime := time.Unix(te.CreationTime, 0).Add(te.ExplicitMaxTTL)
-			if time.Now().Add(periodToUse).After(maxTime) {
-				req.Auth.TTL = maxTime.Sub(time.Now())
+			if now := ts.clock.Now(); now.Add(periodToUse).After(maxTime) {
+				req.Auth.TTL = maxTime.Sub(now)
 			} else {
 				req.Auth.TTL = periodToUse
 			}
@@ -2257,6 +2690,8 @@ func (ts *TokenStore) tokenStoreRoleRead(
 			"orphan":              role.Orphan,
 			"path_suffix":         role.PathSuffix,
 			"renewable":           role.Renewable,
+			"allowed_windows":     role.AllowedWindows,
+			"token_bound_cidrs":   role.BoundCIDRs,
 		},
 	}
 
@@ -2375,6 +2810,27 @@ func (ts *TokenStore) tokenStoreRoleCreateUpdate(
 		entry.DisallowedPolicies = strutil.ParseDedupLowercaseAndSortStrings(data.Get("disallowed_policies").(string), ",")
 	}
 
+	allowedWindowsRaw, ok := data.GetOk("allowed_windows")
+	if ok {
+		windows, err := schedule.ParseWindows(allowedWindowsRaw.([]string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		entry.AllowedWindows = windows.Strings()
+	} else if req.Operation == logical.CreateOperation {
+		entry.AllowedWindows = []string{}
+	}
+
+	if boundCIDRsRaw, ok := data.GetOk("token_bound_cidrs"); ok {
+		boundCIDRs, err := parseTokenBoundCIDRs(boundCIDRsRaw)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		entry.BoundCIDRs = boundCIDRs
+	} else if req.Operation == logical.CreateOperation {
+		entry.BoundCIDRs = []string{}
+	}
+
 	// Store it
 	jsonEntry, err := logical.StorageEntryJSON(fmt.Sprintf("%s%s", rolesPrefix, name), entry)
 	if err != nil {
@@ -2394,9 +2850,11 @@ conditions have occurred.
 `
 	tokenTidyDesc = `
 This endpoint performs cleanup tasks that can be run to clean up token and
-lease entries after certain error conditions. Usually running this is not
-necessary, and is only required if upgrade notes or support personnel suggest
-it.
+lease entries after certain error conditions: accessors of tokens which no
+longer exist, along with the leases of these tokens, and the parent index
+entries of tokens or parents which no longer exist. Usually running this is
+not necessary, and is only required if upgrade notes or support personnel
+suggest it.
 `
 	tokenBackendHelp = `The token credential backend is always enabled and builtin to Vault.
 Client tokens are used to identify a client and to allow Vault to associate policies and ACLs
@@ -2434,6 +2892,13 @@ their path. This can be used to assist use
 of the 'revoke-prefix' endpoint later on.
 The given suffix must match the regular
 expression.`
+	tokenBoundCIDRsHelp = `Comma separated string or list of CIDR blocks. If
+set, tokens created using this role can only be used from client addresses
+within these blocks.`
+	tokenAllowedWindowsHelp = `If set, tokens can only be created via
+this role within these windows of time.
+Each window is written like a crontab
+schedule, e.g. "* 9-17 * * 1-5".`
 	tokenExplicitMaxTTLHelp = `If set, tokens created via this role
 carry an explicit maximum TTL. During renewal,
 the current maximum TTL values of the role
@@ -2443,6 +2908,14 @@ no effect on the token being renewed.`
 	tokenRenewableHelp = `Tokens created via this role will be
 renewable or not according to this value.
 Defaults to "true".`
+	tokenRevokeAccessorsHelp = `This endpoint will delete the tokens associated with a batch of accessors and all of their child tokens.`
+	tokenRevokeAccessorsDesc = `
+This endpoint revokes the tokens associated with the given accessors, and all
+of their child tokens, e.g. to sweep the tokens of a compromised application
+found by listing the accessors. The revocation of each token is attempted even
+if others fail; the response lists the revoked accessors and the error for each
+accessor which could not be revoked.
+`
 	tokenListAccessorsHelp = `List token accessors, which can then be
 be used to iterate and discover their properities
 or revoke them. Because this can be used to
@@ -10,7 +10,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/tokenformat"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -254,6 +256,81 @@ func TestTokenStore_AccessorIndex(t *testing.T) {
 	}
 }
 
+func TestTokenStore_AccessorIndexShards(t *testing.T) {
+	_, ts, _, _ := TestCoreWithTokenStore(t)
+
+	sharded := &TokenEntry{Path: "test", Policies: []string{"dev"}}
+	if err := ts.create(sharded); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	saltedSharded, err := ts.SaltID(sharded.Accessor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := ts.view.Get(accessorPrefix + saltedSharded[:accessorShardLength] + "/" + saltedSharded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil {
+		t.Fatalf("accessor index entry not stored in its shard")
+	}
+
+	// Move the index entry of a second token to the flat index used before
+	// sharding
+	legacy := &TokenEntry{Path: "test", Policies: []string{"dev"}}
+	if err := ts.create(legacy); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	saltedLegacy, err := ts.SaltID(legacy.Accessor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err = ts.view.Get(accessorIndexPath(saltedLegacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.Key = accessorPrefix + saltedLegacy
+	if err := ts.view.Put(entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.view.Delete(accessorIndexPath(saltedLegacy)); err != nil {
+		t.Fatal(err)
+	}
+
+	aEntry, err := ts.lookupByAccessor(legacy.Accessor, false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if aEntry.TokenID != legacy.ID {
+		t.Fatalf("bad: got\n%s\nexpected\n%s\n", aEntry.TokenID, legacy.ID)
+	}
+
+	// The root token has an accessor as well
+	list, err := ts.listSaltedAccessors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, saltedAccessor := range list {
+		found[saltedAccessor] = true
+	}
+	if len(list) != 3 || !found[saltedSharded] || !found[saltedLegacy] {
+		t.Fatalf("bad: %#v", list)
+	}
+
+	// Revoking the token clears the flat index entry
+	if err := ts.Revoke(legacy.ID); err != nil {
+		t.Fatal(err)
+	}
+	entry, err = ts.view.Get(accessorPrefix + saltedLegacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != nil {
+		t.Fatalf("flat accessor index entry not deleted")
+	}
+}
+
 func TestTokenStore_HandleRequest_LookupAccessor(t *testing.T) {
 	_, ts, _, root := TestCoreWithTokenStore(t)
 	testMakeToken(t, ts, root, "tokenid", "", []string{"foo"})
@@ -336,7 +413,7 @@ func TestTokenStore_HandleRequest_ListAccessors(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		path := accessorPrefix + salted
+		path := accessorIndexPath(salted)
 		le := &logical.StorageEntry{Key: path, Value: []byte(aEntry.TokenID)}
 		if err := ts.view.Put(le); err != nil {
 			t.Fatalf("failed to persist accessor index entry: %v", err)
@@ -404,6 +481,50 @@ func TestTokenStore_HandleRequest_RevokeAccessor(t *testing.T) {
 	}
 }
 
+func TestTokenStore_HandleRequest_RevokeAccessors(t *testing.T) {
+	_, ts, _, root := TestCoreWithTokenStore(t)
+	testMakeToken(t, ts, root, "app1", "", []string{"root"})
+	testMakeToken(t, ts, "app1", "app1-child", "", []string{"foo"})
+	testMakeToken(t, ts, root, "app2", "", []string{"foo"})
+	testMakeToken(t, ts, root, "other", "", []string{"foo"})
+
+	var accessors []string
+	for _, id := range []string{"app1", "app2"} {
+		out, err := ts.Lookup(id)
+		if err != nil || out == nil {
+			t.Fatalf("err: %v", err)
+		}
+		accessors = append(accessors, out.Accessor)
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "revoke-accessors")
+	req.Data["accessors"] = strings.Join(append(accessors, "bogus"), ",")
+	resp, err := ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if revoked := resp.Data["revoked"].([]string); len(revoked) != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if failed := resp.Data["failed"].(map[string]interface{}); len(failed) != 1 || failed["bogus"] == nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	for _, id := range []string{"app1", "app1-child", "app2"} {
+		if out, err := ts.Lookup(id); err != nil || out != nil {
+			t.Fatalf("expected %s to be revoked: %v %v", id, out, err)
+		}
+	}
+	if out, err := ts.Lookup("other"); err != nil || out == nil {
+		t.Fatalf("expected the other token to remain: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "revoke-accessors")
+	if _, err := ts.HandleRequest(req); err != logical.ErrInvalidRequest {
+		t.Fatalf("expected an invalid request, got %v", err)
+	}
+}
+
 func TestTokenStore_RootToken(t *testing.T) {
 	_, ts, _, _ := TestCoreWithTokenStore(t)
 
@@ -462,6 +583,41 @@ func TestTokenStore_CreateLookup(t *testing.T) {
 	}
 }
 
+func TestTokenStore_TokenFormat(t *testing.T) {
+	_, ts, _, root := TestCoreWithTokenStore(t)
+
+	// Tokens are issued in the versioned format by default
+	ent := &TokenEntry{Path: "test", Policies: []string{"dev"}}
+	if err := ts.create(ent); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !strings.HasPrefix(ent.ID, tokenformat.Prefix) || tokenformat.Version(ent.ID) != tokenformat.CurrentVersion {
+		t.Fatalf("bad token ID: %q", ent.ID)
+	}
+
+	// Legacy UUID tokens are issued if configured, and keep working once
+	// the format changes
+	ts.tokenFormat = tokenformat.FormatUUID
+	legacy := &TokenEntry{Path: "test", Policies: []string{"dev"}}
+	if err := ts.create(legacy); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := uuid.ParseUUID(legacy.ID); err != nil {
+		t.Fatalf("bad token ID %q: %v", legacy.ID, err)
+	}
+	ts.tokenFormat = tokenformat.FormatV1
+
+	for _, id := range []string{root, ent.ID, legacy.ID} {
+		out, err := ts.Lookup(id)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out == nil {
+			t.Fatalf("token %q not found", id)
+		}
+	}
+}
+
 func TestTokenStore_CreateLookup_ProvidedID(t *testing.T) {
 	c, ts, _, _ := TestCoreWithTokenStore(t)
 
@@ -1370,6 +1526,7 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"path":             "auth/token/root",
 		"meta":             map[string]string(nil),
 		"display_name":     "root",
+		"entity":           "",
 		"orphan":           true,
 		"num_uses":         0,
 		"creation_ttl":     int64(0),
@@ -1409,6 +1566,7 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"path":             "auth/token/create",
 		"meta":             map[string]string(nil),
 		"display_name":     "token",
+		"entity":           "",
 		"orphan":           false,
 		"num_uses":         0,
 		"creation_ttl":     int64(3600),
@@ -1459,6 +1617,7 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"path":             "auth/token/create",
 		"meta":             map[string]string(nil),
 		"display_name":     "token",
+		"entity":           "",
 		"orphan":           false,
 		"num_uses":         0,
 		"creation_ttl":     int64(3600),
@@ -1540,6 +1699,7 @@ func TestTokenStore_HandleRequest_LookupSelf(t *testing.T) {
 		"path":             "auth/token/create",
 		"meta":             map[string]string(nil),
 		"display_name":     "token",
+		"entity":           "",
 		"orphan":           false,
 		"renewable":        true,
 		"num_uses":         0,
@@ -1713,6 +1873,8 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"period":              int64(259200),
 		"allowed_policies":    []string{"test1", "test2"},
 		"disallowed_policies": []string{},
+		"allowed_windows":     []string{},
+		"token_bound_cidrs":   []string{},
 		"path_suffix":         "happenin",
 		"explicit_max_ttl":    int64(0),
 		"renewable":           true,
@@ -1757,6 +1919,8 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"period":              int64(284400),
 		"allowed_policies":    []string{"test3"},
 		"disallowed_policies": []string{},
+		"allowed_windows":     []string{},
+		"token_bound_cidrs":   []string{},
 		"path_suffix":         "happenin",
 		"explicit_max_ttl":    int64(0),
 		"renewable":           false,
@@ -1794,6 +1958,8 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"explicit_max_ttl":    int64(5),
 		"allowed_policies":    []string{"test3"},
 		"disallowed_policies": []string{},
+		"allowed_windows":     []string{},
+		"token_bound_cidrs":   []string{},
 		"path_suffix":         "happenin",
 		"period":              int64(0),
 		"renewable":           false,
@@ -2539,6 +2705,47 @@ func TestTokenStore_RoleExplicitMaxTTL(t *testing.T) {
 	}
 }
 
+func TestTokenStore_Periodic_clock(t *testing.T) {
+	clock := NewTestClock(time.Unix(time.Now().Unix(), 0))
+	core, _, root := TestCoreUnsealedWithClock(t, clock)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+	req.ClientToken = root
+	req.Data = map[string]interface{}{
+		"period":           3600,
+		"explicit_max_ttl": 5400,
+	}
+	resp, err := core.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	// The renewal is capped by the explicit max TTL
+	clock.Advance(50 * time.Minute)
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/renew-self")
+	req.ClientToken = resp.Auth.ClientToken
+	if resp, err := core.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "auth/token/lookup-self")
+	req.ClientToken = resp.Auth.ClientToken
+	resp, err = core.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if ttl := resp.Data["ttl"].(int64); ttl != 2400 {
+		t.Fatalf("bad: %d", ttl)
+	}
+
+	// The token is revoked once expired
+	clock.Advance(40 * time.Minute)
+	resp, err = core.HandleRequest(req)
+	if err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied: %v %v", err, resp)
+	}
+}
+
 func TestTokenStore_Periodic(t *testing.T) {
 	core, _, _, root := TestCoreWithTokenStore(t)
 
@@ -3502,3 +3709,178 @@ func TestTokenStore_TidyLeaseRevocation(t *testing.T) {
 		t.Fatal("found leases")
 	}
 }
+
+func TestTokenStore_HandleTidyOrphanedParentIndex(t *testing.T) {
+	_, ts, _, root := TestCoreWithTokenStore(t)
+
+	testMakeToken(t, ts, root, "parent", "", []string{"root"})
+	testMakeToken(t, ts, "parent", "child", "", []string{"foo"})
+
+	saltedParent, err := ts.SaltID("parent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saltedChild, err := ts.SaltID("child")
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := parentPrefix + saltedParent + "/" + saltedChild
+
+	// Revoking the parent alone orphans the child, leaving its entry in the
+	// parent index
+	if err := ts.Revoke("parent"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out, err := ts.view.Get(index); err != nil || out == nil {
+		t.Fatalf("expected the parent index entry to remain: %v %v", out, err)
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "tidy")
+	req.ClientToken = root
+	if resp, err := ts.HandleRequest(req); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	if out, err := ts.view.Get(index); err != nil || out != nil {
+		t.Fatalf("expected the parent index entry to be deleted: %v %v", out, err)
+	}
+	if out, err := ts.Lookup("child"); err != nil || out == nil {
+		t.Fatalf("expected the child to remain valid: %v %v", out, err)
+	}
+}
+
+func TestTokenStore_RoleAllowedWindows(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+
+	// A window that never contains the current time during the test
+	closed := fmt.Sprintf("* %d * * *", (time.Now().UTC().Hour()+12)%24)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/roles/test")
+	req.ClientToken = root
+	req.Data = map[string]interface{}{
+		"allowed_windows": []string{"* 25 * * *"},
+	}
+	resp, err := core.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected error for invalid window, got: %#v", resp)
+	}
+
+	req.Data = map[string]interface{}{
+		"allowed_windows": []string{closed},
+	}
+	resp, err = core.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/create/test")
+	req.ClientToken = root
+	resp, err = core.HandleRequest(req)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected token creation outside of the window to fail, got: %v %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/roles/test")
+	req.ClientToken = root
+	req.Data = map[string]interface{}{
+		"allowed_windows": []string{closed, "* * * * *"},
+	}
+	resp, err = core.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/create/test")
+	req.ClientToken = root
+	resp, err = core.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp == nil || resp.Auth == nil || resp.Auth.ClientToken == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestTokenStore_BoundCIDRs(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	request := func(token, path, remoteAddr string, data map[string]interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, path)
+		req.ClientToken = token
+		req.Connection = &logical.Connection{RemoteAddr: remoteAddr}
+		for k, v := range data {
+			req.Data[k] = v
+		}
+		return c.HandleRequest(req)
+	}
+
+	resp, err := request(root, "auth/token/create", "127.0.0.1", map[string]interface{}{
+		"token_bound_cidrs": "10.1.0.0/16,192.168.1.0/24",
+	})
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	token := resp.Auth.ClientToken
+
+	// The token can only be used from its bound CIDR blocks
+	if resp, err := request(token, "auth/token/lookup-self", "10.1.2.3", nil); err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	} else if !reflect.DeepEqual(resp.Data["bound_cidrs"], []string{"10.1.0.0/16", "192.168.1.0/24"}) {
+		t.Fatalf("bad: %#v", resp.Data["bound_cidrs"])
+	}
+	for _, remoteAddr := range []string{"10.2.0.1", "127.0.0.1", ""} {
+		if _, err := request(token, "auth/token/lookup-self", remoteAddr, nil); !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+			t.Fatalf("%q: expected permission denied, got %v", remoteAddr, err)
+		}
+	}
+
+	// Child tokens inherit the binding and cannot widen it
+	if _, err := request(token, "auth/token/create", "10.1.2.3", map[string]interface{}{
+		"token_bound_cidrs": "10.0.0.0/8",
+	}); !errwrap.Contains(err, logical.ErrInvalidRequest.Error()) {
+		t.Fatalf("expected an invalid request, got %v", err)
+	}
+	resp, err = request(token, "auth/token/create", "10.1.2.3", nil)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if _, err := request(resp.Auth.ClientToken, "auth/token/lookup-self", "10.2.0.1", nil); !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+	resp, err = request(token, "auth/token/create", "10.1.2.3", map[string]interface{}{
+		"token_bound_cidrs": []interface{}{"10.1.2.0/24"},
+	})
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if _, err := request(resp.Auth.ClientToken, "auth/token/lookup-self", "10.1.3.1", nil); !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	// Tokens created against a role are bound to the blocks of the role
+	if resp, err := request(root, "auth/token/roles/bound", "127.0.0.1", map[string]interface{}{
+		"token_bound_cidrs": "172.16.0.0/12",
+	}); err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	resp, err = request(root, "auth/token/create/bound", "127.0.0.1", nil)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if _, err := request(resp.Auth.ClientToken, "auth/token/lookup-self", "172.16.5.5", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := request(resp.Auth.ClientToken, "auth/token/lookup-self", "127.0.0.1", nil); !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	// Invalid blocks are rejected
+	if _, err := request(root, "auth/token/create", "127.0.0.1", map[string]interface{}{
+		"token_bound_cidrs": "10.1.0.0",
+	}); !errwrap.Contains(err, logical.ErrInvalidRequest.Error()) {
+		t.Fatalf("expected an invalid request, got %v", err)
+	}
+}
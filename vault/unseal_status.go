@@ -0,0 +1,124 @@
+package vault
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	UnsealPhaseKeyring     = "keyring"
+	UnsealPhaseMounts      = "mounts"
+	UnsealPhasePolicies    = "policies"
+	UnsealPhaseCredentials = "credentials"
+	UnsealPhaseExpiration  = "expiration"
+	UnsealPhaseAudit       = "audit"
+	UnsealPhasePlugins     = "plugins"
+)
+
+// UnsealPhase describes a single phase of the unseal process
+type UnsealPhase struct {
+	Name      string
+	StartTime time.Time
+
+	// EndTime is zero while the phase is running
+	EndTime time.Time
+
+	// Completed and Total are set by phases that process a known number of
+	// items, such as the restoration of leases
+	Completed int
+	Total     int
+}
+
+// UnsealStatus describes the progress of the current, or most recent,
+// unseal of the core once the threshold of keys has been reached
+type UnsealStatus struct {
+	InProgress bool
+	StartTime  time.Time
+	Phases     []UnsealPhase
+
+	// Error is set if the most recent unseal failed
+	Error string
+}
+
+// unsealStatusTracker records the progress of unsealing. It has its own lock
+// since the state lock is held for the whole of the unseal process.
+type unsealStatusTracker struct {
+	l      sync.RWMutex
+	status UnsealStatus
+}
+
+// start resets the tracker for a new unseal
+func (t *unsealStatusTracker) start() {
+	t.l.Lock()
+	defer t.l.Unlock()
+	t.status = UnsealStatus{
+		InProgress: true,
+		StartTime:  time.Now().UTC(),
+	}
+}
+
+// inProgress returns true between calls to start and finish
+func (t *unsealStatusTracker) inProgress() bool {
+	t.l.RLock()
+	defer t.l.RUnlock()
+	return t.status.InProgress
+}
+
+// startPhase ends the running phase, if any, and starts the named one. It
+// does nothing unless an unseal is in progress.
+func (t *unsealStatusTracker) startPhase(name string) {
+	t.l.Lock()
+	defer t.l.Unlock()
+	if !t.status.InProgress {
+		return
+	}
+	now := time.Now().UTC()
+	t.endPhase(now)
+	t.status.Phases = append(t.status.Phases, UnsealPhase{
+		Name:      name,
+		StartTime: now,
+	})
+}
+
+// setPhaseProgress updates the item counts of the running phase
+func (t *unsealStatusTracker) setPhaseProgress(completed, total int) {
+	t.l.Lock()
+	defer t.l.Unlock()
+	if n := len(t.status.Phases); t.status.InProgress && n > 0 {
+		t.status.Phases[n-1].Completed = completed
+		t.status.Phases[n-1].Total = total
+	}
+}
+
+// finish ends the running phase and the unseal, recording any error
+func (t *unsealStatusTracker) finish(err error) {
+	t.l.Lock()
+	defer t.l.Unlock()
+	if !t.status.InProgress {
+		return
+	}
+	t.endPhase(time.Now().UTC())
+	t.status.InProgress = false
+	if err != nil {
+		t.status.Error = err.Error()
+	}
+}
+
+// endPhase must be called with the lock held
+func (t *unsealStatusTracker) endPhase(now time.Time) {
+	if n := len(t.status.Phases); n > 0 && t.status.Phases[n-1].EndTime.IsZero() {
+		t.status.Phases[n-1].EndTime = now
+	}
+}
+
+// UnsealStatus returns the progress of the current, or most recent, unseal.
+// Unlike most status functions it does not wait on the state lock, so it can
+// be used to follow an unseal that is taking a long time, for instance while
+// restoring a large number of leases.
+func (c *Core) UnsealStatus() UnsealStatus {
+	c.unsealStatus.l.RLock()
+	defer c.unsealStatus.l.RUnlock()
+	status := c.unsealStatus.status
+	status.Phases = append([]UnsealPhase(nil), status.Phases...)
+	return status
+}
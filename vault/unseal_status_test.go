@@ -0,0 +1,98 @@
+package vault
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestCore_UnsealStatus(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+
+	// Create a token with a TTL so that there is a lease to restore
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+	req.ClientToken = root
+	req.Data["ttl"] = "1h"
+	resp, err := c.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+
+	status := c.UnsealStatus()
+	if status.InProgress || status.Error != "" || status.StartTime.IsZero() {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	var names []string
+	for _, phase := range status.Phases {
+		names = append(names, phase.Name)
+		if phase.StartTime.IsZero() || phase.EndTime.IsZero() {
+			t.Fatalf("bad phase: %#v", phase)
+		}
+		if phase.Name == UnsealPhaseExpiration && (phase.Total == 0 || phase.Completed != phase.Total) {
+			t.Fatalf("bad expiration progress: %#v", phase)
+		}
+	}
+	expected := []string{
+		UnsealPhaseKeyring,
+		UnsealPhaseMounts,
+		UnsealPhasePolicies,
+		UnsealPhaseCredentials,
+		UnsealPhaseExpiration,
+		UnsealPhaseAudit,
+		UnsealPhasePlugins,
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("bad phases: %#v", names)
+	}
+}
+
+func TestUnsealStatusTracker(t *testing.T) {
+	var tracker unsealStatusTracker
+
+	// Phases are only recorded while an unseal is in progress
+	tracker.startPhase(UnsealPhaseMounts)
+	tracker.setPhaseProgress(5, 10)
+	if len(tracker.status.Phases) != 0 {
+		t.Fatalf("bad: %#v", tracker.status)
+	}
+
+	tracker.start()
+	tracker.startPhase(UnsealPhaseKeyring)
+	tracker.startPhase(UnsealPhaseExpiration)
+	tracker.setPhaseProgress(5, 10)
+
+	if !tracker.inProgress() {
+		t.Fatal("expected unseal to be in progress")
+	}
+	status := tracker.status
+	if len(status.Phases) != 2 || status.Phases[0].EndTime.IsZero() || !status.Phases[1].EndTime.IsZero() {
+		t.Fatalf("bad: %#v", status)
+	}
+	if status.Phases[1].Completed != 5 || status.Phases[1].Total != 10 {
+		t.Fatalf("bad: %#v", status.Phases[1])
+	}
+
+	tracker.finish(errors.New("restore failed"))
+	status = tracker.status
+	if status.InProgress || status.Error != "restore failed" || status.Phases[1].EndTime.IsZero() {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	// Finishing twice must not overwrite the result
+	tracker.finish(nil)
+	if tracker.status.Error != "restore failed" {
+		t.Fatalf("bad: %#v", tracker.status)
+	}
+}
@@ -3,6 +3,9 @@ package vault
 import (
 	"crypto/rand"
 	"fmt"
+	"io"
+
+	"github.com/hashicorp/go-uuid"
 )
 
 // memzero is used to zero out a byte buffer. This specific format is optimized
@@ -40,3 +43,16 @@ func randbytes(n int) []byte {
 	}
 	return buf
 }
+
+// generateUUID is used to create a UUID from the given source of
+// randomness, or from crypto/rand if nil
+func generateUUID(entropy io.Reader) (string, error) {
+	if entropy == nil {
+		return uuid.GenerateUUID()
+	}
+	buf := make([]byte, 16)
+	if _, err := io.ReadFull(entropy, buf); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %v", err)
+	}
+	return uuid.FormatUUID(buf)
+}
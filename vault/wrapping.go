@@ -261,7 +261,10 @@ func (c *Core) ValidateWrappingToken(req *logical.Request) (bool, error) {
 		wt, err := jws.ParseJWT([]byte(token))
 		// If there's an error we simply fall back to attempting to use it as a regular token
 		if err == nil && wt != nil {
-			validator := &jwt.Validator{}
+			validator := &jwt.Validator{
+				EXP: c.clockSkewTolerance,
+				NBF: c.clockSkewTolerance,
+			}
 			validator.SetClaim("type", "wrapping")
 			if err = wt.Validate(&c.wrappingJWTKey.PublicKey, crypto.SigningMethodES512, []*jwt.Validator{validator}...); err != nil {
 				return false, errwrap.Wrapf("wrapping token signature could not be validated: {{err}}", err)